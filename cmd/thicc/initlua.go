@@ -64,6 +64,7 @@ func luaImportMicro() *lua.LTable {
 			timerChan <- f
 		})
 	}))
+	ulua.L.SetField(pkg, "AddPaletteEntry", luar.New(ulua.L, action.AddPaletteEntry))
 
 	return pkg
 }
@@ -31,7 +31,9 @@ import (
 	"github.com/ellery/thicc/internal/config"
 	"github.com/ellery/thicc/internal/dashboard"
 	"github.com/ellery/thicc/internal/layout"
+	"github.com/ellery/thicc/internal/lsp"
 	"github.com/ellery/thicc/internal/screen"
+	"github.com/ellery/thicc/internal/sessiond"
 	"github.com/ellery/thicc/internal/shell"
 	"github.com/ellery/thicc/internal/thicc"
 	"github.com/ellery/thicc/internal/update"
@@ -54,6 +56,11 @@ var (
 	flagReportBug = flag.Bool("report-bug", false, "Report a bug or issue")
 	optionFlags   map[string]*string
 
+	// Detachable terminal sessions (see sessions.go)
+	flagAttach        = flag.Bool("attach", false, "Reattach detached terminal sessions")
+	flagListSessions  = flag.Bool("list-sessions", false, "List terminal sessions kept by the session daemon")
+	flagSessionDaemon = flag.Bool(sessiond.DaemonFlag, false, "Run the terminal session daemon (internal)")
+
 	sighup chan os.Signal
 
 	timerChan chan func()
@@ -75,6 +82,7 @@ func InitFlags() {
 		fmt.Println("  thicc .            Open current directory")
 		fmt.Println("  thicc <file>       Open a file")
 		fmt.Println("  thicc <dir>        Open a directory")
+		fmt.Println("  thicc -attach [id] Reattach detached terminal sessions")
 		fmt.Println("")
 		fmt.Println("Options:")
 		fmt.Println("  -version           Show version and exit")
@@ -86,6 +94,8 @@ func InitFlags() {
 		fmt.Println("  -clean             Clean configuration directory and exit")
 		fmt.Println("  -config-dir <dir>  Use custom configuration directory")
 		fmt.Println("  -debug             Enable debug logging to ./log.txt")
+		fmt.Println("  -attach [id]       Reattach detached terminals (most recent project, or session id)")
+		fmt.Println("  -list-sessions     List detachable terminal sessions")
 		fmt.Println("")
 		fmt.Println("Navigation:")
 		fmt.Println("  Ctrl+Space         Switch between panes")
@@ -587,6 +597,7 @@ func exit(rc int) {
 			b.Fini()
 		}
 	}
+	lsp.Shutdown()
 
 	if screen.Screen != nil {
 		screen.Screen.Fini()
@@ -630,6 +641,9 @@ func main() {
 	InitLog()
 	log.Println("THICC: After InitLog")
 
+	// Session daemon / -list-sessions exit here; -attach opens the sessions' project
+	attachRoot := DoSessionFlags()
+
 	err = config.InitConfigDir(*flagConfigDir)
 	log.Println("THICC: After InitConfigDir")
 	if err != nil {
@@ -781,7 +795,14 @@ func main() {
 	log.Println("THICC: After InitGlobals")
 
 	buffer.SetMessager(action.InfoBar)
+
+	// Start language servers for the files opened from here on
+	lsp.Init(postJob)
+
 	args := flag.Args()
+	if attachRoot != "" {
+		args = []string{attachRoot}
+	}
 	log.Println("THICC: Before LoadInput, args:", args)
 
 	// THICC: Check if we should show the dashboard (no args and interactive terminal)
@@ -961,16 +982,27 @@ func main() {
 				}
 			}
 
-			// Preload terminal to give it time to initialize the pretty prompt
-			// The terminal is created in a goroutine, and prompt injection waits 1000ms
-			// before sending the source command. We wait 1500ms total to ensure:
-			// 1. Terminal creation completes
-			// 2. 1000ms prompt injection delay
-			// 3. source command executes and clears screen
-			w, h := screen.Screen.Size()
-			log.Printf("THICC: Preloading terminal before layout init (%dx%d)", w, h)
-			thiccLayout.PreloadTerminal(w, h)
-			time.Sleep(1500 * time.Millisecond)
+			// Restore the project's saved session (overrides the AI tool preference
+			// with whatever the main terminal was running last time)
+			if thiccLayout.PrepareSessionRestore() {
+				log.Println("THICC: Found saved session for project")
+			}
+
+			if len(attachSessions) > 0 {
+				// Reattached terminals already have their prompt - no preload needed
+				thiccLayout.SetAttachSessions(attachSessions)
+			} else {
+				// Preload terminal to give it time to initialize the pretty prompt
+				// The terminal is created in a goroutine, and prompt injection waits 1000ms
+				// before sending the source command. We wait 1500ms total to ensure:
+				// 1. Terminal creation completes
+				// 2. 1000ms prompt injection delay
+				// 3. source command executes and clears screen
+				w, h := screen.Screen.Size()
+				log.Printf("THICC: Preloading terminal before layout init (%dx%d)", w, h)
+				thiccLayout.PreloadTerminal(w, h)
+				time.Sleep(1500 * time.Millisecond)
+			}
 
 			log.Println("THICC: Initializing layout panels")
 			if err := thiccLayout.Initialize(screen.Screen); err != nil {
@@ -984,6 +1016,12 @@ func main() {
 				log.Println("THICC: Hiding editor, focusing terminal for project startup")
 				thiccLayout.EditorVisible = false
 				thiccLayout.ActivePanel = 2 // Focus terminal
+
+				// Reopen tabs, panes and terminals from the last session
+				thiccLayout.RestoreSession()
+
+				// Bring back detached terminals picked by -attach
+				thiccLayout.AttachSecondaryTerminals()
 			}
 		}
 	}
@@ -1136,6 +1174,7 @@ func InitThiccLayout() {
 
 	// Create layout manager
 	thiccLayout = layout.NewLayoutManager(root)
+	thiccLayout.Post = postJob
 
 	log.Println("THICC: Layout manager created")
 
@@ -1329,6 +1368,11 @@ func TransitionToEditor(buffers []*buffer.Buffer, filePath string, showEditor bo
 		}
 	}
 
+	// Opening a project (not a file) restores its saved session
+	if thiccLayout != nil && !showEditor && filePath == "" {
+		thiccLayout.PrepareSessionRestore()
+	}
+
 	// Initialize layout panels
 	if thiccLayout != nil {
 		if err := thiccLayout.Initialize(screen.Screen); err != nil {
@@ -1344,6 +1388,9 @@ func TransitionToEditor(buffers []*buffer.Buffer, filePath string, showEditor bo
 				thiccLayout.EditorVisible = false
 				thiccLayout.ActivePanel = 2 // Focus terminal
 			}
+
+			// Reopen tabs, panes and terminals from the last session
+			thiccLayout.RestoreSession()
 		}
 	}
 
@@ -1356,3 +1403,10 @@ func TransitionToEditor(buffers []*buffer.Buffer, filePath string, showEditor bo
 
 	log.Println("THICC: TransitionToEditor completed")
 }
+
+// postJob runs f on the main loop, which redraws after it
+func postJob(f func()) {
+	go func() {
+		shell.Jobs <- shell.JobFunction{Function: func(string, []any) { f() }}
+	}()
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ellery/thicc/internal/sessiond"
+	"github.com/ellery/thicc/internal/terminal"
+)
+
+// attachSessions holds the detached sessions picked by -attach. They are
+// reattached to the terminal panes once the layout is initialized.
+var attachSessions []sessiond.SessionInfo
+
+// DoSessionFlags handles -session-daemon, -list-sessions and -attach.
+// The first two exit; -attach changes to the sessions' project directory and
+// returns the path to open.
+func DoSessionFlags() string {
+	if *flagSessionDaemon {
+		srv := sessiond.NewServer()
+		srv.Foreground = terminal.ForegroundProcessName
+		if err := srv.Run(sessiond.SocketPath()); err != nil {
+			log.Printf("THICC sessiond: %v", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if *flagListSessions {
+		listSessions()
+		os.Exit(0)
+	}
+
+	if !*flagAttach {
+		return ""
+	}
+
+	infos, err := sessiond.List()
+	if err != nil {
+		fmt.Printf("Error listing sessions: %v\n", err)
+		os.Exit(1)
+	}
+
+	var id string
+	if args := flag.Args(); len(args) > 0 {
+		id = args[0]
+	}
+	sessions, err := pickAttachSessions(infos, id)
+	if err != nil {
+		fmt.Printf("Cannot attach: %v\n", err)
+		os.Exit(1)
+	}
+
+	root := sessions[0].Root
+	if err := os.Chdir(root); err != nil {
+		fmt.Printf("Cannot open %s: %v\n", root, err)
+		os.Exit(1)
+	}
+	attachSessions = sessions
+	log.Printf("THICC: Attaching %d session(s) in %s", len(sessions), root)
+	return root
+}
+
+// pickAttachSessions chooses which sessions -attach reopens: the requested
+// one (or the most recently started detached one) plus every other detached
+// session from the same project
+func pickAttachSessions(infos []sessiond.SessionInfo, id string) ([]sessiond.SessionInfo, error) {
+	var target *sessiond.SessionInfo
+	for i := range infos {
+		info := &infos[i]
+		if id != "" {
+			if info.ID == id {
+				target = info
+				break
+			}
+			continue
+		}
+		if !info.Attached && (target == nil || info.Created.After(target.Created)) {
+			target = info
+		}
+	}
+	if target == nil {
+		if id != "" {
+			return nil, fmt.Errorf("no session %q (see thicc -list-sessions)", id)
+		}
+		return nil, errors.New("no detached sessions")
+	}
+
+	sessions := []sessiond.SessionInfo{*target}
+	for _, info := range infos {
+		if info.ID != target.ID && info.Root == target.Root && !info.Attached {
+			sessions = append(sessions, info)
+		}
+	}
+	return sessions, nil
+}
+
+// listSessions prints the daemon's sessions
+func listSessions() {
+	infos, err := sessiond.List()
+	if err != nil {
+		fmt.Printf("Error listing sessions: %v\n", err)
+		os.Exit(1)
+	}
+	if len(infos) == 0 {
+		fmt.Println("No sessions")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tPROJECT\tCOMMAND\tSTARTED\tSTATE")
+	for _, info := range infos {
+		state := "detached"
+		if info.Attached {
+			state = "attached"
+		}
+		command := ""
+		if len(info.Command) > 0 {
+			command = strings.Join(append([]string{filepath.Base(info.Command[0])}, info.Command[1:]...), " ")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", info.ID, info.Root, command,
+			info.Created.Format(time.DateTime), state)
+	}
+	w.Flush()
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/ellery/thicc/internal/clipboard"
 	"github.com/ellery/thicc/internal/config"
 	"github.com/ellery/thicc/internal/display"
+	"github.com/ellery/thicc/internal/lsp"
 	"github.com/ellery/thicc/internal/screen"
 	"github.com/ellery/thicc/internal/shell"
 	"github.com/ellery/thicc/internal/thicc"
@@ -950,6 +951,10 @@ func (h *BufPane) Autocomplete() bool {
 		return false
 	}
 
+	// Ask the language server first, then fall back to words in the buffer
+	if lsp.Default.HasServer(b) && b.Autocomplete(lsp.Default.Complete) {
+		return true
+	}
 	return b.Autocomplete(buffer.BufferComplete)
 }
 
@@ -1971,6 +1976,7 @@ func (h *BufPane) ForceQuit() bool {
 	} else if len(Tabs.List) > 1 {
 		Tabs.RemoveTab(h.splitID)
 	} else {
+		runBeforeExit()
 		screen.Screen.Fini()
 		InfoBar.Close()
 		runtime.Goexit()
@@ -2021,6 +2027,7 @@ func (h *BufPane) QuitAll() bool {
 
 	// doQuit performs the actual quit after update check
 	doQuit := func() {
+		runBeforeExit()
 		buffer.CloseOpenBuffers()
 		screen.Screen.Fini()
 		InfoBar.Close()
@@ -0,0 +1,94 @@
+package action
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/ellery/thicc/internal/buffer"
+	"github.com/ellery/thicc/internal/screen"
+)
+
+// ToggleBlame turns the git blame gutter on and off. The blame is run in the
+// background against the buffer's current text, so unsaved edits show up as
+// uncommitted lines.
+func (h *BufPane) ToggleBlame() bool {
+	b := h.Buf
+	if b.HasBlame() {
+		b.ClearBlame()
+		InfoBar.Message("Disabled blame")
+		return true
+	}
+	if b.AbsPath == "" || b.Type != buffer.BTDefault {
+		InfoBar.Error("Blame is only available for files")
+		return false
+	}
+
+	gitRoot, err := getGitRoot(b.AbsPath)
+	if err != nil {
+		InfoBar.Error("Not in a git repository")
+		return false
+	}
+	relPath := getRelativeGitPath(b.AbsPath)
+	content := b.Bytes()
+	InfoBar.Message("Loading blame...")
+
+	go func() {
+		lines, err := runBlame(gitRoot, relPath, content)
+		if err != nil {
+			log.Printf("THICC Blame: git blame failed for %s: %v", relPath, err)
+			InfoBar.Error("Blame failed: ", err)
+		} else {
+			b.SetBlame(content, lines)
+			log.Printf("THICC Blame: Blamed %d lines of %s", len(lines), relPath)
+			InfoBar.Message("Enabled blame")
+		}
+		screen.Redraw()
+	}()
+	return true
+}
+
+// runBlame runs git blame on content as the current version of relPath
+func runBlame(gitRoot, relPath string, content []byte) ([]*buffer.BlameLine, error) {
+	cmd := exec.Command("git", "blame", "--porcelain", "--contents", "-", "--", relPath)
+	cmd.Dir = gitRoot
+	cmd.Stdin = bytes.NewReader(content)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.New(msg)
+		}
+		return nil, err
+	}
+	return buffer.ParseBlamePorcelain(string(output)), nil
+}
+
+// BlameCommitDiff returns the diff of the commit that last changed the line
+// under the cursor, for opening in a new tab. The commit's author, age and
+// summary are shown in the infobar.
+func (h *BufPane) BlameCommitDiff() *buffer.Buffer {
+	line := h.Buf.Blame(h.Cursor.Y)
+	if line == nil {
+		InfoBar.Message("No blame for this line")
+		return nil
+	}
+	if !line.Committed() {
+		InfoBar.Message("Not committed yet")
+		return nil
+	}
+	gitRoot, err := getGitRoot(h.Buf.AbsPath)
+	if err != nil {
+		InfoBar.Error("Not in a git repository")
+		return nil
+	}
+
+	InfoBar.Message(fmt.Sprintf("%s %s, %s ago: %s", line.Hash[:7], line.Author,
+		buffer.BlameAge(line.Time, time.Now()), line.Summary))
+	return NewCommitDiffBuffer(line.Hash, line.Filename, gitRoot)
+}
@@ -827,6 +827,7 @@ var BufKeyActions = map[string]BufKeyAction{
 	"ToggleKeyMenu":             (*BufPane).ToggleKeyMenu,
 	"ToggleDiffGutter":          (*BufPane).ToggleDiffGutter,
 	"ToggleRuler":               (*BufPane).ToggleRuler,
+	"ToggleBlame":               (*BufPane).ToggleBlame,
 	"ToggleHighlightSearch":     (*BufPane).ToggleHighlightSearch,
 	"UnhighlightSearch":         (*BufPane).UnhighlightSearch,
 	"ResetSearch":               (*BufPane).ResetSearch,
@@ -346,12 +346,31 @@ func ShowCommitDiff(commitHash, filePath, repoRoot string) (*buffer.Buffer, bool
 		return nil, false
 	}
 
+	diffBuf := NewCommitDiffBuffer(commitHash, filePath, repoRoot)
+	if diffBuf == nil {
+		return nil, false
+	}
+
+	// Open in current pane
+	curPane.OpenBuffer(diffBuf)
+
+	log.Printf("THICC Diff: Successfully opened commit diff view for '%s' at %s", filePath, commitHash)
+	return diffBuf, true
+}
+
+// NewCommitDiffBuffer creates the read-only buffer ShowCommitDiff displays,
+// without opening it. Returns nil if the diff can't be generated.
+func NewCommitDiffBuffer(commitHash, filePath, repoRoot string) *buffer.Buffer {
 	// Use provided repo root
 	gitRoot := repoRoot
 
 	// Run git show to get the diff
 	var cmd *exec.Cmd
-	if filePath == "" {
+	if isStashRef(commitHash) {
+		// Stashes are merge commits: show them against the commit they were
+		// made on, including any untracked files they hold
+		cmd = exec.Command("git", "stash", "show", "--no-color", "--stat", "--patch", "--include-untracked", commitHash)
+	} else if filePath == "" {
 		// Show full commit diff (all files)
 		cmd = exec.Command("git", "show", "--no-color", "--stat", "--patch", commitHash)
 	} else {
@@ -362,7 +381,7 @@ func ShowCommitDiff(commitHash, filePath, repoRoot string) (*buffer.Buffer, bool
 	output, err := cmd.Output()
 	if err != nil {
 		log.Printf("THICC Diff: git show failed: %v", err)
-		return nil, false
+		return nil
 	}
 
 	diffOutput := string(output)
@@ -384,11 +403,13 @@ func ShowCommitDiff(commitHash, filePath, repoRoot string) (*buffer.Buffer, bool
 
 	// Create read-only buffer with clean content
 	shortHash := commitHash
-	if len(shortHash) > 7 {
+	if len(shortHash) > 7 && !isStashRef(commitHash) {
 		shortHash = shortHash[:7]
 	}
 	var bufName string
-	if filePath == "" {
+	if isStashRef(commitHash) {
+		bufName = shortHash
+	} else if filePath == "" {
 		bufName = "commit " + shortHash
 	} else {
 		baseName := filepath.Base(filePath)
@@ -397,7 +418,7 @@ func ShowCommitDiff(commitHash, filePath, repoRoot string) (*buffer.Buffer, bool
 	diffBuf := buffer.NewBufferFromString(cleanContent, bufName, buffer.BTHelp)
 	if diffBuf == nil {
 		log.Println("THICC Diff: Failed to create buffer")
-		return nil, false
+		return nil
 	}
 
 	// Store the diff line metadata for gutter rendering
@@ -408,10 +429,73 @@ func ShowCommitDiff(commitHash, filePath, repoRoot string) (*buffer.Buffer, bool
 		diffBuf.SetOptionNative("filetype", fileType)
 	}
 
+	return diffBuf
+}
+
+// isStashRef returns true for stash references like "stash@{0}"
+func isStashRef(ref string) bool {
+	return strings.HasPrefix(ref, "stash@{")
+}
+
+// NewRevisionBuffer creates a read-only buffer with a file's content as it
+// was at a commit, e.g. "main.go @ abc1234"
+func NewRevisionBuffer(content, filePath, commitHash string) *buffer.Buffer {
+	shortHash := commitHash
+	if len(shortHash) > 7 {
+		shortHash = shortHash[:7]
+	}
+	bufName := filepath.Base(filePath) + " @ " + shortHash
+	revBuf := buffer.NewBufferFromString(content, bufName, buffer.BTHelp)
+	if revBuf == nil {
+		log.Println("THICC Diff: Failed to create buffer")
+		return nil
+	}
+
+	if fileType := extToFileType(filepath.Ext(filePath)); fileType != "" {
+		revBuf.SetOptionNative("filetype", fileType)
+	}
+	log.Printf("THICC Diff: Created revision buffer for '%s' at %s", filePath, shortHash)
+	return revBuf
+}
+
+// ShowPatchDiff shows an already generated patch (e.g. a single hunk) in a
+// read-only buffer, using the same gutter rendering as ShowUnifiedDiff.
+// filePath is only used for the buffer name and syntax highlighting.
+func ShowPatchDiff(patch, filePath string) (*buffer.Buffer, bool) {
+	log.Printf("THICC Diff: ShowPatchDiff called for %s (%d bytes)", filePath, len(patch))
+
+	// Get current pane
+	curPane := MainTab().CurPane()
+	if curPane == nil {
+		log.Println("THICC Diff: No current pane")
+		return nil, false
+	}
+
+	cleanContent, lineTypes := parseDiffContent(patch)
+	if len(lineTypes) == 0 {
+		cleanContent = "No changes in this hunk"
+		lineTypes = map[int]byte{0: DiffLineNone}
+	}
+
+	bufName := filepath.Base(filePath) + " [hunk]"
+	diffBuf := buffer.NewBufferFromString(cleanContent, bufName, buffer.BTHelp)
+	if diffBuf == nil {
+		log.Println("THICC Diff: Failed to create buffer")
+		return nil, false
+	}
+
+	// Store the diff line metadata for gutter rendering
+	diffBuf.UnifiedDiffLines = lineTypes
+
+	// Set filetype for proper syntax highlighting of the actual code
+	if fileType := extToFileType(filepath.Ext(filePath)); fileType != "" {
+		diffBuf.SetOptionNative("filetype", fileType)
+	}
+
 	// Open in current pane
 	curPane.OpenBuffer(diffBuf)
 
-	log.Printf("THICC Diff: Successfully opened commit diff view for '%s' at %s", filePath, shortHash)
+	log.Printf("THICC Diff: Successfully opened hunk view for %s with %d lines", filePath, len(lineTypes))
 	return diffBuf, true
 }
 
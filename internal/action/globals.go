@@ -8,6 +8,17 @@ var InfoBar *InfoPane
 // LogBufPane is a global log buffer.
 var LogBufPane *BufPane
 
+// BeforeExit, if set, is called when quitting the last pane or quitting all
+// is about to exit the editor
+var BeforeExit func()
+
+// runBeforeExit calls BeforeExit if it's set
+func runBeforeExit() {
+	if BeforeExit != nil {
+		BeforeExit()
+	}
+}
+
 // InitGlobals initializes the log buffer and the info bar
 func InitGlobals() {
 	InfoBar = NewInfoBar()
@@ -0,0 +1,166 @@
+package action
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ellery/thicc/internal/config"
+	"github.com/ellery/thicc/internal/util"
+)
+
+// PaletteEntry is an entry a plugin adds to the command palette
+type PaletteEntry struct {
+	Name        string
+	Description string
+	Run         func(bp *BufPane)
+}
+
+var paletteEntries []PaletteEntry
+
+// AddPaletteEntry adds an entry to the command palette, replacing any entry
+// with the same name. run is called with the editor's pane.
+// This can be called by plugins in Lua
+func AddPaletteEntry(name, description string, run func(bp *BufPane)) {
+	if name == "" || run == nil {
+		return
+	}
+	entry := PaletteEntry{name, description, run}
+	for i, e := range paletteEntries {
+		if e.Name == name {
+			paletteEntries[i] = entry
+			return
+		}
+	}
+	paletteEntries = append(paletteEntries, entry)
+}
+
+// PaletteEntries returns the entries plugins added to the command palette
+func PaletteEntries() []PaletteEntry {
+	return paletteEntries
+}
+
+// ActionNames returns the names of the key actions, sorted
+func ActionNames() []string {
+	names := make([]string, 0, len(BufKeyActions))
+	for name := range BufKeyActions {
+		if name != "None" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CommandNames returns the names of the commands, sorted
+func CommandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ActionKey returns the key bound to an action in the editor, or "" if
+// there is none
+func ActionKey(name string) string {
+	return boundKey(func(a string) bool {
+		return a == name
+	})
+}
+
+// CommandKey returns the key bound to a command in the editor, or "" if
+// there is none
+func CommandKey(name string) string {
+	return boundKey(func(a string) bool {
+		for _, prefix := range []string{"command:", "command-edit:"} {
+			if cmd, ok := strings.CutPrefix(a, prefix); ok {
+				fields := strings.Fields(cmd)
+				return len(fields) > 0 && fields[0] == name
+			}
+		}
+		return false
+	})
+}
+
+// boundKey returns the shortest key whose bound actions include one that
+// matches, formatted for display
+func boundKey(match func(a string) bool) string {
+	var keys []string
+	for k, v := range config.Bindings["buffer"] {
+		if strings.Contains(k, "Mouse") {
+			continue
+		}
+		for v != "" {
+			a := v
+			if idx := util.IndexAnyUnquoted(v, "&|,"); idx >= 0 {
+				a, v = v[:idx], v[idx+1:]
+			} else {
+				v = ""
+			}
+			if match(a) {
+				keys = append(keys, DisplayKey(k))
+				break
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) < len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	return keys[0]
+}
+
+// DisplayKey formats a key name from the bindings, like "Ctrl-s" or
+// "CtrlShiftUp", the way keys are written in the UI: "Ctrl+S", "Ctrl+Shift+Up"
+func DisplayKey(k string) string {
+	if strings.HasPrefix(k, "<") {
+		return k // Key sequence
+	}
+	var parts []string
+	for {
+		found := false
+		for _, mod := range []string{"Ctrl", "Alt", "Shift"} {
+			if rest, ok := strings.CutPrefix(k, mod); ok && rest != "" {
+				parts = append(parts, mod)
+				k = strings.TrimPrefix(rest, "-")
+				found = true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	if utf8.RuneCountInString(k) == 1 {
+		k = strings.ToUpper(k)
+	}
+	return strings.Join(append(parts, k), "+")
+}
+
+// RunAction runs the key action with the given name as if a key bound to it
+// was pressed. Returns false if there is no such action.
+func (h *BufPane) RunAction(name string) bool {
+	a, ok := BufKeyActions[name]
+	if !ok {
+		return false
+	}
+	if _, ok := MultiActions[name]; ok {
+		for _, c := range h.Buf.GetCursors() {
+			h.Buf.SetCurCursor(c.Num)
+			h.Cursor = c
+			h.execAction(a, name, nil)
+		}
+	} else {
+		h.Buf.SetCurCursor(0)
+		h.Cursor = h.Buf.GetActiveCursor()
+		h.execAction(a, name, nil)
+	}
+	return true
+}
@@ -0,0 +1,209 @@
+package buffer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ellery/thicc/internal/screen"
+	dmp "github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// BlameLine is the commit that last changed a line, as reported by git blame
+type BlameLine struct {
+	Hash     string // Full commit hash, empty if the line isn't committed yet
+	Author   string
+	Time     time.Time
+	Summary  string // First line of the commit message
+	Filename string // Path of the file in that commit (relative to the repo root)
+}
+
+// Committed returns true if the line comes from a commit
+func (l *BlameLine) Committed() bool {
+	return l.Hash != ""
+}
+
+// uncommittedLine is the blame of lines that were added since the blame ran
+var uncommittedLine = &BlameLine{Author: "Not Committed Yet"}
+
+// ParseBlamePorcelain parses `git blame --porcelain` output into the blame
+// of each line of the file. Lines git reports with the all-zero hash (changes
+// in the working tree) get an uncommitted BlameLine.
+func ParseBlamePorcelain(output string) []*BlameLine {
+	var lines []*BlameLine
+	commits := make(map[string]*BlameLine)
+	var cur *BlameLine
+	final := 0
+
+	for _, line := range strings.Split(output, "\n") {
+		if cur == nil {
+			// Header: <hash> <orig line> <final line> [<lines in group>]
+			fields := strings.Fields(line)
+			if len(fields) < 3 || len(fields[0]) < 40 {
+				continue
+			}
+			n, err := strconv.Atoi(fields[2])
+			if err != nil {
+				continue
+			}
+			final = n
+			cur = commits[fields[0]]
+			if cur == nil {
+				cur = &BlameLine{}
+				if strings.Trim(fields[0], "0") != "" {
+					cur.Hash = fields[0]
+				}
+				commits[fields[0]] = cur
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "\t"):
+			// Content line ends the entry
+			for len(lines) < final {
+				lines = append(lines, nil)
+			}
+			lines[final-1] = cur
+			cur = nil
+		case strings.HasPrefix(line, "author "):
+			cur.Author = strings.TrimPrefix(line, "author ")
+		case strings.HasPrefix(line, "author-time "):
+			if secs, err := strconv.ParseInt(strings.TrimPrefix(line, "author-time "), 10, 64); err == nil {
+				cur.Time = time.Unix(secs, 0)
+			}
+		case strings.HasPrefix(line, "summary "):
+			cur.Summary = strings.TrimPrefix(line, "summary ")
+		case strings.HasPrefix(line, "filename "):
+			cur.Filename = strings.TrimPrefix(line, "filename ")
+		}
+	}
+
+	for _, l := range commits {
+		if !l.Committed() {
+			l.Author = uncommittedLine.Author
+		}
+	}
+	return lines
+}
+
+// BlameAge returns a short age like "5m", "3d" or "2y" for the blame gutter
+func BlameAge(t, now time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d/time.Minute))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d/time.Hour))
+	case d < 30*24*time.Hour:
+		return fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	case d < 365*24*time.Hour:
+		return fmt.Sprintf("%dmo", int(d/(30*24*time.Hour)))
+	}
+	return fmt.Sprintf("%dy", int(d/(365*24*time.Hour)))
+}
+
+func (b *Buffer) updateBlame(synchronous bool) {
+	b.blameLock.Lock()
+	defer b.blameLock.Unlock()
+
+	if b.blameBase == nil {
+		b.blame = nil
+		return
+	}
+
+	if !synchronous {
+		b.Lock()
+	}
+	bytes := b.Bytes()
+	if !synchronous {
+		b.Unlock()
+	}
+
+	// Carry each line's blame over from the text that was blamed, the same
+	// way the diff gutter compares against the diff base
+	differ := dmp.New()
+	baseRunes, bufferRunes, _ := differ.DiffLinesToRunes(string(b.blameBase), string(bytes))
+	diffs := differ.DiffMainRunes(baseRunes, bufferRunes, false)
+
+	blame := make([]*BlameLine, 0, len(b.blameBaseLines))
+	baseN := 0
+	for _, diff := range diffs {
+		lineCount := len([]rune(diff.Text))
+
+		switch diff.Type {
+		case dmp.DiffEqual:
+			for i := 0; i < lineCount; i++ {
+				var line *BlameLine
+				if baseN < len(b.blameBaseLines) {
+					line = b.blameBaseLines[baseN]
+				}
+				blame = append(blame, line)
+				baseN++
+			}
+		case dmp.DiffInsert:
+			for i := 0; i < lineCount; i++ {
+				blame = append(blame, uncommittedLine)
+			}
+		case dmp.DiffDelete:
+			baseN += lineCount
+		}
+	}
+	b.blame = blame
+}
+
+// UpdateBlame maps the blame onto the buffer's current lines after edits.
+// Like UpdateDiff, the update is done in the background for large files.
+func (b *Buffer) UpdateBlame() {
+	if b.updateBlameTimer != nil || !b.HasBlame() {
+		return
+	}
+
+	if b.LinesNum() < 1000 {
+		b.updateBlame(true)
+	} else {
+		b.updateBlameTimer = time.AfterFunc(500*time.Millisecond, func() {
+			b.updateBlameTimer = nil
+			b.updateBlame(false)
+			screen.Redraw()
+		})
+	}
+}
+
+// SetBlame sets the blame of each line of base, the text that was blamed
+func (b *Buffer) SetBlame(base []byte, lines []*BlameLine) {
+	b.blameLock.Lock()
+	b.blameBase = base
+	b.blameBaseLines = lines
+	b.blameLock.Unlock()
+	b.updateBlame(false)
+}
+
+// ClearBlame turns off the blame for the buffer
+func (b *Buffer) ClearBlame() {
+	b.blameLock.Lock()
+	defer b.blameLock.Unlock()
+	b.blameBase = nil
+	b.blameBaseLines = nil
+	b.blame = nil
+}
+
+// HasBlame returns true if blame information is set for the buffer
+func (b *Buffer) HasBlame() bool {
+	b.blameLock.RLock()
+	defer b.blameLock.RUnlock()
+	return b.blameBase != nil
+}
+
+// Blame returns the blame of a line in the buffer, or nil if it is unknown
+func (b *Buffer) Blame(lineN int) *BlameLine {
+	b.blameLock.RLock()
+	defer b.blameLock.RUnlock()
+	if lineN < 0 || lineN >= len(b.blame) {
+		return nil
+	}
+	return b.blame[lineN]
+}
@@ -0,0 +1,133 @@
+package buffer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	hashA = "1111111111111111111111111111111111111111"
+	hashB = "2222222222222222222222222222222222222222"
+	hashZ = "0000000000000000000000000000000000000000"
+)
+
+// blameOutput is `git blame --porcelain` for a three line file where line 2
+// is uncommitted. Commit details only appear the first time a commit does.
+var blameOutput = strings.Join([]string{
+	hashA + " 1 1 1",
+	"author Alice",
+	"author-mail <alice@example.com>",
+	"author-time 1700000000",
+	"author-tz +0000",
+	"summary First commit",
+	"filename old/main.go",
+	"\tpackage main",
+	hashZ + " 2 2 1",
+	"author Not Committed Yet",
+	"author-time 1800000000",
+	"summary Version of main.go from -",
+	"filename main.go",
+	"\t// edited",
+	hashA + " 3 3",
+	"\tfunc main() {}",
+	"",
+}, "\n")
+
+// =============================================================================
+// Porcelain Parsing Tests
+// =============================================================================
+
+func TestParseBlamePorcelain(t *testing.T) {
+	lines := ParseBlamePorcelain(blameOutput)
+	require.Len(t, lines, 3)
+
+	assert.Equal(t, hashA, lines[0].Hash)
+	assert.Equal(t, "Alice", lines[0].Author)
+	assert.Equal(t, "First commit", lines[0].Summary)
+	assert.Equal(t, "old/main.go", lines[0].Filename)
+	assert.Equal(t, time.Unix(1700000000, 0), lines[0].Time)
+
+	assert.False(t, lines[1].Committed())
+	assert.Same(t, lines[0], lines[2], "repeated commits share their details")
+}
+
+func TestParseBlamePorcelain_Empty(t *testing.T) {
+	assert.Empty(t, ParseBlamePorcelain(""))
+}
+
+func TestBlameAge(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	for _, tc := range []struct {
+		ago  time.Duration
+		want string
+	}{
+		{10 * time.Second, "now"},
+		{5 * time.Minute, "5m"},
+		{3 * time.Hour, "3h"},
+		{4 * 24 * time.Hour, "4d"},
+		{70 * 24 * time.Hour, "2mo"},
+		{800 * 24 * time.Hour, "2y"},
+	} {
+		assert.Equal(t, tc.want, BlameAge(now.Add(-tc.ago), now), tc.ago.String())
+	}
+}
+
+// =============================================================================
+// Blame Mapping Tests
+// =============================================================================
+
+func blamedBuffer(t *testing.T) (*Buffer, []*BlameLine) {
+	text := "one\ntwo\nthree"
+	b := NewBufferFromString(text, "", BTDefault)
+	lines := []*BlameLine{{Hash: hashA}, {Hash: hashB}, {Hash: hashA}}
+	b.SetBlame([]byte(text), lines)
+	require.True(t, b.HasBlame())
+	return b, lines
+}
+
+func TestBlame_Unedited(t *testing.T) {
+	b, lines := blamedBuffer(t)
+
+	for i := range lines {
+		assert.Same(t, lines[i], b.Blame(i))
+	}
+	assert.Nil(t, b.Blame(3))
+	assert.Nil(t, b.Blame(-1))
+}
+
+func TestBlame_FollowsEdits(t *testing.T) {
+	b, lines := blamedBuffer(t)
+
+	// Insert a line at the top and delete "two"
+	b.Insert(Loc{0, 0}, "zero\n")
+	b.Remove(Loc{0, 2}, Loc{0, 3})
+	b.UpdateBlame()
+	require.Equal(t, "zero\none\nthree", string(b.Bytes()))
+
+	assert.False(t, b.Blame(0).Committed(), "inserted lines are uncommitted")
+	assert.Same(t, lines[0], b.Blame(1))
+	assert.Same(t, lines[2], b.Blame(2))
+}
+
+func TestBlame_EditedLineIsUncommitted(t *testing.T) {
+	b, lines := blamedBuffer(t)
+
+	b.Insert(Loc{3, 1}, "!")
+	b.UpdateBlame()
+
+	assert.Same(t, lines[0], b.Blame(0))
+	assert.False(t, b.Blame(1).Committed())
+	assert.Same(t, lines[2], b.Blame(2))
+}
+
+func TestClearBlame(t *testing.T) {
+	b, _ := blamedBuffer(t)
+
+	b.ClearBlame()
+	assert.False(t, b.HasBlame())
+	assert.Nil(t, b.Blame(0))
+}
@@ -129,6 +129,14 @@ type SharedBuffer struct {
 	// Values: 0=none, 1=added (+), 2=deleted (-), 3=context (space), 4=header/hunk
 	UnifiedDiffLines map[int]byte
 
+	// Git blame of the buffer (see blame.go): the blamed text, the blame
+	// of each of its lines, and the blame mapped onto the current lines
+	updateBlameTimer *time.Timer
+	blameBase        []byte
+	blameBaseLines   []*BlameLine
+	blameLock        sync.RWMutex
+	blame            []*BlameLine
+
 	forceKeepBackup bool
 
 	// ReloadDisabled allows the user to disable reloads if they
@@ -159,6 +167,7 @@ type SharedBuffer struct {
 }
 
 func (b *SharedBuffer) insert(pos Loc, value []byte) {
+	b.changing(pos, pos, value)
 	b.HasSuggestions = false
 	b.LineArray.insert(pos, value)
 	b.setModified()
@@ -168,6 +177,7 @@ func (b *SharedBuffer) insert(pos Loc, value []byte) {
 }
 
 func (b *SharedBuffer) remove(start, end Loc) []byte {
+	b.changing(start, end, nil)
 	b.HasSuggestions = false
 	defer b.setModified()
 	defer b.MarkModified(start.Y, end.Y)
@@ -542,6 +552,10 @@ func NewBuffer(r io.Reader, size int64, path string, btype BufType, cmd Command)
 
 	OpenBuffers = append(OpenBuffers, b)
 
+	for _, h := range hooks {
+		h.BufferOpened(b)
+	}
+
 	return b
 }
 
@@ -562,6 +576,9 @@ func (b *Buffer) Close() {
 			copy(OpenBuffers[i:], OpenBuffers[i+1:])
 			OpenBuffers[len(OpenBuffers)-1] = nil
 			OpenBuffers = OpenBuffers[:len(OpenBuffers)-1]
+			for _, h := range hooks {
+				h.BufferClosed(b)
+			}
 			return
 		}
 	}
@@ -1334,6 +1351,9 @@ func (b *Buffer) Retab() {
 	}
 
 	b.setModified()
+	for _, h := range hooks {
+		h.BufferReset(b.SharedBuffer)
+	}
 }
 
 // ParseCursorLocation turns a cursor location like 10:5 (LINE:COL)
@@ -0,0 +1,49 @@
+package buffer
+
+// A TextChange is an edit about to be made to a buffer: the text from Start
+// to End is replaced with Text
+type TextChange struct {
+	Start, End Loc
+	Text       []byte
+}
+
+// Hooks follow buffers as they are opened, edited, saved and closed, for
+// subsystems that keep a copy of their text such as language servers
+type Hooks interface {
+	// BufferOpened is called when a buffer is created. Buffers of the same
+	// file share one SharedBuffer.
+	BufferOpened(b *Buffer)
+	// BufferChanging is called before each edit, while the buffer still holds
+	// the text the change applies to
+	BufferChanging(b *SharedBuffer, c TextChange)
+	// BufferReset is called after the whole text changed in a way that isn't
+	// described by edits
+	BufferReset(b *SharedBuffer)
+	// BufferSaved is called after a buffer was written to its file
+	BufferSaved(b *Buffer)
+	// BufferClosed is called when a buffer is closed
+	BufferClosed(b *Buffer)
+}
+
+var hooks []Hooks
+
+// AddHooks registers hooks for the buffers opened from now on
+func AddHooks(h Hooks) {
+	hooks = append(hooks, h)
+}
+
+// RemoveHooks unregisters hooks added with AddHooks
+func RemoveHooks(h Hooks) {
+	for i, other := range hooks {
+		if other == h {
+			hooks = append(hooks[:i], hooks[i+1:]...)
+			return
+		}
+	}
+}
+
+func (b *SharedBuffer) changing(start, end Loc, text []byte) {
+	for _, h := range hooks {
+		h.BufferChanging(b, TextChange{start, end, text})
+	}
+}
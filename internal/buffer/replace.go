@@ -0,0 +1,156 @@
+package buffer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/ellery/thicc/internal/util"
+)
+
+// ReplaceMatches replaces the matches of search that keep selects as a single
+// undoable change. keep is given the 1-based line and character column where
+// each match starts and the text matched there; empty matches are never
+// replaced. With captureGroups, $1 and ${name} in replace expand as they do
+// in ReplaceRegex. Returns the number of replacements made.
+func (b *Buffer) ReplaceMatches(search *regexp.Regexp, replace []byte, captureGroups bool, keep func(line, col int, text string) bool) int {
+	found := 0
+	var deltas []Delta
+
+	for i := 0; i < b.LinesNum(); i++ {
+		l := b.LineBytes(i)
+		newLine, n := replaceLine(search, l, replace, captureGroups, func(col int, text string) bool {
+			return keep(i+1, col, text)
+		})
+		if n == 0 {
+			continue
+		}
+		found += n
+		deltas = append(deltas, Delta{newLine, Loc{0, i}, Loc{util.CharacterCount(l), i}})
+	}
+
+	if len(deltas) > 0 {
+		b.MultipleReplace(deltas)
+		b.RelocateCursors()
+	}
+	return found
+}
+
+// replaceLine replaces the matches of search in one line that keep selects,
+// given their 1-based character column and text. Returns the new line and
+// the number of replacements made.
+func replaceLine(search *regexp.Regexp, l, replace []byte, captureGroups bool, keep func(col int, text string) bool) ([]byte, int) {
+	var newLine []byte
+	last, found := 0, 0
+	for _, match := range search.FindAllSubmatchIndex(l, -1) {
+		if match[0] == match[1] || !keep(utf8.RuneCount(l[:match[0]])+1, string(l[match[0]:match[1]])) {
+			continue
+		}
+		newLine = append(newLine, l[last:match[0]]...)
+		if captureGroups {
+			newLine = search.Expand(newLine, replace, l, match)
+		} else {
+			newLine = append(newLine, replace...)
+		}
+		last = match[1]
+		found++
+	}
+	if found == 0 {
+		return l, 0
+	}
+	return append(newLine, l[last:]...), found
+}
+
+// ReplaceInFile replaces the matches of search that keep selects in the file
+// at path (see EditFile). Returns the number of replacements made.
+func ReplaceInFile(path string, search *regexp.Regexp, replace []byte, captureGroups bool, keep func(line, col int, text string) bool) (int, error) {
+	return EditFile(path, func(b *Buffer) int {
+		return b.ReplaceMatches(search, replace, captureGroups, keep)
+	})
+}
+
+// EditFile makes changes to the file at path through a buffer. edit makes
+// the changes and returns how many it made. If the file is open, its buffer
+// is changed so that the edit can be undone, and saved unless it already had
+// unsaved changes. Otherwise the file is loaded into a buffer, changed and
+// saved through the usual safe write. Returns the result of edit.
+func EditFile(path string, edit func(b *Buffer) int) (int, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return 0, err
+	}
+
+	if b := FindOpenBuffer(absPath); b != nil {
+		modified := b.Modified()
+		n := edit(b)
+		if n == 0 || modified {
+			return n, nil
+		}
+		return n, b.Save()
+	}
+
+	b, err := NewBufferFromFile(absPath, BTDefault)
+	if err != nil {
+		return 0, err
+	}
+	defer b.Close()
+
+	n := edit(b)
+	if n == 0 {
+		return 0, nil
+	}
+	if err := b.Save(); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// FindOpenBuffer returns the open buffer editing the file at absPath, or nil
+func FindOpenBuffer(absPath string) *Buffer {
+	for _, b := range OpenBuffers {
+		if b.AbsPath == absPath && b.Type.Kind == BTDefault.Kind {
+			return b
+		}
+	}
+	return nil
+}
+
+// ReplaceOnDisk replaces the matches of search that keep selects in the file
+// at path without loading it into a buffer, so that it can run off the main
+// goroutine. Use it for files that aren't open. Line endings are kept.
+// Returns the number of replacements made.
+func ReplaceOnDisk(path string, search *regexp.Regexp, replace []byte, captureGroups bool, keep func(line, col int, text string) bool) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	found := 0
+	lines := bytes.Split(data, []byte{'\n'})
+	for i, l := range lines {
+		cr := bytes.HasSuffix(l, []byte{'\r'})
+		if cr {
+			l = l[:len(l)-1]
+		}
+		newLine, n := replaceLine(search, l, replace, captureGroups, func(col int, text string) bool {
+			return keep(i+1, col, text)
+		})
+		if n == 0 {
+			continue
+		}
+		found += n
+		if cr {
+			newLine = append(newLine, '\r')
+		}
+		lines[i] = newLine
+	}
+	if found == 0 {
+		return 0, nil
+	}
+	if err := util.SafeWrite(path, bytes.Join(lines, []byte{'\n'}), false); err != nil {
+		return 0, err
+	}
+	return found, nil
+}
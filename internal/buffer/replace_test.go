@@ -0,0 +1,160 @@
+package buffer
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/ellery/thicc/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// keepAll replaces every match
+func keepAll(line, col int, text string) bool { return true }
+
+// tempConfigDir points the config dir, where saves keep their backups, at a
+// temporary directory for the test
+func tempConfigDir(t *testing.T) {
+	old := config.ConfigDir
+	config.ConfigDir = t.TempDir()
+	t.Cleanup(func() { config.ConfigDir = old })
+}
+
+// =============================================================================
+// Buffer Replace Tests
+// =============================================================================
+
+func TestReplaceMatches_CaptureGroups(t *testing.T) {
+	b := NewBufferFromString("getName(user)\nx := getID(id, getName(v))", "", BTDefault)
+	defer b.Close()
+
+	n := b.ReplaceMatches(regexp.MustCompile(`get(\w+)\((\w+)`), []byte("$2.$1("), true, keepAll)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, "user.Name()\nx := id.ID(, v.Name())", string(b.Bytes()))
+
+	b.Undo()
+	assert.Equal(t, "getName(user)\nx := getID(id, getName(v))", string(b.Bytes()), "one undo reverts every line")
+}
+
+func TestReplaceMatches_Literal(t *testing.T) {
+	b := NewBufferFromString("cost: $1", "", BTDefault)
+	defer b.Close()
+
+	n := b.ReplaceMatches(regexp.MustCompile(`cost`), []byte("$price"), false, keepAll)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, "$price: $1", string(b.Bytes()), "$ isn't expanded without capture groups")
+}
+
+func TestReplaceMatches_OnlyKeptMatches(t *testing.T) {
+	b := NewBufferFromString("ä foo foo\nfoo", "", BTDefault)
+	defer b.Close()
+
+	var seen [][2]int
+	n := b.ReplaceMatches(regexp.MustCompile(`fo+`), []byte("bar"), false, func(line, col int, text string) bool {
+		seen = append(seen, [2]int{line, col})
+		assert.Equal(t, "foo", text, "the matched text is passed along")
+		return line == 1 && col == 7
+	})
+	assert.Equal(t, 1, n)
+	assert.Equal(t, [][2]int{{1, 3}, {1, 7}, {2, 1}}, seen, "columns count characters from 1")
+	assert.Equal(t, "ä foo bar\nfoo", string(b.Bytes()))
+}
+
+func TestReplaceMatches_NoMatches(t *testing.T) {
+	b := NewBufferFromString("text", "", BTDefault)
+	defer b.Close()
+
+	assert.Equal(t, 0, b.ReplaceMatches(regexp.MustCompile(`z*`), []byte("y"), false, keepAll), "empty matches are skipped")
+	assert.False(t, b.Modified())
+	assert.False(t, b.Undo(), "nothing to undo")
+}
+
+// =============================================================================
+// File Replace Tests
+// =============================================================================
+
+func TestReplaceInFile_ClosedFile(t *testing.T) {
+	tempConfigDir(t)
+	path := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("old := old\n"), 0644))
+	openBefore := len(OpenBuffers)
+
+	n, err := ReplaceInFile(path, regexp.MustCompile(`old`), []byte("new"), false, keepAll)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "new := new\n", string(data))
+	assert.Len(t, OpenBuffers, openBefore, "the buffer used for the write is closed again")
+}
+
+func TestReplaceInFile_OpenBuffer(t *testing.T) {
+	tempConfigDir(t)
+	path := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("old\n"), 0644))
+	b, err := NewBufferFromFile(path, BTDefault)
+	require.NoError(t, err)
+	defer b.Close()
+
+	n, err := ReplaceInFile(path, regexp.MustCompile(`old`), []byte("new"), false, keepAll)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, "new\n", string(b.Bytes()), "the open buffer is changed")
+	data, _ := os.ReadFile(path)
+	assert.Equal(t, "new\n", string(data), "and saved")
+
+	b.Undo()
+	assert.Equal(t, "old\n", string(b.Bytes()), "the replace can be undone")
+}
+
+func TestReplaceInFile_KeepsUnsavedChanges(t *testing.T) {
+	tempConfigDir(t)
+	path := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("old\n"), 0644))
+	b, err := NewBufferFromFile(path, BTDefault)
+	require.NoError(t, err)
+	defer b.Close()
+	b.Insert(Loc{0, 1}, "old")
+
+	n, err := ReplaceInFile(path, regexp.MustCompile(`old`), []byte("new"), false, keepAll)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, "new\nnew", string(b.Bytes()))
+	assert.True(t, b.Modified(), "a buffer with unsaved changes isn't saved")
+	data, _ := os.ReadFile(path)
+	assert.Equal(t, "old\n", string(data))
+}
+
+func TestReplaceOnDisk(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("old := old\r\nfoo(old)\r\n"), 0644))
+	openBefore := len(OpenBuffers)
+
+	n, err := ReplaceOnDisk(path, regexp.MustCompile(`old`), []byte("new"), false, func(line, col int, text string) bool {
+		return !(line == 1 && col == 1)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "old := new\r\nfoo(new)\r\n", string(data), "line endings are kept")
+	assert.Len(t, OpenBuffers, openBefore, "no buffer is opened")
+}
+
+func TestReplaceOnDisk_NoMatches(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("text\n"), 0644))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+
+	n, err := ReplaceOnDisk(path, regexp.MustCompile(`old`), []byte("new"), false, keepAll)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+	after, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, info.ModTime(), after.ModTime(), "the file isn't rewritten")
+}
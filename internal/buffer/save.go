@@ -346,6 +346,10 @@ func (b *Buffer) saveToFile(filename string, withSudo bool, autoSave bool) error
 		b.ReloadSettings(true)
 	}
 
+	for _, h := range hooks {
+		h.BufferSaved(b)
+	}
+
 	err = b.Serialize()
 	return err
 }
@@ -3,6 +3,7 @@ package display
 import (
 	"strconv"
 	"strings"
+	"time"
 
 	runewidth "github.com/mattn/go-runewidth"
 	"github.com/micro-editor/tcell/v2"
@@ -17,6 +18,9 @@ var BinaryFileStyle = tcell.StyleDefault.
 	Background(tcell.GetColor("#1a0a1a")). // Deep magenta/purple
 	Foreground(tcell.Color205)              // Hot pink text
 
+// blameGutterWidth is the width of the git blame gutter: " author       age "
+const blameGutterWidth = 20
+
 // The BufWindow provides a way of displaying a certain section of a buffer.
 type BufWindow struct {
 	*View
@@ -159,6 +163,9 @@ func (w *BufWindow) updateDisplayInfo() {
 	if b.Settings["diffgutter"].(bool) {
 		w.gutterOffset++
 	}
+	if b.HasBlame() {
+		w.gutterOffset += blameGutterWidth
+	}
 	if b.Settings["ruler"].(bool) {
 		w.gutterOffset += w.maxLineNumLength + 1
 	}
@@ -389,6 +396,42 @@ func (w *BufWindow) drawUnifiedDiffGutter(backgroundStyle tcell.Style, softwrapp
 	vloc.X++
 }
 
+// drawBlameGutter draws the author and age of the commit that last changed the line
+func (w *BufWindow) drawBlameGutter(backgroundStyle tcell.Style, softwrapped bool, vloc *buffer.Loc, bloc *buffer.Loc) {
+	text := ""
+	if line := w.Buf.Blame(bloc.Y); line != nil && !softwrapped {
+		author, age := "Uncommitted", ""
+		if line.Committed() {
+			author, age = line.Author, buffer.BlameAge(line.Time, time.Now())
+		}
+		// Truncate the author to fit, leaving room for the age
+		authorWidth := blameGutterWidth - 8
+		if runewidth.StringWidth(author) > authorWidth {
+			author = runewidth.Truncate(author, authorWidth, "…")
+		}
+		text = " " + runewidth.FillRight(author, authorWidth) + " " + runewidth.FillLeft(age, 5)
+	}
+
+	style := backgroundStyle
+	if s, ok := config.Colorscheme["comment"]; ok {
+		foreground, _, _ := s.Decompose()
+		style = style.Foreground(foreground)
+	}
+
+	start := vloc.X
+	for _, r := range text {
+		if vloc.X >= w.gutterOffset {
+			return
+		}
+		screen.SetContent(w.X+vloc.X, w.Y+vloc.Y, r, nil, style)
+		vloc.X += runewidth.RuneWidth(r)
+	}
+	for vloc.X < start+blameGutterWidth && vloc.X < w.gutterOffset {
+		screen.SetContent(w.X+vloc.X, w.Y+vloc.Y, ' ', nil, style)
+		vloc.X++
+	}
+}
+
 func (w *BufWindow) drawLineNum(lineNumStyle tcell.Style, softwrapped bool, vloc *buffer.Loc, bloc *buffer.Loc) {
 	cursorLine := w.Buf.GetActiveCursor().Loc.Y
 	var lineInt int
@@ -455,6 +498,7 @@ func (w *BufWindow) displayBuffer() {
 		if b.Settings["diffgutter"].(bool) {
 			b.UpdateDiff()
 		}
+		b.UpdateBlame()
 		b.ModifiedThisFrame = false
 	}
 
@@ -595,6 +639,10 @@ func (w *BufWindow) displayBuffer() {
 				w.drawUnifiedDiffGutter(gutterStyle, false, &vloc, &bloc)
 			}
 
+			if b.HasBlame() {
+				w.drawBlameGutter(gutterStyle, false, &vloc, &bloc)
+			}
+
 			if b.Settings["ruler"].(bool) {
 				w.drawLineNum(gutterStyle, false, &vloc, &bloc)
 			}
@@ -821,6 +869,10 @@ func (w *BufWindow) displayBuffer() {
 					w.drawUnifiedDiffGutter(gutterStyle, true, &vloc, &bloc)
 				}
 
+				if b.HasBlame() {
+					w.drawBlameGutter(gutterStyle, true, &vloc, &bloc)
+				}
+
 				// This will draw an empty line number because the current line is wrapped
 				if b.Settings["ruler"].(bool) {
 					w.drawLineNum(gutterStyle, true, &vloc, &bloc)
@@ -47,6 +47,8 @@ func NewPanel(x, y, w, h int, root string) *Panel {
 		if err != nil {
 			log.Printf("THICC FileBrowser: Refresh failed: %v", err)
 		} else {
+			// Read the git status now rather than on the first draw
+			p.Tree.LoadGitStatus()
 			atomic.StoreInt32(&p.ready, 1)
 			log.Printf("THICC FileBrowser: Tree refresh complete, loaded %d nodes", len(p.Tree.GetNodes()))
 
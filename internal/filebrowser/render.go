@@ -180,9 +180,22 @@ func (p *Panel) renderNode(screen tcell.Screen, y int, node *filemanager.TreeNod
 		name += "/"
 	}
 
+	// Git status icon at the right edge; folders get a dot when anything
+	// below them changed
+	_, gitIcon := p.Tree.GetGitStatus(node.Path)
+	gitWidth := 0
+	if gitIcon != "" {
+		gitWidth = 2
+		gitStyle := GetGitStatusStyle(gitIcon)
+		if isSelected {
+			gitStyle = selStyle
+		}
+		p.drawText(screen, p.Region.Width-4, y, gitIcon, gitStyle)
+	}
+
 	// Truncate if needed
-	maxWidth := p.Region.Width - x - 2
-	if maxWidth > 0 && len(name) > maxWidth {
+	maxWidth := p.Region.Width - x - 2 - gitWidth
+	if maxWidth > 3 && len(name) > maxWidth {
 		name = name[:maxWidth-3] + "..."
 	}
 
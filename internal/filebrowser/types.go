@@ -247,6 +247,8 @@ const (
 	gitIconDeleted   = "\uf00d" // X mark
 	gitIconRenamed   = "\uf061" // Arrow
 	gitIconConflict  = "\uf071" // Warning
+
+	gitIconContainsChanges = "\uf111" // Dot
 )
 
 // GetGitStatusStyle returns the style for a git status icon
@@ -264,6 +266,8 @@ func GetGitStatusStyle(icon string) tcell.Style {
 		return config.DefStyle.Foreground(gitStagedColor)
 	case gitIconConflict:
 		return config.DefStyle.Foreground(gitConflictColor)
+	case gitIconContainsChanges:
+		return config.DefStyle.Foreground(gitModifiedColor)
 	default:
 		return config.DefStyle
 	}
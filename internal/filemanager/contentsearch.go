@@ -0,0 +1,387 @@
+package filemanager
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// DefaultMaxContentMatches caps how many matches a content search returns
+const DefaultMaxContentMatches = 2000
+
+// maxSearchFileSize is the largest file the built-in search reads
+const maxSearchFileSize = 4 * 1024 * 1024
+
+// maxPreviewBytes is how much of a long line is kept around a match
+const maxPreviewBytes = 300
+
+// ContentSearchOptions describes a search of file contents
+type ContentSearchOptions struct {
+	Query         string
+	Regex         bool   // Query is a regular expression rather than literal text
+	CaseSensitive bool   // Match case exactly
+	WholeWord     bool   // Only match whole words
+	MaxMatches    int    // Stop after this many matches (0 = DefaultMaxContentMatches)
+	Ripgrep       string // Path to rg to search with, or "" for the built-in search
+}
+
+// ContentMatch is one match within a file
+type ContentMatch struct {
+	Line   int    // 1-based line number
+	Column int    // 1-based column (in characters) where the match starts
+	Text   string // The line, cut down around the match if it is long
+	Start  int    // Byte offset of the match in Text
+	End    int    // Byte offset just past the match in Text
+}
+
+// ContentFileMatches holds the matches in one file
+type ContentFileMatches struct {
+	Path    string
+	RelPath string
+	Matches []ContentMatch
+}
+
+// RipgrepPath returns the path of rg if it is installed, or ""
+func RipgrepPath() string {
+	path, err := exec.LookPath("rg")
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// CompileContentQuery turns the search options into a regular expression
+func CompileContentQuery(opts ContentSearchOptions) (*regexp.Regexp, error) {
+	if opts.Query == "" {
+		return nil, errors.New("empty query")
+	}
+	pattern := opts.Query
+	if !opts.Regex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if opts.WholeWord {
+		pattern = `\b(?:` + pattern + `)\b`
+	}
+	if !opts.CaseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// SearchContent searches the files below root for opts.Query, calling onFile
+// with each file's matches as soon as the file is done. Directories in
+// SkipDirs and paths ignored by git are left out. Returns true if the search
+// dropped matches past opts.MaxMatches.
+func SearchContent(ctx context.Context, root string, opts ContentSearchOptions, onFile func(ContentFileMatches)) (bool, error) {
+	if opts.MaxMatches <= 0 {
+		opts.MaxMatches = DefaultMaxContentMatches
+	}
+	if opts.Ripgrep != "" {
+		return searchWithRipgrep(ctx, root, opts, onFile)
+	}
+
+	re, err := CompileContentQuery(opts)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Ignore rules are relative to the repository, which may be above root
+	repoRoot := findGitRepo(root, true)
+	var ignores *ignoreMatcher
+	if repoRoot != "" {
+		ignores = newIgnoreMatcher(repoRoot)
+	}
+
+	var total int64
+	var truncated int32
+	var mu sync.Mutex // Serializes onFile
+	files := make(chan string, 64)
+
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range files {
+				if ctx.Err() != nil {
+					continue // Drain
+				}
+				matches := searchFile(path, re)
+				if len(matches) == 0 {
+					continue
+				}
+				// Keep within the limit. Reaching it exactly isn't truncation:
+				// only a match past it means something was dropped.
+				n := atomic.AddInt64(&total, int64(len(matches)))
+				if over := n - int64(opts.MaxMatches); over > 0 {
+					matches = matches[:len(matches)-int(min(over, int64(len(matches))))]
+					atomic.StoreInt32(&truncated, 1)
+					cancel()
+					if len(matches) == 0 {
+						continue
+					}
+				}
+				rel, _ := filepath.Rel(root, path)
+				mu.Lock()
+				onFile(ContentFileMatches{Path: path, RelPath: rel, Matches: matches})
+				mu.Unlock()
+			}
+		}()
+	}
+
+	walkErr := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return filepath.SkipAll
+		}
+		if err != nil {
+			return nil // Skip what we can't read
+		}
+		if path == root {
+			return nil
+		}
+		if d.IsDir() && SkipDirs[d.Name()] {
+			return filepath.SkipDir
+		}
+		if ignores != nil {
+			if rel, err := filepath.Rel(repoRoot, path); err == nil && ignores.Ignored(filepath.ToSlash(rel), d.IsDir()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+		if d.Type().IsRegular() {
+			files <- path
+		}
+		return nil
+	})
+	close(files)
+	wg.Wait()
+
+	if walkErr != nil {
+		return false, walkErr
+	}
+	log.Printf("THICC Search: %q matched %d times under %s", opts.Query, min(total, int64(opts.MaxMatches)), root)
+	return atomic.LoadInt32(&truncated) == 1, nil
+}
+
+// searchFile returns every match of re in a text file. Large and binary
+// files are skipped.
+func searchFile(path string, re *regexp.Regexp) []ContentMatch {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > maxSearchFileSize {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || isBinary(data) {
+		return nil
+	}
+
+	var matches []ContentMatch
+	lineNum := 0
+	for len(data) > 0 {
+		lineNum++
+		var line []byte
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line, data = data[:i], data[i+1:]
+		} else {
+			line, data = data, nil
+		}
+		line = bytes.TrimSuffix(line, []byte("\r"))
+
+		for _, loc := range re.FindAllIndex(line, -1) {
+			if loc[0] == loc[1] {
+				continue // Empty matches aren't useful results
+			}
+			matches = append(matches, NewContentMatch(lineNum, string(line), loc[0], loc[1]))
+		}
+	}
+	return matches
+}
+
+// isBinary guesses whether data is binary, the way git does: a NUL byte in
+// the first 8000 bytes
+func isBinary(data []byte) bool {
+	if len(data) > 8000 {
+		data = data[:8000]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+// NewContentMatch builds a match of the bytes from start to end of line,
+// cutting long lines down to a window around the match
+func NewContentMatch(lineNum int, line string, start, end int) ContentMatch {
+	m := ContentMatch{
+		Line:   lineNum,
+		Column: utf8.RuneCountInString(line[:start]) + 1,
+		Text:   line,
+		Start:  start,
+		End:    end,
+	}
+	if len(line) <= maxPreviewBytes {
+		return m
+	}
+
+	from := start - maxPreviewBytes/3
+	if from < 0 {
+		from = 0
+	}
+	to := from + maxPreviewBytes
+	if to < end {
+		to = end
+	}
+	if to > len(line) {
+		to = len(line)
+	}
+	// Don't cut characters in half
+	for from > 0 && !utf8.RuneStart(line[from]) {
+		from--
+	}
+	for to < len(line) && !utf8.RuneStart(line[to]) {
+		to++
+	}
+	m.Text = line[from:to]
+	m.Start -= from
+	m.End -= from
+	return m
+}
+
+// ripgrepArgs returns the rg arguments for a search
+func ripgrepArgs(opts ContentSearchOptions) []string {
+	args := []string{"--json", "--no-messages", "--hidden"}
+	if !opts.Regex {
+		args = append(args, "--fixed-strings")
+	}
+	if opts.CaseSensitive {
+		args = append(args, "--case-sensitive")
+	} else {
+		args = append(args, "--ignore-case")
+	}
+	if opts.WholeWord {
+		args = append(args, "--word-regexp")
+	}
+	for dir := range SkipDirs {
+		args = append(args, "--glob", "!"+dir+"/")
+	}
+	return append(args, "--regexp", opts.Query, "--", ".")
+}
+
+// ripgrepEvent is the part of an `rg --json` line we read
+type ripgrepEvent struct {
+	Type string `json:"type"`
+	Data struct {
+		Path struct {
+			Text string `json:"text"`
+		} `json:"path"`
+		Lines struct {
+			Text string `json:"text"`
+		} `json:"lines"`
+		LineNumber int `json:"line_number"`
+		Submatches []struct {
+			Start int `json:"start"`
+			End   int `json:"end"`
+		} `json:"submatches"`
+	} `json:"data"`
+}
+
+// searchWithRipgrep runs the search with rg, which respects .gitignore itself
+func searchWithRipgrep(ctx context.Context, root string, opts ContentSearchOptions, onFile func(ContentFileMatches)) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, opts.Ripgrep, ripgrepArgs(opts)...)
+	cmd.Dir = root
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return false, err
+	}
+	if err := cmd.Start(); err != nil {
+		return false, fmt.Errorf("rg failed: %w", err)
+	}
+
+	truncated := parseRipgrepOutput(stdout, root, opts.MaxMatches, onFile)
+	if truncated {
+		cancel()
+	}
+	err = cmd.Wait()
+	if truncated || ctx.Err() != nil {
+		return truncated, nil
+	}
+	// Exit code 1 means no matches
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("rg failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return false, nil
+}
+
+// parseRipgrepOutput reads `rg --json` output, passing each file's matches
+// to onFile. Returns true if it stopped at maxMatches.
+func parseRipgrepOutput(r io.Reader, root string, maxMatches int, onFile func(ContentFileMatches)) bool {
+	var current *ContentFileMatches
+	total := 0
+	flush := func() {
+		if current != nil && len(current.Matches) > 0 {
+			onFile(*current)
+		}
+		current = nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var ev ripgrepEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			continue
+		}
+		switch ev.Type {
+		case "begin":
+			flush()
+			rel := filepath.Clean(ev.Data.Path.Text)
+			current = &ContentFileMatches{Path: filepath.Join(root, rel), RelPath: rel}
+		case "match":
+			if current == nil {
+				continue
+			}
+			line := strings.TrimRight(ev.Data.Lines.Text, "\r\n")
+			for _, sub := range ev.Data.Submatches {
+				if sub.End > len(line) || sub.Start == sub.End {
+					continue
+				}
+				if total == maxMatches {
+					flush()
+					return true
+				}
+				current.Matches = append(current.Matches, NewContentMatch(ev.Data.LineNumber, line, sub.Start, sub.End))
+				total++
+			}
+		case "end":
+			flush()
+		}
+	}
+	flush()
+	return false
+}
@@ -0,0 +1,163 @@
+package filemanager
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// searchAll runs a content search and returns the matches by relative path
+func searchAll(t *testing.T, root string, opts ContentSearchOptions) (map[string][]ContentMatch, bool) {
+	t.Helper()
+	results := make(map[string][]ContentMatch)
+	truncated, err := SearchContent(context.Background(), root, opts, func(f ContentFileMatches) {
+		results[filepath.ToSlash(f.RelPath)] = f.Matches
+	})
+	require.NoError(t, err)
+	return results, truncated
+}
+
+// =============================================================================
+// Query Tests
+// =============================================================================
+
+func TestCompileContentQuery(t *testing.T) {
+	re, err := CompileContentQuery(ContentSearchOptions{Query: "a.b"})
+	require.NoError(t, err)
+	assert.True(t, re.MatchString("A.B"), "case-insensitive by default")
+	assert.False(t, re.MatchString("axb"), "literal unless regex")
+
+	re, err = CompileContentQuery(ContentSearchOptions{Query: "a.b", Regex: true, CaseSensitive: true})
+	require.NoError(t, err)
+	assert.True(t, re.MatchString("axb"))
+	assert.False(t, re.MatchString("AXB"))
+
+	re, err = CompileContentQuery(ContentSearchOptions{Query: "id|name", Regex: true, WholeWord: true})
+	require.NoError(t, err)
+	assert.True(t, re.MatchString("user name"))
+	assert.False(t, re.MatchString("userid"))
+
+	_, err = CompileContentQuery(ContentSearchOptions{Query: "(", Regex: true})
+	assert.Error(t, err)
+	_, err = CompileContentQuery(ContentSearchOptions{})
+	assert.Error(t, err)
+}
+
+func TestNewContentMatch_LongLine(t *testing.T) {
+	line := strings.Repeat("x", 1000) + "needle" + strings.Repeat("y", 1000)
+	m := NewContentMatch(3, line, 1000, 1006)
+	assert.Equal(t, 1001, m.Column)
+	assert.LessOrEqual(t, len(m.Text), maxPreviewBytes)
+	assert.Equal(t, "needle", m.Text[m.Start:m.End])
+
+	m = NewContentMatch(1, "héllo wörld", 7, 12)
+	assert.Equal(t, 7, m.Column, "columns count characters")
+}
+
+// =============================================================================
+// Search Tests
+// =============================================================================
+
+func TestSearchContent(t *testing.T) {
+	dir := createTestRepo(t)
+	writeTestFile(t, dir, ".gitignore", "*.gen.go\n")
+	writeTestFile(t, dir, "main.go", "package main\n\nfunc Handler() {}\n// handler docs\r\n")
+	writeTestFile(t, dir, "src/app.go", "var handlers = Handler\n")
+	writeTestFile(t, dir, "src/api.gen.go", "func Handler() {}\n")
+	writeTestFile(t, dir, "node_modules/lib/index.js", "Handler\n")
+	writeTestFile(t, dir, "image.bin", "Handler\x00\x01")
+
+	results, truncated := searchAll(t, dir, ContentSearchOptions{Query: "handler"})
+	assert.False(t, truncated)
+	assert.Equal(t, []string{"main.go", "src/app.go"}, sortedKeys(results), "skipped, ignored and binary files are left out")
+
+	main := results["main.go"]
+	require.Len(t, main, 2)
+	assert.Equal(t, ContentMatch{Line: 3, Column: 6, Text: "func Handler() {}", Start: 5, End: 12}, main[0])
+	assert.Equal(t, "// handler docs", main[1].Text, "CRLF line endings are trimmed")
+	assert.Len(t, results["src/app.go"], 2, "every match on a line")
+
+	results, _ = searchAll(t, dir, ContentSearchOptions{Query: "Handler", CaseSensitive: true, WholeWord: true})
+	assert.Len(t, results["main.go"], 1)
+	assert.Len(t, results["src/app.go"], 1)
+}
+
+func TestSearchContent_StopsAtMaxMatches(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 5; i++ {
+		writeTestFile(t, dir, string(rune('a'+i))+".txt", "match\nmatch\n")
+	}
+
+	results, truncated := searchAll(t, dir, ContentSearchOptions{Query: "match", MaxMatches: 3})
+	assert.True(t, truncated)
+	total := 0
+	for _, matches := range results {
+		total += len(matches)
+	}
+	assert.Equal(t, 3, total)
+}
+
+func TestSearchContent_ExactlyMaxMatchesIsNotTruncated(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "a.txt", "match\nmatch\n")
+	writeTestFile(t, dir, "b.txt", "match\n")
+
+	results, truncated := searchAll(t, dir, ContentSearchOptions{Query: "match", MaxMatches: 3})
+	assert.False(t, truncated)
+	assert.Len(t, results["a.txt"], 2)
+	assert.Len(t, results["b.txt"], 1)
+}
+
+func TestParseRipgrepOutput(t *testing.T) {
+	output := `{"type":"begin","data":{"path":{"text":"./src/app.go"}}}
+{"type":"match","data":{"path":{"text":"./src/app.go"},"lines":{"text":"var a = Handler(Handler)\n"},"line_number":7,"submatches":[{"match":{"text":"Handler"},"start":8,"end":15},{"match":{"text":"Handler"},"start":16,"end":23}]}}
+{"type":"end","data":{"path":{"text":"./src/app.go"}}}
+{"type":"begin","data":{"path":{"text":"./main.go"}}}
+{"type":"match","data":{"path":{"text":"./main.go"},"lines":{"text":"Handler\n"},"line_number":1,"submatches":[{"match":{"text":"Handler"},"start":0,"end":7}]}}
+{"type":"end","data":{"path":{"text":"./main.go"}}}
+{"type":"summary","data":{}}
+`
+	var files []ContentFileMatches
+	truncated := parseRipgrepOutput(strings.NewReader(output), "/repo", 10, func(f ContentFileMatches) {
+		files = append(files, f)
+	})
+	assert.False(t, truncated)
+	require.Len(t, files, 2)
+	assert.Equal(t, filepath.Join("/repo", "src", "app.go"), files[0].Path)
+	assert.Equal(t, filepath.Join("src", "app.go"), files[0].RelPath)
+	assert.Equal(t, []ContentMatch{
+		{Line: 7, Column: 9, Text: "var a = Handler(Handler)", Start: 8, End: 15},
+		{Line: 7, Column: 17, Text: "var a = Handler(Handler)", Start: 16, End: 23},
+	}, files[0].Matches)
+
+	files = nil
+	truncated = parseRipgrepOutput(strings.NewReader(output), "/repo", 1, func(f ContentFileMatches) {
+		files = append(files, f)
+	})
+	assert.True(t, truncated)
+	require.Len(t, files, 1)
+	assert.Len(t, files[0].Matches, 1)
+
+	// Exactly at the limit
+	files = nil
+	truncated = parseRipgrepOutput(strings.NewReader(output), "/repo", 3, func(f ContentFileMatches) {
+		files = append(files, f)
+	})
+	assert.False(t, truncated)
+	assert.Len(t, files, 2)
+}
+
+// sortedKeys returns the keys of a result map in order
+func sortedKeys(m map[string][]ContentMatch) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,10 +1,9 @@
 package filemanager
 
 import (
+	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"strings"
 	"sync"
 )
 
@@ -16,6 +15,8 @@ const (
 	GitIconDeleted   = "\uf00d" // X mark - deleted
 	GitIconRenamed   = "\uf061" // Arrow - renamed
 	GitIconConflict  = "\uf071" // Warning - conflict
+
+	GitIconContainsChanges = "\uf111" // Dot - folder contains changes
 )
 
 // GitStatus represents the git status of a file
@@ -29,117 +30,93 @@ const (
 	GitStatusDeleted
 	GitStatusRenamed
 	GitStatusConflict
+	GitStatusContainsChanges // Directory with changed files below it
 )
 
-// gitCache caches git status for directories
+// gitCache caches repository roots and status snapshots
 type gitCache struct {
-	mu         sync.RWMutex
-	repoRoots  map[string]string      // dir -> repo root
-	ignoredMap map[string]bool        // path -> is ignored
-	gitDirs    map[string]bool        // dir -> has .git
-	statusMap  map[string]GitStatus   // path -> git status
+	mu        sync.RWMutex
+	repoRoots map[string]string       // dir -> repo root
+	gitDirs   map[string]bool         // dir -> has .git
+	snapshots map[string]*gitSnapshot // repo root -> status snapshot
 }
 
 var cache = &gitCache{
-	repoRoots:  make(map[string]string),
-	ignoredMap: make(map[string]bool),
-	gitDirs:    make(map[string]bool),
-	statusMap:  make(map[string]GitStatus),
+	repoRoots: make(map[string]string),
+	gitDirs:   make(map[string]bool),
+	snapshots: make(map[string]*gitSnapshot),
 }
 
-// GetGitStatus returns the git status of a file and the corresponding icon
+// GetGitStatus returns the git status of a file and the corresponding icon.
+// Directories report GitStatusContainsChanges when anything below them
+// changed.
 func (t *Tree) GetGitStatus(path string) (GitStatus, string) {
-	// Check cache first
-	cache.mu.RLock()
-	if status, ok := cache.statusMap[path]; ok {
-		cache.mu.RUnlock()
-		return status, gitStatusIcon(status)
-	}
-	cache.mu.RUnlock()
-
-	// Find git repo root
-	repoRoot := t.findGitRepo(path)
-	if repoRoot == "" {
-		cache.mu.Lock()
-		cache.statusMap[path] = GitStatusNone
-		cache.mu.Unlock()
+	snapshot, rel, isDir := t.gitSnapshotFor(path)
+	if snapshot == nil {
 		return GitStatusNone, ""
 	}
 
-	// Get relative path from repo root
-	relPath, err := filepath.Rel(repoRoot, path)
-	if err != nil {
-		return GitStatusNone, ""
+	status := snapshot.Status(rel)
+	if status == GitStatusNone && isDir && snapshot.HasChanges(rel) {
+		status = GitStatusContainsChanges
 	}
-
-	// Run git status for this file
-	status := t.checkGitStatus(repoRoot, relPath)
-
-	// Cache result
-	cache.mu.Lock()
-	cache.statusMap[path] = status
-	cache.mu.Unlock()
-
 	return status, gitStatusIcon(status)
 }
 
-// checkGitStatus runs git status for a specific file
-func (t *Tree) checkGitStatus(repoRoot, relPath string) GitStatus {
-	cmd := exec.Command("git", "status", "--porcelain", "--", relPath)
-	cmd.Dir = repoRoot
-
-	output, err := cmd.Output()
-	if err != nil {
-		return GitStatusNone
-	}
-
-	line := strings.TrimSpace(string(output))
-	if line == "" {
-		return GitStatusNone
+// gitSnapshotFor returns the status snapshot of the repository path is in,
+// the path relative to it and whether path is a directory. Returns a nil
+// snapshot outside a repository.
+func (t *Tree) gitSnapshotFor(path string) (*gitSnapshot, string, bool) {
+	isDir := t.pathIsDir(path)
+	repoRoot := findGitRepo(path, isDir)
+	if repoRoot == "" {
+		return nil, "", isDir
 	}
-
-	// Parse porcelain format: XY filename
-	// X = index status, Y = working tree status
-	if len(line) < 2 {
-		return GitStatusNone
+	rel, err := filepath.Rel(repoRoot, path)
+	if err != nil {
+		return nil, "", isDir
 	}
-
-	indexStatus := line[0]
-	workTreeStatus := line[1]
-
-	// Check for conflicts first
-	if indexStatus == 'U' || workTreeStatus == 'U' ||
-		(indexStatus == 'A' && workTreeStatus == 'A') ||
-		(indexStatus == 'D' && workTreeStatus == 'D') {
-		return GitStatusConflict
+	rel = filepath.ToSlash(rel)
+	if rel == "." {
+		rel = ""
 	}
+	return snapshotFor(repoRoot), rel, isDir
+}
 
-	// Check for untracked
-	if indexStatus == '?' && workTreeStatus == '?' {
-		return GitStatusUntracked
+// pathIsDir checks the tree's own nodes before asking the file system
+func (t *Tree) pathIsDir(path string) bool {
+	t.mu.RLock()
+	node, ok := t.Index[path]
+	t.mu.RUnlock()
+	if ok {
+		return node.IsDir
 	}
+	return isDir(path)
+}
 
-	// Check for staged changes (index has changes)
-	if indexStatus == 'A' || indexStatus == 'M' || indexStatus == 'D' || indexStatus == 'R' {
-		if indexStatus == 'R' {
-			return GitStatusRenamed
-		}
-		if indexStatus == 'D' {
-			return GitStatusDeleted
-		}
-		return GitStatusStaged
+// snapshotFor returns the status snapshot of a repository, reading it the
+// first time it is asked for. Returns nil if git status fails.
+func snapshotFor(repoRoot string) *gitSnapshot {
+	cache.mu.RLock()
+	snapshot, ok := cache.snapshots[repoRoot]
+	cache.mu.RUnlock()
+	if ok {
+		return snapshot
 	}
 
-	// Check for modified in working tree
-	if workTreeStatus == 'M' {
-		return GitStatusModified
+	snapshot, err := loadGitSnapshot(repoRoot)
+	if err != nil {
+		log.Printf("THICC Git: Reading status for %s failed: %v", repoRoot, err)
 	}
 
-	if workTreeStatus == 'D' {
-		return GitStatusDeleted
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	// Another caller may have loaded it meanwhile
+	if existing, ok := cache.snapshots[repoRoot]; ok {
+		return existing
 	}
-
-	return GitStatusNone
+	cache.snapshots[repoRoot] = snapshot
+	return snapshot
 }
 
 // gitStatusIcon returns the Nerd Font icon for a git status
@@ -157,53 +134,99 @@ func gitStatusIcon(status GitStatus) string {
 		return GitIconRenamed
 	case GitStatusConflict:
 		return GitIconConflict
+	case GitStatusContainsChanges:
+		return GitIconContainsChanges
 	default:
 		return ""
 	}
 }
 
-// RefreshGitStatus clears the git status cache (call when files change)
+// LoadGitStatus reads the status of the tree's repository if it hasn't been
+// read yet, so the first lookup doesn't wait on git
+func (t *Tree) LoadGitStatus() {
+	if repoRoot := findGitRepo(t.Root, true); repoRoot != "" {
+		snapshotFor(repoRoot)
+	}
+}
+
+// RefreshGitStatus drops the status snapshots so they are reread on next
+// use (call when files change outside the watcher's view)
 func (t *Tree) RefreshGitStatus() {
 	cache.mu.Lock()
-	cache.statusMap = make(map[string]GitStatus)
+	cache.snapshots = make(map[string]*gitSnapshot)
 	cache.mu.Unlock()
 }
 
-// IsGitIgnored checks if a path is gitignored
-func (t *Tree) IsGitIgnored(path string) bool {
-	// Check cache first
-	cache.mu.RLock()
-	if ignored, ok := cache.ignoredMap[path]; ok {
+// UpdateGitStatus refreshes the status of the given changed paths. A
+// changed .gitignore rereads the whole repository; anything else only asks
+// git about the changed paths.
+func (t *Tree) UpdateGitStatus(paths []string) {
+	byRepo := make(map[string][]string)
+	reload := make(map[string]bool)
+	for _, p := range paths {
+		repoRoot := findGitRepo(filepath.Dir(p), true)
+		if repoRoot == "" {
+			continue
+		}
+		rel, err := filepath.Rel(repoRoot, p)
+		if err != nil || rel == "." {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		if needsReload(rel) {
+			reload[repoRoot] = true
+		}
+		byRepo[repoRoot] = append(byRepo[repoRoot], rel)
+	}
+
+	for repoRoot, rels := range byRepo {
+		cache.mu.RLock()
+		snapshot := cache.snapshots[repoRoot]
 		cache.mu.RUnlock()
-		return ignored
+		if snapshot == nil {
+			continue // Not read yet, or git status failed
+		}
+
+		var err error
+		if reload[repoRoot] {
+			err = snapshot.Reload()
+		} else {
+			err = snapshot.Update(rels)
+		}
+		if err != nil {
+			log.Printf("THICC Git: Updating status for %s failed: %v", repoRoot, err)
+		}
 	}
+}
+
+// reloadGitStatus rereads the whole status of the tree's repository, for
+// changes to the index or HEAD
+func (t *Tree) reloadGitStatus() {
+	repoRoot := findGitRepo(t.Root, true)
+	cache.mu.RLock()
+	snapshot := cache.snapshots[repoRoot]
 	cache.mu.RUnlock()
+	if snapshot == nil {
+		return
+	}
+	if err := snapshot.Reload(); err != nil {
+		log.Printf("THICC Git: Reloading status for %s failed: %v", repoRoot, err)
+	}
+}
 
-	// Find git repo root
-	repoRoot := t.findGitRepo(path)
-	if repoRoot == "" {
-		// Not in a git repo
-		cache.mu.Lock()
-		cache.ignoredMap[path] = false
-		cache.mu.Unlock()
+// IsGitIgnored checks if a path is gitignored
+func (t *Tree) IsGitIgnored(path string) bool {
+	snapshot, rel, isDir := t.gitSnapshotFor(path)
+	if snapshot == nil || rel == "" {
 		return false
 	}
-
-	// Check with git check-ignore
-	ignored := t.checkGitIgnore(repoRoot, path)
-
-	// Cache result
-	cache.mu.Lock()
-	cache.ignoredMap[path] = ignored
-	cache.mu.Unlock()
-
-	return ignored
+	return snapshot.Ignored(rel, isDir)
 }
 
 // findGitRepo finds the git repository root for a path
-func (t *Tree) findGitRepo(path string) string {
+func findGitRepo(path string, pathIsDir bool) string {
 	dir := path
-	if !isDir(path) {
+	if !pathIsDir {
 		dir = filepath.Dir(path)
 	}
 
@@ -241,34 +264,14 @@ func (t *Tree) findGitRepo(path string) string {
 	}
 }
 
-// checkGitIgnore uses git check-ignore to determine if path is ignored
-func (t *Tree) checkGitIgnore(repoRoot, path string) bool {
-	// Run git check-ignore
-	cmd := exec.Command("git", "check-ignore", "-q", path)
-	cmd.Dir = repoRoot
-
-	err := cmd.Run()
-	if err != nil {
-		// Exit code 1 means not ignored
-		// Exit code 0 means ignored
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return exitErr.ExitCode() == 0
-		}
-		// Command failed, assume not ignored
-		return false
-	}
-
-	// Exit code 0 means ignored
-	return true
-}
-
-// ClearGitCache clears the git ignore cache
+// ClearGitCache forgets repository roots and status snapshots
 func (t *Tree) ClearGitCache() {
 	cache.mu.Lock()
 	defer cache.mu.Unlock()
 
-	cache.ignoredMap = make(map[string]bool)
 	cache.repoRoots = make(map[string]string)
+	cache.gitDirs = make(map[string]bool)
+	cache.snapshots = make(map[string]*gitSnapshot)
 }
 
 // Helper functions
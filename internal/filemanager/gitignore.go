@@ -0,0 +1,262 @@
+package filemanager
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ignorePattern is one line of a .gitignore file
+type ignorePattern struct {
+	segments []string // Pattern split on "/", "**" matches any number of segments
+	negate   bool     // "!pattern" re-includes a path
+	dirOnly  bool     // "pattern/" only matches directories
+	anchored bool     // Contains a slash, so it matches from the .gitignore's directory
+}
+
+// parseIgnorePattern parses a .gitignore line. Returns false for blank lines
+// and comments.
+func parseIgnorePattern(line string) (ignorePattern, bool) {
+	line = strings.TrimRight(line, "\r")
+	// Trailing spaces are dropped unless escaped
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignorePattern{}, false
+	}
+
+	var p ignorePattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return ignorePattern{}, false
+	}
+
+	p.anchored = strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	p.segments = strings.Split(line, "/")
+	return p, true
+}
+
+// match reports whether rel (slash separated, relative to the directory of
+// the .gitignore the pattern came from) matches the pattern
+func (p ignorePattern) match(rel string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+	if !p.anchored {
+		return matchSegment(p.segments[0], path.Base(rel))
+	}
+	return matchSegments(p.segments, strings.Split(rel, "/"))
+}
+
+// matchSegments matches pattern segments against path segments, with "**"
+// standing for zero or more whole segments
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			if len(rest) == 0 {
+				return len(parts) > 0 // "foo/**" matches inside foo, not foo itself
+			}
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(rest, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 || !matchSegment(pattern[0], parts[0]) {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+// matchSegment matches one glob segment ("*", "?", "[a-z]") against a name
+func matchSegment(pattern, name string) bool {
+	ok, err := path.Match(pattern, name)
+	return err == nil && ok
+}
+
+// ignoreFile holds the patterns of one .gitignore, relative to base
+type ignoreFile struct {
+	base     string // Directory the patterns are relative to ("" for the repo root)
+	patterns []ignorePattern
+}
+
+// readIgnoreFile reads patterns from a file, returning nil if it is missing
+func readIgnoreFile(filename, base string) *ignoreFile {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	file := &ignoreFile{base: base}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if p, ok := parseIgnorePattern(scanner.Text()); ok {
+			file.patterns = append(file.patterns, p)
+		}
+	}
+	return file
+}
+
+// ignoreMatcher answers "is this path ignored" for one repository without
+// running git. It reads the global excludes file, .git/info/exclude and
+// .gitignore files, loading nested ones the first time their directory is
+// asked about.
+type ignoreMatcher struct {
+	mu       sync.Mutex
+	root     string
+	base     []*ignoreFile          // Global excludes and info/exclude, lowest precedence first
+	dirs     map[string]*ignoreFile // Relative dir -> its .gitignore (nil if it has none)
+	verdicts map[verdictKey]bool    // Path asked about -> ignored
+}
+
+// verdictKey is a path asked about; the same name can match as a directory
+// but not as a file
+type verdictKey struct {
+	rel   string
+	isDir bool
+}
+
+// newIgnoreMatcher creates a matcher for the repository at root
+func newIgnoreMatcher(root string) *ignoreMatcher {
+	m := &ignoreMatcher{
+		root:     root,
+		dirs:     make(map[string]*ignoreFile),
+		verdicts: make(map[verdictKey]bool),
+	}
+	if f := readIgnoreFile(globalExcludesFile(root), ""); f != nil {
+		m.base = append(m.base, f)
+	}
+	if f := readIgnoreFile(infoExcludeFile(root), ""); f != nil {
+		m.base = append(m.base, f)
+	}
+	return m
+}
+
+// infoExcludeFile returns the path of the repository's info/exclude. In a
+// linked worktree .git is a file, and git reads the main repository's
+// info/exclude.
+func infoExcludeFile(root string) string {
+	cmd := exec.Command("git", "rev-parse", "--git-path", "info/exclude")
+	cmd.Dir = root
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	file := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(root, file)
+	}
+	return file
+}
+
+// globalExcludesFile returns the path of the user's core.excludesFile
+func globalExcludesFile(root string) string {
+	cmd := exec.Command("git", "config", "--path", "core.excludesFile")
+	cmd.Dir = root
+	if output, err := cmd.Output(); err == nil {
+		if file := strings.TrimSpace(string(output)); file != "" {
+			return file
+		}
+	}
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "git", "ignore")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "git", "ignore")
+}
+
+// dirFile returns the .gitignore of a relative directory (must be called
+// with lock held)
+func (m *ignoreMatcher) dirFile(dir string) *ignoreFile {
+	if f, ok := m.dirs[dir]; ok {
+		return f
+	}
+	f := readIgnoreFile(filepath.Join(m.root, filepath.FromSlash(dir), ".gitignore"), dir)
+	m.dirs[dir] = f
+	return f
+}
+
+// Ignored reports whether a path relative to the repository root (slash
+// separated) is ignored. A path inside an ignored directory is ignored too,
+// as git can't re-include it.
+func (m *ignoreMatcher) Ignored(rel string, isDir bool) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ignoredLocked(rel, isDir)
+}
+
+// ignoredLocked is Ignored with the lock held
+func (m *ignoreMatcher) ignoredLocked(rel string, isDir bool) bool {
+	if rel == "" || rel == "." {
+		return false
+	}
+	key := verdictKey{rel, isDir}
+	if ignored, ok := m.verdicts[key]; ok {
+		return ignored
+	}
+
+	parent := path.Dir(rel)
+	if parent == "." {
+		parent = ""
+	}
+	ignored := parent != "" && m.ignoredLocked(parent, true)
+	if !ignored {
+		ignored = m.matchLocked(rel, parent, isDir)
+	}
+	m.verdicts[key] = ignored
+	return ignored
+}
+
+// matchLocked checks rel against every pattern that applies to it; the last
+// match wins, and deeper .gitignore files come after shallower ones
+func (m *ignoreMatcher) matchLocked(rel, parent string, isDir bool) bool {
+	files := append([]*ignoreFile{}, m.base...)
+	files = append(files, m.dirFile(""))
+	if parent != "" {
+		dir := ""
+		for _, part := range strings.Split(parent, "/") {
+			dir = path.Join(dir, part)
+			files = append(files, m.dirFile(dir))
+		}
+	}
+
+	ignored := false
+	for _, f := range files {
+		if f == nil {
+			continue
+		}
+		sub := rel
+		if f.base != "" {
+			sub = strings.TrimPrefix(rel, f.base+"/")
+		}
+		for _, p := range f.patterns {
+			if p.match(sub, isDir) {
+				ignored = !p.negate
+			}
+		}
+	}
+	return ignored
+}
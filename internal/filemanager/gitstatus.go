@@ -0,0 +1,256 @@
+package filemanager
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+)
+
+// maxIncrementalPaths is how many changed paths are refreshed with a
+// pathspec before a full status is cheaper
+const maxIncrementalPaths = 200
+
+// gitSnapshot is the status of a whole repository, read with one
+// `git status` and kept up to date from file system events
+type gitSnapshot struct {
+	mu      sync.RWMutex
+	root    string
+	files   map[string]GitStatus // Relative path -> status; untracked dirs appear as one entry
+	dirs    map[string]int       // Relative dir -> number of changed entries below it
+	ignores *ignoreMatcher
+}
+
+// loadGitSnapshot reads the full status of the repository at root
+func loadGitSnapshot(root string) (*gitSnapshot, error) {
+	s := &gitSnapshot{root: root, ignores: newIgnoreMatcher(root)}
+	files, err := s.runStatus()
+	if err != nil {
+		return nil, err
+	}
+	s.files = files
+	s.dirs = rollUpDirs(files)
+	return s, nil
+}
+
+// runStatus runs `git status` for the given relative paths, or the whole
+// repository if there are none
+func (s *gitSnapshot) runStatus(paths ...string) (map[string]GitStatus, error) {
+	// --no-optional-locks keeps status from rewriting the index, which the
+	// watcher would report back as a change
+	args := []string{"--no-optional-locks", "status", "--porcelain=v2", "-z", "--untracked-files=normal"}
+	if len(paths) > 0 {
+		args = append(args, "--")
+		args = append(args, paths...)
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Dir = s.root
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git status failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return parsePorcelainV2(output), nil
+}
+
+// parsePorcelainV2 parses `git status --porcelain=v2 -z` output into a map
+// of relative path to status. Untracked directories keep their trailing
+// slash trimmed.
+func parsePorcelainV2(output []byte) map[string]GitStatus {
+	files := make(map[string]GitStatus)
+	records := strings.Split(string(output), "\x00")
+	for i := 0; i < len(records); i++ {
+		record := records[i]
+		if len(record) < 2 {
+			continue
+		}
+		switch record[0] {
+		case '1':
+			// 1 XY sub mH mI mW hH hI path
+			if fields := strings.SplitN(record, " ", 9); len(fields) == 9 {
+				files[fields[8]] = statusFromXY(fields[1])
+			}
+		case '2':
+			// 2 XY sub mH mI mW hH hI Xscore path, then the original path
+			if fields := strings.SplitN(record, " ", 10); len(fields) == 10 {
+				files[fields[9]] = statusFromXY(fields[1])
+			}
+			i++ // Skip the original path
+		case 'u':
+			// u XY sub m1 m2 m3 mW h1 h2 h3 path
+			if fields := strings.SplitN(record, " ", 11); len(fields) == 11 {
+				files[fields[10]] = GitStatusConflict
+			}
+		case '?':
+			files[strings.TrimSuffix(record[2:], "/")] = GitStatusUntracked
+		}
+	}
+	return files
+}
+
+// statusFromXY maps porcelain v2 index/worktree status letters to a status.
+// Staged changes win over worktree ones.
+func statusFromXY(xy string) GitStatus {
+	if len(xy) < 2 {
+		return GitStatusNone
+	}
+	switch xy[0] {
+	case 'R', 'C':
+		return GitStatusRenamed
+	case 'D':
+		return GitStatusDeleted
+	case 'A', 'M', 'T':
+		return GitStatusStaged
+	}
+	switch xy[1] {
+	case 'M', 'T':
+		return GitStatusModified
+	case 'D':
+		return GitStatusDeleted
+	}
+	return GitStatusNone
+}
+
+// rollUpDirs counts the changed entries below every directory
+func rollUpDirs(files map[string]GitStatus) map[string]int {
+	dirs := make(map[string]int)
+	for rel, status := range files {
+		if status == GitStatusNone {
+			continue
+		}
+		for dir := path.Dir(rel); dir != "." && dir != "/"; dir = path.Dir(dir) {
+			dirs[dir]++
+		}
+		dirs[""]++
+	}
+	return dirs
+}
+
+// Status returns the status of a relative path. Files inside an untracked
+// directory are untracked.
+func (s *gitSnapshot) Status(rel string) GitStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if status, ok := s.files[rel]; ok {
+		return status
+	}
+	for dir := path.Dir(rel); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if s.files[dir] == GitStatusUntracked {
+			return GitStatusUntracked
+		}
+	}
+	return GitStatusNone
+}
+
+// HasChanges reports whether anything below a relative directory changed
+func (s *gitSnapshot) HasChanges(rel string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if rel == "." {
+		rel = ""
+	}
+	return s.dirs[rel] > 0
+}
+
+// Update refreshes the status of the given relative paths (and anything
+// below them) with one `git status`, leaving the rest of the snapshot as is
+func (s *gitSnapshot) Update(paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	if len(paths) > maxIncrementalPaths {
+		return s.Reload()
+	}
+
+	paths = s.widenToUntrackedDirs(paths)
+	files, err := s.runStatus(paths...)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for rel := range s.files {
+		for _, p := range paths {
+			if rel == p || strings.HasPrefix(rel, p+"/") {
+				delete(s.files, rel)
+				break
+			}
+		}
+	}
+	for rel, status := range files {
+		s.files[rel] = status
+	}
+	s.dirs = rollUpDirs(s.files)
+	return nil
+}
+
+// widenToUntrackedDirs replaces paths inside an untracked directory entry
+// with that directory. Status reports such a directory as one entry, so it
+// has to be asked about again once a file in it is tracked or deleted.
+func (s *gitSnapshot) widenToUntrackedDirs(paths []string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	seen := make(map[string]bool, len(paths))
+	widened := make([]string, 0, len(paths))
+	for _, p := range paths {
+		for dir := path.Dir(p); dir != "." && dir != "/"; dir = path.Dir(dir) {
+			if s.files[dir] == GitStatusUntracked {
+				p = dir
+			}
+		}
+		if !seen[p] {
+			seen[p] = true
+			widened = append(widened, p)
+		}
+	}
+	return widened
+}
+
+// Reload rereads the status of the whole repository and the ignore files
+func (s *gitSnapshot) Reload() error {
+	files, err := s.runStatus()
+	if err != nil {
+		return err
+	}
+	ignores := newIgnoreMatcher(s.root)
+
+	s.mu.Lock()
+	s.files = files
+	s.dirs = rollUpDirs(files)
+	s.ignores = ignores
+	s.mu.Unlock()
+	log.Printf("THICC Git: Reloaded status for %s (%d changed)", s.root, len(files))
+	return nil
+}
+
+// Ignored reports whether a relative path is ignored
+func (s *gitSnapshot) Ignored(rel string, isDir bool) bool {
+	s.mu.RLock()
+	ignores := s.ignores
+	s.mu.RUnlock()
+	return ignores.Ignored(rel, isDir)
+}
+
+// needsReload reports whether a change to rel (relative to the repository
+// root) can change more than its own status, as ignore rules do
+func needsReload(rel string) bool {
+	return path.Base(rel) == ".gitignore"
+}
+
+// absoluteGitDir returns the git directory of the repository dir is in
+// (.git, or the worktree's directory inside the main repository), or "" if
+// dir is not in a repository
+func absoluteGitDir(dir string) string {
+	cmd := exec.Command("git", "rev-parse", "--absolute-git-dir")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
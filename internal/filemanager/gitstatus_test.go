@@ -0,0 +1,380 @@
+package filemanager
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runGit runs a git command in dir
+func runGit(tb testing.TB, dir string, args ...string) {
+	tb.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(tb, err, "git %v: %s", args, output)
+}
+
+// writeTestFile writes a file below dir, creating its directories
+func writeTestFile(tb testing.TB, dir, name, content string) {
+	tb.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(tb, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(tb, os.WriteFile(path, []byte(content), 0644))
+}
+
+// createTestRepo creates a git repository with the given files committed
+func createTestRepo(tb testing.TB, files ...string) string {
+	tb.Helper()
+	dir := tb.TempDir()
+	runGit(tb, dir, "init", "-q")
+	runGit(tb, dir, "config", "user.name", "Test")
+	runGit(tb, dir, "config", "user.email", "test@example.com")
+	runGit(tb, dir, "config", "commit.gpgsign", "false")
+	for _, f := range files {
+		writeTestFile(tb, dir, f, "package main\n")
+	}
+	runGit(tb, dir, "add", "-A")
+	runGit(tb, dir, "commit", "-q", "--allow-empty", "-m", "initial")
+	return dir
+}
+
+// =============================================================================
+// Porcelain Parsing Tests
+// =============================================================================
+
+func TestParsePorcelainV2(t *testing.T) {
+	output := "1 .M N... 100644 100644 100644 abc abc src/main.go\x00" +
+		"1 A. N... 000000 100644 100644 000 abc new file.go\x00" +
+		"2 R. N... 100644 100644 100644 abc abc R100 renamed.go\x00old.go\x00" +
+		"u UU N... 100644 100644 100644 100644 a b c conflict.go\x00" +
+		"? scratch/\x00" +
+		"? notes.txt\x00" +
+		"! build/\x00"
+
+	files := parsePorcelainV2([]byte(output))
+	assert.Equal(t, map[string]GitStatus{
+		"src/main.go": GitStatusModified,
+		"new file.go": GitStatusStaged,
+		"renamed.go":  GitStatusRenamed,
+		"conflict.go": GitStatusConflict,
+		"scratch":     GitStatusUntracked,
+		"notes.txt":   GitStatusUntracked,
+	}, files, "the original path of a rename is not an entry")
+}
+
+func TestStatusFromXY(t *testing.T) {
+	assert.Equal(t, GitStatusStaged, statusFromXY("MM"), "staged wins")
+	assert.Equal(t, GitStatusDeleted, statusFromXY("D."))
+	assert.Equal(t, GitStatusDeleted, statusFromXY(".D"))
+	assert.Equal(t, GitStatusModified, statusFromXY(".M"))
+	assert.Equal(t, GitStatusNone, statusFromXY(".."))
+}
+
+func TestRollUpDirs(t *testing.T) {
+	dirs := rollUpDirs(map[string]GitStatus{
+		"a/b/c.go": GitStatusModified,
+		"a/d.go":   GitStatusStaged,
+		"e.go":     GitStatusUntracked,
+	})
+	assert.Equal(t, 2, dirs["a"])
+	assert.Equal(t, 1, dirs["a/b"])
+	assert.Equal(t, 3, dirs[""])
+	assert.Zero(t, dirs["e.go"])
+}
+
+// =============================================================================
+// Ignore Matcher Tests
+// =============================================================================
+
+func TestParseIgnorePattern(t *testing.T) {
+	_, ok := parseIgnorePattern("# comment")
+	assert.False(t, ok)
+	_, ok = parseIgnorePattern("   ")
+	assert.False(t, ok)
+
+	p, ok := parseIgnorePattern("!/build/ ")
+	require.True(t, ok)
+	assert.True(t, p.negate)
+	assert.True(t, p.dirOnly)
+	assert.True(t, p.anchored)
+	assert.Equal(t, []string{"build"}, p.segments)
+
+	p, ok = parseIgnorePattern("*.log")
+	require.True(t, ok)
+	assert.False(t, p.anchored)
+}
+
+func TestIgnorePattern_Match(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		{"*.log", "debug.log", false, true},
+		{"*.log", "logs/debug.log", false, true},
+		{"/todo.txt", "todo.txt", false, true},
+		{"/todo.txt", "docs/todo.txt", false, false},
+		{"build/", "build", true, true},
+		{"build/", "build", false, false},
+		{"doc/*.txt", "doc/notes.txt", false, true},
+		{"doc/*.txt", "doc/server/notes.txt", false, false},
+		{"**/fixtures", "a/b/fixtures", true, true},
+		{"a/**/b", "a/b", false, true},
+		{"a/**/b", "a/x/y/b", false, true},
+		{"a/**", "a/x/y", false, true},
+		{"a/**", "a", true, false},
+		{"file[0-9].go", "file7.go", false, true},
+	}
+	for _, tt := range tests {
+		p, ok := parseIgnorePattern(tt.pattern)
+		require.True(t, ok)
+		assert.Equal(t, tt.want, p.match(tt.path, tt.isDir), "%s vs %s", tt.pattern, tt.path)
+	}
+}
+
+func TestIgnoreMatcher_AgreesWithGit(t *testing.T) {
+	dir := createTestRepo(t)
+	writeTestFile(t, dir, ".gitignore", "*.log\n!keep.log\nbuild/\n/root-only.txt\ndocs/**/draft*\n")
+	writeTestFile(t, dir, "src/.gitignore", "generated.go\n!important.log\n")
+	writeTestFile(t, dir, ".git/info/exclude", "secret.env\n")
+
+	paths := []struct {
+		path  string
+		isDir bool
+	}{
+		{"app.log", false},
+		{"keep.log", false},
+		{"src/important.log", false},
+		{"src/other.log", false},
+		{"build", true},
+		{"build/out.bin", false},
+		{"src/build", true},
+		{"root-only.txt", false},
+		{"src/root-only.txt", false},
+		{"docs/a/b/draft-1.md", false},
+		{"docs/final.md", false},
+		{"src/generated.go", false},
+		{"generated.go", false},
+		{"secret.env", false},
+		{"main.go", false},
+	}
+
+	m := newIgnoreMatcher(dir)
+	for _, p := range paths {
+		arg := p.path
+		if p.isDir {
+			arg += "/" // How check-ignore tells a directory that doesn't exist
+		}
+		gitSays := exec.Command("git", "-C", dir, "check-ignore", "-q", "--no-index", arg).Run() == nil
+		assert.Equal(t, gitSays, m.Ignored(p.path, p.isDir), p.path)
+	}
+}
+
+func TestIgnoreMatcher_CachesFilesAndDirsSeparately(t *testing.T) {
+	dir := createTestRepo(t)
+	writeTestFile(t, dir, ".gitignore", "build/\n")
+
+	m := newIgnoreMatcher(dir)
+	assert.False(t, m.Ignored("build", false), "a file named build is not ignored")
+	assert.True(t, m.Ignored("build", true), "a directory named build is")
+}
+
+func TestIgnoreMatcher_ReadsInfoExcludeInWorktree(t *testing.T) {
+	dir := createTestRepo(t)
+	writeTestFile(t, dir, ".git/info/exclude", "secret.env\n")
+	wt := filepath.Join(t.TempDir(), "wt")
+	runGit(t, dir, "worktree", "add", "-q", "-b", "side", wt)
+
+	m := newIgnoreMatcher(wt)
+	assert.True(t, m.Ignored("secret.env", false), "linked worktrees share the main repository's info/exclude")
+}
+
+// =============================================================================
+// Snapshot Tests
+// =============================================================================
+
+func TestTree_GetGitStatus_RollsUpToFolders(t *testing.T) {
+	dir := createTestRepo(t, "main.go", "src/app.go", "src/deep/util.go", "docs/readme.go")
+	writeTestFile(t, dir, "src/deep/util.go", "package deep // changed\n")
+	writeTestFile(t, dir, "scratch/notes.txt", "notes\n")
+	writeTestFile(t, dir, "staged.go", "package main\n")
+	runGit(t, dir, "add", "staged.go")
+
+	tree := NewTree(dir)
+	status, icon := tree.GetGitStatus(filepath.Join(dir, "src", "deep", "util.go"))
+	assert.Equal(t, GitStatusModified, status)
+	assert.Equal(t, GitIconModified, icon)
+
+	status, _ = tree.GetGitStatus(filepath.Join(dir, "staged.go"))
+	assert.Equal(t, GitStatusStaged, status)
+	status, _ = tree.GetGitStatus(filepath.Join(dir, "main.go"))
+	assert.Equal(t, GitStatusNone, status)
+
+	status, icon = tree.GetGitStatus(filepath.Join(dir, "src"))
+	assert.Equal(t, GitStatusContainsChanges, status)
+	assert.Equal(t, GitIconContainsChanges, icon)
+	status, _ = tree.GetGitStatus(filepath.Join(dir, "src", "deep"))
+	assert.Equal(t, GitStatusContainsChanges, status)
+	status, _ = tree.GetGitStatus(filepath.Join(dir, "docs"))
+	assert.Equal(t, GitStatusNone, status)
+
+	status, _ = tree.GetGitStatus(filepath.Join(dir, "scratch"))
+	assert.Equal(t, GitStatusUntracked, status, "untracked folders are one entry")
+	status, _ = tree.GetGitStatus(filepath.Join(dir, "scratch", "notes.txt"))
+	assert.Equal(t, GitStatusUntracked, status)
+}
+
+func TestTree_UpdateGitStatus(t *testing.T) {
+	dir := createTestRepo(t, "a.go", "src/b.go")
+	tree := NewTree(dir)
+	tree.LoadGitStatus()
+
+	b := filepath.Join(dir, "src", "b.go")
+	status, _ := tree.GetGitStatus(b)
+	require.Equal(t, GitStatusNone, status)
+
+	// Only the changed path is passed on
+	writeTestFile(t, dir, "src/b.go", "package main // changed\n")
+	tree.UpdateGitStatus([]string{b})
+	status, _ = tree.GetGitStatus(b)
+	assert.Equal(t, GitStatusModified, status)
+	status, _ = tree.GetGitStatus(filepath.Join(dir, "src"))
+	assert.Equal(t, GitStatusContainsChanges, status)
+
+	// Changes elsewhere aren't picked up until their paths come in
+	writeTestFile(t, dir, "a.go", "package main // changed\n")
+	status, _ = tree.GetGitStatus(filepath.Join(dir, "a.go"))
+	assert.Equal(t, GitStatusNone, status)
+
+	// Reverting the file clears it and the folder
+	runGit(t, dir, "checkout", "--", "src/b.go")
+	tree.UpdateGitStatus([]string{b})
+	status, _ = tree.GetGitStatus(filepath.Join(dir, "src"))
+	assert.Equal(t, GitStatusNone, status)
+
+	// A new .gitignore rereads everything
+	writeTestFile(t, dir, ".gitignore", "*.tmp\n")
+	tree.UpdateGitStatus([]string{filepath.Join(dir, ".gitignore")})
+	status, _ = tree.GetGitStatus(filepath.Join(dir, "a.go"))
+	assert.Equal(t, GitStatusModified, status)
+	assert.True(t, tree.IsGitIgnored(filepath.Join(dir, "x.tmp")))
+}
+
+func TestTree_UpdateGitStatus_ClearsUntrackedFolder(t *testing.T) {
+	dir := createTestRepo(t, "a.go")
+	writeTestFile(t, dir, "new/one.go", "package one\n")
+	writeTestFile(t, dir, "gone/two.go", "package two\n")
+	tree := NewTree(dir)
+	tree.LoadGitStatus()
+
+	status, _ := tree.GetGitStatus(filepath.Join(dir, "new"))
+	require.Equal(t, GitStatusUntracked, status)
+	status, _ = tree.GetGitStatus(filepath.Join(dir, "gone"))
+	require.Equal(t, GitStatusUntracked, status)
+
+	// Tracking the only file in an untracked folder
+	one := filepath.Join(dir, "new", "one.go")
+	runGit(t, dir, "add", "new/one.go")
+	tree.UpdateGitStatus([]string{one})
+	status, _ = tree.GetGitStatus(one)
+	assert.Equal(t, GitStatusStaged, status)
+	status, _ = tree.GetGitStatus(filepath.Join(dir, "new"))
+	assert.Equal(t, GitStatusContainsChanges, status)
+
+	// Deleting the only file in an untracked folder
+	two := filepath.Join(dir, "gone", "two.go")
+	require.NoError(t, os.Remove(two))
+	tree.UpdateGitStatus([]string{two})
+	status, _ = tree.GetGitStatus(filepath.Join(dir, "gone"))
+	assert.Equal(t, GitStatusNone, status)
+}
+
+// =============================================================================
+// Benchmarks
+// =============================================================================
+
+// benchmarkRepo creates a repository with n committed files, every tenth of
+// them modified
+func benchmarkRepo(b *testing.B, n int) (string, []string) {
+	files := make([]string, n)
+	for i := range files {
+		files[i] = fmt.Sprintf("pkg%d/file%d.go", i%10, i)
+	}
+	dir := createTestRepo(b, files...)
+	for i := 0; i < n; i += 10 {
+		writeTestFile(b, dir, files[i], "package main // changed\n")
+	}
+	return dir, files
+}
+
+// BenchmarkGitStatus_PerFile is the old approach: one git status per path
+func BenchmarkGitStatus_PerFile(b *testing.B) {
+	dir, files := benchmarkRepo(b, 100)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, f := range files {
+			cmd := exec.Command("git", "status", "--porcelain", "--", f)
+			cmd.Dir = dir
+			_, _ = cmd.Output()
+		}
+	}
+}
+
+// BenchmarkGitStatus_Snapshot reads one snapshot and looks every path up
+func BenchmarkGitStatus_Snapshot(b *testing.B) {
+	dir, files := benchmarkRepo(b, 100)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s, err := loadGitSnapshot(dir)
+		require.NoError(b, err)
+		for _, f := range files {
+			s.Status(f)
+		}
+	}
+}
+
+// BenchmarkGitStatus_Update refreshes the snapshot for one changed file
+func BenchmarkGitStatus_Update(b *testing.B) {
+	dir, files := benchmarkRepo(b, 100)
+	s, err := loadGitSnapshot(dir)
+	require.NoError(b, err)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		require.NoError(b, s.Update(files[:1]))
+	}
+}
+
+// BenchmarkIgnored_CheckIgnore is the old approach: one git check-ignore
+// per path
+func BenchmarkIgnored_CheckIgnore(b *testing.B) {
+	dir, files := benchmarkRepo(b, 100)
+	writeTestFile(b, dir, ".gitignore", "*.log\nbuild/\n/pkg3/\n")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, f := range files {
+			cmd := exec.Command("git", "check-ignore", "-q", f)
+			cmd.Dir = dir
+			_ = cmd.Run()
+		}
+	}
+}
+
+// BenchmarkIgnored_Matcher answers the same questions natively
+func BenchmarkIgnored_Matcher(b *testing.B) {
+	dir, files := benchmarkRepo(b, 100)
+	writeTestFile(b, dir, ".gitignore", "*.log\nbuild/\n/pkg3/\n")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m := newIgnoreMatcher(dir)
+		for _, f := range files {
+			m.Ignored(f, false)
+		}
+	}
+}
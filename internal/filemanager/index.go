@@ -213,7 +213,7 @@ func (idx *FileIndex) EnableWatching() error {
 		return nil // Already watching
 	}
 
-	watcher, err := NewFileWatcher(idx.Root, SkipDirs, func() {
+	watcher, err := NewFileWatcher(idx.Root, SkipDirs, func(paths []string) {
 		// Mark index as stale and rebuild
 		log.Println("THICC FileIndex: Rebuilding after file system change")
 		idx.Refresh()
@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -46,6 +47,7 @@ type Tree struct {
 
 	// File system watcher
 	watcher   *FileWatcher
+	gitDir    string // Repository's git directory, watched for index and HEAD changes
 	onRefresh func() // Callback when tree is refreshed (for UI update)
 
 	// Synchronization
@@ -523,6 +525,43 @@ func (t *Tree) MoveDown() bool {
 	return t.SelectIndex(t.SelectedIdx + 1)
 }
 
+// GetExpandedPaths returns the expanded directory paths in sorted order (thread-safe)
+func (t *Tree) GetExpandedPaths() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	paths := make([]string, 0, len(t.ExpandedPaths))
+	for path, expanded := range t.ExpandedPaths {
+		if expanded {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// ExpandPaths marks the given directories as expanded and rebuilds the tree.
+// Paths outside the root or that are no longer directories are skipped.
+func (t *Tree) ExpandPaths(paths []string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, path := range paths {
+		if path != t.Root && !strings.HasPrefix(path, t.Root+string(filepath.Separator)) {
+			continue
+		}
+		if info, err := os.Stat(path); err != nil || !info.IsDir() {
+			continue
+		}
+		t.ExpandedPaths[path] = true
+	}
+
+	// Rebuild tree (scanDir will use ExpandedPaths)
+	t.Nodes = make([]*TreeNode, 0)
+	t.Index = make(map[string]*TreeNode)
+	return t.scanDir(t.Root, 0, -1)
+}
+
 // GetNodes returns a copy of the current nodes (thread-safe)
 func (t *Tree) GetNodes() []*TreeNode {
 	t.mu.RLock()
@@ -584,25 +623,56 @@ func (t *Tree) EnableWatching() error {
 		return nil // Already watching
 	}
 
-	watcher, err := NewFileWatcher(t.Root, SkipDirs, func() {
-		// Refresh tree and notify UI
-		if err := t.Refresh(); err != nil {
-			log.Printf("THICC Tree: Refresh after watch event failed: %v", err)
-		}
-		if t.onRefresh != nil {
-			t.onRefresh()
-		}
-	})
+	watcher, err := NewFileWatcher(t.Root, SkipDirs, t.handleWatchEvent)
 	if err != nil {
 		return err
 	}
 
+	// Watch the git directory too (not recursively), so staging, commits and
+	// checkouts update the git status
+	t.gitDir = absoluteGitDir(t.Root)
+	if t.gitDir != "" {
+		if err := watcher.WatchDir(t.gitDir); err != nil {
+			log.Printf("THICC Tree: Failed to watch %s: %v", t.gitDir, err)
+		}
+	}
+
 	t.watcher = watcher
 	go t.watcher.Start()
 	log.Printf("THICC Tree: Watching enabled for %s", t.Root)
 	return nil
 }
 
+// handleWatchEvent updates the git status of the changed paths, rescans the
+// tree and notifies the UI
+func (t *Tree) handleWatchEvent(paths []string) {
+	var changed []string
+	gitChanged := false
+	for _, p := range paths {
+		if t.gitDir != "" && filepath.Dir(p) == t.gitDir {
+			gitChanged = true
+		} else {
+			changed = append(changed, p)
+		}
+	}
+
+	if gitChanged {
+		t.reloadGitStatus()
+	} else {
+		t.UpdateGitStatus(changed)
+	}
+
+	// Changes inside the git directory don't change the tree itself
+	if len(changed) > 0 {
+		if err := t.Refresh(); err != nil {
+			log.Printf("THICC Tree: Refresh after watch event failed: %v", err)
+		}
+	}
+	if t.onRefresh != nil {
+		t.onRefresh()
+	}
+}
+
 // DisableWatching stops file system watching (can be re-enabled later)
 func (t *Tree) DisableWatching() {
 	if t.watcher != nil {
@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,15 +16,17 @@ type FileWatcher struct {
 	watcher    *fsnotify.Watcher
 	root       string
 	skipDirs   map[string]bool
-	onChange   func()
+	extraDirs  map[string]bool // Directories watched on their own, even inside skipDirs
+	onChange   func(paths []string)
 	debounceMs int
 	stop       chan struct{}
 	stopped    bool
 	mu         sync.Mutex
 }
 
-// NewFileWatcher creates a new file watcher for the given root directory
-func NewFileWatcher(root string, skipDirs map[string]bool, onChange func()) (*FileWatcher, error) {
+// NewFileWatcher creates a new file watcher for the given root directory.
+// onChange receives the paths that changed since the last call.
+func NewFileWatcher(root string, skipDirs map[string]bool, onChange func(paths []string)) (*FileWatcher, error) {
 	w, err := fsnotify.NewWatcher()
 	if err != nil {
 		return nil, err
@@ -33,6 +36,7 @@ func NewFileWatcher(root string, skipDirs map[string]bool, onChange func()) (*Fi
 		watcher:    w,
 		root:       root,
 		skipDirs:   skipDirs,
+		extraDirs:  make(map[string]bool),
 		onChange:   onChange,
 		debounceMs: 100,
 		stop:       make(chan struct{}),
@@ -72,6 +76,19 @@ func (fw *FileWatcher) Stop() {
 	log.Printf("THICC Watcher: Stopped watching %s", fw.root)
 }
 
+// WatchDir watches one more directory, without its subdirectories. Events
+// in it are reported even if it is in a skipped directory such as .git, but
+// lock files are left out.
+func (fw *FileWatcher) WatchDir(dir string) error {
+	if err := fw.watcher.Add(dir); err != nil {
+		return err
+	}
+	fw.mu.Lock()
+	fw.extraDirs[dir] = true
+	fw.mu.Unlock()
+	return nil
+}
+
 // addDirRecursive adds watches for a directory and all its subdirectories
 func (fw *FileWatcher) addDirRecursive(path string) error {
 	return filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
@@ -107,22 +124,32 @@ func (fw *FileWatcher) addDirRecursive(path string) error {
 func (fw *FileWatcher) eventLoop() {
 	var timer *time.Timer
 	var timerMu sync.Mutex
+	pending := make(map[string]bool) // Paths changed since the last callback
 
-	resetTimer := func() {
+	resetTimer := func(path string) {
 		timerMu.Lock()
 		defer timerMu.Unlock()
 
+		pending[path] = true
 		if timer != nil {
 			timer.Stop()
 		}
 		timer = time.AfterFunc(time.Duration(fw.debounceMs)*time.Millisecond, func() {
+			timerMu.Lock()
+			paths := make([]string, 0, len(pending))
+			for p := range pending {
+				paths = append(paths, p)
+			}
+			pending = make(map[string]bool)
+			timerMu.Unlock()
+
 			fw.mu.Lock()
 			stopped := fw.stopped
 			fw.mu.Unlock()
 
-			if !stopped && fw.onChange != nil {
-				log.Println("THICC Watcher: Triggering refresh")
-				fw.onChange()
+			if !stopped && fw.onChange != nil && len(paths) > 0 {
+				log.Printf("THICC Watcher: Triggering refresh for %d changed paths", len(paths))
+				fw.onChange(paths)
 			}
 		})
 	}
@@ -143,7 +170,14 @@ func (fw *FileWatcher) eventLoop() {
 			}
 
 			// Check if the changed path is in a skipped directory
-			if fw.shouldSkipEvent(event.Name) {
+			fw.mu.Lock()
+			extra := fw.extraDirs[filepath.Dir(event.Name)]
+			fw.mu.Unlock()
+			if extra {
+				if strings.HasSuffix(event.Name, ".lock") {
+					continue
+				}
+			} else if fw.shouldSkipEvent(event.Name) {
 				continue
 			}
 
@@ -167,7 +201,7 @@ func (fw *FileWatcher) eventLoop() {
 			}
 
 			// Debounce the refresh callback
-			resetTimer()
+			resetTimer(event.Name)
 
 		case err, ok := <-fw.watcher.Errors:
 			if !ok {
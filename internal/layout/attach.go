@@ -0,0 +1,91 @@
+package layout
+
+import (
+	"log"
+
+	"github.com/ellery/thicc/internal/sessiond"
+	"github.com/ellery/thicc/internal/terminal"
+)
+
+// SetAttachSessions queues detached daemon sessions to be reattached to the
+// terminal panels. Sessions go back to the panel they came from when it's
+// free, otherwise to the next free one; extras are left detached.
+// Call before PreloadTerminal/Initialize.
+func (lm *LayoutManager) SetAttachSessions(infos []sessiond.SessionInfo) {
+	lm.pendingAttach = make(map[int]string)
+
+	var unplaced []sessiond.SessionInfo
+	for _, info := range infos {
+		if info.Slot >= 2 && info.Slot <= 4 && lm.pendingAttach[info.Slot] == "" {
+			lm.pendingAttach[info.Slot] = info.ID
+		} else {
+			unplaced = append(unplaced, info)
+		}
+	}
+	for _, info := range unplaced {
+		placed := false
+		for slot := 2; slot <= 4; slot++ {
+			if lm.pendingAttach[slot] == "" {
+				lm.pendingAttach[slot] = info.ID
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			log.Printf("THICC: No free terminal panel for session %s, leaving it detached", info.ID)
+		}
+	}
+	log.Printf("THICC: Sessions to reattach: %v", lm.pendingAttach)
+}
+
+// takeAttachSession returns (and forgets) the session queued for a panel
+func (lm *LayoutManager) takeAttachSession(panel int) string {
+	id := lm.pendingAttach[panel]
+	delete(lm.pendingAttach, panel)
+	return id
+}
+
+// newTerminalPanel creates the terminal for a panel, attaching to a daemon
+// session if attachID is set. If the session is gone, cmdArgs is started
+// instead, in dir (the working directory when empty).
+func (lm *LayoutManager) newTerminalPanel(panel int, attachID string, termX, termW int, cmdArgs []string, dir string) (*terminal.Panel, error) {
+	if attachID != "" {
+		term, err := terminal.AttachPanel(termX, 1, termW, lm.ScreenH-1, attachID)
+		if err == nil {
+			return term, nil
+		}
+		log.Printf("THICC: Failed to attach session %s to panel %d: %v", attachID, panel, err)
+	}
+	return terminal.NewPanelInDir(termX, 1, termW, lm.ScreenH-1, cmdArgs, panel, dir)
+}
+
+// AttachSecondaryTerminals opens the secondary terminal panels that have a
+// session queued by SetAttachSessions. Call after Initialize and RestoreSession.
+func (lm *LayoutManager) AttachSecondaryTerminals() {
+	attached := false
+	for _, panel := range []int{3, 4} {
+		if lm.pendingAttach[panel] == "" {
+			continue
+		}
+		switch panel {
+		case 3:
+			lm.Terminal2Visible = true
+			lm.Terminal2Initialized = true
+		case 4:
+			lm.Terminal3Visible = true
+			lm.Terminal3Initialized = true
+		}
+		lm.createTerminalForPanel(panel, nil)
+		attached = true
+	}
+	if !attached {
+		return
+	}
+
+	// createTerminalForPanel focuses each new pane; go back to the main terminal
+	if lm.TerminalVisible {
+		lm.setActivePanel(2)
+	}
+	lm.updatePanelRegions()
+	lm.triggerRedraw()
+}
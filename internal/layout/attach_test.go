@@ -0,0 +1,48 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/ellery/thicc/internal/sessiond"
+	"github.com/stretchr/testify/assert"
+)
+
+// =============================================================================
+// Session Attach Tests
+// =============================================================================
+
+func TestSetAttachSessions_KeepsOriginalPanels(t *testing.T) {
+	lm := &LayoutManager{}
+	lm.SetAttachSessions([]sessiond.SessionInfo{
+		{ID: "b", Slot: 4},
+		{ID: "a", Slot: 2},
+	})
+
+	assert.Equal(t, "a", lm.takeAttachSession(2))
+	assert.Equal(t, "", lm.takeAttachSession(3))
+	assert.Equal(t, "b", lm.takeAttachSession(4))
+}
+
+func TestSetAttachSessions_FillsFreePanels(t *testing.T) {
+	lm := &LayoutManager{}
+	lm.SetAttachSessions([]sessiond.SessionInfo{
+		{ID: "a", Slot: 2},
+		{ID: "b", Slot: 2},
+		{ID: "c", Slot: 0},
+		{ID: "d", Slot: 3},
+		{ID: "e", Slot: 0},
+	})
+
+	assert.Equal(t, "a", lm.takeAttachSession(2))
+	assert.Equal(t, "d", lm.takeAttachSession(3))
+	assert.Equal(t, "b", lm.takeAttachSession(4))
+}
+
+func TestTakeAttachSession_OnlyOnce(t *testing.T) {
+	lm := &LayoutManager{}
+	assert.Equal(t, "", lm.takeAttachSession(2))
+
+	lm.SetAttachSessions([]sessiond.SessionInfo{{ID: "a", Slot: 2}})
+	assert.Equal(t, "a", lm.takeAttachSession(2))
+	assert.Equal(t, "", lm.takeAttachSession(2))
+}
@@ -0,0 +1,49 @@
+package layout
+
+import (
+	"log"
+
+	"github.com/ellery/thicc/internal/action"
+)
+
+// editorPane returns the BufPane shown in the editor, or nil
+func (lm *LayoutManager) editorPane() *action.BufPane {
+	tab := action.MainTab()
+	if tab == nil {
+		return nil
+	}
+	for _, pane := range tab.Panes {
+		if bp, ok := pane.(*action.BufPane); ok {
+			return bp
+		}
+	}
+	return nil
+}
+
+// ToggleBlame turns the git blame gutter on or off for the editor's buffer
+func (lm *LayoutManager) ToggleBlame() {
+	if bp := lm.editorPane(); bp != nil {
+		bp.ToggleBlame()
+	}
+}
+
+// OpenBlameCommit opens the commit that last changed the cursor's line in a
+// new tab, keeping the blamed file open in its own tab
+func (lm *LayoutManager) OpenBlameCommit() bool {
+	bp := lm.editorPane()
+	if bp == nil || !bp.Buf.HasBlame() {
+		return false
+	}
+	diffBuf := bp.BlameCommitDiff()
+	if diffBuf == nil {
+		return true
+	}
+	log.Printf("THICC: Opening blamed commit of line %d in %s", bp.Cursor.Y+1, bp.Buf.Path)
+
+	if lm.TabBar != nil {
+		lm.TabBar.AddTab(diffBuf)
+	}
+	lm.displayBufferInEditor(diffBuf)
+	lm.triggerRedraw()
+	return true
+}
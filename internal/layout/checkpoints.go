@@ -0,0 +1,172 @@
+package layout
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ellery/thicc/internal/buffer"
+	"github.com/ellery/thicc/internal/sourcecontrol"
+	"github.com/ellery/thicc/internal/terminal"
+	"github.com/ellery/thicc/internal/thicc"
+)
+
+// checkpointPollInterval is how often terminals are checked for AI activity
+const checkpointPollInterval = 500 * time.Millisecond
+
+// turnIdleTimeout is how long an AI tool must be quiet before its turn counts
+// as finished
+const turnIdleTimeout = 5 * time.Second
+
+// turnEvent is a change in an AI terminal's activity
+type turnEvent int
+
+const (
+	turnNone    turnEvent = iota
+	turnStarted           // Idle -> active: take a "before" checkpoint
+	turnEnded             // Active -> idle long enough: take an "after" checkpoint
+)
+
+// aiTurn tracks one terminal's AI turns for checkpointing
+type aiTurn struct {
+	term       *terminal.Panel // Terminal the session belongs to
+	session    string          // Checkpoint session name
+	turn       int             // Number of the current (or last) turn
+	active     bool            // Whether a turn is in progress
+	lastActive time.Time       // Last time the AI tool produced output
+}
+
+// update advances the turn state with the terminal's current activity
+func (t *aiTurn) update(active bool, now time.Time) turnEvent {
+	if active {
+		t.lastActive = now
+		if !t.active {
+			t.active = true
+			t.turn++
+			return turnStarted
+		}
+		return turnNone
+	}
+	if t.active && now.Sub(t.lastActive) >= turnIdleTimeout {
+		t.active = false
+		return turnEnded
+	}
+	return turnNone
+}
+
+// checkpointSession returns the checkpoint session name for a terminal (1-3)
+// started at the given time
+func checkpointSession(termNum int, start time.Time) string {
+	return fmt.Sprintf("terminal-%d-%s", termNum, start.Format("20060102-150405"))
+}
+
+// checkpointLabel returns the label of a checkpoint taken at a turn boundary
+func checkpointLabel(ev turnEvent, turn, termNum int) string {
+	when := "Before"
+	if ev == turnEnded {
+		when = "After"
+	}
+	return fmt.Sprintf("%s turn %d (Terminal %d)", when, turn, termNum)
+}
+
+// checkpointWatcher snapshots the working tree whenever an AI terminal starts
+// or finishes a turn, so each turn's changes can be reviewed or rolled back
+// from the Source Control checkpoint timeline
+func (lm *LayoutManager) checkpointWatcher() {
+	ticker := time.NewTicker(checkpointPollInterval)
+	defer ticker.Stop()
+
+	var turns [3]aiTurn
+	repoRoot, isRepo := "", false
+
+	for {
+		select {
+		case <-lm.idleCheckStop:
+			return
+		case <-ticker.C:
+		}
+
+		lm.mu.RLock()
+		root := lm.Root
+		terms := [3]*terminal.Panel{lm.Terminal, lm.Terminal2, lm.Terminal3}
+		lm.mu.RUnlock()
+
+		// Only re-check for a repo after a project switch
+		if root != repoRoot {
+			repoRoot, isRepo = root, root != "" && sourcecontrol.IsGitRepo(root)
+			turns = [3]aiTurn{}
+		}
+		if !isRepo {
+			continue
+		}
+
+		now := time.Now()
+		for i, term := range terms {
+			t := &turns[i]
+			if term != t.term {
+				// Terminal closed or replaced: finish its turn, start a new session
+				if t.active {
+					lm.takeCheckpoint(repoRoot, t.session, checkpointLabel(turnEnded, t.turn, i+1))
+				}
+				*t = aiTurn{term: term, session: checkpointSession(i+1, now)}
+			}
+			if term == nil || term.Dir != "" {
+				// Sessions in their own worktree are rolled back by discarding it
+				continue
+			}
+			if ev := t.update(term.IsAIToolActive(), now); ev != turnNone {
+				lm.takeCheckpoint(repoRoot, t.session, checkpointLabel(ev, t.turn, i+1))
+			}
+		}
+	}
+}
+
+// takeCheckpoint snapshots the working tree, unless checkpoints are turned
+// off, and refreshes the timeline if open. It runs on the watcher goroutine,
+// so the refresh is posted to the UI.
+func (lm *LayoutManager) takeCheckpoint(repoRoot, session, label string) {
+	if !thicc.GetCheckpointsEnabled() {
+		return
+	}
+	cp, err := sourcecontrol.CreateCheckpoint(repoRoot, session, label)
+	if err != nil {
+		log.Printf("THICC: Checkpoint %q failed: %v", label, err)
+		return
+	}
+	if cp == nil {
+		return // Nothing changed
+	}
+	log.Printf("THICC: Took checkpoint %q (%s)", label, cp.ShortHash())
+	if sc := lm.SourceControl; sc != nil {
+		lm.post(sc.RefreshCheckpoints)
+	}
+}
+
+// openMultiFileDiff shows a multi-file diff (checkpoints, worktree sessions) in the editor
+func (lm *LayoutManager) openMultiFileDiff(title string, patch string) {
+	// Hide terminal for cleaner diff view (only SC + editor visible)
+	lm.TerminalVisible = false
+
+	// Show editor if hidden
+	lm.EditorVisible = true
+	lm.updatePanelRegions()
+
+	if patch == "" {
+		patch = "No changes\n"
+	}
+	diffBuf := buffer.NewBufferFromString(patch, title, buffer.BTHelp)
+	diffBuf.SetOptionNative("filetype", "patch")
+	lm.displayBufferInEditor(diffBuf)
+
+	// Update the tab bar with the diff buffer
+	if lm.TabBar != nil {
+		if lm.TabBar.ActiveIndex >= 0 && lm.TabBar.ActiveIndex < len(lm.TabBar.Tabs) {
+			lm.TabBar.Tabs[lm.TabBar.ActiveIndex].Buffer = diffBuf
+			lm.TabBar.Tabs[lm.TabBar.ActiveIndex].Name = truncateName(diffBuf.GetName())
+			lm.TabBar.Tabs[lm.TabBar.ActiveIndex].Loaded = true
+		}
+	}
+
+	// Keep focus on SC so user can keep browsing
+	lm.triggerRedraw()
+}
@@ -0,0 +1,41 @@
+package layout
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// =============================================================================
+// AI Turn Checkpoint Tests
+// =============================================================================
+
+func TestAITurn_StartAndEnd(t *testing.T) {
+	var turn aiTurn
+	start := time.Date(2026, 3, 4, 15, 0, 0, 0, time.Local)
+
+	assert.Equal(t, turnNone, turn.update(false, start))
+	assert.Equal(t, turnStarted, turn.update(true, start))
+	assert.Equal(t, 1, turn.turn)
+	assert.Equal(t, turnNone, turn.update(true, start.Add(time.Second)))
+
+	// Short pauses (thinking, tool calls) stay in the same turn
+	assert.Equal(t, turnNone, turn.update(false, start.Add(3*time.Second)))
+	assert.Equal(t, turnNone, turn.update(true, start.Add(4*time.Second)))
+
+	assert.Equal(t, turnNone, turn.update(false, start.Add(8*time.Second)))
+	assert.Equal(t, turnEnded, turn.update(false, start.Add(9*time.Second)))
+	assert.Equal(t, turnNone, turn.update(false, start.Add(20*time.Second)))
+
+	assert.Equal(t, turnStarted, turn.update(true, start.Add(30*time.Second)))
+	assert.Equal(t, 2, turn.turn)
+}
+
+func TestCheckpointNames(t *testing.T) {
+	now := time.Date(2026, 3, 4, 15, 6, 7, 0, time.Local)
+
+	assert.Equal(t, "terminal-2-20260304-150607", checkpointSession(2, now))
+	assert.Equal(t, "Before turn 3 (Terminal 1)", checkpointLabel(turnStarted, 3, 1))
+	assert.Equal(t, "After turn 3 (Terminal 1)", checkpointLabel(turnEnded, 3, 1))
+}
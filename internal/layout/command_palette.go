@@ -0,0 +1,338 @@
+package layout
+
+import (
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/micro-editor/tcell/v2"
+	"github.com/sahilm/fuzzy"
+)
+
+// PaletteItem is one entry in the command palette
+type PaletteItem struct {
+	Name        string
+	Description string
+	Category    string // "Pane", "Plugin", "Action" or "Command"
+	Key         string // The key bound to it, if any
+	Run         func()
+}
+
+// paletteResult is an item that matches the query
+type paletteResult struct {
+	Item       *PaletteItem
+	MatchedIdx []int // Rune indexes of the matched characters in the name
+}
+
+// CommandPalette is a modal listing everything that can be run, searched
+// by name (F1)
+type CommandPalette struct {
+	Active bool
+	Screen tcell.Screen
+
+	// Input field
+	Query     string
+	CursorPos int // Byte offset in Query
+
+	// Results
+	Items       []PaletteItem
+	Results     []paletteResult
+	SelectedIdx int
+	TopLine     int
+
+	// Dimensions
+	Width      int
+	Height     int
+	ListHeight int
+}
+
+// NewCommandPalette creates a new command palette
+func NewCommandPalette(screen tcell.Screen) *CommandPalette {
+	return &CommandPalette{
+		Screen:     screen,
+		Width:      80,
+		Height:     20,
+		ListHeight: 12,
+	}
+}
+
+// Show activates the palette with the given items
+func (p *CommandPalette) Show(items []PaletteItem) {
+	p.Active = true
+	p.Items = items
+	p.Query = ""
+	p.CursorPos = 0
+	p.updateResults()
+}
+
+// Hide deactivates the palette
+func (p *CommandPalette) Hide() {
+	p.Active = false
+	p.Query = ""
+	p.Items = nil
+	p.Results = nil
+}
+
+// HandleEvent processes input events
+func (p *CommandPalette) HandleEvent(event tcell.Event) bool {
+	if !p.Active {
+		return false
+	}
+
+	switch ev := event.(type) {
+	case *tcell.EventKey:
+		p.handleKey(ev)
+	case *tcell.EventMouse:
+		p.handleMouse(ev)
+	}
+	return true // Consume all events while active
+}
+
+func (p *CommandPalette) handleKey(ev *tcell.EventKey) {
+	switch ev.Key() {
+	case tcell.KeyEscape, tcell.KeyF1:
+		p.Hide()
+
+	case tcell.KeyEnter:
+		p.runSelected()
+
+	case tcell.KeyUp, tcell.KeyCtrlP:
+		p.moveSelection(-1)
+	case tcell.KeyDown, tcell.KeyCtrlN:
+		p.moveSelection(1)
+	case tcell.KeyPgUp:
+		p.moveSelection(-p.ListHeight)
+	case tcell.KeyPgDn:
+		p.moveSelection(p.ListHeight)
+
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if p.CursorPos > 0 {
+			_, size := utf8.DecodeLastRuneInString(p.Query[:p.CursorPos])
+			p.Query = p.Query[:p.CursorPos-size] + p.Query[p.CursorPos:]
+			p.CursorPos -= size
+			p.updateResults()
+		}
+
+	case tcell.KeyDelete:
+		if p.CursorPos < len(p.Query) {
+			_, size := utf8.DecodeRuneInString(p.Query[p.CursorPos:])
+			p.Query = p.Query[:p.CursorPos] + p.Query[p.CursorPos+size:]
+			p.updateResults()
+		}
+
+	case tcell.KeyLeft:
+		if p.CursorPos > 0 {
+			_, size := utf8.DecodeLastRuneInString(p.Query[:p.CursorPos])
+			p.CursorPos -= size
+		}
+
+	case tcell.KeyRight:
+		if p.CursorPos < len(p.Query) {
+			_, size := utf8.DecodeRuneInString(p.Query[p.CursorPos:])
+			p.CursorPos += size
+		}
+
+	case tcell.KeyHome, tcell.KeyCtrlA:
+		p.CursorPos = 0
+
+	case tcell.KeyEnd, tcell.KeyCtrlE:
+		p.CursorPos = len(p.Query)
+
+	case tcell.KeyCtrlU:
+		p.Query = ""
+		p.CursorPos = 0
+		p.updateResults()
+
+	case tcell.KeyRune:
+		r := string(ev.Rune())
+		p.Query = p.Query[:p.CursorPos] + r + p.Query[p.CursorPos:]
+		p.CursorPos += len(r)
+		p.updateResults()
+	}
+}
+
+func (p *CommandPalette) handleMouse(ev *tcell.EventMouse) {
+	x, y := ev.Position()
+	w, h := p.Screen.Size()
+	modalX := (w - p.Width) / 2
+	modalY := (h - p.Height) / 2
+
+	switch ev.Buttons() {
+	case tcell.WheelUp:
+		p.moveSelection(-1)
+	case tcell.WheelDown:
+		p.moveSelection(1)
+	case tcell.Button1:
+		if x < modalX || x >= modalX+p.Width || y < modalY || y >= modalY+p.Height {
+			p.Hide() // Click outside
+			return
+		}
+		listY := modalY + 4 // After title, separator, input, separator
+		if y >= listY && y < listY+p.ListHeight && p.TopLine+(y-listY) < len(p.Results) {
+			p.SelectedIdx = p.TopLine + (y - listY)
+			p.runSelected()
+		}
+	}
+}
+
+// runSelected closes the palette and runs the selected item
+func (p *CommandPalette) runSelected() {
+	if p.SelectedIdx >= len(p.Results) {
+		return
+	}
+	item := p.Results[p.SelectedIdx].Item
+	p.Hide()
+	if item.Run != nil {
+		item.Run()
+	}
+}
+
+// updateResults filters the items by the query, best matches first
+func (p *CommandPalette) updateResults() {
+	p.SelectedIdx = 0
+	p.TopLine = 0
+	p.Results = p.Results[:0]
+
+	if p.Query == "" {
+		for i := range p.Items {
+			p.Results = append(p.Results, paletteResult{Item: &p.Items[i]})
+		}
+		return
+	}
+
+	names := make([]string, len(p.Items))
+	for i, item := range p.Items {
+		names[i] = item.Name
+	}
+	for _, m := range fuzzy.Find(p.Query, names) {
+		// fuzzy reports byte offsets; the name is drawn by rune
+		var idx []int
+		for _, b := range m.MatchedIndexes {
+			idx = append(idx, utf8.RuneCountInString(m.Str[:b]))
+		}
+		p.Results = append(p.Results, paletteResult{Item: &p.Items[m.Index], MatchedIdx: idx})
+	}
+}
+
+// moveSelection moves the selection by delta, keeping it in view
+func (p *CommandPalette) moveSelection(delta int) {
+	p.SelectedIdx = max(0, min(p.SelectedIdx+delta, len(p.Results)-1))
+	if p.SelectedIdx < p.TopLine {
+		p.TopLine = p.SelectedIdx
+	}
+	if p.SelectedIdx >= p.TopLine+p.ListHeight {
+		p.TopLine = p.SelectedIdx - p.ListHeight + 1
+	}
+}
+
+// Render draws the command palette
+func (p *CommandPalette) Render(screen tcell.Screen) {
+	if !p.Active {
+		return
+	}
+
+	w, h := screen.Size()
+	x := (w - p.Width) / 2
+	y := (h - p.Height) / 2
+
+	// Colors (explicit fg AND bg to prevent issues in light mode)
+	bgColor := tcell.ColorBlack
+	textColor := tcell.ColorWhite
+	dimColor := tcell.Color245       // Gray
+	highlightColor := tcell.Color226 // Yellow
+
+	bgStyle := tcell.StyleDefault.Foreground(textColor).Background(bgColor)
+	borderStyle := tcell.StyleDefault.Foreground(tcell.Color51).Background(bgColor).Bold(true) // Cyan
+	titleStyle := tcell.StyleDefault.Foreground(tcell.Color51).Background(bgColor).Bold(true)
+	cursorStyle := tcell.StyleDefault.Foreground(bgColor).Background(textColor)
+	listStyle := tcell.StyleDefault.Foreground(textColor).Background(bgColor)
+	dimStyle := tcell.StyleDefault.Foreground(dimColor).Background(bgColor)
+	selectedStyle := tcell.StyleDefault.Foreground(bgColor).Background(highlightColor).Bold(true)
+	selectedDimStyle := tcell.StyleDefault.Foreground(bgColor).Background(highlightColor)
+	matchStyle := tcell.StyleDefault.Foreground(tcell.Color205).Background(bgColor).Bold(true) // Hot pink
+
+	for dy := 0; dy < p.Height; dy++ {
+		for dx := 0; dx < p.Width; dx++ {
+			screen.SetContent(x+dx, y+dy, ' ', nil, bgStyle)
+		}
+	}
+
+	// Frame: title, input, list and hints separated by lines
+	drawFrameLine(screen, x, y, p.Width, '╔', '═', '╗', borderStyle)
+	drawFrameLine(screen, x, y+1, p.Width, '╠', '═', '╣', borderStyle)
+	drawFrameLine(screen, x, y+3, p.Width, '╠', '─', '╣', borderStyle)
+	drawFrameLine(screen, x, y+p.Height-3, p.Width, '╠', '─', '╣', borderStyle)
+	drawFrameLine(screen, x, y+p.Height-1, p.Width, '╚', '═', '╝', borderStyle)
+	for _, dy := range []int{2, p.Height - 2} {
+		screen.SetContent(x, y+dy, '║', nil, borderStyle)
+		screen.SetContent(x+p.Width-1, y+dy, '║', nil, borderStyle)
+	}
+	for dy := 4; dy < p.Height-3; dy++ {
+		screen.SetContent(x, y+dy, '║', nil, borderStyle)
+		screen.SetContent(x+p.Width-1, y+dy, '║', nil, borderStyle)
+	}
+
+	title := " Command Palette "
+	drawUntil(screen, x+(p.Width-len(title))/2, y, title, titleStyle, x+p.Width-1)
+
+	// Input with cursor
+	right := x + p.Width - 2
+	inputX := drawUntil(screen, x+2, y+2, "> ", bgStyle, right)
+	cursorX := drawUntil(screen, inputX, y+2, p.Query[:p.CursorPos], bgStyle, right)
+	rest := p.Query[p.CursorPos:]
+	ch, size := utf8.DecodeRuneInString(rest)
+	if size == 0 {
+		ch = ' '
+	}
+	if cursorX < right {
+		screen.SetContent(cursorX, y+2, ch, nil, cursorStyle)
+		drawUntil(screen, cursorX+1, y+2, rest[size:], bgStyle, right)
+	}
+
+	listY := y + 4
+	if len(p.Results) == 0 {
+		msg := "No matching commands"
+		drawUntil(screen, x+(p.Width-len(msg))/2, listY+p.ListHeight/2, msg, dimStyle, right)
+	}
+	for i := 0; i < p.ListHeight && p.TopLine+i < len(p.Results); i++ {
+		result := p.Results[p.TopLine+i]
+		item := result.Item
+		lineY := listY + i
+		selected := p.TopLine+i == p.SelectedIdx
+
+		nameStyle, descStyle, hitStyle := listStyle, dimStyle, matchStyle
+		prefix := "   "
+		if selected {
+			nameStyle, descStyle, hitStyle = selectedStyle, selectedDimStyle, selectedStyle
+			prefix = " > "
+			for dx := 1; dx < p.Width-1; dx++ {
+				screen.SetContent(x+dx, lineY, ' ', nil, selectedStyle)
+			}
+		}
+
+		// Category and key on the right, the category in a fixed column
+		catX := right - 7
+		drawUntil(screen, catX, lineY, item.Category, descStyle, right+1)
+		keyX := catX - 2 - runewidth.StringWidth(item.Key)
+		drawUntil(screen, keyX, lineY, item.Key, descStyle, catX)
+
+		// Name with the matched characters highlighted, then the description
+		matched := make(map[int]bool)
+		for _, idx := range result.MatchedIdx {
+			matched[idx] = true
+		}
+		nx := drawUntil(screen, x+1, lineY, prefix, nameStyle, keyX-1)
+		for j, r := range []rune(item.Name) {
+			style := nameStyle
+			if matched[j] {
+				style = hitStyle
+			}
+			nx = drawUntil(screen, nx, lineY, string(r), style, keyX-1)
+		}
+		if item.Description != "" {
+			drawUntil(screen, nx, lineY, "  "+item.Description, descStyle, keyX-1)
+		}
+	}
+
+	hints := "[Enter] Run  [↑↓] Select  [Esc] Close"
+	drawUntil(screen, x+(p.Width-runewidth.StringWidth(hints))/2, y+p.Height-2, hints, dimStyle, right)
+}
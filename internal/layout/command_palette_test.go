@@ -0,0 +1,89 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/ellery/thicc/internal/config"
+	"github.com/micro-editor/tcell/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// Command Palette Tests
+// =============================================================================
+
+func typeQuery(p *CommandPalette, query string) {
+	for _, r := range query {
+		p.HandleEvent(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone, ""))
+	}
+}
+
+func TestCommandPalette_ListsEverythingWithoutQuery(t *testing.T) {
+	p := NewCommandPalette(nil)
+	p.Show([]PaletteItem{{Name: "Quick Find"}, {Name: "Save"}, {Name: "goto"}})
+
+	require.Len(t, p.Results, 3)
+	assert.Equal(t, "Quick Find", p.Results[0].Item.Name, "in the order given")
+	assert.Equal(t, "goto", p.Results[2].Item.Name)
+}
+
+func TestCommandPalette_FiltersByName(t *testing.T) {
+	p := NewCommandPalette(nil)
+	p.Show([]PaletteItem{{Name: "Toggle Terminal"}, {Name: "Save"}, {Name: "SaveAll"}, {Name: "Sélection"}})
+
+	typeQuery(p, "sav")
+	require.Len(t, p.Results, 2)
+	assert.Equal(t, "Save", p.Results[0].Item.Name, "the closest match is first")
+	assert.Equal(t, []int{0, 1, 2}, p.Results[0].MatchedIdx)
+
+	p.HandleEvent(tcell.NewEventKey(tcell.KeyCtrlU, 0, tcell.ModNone, ""))
+	typeQuery(p, "én")
+	require.Len(t, p.Results, 1)
+	assert.Equal(t, []int{1, 8}, p.Results[0].MatchedIdx, "indexes count characters, not bytes")
+
+	p.HandleEvent(tcell.NewEventKey(tcell.KeyBackspace2, 0, tcell.ModNone, ""))
+	assert.Equal(t, "é", p.Query)
+}
+
+func TestCommandPalette_RunsSelection(t *testing.T) {
+	var ran string
+	p := NewCommandPalette(nil)
+	p.Show([]PaletteItem{
+		{Name: "Save", Run: func() { ran = "Save" }},
+		{Name: "SaveAll", Run: func() { ran = "SaveAll" }},
+	})
+
+	p.HandleEvent(tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone, ""))
+	p.HandleEvent(tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone, ""))
+	assert.Equal(t, 1, p.SelectedIdx, "stays on the last entry")
+
+	p.HandleEvent(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone, ""))
+	assert.Equal(t, "SaveAll", ran)
+	assert.False(t, p.Active, "closes before running")
+}
+
+func TestCommandPalette_EnterWithNoMatchesDoesNothing(t *testing.T) {
+	p := NewCommandPalette(nil)
+	p.Show([]PaletteItem{{Name: "Save", Run: func() { t.Fatal("ran") }}})
+
+	typeQuery(p, "xyz")
+	assert.Empty(t, p.Results)
+	p.HandleEvent(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone, ""))
+	assert.True(t, p.Active)
+}
+
+func TestPaletteItems_PaneKeysFollowBindings(t *testing.T) {
+	config.Bindings["buffer"]["F5"] = "OpenSettings"
+	t.Cleanup(func() { delete(config.Bindings["buffer"], "F5") })
+
+	keys := make(map[string]string)
+	for _, item := range newTestLayoutManager(100, 50).paletteItems() {
+		if item.Category == "Pane" {
+			keys[item.Name] = item.Key
+		}
+	}
+	assert.Equal(t, "F5", keys["Open Settings"], "the editor action's binding")
+	assert.Equal(t, `Ctrl+\ B`, keys["Toggle Blame"], "unbound, so the layout's shortcut")
+	assert.Equal(t, "Ctrl+P", keys["Quick Find"])
+}
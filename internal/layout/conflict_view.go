@@ -0,0 +1,425 @@
+package layout
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ellery/thicc/internal/action"
+	"github.com/ellery/thicc/internal/buffer"
+	"github.com/ellery/thicc/internal/config"
+	"github.com/ellery/thicc/internal/sourcecontrol"
+	"github.com/micro-editor/tcell/v2"
+)
+
+// ConflictView is the three-way merge resolver: the ours / base / theirs
+// versions of a conflicted file side by side above the editor, which holds
+// the file itself as the editable result
+type ConflictView struct {
+	Active  bool
+	Path    string // Absolute path of the conflicted file
+	RelPath string // Path relative to the repo root (for git)
+
+	// Versions from the index: :1: (common ancestor), :2: (ours), :3: (theirs)
+	Base   []string
+	Ours   []string
+	Theirs []string
+
+	Blocks  []sourcecontrol.ConflictBlock // Conflict blocks left in the result
+	Current int                           // Block the panes are showing
+
+	Region Region // Set by ConstrainEditorRegion each frame
+}
+
+// conflictSide is one of the three version panes
+type conflictSide int
+
+const (
+	sideOurs conflictSide = iota
+	sideBase
+	sideTheirs
+)
+
+// NewConflictView loads the three versions of a conflicted file
+func NewConflictView(repoRoot, relPath string) *ConflictView {
+	base, ours, theirs := sourcecontrol.ConflictVersions(repoRoot, relPath)
+	split := func(s string) []string {
+		if s == "" {
+			return nil
+		}
+		return strings.Split(s, "\n")
+	}
+	return &ConflictView{
+		Active:  true,
+		Path:    filepath.Join(repoRoot, relPath),
+		RelPath: relPath,
+		Base:    split(base),
+		Ours:    split(ours),
+		Theirs:  split(theirs),
+	}
+}
+
+// Sync re-reads the conflict blocks from the result's current lines
+func (cv *ConflictView) Sync(lines []string) {
+	cv.Blocks = sourcecontrol.ParseConflicts(lines)
+	if cv.Current >= len(cv.Blocks) {
+		cv.Current = len(cv.Blocks) - 1
+	}
+	if cv.Current < 0 {
+		cv.Current = 0
+	}
+}
+
+// Next moves to the next conflict block
+func (cv *ConflictView) Next() {
+	if cv.Current < len(cv.Blocks)-1 {
+		cv.Current++
+	}
+}
+
+// Prev moves to the previous conflict block
+func (cv *ConflictView) Prev() {
+	if cv.Current > 0 {
+		cv.Current--
+	}
+}
+
+// sideLines returns a version's full lines and the current block's lines for it
+func (cv *ConflictView) sideLines(side conflictSide) (file, block []string) {
+	if cv.Current >= len(cv.Blocks) {
+		return cv.sideFile(side), nil
+	}
+	blk := cv.Blocks[cv.Current]
+	switch side {
+	case sideOurs:
+		return cv.Ours, blk.Ours
+	case sideBase:
+		return cv.Base, blk.Base
+	}
+	return cv.Theirs, blk.Theirs
+}
+
+// sideFile returns a version's full lines
+func (cv *ConflictView) sideFile(side conflictSide) []string {
+	switch side {
+	case sideOurs:
+		return cv.Ours
+	case sideBase:
+		return cv.Base
+	}
+	return cv.Theirs
+}
+
+// locate returns where the current block sits in a version: the block's
+// lines for that side, searched for near where the result suggests they are.
+// Blocks before it take more lines in the result than in the version, so
+// the estimate is corrected by the difference.
+func (cv *ConflictView) locate(side conflictSide) (start, length int) {
+	file, want := cv.sideLines(side)
+	if cv.Current >= len(cv.Blocks) {
+		return 0, 0
+	}
+	hint := cv.Blocks[cv.Current].Start
+	for _, prev := range cv.Blocks[:cv.Current] {
+		var sideLen int
+		switch side {
+		case sideOurs:
+			sideLen = len(prev.Ours)
+		case sideBase:
+			sideLen = len(prev.Base)
+		default:
+			sideLen = len(prev.Theirs)
+		}
+		hint -= (prev.End - prev.Start + 1) - sideLen
+	}
+	return sourcecontrol.LocateLines(file, want, hint), len(want)
+}
+
+// conflictPaneHeight returns how many rows the panes take out of the
+// editor's height (0 when the editor is too short to share)
+func conflictPaneHeight(editorH int) int {
+	h := editorH * 40 / 100
+	if h > 16 {
+		h = 16
+	}
+	if editorH-h < 5 {
+		h = editorH - 5
+	}
+	if h < 5 {
+		return 0
+	}
+	return h
+}
+
+// Render draws the status line, the three version panes and a separator
+func (cv *ConflictView) Render(screen tcell.Screen, modified bool) {
+	r := cv.Region
+	if r.Height < 5 || r.Width < 30 {
+		return
+	}
+
+	bg := config.DefStyle
+	for y := r.Y; y < r.Y+r.Height; y++ {
+		for x := r.X; x < r.X+r.Width; x++ {
+			screen.SetContent(x, y, ' ', nil, bg)
+		}
+	}
+
+	// Status line: which conflict, and the actions
+	var status string
+	statusStyle := config.DefStyle.Foreground(tcell.ColorTeal).Bold(true)
+	switch {
+	case len(cv.Blocks) > 0:
+		status = fmt.Sprintf(" Conflict %d/%d  Alt+o:ours  Alt+t:theirs  Alt+b:both  Alt+j/k:next/prev  Alt+q:close",
+			cv.Current+1, len(cv.Blocks))
+	case modified:
+		status = " No conflicts left. Ctrl+S saves the file and marks it resolved"
+		statusStyle = config.DefStyle.Foreground(tcell.ColorGreen).Bold(true)
+	default:
+		status = " No conflicts left"
+		statusStyle = config.DefStyle.Foreground(tcell.ColorGreen).Bold(true)
+	}
+	drawClipped(screen, r.X, r.Y, r.Width, status, statusStyle)
+
+	// Three columns separated by a vertical line
+	colW := (r.Width - 2) / 3
+	sides := []struct {
+		side  conflictSide
+		title string
+		color tcell.Color
+		hl    tcell.Color
+	}{
+		{sideOurs, "OURS (:2)", tcell.Color40, tcell.Color22},
+		{sideBase, "BASE (:1)", tcell.ColorGray, tcell.Color236},
+		{sideTheirs, "THEIRS (:3)", tcell.Color39, tcell.Color17},
+	}
+	sepStyle := config.DefStyle.Foreground(tcell.Color240)
+	bodyTop, bodyH := r.Y+2, r.Height-3
+
+	for i, s := range sides {
+		x := r.X + i*(colW+1)
+		w := colW
+		if i == len(sides)-1 {
+			w = r.X + r.Width - x // Last column takes the remainder
+		}
+		if i > 0 {
+			for y := r.Y + 1; y < r.Y+r.Height-1; y++ {
+				screen.SetContent(x-1, y, '│', nil, sepStyle)
+			}
+		}
+		drawClipped(screen, x+1, r.Y+1, w-1, s.title, config.DefStyle.Foreground(s.color).Bold(true))
+
+		file := cv.sideFile(s.side)
+		if len(file) == 0 {
+			drawClipped(screen, x+1, bodyTop, w-1, "(not in this version)", config.DefStyle.Foreground(tcell.ColorGray))
+			continue
+		}
+
+		start, length := cv.locate(s.side)
+		top := start - 2 // A little context above the block
+		if top < 0 {
+			top = 0
+		}
+		for row := 0; row < bodyH && top+row < len(file); row++ {
+			ln := top + row
+			style := config.DefStyle.Foreground(tcell.Color252)
+			if ln >= start && ln < start+length {
+				style = config.DefStyle.Foreground(s.color).Background(s.hl)
+				for dx := 0; dx < w; dx++ {
+					screen.SetContent(x+dx, bodyTop+row, ' ', nil, style)
+				}
+			}
+			drawClipped(screen, x+1, bodyTop+row, w-1, strings.ReplaceAll(file[ln], "\t", "    "), style)
+		}
+	}
+
+	// Separator between the panes and the result
+	for x := r.X; x < r.X+r.Width; x++ {
+		screen.SetContent(x, r.Y+r.Height-1, '─', nil, sepStyle)
+	}
+	drawClipped(screen, r.X+1, r.Y+r.Height-1, r.Width-2, " RESULT ", config.DefStyle.Foreground(tcell.ColorTeal).Bold(true))
+}
+
+// drawClipped draws text at (x, y), cut off after w cells
+func drawClipped(screen tcell.Screen, x, y, w int, text string, style tcell.Style) {
+	i := 0
+	for _, r := range text {
+		if i >= w {
+			return
+		}
+		screen.SetContent(x+i, y, r, nil, style)
+		i++
+	}
+}
+
+// bufferLines returns a buffer's lines
+func bufferLines(buf *buffer.Buffer) []string {
+	lines := make([]string, buf.LinesNum())
+	for i := range lines {
+		lines[i] = buf.Line(i)
+	}
+	return lines
+}
+
+// replaceLines replaces lines start..end (inclusive) of a buffer, keeping
+// the edit undoable
+func replaceLines(buf *buffer.Buffer, start, end int, lines []string) {
+	text := strings.Join(lines, "\n")
+	from := buffer.Loc{X: 0, Y: start}
+	var to buffer.Loc
+	if end+1 < buf.LinesNum() {
+		to = buffer.Loc{X: 0, Y: end + 1}
+		if len(lines) > 0 {
+			text += "\n"
+		}
+	} else {
+		to = buf.End()
+		if len(lines) == 0 && start > 0 {
+			// Removing the last lines: take the newline before them too
+			from = buffer.Loc{X: utf8.RuneCountInString(buf.Line(start - 1)), Y: start - 1}
+		}
+	}
+	buf.Replace(from, to, text)
+}
+
+// OpenConflictResolver opens a conflicted file (relative to the project
+// root) in the editor with the three-way panes above it
+func (lm *LayoutManager) OpenConflictResolver(relPath string) {
+	cv := NewConflictView(lm.Root, relPath)
+	lm.ConflictView = cv
+	log.Printf("THICC: Resolving conflicts in %s", relPath)
+
+	lm.OpenFileAt(cv.Path, 0, 0)
+	if bp := lm.conflictPane(); bp != nil {
+		cv.Sync(bufferLines(bp.Buf))
+		lm.gotoConflict(bp)
+	}
+	lm.triggerRedraw()
+}
+
+// conflictPane returns the editor pane when it's showing the file being
+// resolved, or nil
+func (lm *LayoutManager) conflictPane() *action.BufPane {
+	cv := lm.ConflictView
+	if cv == nil || !cv.Active {
+		return nil
+	}
+	tab := action.MainTab()
+	if tab == nil {
+		return nil
+	}
+	for _, pane := range tab.Panes {
+		if bp, ok := pane.(*action.BufPane); ok && bp.Buf.AbsPath == cv.Path {
+			return bp
+		}
+	}
+	return nil
+}
+
+// gotoConflict moves the editor cursor to the current conflict block
+func (lm *LayoutManager) gotoConflict(bp *action.BufPane) {
+	cv := lm.ConflictView
+	if cv.Current < len(cv.Blocks) {
+		bp.GotoLoc(buffer.Loc{X: 0, Y: cv.Blocks[cv.Current].Start})
+	}
+}
+
+// handleConflictKey handles the resolver's shortcuts while the conflicted
+// file has focus (macOS Option+key arrives as a special character)
+func (lm *LayoutManager) handleConflictKey(ev *tcell.EventKey) bool {
+	if ev.Key() != tcell.KeyRune {
+		return false
+	}
+	bp := lm.conflictPane()
+	if bp == nil {
+		return false
+	}
+
+	alt := ev.Modifiers()&tcell.ModAlt != 0
+	key := ev.Rune()
+	switch {
+	case alt && key == 'o', key == 'ø':
+		lm.acceptConflict(bp, sourcecontrol.ResolveOurs)
+	case alt && key == 't', key == '†':
+		lm.acceptConflict(bp, sourcecontrol.ResolveTheirs)
+	case alt && key == 'b', key == '∫':
+		lm.acceptConflict(bp, sourcecontrol.ResolveBoth)
+	case alt && key == 'j', key == '∆':
+		lm.ConflictView.Next()
+		lm.gotoConflict(bp)
+	case alt && key == 'k', key == '˚':
+		lm.ConflictView.Prev()
+		lm.gotoConflict(bp)
+	case alt && key == 'q', key == 'œ':
+		lm.closeConflictView()
+	default:
+		return false
+	}
+	lm.triggerRedraw()
+	return true
+}
+
+// acceptConflict resolves the current block with one side (or both). Once
+// the last block is resolved the file is saved and staged.
+func (lm *LayoutManager) acceptConflict(bp *action.BufPane, res sourcecontrol.Resolution) {
+	cv := lm.ConflictView
+	cv.Sync(bufferLines(bp.Buf))
+	if cv.Current >= len(cv.Blocks) {
+		return
+	}
+
+	blk := cv.Blocks[cv.Current]
+	replaceLines(bp.Buf, blk.Start, blk.End, blk.Lines(res))
+	cv.Sync(bufferLines(bp.Buf))
+
+	if len(cv.Blocks) > 0 {
+		lm.gotoConflict(bp) // The next block now has the current index
+		return
+	}
+	if err := bp.Buf.Save(); err != nil {
+		action.InfoBar.Error("Could not save ", cv.RelPath, ": ", err)
+		return
+	}
+	lm.markConflictResolved()
+}
+
+// checkConflictResolved stages the file being resolved if it was saved
+// without conflict markers (called after Ctrl+S)
+func (lm *LayoutManager) checkConflictResolved() {
+	bp := lm.conflictPane()
+	if bp == nil || bp.Buf.Modified() {
+		return
+	}
+	lm.ConflictView.Sync(bufferLines(bp.Buf))
+	if len(lm.ConflictView.Blocks) == 0 {
+		lm.markConflictResolved()
+	}
+}
+
+// markConflictResolved stages the resolved file and closes the resolver
+func (lm *LayoutManager) markConflictResolved() {
+	cv := lm.ConflictView
+	if err := sourcecontrol.MarkResolved(lm.Root, cv.RelPath); err != nil {
+		action.InfoBar.Error("Could not stage ", cv.RelPath, ": ", err)
+		return
+	}
+	lm.closeConflictView()
+	lm.ShowTimedMessage("Resolved and staged "+cv.RelPath, 3*time.Second)
+
+	if lm.SourceControl != nil {
+		go func() {
+			lm.SourceControl.RefreshStatus()
+			lm.triggerRedraw()
+		}()
+	}
+}
+
+// closeConflictView hides the resolver's panes, leaving the file open
+func (lm *LayoutManager) closeConflictView() {
+	lm.ConflictView = nil
+	lm.ConstrainEditorRegion()
+	lm.triggerRedraw()
+}
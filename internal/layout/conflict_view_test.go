@@ -0,0 +1,50 @@
+package layout
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// =============================================================================
+// Conflict View Tests
+// =============================================================================
+
+func TestConflictView_LocateAcrossEarlierBlocks(t *testing.T) {
+	cv := &ConflictView{
+		Ours:   []string{"a", "ours 1", "b", "c", "ours 2", "d"},
+		Theirs: []string{"a", "theirs 1", "b", "c", "theirs 2", "d"},
+	}
+	result := "a\n<<<<<<< HEAD\nours 1\n=======\ntheirs 1\n>>>>>>> x\nb\nc\n" +
+		"<<<<<<< HEAD\nours 2\n=======\ntheirs 2\n>>>>>>> x\nd"
+	cv.Sync(strings.Split(result, "\n"))
+
+	assert.Len(t, cv.Blocks, 2)
+	cv.Next()
+	start, length := cv.locate(sideOurs)
+	assert.Equal(t, 4, start)
+	assert.Equal(t, 1, length)
+	start, _ = cv.locate(sideTheirs)
+	assert.Equal(t, 4, start)
+
+	// No diff3 base: the estimate is used
+	start, length = cv.locate(sideBase)
+	assert.Equal(t, 0, start, "clamped to the empty base")
+	assert.Equal(t, 0, length)
+}
+
+func TestConflictView_SyncClampsCurrent(t *testing.T) {
+	cv := &ConflictView{Current: 3}
+	cv.Sync([]string{"no conflicts"})
+	assert.Equal(t, 0, cv.Current)
+
+	cv.Prev()
+	assert.Equal(t, 0, cv.Current)
+}
+
+func TestConflictPaneHeight(t *testing.T) {
+	assert.Equal(t, 16, conflictPaneHeight(60))
+	assert.Equal(t, 8, conflictPaneHeight(20))
+	assert.Equal(t, 0, conflictPaneHeight(8), "too short to share")
+}
@@ -0,0 +1,1024 @@
+package layout
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ellery/thicc/internal/buffer"
+	"github.com/ellery/thicc/internal/filemanager"
+	"github.com/micro-editor/tcell/v2"
+)
+
+// contentSearchDelay is how long typing has to pause before a search starts
+const contentSearchDelay = 150 * time.Millisecond
+
+// contentSearchRow is one line of the results list: a file header (match
+// is -1) or one of its matches
+type contentSearchRow struct {
+	file  int
+	match int
+}
+
+// replaceKey identifies a file (line 0) or a match left out of a replace
+type replaceKey struct {
+	path      string
+	line, col int
+}
+
+// ContentSearchPanel is a modal for searching file contents across the
+// project (Alt+/), and replacing them (Alt+H in the panel). It also lists
+// fixed results such as the references a language server found.
+type ContentSearchPanel struct {
+	Active bool
+	Screen tcell.Screen
+	Root   string
+
+	// Title of fixed results shown with ShowResults, "" when searching
+	Results string
+
+	// Input field
+	Query     string
+	CursorPos int
+
+	// Options
+	Regex         bool
+	CaseSensitive bool
+	WholeWord     bool
+	Ripgrep       string // rg to search with, "" for the built-in search
+
+	// Replace field, shown below the query in replace mode
+	ReplaceMode      bool
+	Replacement      string
+	ReplaceCursorPos int
+	editReplace      bool // Typing goes to the replace field
+
+	// Results, filled in from the search goroutine (guarded by mu)
+	mu          sync.Mutex
+	Files       []filemanager.ContentFileMatches
+	MatchCount  int
+	Searching   bool
+	Truncated   bool
+	Err         string
+	SelectedIdx int // Index into rows()
+	TopLine     int
+	cancel      context.CancelFunc
+	generation  int
+	timer       *time.Timer
+	re          *regexp.Regexp      // The compiled query, for previews
+	excluded    map[replaceKey]bool // Files and matches left out of a replace
+	replacing   bool                // A replace is writing files
+
+	// Dimensions (recomputed from the screen size on render)
+	Width      int
+	Height     int
+	ListHeight int
+
+	// Option toggles on the input line, for clicks
+	optionX [3]int
+
+	// Callbacks
+	OnSelect  func(path string, line, col int)
+	OnCancel  func()
+	OnUpdate  func() // Results changed from the background
+	OnReplace func(replaced, files, skipped int, err error)
+
+	// Post runs a function on the UI goroutine; nil runs it right away
+	Post func(f func())
+}
+
+// NewContentSearchPanel creates a new content search panel
+func NewContentSearchPanel(screen tcell.Screen, root string, onSelect func(path string, line, col int), onCancel func()) *ContentSearchPanel {
+	return &ContentSearchPanel{
+		Screen:   screen,
+		Root:     root,
+		Ripgrep:  filemanager.RipgrepPath(),
+		OnSelect: onSelect,
+		OnCancel: onCancel,
+	}
+}
+
+// Show activates the panel, optionally starting with a query (such as the
+// editor's selection). The last query is kept otherwise.
+func (p *ContentSearchPanel) Show(query string) {
+	p.Active = true
+	p.Results = ""
+	if query != "" {
+		p.Query = query
+	}
+	p.CursorPos = len(p.Query)
+	p.startSearch(0)
+}
+
+// ShowResults activates the panel with a fixed list of matches under a title,
+// instead of a search
+func (p *ContentSearchPanel) ShowResults(title string, files []filemanager.ContentFileMatches) {
+	p.mu.Lock()
+	p.stopLocked()
+	p.generation++
+	p.Files = files
+	p.MatchCount = 0
+	for _, f := range files {
+		p.MatchCount += len(f.Matches)
+	}
+	p.Truncated = false
+	p.Err = ""
+	p.SelectedIdx = 0
+	if len(files) > 0 {
+		p.SelectedIdx = 1 // The first match, below its file header
+	}
+	p.TopLine = 0
+	p.re = nil
+	p.excluded = nil
+	p.mu.Unlock()
+
+	p.Active = true
+	p.Results = title
+	p.ReplaceMode = false
+	p.editReplace = false
+}
+
+// Hide deactivates the panel and stops a running search
+func (p *ContentSearchPanel) Hide() {
+	p.Active = false
+	p.mu.Lock()
+	p.stopLocked()
+	p.mu.Unlock()
+}
+
+// stopLocked cancels the pending or running search (must be called with mu
+// held)
+func (p *ContentSearchPanel) stopLocked() {
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+	if p.cancel != nil {
+		p.cancel()
+		p.cancel = nil
+	}
+	p.Searching = false
+}
+
+// startSearch clears the results and searches for the current query after
+// delay, replacing any search still running
+func (p *ContentSearchPanel) startSearch(delay time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stopLocked()
+	p.generation++
+	p.Files = nil
+	p.MatchCount = 0
+	p.Truncated = false
+	p.Err = ""
+	p.SelectedIdx = 0
+	p.TopLine = 0
+	p.re = nil
+	p.excluded = nil
+	if p.Query == "" {
+		return
+	}
+
+	opts := filemanager.ContentSearchOptions{
+		Query:         p.Query,
+		Regex:         p.Regex,
+		CaseSensitive: p.CaseSensitive,
+		WholeWord:     p.WholeWord,
+		Ripgrep:       p.Ripgrep,
+	}
+	// Report bad patterns right away instead of per backend
+	re, err := filemanager.CompileContentQuery(opts)
+	if err != nil {
+		p.Err = err.Error()
+		return
+	}
+	p.re = re
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.Searching = true
+	gen := p.generation
+	root := p.Root
+	p.timer = time.AfterFunc(delay, func() {
+		go p.runSearch(ctx, gen, root, opts)
+	})
+}
+
+// runSearch runs one search, streaming its results into the panel as long
+// as it is still the current one
+func (p *ContentSearchPanel) runSearch(ctx context.Context, gen int, root string, opts filemanager.ContentSearchOptions) {
+	lastUpdate := time.Now()
+	truncated, err := filemanager.SearchContent(ctx, root, opts, func(f filemanager.ContentFileMatches) {
+		p.mu.Lock()
+		if gen != p.generation {
+			p.mu.Unlock()
+			return
+		}
+		p.Files = append(p.Files, f)
+		p.MatchCount += len(f.Matches)
+		if len(p.Files) == 1 {
+			p.SelectedIdx = 1 // The first match, below its file header
+		}
+		p.mu.Unlock()
+
+		// Redraw now and then while results come in
+		if time.Since(lastUpdate) > 50*time.Millisecond {
+			lastUpdate = time.Now()
+			p.notify()
+		}
+	})
+
+	p.mu.Lock()
+	if gen != p.generation {
+		p.mu.Unlock()
+		return
+	}
+	p.Searching = false
+	p.Truncated = truncated
+	if err != nil && ctx.Err() == nil {
+		log.Printf("THICC Search: Search failed: %v", err)
+		p.Err = err.Error()
+	}
+	p.mu.Unlock()
+	p.notify()
+}
+
+// notify asks for a redraw
+func (p *ContentSearchPanel) notify() {
+	if p.OnUpdate != nil {
+		p.OnUpdate()
+	}
+}
+
+// rows flattens the results into list rows (must be called with mu held)
+func (p *ContentSearchPanel) rows() []contentSearchRow {
+	var rows []contentSearchRow
+	for i, f := range p.Files {
+		rows = append(rows, contentSearchRow{file: i, match: -1})
+		for j := range f.Matches {
+			rows = append(rows, contentSearchRow{file: i, match: j})
+		}
+	}
+	return rows
+}
+
+// HandleEvent processes input events
+func (p *ContentSearchPanel) HandleEvent(event tcell.Event) bool {
+	if !p.Active {
+		return false
+	}
+
+	switch ev := event.(type) {
+	case *tcell.EventKey:
+		return p.handleKey(ev)
+	case *tcell.EventMouse:
+		return p.handleMouse(ev)
+	}
+
+	return true // Consume all events while active
+}
+
+func (p *ContentSearchPanel) handleKey(ev *tcell.EventKey) bool {
+	// Fixed results can only be browsed
+	if p.Results != "" {
+		switch ev.Key() {
+		case tcell.KeyEscape, tcell.KeyEnter, tcell.KeyUp, tcell.KeyDown, tcell.KeyPgUp, tcell.KeyPgDn:
+		default:
+			return true
+		}
+	}
+
+	// Option toggles
+	if ev.Key() == tcell.KeyRune && ev.Modifiers()&tcell.ModAlt != 0 {
+		switch ev.Rune() {
+		case 'c', 'C':
+			p.CaseSensitive = !p.CaseSensitive
+			p.startSearch(0)
+		case 'w', 'W':
+			p.WholeWord = !p.WholeWord
+			p.startSearch(0)
+		case 'r', 'R':
+			p.Regex = !p.Regex
+			p.startSearch(0)
+		case 'h', 'H':
+			p.ReplaceMode = !p.ReplaceMode
+			p.editReplace = p.ReplaceMode
+		case 'x', 'X':
+			p.toggleSelected()
+		}
+		return true
+	}
+
+	switch ev.Key() {
+	case tcell.KeyEscape:
+		if p.OnCancel != nil {
+			p.OnCancel()
+		}
+		p.Hide()
+		return true
+
+	case tcell.KeyEnter:
+		if ev.Modifiers()&tcell.ModAlt != 0 {
+			if p.ReplaceMode {
+				p.replaceAll()
+			}
+			return true
+		}
+		p.openSelected()
+		return true
+
+	case tcell.KeyTab, tcell.KeyBacktab:
+		if p.ReplaceMode {
+			p.editReplace = !p.editReplace
+		}
+		return true
+
+	case tcell.KeyUp:
+		p.moveSelection(-1)
+		return true
+
+	case tcell.KeyDown:
+		p.moveSelection(1)
+		return true
+
+	case tcell.KeyPgUp:
+		p.moveSelection(-p.ListHeight)
+		return true
+
+	case tcell.KeyPgDn:
+		p.moveSelection(p.ListHeight)
+		return true
+	}
+
+	// Editing keys go to the focused field
+	text, pos := &p.Query, &p.CursorPos
+	if p.editReplace {
+		text, pos = &p.Replacement, &p.ReplaceCursorPos
+	}
+	delay := contentSearchDelay
+
+	switch ev.Key() {
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if *pos == 0 {
+			return true
+		}
+		_, size := utf8.DecodeLastRuneInString((*text)[:*pos])
+		*text = (*text)[:*pos-size] + (*text)[*pos:]
+		*pos -= size
+
+	case tcell.KeyDelete:
+		if *pos == len(*text) {
+			return true
+		}
+		_, size := utf8.DecodeRuneInString((*text)[*pos:])
+		*text = (*text)[:*pos] + (*text)[*pos+size:]
+
+	case tcell.KeyLeft:
+		if *pos > 0 {
+			_, size := utf8.DecodeLastRuneInString((*text)[:*pos])
+			*pos -= size
+		}
+		return true
+
+	case tcell.KeyRight:
+		if *pos < len(*text) {
+			_, size := utf8.DecodeRuneInString((*text)[*pos:])
+			*pos += size
+		}
+		return true
+
+	case tcell.KeyHome, tcell.KeyCtrlA:
+		*pos = 0
+		return true
+
+	case tcell.KeyEnd, tcell.KeyCtrlE:
+		*pos = len(*text)
+		return true
+
+	case tcell.KeyCtrlU:
+		// Clear input
+		*text = ""
+		*pos = 0
+		delay = 0
+
+	case tcell.KeyRune:
+		// Insert character at cursor
+		r := string(ev.Rune())
+		*text = (*text)[:*pos] + r + (*text)[*pos:]
+		*pos += len(r)
+
+	default:
+		return true
+	}
+
+	// The preview follows the replace field on the next render
+	if !p.editReplace {
+		p.startSearch(delay)
+	}
+	return true
+}
+
+// moveSelection moves the selection by delta rows, skipping file headers
+// unless they can be toggled for a replace
+func (p *ContentSearchPanel) moveSelection(delta int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	rows := p.rows()
+	if len(rows) == 0 {
+		return
+	}
+	idx := p.SelectedIdx + delta
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(rows) {
+		idx = len(rows) - 1
+	}
+	// Step off a header in the direction of travel (or back at the ends)
+	step := 1
+	if delta < 0 {
+		step = -1
+	}
+	for !p.ReplaceMode && idx >= 0 && idx < len(rows) && rows[idx].match < 0 {
+		idx += step
+	}
+	if idx < 0 || idx >= len(rows) {
+		return
+	}
+	p.SelectedIdx = idx
+	p.ensureVisibleLocked()
+}
+
+// ensureVisibleLocked scrolls the selection into view (must be called with
+// mu held)
+func (p *ContentSearchPanel) ensureVisibleLocked() {
+	if p.SelectedIdx < p.TopLine {
+		p.TopLine = p.SelectedIdx
+		// Keep the file header of the first match in view
+		if p.TopLine > 0 {
+			p.TopLine--
+		}
+	}
+	if p.SelectedIdx >= p.TopLine+p.ListHeight {
+		p.TopLine = p.SelectedIdx - p.ListHeight + 1
+	}
+}
+
+// selectedMatch returns the selected file and match, if a match is selected
+// (must be called with mu held)
+func (p *ContentSearchPanel) selectedMatch(rows []contentSearchRow) (filemanager.ContentFileMatches, filemanager.ContentMatch, bool) {
+	if p.SelectedIdx >= len(rows) {
+		return filemanager.ContentFileMatches{}, filemanager.ContentMatch{}, false
+	}
+	row := rows[p.SelectedIdx]
+	f := p.Files[row.file]
+	if row.match < 0 {
+		// A header: its first match
+		return f, f.Matches[0], true
+	}
+	return f, f.Matches[row.match], true
+}
+
+// openSelected opens the selected match in the editor
+func (p *ContentSearchPanel) openSelected() {
+	p.mu.Lock()
+	f, m, ok := p.selectedMatch(p.rows())
+	p.mu.Unlock()
+	if !ok {
+		return
+	}
+	p.Hide()
+	if p.OnSelect != nil {
+		p.OnSelect(f.Path, m.Line, m.Column)
+	}
+}
+
+// toggleSelected includes or leaves out the selected file or match when
+// replacing
+func (p *ContentSearchPanel) toggleSelected() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	rows := p.rows()
+	if !p.ReplaceMode || p.SelectedIdx >= len(rows) {
+		return
+	}
+	row := rows[p.SelectedIdx]
+	f := p.Files[row.file]
+	key := replaceKey{path: f.Path}
+	if row.match >= 0 {
+		m := f.Matches[row.match]
+		key.line, key.col = m.Line, m.Column
+	}
+	if p.excluded == nil {
+		p.excluded = make(map[replaceKey]bool)
+	}
+	if p.excluded[key] {
+		delete(p.excluded, key)
+	} else {
+		p.excluded[key] = true
+	}
+}
+
+// includedLocked returns true if a match will be replaced (must be called
+// with mu held)
+func (p *ContentSearchPanel) includedLocked(f filemanager.ContentFileMatches, m filemanager.ContentMatch) bool {
+	return !p.excluded[replaceKey{path: f.Path}] && !p.excluded[replaceKey{f.Path, m.Line, m.Column}]
+}
+
+// replacementLocked returns the text a match will be replaced with. In regex
+// mode the match is found again in its line to expand capture groups, the
+// same way the replace does (must be called with mu held).
+func (p *ContentSearchPanel) replacementLocked(m filemanager.ContentMatch) string {
+	if !p.Regex || p.re == nil {
+		return p.Replacement
+	}
+	for _, loc := range p.re.FindAllStringSubmatchIndex(m.Text, -1) {
+		if loc[0] == m.Start {
+			return string(p.re.ExpandString(nil, p.Replacement, m.Text, loc))
+		}
+	}
+	return p.Replacement
+}
+
+// replaceAll replaces the included matches in the results and searches
+// again. Open files are changed through their buffers, so the replace can be
+// undone there; the others are rewritten in the background. A match is only
+// replaced if its text is still what the search found at its line and
+// column, since an open buffer may have unsaved edits.
+func (p *ContentSearchPanel) replaceAll() {
+	p.mu.Lock()
+	re := p.re
+	if re == nil || p.replacing {
+		p.mu.Unlock()
+		return
+	}
+	type fileReplace struct {
+		path, relPath string
+		keep          map[[2]int]string // Line and column -> matched text
+	}
+	var todo []fileReplace
+	kept := 0
+	for _, f := range p.Files {
+		keep := make(map[[2]int]string)
+		for _, m := range f.Matches {
+			if p.includedLocked(f, m) {
+				keep[[2]int{m.Line, m.Column}] = m.Text[m.Start:m.End]
+			}
+		}
+		if len(keep) > 0 {
+			todo = append(todo, fileReplace{f.Path, f.RelPath, keep})
+			kept += len(keep)
+		}
+	}
+	replacement := []byte(p.Replacement)
+	captureGroups := p.Regex
+	query := p.Query
+	p.replacing = true
+	p.mu.Unlock()
+
+	replaced, files := 0, 0
+	var firstErr error
+	replaceIn := func(fr fileReplace, open bool) {
+		keep := func(line, col int, text string) bool {
+			want, ok := fr.keep[[2]int{line, col}]
+			return ok && want == text
+		}
+		var n int
+		var err error
+		if open {
+			n, err = buffer.ReplaceInFile(fr.path, re, replacement, captureGroups, keep)
+		} else {
+			n, err = buffer.ReplaceOnDisk(fr.path, re, replacement, captureGroups, keep)
+		}
+		if err != nil {
+			log.Printf("THICC Replace: Failed in %s: %v", fr.path, err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", filepath.ToSlash(fr.relPath), err)
+			}
+			return
+		}
+		replaced += n
+		if n > 0 {
+			files++
+		}
+	}
+
+	var closed []fileReplace
+	for _, fr := range todo {
+		if buffer.FindOpenBuffer(fr.path) == nil {
+			closed = append(closed, fr)
+			continue
+		}
+		replaceIn(fr, true)
+	}
+
+	go func() {
+		for _, fr := range closed {
+			replaceIn(fr, false)
+		}
+		p.post(func() {
+			log.Printf("THICC Replace: %q with %q, %d replacements in %d files", query, replacement, replaced, files)
+			p.mu.Lock()
+			p.replacing = false
+			p.mu.Unlock()
+			if p.OnReplace != nil {
+				p.OnReplace(replaced, files, kept-replaced, firstErr)
+			}
+			p.startSearch(0)
+		})
+	}()
+}
+
+// post runs f on the UI goroutine
+func (p *ContentSearchPanel) post(f func()) {
+	if p.Post != nil {
+		p.Post(f)
+	} else {
+		f()
+	}
+}
+
+func (p *ContentSearchPanel) handleMouse(ev *tcell.EventMouse) bool {
+	x, y := ev.Position()
+	w, h := p.Screen.Size()
+	p.resize(w, h)
+
+	modalX := (w - p.Width) / 2
+	modalY := (h - p.Height) / 2
+
+	switch ev.Buttons() {
+	case tcell.WheelUp, tcell.WheelDown:
+		p.mu.Lock()
+		rows := len(p.rows())
+		if ev.Buttons() == tcell.WheelUp {
+			p.TopLine -= 3
+		} else {
+			p.TopLine += 3
+		}
+		if p.TopLine > rows-p.ListHeight {
+			p.TopLine = rows - p.ListHeight
+		}
+		if p.TopLine < 0 {
+			p.TopLine = 0
+		}
+		p.mu.Unlock()
+		return true
+	case tcell.Button1:
+	default:
+		return true
+	}
+
+	// Click outside - cancel
+	if x < modalX || x >= modalX+p.Width || y < modalY || y >= modalY+p.Height {
+		if p.OnCancel != nil {
+			p.OnCancel()
+		}
+		p.Hide()
+		return true
+	}
+
+	// Option toggles on the input line
+	if y == modalY+2 && p.Results == "" {
+		switch {
+		case x >= p.optionX[0] && x < p.optionX[0]+4:
+			p.CaseSensitive = !p.CaseSensitive
+		case x >= p.optionX[1] && x < p.optionX[1]+4:
+			p.WholeWord = !p.WholeWord
+		case x >= p.optionX[2] && x < p.optionX[2]+4:
+			p.Regex = !p.Regex
+		default:
+			return true
+		}
+		p.startSearch(0)
+		return true
+	}
+
+	// Click on a replace field focuses it
+	if p.ReplaceMode && (y == modalY+2 || y == modalY+3) {
+		p.editReplace = y == modalY+3
+		return true
+	}
+
+	// Click in the list opens the match, or toggles it on its checkbox
+	listY := modalY + p.listOffset()
+	if y >= listY && y < listY+p.ListHeight {
+		p.mu.Lock()
+		rows := p.rows()
+		idx := p.TopLine + (y - listY)
+		if idx >= len(rows) {
+			p.mu.Unlock()
+			return true
+		}
+		p.SelectedIdx = idx
+		checkX := modalX + 4
+		if rows[idx].match < 0 {
+			checkX = modalX + 2
+		}
+		p.mu.Unlock()
+		if p.ReplaceMode && x >= checkX && x < checkX+3 {
+			p.toggleSelected()
+			return true
+		}
+		p.openSelected()
+	}
+	return true
+}
+
+// listOffset is how far below the top of the modal the results start
+func (p *ContentSearchPanel) listOffset() int {
+	if p.ReplaceMode {
+		return 5
+	}
+	return 4
+}
+
+// resize fits the modal to the screen
+func (p *ContentSearchPanel) resize(w, h int) {
+	p.Width = w - 8
+	if p.Width > 120 {
+		p.Width = 120
+	}
+	p.Height = h - 4
+	if p.Height > 34 {
+		p.Height = 34
+	}
+	p.ListHeight = p.Height - 3 - p.listOffset()
+	if p.ListHeight < 1 {
+		p.ListHeight = 1
+	}
+}
+
+// Render draws the content search panel
+func (p *ContentSearchPanel) Render(screen tcell.Screen) {
+	if !p.Active {
+		return
+	}
+
+	w, h := screen.Size()
+	p.resize(w, h)
+	if p.Width < 30 || p.Height < 10 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Calculate position (centered)
+	x := (w - p.Width) / 2
+	y := (h - p.Height) / 2
+
+	// Colors (explicit fg AND bg to prevent issues in light mode)
+	bgColor := tcell.ColorBlack
+	borderColor := tcell.Color51 // Cyan
+	textColor := tcell.ColorWhite
+	dimColor := tcell.Color245       // Gray
+	highlightColor := tcell.Color226 // Yellow
+	matchColor := tcell.Color205     // Hot pink for matched text
+	removedColor := tcell.Color203   // Red for replaced text
+	addedColor := tcell.Color114     // Green for its replacement
+
+	bgStyle := tcell.StyleDefault.Foreground(textColor).Background(bgColor)
+	borderStyle := tcell.StyleDefault.Foreground(borderColor).Background(bgColor).Bold(true)
+	titleStyle := tcell.StyleDefault.Foreground(borderColor).Background(bgColor).Bold(true)
+	cursorStyle := tcell.StyleDefault.Foreground(bgColor).Background(textColor)
+	optionStyle := tcell.StyleDefault.Foreground(dimColor).Background(bgColor)
+	optionOnStyle := tcell.StyleDefault.Foreground(bgColor).Background(borderColor).Bold(true)
+	fileStyle := tcell.StyleDefault.Foreground(borderColor).Background(bgColor).Bold(true)
+	dimStyle := tcell.StyleDefault.Foreground(dimColor).Background(bgColor)
+	lineStyle := tcell.StyleDefault.Foreground(textColor).Background(bgColor)
+	matchStyle := tcell.StyleDefault.Foreground(matchColor).Background(bgColor).Bold(true)
+	removedStyle := tcell.StyleDefault.Foreground(removedColor).Background(bgColor).StrikeThrough(true)
+	addedStyle := tcell.StyleDefault.Foreground(addedColor).Background(bgColor).Bold(true)
+	selectedStyle := tcell.StyleDefault.Foreground(bgColor).Background(highlightColor)
+	selectedMatchStyle := selectedStyle.Bold(true).Underline(true)
+	errorStyle := tcell.StyleDefault.Foreground(tcell.Color196).Background(bgColor)
+	statusStyle := tcell.StyleDefault.Foreground(highlightColor).Background(bgColor)
+
+	// Background and frame
+	listY := y + p.listOffset()
+	for dy := 0; dy < p.Height; dy++ {
+		for dx := 0; dx < p.Width; dx++ {
+			screen.SetContent(x+dx, y+dy, ' ', nil, bgStyle)
+		}
+	}
+	drawFrameLine(screen, x, y, p.Width, '╔', '═', '╗', borderStyle)
+	drawFrameLine(screen, x, y+1, p.Width, '╠', '═', '╣', borderStyle)
+	drawFrameLine(screen, x, listY-1, p.Width, '╠', '─', '╣', borderStyle)
+	drawFrameLine(screen, x, y+p.Height-3, p.Width, '╠', '─', '╣', borderStyle)
+	drawFrameLine(screen, x, y+p.Height-1, p.Width, '╚', '═', '╝', borderStyle)
+	for i := 2; i < p.Height-1; i++ {
+		if y+i == listY-1 || i == p.Height-3 {
+			continue
+		}
+		screen.SetContent(x, y+i, '║', nil, borderStyle)
+		screen.SetContent(x+p.Width-1, y+i, '║', nil, borderStyle)
+	}
+
+	title := " Search in Files "
+	if p.ReplaceMode {
+		title = " Replace in Files "
+	} else if p.Results != "" {
+		title = " " + p.Results + " "
+	}
+	drawUntil(screen, x+(p.Width-len(title))/2, y, title, titleStyle, x+p.Width-1)
+
+	// Option toggles, right-aligned on the input line, and the inputs (fixed
+	// results have neither)
+	inputY := y + 2
+	if p.Results == "" {
+		options := []struct {
+			label string
+			on    bool
+		}{
+			{" Aa ", p.CaseSensitive},
+			{" ab ", p.WholeWord},
+			{" .* ", p.Regex},
+		}
+		optX := x + p.Width - 2 - 3*len(options[0].label) - 2
+		for i, opt := range options {
+			style := optionStyle
+			if opt.on {
+				style = optionOnStyle
+			}
+			p.optionX[i] = optX
+			drawUntil(screen, optX, inputY, opt.label, style, x+p.Width-1)
+			optX += len(opt.label) + 1
+		}
+
+		// Inputs with cursor
+		if p.ReplaceMode {
+			col := drawUntil(screen, x+2, inputY, "Find    ", dimStyle, x+p.Width-1)
+			drawInput(screen, col, inputY, p.Query, p.CursorPos, !p.editReplace, bgStyle, cursorStyle, p.optionX[0]-1)
+			col = drawUntil(screen, x+2, inputY+1, "Replace ", dimStyle, x+p.Width-1)
+			drawInput(screen, col, inputY+1, p.Replacement, p.ReplaceCursorPos, p.editReplace, bgStyle, cursorStyle, x+p.Width-2)
+		} else {
+			col := drawUntil(screen, x+2, inputY, "> ", bgStyle, x+p.Width-1)
+			drawInput(screen, col, inputY, p.Query, p.CursorPos, true, bgStyle, cursorStyle, p.optionX[0]-1)
+		}
+	}
+
+	// Results
+	right := x + p.Width - 2
+	rows := p.rows()
+	switch {
+	case p.Err != "":
+		drawUntil(screen, x+3, listY, p.Err, errorStyle, right)
+	case len(rows) == 0 && p.Query != "" && !p.Searching:
+		msg := "No results"
+		drawUntil(screen, x+(p.Width-len(msg))/2, listY+p.ListHeight/2, msg, dimStyle, right)
+	}
+	replacing := 0
+	for _, f := range p.Files {
+		for _, m := range f.Matches {
+			if p.includedLocked(f, m) {
+				replacing++
+			}
+		}
+	}
+	for i := 0; i < p.ListHeight && p.TopLine+i < len(rows); i++ {
+		idx := p.TopLine + i
+		row := rows[idx]
+		lineY := listY + i
+		f := p.Files[row.file]
+
+		isSelected := idx == p.SelectedIdx
+		if isSelected {
+			for dx := x + 1; dx < x+p.Width-1; dx++ {
+				screen.SetContent(dx, lineY, ' ', nil, selectedStyle)
+			}
+		}
+
+		if row.match < 0 {
+			headerStyle, countStyle := fileStyle, dimStyle
+			if isSelected {
+				headerStyle, countStyle = selectedMatchStyle, selectedStyle
+			}
+			col := x + 2
+			if p.ReplaceMode {
+				col = drawUntil(screen, col, lineY, checkbox(!p.excluded[replaceKey{path: f.Path}])+" ", countStyle, right)
+			}
+			col = drawUntil(screen, col, lineY, f.RelPath, headerStyle, right)
+			drawUntil(screen, col+1, lineY, fmt.Sprintf("(%d)", len(f.Matches)), countStyle, right)
+			continue
+		}
+
+		m := f.Matches[row.match]
+		included := p.ReplaceMode && p.includedLocked(f, m)
+		numStyle, textStyle, hitStyle, newStyle := dimStyle, lineStyle, matchStyle, addedStyle
+		if included {
+			hitStyle = removedStyle
+		} else if p.ReplaceMode {
+			textStyle, hitStyle = dimStyle, dimStyle
+		}
+		if isSelected {
+			numStyle, textStyle, hitStyle, newStyle = selectedStyle, selectedStyle, selectedMatchStyle, selectedMatchStyle
+			if included {
+				hitStyle = selectedStyle.StrikeThrough(true)
+			}
+		}
+
+		col := x + 4
+		if p.ReplaceMode {
+			col = drawUntil(screen, col, lineY, checkbox(included), numStyle, right)
+		}
+		col = drawUntil(screen, col, lineY, fmt.Sprintf("%5d  ", m.Line), numStyle, right)
+		// Drop leading indentation so previews line up
+		trimmed := strings.TrimLeft(m.Text[:m.Start], " \t")
+		col = drawUntil(screen, col, lineY, trimmed, textStyle, right)
+		col = drawUntil(screen, col, lineY, m.Text[m.Start:m.End], hitStyle, right)
+		if included {
+			// The change as an inline diff: the match struck out, then what
+			// replaces it
+			col = drawUntil(screen, col, lineY, p.replacementLocked(m), newStyle, right)
+		}
+		drawUntil(screen, col, lineY, m.Text[m.End:], textStyle, right)
+	}
+
+	// Status and hints
+	hintY := y + p.Height - 2
+	status := ""
+	switch {
+	case p.Searching:
+		status = fmt.Sprintf("Searching... %d matches", p.MatchCount)
+	case p.MatchCount > 0:
+		status = fmt.Sprintf("%d matches in %d files", p.MatchCount, len(p.Files))
+		if p.Truncated {
+			status = fmt.Sprintf("First %d matches in %d files", p.MatchCount, len(p.Files))
+		}
+		if p.ReplaceMode {
+			status += fmt.Sprintf(", replacing %d", replacing)
+		}
+	}
+	col := drawUntil(screen, x+2, hintY, status, statusStyle, right)
+	hints := "[Enter] Open  [Alt+H] Replace  [Alt+C] Case  [Alt+W] Word  [Alt+R] Regex  [Esc] Close"
+	if p.Results != "" {
+		hints = "[Enter] Open  [Esc] Close"
+	} else if p.ReplaceMode {
+		hints = "[Alt+Enter] Replace all  [Alt+X] Include/exclude  [Tab] Field  [Alt+H] Search  [Esc] Close"
+	}
+	hintX := x + p.Width - 2 - len(hints)
+	if hintX > col+2 {
+		drawUntil(screen, hintX, hintY, hints, dimStyle, right)
+	}
+}
+
+// drawInput draws a text field from x up to maxX, scrolling long text so the
+// cursor (at byte offset pos) stays visible
+func drawInput(screen tcell.Screen, x, y int, text string, pos int, focused bool, style, cursorStyle tcell.Style, maxX int) {
+	runes := []rune(text)
+	cursor := utf8.RuneCountInString(text[:pos])
+	if room := maxX - x - 1; room > 0 && cursor > room {
+		runes = runes[cursor-room:]
+		cursor = room
+	}
+	drawUntil(screen, x, y, string(runes), style, maxX)
+	if focused && x+cursor < maxX {
+		ch := ' '
+		if cursor < len(runes) {
+			ch = runes[cursor]
+		}
+		screen.SetContent(x+cursor, y, ch, nil, cursorStyle)
+	}
+}
+
+// checkbox returns the checkbox of a file or match in replace mode
+func checkbox(on bool) string {
+	if on {
+		return "[x]"
+	}
+	return "[ ]"
+}
+
+// drawFrameLine draws one horizontal line of a modal frame
+func drawFrameLine(screen tcell.Screen, x, y, width int, left, fill, right rune, style tcell.Style) {
+	screen.SetContent(x, y, left, nil, style)
+	for i := 1; i < width-1; i++ {
+		screen.SetContent(x+i, y, fill, nil, style)
+	}
+	screen.SetContent(x+width-1, y, right, nil, style)
+}
+
+// drawUntil draws text from x up to (not including) maxX, turning tabs into
+// spaces, and returns the column after it
+func drawUntil(screen tcell.Screen, x, y int, text string, style tcell.Style, maxX int) int {
+	for _, ch := range text {
+		if x >= maxX {
+			break
+		}
+		if ch == '\t' {
+			ch = ' '
+		}
+		screen.SetContent(x, y, ch, nil, style)
+		x++
+	}
+	return x
+}
@@ -0,0 +1,160 @@
+package layout
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ellery/thicc/internal/filemanager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// Content Search Panel Tests
+// =============================================================================
+
+func TestContentSearch_SelectionSkipsFileHeaders(t *testing.T) {
+	p := &ContentSearchPanel{ListHeight: 10, SelectedIdx: 1}
+	p.Files = []filemanager.ContentFileMatches{
+		{Path: "/a.go", Matches: []filemanager.ContentMatch{{Line: 1}, {Line: 5}}},
+		{Path: "/b.go", Matches: []filemanager.ContentMatch{{Line: 2}}},
+	}
+
+	p.moveSelection(1)
+	assert.Equal(t, 2, p.SelectedIdx)
+	p.moveSelection(1)
+	assert.Equal(t, 4, p.SelectedIdx, "b.go's header is stepped over")
+	p.moveSelection(1)
+	assert.Equal(t, 4, p.SelectedIdx, "stays on the last match")
+	p.moveSelection(-1)
+	assert.Equal(t, 2, p.SelectedIdx)
+	p.moveSelection(-10)
+	assert.Equal(t, 2, p.SelectedIdx, "the first row is a header, so nothing moves")
+}
+
+func TestContentSearch_OpensMatch(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\tfunc needle() {}\n"), 0644))
+
+	var opened string
+	var line, col int
+	p := NewContentSearchPanel(nil, dir, func(path string, l, c int) {
+		opened, line, col = path, l, c
+	}, nil)
+	p.Ripgrep = "" // The built-in search, whether or not rg is installed
+	done := make(chan struct{}, 10)
+	p.OnUpdate = func() { done <- struct{}{} }
+
+	p.Show("needle")
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("search didn't finish")
+	}
+
+	p.openSelected()
+	assert.Equal(t, filepath.Join(dir, "main.go"), opened)
+	assert.Equal(t, 2, line)
+	assert.Equal(t, 7, col)
+	assert.False(t, p.Active)
+}
+
+// =============================================================================
+// Replace Tests
+// =============================================================================
+
+// replaceResults returns a panel in replace mode with two files of results
+func replaceResults() *ContentSearchPanel {
+	p := &ContentSearchPanel{ListHeight: 10, SelectedIdx: 1, ReplaceMode: true}
+	p.Files = []filemanager.ContentFileMatches{
+		{Path: "/a.go", Matches: []filemanager.ContentMatch{{Line: 1, Column: 1}, {Line: 5, Column: 3}}},
+		{Path: "/b.go", Matches: []filemanager.ContentMatch{{Line: 2, Column: 1}}},
+	}
+	return p
+}
+
+func TestContentSearch_ReplaceSelectsFileHeaders(t *testing.T) {
+	p := replaceResults()
+
+	p.moveSelection(2)
+	assert.Equal(t, 3, p.SelectedIdx, "headers can be selected to toggle the file")
+	p.moveSelection(-10)
+	assert.Equal(t, 0, p.SelectedIdx)
+}
+
+func TestContentSearch_ToggleExcludes(t *testing.T) {
+	p := replaceResults()
+	a, b := p.Files[0], p.Files[1]
+
+	p.toggleSelected()
+	assert.False(t, p.includedLocked(a, a.Matches[0]))
+	assert.True(t, p.includedLocked(a, a.Matches[1]))
+
+	p.SelectedIdx = 3 // b.go's header
+	p.toggleSelected()
+	assert.False(t, p.includedLocked(b, b.Matches[0]), "the whole file is left out")
+
+	p.toggleSelected()
+	p.SelectedIdx = 1
+	p.toggleSelected()
+	assert.True(t, p.includedLocked(a, a.Matches[0]))
+	assert.True(t, p.includedLocked(b, b.Matches[0]))
+}
+
+func TestContentSearch_ReplacementPreview(t *testing.T) {
+	p := &ContentSearchPanel{Query: `get(\w+)`, Regex: true, Replacement: "fetch$1"}
+	p.startSearch(time.Hour) // Compiles the query without searching yet
+	defer p.Hide()
+
+	m := filemanager.ContentMatch{Text: "getName() + getID()", Start: 12, End: 17}
+	assert.Equal(t, "fetchID", p.replacementLocked(m), "capture groups of the match itself")
+
+	p.Regex = false
+	assert.Equal(t, "fetch$1", p.replacementLocked(m), "literal without regex")
+}
+
+func TestContentSearch_ReplaceSkipsChangedMatches(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("old()\nold()\n"), 0644))
+
+	p := NewContentSearchPanel(nil, dir, nil, nil)
+	p.Ripgrep = ""
+	updated := make(chan struct{}, 10)
+	p.OnUpdate = func() { updated <- struct{}{} }
+	posted := make(chan func(), 1)
+	p.Post = func(f func()) { posted <- f }
+	var replaced, skipped int
+	p.OnReplace = func(r, files, s int, err error) {
+		require.NoError(t, err)
+		replaced, skipped = r, s
+	}
+
+	p.Show("old")
+	select {
+	case <-updated:
+	case <-time.After(5 * time.Second):
+		t.Fatal("search didn't finish")
+	}
+	require.Equal(t, 2, p.MatchCount)
+
+	// The first line changes after the search
+	require.NoError(t, os.WriteFile(path, []byte("xold()\nold()\n"), 0644))
+	p.Replacement = "new"
+	p.replaceAll()
+	select {
+	case f := <-posted:
+		f()
+	case <-time.After(5 * time.Second):
+		t.Fatal("replace didn't finish")
+	}
+	p.Hide()
+
+	assert.Equal(t, 1, replaced)
+	assert.Equal(t, 1, skipped)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "xold()\nnew()\n", string(data), "only the match the search saw is replaced")
+}
@@ -0,0 +1,137 @@
+package layout
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ellery/thicc/internal/action"
+	"github.com/ellery/thicc/internal/buffer"
+	"github.com/ellery/thicc/internal/terminal"
+)
+
+// scrollbackName returns the tab name for an exported terminal (panel 2-4)
+func scrollbackName(panel int, now time.Time) string {
+	return fmt.Sprintf("Terminal %d %s", panel-1, now.Format("15.04.05"))
+}
+
+// scrollbackFileName returns the default file name for saving a terminal
+// (.ansi when the colors are kept)
+func scrollbackFileName(panel int, now time.Time, ansi bool) string {
+	ext := ".log"
+	if ansi {
+		ext = ".ansi"
+	}
+	return fmt.Sprintf("terminal-%d-%s%s", panel-1, now.Format("20060102-150405"), ext)
+}
+
+// askExportFormat asks whether a terminal export keeps colors as ANSI
+// escape codes, then calls export with the answer
+func (lm *LayoutManager) askExportFormat(title string, export func(ansi bool)) {
+	lm.ShowModal(title, "Keep colors as ANSI escape codes?", func(yes, canceled bool) {
+		if !canceled {
+			export(yes)
+		}
+	})
+}
+
+// ExportTerminalToTab asks for the format, then opens the active terminal's
+// scrollback and screen in a new read-only editor tab, with the cursor at
+// the end
+func (lm *LayoutManager) ExportTerminalToTab() bool {
+	term := lm.getActiveTerminal()
+	if term == nil {
+		return false
+	}
+
+	panel := lm.ActivePanel
+	lm.askExportFormat("Export Scrollback", func(ansi bool) {
+		lm.openScrollbackTab(term, panel, ansi)
+	})
+	return true
+}
+
+// openScrollbackTab opens a terminal's scrollback and screen in a new
+// read-only editor tab
+func (lm *LayoutManager) openScrollbackTab(term *terminal.Panel, panel int, ansi bool) {
+	name := scrollbackName(panel, time.Now())
+	buf := buffer.NewBufferFromString(term.Export(ansi), name, buffer.BTHelp)
+	log.Printf("THICC: Exported terminal %d scrollback to tab (%d lines, ansi=%v)", panel, buf.LinesNum(), ansi)
+
+	if !lm.EditorVisible {
+		lm.EditorVisible = true
+		lm.updateLayout()
+	}
+	if lm.TabBar != nil {
+		lm.TabBar.AddTab(buf)
+	}
+	lm.displayBufferInEditor(buf)
+	lm.FocusEditor()
+
+	if tab := action.MainTab(); tab != nil {
+		for _, pane := range tab.Panes {
+			if bp, ok := pane.(*action.BufPane); ok {
+				bp.GotoLoc(buf.End())
+				break
+			}
+		}
+	}
+	lm.triggerRedraw()
+}
+
+// SaveTerminalScrollback asks for the format and a path, then saves the
+// active terminal's scrollback and screen to it
+func (lm *LayoutManager) SaveTerminalScrollback() bool {
+	term := lm.getActiveTerminal()
+	if term == nil {
+		return false
+	}
+
+	panel := lm.ActivePanel
+	lm.askExportFormat("Save Scrollback", func(ansi bool) {
+		lm.askScrollbackPath(term, panel, ansi)
+	})
+	return true
+}
+
+// askScrollbackPath asks where to save a terminal's scrollback
+func (lm *LayoutManager) askScrollbackPath(term *terminal.Panel, panel int, ansi bool) {
+	defaultName := scrollbackFileName(panel, time.Now(), ansi)
+	lm.ShowInputModal("Save Scrollback", "File:", defaultName, func(path string, canceled bool) {
+		if canceled || path == "" {
+			return
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(lm.Root, path)
+		}
+
+		if _, err := os.Stat(path); err == nil {
+			lm.ShowModal("Save Scrollback",
+				fmt.Sprintf("%s already exists. Overwrite it?", filepath.Base(path)),
+				func(yes, canceled bool) {
+					if yes && !canceled {
+						lm.writeScrollback(term, path, ansi)
+					}
+				})
+			return
+		}
+		lm.writeScrollback(term, path, ansi)
+	})
+}
+
+// writeScrollback writes a terminal's scrollback and screen to path
+func (lm *LayoutManager) writeScrollback(term *terminal.Panel, path string, ansi bool) {
+	text := term.Export(ansi)
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		action.InfoBar.Error("Save scrollback failed: " + err.Error())
+		return
+	}
+	log.Printf("THICC: Saved terminal scrollback to %s (%d bytes)", path, len(text))
+	lm.ShowTimedMessage("Saved scrollback to "+filepath.Base(path), 3*time.Second)
+	if lm.FileBrowser != nil {
+		lm.FileBrowser.Tree.Refresh()
+	}
+	lm.triggerRedraw()
+}
@@ -0,0 +1,20 @@
+package layout
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// =============================================================================
+// Scrollback Export Tests
+// =============================================================================
+
+func TestScrollbackNames(t *testing.T) {
+	now := time.Date(2026, 3, 4, 15, 6, 7, 0, time.Local)
+
+	assert.Equal(t, "Terminal 2 15.06.07", scrollbackName(3, now))
+	assert.Equal(t, "terminal-1-20260304-150607.log", scrollbackFileName(2, now, false))
+	assert.Equal(t, "terminal-1-20260304-150607.ansi", scrollbackFileName(2, now, true))
+}
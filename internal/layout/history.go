@@ -0,0 +1,86 @@
+package layout
+
+import (
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ellery/thicc/internal/action"
+	"github.com/ellery/thicc/internal/buffer"
+	"github.com/ellery/thicc/internal/sourcecontrol"
+)
+
+// ShowFileHistory opens the Source Control panel on the history of a file
+func (lm *LayoutManager) ShowFileHistory(absPath string) {
+	rel, err := filepath.Rel(lm.Root, absPath)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		lm.ShowTimedMessage("File is outside the project", 2*time.Second)
+		return
+	}
+	log.Printf("THICC: Showing history of %s", rel)
+
+	if !lm.SourceControlVisible {
+		lm.ToggleSourceControl()
+	} else {
+		lm.setActivePanel(0)
+	}
+	lm.triggerRedraw()
+	sc := lm.SourceControl
+	if sc == nil {
+		return
+	}
+
+	// git log --follow can take a while in a large repository
+	repoRoot := sc.RepoRoot
+	go func() {
+		path := filepath.ToSlash(rel)
+		var revs []sourcecontrol.FileRevision
+		repoPath, err := sourcecontrol.RepoRelPath(absPath)
+		if err == nil {
+			path = repoPath
+			revs, err = sourcecontrol.FileHistory(repoRoot, path)
+		}
+		lm.post(func() {
+			if lm.SourceControl != sc || sc.RepoRoot != repoRoot {
+				return // Switched projects in the meantime
+			}
+			sc.ShowFileHistory(path, revs, err)
+			lm.triggerRedraw()
+		})
+	}()
+}
+
+// activeFilePath returns the file the history quick command applies to: the
+// editor's file or the file selected in the tree
+func (lm *LayoutManager) activeFilePath() string {
+	switch {
+	case lm.ActivePanel == 1:
+		if bp := lm.editorPane(); bp != nil && bp.Buf.Type == buffer.BTDefault {
+			return bp.Buf.AbsPath
+		}
+	case lm.ActivePanel == 0 && lm.TreeVisible && lm.FileBrowser != nil:
+		if node := lm.FileBrowser.GetSelectedNode(); node != nil && !node.IsDir {
+			return node.Path
+		}
+	}
+	return ""
+}
+
+// openRevision shows a file as it was at a commit in a new read-only tab
+func (lm *LayoutManager) openRevision(path, hash, content string) {
+	revBuf := action.NewRevisionBuffer(content, path, hash)
+	if revBuf == nil {
+		return
+	}
+
+	lm.EditorVisible = true
+	lm.updatePanelRegions()
+	if lm.TabBar != nil {
+		lm.TabBar.AddTab(revBuf)
+	}
+	lm.displayBufferInEditor(revBuf)
+
+	// Keep focus on SC so user can keep browsing
+	lm.triggerRedraw()
+}
@@ -0,0 +1,157 @@
+package layout
+
+import (
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/micro-editor/tcell/v2"
+)
+
+// HoverPopup shows the documentation of a symbol from the language server.
+// The arrow keys scroll it and any other key closes it.
+type HoverPopup struct {
+	Active  bool
+	Title   string
+	Text    string
+	ScreenW int
+	ScreenH int
+
+	topLine  int
+	lines    []string // Text wrapped to the width of the box
+	maxLines int      // Lines that fit in the box, for scrolling
+}
+
+// NewHoverPopup creates a new hover popup
+func NewHoverPopup() *HoverPopup {
+	return &HoverPopup{}
+}
+
+// Show displays text in the popup
+func (p *HoverPopup) Show(title, text string, screenW, screenH int) {
+	p.Active = true
+	p.Title = title
+	p.Text = text
+	p.ScreenW = screenW
+	p.ScreenH = screenH
+	p.topLine = 0
+	p.lines = nil
+}
+
+// Hide closes the popup
+func (p *HoverPopup) Hide() {
+	p.Active = false
+}
+
+// HandleEvent processes events for the popup
+// Returns true if the event was consumed
+func (p *HoverPopup) HandleEvent(event tcell.Event) bool {
+	if !p.Active {
+		return false
+	}
+
+	switch ev := event.(type) {
+	case *tcell.EventKey:
+		switch ev.Key() {
+		case tcell.KeyUp:
+			p.scroll(-1)
+		case tcell.KeyDown:
+			p.scroll(1)
+		case tcell.KeyPgUp:
+			p.scroll(-p.maxLines)
+		case tcell.KeyPgDn:
+			p.scroll(p.maxLines)
+		default:
+			p.Hide()
+		}
+	case *tcell.EventMouse:
+		switch ev.Buttons() {
+		case tcell.WheelUp:
+			p.scroll(-3)
+		case tcell.WheelDown:
+			p.scroll(3)
+		case tcell.Button1:
+			p.Hide()
+		}
+	}
+	return true // Consume all events while active
+}
+
+// scroll moves the text by delta lines
+func (p *HoverPopup) scroll(delta int) {
+	p.topLine = max(0, min(p.topLine+delta, len(p.lines)-p.maxLines))
+}
+
+// wrap breaks text into lines of at most width cells, at spaces where it can
+func wrap(text string, width int) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\t", "    "), "\n") {
+		for runewidth.StringWidth(line) > width {
+			cut := runewidth.Truncate(line, width, "")
+			if cut == "" {
+				break
+			}
+			if i := strings.LastIndexByte(cut, ' '); i > 0 {
+				cut = cut[:i]
+			}
+			lines = append(lines, cut)
+			line = strings.TrimLeft(line[len(cut):], " ")
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Render draws the popup centered on screen
+func (p *HoverPopup) Render(screen tcell.Screen) {
+	if !p.Active {
+		return
+	}
+
+	boxWidth := min(84, p.ScreenW-4)
+	if boxWidth < 20 {
+		return
+	}
+	p.lines = wrap(p.Text, boxWidth-4)
+	boxHeight := min(len(p.lines)+4, p.ScreenH-4)
+	p.maxLines = boxHeight - 4
+	if p.maxLines < 1 {
+		return
+	}
+	p.scroll(0)
+
+	startX := (p.ScreenW - boxWidth) / 2
+	startY := (p.ScreenH - boxHeight) / 2
+
+	// Styles - all must have explicit fg AND bg to prevent color changes in light mode
+	bgColor := tcell.ColorBlack
+	borderStyle := tcell.StyleDefault.Foreground(tcell.Color51).Background(bgColor) // Cyan
+	bgStyle := tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(bgColor)
+	titleStyle := tcell.StyleDefault.Foreground(tcell.Color51).Background(bgColor).Bold(true)
+	hintStyle := tcell.StyleDefault.Foreground(tcell.Color243).Background(bgColor) // Dim gray
+
+	for y := startY; y < startY+boxHeight; y++ {
+		for x := startX; x < startX+boxWidth; x++ {
+			screen.SetContent(x, y, ' ', nil, bgStyle)
+		}
+	}
+	drawFrameLine(screen, startX, startY, boxWidth, '╭', '─', '╮', borderStyle)
+	drawFrameLine(screen, startX, startY+boxHeight-1, boxWidth, '╰', '─', '╯', borderStyle)
+	for y := startY + 1; y < startY+boxHeight-1; y++ {
+		screen.SetContent(startX, y, '│', nil, borderStyle)
+		screen.SetContent(startX+boxWidth-1, y, '│', nil, borderStyle)
+	}
+
+	right := startX + boxWidth - 2
+	title := " " + p.Title + " "
+	drawUntil(screen, startX+2, startY, title, titleStyle, right)
+
+	for i := 0; i < p.maxLines && p.topLine+i < len(p.lines); i++ {
+		drawUntil(screen, startX+2, startY+2+i, p.lines[p.topLine+i], bgStyle, right)
+	}
+
+	hint := "Esc to close"
+	if len(p.lines) > p.maxLines {
+		hint = "↑↓ to scroll, Esc to close"
+	}
+	drawUntil(screen, startX+boxWidth-2-len([]rune(hint)), startY+boxHeight-1, hint, hintStyle, right)
+}
@@ -55,17 +55,20 @@ func (m *InputModal) HandleEvent(event tcell.Event) bool {
 
 	switch ev.Key() {
 	case tcell.KeyEscape:
-		if m.Callback != nil {
-			m.Callback("", true)
-		}
+		// Hide before calling back, so the callback can show another prompt
+		callback := m.Callback
 		m.Hide()
+		if callback != nil {
+			callback("", true)
+		}
 		return true
 
 	case tcell.KeyEnter:
-		if m.Callback != nil {
-			m.Callback(m.Value, false)
-		}
+		callback, value := m.Callback, m.Value
 		m.Hide()
+		if callback != nil {
+			callback(value, false)
+		}
 		return true
 
 	case tcell.KeyBackspace, tcell.KeyBackspace2:
@@ -0,0 +1,158 @@
+package layout
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ellery/thicc/internal/action"
+	"github.com/ellery/thicc/internal/buffer"
+	"github.com/ellery/thicc/internal/filemanager"
+	"github.com/ellery/thicc/internal/lsp"
+	"github.com/ellery/thicc/internal/util"
+)
+
+// lspBuffer returns the editor's buffer if a language server follows it,
+// or reports that there is none
+func (lm *LayoutManager) lspBuffer() *buffer.Buffer {
+	bp := lm.editorPane()
+	if bp == nil {
+		return nil
+	}
+	if !lsp.Default.HasServer(bp.Buf) {
+		lm.ShowTimedMessage("No language server for this file", 2*time.Second)
+		return nil
+	}
+	return bp.Buf
+}
+
+// lspFailed reports a failed language server request
+func (lm *LayoutManager) lspFailed(what string, err error) {
+	log.Printf("THICC LSP: %s failed: %v", what, err)
+	action.InfoBar.Error(what+" failed: ", err)
+}
+
+// GoToDefinition opens where the symbol at the editor's cursor is defined
+func (lm *LayoutManager) GoToDefinition() {
+	b := lm.lspBuffer()
+	if b == nil {
+		return
+	}
+	lsp.Default.Definition(b, func(targets []lsp.Target, err error) {
+		switch {
+		case err != nil:
+			lm.lspFailed("Go to definition", err)
+		case len(targets) == 0:
+			lm.ShowTimedMessage("No definition found", 2*time.Second)
+		case len(targets) == 1:
+			t := targets[0]
+			lm.OpenFileAt(t.Path, t.Start.Y+1, t.Start.X+1)
+		default:
+			lm.showTargets("Definitions", targets)
+		}
+	})
+}
+
+// FindReferences lists where the symbol at the editor's cursor is used
+func (lm *LayoutManager) FindReferences() {
+	b := lm.lspBuffer()
+	if b == nil {
+		return
+	}
+	lsp.Default.References(b, func(targets []lsp.Target, err error) {
+		switch {
+		case err != nil:
+			lm.lspFailed("Find references", err)
+		case len(targets) == 0:
+			lm.ShowTimedMessage("No references found", 2*time.Second)
+		default:
+			lm.showTargets(fmt.Sprintf("References (%d)", len(targets)), targets)
+		}
+	})
+}
+
+// showTargets lists locations in the search panel, grouped by file
+func (lm *LayoutManager) showTargets(title string, targets []lsp.Target) {
+	if lm.ContentSearch == nil {
+		return
+	}
+
+	var files []filemanager.ContentFileMatches
+	for _, t := range targets {
+		if len(files) == 0 || files[len(files)-1].Path != t.Path {
+			rel, err := filepath.Rel(lm.Root, t.Path)
+			if err != nil || strings.HasPrefix(rel, "..") {
+				rel = t.Path
+			}
+			files = append(files, filemanager.ContentFileMatches{Path: t.Path, RelPath: rel})
+		}
+		line := []byte(t.Line)
+		start := len(line) - len(util.SliceEnd(line, t.Start.X))
+		end := len(line) - len(util.SliceEnd(line, t.End.X))
+		f := &files[len(files)-1]
+		f.Matches = append(f.Matches, filemanager.NewContentMatch(t.Start.Y+1, t.Line, start, end))
+	}
+
+	lm.ContentSearch.Root = lm.Root
+	lm.ContentSearch.ShowResults(title, files)
+}
+
+// ShowHover shows the documentation of the symbol at the editor's cursor
+func (lm *LayoutManager) ShowHover() {
+	b := lm.lspBuffer()
+	if b == nil {
+		return
+	}
+	word := wordAtCursor(b)
+	lsp.Default.Hover(b, func(text string, err error) {
+		switch {
+		case err != nil:
+			lm.lspFailed("Hover", err)
+		case text == "":
+			lm.ShowTimedMessage("No information here", 2*time.Second)
+		case lm.HoverPopup != nil:
+			lm.HoverPopup.Show(word, text, lm.ScreenW, lm.ScreenH)
+		}
+	})
+}
+
+// RenameSymbol asks for a new name for the symbol at the editor's cursor and
+// renames it across the project
+func (lm *LayoutManager) RenameSymbol() {
+	b := lm.lspBuffer()
+	if b == nil {
+		return
+	}
+	word := wordAtCursor(b)
+	lm.ShowInputModal("Rename Symbol", "New name:", word, func(name string, canceled bool) {
+		name = strings.TrimSpace(name)
+		if canceled || name == "" || name == word {
+			return
+		}
+		lsp.Default.Rename(b, name, func(edits, files int, err error) {
+			if err != nil {
+				lm.lspFailed("Rename", err)
+				return
+			}
+			log.Printf("THICC LSP: Renamed %q to %q with %d edits in %d files", word, name, edits, files)
+			action.InfoBar.Message(fmt.Sprintf("Renamed %s to %s: %d edits in %d files", word, name, edits, files))
+		})
+	})
+}
+
+// wordAtCursor returns the word the cursor is in or next to
+func wordAtCursor(b *buffer.Buffer) string {
+	c := b.GetActiveCursor()
+	line := []rune(b.Line(c.Y))
+	start := min(c.X, len(line))
+	for start > 0 && util.IsWordChar(line[start-1]) {
+		start--
+	}
+	end := min(c.X, len(line))
+	for end < len(line) && util.IsWordChar(line[end]) {
+		end++
+	}
+	return string(line[start:end])
+}
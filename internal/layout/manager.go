@@ -8,6 +8,7 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/ellery/thicc/internal/action"
 	"github.com/ellery/thicc/internal/buffer"
@@ -17,6 +18,7 @@ import (
 	"github.com/ellery/thicc/internal/dashboard"
 	"github.com/ellery/thicc/internal/filebrowser"
 	"github.com/ellery/thicc/internal/filemanager"
+	"github.com/ellery/thicc/internal/lsp"
 	"github.com/ellery/thicc/internal/sourcecontrol"
 	"github.com/ellery/thicc/internal/terminal"
 	"github.com/ellery/thicc/internal/thicc"
@@ -74,9 +76,12 @@ type LayoutManager struct {
 	InputModal     *InputModal
 	ConfirmModal   *ConfirmModal
 	ShortcutsModal *ShortcutsModal
+	HoverPopup     *HoverPopup
 	LoadingOverlay *LoadingOverlay
 	ProjectPicker  *dashboard.ProjectPicker
 	QuickFindPicker *QuickFindPicker
+	ContentSearch   *ContentSearchPanel
+	CommandPalette  *CommandPalette
 
 	// File index for quick find
 	FileIndex *filemanager.FileIndex
@@ -87,6 +92,9 @@ type LayoutManager struct {
 	// Pane navigation bar at top of screen
 	PaneNavBar *PaneNavBar
 
+	// Three-way merge resolver shown above a conflicted file (nil when closed)
+	ConflictView *ConflictView
+
 	// Layout configuration
 	TreeWidth         int // Left panel width (fixed at 30)
 	TreeWidthExpanded int // Expanded tree width when focused with single pane
@@ -146,6 +154,17 @@ type LayoutManager struct {
 	// aiToolEverSpawned tracks if ANY AI tool was spawned this session
 	// Enables dynamic detection on quit (vs fast-path when only shells used)
 	aiToolEverSpawned bool
+
+	// Per-project session restore (see session.go)
+	sessionEnabled bool     // Save the workspace on quit/project switch
+	pendingSession *Session // Loaded before Initialize, applied by RestoreSession
+
+	// Detached daemon sessions to reattach, by terminal panel (see attach.go)
+	pendingAttach map[int]string
+
+	// Post runs a function on the main loop. Work done in the background
+	// hands its results to the UI through it.
+	Post func(func())
 }
 
 // NewLayoutManager creates a new layout manager
@@ -169,6 +188,7 @@ func NewLayoutManager(root string) *LayoutManager {
 		InputModal:      NewInputModal(),
 		ConfirmModal:    NewConfirmModal(),
 		ShortcutsModal:  NewShortcutsModal(),
+		HoverPopup:      NewHoverPopup(),
 		LoadingOverlay:  NewLoadingOverlay(),
 		ProjectPicker:   nil, // Initialized when screen is available
 		TabBar:          NewTabBar(),
@@ -181,6 +201,9 @@ func NewLayoutManager(root string) *LayoutManager {
 	// Start idle checker goroutine
 	go lm.idleChecker()
 
+	// Start checkpointing AI turns (see checkpoints.go)
+	go lm.checkpointWatcher()
+
 	return lm
 }
 
@@ -300,13 +323,14 @@ func (lm *LayoutManager) PreloadTerminal(screenW, screenH int) {
 	}
 
 	log.Println("THICC: Preloading terminal panel in background")
+	attachID := lm.takeAttachSession(2)
 	go func() {
 		// Use configured AI tool command, or default shell if nil
 		cmdArgs := lm.AIToolCommand
 		if cmdArgs != nil && len(cmdArgs) > 0 {
 			log.Printf("THICC: Auto-launching AI tool: %v", cmdArgs)
 		}
-		term, err := terminal.NewPanel(termX, 1, termW, lm.ScreenH-1, cmdArgs)
+		term, err := lm.newTerminalPanel(2, attachID, termX, termW, cmdArgs, "")
 		if err != nil {
 			log.Printf("THICC: Failed to preload terminal: %v", err)
 			return
@@ -360,6 +384,10 @@ func (lm *LayoutManager) setupTerminalCallbacks(term *terminal.Panel) {
 		lm.cycleFocus()
 		lm.triggerRedraw()
 	}
+	term.OnOpenFile = func(path string, line, col int) {
+		lm.OpenFileAt(path, line, col)
+		lm.triggerRedraw()
+	}
 	term.OnSessionEnd = func() {
 		// Called when terminal process exits - hide pane and reset to show tool selector next time
 		lm.mu.Lock()
@@ -461,6 +489,39 @@ func (lm *LayoutManager) handleQuickCommand(ev *tcell.EventKey) bool {
 				lm.triggerRedraw()
 				return true
 			}
+			// Save scrollback to a file - terminal
+			if lm.ActivePanel >= 2 && lm.SaveTerminalScrollback() {
+				log.Println("THICC: Quick command - Save Scrollback")
+				lm.triggerRedraw()
+				return true
+			}
+		case 'b', 'B':
+			// Toggle the blame gutter - only works in editor
+			if lm.ActivePanel == 1 {
+				log.Println("THICC: Quick command - Toggle Blame")
+				lm.ToggleBlame()
+				lm.triggerRedraw()
+				return true
+			}
+		case 'c', 'C':
+			// Open the commit of the cursor's line - only works in editor with blame on
+			if lm.ActivePanel == 1 && lm.OpenBlameCommit() {
+				log.Println("THICC: Quick command - Blame Commit")
+				return true
+			}
+		case 'h', 'H':
+			// File history - works in editor and tree
+			if path := lm.activeFilePath(); path != "" {
+				log.Println("THICC: Quick command - File History")
+				lm.ShowFileHistory(path)
+				return true
+			}
+		case 'e', 'E':
+			// Export scrollback to a read-only tab - only works in terminal
+			if lm.ActivePanel >= 2 && lm.ExportTerminalToTab() {
+				log.Println("THICC: Quick command - Export Scrollback")
+				return true
+			}
 		case 'n', 'N':
 			// New file - only works in tree
 			if lm.ActivePanel == 0 && lm.FileBrowser != nil {
@@ -469,8 +530,28 @@ func (lm *LayoutManager) handleQuickCommand(ev *tcell.EventKey) bool {
 				lm.triggerRedraw()
 				return true
 			}
+		case 'g', 'G':
+			// Go to definition - only works in editor
+			if lm.ActivePanel == 1 {
+				log.Println("THICC: Quick command - Go to Definition")
+				lm.GoToDefinition()
+				return true
+			}
+		case 'k', 'K':
+			// Hover documentation - only works in editor
+			if lm.ActivePanel == 1 {
+				log.Println("THICC: Quick command - Hover")
+				lm.ShowHover()
+				return true
+			}
 		case 'f', 'F':
-			// New folder - only works in tree
+			// Find references - editor
+			if lm.ActivePanel == 1 {
+				log.Println("THICC: Quick command - Find References")
+				lm.FindReferences()
+				return true
+			}
+			// New folder - tree
 			if lm.ActivePanel == 0 && lm.FileBrowser != nil {
 				log.Println("THICC: Quick command - New Folder")
 				lm.FileBrowser.NewFolderSelected()
@@ -486,13 +567,55 @@ func (lm *LayoutManager) handleQuickCommand(ev *tcell.EventKey) bool {
 				return true
 			}
 		case 'r', 'R':
-			// Rename - only works in tree
+			// Rename symbol - editor
+			if lm.ActivePanel == 1 {
+				log.Println("THICC: Quick command - Rename Symbol")
+				lm.RenameSymbol()
+				return true
+			}
+			// Rename - tree
 			if lm.ActivePanel == 0 && lm.FileBrowser != nil {
 				log.Println("THICC: Quick command - Rename")
 				lm.FileBrowser.RenameSelected()
 				lm.triggerRedraw()
 				return true
 			}
+		case '[', ']':
+			// Jump between shell prompts - only works in terminal
+			if term := lm.getActiveTerminal(); term != nil && lm.ActivePanel >= 2 {
+				if ev.Rune() == '[' {
+					term.ScrollToPreviousPrompt()
+				} else {
+					term.ScrollToNextPrompt()
+				}
+				lm.triggerRedraw()
+				return true
+			}
+		case 'l', 'L':
+			// Label file references for opening - only works in terminal
+			if term := lm.getActiveTerminal(); term != nil && lm.ActivePanel >= 2 {
+				if term.EnterLinkMode() == 0 {
+					lm.ShowTimedMessage("No file references on screen", 2*time.Second)
+				}
+				lm.triggerRedraw()
+				return true
+			}
+		case '/':
+			// Search scrollback - only works in terminal
+			if term := lm.getActiveTerminal(); term != nil && lm.ActivePanel >= 2 {
+				term.EnterSearchMode()
+				lm.triggerRedraw()
+				return true
+			}
+		case 'o', 'O':
+			// Select last command's output - only works in terminal
+			if term := lm.getActiveTerminal(); term != nil && lm.ActivePanel >= 2 {
+				if !term.SelectLastCommandOutput() {
+					lm.ShowTimedMessage("No command output to select", 2*time.Second)
+				}
+				lm.triggerRedraw()
+				return true
+			}
 		case 'p', 'P':
 			// Passthrough mode - only works in terminal
 			if lm.ActivePanel >= 2 && lm.ActivePanel <= 4 {
@@ -530,11 +653,11 @@ func (lm *LayoutManager) RenderQuickCommandHints(s tcell.Screen) {
 	var hints string
 	switch lm.ActivePanel {
 	case 0: // Tree
-		hints = "  N File   F Folder   D Delete   R Rename   Q Quit   [Space] Next   ESC Cancel"
+		hints = "  N File   F Folder   D Delete   R Rename   H History   Q Quit   [Space] Next   ESC Cancel"
 	case 1: // Editor
-		hints = "  S Save   W Close   Q Quit   [Space] Next   ESC Cancel"
+		hints = "  S Save   W Close   G Definition   F References   K Hover   R Rename   B Blame   C Line Commit   H History   Q Quit   [Space] Next   ESC Cancel"
 	default: // Terminal (2, 3, 4)
-		hints = "  P Passthrough   Q Quit   [Space] Next   ESC Cancel"
+		hints = "  P Passthrough   / Search   L Open Link   [ ] Prompts   O Output   E Export   S Save   Q Quit   [Space] Next   ESC Cancel"
 	}
 
 	x := 0
@@ -972,13 +1095,14 @@ func (lm *LayoutManager) Initialize(screen tcell.Screen) error {
 	} else {
 		// Create terminal asynchronously (to avoid blocking UI)
 		log.Println("THICC: Creating terminal panel asynchronously")
+		attachID := lm.takeAttachSession(2)
 		go func() {
 			// Use configured AI tool command, or default shell if nil
 			cmdArgs := lm.AIToolCommand
 			if cmdArgs != nil && len(cmdArgs) > 0 {
 				log.Printf("THICC: Auto-launching AI tool: %v", cmdArgs)
 			}
-			term, err := terminal.NewPanel(termX, 1, termW, lm.ScreenH-1, cmdArgs)
+			term, err := lm.newTerminalPanel(2, attachID, termX, termW, cmdArgs, "")
 			if err != nil {
 				log.Printf("THICC: Failed to create terminal: %v", err)
 				return
@@ -1051,6 +1175,37 @@ func (lm *LayoutManager) Initialize(screen tcell.Screen) error {
 	)
 	log.Println("THICC: Quick find picker initialized")
 
+	lm.CommandPalette = NewCommandPalette(screen)
+
+	// Initialize content search panel
+	lm.ContentSearch = NewContentSearchPanel(screen, lm.Root,
+		func(path string, line, col int) {
+			lm.OpenFileAt(path, line, col)
+			// Also select the file in the file browser
+			if lm.FileBrowser != nil {
+				lm.FileBrowser.SelectFile(path)
+			}
+			lm.triggerRedraw()
+		},
+		func() {
+			lm.triggerRedraw()
+		},
+	)
+	lm.ContentSearch.OnUpdate = lm.triggerRedraw
+	lm.ContentSearch.Post = lm.post
+	lm.ContentSearch.OnReplace = func(replaced, files, skipped int, err error) {
+		if err != nil {
+			action.InfoBar.Error("Replace failed in ", err)
+			return
+		}
+		msg := fmt.Sprintf("Replaced %d matches in %d files", replaced, files)
+		if skipped > 0 {
+			msg += fmt.Sprintf(" (%d skipped: changed since the search)", skipped)
+		}
+		action.InfoBar.Message(msg)
+	}
+	log.Printf("THICC: Content search initialized (ripgrep: %q)", lm.ContentSearch.Ripgrep)
+
 	log.Println("THICC: Layout initialization complete")
 	return nil
 }
@@ -1137,6 +1292,12 @@ func (lm *LayoutManager) RenderOverlay(screen tcell.Screen) {
 			lm.TabBar.Focused = (lm.ActivePanel == 1)
 			lm.TabBar.Render(screen)
 		}
+
+		// Draw the conflict resolver's panes above a conflicted file
+		if bp := lm.conflictPane(); bp != nil {
+			lm.ConflictView.Sync(bufferLines(bp.Buf))
+			lm.ConflictView.Render(screen, bp.Buf.Modified())
+		}
 	}
 
 	// Draw loading overlay on top of everything (full screen)
@@ -1169,6 +1330,16 @@ func (lm *LayoutManager) RenderOverlay(screen tcell.Screen) {
 		lm.QuickFindPicker.Render(screen)
 	}
 
+	// Draw content search on top of everything
+	if lm.ContentSearch != nil && lm.ContentSearch.Active {
+		lm.ContentSearch.Render(screen)
+	}
+
+	// Draw command palette on top of everything
+	if lm.CommandPalette != nil && lm.CommandPalette.Active {
+		lm.CommandPalette.Render(screen)
+	}
+
 	// Draw tool selector modal centered over the entire terminal region
 	if lm.ShowingToolSelector && lm.ToolSelector != nil && lm.ToolSelector.IsActive() {
 		termX := lm.getTermX()
@@ -1180,6 +1351,11 @@ func (lm *LayoutManager) RenderOverlay(screen tcell.Screen) {
 	if lm.ShortcutsModal != nil && lm.ShortcutsModal.Active {
 		lm.ShortcutsModal.Render(screen)
 	}
+
+	// Draw language server hover on top of everything
+	if lm.HoverPopup != nil && lm.HoverPopup.Active {
+		lm.HoverPopup.Render(screen)
+	}
 }
 
 
@@ -1216,6 +1392,16 @@ func (lm *LayoutManager) HandleEvent(event tcell.Event) bool {
 		return lm.QuickFindPicker.HandleEvent(event)
 	}
 
+	// Handle content search
+	if lm.ContentSearch != nil && lm.ContentSearch.Active {
+		return lm.ContentSearch.HandleEvent(event)
+	}
+
+	// Handle command palette
+	if lm.CommandPalette != nil && lm.CommandPalette.Active {
+		return lm.CommandPalette.HandleEvent(event)
+	}
+
 	// Handle tool selector modal
 	if lm.ShowingToolSelector && lm.ToolSelector != nil && lm.ToolSelector.IsActive() {
 		return lm.ToolSelector.HandleEvent(event)
@@ -1226,6 +1412,11 @@ func (lm *LayoutManager) HandleEvent(event tcell.Event) bool {
 		return lm.ShortcutsModal.HandleEvent(event)
 	}
 
+	// Handle language server hover
+	if lm.HoverPopup != nil && lm.HoverPopup.Active {
+		return lm.HoverPopup.HandleEvent(event)
+	}
+
 	// Handle quick command mode
 	if lm.QuickCommandMode {
 		if ev, ok := event.(*tcell.EventKey); ok {
@@ -1263,6 +1454,10 @@ func (lm *LayoutManager) HandleEvent(event tcell.Event) bool {
 			log.Println("THICC: ESC+a raw sequence detected, toggling source control")
 			lm.ToggleSourceControl()
 			return true
+		case "\x1b/":
+			log.Println("THICC: ESC+/ raw sequence detected, showing content search")
+			lm.ShowContentSearch()
+			return true
 		}
 	}
 
@@ -1294,6 +1489,10 @@ func (lm *LayoutManager) HandleEvent(event tcell.Event) bool {
 			log.Println("THICC: macOS Option+a detected, toggling source control")
 			lm.ToggleSourceControl()
 			return true
+		case '÷': // Option+/ on macOS
+			log.Println("THICC: macOS Option+/ detected, showing content search")
+			lm.ShowContentSearch()
+			return true
 		}
 	}
 
@@ -1361,6 +1560,7 @@ func (lm *LayoutManager) HandleEvent(event tcell.Event) bool {
 				(ev.Rune() == '5' && ev.Modifiers()&tcell.ModAlt != 0) ||
 				(ev.Rune() == 'a' && ev.Modifiers()&tcell.ModAlt != 0) ||
 				(ev.Rune() == ',' && ev.Modifiers()&tcell.ModAlt != 0) ||
+				(ev.Rune() == '/' && ev.Modifiers()&tcell.ModAlt != 0) ||
 				(ev.Rune() == '/' && ev.Modifiers()&tcell.ModCtrl != 0) ||
 				ev.Key() == tcell.KeyCtrlUnderscore // Ctrl+/ often sends this
 
@@ -1467,6 +1667,25 @@ func (lm *LayoutManager) HandleEvent(event tcell.Event) bool {
 			return lm.SaveCurrentBuffer()
 		}
 
+		// F12 goes to the definition, Shift+F12 finds references when editor is focused
+		if ev.Key() == tcell.KeyF12 && lm.ActivePanel == 1 {
+			if ev.Modifiers()&tcell.ModShift != 0 {
+				log.Println("THICC: Shift+F12 detected, finding references")
+				lm.FindReferences()
+			} else {
+				log.Println("THICC: F12 detected, going to definition")
+				lm.GoToDefinition()
+			}
+			return true
+		}
+
+		// F1 for the command palette (works globally)
+		if ev.Key() == tcell.KeyF1 {
+			log.Println("THICC: F1 detected, showing command palette")
+			lm.ShowCommandPalette()
+			return true
+		}
+
 		// Ctrl+P for quick find (works globally)
 		if ev.Key() == tcell.KeyCtrlP {
 			log.Println("THICC: Ctrl+P detected, showing quick find")
@@ -1565,6 +1784,10 @@ func (lm *LayoutManager) HandleEvent(event tcell.Event) bool {
 				log.Println("THICC: Alt+, detected, opening settings")
 				lm.OpenSettings()
 				return true
+			case '/':
+				log.Println("THICC: Alt+/ detected, showing content search")
+				lm.ShowContentSearch()
+				return true
 			}
 		}
 
@@ -1587,6 +1810,13 @@ func (lm *LayoutManager) HandleEvent(event tcell.Event) bool {
 		}
 	}
 
+	// Conflict resolver shortcuts while the conflicted file has focus
+	if ev, ok := event.(*tcell.EventKey); ok && lm.ActivePanel == 1 {
+		if lm.handleConflictKey(ev) {
+			return true
+		}
+	}
+
 	// Check for focus switching keys
 	if lm.handleFocusSwitch(event) {
 		return true
@@ -1921,10 +2151,52 @@ func (lm *LayoutManager) loadAndDisplayActiveTab() {
 	// Mark tab as loaded
 	lm.TabBar.MarkTabLoaded(lm.TabBar.ActiveIndex, buf)
 
+	// Restore the saved cursor position (session restore)
+	if tab.PendingCursor != nil {
+		buf.GetActiveCursor().GotoLoc(tab.PendingCursor.Clamp(buf.Start(), buf.End()))
+		tab.PendingCursor = nil
+	}
+
 	// Display in editor
 	lm.displayBufferInEditor(buf)
 }
 
+// OpenFileAt opens a file in a pinned editor tab with the cursor at line/col
+// (1-based; 0 means unknown) and focuses the editor
+func (lm *LayoutManager) OpenFileAt(path string, line, col int) {
+	if !lm.EditorVisible {
+		lm.EditorVisible = true
+		lm.updateLayout()
+	}
+	lm.previewFileInEditor(path)
+	lm.FocusEditor() // Pins the tab
+
+	if line <= 0 {
+		return
+	}
+	tab := action.MainTab()
+	if tab == nil {
+		return
+	}
+	for _, pane := range tab.Panes {
+		if bp, ok := pane.(*action.BufPane); ok {
+			y := line - 1
+			if y >= bp.Buf.LinesNum() {
+				y = bp.Buf.LinesNum() - 1
+			}
+			x := col - 1
+			if n := utf8.RuneCountInString(bp.Buf.Line(y)); x > n {
+				x = n
+			}
+			if x < 0 {
+				x = 0
+			}
+			bp.GotoLoc(buffer.Loc{X: x, Y: y})
+			return
+		}
+	}
+}
+
 // displayBufferInEditor switches the BufPane to show the given buffer
 func (lm *LayoutManager) displayBufferInEditor(buf *buffer.Buffer) {
 	if buf == nil {
@@ -2352,14 +2624,15 @@ func (lm *LayoutManager) Close() {
 	term3 := lm.Terminal3
 	lm.mu.RUnlock()
 
+	// Detachable terminals keep running in the session daemon
 	if term != nil {
-		term.Close()
+		term.Detach()
 	}
 	if term2 != nil {
-		term2.Close()
+		term2.Detach()
 	}
 	if term3 != nil {
-		term3.Close()
+		term3.Detach()
 	}
 
 	log.Println("THICC: Layout closed")
@@ -2611,6 +2884,45 @@ func (lm *LayoutManager) initSourceControl() {
 		lm.openCommitDiff(commitHash, path)
 	}
 
+	lm.SourceControl.OnHunkSelect = func(path string, patch string) {
+		log.Printf("THICC: Source Control hunk selected in %s", path)
+		// Show just this hunk in the editor
+		lm.openPatchDiff(path, patch)
+	}
+
+	lm.SourceControl.OnConflictSelect = func(path string) {
+		log.Printf("THICC: Source Control conflict selected: %s", path)
+		lm.OpenConflictResolver(path)
+	}
+
+	lm.SourceControl.OnShowDiff = func(title string, patch string) {
+		log.Printf("THICC: Source Control diff: %s", title)
+		lm.openMultiFileDiff(title, patch)
+	}
+
+	lm.SourceControl.OnOpenRevision = func(path, hash, content string) {
+		log.Printf("THICC: Source Control revision opened: %s at %s", path, hash)
+		lm.openRevision(path, hash, content)
+	}
+
+	lm.SourceControl.WorktreeBaseDir = worktreeBaseDir(lm.Root)
+	lm.SourceControl.OnMessage = func(msg string, isError bool) {
+		if isError {
+			action.InfoBar.Error(msg)
+			lm.triggerRedraw()
+			return
+		}
+		lm.ShowTimedMessage(msg, 3*time.Second)
+		lm.triggerRedraw()
+	}
+
+	lm.SourceControl.OnInput = func(title, prompt, initial string, done func(text string, canceled bool)) {
+		lm.ShowInputModal(title, prompt, initial, func(value string, canceled bool) {
+			done(value, canceled)
+			lm.triggerRedraw()
+		})
+	}
+
 	lm.SourceControl.OnRefresh = func() {
 		lm.triggerRedraw()
 	}
@@ -2681,6 +2993,31 @@ func (lm *LayoutManager) openCommitDiff(commitHash string, path string) {
 	lm.triggerRedraw()
 }
 
+// openPatchDiff shows a single patch (one hunk of a file) in the editor
+func (lm *LayoutManager) openPatchDiff(path string, patch string) {
+	// Hide terminal for cleaner diff view (only SC + editor visible)
+	lm.TerminalVisible = false
+
+	// Show editor if hidden
+	lm.EditorVisible = true
+	lm.updatePanelRegions()
+
+	diffBuf, success := action.ShowPatchDiff(patch, path)
+	log.Printf("THICC: ShowPatchDiff returned: %v", success)
+
+	// Update the tab bar with the diff buffer
+	if success && diffBuf != nil && lm.TabBar != nil {
+		if lm.TabBar.ActiveIndex >= 0 && lm.TabBar.ActiveIndex < len(lm.TabBar.Tabs) {
+			lm.TabBar.Tabs[lm.TabBar.ActiveIndex].Buffer = diffBuf
+			lm.TabBar.Tabs[lm.TabBar.ActiveIndex].Name = truncateName(diffBuf.GetName())
+			lm.TabBar.Tabs[lm.TabBar.ActiveIndex].Loaded = true
+		}
+	}
+
+	// Keep focus on SC so user can keep staging hunks
+	lm.triggerRedraw()
+}
+
 // showToolSelectorFor shows the tool selector for the specified terminal panel
 func (lm *LayoutManager) showToolSelectorFor(panel int) {
 	if lm.ToolSelector == nil {
@@ -2690,9 +3027,14 @@ func (lm *LayoutManager) showToolSelectorFor(panel int) {
 	lm.ToolSelectorTarget = panel
 	lm.ShowingToolSelector = true
 
+	lm.ToolSelector.WorktreeAvailable = sourcecontrol.IsGitRepo(lm.Root)
 	lm.ToolSelector.Show(
 		func(cmdArgs []string) {
 			// Tool selected - create terminal with this command
+			if lm.ToolSelector.UseWorktree {
+				lm.createTerminalInWorktree(panel, cmdArgs)
+				return
+			}
 			lm.createTerminalForPanel(panel, cmdArgs)
 		},
 		func(installCmd string) {
@@ -2710,6 +3052,12 @@ func (lm *LayoutManager) showToolSelectorFor(panel int) {
 
 // createTerminalForPanel creates a terminal for the specified panel with the given command
 func (lm *LayoutManager) createTerminalForPanel(panel int, cmdArgs []string) {
+	lm.createTerminalForPanelIn(panel, cmdArgs, nil)
+}
+
+// createTerminalForPanelIn creates a terminal for the specified panel, running
+// the command in a worktree session's checkout when wt is set
+func (lm *LayoutManager) createTerminalForPanelIn(panel int, cmdArgs []string, wt *sourcecontrol.Worktree) {
 	// Hide tool selector FIRST
 	lm.ShowingToolSelector = false
 
@@ -2743,14 +3091,21 @@ func (lm *LayoutManager) createTerminalForPanel(panel int, cmdArgs []string) {
 		lm.MarkAIToolSpawned()
 	}
 
+	attachID, dir := lm.takeAttachSession(panel), ""
+	if wt != nil {
+		dir = wt.Path
+	}
 	go func() {
-		term, err := terminal.NewPanel(termX, 1, termW, lm.ScreenH-1, cmdArgs)
+		term, err := lm.newTerminalPanel(panel, attachID, termX, termW, cmdArgs, dir)
 		if err != nil {
 			log.Printf("THICC: Failed to create terminal for panel %d: %v", panel, err)
 			return
 		}
 
 		lm.setupTerminalCallbacks(term)
+		if wt != nil {
+			lm.setupWorktreeTerminal(term, *wt)
+		}
 
 		lm.mu.Lock()
 		switch panel {
@@ -2804,7 +3159,7 @@ func (lm *LayoutManager) createTerminalWithInstallCommand(panel int, installCmd
 
 	go func() {
 		// Create a shell terminal (nil cmdArgs = default shell)
-		term, err := terminal.NewPanel(termX, 1, termW, lm.ScreenH-1, nil)
+		term, err := terminal.NewPanelForSlot(termX, 1, termW, lm.ScreenH-1, nil, panel)
 		if err != nil {
 			log.Printf("THICC: Failed to create terminal for panel %d: %v", panel, err)
 			return
@@ -3091,19 +3446,20 @@ func (lm *LayoutManager) GetWorkInProgress() *WorkInProgress {
 	log.Printf("THICC: GetWorkInProgress checking terminals - T1=%v T2=%v T3=%v",
 		lm.Terminal != nil, lm.Terminal2 != nil, lm.Terminal3 != nil)
 
-	if lm.Terminal != nil {
+	// Detachable terminals survive quitting, so they aren't lost work
+	if lm.Terminal != nil && !lm.Terminal.IsDetachable() {
 		if tool := lm.Terminal.GetForegroundAITool(); tool != "" {
 			log.Printf("THICC: Terminal1 has AI tool: %s", tool)
 			wip.ActiveAISessions = append(wip.ActiveAISessions, tool)
 		}
 	}
-	if lm.Terminal2 != nil {
+	if lm.Terminal2 != nil && !lm.Terminal2.IsDetachable() {
 		if tool := lm.Terminal2.GetForegroundAITool(); tool != "" {
 			log.Printf("THICC: Terminal2 has AI tool: %s", tool)
 			wip.ActiveAISessions = append(wip.ActiveAISessions, tool)
 		}
 	}
-	if lm.Terminal3 != nil {
+	if lm.Terminal3 != nil && !lm.Terminal3.IsDetachable() {
 		if tool := lm.Terminal3.GetForegroundAITool(); tool != "" {
 			log.Printf("THICC: Terminal3 has AI tool: %s", tool)
 			wip.ActiveAISessions = append(wip.ActiveAISessions, tool)
@@ -3191,8 +3547,12 @@ func (lm *LayoutManager) handleQuit() bool {
 
 // forceQuitAll closes all buffers and exits the application
 func (lm *LayoutManager) forceQuitAll() {
+	// Remember the workspace so reopening the project restores it
+	lm.SaveSession()
+
 	doQuit := func() {
 		buffer.CloseOpenBuffers()
+		lsp.Shutdown()
 		screen.Screen.Fini()
 		action.InfoBar.Close()
 		os.Exit(0)
@@ -3239,6 +3599,15 @@ func (lm *LayoutManager) ShowTerminalCursor(screen tcell.Screen) {
 	}
 }
 
+// post runs f on the main loop, or right away if there is none (tests)
+func (lm *LayoutManager) post(f func()) {
+	if lm.Post == nil {
+		f()
+		return
+	}
+	lm.Post(f)
+}
+
 // triggerRedraw posts a resize event to wake up the event loop and trigger a screen refresh
 func (lm *LayoutManager) triggerRedraw() {
 	if lm.Screen != nil {
@@ -3271,7 +3640,7 @@ func (lm *LayoutManager) handleTerminalPaste(event tcell.Event) bool {
 	// Handle tcell.EventPaste (bracketed paste from outer terminal)
 	if ev, ok := event.(*tcell.EventPaste); ok {
 		log.Printf("THICC: EventPaste for terminal, len=%d", len(ev.Text()))
-		term.Write([]byte(ev.Text()))
+		term.Paste(ev.Text())
 		return true
 	}
 
@@ -3299,7 +3668,7 @@ func (lm *LayoutManager) handleTerminalPaste(event tcell.Event) bool {
 				return true // Still consume to prevent editor paste
 			}
 			log.Printf("THICC: Pasting %d bytes to terminal", len(clip))
-			term.Write([]byte(clip))
+			term.Paste(clip)
 			return true
 		}
 	}
@@ -3334,6 +3703,14 @@ func (lm *LayoutManager) ConstrainEditorRegion() {
 				view.Width = editorWidth - (borderOffset * 2)
 				view.Y = paneNavBarHeight + borderOffset + tabBarHeight // Leave room for pane nav bar, border, tab bar
 				view.Height = lm.ScreenH - view.Y - borderOffset        // Subtract top offset and bottom border
+
+				// The conflict resolver's panes sit above the result
+				if cv := lm.ConflictView; cv != nil && cv.Active && bp.Buf.AbsPath == cv.Path {
+					h := conflictPaneHeight(view.Height)
+					cv.Region = Region{X: view.X, Y: view.Y, Width: view.Width, Height: h}
+					view.Y += h
+					view.Height -= h
+				}
 			}
 		}
 	}
@@ -3550,6 +3927,9 @@ func (lm *LayoutManager) SaveCurrentBuffer() bool {
 				log.Printf("THICC: Saving existing file: %s", bp.Buf.Path)
 				bp.Save()
 
+				// Saving a conflicted file without markers marks it resolved
+				lm.checkConflictResolved()
+
 				// Update tab name (in case it changed)
 				if lm.TabBar != nil {
 					lm.TabBar.UpdateTabName(lm.TabBar.ActiveIndex, bp.Buf)
@@ -3616,6 +3996,28 @@ func (lm *LayoutManager) ShowQuickFind() {
 	}
 }
 
+// ShowContentSearch shows the search-in-files panel, starting from the
+// editor's selection if it is on one line
+func (lm *LayoutManager) ShowContentSearch() {
+	if lm.ContentSearch == nil {
+		return
+	}
+	query := ""
+	if tab := action.MainTab(); tab != nil && lm.ActivePanel == 1 {
+		for _, pane := range tab.Panes {
+			if bp, ok := pane.(*action.BufPane); ok && bp.Cursor.HasSelection() {
+				if sel := string(bp.Cursor.GetSelection()); !strings.Contains(sel, "\n") {
+					query = sel
+				}
+				break
+			}
+		}
+	}
+	lm.ContentSearch.Root = lm.Root
+	lm.ContentSearch.Show(query)
+	lm.triggerRedraw()
+}
+
 // OpenSettings opens the THICC settings file in the editor
 func (lm *LayoutManager) OpenSettings() {
 	// Ensure settings file exists with defaults
@@ -3654,6 +4056,9 @@ func (lm *LayoutManager) navigateToProject(newRoot string) {
 		lm.ProjectPicker.Hide()
 	}
 
+	// Save the workspace of the project we're leaving
+	lm.SaveSession()
+
 	// Update root
 	lm.Root = newRoot
 
@@ -3818,5 +4223,13 @@ func (lm *LayoutManager) navigateToProject(newRoot string) {
 	}
 
 	lm.resetToEmptyBuffer()
+
+	// Restore the new project's saved workspace (running terminals are kept)
+	lm.sessionEnabled = true
+	if session := LoadSession(newRoot); session != nil {
+		log.Printf("THICC Session: Restoring session for %s (%d tabs)", newRoot, len(session.Tabs))
+		lm.applySession(session)
+	}
+
 	lm.triggerRedraw()
 }
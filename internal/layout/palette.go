@@ -0,0 +1,123 @@
+package layout
+
+import (
+	"time"
+
+	"github.com/ellery/thicc/internal/action"
+)
+
+// ShowCommandPalette lists everything that can be run: the layout's own
+// operations, plugin entries, the editor's actions and its commands
+func (lm *LayoutManager) ShowCommandPalette() {
+	if lm.CommandPalette == nil {
+		return
+	}
+	lm.CommandPalette.Show(lm.paletteItems())
+	lm.triggerRedraw()
+}
+
+// paletteItems returns the command palette's entries with the keys that run
+// them. Pane operations run in the pane that had focus when the palette was
+// opened; everything else runs in the editor.
+func (lm *LayoutManager) paletteItems() []PaletteItem {
+	pane := func(name, key string, run func()) PaletteItem {
+		return PaletteItem{Name: name, Category: "Pane", Key: key, Run: run}
+	}
+	// Operations the editor also has as actions show the key bound to the
+	// action, so rebinding it shows up here
+	editorPane := func(name, actionName, key string, run func()) PaletteItem {
+		return pane(name, paneKey(actionName, key), run)
+	}
+	items := []PaletteItem{
+		pane("Quick Find", "Ctrl+P", lm.ShowQuickFind),
+		pane("Search in Files", "Alt+/", lm.ShowContentSearch),
+		pane("Toggle File Browser", "Alt+1", lm.ToggleTree),
+		pane("Toggle Editor", "Alt+2", lm.ToggleEditor),
+		pane("Toggle Terminal", "Alt+3", lm.ToggleTerminal),
+		pane("Toggle Terminal 2", "Alt+4", lm.ToggleTerminal2),
+		pane("Toggle Terminal 3", "Alt+5", lm.ToggleTerminal3),
+		pane("Toggle Source Control", "Alt+A", lm.ToggleSourceControl),
+		pane("Focus File Browser", "", lm.FocusTree),
+		pane("Focus Editor", "", lm.FocusEditor),
+		pane("Focus Terminal", "", lm.FocusTerminal),
+		pane("Next Tab", "Ctrl+]", lm.NextTab),
+		pane("Previous Tab", "Ctrl+[", lm.PreviousTab),
+		pane("Close Tab", "Ctrl+W", lm.CloseActiveTab),
+		pane("Go to Definition", "F12", lm.GoToDefinition),
+		pane("Find References", "Shift+F12", lm.FindReferences),
+		pane("Show Hover", `Ctrl+\ K`, lm.ShowHover),
+		pane("Rename Symbol", `Ctrl+\ R`, lm.RenameSymbol),
+		editorPane("Toggle Blame", "ToggleBlame", `Ctrl+\ B`, lm.ToggleBlame),
+		pane("File History", `Ctrl+\ H`, func() {
+			if path := lm.activeFilePath(); path != "" {
+				lm.ShowFileHistory(path)
+			} else {
+				lm.ShowTimedMessage("No file selected", 2*time.Second)
+			}
+		}),
+		pane("Open Project", "", lm.ShowProjectPicker),
+		editorPane("Open Settings", "OpenSettings", "Alt+,", lm.OpenSettings),
+		pane("Keyboard Shortcuts", "Ctrl+/", lm.ShowShortcutsModal),
+		pane("Quit", "Ctrl+Q", func() { lm.handleQuit() }),
+	}
+
+	for _, e := range action.PaletteEntries() {
+		run := e.Run
+		items = append(items, PaletteItem{
+			Name:        e.Name,
+			Description: e.Description,
+			Category:    "Plugin",
+			Run:         func() { lm.runInEditor(run) },
+		})
+	}
+	for _, name := range action.ActionNames() {
+		items = append(items, PaletteItem{
+			Name:     name,
+			Category: "Action",
+			Key:      action.ActionKey(name),
+			Run: func() {
+				lm.runInEditor(func(bp *action.BufPane) { bp.RunAction(name) })
+			},
+		})
+	}
+	// Commands open the command prompt so arguments can be added
+	for _, name := range action.CommandNames() {
+		items = append(items, PaletteItem{
+			Name:     name,
+			Category: "Command",
+			Key:      action.CommandKey(name),
+			Run: func() {
+				lm.runInEditor(func(bp *action.BufPane) { action.CommandEditAction(name + " ")(bp) })
+			},
+		})
+	}
+	return items
+}
+
+// paneKey returns the key shown for a pane operation: the editor's binding
+// for its action if there is one, else the layout's own shortcut. The
+// layout handles its shortcuts before the bindings apply, so those keys
+// can't be rebound and are always current.
+func paneKey(actionName, shortcut string) string {
+	if key := action.ActionKey(actionName); key != "" {
+		return key
+	}
+	return shortcut
+}
+
+// runInEditor focuses the editor, showing it if it is hidden, and runs f
+// with its pane
+func (lm *LayoutManager) runInEditor(f func(bp *action.BufPane)) {
+	bp := lm.editorPane()
+	if bp == nil {
+		lm.ShowTimedMessage("No file open in the editor", 2*time.Second)
+		return
+	}
+	if !lm.EditorVisible {
+		lm.EditorVisible = true
+		lm.updateLayout()
+	}
+	lm.FocusEditor()
+	f(bp)
+	lm.triggerRedraw()
+}
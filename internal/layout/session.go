@@ -0,0 +1,328 @@
+package layout
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ellery/thicc/internal/action"
+	"github.com/ellery/thicc/internal/buffer"
+	"github.com/ellery/thicc/internal/dashboard"
+	"github.com/ellery/thicc/internal/terminal"
+)
+
+// SessionsSubdir is the directory (under the thicc config dir) holding per-project sessions
+const SessionsSubdir = "sessions"
+
+// SessionTab is a saved editor tab
+type SessionTab struct {
+	Path string `json:"path"`
+	Line int    `json:"line"`
+	Col  int    `json:"col"`
+}
+
+// SessionTerminal is a saved terminal pane and the command it was running
+type SessionTerminal struct {
+	Panel   int      `json:"panel"`             // 2=terminal, 3=terminal2, 4=terminal3
+	Command []string `json:"command,omitempty"` // nil = default shell
+}
+
+// Session is the saved workspace for a single project root
+type Session struct {
+	Root    string    `json:"root"`
+	SavedAt time.Time `json:"saved_at"`
+
+	Tabs      []SessionTab `json:"tabs"`
+	ActiveTab int          `json:"active_tab"`
+
+	TreeVisible          bool `json:"tree_visible"`
+	SourceControlVisible bool `json:"source_control_visible"`
+	EditorVisible        bool `json:"editor_visible"`
+	TerminalVisible      bool `json:"terminal_visible"`
+	Terminal2Visible     bool `json:"terminal2_visible"`
+	Terminal3Visible     bool `json:"terminal3_visible"`
+	ActivePanel          int  `json:"active_panel"`
+
+	ExpandedPaths []string          `json:"expanded_paths,omitempty"`
+	Terminals     []SessionTerminal `json:"terminals,omitempty"`
+}
+
+// GetSessionFilePath returns the session file for a project root.
+// Roots are hashed so any path maps to a flat, filesystem-safe name.
+func GetSessionFilePath(root string) string {
+	sum := sha1.Sum([]byte(filepath.Clean(root)))
+	return filepath.Join(dashboard.GetConfigDir(), SessionsSubdir, hex.EncodeToString(sum[:])+".json")
+}
+
+// LoadSession reads the saved session for a project root.
+// Returns nil if there is no session or it can't be parsed.
+func LoadSession(root string) *Session {
+	data, err := os.ReadFile(GetSessionFilePath(root))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("THICC Session: Failed to read session for %s: %v", root, err)
+		}
+		return nil
+	}
+
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		log.Printf("THICC Session: Failed to parse session for %s: %v", root, err)
+		return nil
+	}
+
+	// Guard against hash collisions or a moved config dir
+	if filepath.Clean(s.Root) != filepath.Clean(root) {
+		log.Printf("THICC Session: Session root mismatch (%s != %s), ignoring", s.Root, root)
+		return nil
+	}
+	return &s
+}
+
+// Save writes the session to disk
+func (s *Session) Save() error {
+	path := GetSessionFilePath(s.Root)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// TerminalCommand returns the saved command for a terminal panel
+func (s *Session) TerminalCommand(panel int) ([]string, bool) {
+	for _, t := range s.Terminals {
+		if t.Panel == panel {
+			return t.Command, true
+		}
+	}
+	return nil, false
+}
+
+// CaptureSession snapshots the current workspace
+func (lm *LayoutManager) CaptureSession() *Session {
+	s := &Session{
+		Root:                 lm.Root,
+		SavedAt:              time.Now(),
+		TreeVisible:          lm.TreeVisible,
+		SourceControlVisible: lm.SourceControlVisible,
+		EditorVisible:        lm.EditorVisible,
+		TerminalVisible:      lm.TerminalVisible,
+		Terminal2Visible:     lm.Terminal2Visible,
+		Terminal3Visible:     lm.Terminal3Visible,
+		ActivePanel:          lm.ActivePanel,
+	}
+
+	// Tabs - only files on disk can be reopened
+	if lm.TabBar != nil {
+		for i, tab := range lm.TabBar.Tabs {
+			if tab.Path == "" {
+				continue
+			}
+			if i == lm.TabBar.ActiveIndex {
+				s.ActiveTab = len(s.Tabs)
+			}
+			st := SessionTab{Path: tab.Path}
+			if tab.Loaded && tab.Buffer != nil {
+				loc := tab.Buffer.GetActiveCursor().Loc
+				st.Line, st.Col = loc.Y, loc.X
+			} else if tab.PendingCursor != nil {
+				st.Line, st.Col = tab.PendingCursor.Y, tab.PendingCursor.X
+			}
+			s.Tabs = append(s.Tabs, st)
+		}
+	}
+
+	if lm.FileBrowser != nil && lm.FileBrowser.Tree != nil {
+		s.ExpandedPaths = lm.FileBrowser.Tree.GetExpandedPaths()
+	}
+
+	// Terminals - record the command each one was spawned with
+	lm.mu.RLock()
+	terms := [...]*terminal.Panel{lm.Terminal, lm.Terminal2, lm.Terminal3}
+	lm.mu.RUnlock()
+	for i, term := range terms {
+		if term != nil {
+			s.Terminals = append(s.Terminals, SessionTerminal{Panel: i + 2, Command: term.OriginalCommand})
+		}
+	}
+
+	return s
+}
+
+// SaveSession persists the current workspace for lm.Root.
+// No-op unless session tracking was enabled for this project.
+func (lm *LayoutManager) SaveSession() {
+	if !lm.sessionEnabled || lm.Root == "" {
+		return
+	}
+	if err := lm.CaptureSession().Save(); err != nil {
+		log.Printf("THICC Session: Failed to save session for %s: %v", lm.Root, err)
+		return
+	}
+	log.Printf("THICC Session: Saved session for %s", lm.Root)
+}
+
+// PrepareSessionRestore loads the saved session for lm.Root and enables
+// session tracking, saving it whenever thicc quits. Must be called before
+// Initialize() so the main terminal is spawned with the command it was
+// running last time.
+// Returns true if a session was found.
+func (lm *LayoutManager) PrepareSessionRestore() bool {
+	lm.sessionEnabled = true
+	// The editor's own quit actions (closing the last pane, QuitAll) exit
+	// without going through forceQuitAll
+	action.BeforeExit = lm.SaveSession
+	lm.pendingSession = LoadSession(lm.Root)
+	if lm.pendingSession == nil {
+		return false
+	}
+
+	if cmd, ok := lm.pendingSession.TerminalCommand(2); ok {
+		log.Printf("THICC Session: Restoring main terminal command: %v", cmd)
+		lm.SetAIToolCommand(cmd)
+	}
+	return true
+}
+
+// RestoreSession applies the session loaded by PrepareSessionRestore.
+// Call after Initialize() once the file browser exists.
+func (lm *LayoutManager) RestoreSession() {
+	s := lm.pendingSession
+	lm.pendingSession = nil
+	if s == nil {
+		return
+	}
+	log.Printf("THICC Session: Restoring session for %s (%d tabs)", s.Root, len(s.Tabs))
+	lm.applySession(s)
+}
+
+// applySession restores layout, tree and tabs from a session, and respawns
+// any secondary terminal that isn't already running. The main terminal is
+// left alone: Initialize spawns it from AIToolCommand.
+func (lm *LayoutManager) applySession(s *Session) {
+	// Pane visibility
+	lm.TreeVisible = s.TreeVisible
+	lm.SourceControlVisible = s.SourceControlVisible
+	lm.EditorVisible = s.EditorVisible
+	lm.TerminalVisible = s.TerminalVisible
+	lm.Terminal2Visible = s.Terminal2Visible
+	lm.Terminal3Visible = s.Terminal3Visible
+	if lm.SourceControlVisible {
+		lm.TreeVisible = false
+		if lm.SourceControl == nil {
+			lm.initSourceControl()
+		}
+		lm.SourceControl.StartPolling()
+	}
+
+	// Expanded tree directories
+	if lm.FileBrowser != nil && lm.FileBrowser.Tree != nil && len(s.ExpandedPaths) > 0 {
+		if err := lm.FileBrowser.Tree.ExpandPaths(s.ExpandedPaths); err != nil {
+			log.Printf("THICC Session: Failed to expand tree paths: %v", err)
+		}
+	}
+
+	lm.restoreSessionTabs(s)
+
+	// Secondary terminals
+	for _, t := range s.Terminals {
+		switch {
+		case t.Panel == 3 && !lm.Terminal2Initialized:
+			lm.Terminal2Initialized = true
+			lm.createTerminalForPanel(3, t.Command)
+		case t.Panel == 4 && !lm.Terminal3Initialized:
+			lm.Terminal3Initialized = true
+			lm.createTerminalForPanel(4, t.Command)
+		}
+	}
+	if !lm.Terminal2Initialized {
+		lm.Terminal2Visible = false
+	}
+	if !lm.Terminal3Initialized {
+		lm.Terminal3Visible = false
+	}
+
+	// Focus - fall back to the next visible pane if the saved one is gone
+	if lm.isPanelVisible(s.ActivePanel) {
+		lm.setActivePanel(s.ActivePanel)
+	} else {
+		lm.focusNextVisiblePane()
+	}
+
+	lm.updatePanelRegions()
+	lm.triggerRedraw()
+}
+
+// restoreSessionTabs reopens the saved tabs as lazy stubs and loads the active one
+func (lm *LayoutManager) restoreSessionTabs(s *Session) {
+	if lm.TabBar == nil {
+		return
+	}
+
+	var tabs []SessionTab
+	active := 0
+	for i, st := range s.Tabs {
+		if info, err := os.Stat(st.Path); err != nil || info.IsDir() {
+			continue
+		}
+		if i == s.ActiveTab {
+			active = len(tabs)
+		}
+		tabs = append(tabs, st)
+	}
+	if len(tabs) == 0 {
+		return
+	}
+
+	// Drop untouched Untitled placeholders - the restored files replace them
+	for i := len(lm.TabBar.Tabs) - 1; i >= 0; i-- {
+		tab := lm.TabBar.Tabs[i]
+		if tab.Path == "" && (tab.Buffer == nil || !tab.Buffer.Modified()) {
+			lm.TabBar.CloseTab(i)
+		}
+	}
+
+	activeIdx := -1
+	for i, st := range tabs {
+		idx := lm.TabBar.FindTabByPath(st.Path)
+		if idx < 0 {
+			idx = lm.TabBar.AddTabStub(st.Path)
+			lm.TabBar.Tabs[idx].PendingCursor = &buffer.Loc{X: st.Col, Y: st.Line}
+		}
+		if i == active {
+			activeIdx = idx
+		}
+	}
+
+	lm.TabBar.ActiveIndex = activeIdx
+	lm.loadAndDisplayActiveTab()
+	if openTab := lm.TabBar.GetActiveTab(); openTab != nil && lm.FileBrowser != nil {
+		lm.FileBrowser.SelectFile(openTab.Path)
+	}
+}
+
+// isPanelVisible returns true if the given panel index is currently shown
+func (lm *LayoutManager) isPanelVisible(panel int) bool {
+	switch panel {
+	case 0:
+		return lm.TreeVisible || lm.SourceControlVisible
+	case 1:
+		return lm.EditorVisible
+	case 2:
+		return lm.TerminalVisible
+	case 3:
+		return lm.Terminal2Visible
+	case 4:
+		return lm.Terminal3Visible
+	}
+	return false
+}
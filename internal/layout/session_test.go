@@ -0,0 +1,177 @@
+package layout
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ellery/thicc/internal/action"
+	"github.com/ellery/thicc/internal/buffer"
+	"github.com/ellery/thicc/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withTempConfigDir points the thicc config dir at a temp dir for the test
+func withTempConfigDir(t *testing.T) {
+	saved := config.ConfigDir
+	config.ConfigDir = t.TempDir()
+	t.Cleanup(func() { config.ConfigDir = saved })
+}
+
+// =============================================================================
+// Session File Tests
+// =============================================================================
+
+func TestSession_FilePathIsPerProject(t *testing.T) {
+	withTempConfigDir(t)
+
+	a := GetSessionFilePath("/projects/a")
+	b := GetSessionFilePath("/projects/b")
+	assert.NotEqual(t, a, b)
+	assert.Equal(t, a, GetSessionFilePath("/projects/a/"))
+	assert.Equal(t, filepath.Join(config.ConfigDir, "thicc", SessionsSubdir), filepath.Dir(a))
+}
+
+func TestSession_SaveAndLoadRoundTrip(t *testing.T) {
+	withTempConfigDir(t)
+
+	s := &Session{
+		Root:             "/projects/a",
+		Tabs:             []SessionTab{{Path: "/projects/a/main.go", Line: 12, Col: 4}},
+		TreeVisible:      true,
+		Terminal2Visible: true,
+		ActivePanel:      3,
+		ExpandedPaths:    []string{"/projects/a/internal"},
+		Terminals: []SessionTerminal{
+			{Panel: 2, Command: nil},
+			{Panel: 3, Command: []string{"claude", "--continue"}},
+		},
+	}
+	assert.NoError(t, s.Save())
+
+	loaded := LoadSession("/projects/a")
+	assert.NotNil(t, loaded)
+	assert.Equal(t, s.Tabs, loaded.Tabs)
+	assert.Equal(t, 3, loaded.ActivePanel)
+	assert.True(t, loaded.Terminal2Visible)
+	assert.Equal(t, s.ExpandedPaths, loaded.ExpandedPaths)
+
+	cmd, ok := loaded.TerminalCommand(3)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"claude", "--continue"}, cmd)
+
+	cmd, ok = loaded.TerminalCommand(2)
+	assert.True(t, ok)
+	assert.Nil(t, cmd, "default shell is stored as no command")
+
+	_, ok = loaded.TerminalCommand(4)
+	assert.False(t, ok)
+}
+
+func TestSession_LoadMissingReturnsNil(t *testing.T) {
+	withTempConfigDir(t)
+	assert.Nil(t, LoadSession("/projects/none"))
+}
+
+func TestSession_LoadCorruptReturnsNil(t *testing.T) {
+	withTempConfigDir(t)
+
+	path := GetSessionFilePath("/projects/a")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.NoError(t, os.WriteFile(path, []byte("{not json"), 0644))
+	assert.Nil(t, LoadSession("/projects/a"))
+}
+
+func TestSession_LoadRootMismatchReturnsNil(t *testing.T) {
+	withTempConfigDir(t)
+
+	// Simulate a file written for another root landing at this root's path
+	data := []byte(`{"root": "/projects/other"}`)
+	path := GetSessionFilePath("/projects/a")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.NoError(t, os.WriteFile(path, data, 0644))
+	assert.Nil(t, LoadSession("/projects/a"))
+}
+
+// =============================================================================
+// Capture / Prepare Tests
+// =============================================================================
+
+func TestCaptureSession_LayoutAndTabs(t *testing.T) {
+	lm := newTestLayoutManager(100, 50)
+	lm.Terminal2Visible = true
+	lm.EditorVisible = false
+	lm.ActivePanel = 3
+
+	lm.TabBar.AddTabStub("/tmp/test/a.go")
+	lm.TabBar.Tabs = append(lm.TabBar.Tabs, OpenTab{Name: "Untitled"}) // unsaved, skipped
+	lm.TabBar.AddTabStub("/tmp/test/b.go")
+	lm.TabBar.Tabs[2].PendingCursor = &buffer.Loc{X: 3, Y: 7}
+
+	s := lm.CaptureSession()
+	assert.Equal(t, "/tmp/test", s.Root)
+	assert.True(t, s.Terminal2Visible)
+	assert.False(t, s.EditorVisible)
+	assert.Equal(t, 3, s.ActivePanel)
+
+	assert.Equal(t, 2, len(s.Tabs))
+	assert.Equal(t, "/tmp/test/a.go", s.Tabs[0].Path)
+	assert.Equal(t, SessionTab{Path: "/tmp/test/b.go", Line: 7, Col: 3}, s.Tabs[1])
+	assert.Equal(t, 1, s.ActiveTab, "active index should skip unsaved tabs")
+	assert.Empty(t, s.Terminals)
+}
+
+func TestPrepareSessionRestore_SetsMainTerminalCommand(t *testing.T) {
+	withTempConfigDir(t)
+
+	s := &Session{
+		Root:      "/tmp/test",
+		Terminals: []SessionTerminal{{Panel: 2, Command: []string{"codex"}}},
+	}
+	assert.NoError(t, s.Save())
+
+	lm := newTestLayoutManager(100, 50)
+	assert.True(t, lm.PrepareSessionRestore())
+	assert.Equal(t, []string{"codex"}, lm.AIToolCommand)
+	assert.True(t, lm.sessionEnabled)
+}
+
+func TestPrepareSessionRestore_NoSessionKeepsCommand(t *testing.T) {
+	withTempConfigDir(t)
+
+	lm := newTestLayoutManager(100, 50)
+	lm.SetAIToolCommand([]string{"claude"})
+	assert.False(t, lm.PrepareSessionRestore())
+	assert.Equal(t, []string{"claude"}, lm.AIToolCommand)
+	assert.True(t, lm.sessionEnabled, "session should still be saved on quit")
+}
+
+func TestPrepareSessionRestore_SavesOnEditorQuit(t *testing.T) {
+	withTempConfigDir(t)
+	t.Cleanup(func() { action.BeforeExit = nil })
+
+	lm := newTestLayoutManager(100, 50)
+	lm.PrepareSessionRestore()
+	lm.TabBar.AddTabStub("/tmp/test/a.go")
+	assert.Nil(t, LoadSession("/tmp/test"))
+
+	// What closing the last pane or QuitAll runs before exiting
+	require.NotNil(t, action.BeforeExit)
+	action.BeforeExit()
+	s := LoadSession("/tmp/test")
+	require.NotNil(t, s)
+	assert.Equal(t, []SessionTab{{Path: "/tmp/test/a.go"}}, s.Tabs)
+}
+
+func TestIsPanelVisible(t *testing.T) {
+	lm := newTestLayoutManager(100, 50)
+	lm.TreeVisible = false
+	lm.SourceControlVisible = true
+	lm.Terminal3Visible = false
+
+	assert.True(t, lm.isPanelVisible(0), "source control occupies panel 0")
+	assert.True(t, lm.isPanelVisible(1))
+	assert.False(t, lm.isPanelVisible(4))
+	assert.False(t, lm.isPanelVisible(9))
+}
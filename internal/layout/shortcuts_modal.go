@@ -97,6 +97,7 @@ func (m *ShortcutsModal) Render(screen tcell.Screen) {
 				{"Ctrl+Space", "Cycle focus"},
 				{"Tab", "Tree to editor"},
 				{"Shift+Tab", "Editor to tree"},
+				{"F1", "Command palette"},
 			},
 		},
 		{
@@ -115,6 +116,17 @@ func (m *ShortcutsModal) Render(screen tcell.Screen) {
 				{"Ctrl+S", "Save"},
 				{"Ctrl+D", "Delete"},
 				{"Ctrl+R", "Rename"},
+				{"Alt+/", "Search in files"},
+				{"Alt+/ Alt+H", "Replace in files"},
+			},
+		},
+		{
+			title: "Code",
+			shortcuts: []shortcutEntry{
+				{"F12", "Go to definition"},
+				{"Shift+F12", "Find references"},
+				{"Ctrl+\\ K", "Hover documentation"},
+				{"Ctrl+\\ R", "Rename symbol"},
 			},
 		},
 		{
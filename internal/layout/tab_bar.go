@@ -16,6 +16,9 @@ type OpenTab struct {
 	Path      string // Full path for dedup check
 	Loaded    bool   // true if Buffer is loaded, false for stub/lazy tabs
 	IsPreview bool   // true if this is a preview tab (italicized, replaceable)
+
+	// PendingCursor is applied once the buffer is loaded (session restore)
+	PendingCursor *buffer.Loc
 }
 
 // tabPosition tracks where a tab is rendered for click detection
@@ -17,6 +17,10 @@ type ToolSelector struct {
 	OnSelect       func(cmdArgs []string) // callback with selected command
 	OnInstall      func(installCmd string) // callback to open shell with install command
 	OnCancel       func()                  // callback when cancelled
+
+	// Git worktree option (run the tool on a new branch in its own checkout)
+	WorktreeAvailable bool // Whether the project is a git repo
+	UseWorktree       bool // Toggled with 'w'; read by OnSelect
 }
 
 // NewToolSelector creates a new tool selector with available tools
@@ -52,6 +56,7 @@ func NewToolSelector() *ToolSelector {
 func (ts *ToolSelector) Show(onSelect func(cmdArgs []string), onInstall func(installCmd string), onCancel func()) {
 	ts.Active = true
 	ts.SelectedIdx = 0 // Reset to shell
+	ts.UseWorktree = false
 	ts.OnSelect = onSelect
 	ts.OnInstall = onInstall
 	ts.OnCancel = onCancel
@@ -128,6 +133,11 @@ func (ts *ToolSelector) HandleEvent(event tcell.Event) bool {
 					ts.SelectedIdx--
 				}
 				return true
+			case 'w':
+				if ts.WorktreeAvailable {
+					ts.UseWorktree = !ts.UseWorktree
+				}
+				return true
 			}
 		}
 	}
@@ -196,6 +206,9 @@ func (ts *ToolSelector) Render(screen tcell.Screen, termRegionX, termRegionW, sc
 	if hasInstallable {
 		extraRows = len(ts.InstallTools) + 1 // +1 for separator
 	}
+	if ts.WorktreeAvailable {
+		extraRows++ // Worktree checkbox
+	}
 	modalHeight := len(ts.AITools) + extraRows + 6 // tools + title + borders + instructions
 
 	// Center the modal within the terminal region
@@ -287,6 +300,17 @@ func (ts *ToolSelector) Render(screen tcell.Screen, termRegionX, termRegionW, sc
 		}
 	}
 
+	// Draw worktree checkbox above the instructions
+	if ts.WorktreeAvailable {
+		check := "[ ]"
+		checkStyle := hintStyle
+		if ts.UseWorktree {
+			check = "[x]"
+			checkStyle = titleStyle
+		}
+		drawString(screen, startX+2, startY+modalHeight-3, check+" Run in a new git worktree (w)", checkStyle)
+	}
+
 	// Draw instructions
 	hint := "↑/↓:Navigate  Enter:Select  Esc:Shell"
 	hintX := startX + (modalWidth-len(hint))/2
@@ -0,0 +1,88 @@
+package layout
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/ellery/thicc/internal/action"
+	"github.com/ellery/thicc/internal/dashboard"
+	"github.com/ellery/thicc/internal/sourcecontrol"
+	"github.com/ellery/thicc/internal/terminal"
+)
+
+// WorktreesSubdir is the directory (under the thicc config dir) holding the
+// git worktrees of AI sessions, one directory per project
+const WorktreesSubdir = "worktrees"
+
+// worktreeBaseDir returns the directory thicc creates a project's worktrees in,
+// e.g. ~/.config/thicc/worktrees/myproject-1a2b3c4d
+func worktreeBaseDir(root string) string {
+	root = filepath.Clean(root)
+	sum := sha1.Sum([]byte(root))
+	return filepath.Join(dashboard.GetConfigDir(), WorktreesSubdir, filepath.Base(root)+"-"+hex.EncodeToString(sum[:4]))
+}
+
+// worktreeToolName names a worktree session's branch after the command it runs
+func worktreeToolName(cmdArgs []string) string {
+	if len(cmdArgs) == 0 {
+		return "shell"
+	}
+	return filepath.Base(cmdArgs[0])
+}
+
+// createTerminalInWorktree starts cmdArgs in a new git worktree on its own
+// branch, so it can't touch files another terminal is working on. Falls back
+// to the project checkout if the worktree can't be created. Checking out the
+// worktree can take a while on a large repository, so it runs in the
+// background and the terminal is created once it is done.
+func (lm *LayoutManager) createTerminalInWorktree(panel int, cmdArgs []string) {
+	lm.ShowingToolSelector = false
+	branch := sourcecontrol.WorktreeBranchName(worktreeToolName(cmdArgs), time.Now())
+	action.InfoBar.Message("Creating worktree " + branch + "...")
+	lm.triggerRedraw()
+
+	root := lm.Root
+	go func() {
+		wt, err := sourcecontrol.CreateWorktree(root, worktreeBaseDir(root), branch)
+		lm.post(func() {
+			action.InfoBar.Reset()
+			if err != nil {
+				log.Printf("THICC: Failed to create worktree for panel %d: %v", panel, err)
+				action.InfoBar.Error("Could not create worktree: ", err)
+				lm.createTerminalForPanel(panel, cmdArgs)
+				return
+			}
+			log.Printf("THICC: Starting panel %d in worktree %s", panel, wt.Path)
+			lm.createTerminalForPanelIn(panel, cmdArgs, wt)
+		})
+	}()
+}
+
+// setupWorktreeTerminal shows the branch in a worktree terminal's header and
+// points the user at Source Control once the session is over
+func (lm *LayoutManager) setupWorktreeTerminal(term *terminal.Panel, wt sourcecontrol.Worktree) {
+	term.Title = "⎇ " + wt.Branch
+
+	// Called from the terminal's read loop
+	ended := func() {
+		lm.post(func() {
+			lm.ShowTimedMessage("Session on "+wt.Branch+" ended: review, merge or discard it in Source Control (Alt+W)", 8*time.Second)
+			if lm.SourceControl != nil {
+				lm.SourceControl.RefreshWorktrees()
+			}
+			lm.triggerRedraw()
+		})
+	}
+	term.OnToolExit = ended
+
+	onSessionEnd := term.OnSessionEnd
+	term.OnSessionEnd = func() {
+		if onSessionEnd != nil {
+			onSessionEnd()
+		}
+		ended()
+	}
+}
@@ -0,0 +1,47 @@
+package layout
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/micro-editor/tcell/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// =============================================================================
+// Worktree Session Tests
+// =============================================================================
+
+func TestWorktreeBaseDir_PerProject(t *testing.T) {
+	a := worktreeBaseDir("/src/app")
+	b := worktreeBaseDir("/other/app")
+
+	assert.NotEqual(t, a, b, "same-named projects get their own directory")
+	assert.True(t, strings.HasPrefix(filepath.Base(a), "app-"))
+	assert.Equal(t, a, worktreeBaseDir("/src/app/"))
+}
+
+func TestWorktreeToolName(t *testing.T) {
+	assert.Equal(t, "shell", worktreeToolName(nil))
+	assert.Equal(t, "claude", worktreeToolName([]string{"/usr/local/bin/claude", "--resume"}))
+}
+
+func TestToolSelector_WorktreeToggle(t *testing.T) {
+	ts := &ToolSelector{}
+	ts.Show(nil, nil, nil)
+	press := func() {
+		ts.HandleEvent(tcell.NewEventKey(tcell.KeyRune, 'w', tcell.ModNone, ""))
+	}
+
+	press()
+	assert.False(t, ts.UseWorktree, "only offered in a git repo")
+
+	ts.WorktreeAvailable = true
+	press()
+	assert.True(t, ts.UseWorktree)
+
+	// Reset each time the selector opens
+	ts.Show(nil, nil, nil)
+	assert.False(t, ts.UseWorktree)
+}
@@ -0,0 +1,598 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long to wait for a server to start and to stop
+const (
+	initializeTimeout = 30 * time.Second
+	shutdownTimeout   = 2 * time.Second
+)
+
+// Change is an edit to a document, with its range in both position encodings
+// since the one the server uses may not be known yet when the edit is made
+type Change struct {
+	UTF8, UTF16 Range
+	Text        string
+}
+
+// Point is a position in a document in both position encodings
+type Point struct {
+	UTF8, UTF16 Position
+}
+
+// Client is a running language server
+type Client struct {
+	Config ServerConfig
+	Root   string
+
+	cmd  *exec.Cmd
+	conn *Conn
+
+	// ready is closed once initialize has finished; err (under mu) says if
+	// it failed
+	ready    chan struct{}
+	err      error
+	caps     ServerCapabilities
+	encoding string
+
+	// Messages are written in order by one goroutine, so that edits made
+	// while the server starts are sent once it is ready and requests are
+	// never sent before the edits made ahead of them
+	mu     sync.Mutex
+	queue  []func()
+	wake   chan struct{}
+	closed bool
+
+	onDiagnostics func(PublishDiagnosticsParams)
+}
+
+// StartClient starts the server in cfg for the project at root. The server
+// is initialized in the background, after which onReady is called.
+// onDiagnostics is called with the diagnostics the server publishes. Both are
+// called from other goroutines.
+func StartClient(cfg ServerConfig, root string, onReady func(), onDiagnostics func(PublishDiagnosticsParams)) (*Client, error) {
+	cmd := exec.Command(cfg.Command[0], cfg.Command[1:]...)
+	cmd.Dir = root
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		Config:        cfg,
+		Root:          root,
+		cmd:           cmd,
+		ready:         make(chan struct{}),
+		wake:          make(chan struct{}, 1),
+		onDiagnostics: onDiagnostics,
+	}
+	c.conn = NewConn(stdout, stdin, c)
+
+	go c.logStderr(stderr)
+	go func() {
+		<-c.conn.Done()
+		cmd.Wait()
+	}()
+	go c.initialize(onReady)
+	go c.writeLoop()
+
+	return c, nil
+}
+
+// logStderr logs what the server writes to stderr
+func (c *Client) logStderr(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		log.Printf("THICC LSP %s: %s", c.Config.Name, scanner.Text())
+	}
+}
+
+// initialize tells the server about the client and reads its capabilities
+func (c *Client) initialize(onReady func()) {
+	params := map[string]any{
+		"processId":  os.Getpid(),
+		"clientInfo": map[string]string{"name": "thicc"},
+		"rootUri":    PathToURI(c.Root),
+		"workspaceFolders": []map[string]string{
+			{"uri": PathToURI(c.Root), "name": filepath.Base(c.Root)},
+		},
+		"capabilities": map[string]any{
+			"general": map[string]any{
+				"positionEncodings": []string{EncodingUTF8, EncodingUTF16},
+			},
+			"workspace": map[string]any{
+				"configuration":    true,
+				"workspaceFolders": true,
+			},
+			"textDocument": map[string]any{
+				"synchronization":    map[string]any{"didSave": true},
+				"publishDiagnostics": map[string]any{},
+				"hover":              map[string]any{"contentFormat": []string{"plaintext", "markdown"}},
+				"completion": map[string]any{
+					"completionItem": map[string]any{"snippetSupport": false},
+				},
+				"definition": map[string]any{"linkSupport": true},
+				"references": map[string]any{},
+				"rename":     map[string]any{},
+			},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), initializeTimeout)
+	defer cancel()
+	var result InitializeResult
+	err := c.conn.Call(ctx, "initialize", params, &result)
+	if err == nil {
+		err = c.conn.Notify("initialized", map[string]any{})
+	}
+
+	if err != nil {
+		log.Printf("THICC LSP %s: Failed to initialize in %s: %v", c.Config.Name, c.Root, err)
+		c.mu.Lock()
+		c.err = err
+		c.mu.Unlock()
+		c.cmd.Process.Kill()
+	} else {
+		log.Printf("THICC LSP %s: Initialized in %s", c.Config.Name, c.Root)
+		c.caps = result.Capabilities
+		c.encoding = EncodingUTF16
+		if c.caps.PositionEncoding == EncodingUTF8 {
+			c.encoding = EncodingUTF8
+		}
+	}
+	close(c.ready)
+
+	if err == nil && onReady != nil {
+		onReady()
+	}
+}
+
+// Ready returns true once the server is initialized. It is false while the
+// server starts and if it failed to.
+func (c *Client) Ready() bool {
+	select {
+	case <-c.ready:
+		return c.initErr() == nil
+	default:
+		return false
+	}
+}
+
+// Capabilities returns what the server can do. Only call it once the server
+// is Ready.
+func (c *Client) Capabilities() ServerCapabilities {
+	return c.caps
+}
+
+// initErr returns why the server failed to initialize, if it did
+func (c *Client) initErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// waitReady waits for the server to be initialized
+func (c *Client) waitReady(ctx context.Context) error {
+	select {
+	case <-c.ready:
+		return c.initErr()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// enqueue adds a message to send once the server is ready
+func (c *Client) enqueue(f func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.queue = append(c.queue, f)
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// writeLoop sends the queued messages in order
+func (c *Client) writeLoop() {
+	<-c.ready
+	for {
+		c.mu.Lock()
+		queue, closed, failed := c.queue, c.closed, c.err != nil
+		c.queue = nil
+		c.mu.Unlock()
+
+		if !failed {
+			for _, f := range queue {
+				f()
+			}
+		}
+		if closed {
+			return
+		}
+
+		select {
+		case <-c.wake:
+		case <-c.conn.Done():
+			return
+		}
+	}
+}
+
+// notify queues a notification; its parameters are built when it is sent
+func (c *Client) notify(method string, params func() any) {
+	c.enqueue(func() { c.send(method, params()) })
+}
+
+// send sends a notification right away. Only call it from the write loop.
+func (c *Client) send(method string, params any) {
+	if err := c.conn.Notify(method, params); err != nil {
+		log.Printf("THICC LSP %s: Failed to send %s: %v", c.Config.Name, method, err)
+	}
+}
+
+// call queues a request and waits for its result
+func (c *Client) call(ctx context.Context, method string, params func() any, result any) error {
+	if err := c.waitReady(ctx); err != nil {
+		return err
+	}
+
+	sent := make(chan *call, 1)
+	c.enqueue(func() {
+		cl, err := c.conn.send(method, params())
+		if err != nil {
+			log.Printf("THICC LSP %s: Failed to send %s: %v", c.Config.Name, method, err)
+		}
+		sent <- cl
+	})
+
+	select {
+	case cl := <-sent:
+		if cl == nil {
+			return ErrClosed
+		}
+		return c.conn.wait(ctx, cl, result)
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.conn.Done():
+		return ErrClosed
+	}
+}
+
+// position picks the point's position in the server's encoding
+func (c *Client) position(p Point) Position {
+	if c.encoding == EncodingUTF8 {
+		return p.UTF8
+	}
+	return p.UTF16
+}
+
+// Encoding returns what positions from the server count. Only call it once
+// the server is Ready.
+func (c *Client) Encoding() string {
+	return c.encoding
+}
+
+// DidOpen tells the server that a document was opened
+func (c *Client) DidOpen(uri, languageID string, version int, text string) {
+	c.notify("textDocument/didOpen", func() any {
+		return map[string]any{
+			"textDocument": TextDocumentItem{URI: uri, LanguageID: languageID, Version: version, Text: text},
+		}
+	})
+}
+
+// DidChange tells the server about an edit to a document. It is only sent to
+// servers that take incremental changes; others are sent the whole text with
+// DidChangeFull.
+func (c *Client) DidChange(uri string, version int, change Change) {
+	c.enqueue(func() {
+		if c.caps.SyncKind() != SyncIncremental {
+			return
+		}
+		rng := change.UTF16
+		if c.encoding == EncodingUTF8 {
+			rng = change.UTF8
+		}
+		c.send("textDocument/didChange", map[string]any{
+			"textDocument":   VersionedTextDocumentIdentifier{URI: uri, Version: version},
+			"contentChanges": []TextDocumentContentChangeEvent{{Range: &rng, Text: change.Text}},
+		})
+	})
+}
+
+// DidChangeFull sends the whole text of a document
+func (c *Client) DidChangeFull(uri string, version int, text string) {
+	c.enqueue(func() {
+		if c.caps.SyncKind() == SyncNone {
+			return
+		}
+		c.send("textDocument/didChange", map[string]any{
+			"textDocument":   VersionedTextDocumentIdentifier{URI: uri, Version: version},
+			"contentChanges": []TextDocumentContentChangeEvent{{Text: text}},
+		})
+	})
+}
+
+// DidSave tells the server that a document was saved
+func (c *Client) DidSave(uri string) {
+	c.enqueue(func() {
+		if c.caps.SaveNotifications() {
+			c.send("textDocument/didSave", map[string]any{"textDocument": TextDocumentIdentifier{URI: uri}})
+		}
+	})
+}
+
+// DidClose tells the server that a document was closed
+func (c *Client) DidClose(uri string) {
+	c.notify("textDocument/didClose", func() any {
+		return map[string]any{"textDocument": TextDocumentIdentifier{URI: uri}}
+	})
+}
+
+// positionParams returns the parameters of a request at a point
+func (c *Client) positionParams(uri string, p Point) TextDocumentPositionParams {
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position:     c.position(p),
+	}
+}
+
+// ErrUnsupported is returned for requests the server doesn't handle
+var ErrUnsupported = errors.New("not supported by the language server")
+
+// Definition returns where the symbol at a point is defined
+func (c *Client) Definition(ctx context.Context, uri string, p Point) ([]Location, error) {
+	if err := c.waitReady(ctx); err != nil {
+		return nil, err
+	}
+	if !enabled(c.caps.DefinitionProvider) {
+		return nil, ErrUnsupported
+	}
+	var raw json.RawMessage
+	err := c.call(ctx, "textDocument/definition", func() any { return c.positionParams(uri, p) }, &raw)
+	if err != nil {
+		return nil, err
+	}
+	return parseLocations(raw)
+}
+
+// References returns where the symbol at a point is used, including its
+// declaration
+func (c *Client) References(ctx context.Context, uri string, p Point) ([]Location, error) {
+	if err := c.waitReady(ctx); err != nil {
+		return nil, err
+	}
+	if !enabled(c.caps.ReferencesProvider) {
+		return nil, ErrUnsupported
+	}
+	var locs []Location
+	err := c.call(ctx, "textDocument/references", func() any {
+		return map[string]any{
+			"textDocument": TextDocumentIdentifier{URI: uri},
+			"position":     c.position(p),
+			"context":      map[string]bool{"includeDeclaration": true},
+		}
+	}, &locs)
+	return locs, err
+}
+
+// Hover returns the documentation of the symbol at a point as plain text,
+// or "" if there is none
+func (c *Client) Hover(ctx context.Context, uri string, p Point) (string, error) {
+	if err := c.waitReady(ctx); err != nil {
+		return "", err
+	}
+	if !enabled(c.caps.HoverProvider) {
+		return "", ErrUnsupported
+	}
+	var hover *Hover
+	err := c.call(ctx, "textDocument/hover", func() any { return c.positionParams(uri, p) }, &hover)
+	if err != nil || hover == nil {
+		return "", err
+	}
+	return hoverText(hover.Contents), nil
+}
+
+// Rename returns the edits that rename the symbol at a point
+func (c *Client) Rename(ctx context.Context, uri string, p Point, newName string) (*WorkspaceEdit, error) {
+	if err := c.waitReady(ctx); err != nil {
+		return nil, err
+	}
+	if !enabled(c.caps.RenameProvider) {
+		return nil, ErrUnsupported
+	}
+	var edit *WorkspaceEdit
+	err := c.call(ctx, "textDocument/rename", func() any {
+		return map[string]any{
+			"textDocument": TextDocumentIdentifier{URI: uri},
+			"position":     c.position(p),
+			"newName":      newName,
+		}
+	}, &edit)
+	if err == nil && edit == nil {
+		edit = &WorkspaceEdit{}
+	}
+	return edit, err
+}
+
+// Completion returns the completions at a point
+func (c *Client) Completion(ctx context.Context, uri string, p Point) ([]CompletionItem, error) {
+	if err := c.waitReady(ctx); err != nil {
+		return nil, err
+	}
+	if !enabled(c.caps.CompletionProvider) {
+		return nil, ErrUnsupported
+	}
+	var raw json.RawMessage
+	err := c.call(ctx, "textDocument/completion", func() any { return c.positionParams(uri, p) }, &raw)
+	if err != nil {
+		return nil, err
+	}
+	var list CompletionList
+	if json.Unmarshal(raw, &list) == nil {
+		return list.Items, nil
+	}
+	var items []CompletionItem
+	json.Unmarshal(raw, &items)
+	return items, nil
+}
+
+// Shutdown asks the server to exit, and stops it if it doesn't
+func (c *Client) Shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if c.Ready() {
+		if err := c.call(ctx, "shutdown", func() any { return nil }, nil); err == nil {
+			c.notify("exit", func() any { return nil })
+		}
+	}
+
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+
+	select {
+	case <-c.conn.Done():
+	case <-ctx.Done():
+		c.cmd.Process.Kill()
+	}
+}
+
+// Notify handles the notifications of the server
+func (c *Client) Notify(method string, params json.RawMessage) {
+	switch method {
+	case "textDocument/publishDiagnostics":
+		var p PublishDiagnosticsParams
+		if err := json.Unmarshal(params, &p); err == nil && c.onDiagnostics != nil {
+			c.onDiagnostics(p)
+		}
+	case "window/logMessage", "window/showMessage":
+		var p struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(params, &p) == nil {
+			log.Printf("THICC LSP %s: %s", c.Config.Name, p.Message)
+		}
+	}
+}
+
+// Request handles the requests of the server
+func (c *Client) Request(method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "workspace/configuration":
+		// No settings for any section
+		var p struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		json.Unmarshal(params, &p)
+		return make([]any, len(p.Items)), nil
+	case "workspace/workspaceFolders":
+		return []map[string]string{{"uri": PathToURI(c.Root), "name": filepath.Base(c.Root)}}, nil
+	case "window/workDoneProgress/create", "client/registerCapability", "client/unregisterCapability":
+		return nil, nil
+	}
+	return nil, &ResponseError{Code: codeMethodNotFound, Message: fmt.Sprintf("%s is not supported", method)}
+}
+
+// parseLocations reads a definition result: a Location, a list of them or a
+// list of LocationLinks
+func parseLocations(raw json.RawMessage) ([]Location, error) {
+	s := strings.TrimSpace(string(raw))
+	if s == "" || s == "null" {
+		return nil, nil
+	}
+	if strings.HasPrefix(s, "{") {
+		var loc Location
+		err := json.Unmarshal(raw, &loc)
+		return []Location{loc}, err
+	}
+
+	var items []struct {
+		Location
+		TargetURI            string `json:"targetUri"`
+		TargetSelectionRange Range  `json:"targetSelectionRange"`
+	}
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+	locs := make([]Location, len(items))
+	for i, item := range items {
+		locs[i] = item.Location
+		if item.TargetURI != "" {
+			locs[i] = Location{URI: item.TargetURI, Range: item.TargetSelectionRange}
+		}
+	}
+	return locs, nil
+}
+
+// hoverText flattens the contents of a hover to plain text
+func hoverText(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return strings.TrimSpace(s)
+	}
+	var markup struct {
+		Kind     string `json:"kind"`
+		Language string `json:"language"`
+		Value    string `json:"value"`
+	}
+	if json.Unmarshal(raw, &markup) == nil {
+		return strings.TrimSpace(stripFences(markup.Value))
+	}
+	var list []json.RawMessage
+	if json.Unmarshal(raw, &list) == nil {
+		var parts []string
+		for _, item := range list {
+			if text := hoverText(item); text != "" {
+				parts = append(parts, text)
+			}
+		}
+		return strings.Join(parts, "\n\n")
+	}
+	return ""
+}
+
+// stripFences removes the ``` lines around code in markdown
+func stripFences(s string) string {
+	lines := strings.Split(s, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), "```") {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
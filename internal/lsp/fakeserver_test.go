@@ -0,0 +1,195 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/ellery/thicc/internal/config"
+)
+
+// The tests run their own binary as a language server: with THICC_FAKE_LSP
+// set, TestMain serves LSP over stdio instead of running the tests.
+const fakeServerEnv = "THICC_FAKE_LSP"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeServerEnv) != "" {
+		runFakeServer()
+		os.Exit(0)
+	}
+	// Set once: the buffer package's backup goroutine reads it
+	dir, err := os.MkdirTemp("", "thicc-lsp-test")
+	if err != nil {
+		panic(err)
+	}
+	config.ConfigDir = dir
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// fakeServer keeps the text of the open documents from the changes it is
+// sent, and answers requests about the word at a position by looking for
+// it in the document. Positions are UTF-16.
+type fakeServer struct {
+	conn *Conn
+	docs map[string]string
+}
+
+func runFakeServer() {
+	s := &fakeServer{docs: make(map[string]string)}
+	s.conn = NewConn(os.Stdin, os.Stdout, s)
+	<-s.conn.Done()
+}
+
+func (s *fakeServer) Notify(method string, params json.RawMessage) {
+	var p struct {
+		TextDocument   TextDocumentItem                 `json:"textDocument"`
+		ContentChanges []TextDocumentContentChangeEvent `json:"contentChanges"`
+	}
+	json.Unmarshal(params, &p)
+	uri := p.TextDocument.URI
+
+	switch method {
+	case "textDocument/didOpen":
+		s.docs[uri] = p.TextDocument.Text
+	case "textDocument/didChange":
+		for _, change := range p.ContentChanges {
+			if change.Range == nil {
+				s.docs[uri] = change.Text
+				continue
+			}
+			text := s.docs[uri]
+			start, end := offset(text, change.Range.Start), offset(text, change.Range.End)
+			s.docs[uri] = text[:start] + change.Text + text[end:]
+		}
+	case "textDocument/didClose":
+		delete(s.docs, uri)
+		return
+	case "exit":
+		os.Exit(0)
+	default:
+		return
+	}
+
+	// Every ERROR is an error
+	var diags []Diagnostic
+	for i, line := range strings.Split(s.docs[uri], "\n") {
+		if col := strings.Index(line, "ERROR"); col >= 0 {
+			start := Position{Line: i, Character: utf16Count(line[:col])}
+			end := Position{Line: i, Character: start.Character + 5}
+			diags = append(diags, Diagnostic{Range: Range{start, end}, Severity: SeverityError, Source: "fake", Message: "found ERROR"})
+		}
+	}
+	s.conn.Notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: diags})
+}
+
+func (s *fakeServer) Request(method string, params json.RawMessage) (any, error) {
+	var p struct {
+		TextDocumentPositionParams
+		NewName string `json:"newName"`
+	}
+	json.Unmarshal(params, &p)
+	uri := p.TextDocument.URI
+	word := wordAt(s.docs[uri], p.Position)
+
+	switch method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":   map[string]any{"openClose": true, "change": SyncIncremental, "save": true},
+				"hoverProvider":      true,
+				"definitionProvider": true,
+				"referencesProvider": true,
+				"renameProvider":     true,
+				"completionProvider": map[string]any{},
+			},
+		}, nil
+	case "shutdown":
+		return nil, nil
+	case "fake/text":
+		return s.docs[uri], nil
+	case "textDocument/hover":
+		return map[string]any{"contents": MarkupContent{Kind: "markdown", Value: "```go\nfunc " + word + "()\n```"}}, nil
+	case "textDocument/definition":
+		locs := s.find(uri, word)
+		if len(locs) == 0 {
+			return nil, nil
+		}
+		return []map[string]any{{"targetUri": locs[0].URI, "targetRange": locs[0].Range, "targetSelectionRange": locs[0].Range}}, nil
+	case "textDocument/references":
+		return s.find(uri, word), nil
+	case "textDocument/rename":
+		var edits []TextEdit
+		for _, loc := range s.find(uri, word) {
+			edits = append(edits, TextEdit{Range: loc.Range, NewText: p.NewName})
+		}
+		return WorkspaceEdit{Changes: map[string][]TextEdit{uri: edits}}, nil
+	case "textDocument/completion":
+		return CompletionList{Items: []CompletionItem{
+			{Label: "Sprint", SortText: "3"},
+			{Label: "Println", SortText: "1"},
+			{Label: "Printf(format)", InsertText: "Printf", SortText: "2"},
+		}}, nil
+	}
+	return nil, fmt.Errorf("unknown method %s", method)
+}
+
+// find returns where word is in a document
+func (s *fakeServer) find(uri, word string) []Location {
+	var locs []Location
+	if word == "" {
+		return nil
+	}
+	for i, line := range strings.Split(s.docs[uri], "\n") {
+		for col, rest := 0, line; ; {
+			j := strings.Index(rest, word)
+			if j < 0 {
+				break
+			}
+			col += j
+			start := Position{Line: i, Character: utf16Count(line[:col])}
+			end := Position{Line: i, Character: start.Character + utf16Count(word)}
+			locs = append(locs, Location{URI: uri, Range: Range{start, end}})
+			col += len(word)
+			rest = line[col:]
+		}
+	}
+	return locs
+}
+
+// offset returns the byte offset of a UTF-16 position in text
+func offset(text string, pos Position) int {
+	off := 0
+	for i := 0; i < pos.Line; i++ {
+		off += strings.IndexByte(text[off:], '\n') + 1
+	}
+	units := 0
+	for i, r := range text[off:] {
+		if units >= pos.Character || r == '\n' {
+			return off + i
+		}
+		units += utf16.RuneLen(r)
+	}
+	return len(text)
+}
+
+func utf16Count(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
+
+// wordAt returns the word around a position
+func wordAt(text string, pos Position) string {
+	off := offset(text, pos)
+	isWord := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' }
+	start := strings.LastIndexFunc(text[:off], func(r rune) bool { return !isWord(r) }) + 1
+	end := strings.IndexFunc(text[off:], func(r rune) bool { return !isWord(r) })
+	if end < 0 {
+		end = len(text) - off
+	}
+	return text[start : off+end]
+}
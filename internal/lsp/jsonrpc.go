@@ -0,0 +1,264 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrClosed is returned for calls on a connection that has closed
+var ErrClosed = errors.New("language server connection closed")
+
+// codeMethodNotFound is the JSON-RPC error for requests that aren't handled
+const codeMethodNotFound = -32601
+
+// ResponseError is an error returned by the server
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.Code)
+}
+
+// message is any JSON-RPC message: a request (ID and Method), a notification
+// (Method only) or a response (ID, and Result or Error)
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *ResponseError  `json:"error,omitempty"`
+}
+
+// Handler handles what the other side sends. Notify gets notifications in the
+// order they arrive; Request answers requests. Both are called on the
+// connection's reading goroutine.
+type Handler interface {
+	Notify(method string, params json.RawMessage)
+	Request(method string, params json.RawMessage) (any, error)
+}
+
+// Conn is a JSON-RPC 2.0 connection framed with Content-Length headers, as
+// language servers speak over stdio
+type Conn struct {
+	w       io.Writer
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	nextID  int
+	pending map[string]chan *message
+	err     error
+	done    chan struct{}
+}
+
+// NewConn starts reading messages from r and passing them to h
+func NewConn(r io.Reader, w io.Writer, h Handler) *Conn {
+	c := &Conn{
+		w:       w,
+		pending: make(map[string]chan *message),
+		done:    make(chan struct{}),
+	}
+	go c.read(bufio.NewReader(r), h)
+	return c
+}
+
+// Done is closed when the connection has closed
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Call sends a request and waits for its result, which is decoded into
+// result (if it isn't nil). If ctx ends first the request is cancelled.
+func (c *Conn) Call(ctx context.Context, method string, params, result any) error {
+	call, err := c.send(method, params)
+	if err != nil {
+		return err
+	}
+	return c.wait(ctx, call, result)
+}
+
+// call is a request waiting for its response
+type call struct {
+	id int
+	ch chan *message
+}
+
+// send sends a request, to be waited for with wait
+func (c *Conn) send(method string, params any) (*call, error) {
+	raw, err := marshalParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, c.err
+	}
+	c.nextID++
+	cl := &call{id: c.nextID, ch: make(chan *message, 1)}
+	c.pending[strconv.Itoa(cl.id)] = cl.ch
+	c.mu.Unlock()
+
+	id := json.RawMessage(strconv.Itoa(cl.id))
+	if err := c.write(&message{ID: id, Method: method, Params: raw}); err != nil {
+		c.forget(cl)
+		return nil, err
+	}
+	return cl, nil
+}
+
+// wait waits for the response to a request sent with send
+func (c *Conn) wait(ctx context.Context, cl *call, result any) error {
+	defer c.forget(cl)
+
+	select {
+	case resp := <-cl.ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil || len(resp.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(resp.Result, result)
+	case <-ctx.Done():
+		c.Notify("$/cancelRequest", map[string]any{"id": cl.id})
+		return ctx.Err()
+	case <-c.done:
+		return c.closedErr()
+	}
+}
+
+// forget stops waiting for the response to a request
+func (c *Conn) forget(cl *call) {
+	c.mu.Lock()
+	delete(c.pending, strconv.Itoa(cl.id))
+	c.mu.Unlock()
+}
+
+// Notify sends a notification
+func (c *Conn) Notify(method string, params any) error {
+	raw, err := marshalParams(params)
+	if err != nil {
+		return err
+	}
+	return c.write(&message{Method: method, Params: raw})
+}
+
+// marshalParams encodes the parameters of a message, leaving them out if
+// there are none
+func marshalParams(params any) (json.RawMessage, error) {
+	if params == nil {
+		return nil, nil
+	}
+	return json.Marshal(params)
+}
+
+// write sends one message
+func (c *Conn) write(msg *message) error {
+	msg.JSONRPC = "2.0"
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	select {
+	case <-c.done:
+		return c.closedErr()
+	default:
+	}
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(data)); err != nil {
+		return err
+	}
+	_, err = c.w.Write(data)
+	return err
+}
+
+// read handles incoming messages until the reader fails
+func (c *Conn) read(r *bufio.Reader, h Handler) {
+	for {
+		data, err := readMessage(r)
+		if err != nil {
+			c.close(err)
+			return
+		}
+		var msg message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue // Skip what we can't parse
+		}
+
+		switch {
+		case msg.Method != "" && msg.ID != nil:
+			result, err := h.Request(msg.Method, msg.Params)
+			resp := &message{ID: msg.ID}
+			if err != nil {
+				var respErr *ResponseError
+				if !errors.As(err, &respErr) {
+					respErr = &ResponseError{Code: codeMethodNotFound, Message: err.Error()}
+				}
+				resp.Error = respErr
+			} else {
+				resp.Result, _ = json.Marshal(result)
+			}
+			c.write(resp)
+		case msg.Method != "":
+			h.Notify(msg.Method, msg.Params)
+		case msg.ID != nil:
+			c.mu.Lock()
+			ch := c.pending[strings.Trim(string(msg.ID), `"`)]
+			c.mu.Unlock()
+			if ch != nil {
+				ch <- &msg
+			}
+		}
+	}
+}
+
+// readMessage reads one message, its headers and then its content
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	headers, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("bad Content-Length %q", headers.Get("Content-Length"))
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// close marks the connection closed, failing calls still waiting
+func (c *Conn) close(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	if errors.Is(err, io.EOF) {
+		err = ErrClosed
+	}
+	c.err = err
+	close(c.done)
+}
+
+func (c *Conn) closedErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
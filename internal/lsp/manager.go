@@ -0,0 +1,555 @@
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ellery/thicc/internal/buffer"
+	"github.com/ellery/thicc/internal/thicc"
+)
+
+// Owner owns the gutter messages that show diagnostics
+const Owner = "lsp"
+
+const (
+	// requestTimeout is how long to wait for definitions, references, hovers
+	// and renames
+	requestTimeout = 10 * time.Second
+	// completionTimeout is shorter since completion blocks the editor
+	completionTimeout = time.Second
+	// maxCompletions caps the suggestions shown
+	maxCompletions = 100
+)
+
+// ErrNoServer is returned for buffers without a language server
+var ErrNoServer = errors.New("no language server for this file")
+
+// Target is a location found by the server, such as a definition
+type Target struct {
+	Path       string
+	Start, End buffer.Loc // End is on the line of Start
+	Line       string     // The text of the line
+}
+
+// document is a file open in a server
+type document struct {
+	client    *Client
+	uri       string
+	shared    *buffer.SharedBuffer
+	buffers   []*buffer.Buffer
+	version   int
+	messages  []*buffer.Message
+	resyncing bool
+}
+
+// Manager starts language servers for the file buffers that are opened and
+// keeps them in sync. Everything but Post runs on the main goroutine; the
+// buffer hooks run for every buffer but return early for all but file
+// buffers.
+type Manager struct {
+	// Post runs f on the main goroutine
+	Post func(f func())
+
+	clients map[string]*Client // By server name and root
+	failed  map[string]bool
+	docs    map[*buffer.SharedBuffer]*document
+}
+
+// NewManager returns a manager with no servers running
+func NewManager(post func(f func())) *Manager {
+	return &Manager{
+		Post:    post,
+		clients: make(map[string]*Client),
+		failed:  make(map[string]bool),
+		docs:    make(map[*buffer.SharedBuffer]*document),
+	}
+}
+
+// Default is the manager of the editor's buffers, set up by Init
+var Default *Manager
+
+// Init starts following the buffers opened from now on. post runs a function
+// on the main goroutine.
+func Init(post func(f func())) {
+	Default = NewManager(post)
+	buffer.AddHooks(Default)
+}
+
+// Shutdown stops the servers started by the default manager
+func Shutdown() {
+	if Default == nil {
+		return
+	}
+	buffer.RemoveHooks(Default)
+	Default.Shutdown()
+}
+
+// client returns the running server for cfg in root, starting it if needed
+func (m *Manager) client(cfg ServerConfig, root string) *Client {
+	key := cfg.Name + "\x00" + root
+	if c := m.clients[key]; c != nil || m.failed[key] {
+		return c
+	}
+
+	var c *Client
+	c, err := StartClient(cfg, root, func() {
+		m.Post(func() { m.serverReady(c) })
+	}, func(p PublishDiagnosticsParams) {
+		m.Post(func() { m.showDiagnostics(c, p) })
+	})
+	if err != nil {
+		log.Printf("THICC LSP %s: Failed to start in %s: %v", cfg.Name, root, err)
+		m.failed[key] = true
+		return nil
+	}
+	log.Printf("THICC LSP %s: Started in %s", cfg.Name, root)
+	m.clients[key] = c
+	return c
+}
+
+// serverReady sends the whole text of its documents to a server that
+// doesn't take incremental changes, since the changes made while it started
+// weren't sent
+func (m *Manager) serverReady(c *Client) {
+	if c.Capabilities().SyncKind() != SyncFull {
+		return
+	}
+	for _, doc := range m.docs {
+		if doc.client == c {
+			m.resync(doc)
+		}
+	}
+}
+
+// resync sends the whole text of a document
+func (m *Manager) resync(doc *document) {
+	doc.resyncing = false
+	if m.docs[doc.shared] != doc {
+		return // Closed since
+	}
+	doc.version++
+	doc.client.DidChangeFull(doc.uri, doc.version, string(doc.shared.Bytes()))
+}
+
+// document returns the document of a buffer, or nil if it has no server
+func (m *Manager) document(b *buffer.Buffer) *document {
+	return m.docs[b.SharedBuffer]
+}
+
+// HasServer returns true if a language server follows the buffer
+func (m *Manager) HasServer(b *buffer.Buffer) bool {
+	return m != nil && m.document(b) != nil
+}
+
+// isFileBuffer returns true if b edits a file, as opposed to scratch, log,
+// help or diff buffers. Only file buffers are opened in the editor on the
+// main goroutine.
+func isFileBuffer(b *buffer.Buffer) bool {
+	return b.Type.Kind == buffer.BTDefault.Kind && !b.Type.Scratch && b.AbsPath != ""
+}
+
+// BufferOpened opens the buffer's file in the server for its filetype
+func (m *Manager) BufferOpened(b *buffer.Buffer) {
+	if !isFileBuffer(b) || !thicc.GetLSPEnabled() {
+		return
+	}
+	if doc := m.docs[b.SharedBuffer]; doc != nil {
+		// Another view of an open file
+		doc.buffers = append(doc.buffers, b)
+		for _, msg := range doc.messages {
+			b.AddMessage(msg)
+		}
+		return
+	}
+
+	cfg, ok := ServerFor(b.FileType())
+	if !ok || !cfg.Installed() {
+		return
+	}
+	c := m.client(cfg, FindRoot(b.AbsPath, cfg.RootMarkers))
+	if c == nil {
+		return
+	}
+
+	doc := &document{
+		client:  c,
+		uri:     PathToURI(b.AbsPath),
+		shared:  b.SharedBuffer,
+		buffers: []*buffer.Buffer{b},
+		version: 1,
+	}
+	m.docs[b.SharedBuffer] = doc
+
+	// The highlighter started by NewBuffer may still be going over the lines
+	b.Lock()
+	text := string(b.Bytes())
+	b.Unlock()
+	c.DidOpen(doc.uri, cfg.LanguageID, doc.version, text)
+}
+
+// BufferChanging sends an edit to the server
+func (m *Manager) BufferChanging(b *buffer.SharedBuffer, c buffer.TextChange) {
+	doc := m.docs[b]
+	if doc == nil {
+		return
+	}
+
+	doc.version++
+	if doc.client.Ready() && doc.client.Capabilities().SyncKind() == SyncFull {
+		// The text after the edit is sent once the edits at hand are made
+		if !doc.resyncing {
+			doc.resyncing = true
+			m.Post(func() { m.resync(doc) })
+		}
+		return
+	}
+
+	start, end := b.LineBytes(c.Start.Y), b.LineBytes(c.End.Y)
+	doc.client.DidChange(doc.uri, doc.version, Change{
+		UTF8:  Range{ToPosition(start, c.Start, EncodingUTF8), ToPosition(end, c.End, EncodingUTF8)},
+		UTF16: Range{ToPosition(start, c.Start, EncodingUTF16), ToPosition(end, c.End, EncodingUTF16)},
+		Text:  string(c.Text),
+	})
+}
+
+// BufferReset sends the whole text of a buffer that changed at once
+func (m *Manager) BufferReset(b *buffer.SharedBuffer) {
+	if doc := m.docs[b]; doc != nil {
+		m.resync(doc)
+	}
+}
+
+// BufferSaved tells the server the file was saved
+func (m *Manager) BufferSaved(b *buffer.Buffer) {
+	if doc := m.document(b); doc != nil {
+		doc.client.DidSave(doc.uri)
+	}
+}
+
+// BufferClosed closes the file in the server once its last buffer closes
+func (m *Manager) BufferClosed(b *buffer.Buffer) {
+	doc := m.document(b)
+	if doc == nil {
+		return
+	}
+	for i, other := range doc.buffers {
+		if other == b {
+			doc.buffers = append(doc.buffers[:i], doc.buffers[i+1:]...)
+			break
+		}
+	}
+	if len(doc.buffers) > 0 {
+		return
+	}
+	delete(m.docs, b.SharedBuffer)
+	doc.client.DidClose(doc.uri)
+}
+
+// showDiagnostics replaces the gutter messages of a document with the
+// diagnostics the server published
+func (m *Manager) showDiagnostics(c *Client, p PublishDiagnosticsParams) {
+	var doc *document
+	for _, d := range m.docs {
+		if d.client == c && d.uri == p.URI {
+			doc = d
+			break
+		}
+	}
+	if doc == nil {
+		return
+	}
+
+	doc.messages = doc.messages[:0]
+	for _, d := range p.Diagnostics {
+		start := m.toLoc(doc.shared, d.Range.Start, c.Encoding())
+		end := m.toLoc(doc.shared, d.Range.End, c.Encoding())
+		msg := d.Message
+		if d.Source != "" {
+			msg = d.Source + ": " + msg
+		}
+		doc.messages = append(doc.messages, buffer.NewMessage(Owner, msg, start, end, messageKind(d.Severity)))
+	}
+
+	for _, b := range doc.buffers {
+		b.ClearMessages(Owner)
+		for _, msg := range doc.messages {
+			b.AddMessage(msg)
+		}
+	}
+}
+
+// messageKind returns the gutter message kind for a diagnostic severity
+func messageKind(severity int) buffer.MsgType {
+	switch severity {
+	case SeverityError, 0:
+		return buffer.MTError
+	case SeverityWarning:
+		return buffer.MTWarning
+	}
+	return buffer.MTInfo
+}
+
+// toLoc converts a position to a location in a buffer, clamped to its text
+// since diagnostics may be a few edits behind
+func (m *Manager) toLoc(b *buffer.SharedBuffer, pos Position, encoding string) buffer.Loc {
+	pos.Line = max(0, min(pos.Line, b.LinesNum()-1))
+	return ToLoc(b.LineBytes(pos.Line), pos, encoding)
+}
+
+// point returns the position of the cursor of a buffer
+func point(b *buffer.Buffer) Point {
+	c := b.GetActiveCursor()
+	line := b.LineBytes(c.Y)
+	return Point{
+		UTF8:  ToPosition(line, c.Loc, EncodingUTF8),
+		UTF16: ToPosition(line, c.Loc, EncodingUTF16),
+	}
+}
+
+// request runs a request for the symbol at the cursor of b in the background
+// and calls done with its error on the main goroutine
+func (m *Manager) request(b *buffer.Buffer, run func(ctx context.Context, doc *document, p Point) func(), fail func(error)) {
+	doc := m.document(b)
+	if doc == nil {
+		fail(ErrNoServer)
+		return
+	}
+	p := point(b)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+		m.Post(run(ctx, doc, p))
+	}()
+}
+
+// Definition finds where the symbol at the cursor is defined. done is called
+// on the main goroutine.
+func (m *Manager) Definition(b *buffer.Buffer, done func([]Target, error)) {
+	m.request(b, func(ctx context.Context, doc *document, p Point) func() {
+		locs, err := doc.client.Definition(ctx, doc.uri, p)
+		return func() { done(m.targets(doc.client, locs), err) }
+	}, func(err error) { done(nil, err) })
+}
+
+// References finds where the symbol at the cursor is used. done is called
+// on the main goroutine.
+func (m *Manager) References(b *buffer.Buffer, done func([]Target, error)) {
+	m.request(b, func(ctx context.Context, doc *document, p Point) func() {
+		locs, err := doc.client.References(ctx, doc.uri, p)
+		return func() { done(m.targets(doc.client, locs), err) }
+	}, func(err error) { done(nil, err) })
+}
+
+// Hover finds the documentation of the symbol at the cursor. done is called
+// on the main goroutine.
+func (m *Manager) Hover(b *buffer.Buffer, done func(string, error)) {
+	m.request(b, func(ctx context.Context, doc *document, p Point) func() {
+		text, err := doc.client.Hover(ctx, doc.uri, p)
+		return func() { done(text, err) }
+	}, func(err error) { done("", err) })
+}
+
+// Rename renames the symbol at the cursor across the project. Open files
+// are changed in their buffers, others are changed on disk. done is called
+// on the main goroutine with the number of edits and files changed.
+func (m *Manager) Rename(b *buffer.Buffer, newName string, done func(edits, files int, err error)) {
+	m.request(b, func(ctx context.Context, doc *document, p Point) func() {
+		edit, err := doc.client.Rename(ctx, doc.uri, p, newName)
+		return func() {
+			if err != nil {
+				done(0, 0, err)
+				return
+			}
+			edits, files, err := ApplyWorkspaceEdit(edit, doc.client.Encoding())
+			done(edits, files, err)
+		}
+	}, func(err error) { done(0, 0, err) })
+}
+
+// ApplyWorkspaceEdit makes the edits to each file through buffer.EditFile.
+// Returns the number of edits and files changed.
+func ApplyWorkspaceEdit(edit *WorkspaceEdit, encoding string) (int, int, error) {
+	byPath := make(map[string][]TextEdit)
+	for uri, edits := range edit.Changes {
+		byPath[URIToPath(uri)] = append(byPath[URIToPath(uri)], edits...)
+	}
+	for _, change := range edit.DocumentChanges {
+		path := URIToPath(change.TextDocument.URI)
+		byPath[path] = append(byPath[path], change.Edits...)
+	}
+
+	paths := make([]string, 0, len(byPath))
+	for path := range byPath {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	total, files := 0, 0
+	for _, path := range paths {
+		n, err := buffer.EditFile(path, func(b *buffer.Buffer) int {
+			return applyEdits(b, byPath[path], encoding)
+		})
+		if err != nil {
+			return total, files, err
+		}
+		total += n
+		files++
+	}
+	return total, files, nil
+}
+
+// applyEdits makes text edits to a buffer, from the last to the first so
+// that the ranges of the others stay where they were
+func applyEdits(b *buffer.Buffer, edits []TextEdit, encoding string) int {
+	type locEdit struct {
+		start, end buffer.Loc
+		text       string
+	}
+	locEdits := make([]locEdit, len(edits))
+	for i, e := range edits {
+		locEdits[i] = locEdit{
+			start: ToLoc(b.LineBytes(e.Range.Start.Line), e.Range.Start, encoding),
+			end:   ToLoc(b.LineBytes(e.Range.End.Line), e.Range.End, encoding),
+			text:  e.NewText,
+		}
+	}
+	sort.SliceStable(locEdits, func(i, j int) bool {
+		return locEdits[j].start.LessThan(locEdits[i].start)
+	})
+
+	for _, e := range locEdits {
+		b.Replace(e.start, e.end, e.text)
+	}
+	b.RelocateCursors()
+	return len(locEdits)
+}
+
+// Complete is a buffer.Completer that asks the server for the completions at
+// the cursor
+func (m *Manager) Complete(b *buffer.Buffer) ([]string, []string) {
+	doc := m.document(b)
+	if doc == nil || !doc.client.Ready() {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+	items, err := doc.client.Completion(ctx, doc.uri, point(b))
+	if err != nil {
+		log.Printf("THICC LSP %s: Completion failed: %v", doc.client.Config.Name, err)
+		return nil, nil
+	}
+	return completions(items, b)
+}
+
+// completions returns what to insert for each item that completes the word
+// before the cursor, and the item labels
+func completions(items []CompletionItem, b *buffer.Buffer) ([]string, []string) {
+	input, _ := b.GetWord()
+	prefix := string(input)
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return sortKey(items[i]) < sortKey(items[j])
+	})
+
+	var completions, suggestions []string
+	seen := make(map[string]bool)
+	for _, item := range items {
+		text := item.InsertText
+		if item.TextEdit != nil {
+			text = item.TextEdit.NewText
+		}
+		if text == "" {
+			text = item.Label
+		}
+		if !strings.HasPrefix(text, prefix) || seen[text] {
+			continue
+		}
+		seen[text] = true
+		completions = append(completions, text[len(prefix):])
+		suggestions = append(suggestions, item.Label)
+		if len(completions) == maxCompletions {
+			break
+		}
+	}
+	return completions, suggestions
+}
+
+// sortKey orders completion items as the server asks
+func sortKey(item CompletionItem) string {
+	if item.SortText != "" {
+		return item.SortText
+	}
+	return item.Label
+}
+
+// targets converts locations from a server to targets, with the text of
+// their lines from the open buffer or the file on disk
+func (m *Manager) targets(c *Client, locs []Location) []Target {
+	files := make(map[string][][]byte)
+	lines := func(path string) [][]byte {
+		if l, ok := files[path]; ok {
+			return l
+		}
+		var l [][]byte
+		for _, b := range buffer.OpenBuffers {
+			if b.AbsPath == path && b.Type.Kind == buffer.BTDefault.Kind {
+				for i := 0; i < b.LinesNum(); i++ {
+					l = append(l, b.LineBytes(i))
+				}
+				break
+			}
+		}
+		if l == nil {
+			if data, err := os.ReadFile(path); err == nil {
+				l = bytes.Split(data, []byte("\n"))
+			}
+		}
+		files[path] = l
+		return l
+	}
+
+	var targets []Target
+	for _, loc := range locs {
+		path := URIToPath(loc.URI)
+		l := lines(path)
+		if path == "" || loc.Range.Start.Line >= len(l) {
+			continue
+		}
+		line := bytes.TrimRight(l[loc.Range.Start.Line], "\r")
+		end := loc.Range.End
+		if end.Line != loc.Range.Start.Line {
+			end = Position{Line: loc.Range.Start.Line, Character: len(line)}
+		}
+		targets = append(targets, Target{
+			Path:  path,
+			Start: ToLoc(line, loc.Range.Start, c.Encoding()),
+			End:   ToLoc(line, end, c.Encoding()),
+			Line:  string(line),
+		})
+	}
+	return targets
+}
+
+// Shutdown stops all the servers
+func (m *Manager) Shutdown() {
+	var wg sync.WaitGroup
+	for key, c := range m.clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Shutdown()
+		}()
+		delete(m.clients, key)
+	}
+	wg.Wait()
+}
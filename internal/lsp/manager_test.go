@@ -0,0 +1,250 @@
+package lsp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ellery/thicc/internal/buffer"
+	"github.com/ellery/thicc/internal/config"
+	ulua "github.com/ellery/thicc/internal/lua"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func init() {
+	ulua.L = lua.NewState()
+	config.InitRuntimeFiles(false)
+	config.InitGlobalSettings()
+	config.GlobalSettings["backup"] = false
+	config.GlobalSettings["fastdirty"] = true
+	// The highlighter runs on its own goroutine and isn't synchronized with
+	// edits, which the race detector would report
+	config.GlobalSettings["syntax"] = false
+}
+
+// testManager is a manager whose posted functions run when the test waits
+type testManager struct {
+	*Manager
+	jobs chan func()
+}
+
+// newTestManager follows the buffers opened by the test with the fake server
+// standing in for gopls
+func newTestManager(t *testing.T) *testManager {
+	t.Setenv(fakeServerEnv, "1")
+	old := DefaultServers["go"]
+	DefaultServers["go"] = ServerConfig{Name: "fake", Command: []string{os.Args[0]}, LanguageID: "go"}
+
+	tm := &testManager{jobs: make(chan func(), 100)}
+	tm.Manager = NewManager(func(f func()) { tm.jobs <- f })
+	buffer.AddHooks(tm.Manager)
+
+	t.Cleanup(func() {
+		buffer.RemoveHooks(tm.Manager)
+		tm.Shutdown()
+		DefaultServers["go"] = old
+	})
+	return tm
+}
+
+// waitFor runs posted functions until cond is true
+func (tm *testManager) waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for !cond() {
+		select {
+		case f := <-tm.jobs:
+			f()
+		case <-timeout:
+			t.Fatal("timed out")
+		}
+	}
+}
+
+// openFile writes a file and opens it. The filetype of .go files is set
+// rather than detected since the syntax headers are generated at build time.
+func openFile(t *testing.T, name, text string) *buffer.Buffer {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(text), 0644))
+	if filepath.Ext(name) == ".go" {
+		config.GlobalSettings["filetype"] = "go"
+		defer func() { config.GlobalSettings["filetype"] = "unknown" }()
+	}
+	b, err := buffer.NewBufferFromFile(path, buffer.BTDefault)
+	require.NoError(t, err)
+	t.Cleanup(b.Close)
+	return b
+}
+
+// serverText returns the text the server has for a buffer's file
+func (tm *testManager) serverText(t *testing.T, b *buffer.Buffer) string {
+	doc := tm.document(b)
+	require.NotNil(t, doc)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var text string
+	require.NoError(t, doc.client.call(ctx, "fake/text", func() any {
+		return map[string]any{"textDocument": TextDocumentIdentifier{URI: doc.uri}}
+	}, &text))
+	return text
+}
+
+// =============================================================================
+// Sync Tests
+// =============================================================================
+
+func TestManager_OpensFilesOfItsFiletype(t *testing.T) {
+	tm := newTestManager(t)
+	b := openFile(t, "main.go", "package main\n")
+	other := openFile(t, "notes.txt", "text\n")
+
+	assert.True(t, tm.HasServer(b))
+	assert.False(t, tm.HasServer(other), "there is no server for text files")
+	assert.Equal(t, "package main\n", tm.serverText(t, b))
+}
+
+func TestManager_SendsIncrementalChanges(t *testing.T) {
+	tm := newTestManager(t)
+	b := openFile(t, "main.go", "package main\n\nfunc main() {}\n")
+
+	b.Insert(buffer.Loc{X: 0, Y: 1}, "// é😀 a̐ x")
+	b.Insert(buffer.Loc{X: 10, Y: 1}, "y")
+	b.Remove(buffer.Loc{X: 3, Y: 1}, buffer.Loc{X: 5, Y: 1})
+	b.Insert(buffer.Loc{X: 5, Y: 2}, "\nvar z = 1\n")
+	b.Remove(buffer.Loc{X: 12, Y: 0}, buffer.Loc{X: 0, Y: 1})
+
+	assert.Equal(t, string(b.Bytes()), tm.serverText(t, b), "positions after wide and combined characters line up")
+}
+
+func TestManager_ResendsTextAfterRetab(t *testing.T) {
+	tm := newTestManager(t)
+	b := openFile(t, "main.go", "package main\n\n    var x = 1\n")
+	b.Settings["tabstospaces"] = false
+
+	b.Retab()
+	assert.Equal(t, "package main\n\n\tvar x = 1\n", tm.serverText(t, b))
+}
+
+func TestManager_ShowsDiagnostics(t *testing.T) {
+	tm := newTestManager(t)
+	b := openFile(t, "main.go", "package main\n\nvar 😀 = ERROR\n")
+
+	tm.waitFor(t, func() bool { return len(b.Messages) == 1 })
+	msg := b.Messages[0]
+	assert.Equal(t, Owner, msg.Owner)
+	assert.Equal(t, "fake: found ERROR", msg.Msg)
+	assert.Equal(t, buffer.MsgType(buffer.MTError), msg.Kind)
+	assert.Equal(t, buffer.Loc{X: 8, Y: 2}, msg.Start, "the column counts characters")
+	assert.Equal(t, buffer.Loc{X: 13, Y: 2}, msg.End)
+
+	b.Remove(buffer.Loc{X: 8, Y: 2}, buffer.Loc{X: 13, Y: 2})
+	tm.waitFor(t, func() bool { return len(b.Messages) == 0 })
+}
+
+// =============================================================================
+// Request Tests
+// =============================================================================
+
+func TestManager_Definition(t *testing.T) {
+	tm := newTestManager(t)
+	b := openFile(t, "main.go", "package main\n\nfunc helper() {}\n\nvar é = helper\n")
+	b.GetActiveCursor().GotoLoc(buffer.Loc{X: 10, Y: 4})
+
+	var targets []Target
+	var done bool
+	tm.Definition(b, func(found []Target, err error) {
+		require.NoError(t, err)
+		targets, done = found, true
+	})
+	tm.waitFor(t, func() bool { return done })
+
+	require.Len(t, targets, 1)
+	assert.Equal(t, b.AbsPath, targets[0].Path)
+	assert.Equal(t, buffer.Loc{X: 5, Y: 2}, targets[0].Start)
+	assert.Equal(t, buffer.Loc{X: 11, Y: 2}, targets[0].End)
+	assert.Equal(t, "func helper() {}", targets[0].Line)
+}
+
+func TestManager_References(t *testing.T) {
+	tm := newTestManager(t)
+	b := openFile(t, "main.go", "package main\n\nvar x = 1\nvar ü, y = x, x\n")
+	b.GetActiveCursor().GotoLoc(buffer.Loc{X: 4, Y: 2})
+
+	var targets []Target
+	var done bool
+	tm.References(b, func(found []Target, err error) {
+		require.NoError(t, err)
+		targets, done = found, true
+	})
+	tm.waitFor(t, func() bool { return done })
+
+	require.Len(t, targets, 3)
+	assert.Equal(t, buffer.Loc{X: 4, Y: 2}, targets[0].Start)
+	assert.Equal(t, buffer.Loc{X: 11, Y: 3}, targets[1].Start)
+	assert.Equal(t, buffer.Loc{X: 14, Y: 3}, targets[2].Start)
+	assert.Equal(t, "var ü, y = x, x", targets[2].Line)
+}
+
+func TestManager_Hover(t *testing.T) {
+	tm := newTestManager(t)
+	b := openFile(t, "main.go", "package main\n\nfunc main() {}\n")
+	b.GetActiveCursor().GotoLoc(buffer.Loc{X: 6, Y: 2})
+
+	var text string
+	var done bool
+	tm.Hover(b, func(found string, err error) {
+		require.NoError(t, err)
+		text, done = found, true
+	})
+	tm.waitFor(t, func() bool { return done })
+	assert.Equal(t, "func main()", text, "markdown code fences are removed")
+}
+
+func TestManager_Rename(t *testing.T) {
+	tm := newTestManager(t)
+	b := openFile(t, "main.go", "package main\n\nvar 😀, old = 1, 2\nvar y = old + old\n")
+	b.GetActiveCursor().GotoLoc(buffer.Loc{X: 8, Y: 3})
+
+	var edits, files int
+	var done bool
+	tm.Rename(b, "renamed", func(n, f int, err error) {
+		require.NoError(t, err)
+		edits, files, done = n, f, true
+	})
+	tm.waitFor(t, func() bool { return done })
+
+	assert.Equal(t, 3, edits)
+	assert.Equal(t, 1, files)
+	assert.Equal(t, "package main\n\nvar 😀, renamed = 1, 2\nvar y = renamed + renamed\n", string(b.Bytes()))
+	data, _ := os.ReadFile(b.AbsPath)
+	assert.Equal(t, string(b.Bytes()), string(data), "the unmodified buffer is saved")
+	assert.Equal(t, string(b.Bytes()), tm.serverText(t, b))
+}
+
+func TestManager_NoServer(t *testing.T) {
+	tm := newTestManager(t)
+	b := openFile(t, "notes.txt", "text\n")
+
+	var err error
+	tm.Definition(b, func(_ []Target, e error) { err = e })
+	assert.Equal(t, ErrNoServer, err)
+}
+
+// =============================================================================
+// Completion Tests
+// =============================================================================
+
+func TestManager_Complete(t *testing.T) {
+	tm := newTestManager(t)
+	b := openFile(t, "main.go", "package main\n\nvar x = fmt.Pr\n")
+	tm.waitFor(t, func() bool { return tm.document(b).client.Ready() })
+	b.GetActiveCursor().GotoLoc(buffer.Loc{X: 14, Y: 2})
+
+	completions, suggestions := tm.Complete(b)
+	assert.Equal(t, []string{"intln", "intf"}, completions, "sorted, and only what completes the word")
+	assert.Equal(t, []string{"Println", "Printf(format)"}, suggestions)
+}
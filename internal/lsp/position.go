@@ -0,0 +1,63 @@
+package lsp
+
+import (
+	"unicode/utf8"
+
+	"github.com/ellery/thicc/internal/buffer"
+	"github.com/ellery/thicc/internal/util"
+)
+
+// Position encodings, which say what a Position.Character counts
+const (
+	EncodingUTF8  = "utf-8"
+	EncodingUTF16 = "utf-16"
+)
+
+// width returns the length of a character in the encoding
+func width(char []byte, encoding string) int {
+	if encoding == EncodingUTF8 {
+		return len(char)
+	}
+	n := 0
+	for len(char) > 0 {
+		r, size := utf8.DecodeRune(char)
+		n += utf16Len(r)
+		char = char[size:]
+	}
+	return n
+}
+
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// ToPosition converts a buffer location on line to a position in the
+// encoding. Locations past the end of the line are clamped to it.
+func ToPosition(line []byte, loc buffer.Loc, encoding string) Position {
+	character := 0
+	for x := 0; x < loc.X && len(line) > 0; x++ {
+		_, _, size := util.DecodeCharacter(line)
+		character += width(line[:size], encoding)
+		line = line[size:]
+	}
+	return Position{Line: loc.Y, Character: character}
+}
+
+// ToLoc converts a position in the encoding on line to a buffer location.
+// A position inside a character is moved to its start.
+func ToLoc(line []byte, pos Position, encoding string) buffer.Loc {
+	x, character := 0, 0
+	for len(line) > 0 {
+		_, _, size := util.DecodeCharacter(line)
+		character += width(line[:size], encoding)
+		if character > pos.Character {
+			break
+		}
+		line = line[size:]
+		x++
+	}
+	return buffer.Loc{X: x, Y: pos.Line}
+}
@@ -0,0 +1,220 @@
+package lsp
+
+import (
+	"encoding/json"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// The parts of the Language Server Protocol the client uses. See
+// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/
+
+// Position is a zero-based line and character offset. What a character is
+// depends on the position encoding agreed with the server.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is the text from Start up to (not including) End
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Location is a range in a document
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+// TextDocumentIdentifier names a document
+type TextDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+// VersionedTextDocumentIdentifier names a version of a document
+type VersionedTextDocumentIdentifier struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version"`
+}
+
+// TextDocumentItem is a document as it is opened
+type TextDocumentItem struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Version    int    `json:"version"`
+	Text       string `json:"text"`
+}
+
+// TextDocumentPositionParams is a position in a document, the parameters of
+// most requests
+type TextDocumentPositionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+// TextDocumentContentChangeEvent is an edit to a document. Without a Range
+// Text is the whole new text.
+type TextDocumentContentChangeEvent struct {
+	Range *Range `json:"range,omitempty"`
+	Text  string `json:"text"`
+}
+
+// TextEdit replaces a range of a document
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// TextDocumentEdit is a set of edits to one document
+type TextDocumentEdit struct {
+	TextDocument VersionedTextDocumentIdentifier `json:"textDocument"`
+	Edits        []TextEdit                      `json:"edits"`
+}
+
+// WorkspaceEdit is a set of edits across documents. Servers send either
+// Changes or DocumentChanges.
+type WorkspaceEdit struct {
+	Changes         map[string][]TextEdit `json:"changes,omitempty"`
+	DocumentChanges []TextDocumentEdit    `json:"documentChanges,omitempty"`
+}
+
+// Diagnostic severities
+const (
+	SeverityError       = 1
+	SeverityWarning     = 2
+	SeverityInformation = 3
+	SeverityHint        = 4
+)
+
+// Diagnostic is a problem the server found in a document
+type Diagnostic struct {
+	Range    Range  `json:"range"`
+	Severity int    `json:"severity,omitempty"`
+	Source   string `json:"source,omitempty"`
+	Message  string `json:"message"`
+}
+
+// PublishDiagnosticsParams are the diagnostics of a document
+type PublishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Version     int          `json:"version,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// MarkupContent is formatted text, such as a hover
+type MarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+// Hover is the result of a hover request. Contents is MarkupContent, a
+// string or a list of strings and {language, value} objects.
+type Hover struct {
+	Contents json.RawMessage `json:"contents"`
+	Range    *Range          `json:"range,omitempty"`
+}
+
+// CompletionItem is one completion. TextEdit is only used when it is a plain
+// TextEdit (not an InsertReplaceEdit).
+type CompletionItem struct {
+	Label      string    `json:"label"`
+	Kind       int       `json:"kind,omitempty"`
+	Detail     string    `json:"detail,omitempty"`
+	SortText   string    `json:"sortText,omitempty"`
+	FilterText string    `json:"filterText,omitempty"`
+	InsertText string    `json:"insertText,omitempty"`
+	TextEdit   *TextEdit `json:"textEdit,omitempty"`
+}
+
+// CompletionList is the result of a completion request (servers may also
+// send a plain list of items)
+type CompletionList struct {
+	IsIncomplete bool             `json:"isIncomplete"`
+	Items        []CompletionItem `json:"items"`
+}
+
+// Text document sync kinds
+const (
+	SyncNone        = 0
+	SyncFull        = 1
+	SyncIncremental = 2
+)
+
+// ServerCapabilities is what the server can do. Several capabilities are a
+// bool or an options object, so they are kept raw and checked with enabled.
+type ServerCapabilities struct {
+	PositionEncoding   string          `json:"positionEncoding,omitempty"`
+	TextDocumentSync   json.RawMessage `json:"textDocumentSync,omitempty"`
+	HoverProvider      json.RawMessage `json:"hoverProvider,omitempty"`
+	CompletionProvider json.RawMessage `json:"completionProvider,omitempty"`
+	DefinitionProvider json.RawMessage `json:"definitionProvider,omitempty"`
+	ReferencesProvider json.RawMessage `json:"referencesProvider,omitempty"`
+	RenameProvider     json.RawMessage `json:"renameProvider,omitempty"`
+}
+
+// SyncKind returns how the server wants document changes sent
+func (c ServerCapabilities) SyncKind() int {
+	var kind int
+	if json.Unmarshal(c.TextDocumentSync, &kind) == nil {
+		return kind
+	}
+	var opts struct {
+		Change int `json:"change"`
+	}
+	if json.Unmarshal(c.TextDocumentSync, &opts) == nil {
+		return opts.Change
+	}
+	return SyncNone
+}
+
+// SaveNotifications returns true if the server wants to know about saves
+func (c ServerCapabilities) SaveNotifications() bool {
+	var opts struct {
+		Save json.RawMessage `json:"save"`
+	}
+	if json.Unmarshal(c.TextDocumentSync, &opts) != nil {
+		// A sync kind alone doesn't ask for saves
+		return false
+	}
+	return enabled(opts.Save)
+}
+
+// enabled returns true for a capability that is true or an options object
+func enabled(raw json.RawMessage) bool {
+	s := strings.TrimSpace(string(raw))
+	return s != "" && s != "false" && s != "null"
+}
+
+// InitializeResult is the server's answer to initialize
+type InitializeResult struct {
+	Capabilities ServerCapabilities `json:"capabilities"`
+	ServerInfo   *struct {
+		Name    string `json:"name"`
+		Version string `json:"version,omitempty"`
+	} `json:"serverInfo,omitempty"`
+}
+
+// PathToURI turns an absolute path into a file:// URI
+func PathToURI(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path // Windows drive letters
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// URIToPath turns a file:// URI into a path, or returns "" for other URIs
+func URIToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	path := u.Path
+	if len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+		path = path[1:] // Windows drive letters
+	}
+	return filepath.FromSlash(path)
+}
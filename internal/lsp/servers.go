@@ -0,0 +1,127 @@
+package lsp
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/ellery/thicc/internal/thicc"
+	shellquote "github.com/kballard/go-shellquote"
+)
+
+// ServerConfig says how to run the language server for a filetype
+type ServerConfig struct {
+	// Name identifies the server; files with servers of the same name and
+	// root share one process
+	Name string
+	// Command is the program and its arguments. The server speaks LSP over
+	// stdin and stdout.
+	Command []string
+	// LanguageID is the language identifier sent with opened documents
+	LanguageID string
+	// RootMarkers are files that mark the root of a project, looked for in
+	// the file's directory and its parents
+	RootMarkers []string
+}
+
+// DefaultServers are the servers started for each filetype unless the
+// settings say otherwise
+var DefaultServers = map[string]ServerConfig{
+	"go": {
+		Name:        "gopls",
+		Command:     []string{"gopls"},
+		LanguageID:  "go",
+		RootMarkers: []string{"go.work", "go.mod"},
+	},
+	"javascript": {
+		Name:        "typescript-language-server",
+		Command:     []string{"typescript-language-server", "--stdio"},
+		LanguageID:  "javascript",
+		RootMarkers: []string{"tsconfig.json", "jsconfig.json", "package.json"},
+	},
+	"typescript": {
+		Name:        "typescript-language-server",
+		Command:     []string{"typescript-language-server", "--stdio"},
+		LanguageID:  "typescript",
+		RootMarkers: []string{"tsconfig.json", "jsconfig.json", "package.json"},
+	},
+	"python": {
+		Name:        "pyright",
+		Command:     []string{"pyright-langserver", "--stdio"},
+		LanguageID:  "python",
+		RootMarkers: []string{"pyproject.toml", "setup.py", "setup.cfg", "requirements.txt", "pyrightconfig.json"},
+	},
+	"rust": {
+		Name:        "rust-analyzer",
+		Command:     []string{"rust-analyzer"},
+		LanguageID:  "rust",
+		RootMarkers: []string{"Cargo.toml"},
+	},
+	"c": {
+		Name:        "clangd",
+		Command:     []string{"clangd"},
+		LanguageID:  "c",
+		RootMarkers: []string{"compile_commands.json", "compile_flags.txt", ".clangd"},
+	},
+	"c++": {
+		Name:        "clangd",
+		Command:     []string{"clangd"},
+		LanguageID:  "cpp",
+		RootMarkers: []string{"compile_commands.json", "compile_flags.txt", ".clangd"},
+	},
+}
+
+// ServerFor returns the server to run for a filetype, applying the commands
+// from the settings. Returns false if there is none.
+func ServerFor(filetype string) (ServerConfig, bool) {
+	cfg, ok := DefaultServers[filetype]
+	if line, set := thicc.GetLSPServers()[filetype]; set {
+		args, err := shellquote.Split(line)
+		if err != nil || len(args) == 0 {
+			return ServerConfig{}, false
+		}
+		if !ok {
+			cfg = ServerConfig{LanguageID: filetype}
+		}
+		cfg.Name = filepath.Base(args[0])
+		cfg.Command = args
+		ok = true
+	}
+	return cfg, ok
+}
+
+// Installed returns true if the server's program can be found
+func (cfg ServerConfig) Installed() bool {
+	_, err := exec.LookPath(cfg.Command[0])
+	return err == nil
+}
+
+// FindRoot returns the project root of the file at path: the closest
+// directory with one of the markers, else the closest with a .git, else the
+// file's directory
+func FindRoot(path string, markers []string) string {
+	start := filepath.Dir(path)
+	if root := findUp(start, markers); root != "" {
+		return root
+	}
+	if root := findUp(start, []string{".git"}); root != "" {
+		return root
+	}
+	return start
+}
+
+// findUp returns the closest of dir and its parents that has one of names
+func findUp(dir string, names []string) string {
+	for {
+		for _, name := range names {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				return dir
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,270 @@
+package sessiond
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DaemonFlag is the (hidden) command line flag that runs thicc as the daemon
+const DaemonFlag = "session-daemon"
+
+// statusInterval limits how often a client asks for the foreground process
+const statusInterval = time.Second
+
+// SocketPath returns the per-user socket the daemon listens on
+func SocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "thicc", "sessiond.sock")
+	}
+	return filepath.Join(os.TempDir(), "thicc-"+strconv.Itoa(os.Getuid()), "sessiond.sock")
+}
+
+// dial connects to the daemon, if its socket is somewhere only we can reach
+func dial() (net.Conn, error) {
+	path := SocketPath()
+	if err := checkSocketDir(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	return net.DialTimeout("unix", path, 2*time.Second)
+}
+
+// IsRunning returns true if a daemon is answering on the socket
+func IsRunning() bool {
+	conn, err := dial()
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// EnsureDaemon starts the daemon in the background if it isn't running.
+// The daemon is this same binary re-executed with -session-daemon, detached
+// from the terminal so it survives thicc exiting or the SSH session dropping.
+func EnsureDaemon() error {
+	if IsRunning() {
+		return nil
+	}
+	if err := checkSocketDir(filepath.Dir(SocketPath())); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("refusing to start session daemon: %w", err)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if err := startDetached(exe, "-"+DaemonFlag); err != nil {
+		return fmt.Errorf("failed to start session daemon: %w", err)
+	}
+
+	// Wait for the socket to come up
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if IsRunning() {
+			return nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return errors.New("session daemon did not start")
+}
+
+// request sends a one-shot request and returns the daemon's reply frame
+func request(typ byte, payload []byte) (byte, []byte, error) {
+	conn, err := dial()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if err := writeFrame(conn, typ, payload); err != nil {
+		return 0, nil, err
+	}
+	return readFrame(conn)
+}
+
+// List returns the daemon's sessions (empty if the daemon isn't running)
+func List() ([]SessionInfo, error) {
+	if !IsRunning() {
+		return nil, nil
+	}
+	typ, payload, err := request(msgList, nil)
+	if err != nil {
+		return nil, err
+	}
+	if typ == msgError {
+		return nil, errors.New(string(payload))
+	}
+	var infos []SessionInfo
+	if err := json.Unmarshal(payload, &infos); err != nil {
+		return nil, err
+	}
+	return infos, nil
+}
+
+// Kill terminates a session's process
+func Kill(id string) error {
+	typ, payload, err := request(msgKill, []byte(id))
+	if err != nil {
+		return err
+	}
+	if typ == msgError {
+		return errors.New(string(payload))
+	}
+	return nil
+}
+
+// Create starts a new session in the daemon (starting the daemon if needed)
+// and returns a connection attached to it
+func Create(req CreateRequest) (*Conn, error) {
+	if err := EnsureDaemon(); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	return open(msgCreate, data)
+}
+
+// Attach connects to an existing session. The session's recent output is
+// replayed first, so the caller sees its screen and scrollback again.
+func Attach(id string, cols, rows int) (*Conn, error) {
+	data, err := json.Marshal(AttachRequest{ID: id, Cols: cols, Rows: rows})
+	if err != nil {
+		return nil, err
+	}
+	return open(msgAttach, data)
+}
+
+// open performs the create/attach handshake
+func open(typ byte, payload []byte) (*Conn, error) {
+	conn, err := dial()
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if err := writeFrame(conn, typ, payload); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	rtyp, rpayload, err := readFrame(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	if rtyp == msgError {
+		conn.Close()
+		return nil, errors.New(string(rpayload))
+	}
+	c := &Conn{conn: conn}
+	if err := json.Unmarshal(rpayload, &c.Info); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Conn is a client attached to a daemon session. Read returns the process
+// output, Write sends input; closing it detaches and leaves the process running.
+type Conn struct {
+	Info SessionInfo
+
+	conn    net.Conn
+	wmu     sync.Mutex // serializes frame writes
+	pending []byte     // unread part of the last output frame
+
+	mu            sync.Mutex
+	foreground    string
+	lastStatusReq time.Time
+}
+
+// Read returns process output. It returns io.EOF once the process exits.
+func (c *Conn) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		typ, payload, err := readFrame(c.conn)
+		if err != nil {
+			return 0, err
+		}
+		switch typ {
+		case msgOutput:
+			c.pending = payload
+		case msgStatus:
+			var status Status
+			if json.Unmarshal(payload, &status) == nil {
+				c.mu.Lock()
+				c.foreground = status.Foreground
+				c.mu.Unlock()
+			}
+		case msgExit:
+			return 0, io.EOF
+		case msgError:
+			return 0, errors.New(string(payload))
+		}
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+// Write sends input to the process
+func (c *Conn) Write(p []byte) (int, error) {
+	if err := c.send(msgInput, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Resize changes the session's terminal size
+func (c *Conn) Resize(cols, rows int) error {
+	return c.send(msgResize, encodeSize(cols, rows))
+}
+
+// Foreground returns the last known foreground process name and asks the
+// daemon for a fresh one (at most once per statusInterval). The answer
+// arrives asynchronously through Read.
+func (c *Conn) Foreground() string {
+	c.mu.Lock()
+	name := c.foreground
+	refresh := time.Since(c.lastStatusReq) >= statusInterval
+	if refresh {
+		c.lastStatusReq = time.Now()
+	}
+	c.mu.Unlock()
+
+	if refresh {
+		c.send(msgStatus, nil)
+	}
+	return name
+}
+
+// Detach disconnects from the session, leaving its process running
+func (c *Conn) Detach() error {
+	return c.conn.Close()
+}
+
+// Kill terminates the session's process and disconnects
+func (c *Conn) Kill() error {
+	err := Kill(c.Info.ID)
+	c.conn.Close()
+	return err
+}
+
+// send writes a frame to the daemon
+func (c *Conn) send(typ byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	return writeFrame(c.conn, typ, payload)
+}
@@ -0,0 +1,23 @@
+//go:build !linux && !darwin
+
+package sessiond
+
+import (
+	"errors"
+	"net"
+)
+
+// startDetached is a stub for unsupported platforms
+func startDetached(name string, args ...string) error {
+	return errors.New("detachable terminals are not supported on this platform")
+}
+
+// checkSocketDir is a stub for unsupported platforms
+func checkSocketDir(dir string) error {
+	return nil
+}
+
+// listenPrivate is a stub for unsupported platforms
+func listenPrivate(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,59 @@
+//go:build linux || darwin
+
+package sessiond
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// startDetached launches a process in its own session with no terminal attached
+func startDetached(name string, args ...string) error {
+	devNull, err := os.OpenFile(os.DevNull, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer devNull.Close()
+
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = devNull
+	cmd.Stdout = devNull
+	cmd.Stderr = devNull
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// Don't wait - the daemon outlives us
+	return cmd.Process.Release()
+}
+
+// checkSocketDir makes sure the socket's directory belongs to the current
+// user and nobody else can use it. Otherwise another user could create it
+// first and put their own socket there to receive our sessions.
+func checkSocketDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); !ok || int(st.Uid) != os.Getuid() {
+		return fmt.Errorf("%s is not owned by the current user", dir)
+	}
+	if perm := info.Mode().Perm(); perm != 0700 {
+		return fmt.Errorf("%s has mode %04o, expected 0700", dir, perm)
+	}
+	return nil
+}
+
+// listenPrivate listens on a Unix socket that is created with no access for
+// other users, rather than changing its mode after it is already reachable
+func listenPrivate(path string) (net.Listener, error) {
+	old := syscall.Umask(0077)
+	defer syscall.Umask(old)
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,67 @@
+package sessiond
+
+import "bytes"
+
+// DefaultHistoryBytes is how much raw output each session keeps for replay
+const DefaultHistoryBytes = 2 << 20
+
+// History keeps the most recent raw output of a session so a reattaching
+// client can rebuild its screen and scrollback by replaying it. Output is
+// kept in a ring buffer so writes cost the same once the limit is reached.
+type History struct {
+	max     int
+	buf     []byte // Grows to max, then wraps around
+	start   int    // Index of the oldest byte once buf has wrapped
+	dropped bool   // Whether the oldest output has been overwritten
+}
+
+// NewHistory creates a history holding at most max bytes
+func NewHistory(max int) *History {
+	if max <= 0 {
+		max = DefaultHistoryBytes
+	}
+	return &History{max: max}
+}
+
+// Write appends output, overwriting the oldest bytes once the limit is reached
+func (h *History) Write(p []byte) (int, error) {
+	n := len(p)
+	if len(p) >= h.max {
+		h.buf = append(h.buf[:0], p[len(p)-h.max:]...)
+		h.start = 0
+		h.dropped = true
+		return n, nil
+	}
+	if room := h.max - len(h.buf); room > 0 {
+		fill := min(room, len(p))
+		h.buf = append(h.buf, p[:fill]...)
+		p = p[fill:]
+	}
+	for len(p) > 0 {
+		c := copy(h.buf[h.start:], p)
+		p = p[c:]
+		h.start = (h.start + c) % h.max
+		h.dropped = true
+	}
+	return n, nil
+}
+
+// Bytes returns a copy of the stored output, oldest first. Once output has
+// been dropped, the start is moved forward to the next line break when one
+// is close by, so replay doesn't begin in the middle of an escape sequence.
+func (h *History) Bytes() []byte {
+	out := make([]byte, 0, len(h.buf))
+	out = append(out, h.buf[h.start:]...)
+	out = append(out, h.buf[:h.start]...)
+	if h.dropped {
+		if nl := bytes.IndexByte(out, '\n'); nl >= 0 && nl < 4096 {
+			out = out[nl+1:]
+		}
+	}
+	return out
+}
+
+// Len returns the number of stored bytes
+func (h *History) Len() int {
+	return len(h.buf)
+}
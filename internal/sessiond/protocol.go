@@ -0,0 +1,124 @@
+// Package sessiond implements a small background daemon that owns terminal
+// PTYs so thicc can detach from running sessions (AI tools, shells) and
+// reattach later with their output intact - a tmux-lite for thicc panes.
+//
+// Clients talk to the daemon over a Unix socket using length-prefixed frames:
+// one type byte, a big-endian uint32 payload length, then the payload.
+package sessiond
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Frame types
+const (
+	// Client → daemon
+	msgCreate byte = 'C' // CreateRequest (JSON); connection becomes a session stream
+	msgAttach byte = 'A' // AttachRequest (JSON); connection becomes a session stream
+	msgList   byte = 'L' // no payload; answered with msgSessions
+	msgKill   byte = 'K' // session ID; answered with msgOK
+	msgInput  byte = 'I' // raw bytes for the PTY
+	msgResize byte = 'R' // cols, rows as two big-endian uint16
+	msgStatus byte = 'S' // no payload; answered with msgStatus (Status JSON)
+
+	// Daemon → client
+	msgOK       byte = 'O' // SessionInfo (JSON)
+	msgError    byte = 'E' // error text
+	msgOutput   byte = 'D' // raw PTY output
+	msgExit     byte = 'X' // process exited; stream ends
+	msgSessions byte = 'Z' // []SessionInfo (JSON)
+)
+
+// maxFrameSize guards against corrupt length prefixes
+const maxFrameSize = 16 << 20
+
+// SessionInfo describes a session owned by the daemon
+type SessionInfo struct {
+	ID       string    `json:"id"`
+	Root     string    `json:"root"` // Project root the session was created for
+	Slot     int       `json:"slot"` // Layout panel (2=terminal, 3=terminal2, 4=terminal3)
+	Command  []string  `json:"command,omitempty"`
+	Pid      int       `json:"pid"`
+	Created  time.Time `json:"created"`
+	Attached bool      `json:"attached"`
+	Cols     int       `json:"cols"`
+	Rows     int       `json:"rows"`
+}
+
+// CreateRequest asks the daemon to start a new process
+type CreateRequest struct {
+	Command []string `json:"command"`
+	Dir     string   `json:"dir"`
+	Env     []string `json:"env"`
+	Root    string   `json:"root"`
+	Slot    int      `json:"slot"`
+	Cols    int      `json:"cols"`
+	Rows    int      `json:"rows"`
+}
+
+// AttachRequest asks the daemon to stream an existing session
+type AttachRequest struct {
+	ID   string `json:"id"`
+	Cols int    `json:"cols"`
+	Rows int    `json:"rows"`
+}
+
+// Status reports live process state for an attached session
+type Status struct {
+	Foreground string `json:"foreground"` // Name of the PTY's foreground process
+}
+
+// writeFrame writes a single frame
+func writeFrame(w io.Writer, typ byte, payload []byte) error {
+	header := make([]byte, 5, 5+len(payload))
+	header[0] = typ
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	_, err := w.Write(append(header, payload...))
+	return err
+}
+
+// writeJSONFrame marshals v and writes it as a frame
+func writeJSONFrame(w io.Writer, typ byte, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return writeFrame(w, typ, data)
+}
+
+// readFrame reads a single frame
+func readFrame(r io.Reader) (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > maxFrameSize {
+		return 0, nil, fmt.Errorf("frame too large (%d bytes)", size)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[0], payload, nil
+}
+
+// encodeSize packs a terminal size for msgResize
+func encodeSize(cols, rows int) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint16(b[0:], uint16(cols))
+	binary.BigEndian.PutUint16(b[2:], uint16(rows))
+	return b
+}
+
+// decodeSize unpacks a msgResize payload
+func decodeSize(b []byte) (cols, rows int, ok bool) {
+	if len(b) != 4 {
+		return 0, 0, false
+	}
+	return int(binary.BigEndian.Uint16(b[0:])), int(binary.BigEndian.Uint16(b[2:])), true
+}
@@ -0,0 +1,463 @@
+package sessiond
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/creack/pty"
+	"github.com/hinshun/vt10x"
+)
+
+// idleExitDelay is how long the daemon lingers after its last session ends
+const idleExitDelay = 30 * time.Second
+
+// Server owns the sessions and serves clients on a Unix socket
+type Server struct {
+	// Foreground returns the name of the PTY's foreground process.
+	// Optional - injected so the platform-specific lookup stays in one place.
+	Foreground func(pty *os.File) string
+
+	// HistoryBytes is the replay buffer size per session (0 = default)
+	HistoryBytes int
+
+	mu       sync.Mutex
+	sessions map[string]*session
+	nextID   int
+	ln       net.Listener
+	idle     *time.Timer
+}
+
+// session is a single process running under the daemon
+type session struct {
+	server *Server
+	info   SessionInfo
+	cmd    *exec.Cmd
+	pty    *os.File
+
+	// mu guards the emulator, history and attached client
+	mu      sync.Mutex
+	vt      vt10x.Terminal
+	history *History
+	client  net.Conn
+
+	// wmu serializes frame writes to the attached client
+	wmu sync.Mutex
+}
+
+// NewServer creates a daemon server
+func NewServer() *Server {
+	return &Server{sessions: make(map[string]*session)}
+}
+
+// Run listens on socketPath and serves until the listener is closed.
+// The daemon exits on its own once it has had no sessions for a while.
+func (s *Server) Run(socketPath string) error {
+	dir := filepath.Dir(socketPath)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	if err := checkSocketDir(dir); err != nil {
+		return err
+	}
+
+	// A socket file nobody answers on is left over from a dead daemon
+	if conn, err := net.Dial("unix", socketPath); err == nil {
+		conn.Close()
+		return fmt.Errorf("session daemon already running on %s", socketPath)
+	}
+	os.Remove(socketPath)
+
+	ln, err := listenPrivate(socketPath)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.ln = ln
+	s.armIdleTimerLocked()
+	s.mu.Unlock()
+
+	log.Printf("THICC SessionD: Listening on %s", socketPath)
+	defer os.Remove(socketPath)
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.handleConn(conn)
+	}
+}
+
+// Close stops the listener and kills all sessions
+func (s *Server) Close() {
+	s.mu.Lock()
+	ln := s.ln
+	sessions := make([]*session, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	s.mu.Unlock()
+
+	for _, sess := range sessions {
+		sess.kill()
+	}
+	if ln != nil {
+		ln.Close()
+	}
+}
+
+// armIdleTimerLocked schedules a shutdown if there are no sessions
+func (s *Server) armIdleTimerLocked() {
+	if s.idle != nil {
+		s.idle.Stop()
+		s.idle = nil
+	}
+	if len(s.sessions) > 0 || s.ln == nil {
+		return
+	}
+	s.idle = time.AfterFunc(idleExitDelay, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if len(s.sessions) == 0 && s.ln != nil {
+			log.Println("THICC SessionD: No sessions left, exiting")
+			s.ln.Close()
+		}
+	})
+}
+
+// handleConn dispatches on the first frame a client sends
+func (s *Server) handleConn(conn net.Conn) {
+	typ, payload, err := readFrame(conn)
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	switch typ {
+	case msgList:
+		writeJSONFrame(conn, msgSessions, s.List())
+		conn.Close()
+
+	case msgKill:
+		if sess := s.get(string(payload)); sess != nil {
+			sess.kill()
+			writeJSONFrame(conn, msgOK, sess.snapshot())
+		} else {
+			writeFrame(conn, msgError, []byte("no such session: "+string(payload)))
+		}
+		conn.Close()
+
+	case msgCreate:
+		var req CreateRequest
+		if err := json.Unmarshal(payload, &req); err != nil {
+			writeFrame(conn, msgError, []byte("bad create request: "+err.Error()))
+			conn.Close()
+			return
+		}
+		sess, err := s.create(req)
+		if err != nil {
+			writeFrame(conn, msgError, []byte(err.Error()))
+			conn.Close()
+			return
+		}
+		sess.attach(conn, req.Cols, req.Rows, false)
+
+	case msgAttach:
+		var req AttachRequest
+		if err := json.Unmarshal(payload, &req); err != nil {
+			writeFrame(conn, msgError, []byte("bad attach request: "+err.Error()))
+			conn.Close()
+			return
+		}
+		sess := s.get(req.ID)
+		if sess == nil {
+			writeFrame(conn, msgError, []byte("no such session: "+req.ID))
+			conn.Close()
+			return
+		}
+		sess.attach(conn, req.Cols, req.Rows, true)
+
+	default:
+		writeFrame(conn, msgError, []byte(fmt.Sprintf("unexpected frame %q", typ)))
+		conn.Close()
+	}
+}
+
+// List returns all sessions, oldest first
+func (s *Server) List() []SessionInfo {
+	s.mu.Lock()
+	sessions := make([]*session, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	s.mu.Unlock()
+
+	infos := make([]SessionInfo, 0, len(sessions))
+	for _, sess := range sessions {
+		infos = append(infos, sess.snapshot())
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Created.Before(infos[j].Created)
+	})
+	return infos
+}
+
+// get looks up a session by ID
+func (s *Server) get(id string) *session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessions[id]
+}
+
+// create starts a new process on a fresh PTY
+func (s *Server) create(req CreateRequest) (*session, error) {
+	if len(req.Command) == 0 {
+		return nil, errors.New("no command given")
+	}
+	cols, rows := clampSize(req.Cols, req.Rows)
+
+	cmd := exec.Command(req.Command[0], req.Command[1:]...)
+	cmd.Dir = req.Dir
+	cmd.Env = req.Env
+	ptmx, err := pty.StartWithSize(cmd, &pty.Winsize{Cols: uint16(cols), Rows: uint16(rows)})
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.nextID++
+	id := strconv.Itoa(s.nextID)
+	sess := &session{
+		server:  s,
+		cmd:     cmd,
+		pty:     ptmx,
+		history: NewHistory(s.HistoryBytes),
+		info: SessionInfo{
+			ID:      id,
+			Root:    req.Root,
+			Slot:    req.Slot,
+			Command: req.Command,
+			Pid:     cmd.Process.Pid,
+			Created: time.Now(),
+			Cols:    cols,
+			Rows:    rows,
+		},
+	}
+	// The emulator answers terminal queries (cursor position etc.) only while
+	// nobody is attached - otherwise the client's own emulator does
+	sess.vt = vt10x.New(vt10x.WithSize(cols, rows), vt10x.WithWriter(detachedWriter{sess}))
+	s.sessions[id] = sess
+	s.armIdleTimerLocked()
+	s.mu.Unlock()
+
+	log.Printf("THICC SessionD: Session %s started: %v (pid %d)", id, req.Command, cmd.Process.Pid)
+	go sess.readLoop()
+	return sess, nil
+}
+
+// remove drops a finished session
+func (s *Server) remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	s.armIdleTimerLocked()
+}
+
+// detachedWriter forwards emulator replies to the PTY while no client is attached
+type detachedWriter struct {
+	sess *session
+}
+
+func (w detachedWriter) Write(p []byte) (int, error) {
+	// Called from vt.Write with sess.mu held
+	if w.sess.client != nil {
+		return len(p), nil
+	}
+	return w.sess.pty.Write(p)
+}
+
+// snapshot returns the session's current info
+func (sess *session) snapshot() SessionInfo {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	info := sess.info
+	info.Attached = sess.client != nil
+	return info
+}
+
+// readLoop pumps PTY output into the emulator, history and attached client
+func (sess *session) readLoop() {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := sess.pty.Read(buf)
+		if n > 0 {
+			data := buf[:n]
+			sess.mu.Lock()
+			sess.vt.Write(data)
+			sess.history.Write(data)
+			client := sess.client
+			sess.mu.Unlock()
+
+			if client != nil {
+				if werr := sess.send(client, msgOutput, data); werr != nil {
+					sess.detach(client)
+				}
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	sess.cmd.Wait()
+	log.Printf("THICC SessionD: Session %s exited", sess.info.ID)
+
+	sess.mu.Lock()
+	client := sess.client
+	sess.client = nil
+	sess.mu.Unlock()
+	if client != nil {
+		sess.send(client, msgExit, nil)
+		client.Close()
+	}
+	sess.pty.Close()
+	sess.server.remove(sess.info.ID)
+}
+
+// attach makes conn the session's client, replacing any previous one
+func (sess *session) attach(conn net.Conn, cols, rows int, replay bool) {
+	sess.mu.Lock()
+	old := sess.client
+	sess.client = conn
+	var history []byte
+	if replay {
+		history = sess.history.Bytes()
+	}
+	info := sess.info
+	info.Attached = true
+
+	// Take the write lock before releasing mu so output read after the
+	// history snapshot can't reach the client ahead of the handshake
+	sess.wmu.Lock()
+	sess.mu.Unlock()
+
+	if old != nil {
+		log.Printf("THICC SessionD: Session %s attached elsewhere, dropping old client", info.ID)
+		writeFrame(old, msgError, []byte("attached from another client"))
+		old.Close()
+	}
+
+	err := writeJSONFrame(conn, msgOK, info)
+	if err == nil && len(history) > 0 {
+		err = writeFrame(conn, msgOutput, history)
+	}
+	sess.wmu.Unlock()
+	if err != nil {
+		sess.detach(conn)
+		return
+	}
+
+	if cols > 0 && rows > 0 {
+		sess.resize(cols, rows)
+	}
+	sess.serve(conn)
+}
+
+// serve handles frames from an attached client until it disconnects
+func (sess *session) serve(conn net.Conn) {
+	defer sess.detach(conn)
+	for {
+		typ, payload, err := readFrame(conn)
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("THICC SessionD: Session %s client read failed: %v", sess.info.ID, err)
+			}
+			return
+		}
+
+		switch typ {
+		case msgInput:
+			if _, err := sess.pty.Write(payload); err != nil {
+				return
+			}
+		case msgResize:
+			if cols, rows, ok := decodeSize(payload); ok {
+				sess.resize(cols, rows)
+			}
+		case msgStatus:
+			status := Status{}
+			if sess.server.Foreground != nil {
+				status.Foreground = sess.server.Foreground(sess.pty)
+			}
+			data, _ := json.Marshal(status)
+			if err := sess.send(conn, msgStatus, data); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// send writes a frame to a client
+func (sess *session) send(conn net.Conn, typ byte, payload []byte) error {
+	sess.wmu.Lock()
+	defer sess.wmu.Unlock()
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	return writeFrame(conn, typ, payload)
+}
+
+// detach drops conn if it is still the attached client
+func (sess *session) detach(conn net.Conn) {
+	sess.mu.Lock()
+	if sess.client == conn {
+		sess.client = nil
+		log.Printf("THICC SessionD: Session %s detached", sess.info.ID)
+	}
+	sess.mu.Unlock()
+	conn.Close()
+}
+
+// resize changes the PTY and emulator size
+func (sess *session) resize(cols, rows int) {
+	cols, rows = clampSize(cols, rows)
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if cols == sess.info.Cols && rows == sess.info.Rows {
+		return
+	}
+	sess.info.Cols, sess.info.Rows = cols, rows
+	sess.vt.Resize(cols, rows)
+	pty.Setsize(sess.pty, &pty.Winsize{Cols: uint16(cols), Rows: uint16(rows)})
+}
+
+// kill terminates the session's process; readLoop cleans up after it
+func (sess *session) kill() {
+	if sess.cmd.Process != nil {
+		sess.cmd.Process.Kill()
+	}
+}
+
+// clampSize keeps terminal sizes within sane bounds
+func clampSize(cols, rows int) (int, int) {
+	if cols < 10 {
+		cols = 10
+	}
+	if rows < 5 {
+		rows = 5
+	}
+	return cols, rows
+}
@@ -0,0 +1,211 @@
+package sessiond
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// Protocol Tests
+// =============================================================================
+
+func TestFrame_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, writeFrame(&buf, msgInput, []byte("hello")))
+	assert.NoError(t, writeFrame(&buf, msgList, nil))
+
+	typ, payload, err := readFrame(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, msgInput, typ)
+	assert.Equal(t, []byte("hello"), payload)
+
+	typ, payload, err = readFrame(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, msgList, typ)
+	assert.Empty(t, payload)
+
+	_, _, err = readFrame(&buf)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestFrame_RejectsOversizedLength(t *testing.T) {
+	header := []byte{msgOutput, 0xff, 0xff, 0xff, 0xff}
+	_, _, err := readFrame(bytes.NewReader(header))
+	assert.Error(t, err)
+}
+
+func TestSize_EncodeDecode(t *testing.T) {
+	cols, rows, ok := decodeSize(encodeSize(120, 40))
+	assert.True(t, ok)
+	assert.Equal(t, 120, cols)
+	assert.Equal(t, 40, rows)
+
+	_, _, ok = decodeSize([]byte{1, 2})
+	assert.False(t, ok)
+}
+
+// =============================================================================
+// History Tests
+// =============================================================================
+
+func TestHistory_KeepsEverythingUnderLimit(t *testing.T) {
+	h := NewHistory(100)
+	h.Write([]byte("one\n"))
+	h.Write([]byte("two\n"))
+	assert.Equal(t, "one\ntwo\n", string(h.Bytes()))
+}
+
+func TestHistory_TrimsToLineBoundary(t *testing.T) {
+	h := NewHistory(10)
+	h.Write([]byte("aaaa\nbbbb\n"))
+	h.Write([]byte("cc\n"))
+
+	// Dropping 3 bytes would cut "aaaa" mid-line, so the whole line goes
+	assert.Equal(t, "bbbb\ncc\n", string(h.Bytes()))
+	assert.LessOrEqual(t, h.Len(), 10)
+}
+
+func TestHistory_TrimsWithoutLineBreaks(t *testing.T) {
+	h := NewHistory(4)
+	h.Write([]byte("abcdefgh"))
+	assert.Equal(t, "efgh", string(h.Bytes()))
+}
+
+func TestHistory_WrapsAround(t *testing.T) {
+	h := NewHistory(8)
+	for _, chunk := range []string{"ab", "cd", "ef", "gh", "ij", "k"} {
+		h.Write([]byte(chunk))
+	}
+	assert.Equal(t, "defghijk", string(h.Bytes()))
+	assert.Equal(t, 8, h.Len())
+
+	h.Write([]byte("l\nmn"))
+	assert.Equal(t, "mn", string(h.Bytes()), "replay starts after the line break")
+}
+
+func TestHistory_DefaultLimit(t *testing.T) {
+	h := NewHistory(0)
+	assert.Equal(t, DefaultHistoryBytes, h.max)
+}
+
+// =============================================================================
+// Daemon Tests
+// =============================================================================
+
+// startTestServer runs a daemon on a private socket for the test
+func startTestServer(t *testing.T) *Server {
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat not available")
+	}
+
+	// Keep the socket path short - Unix socket paths are limited to ~100 bytes
+	dir, err := os.MkdirTemp("", "sd")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	t.Setenv("XDG_RUNTIME_DIR", dir)
+
+	srv := NewServer()
+	go srv.Run(SocketPath())
+	t.Cleanup(srv.Close)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !IsRunning() {
+		if time.Now().After(deadline) {
+			t.Fatal("daemon did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return srv
+}
+
+// readUntil reads from c until the output contains want
+func readUntil(t *testing.T, c *Conn, want string) {
+	done := make(chan string, 1)
+	go func() {
+		var out strings.Builder
+		buf := make([]byte, 1024)
+		for !strings.Contains(out.String(), want) {
+			n, err := c.Read(buf)
+			if err != nil {
+				break
+			}
+			out.Write(buf[:n])
+		}
+		done <- out.String()
+	}()
+
+	select {
+	case out := <-done:
+		assert.Contains(t, out, want)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %q", want)
+	}
+}
+
+func TestDaemon_DetachAndReattach(t *testing.T) {
+	srv := startTestServer(t)
+
+	conn, err := open(msgCreate, []byte(`{"command":["cat"],"root":"/proj","slot":3,"cols":80,"rows":24}`))
+	require.NoError(t, err)
+	assert.Equal(t, "/proj", conn.Info.Root)
+	assert.Equal(t, 3, conn.Info.Slot)
+
+	_, err = conn.Write([]byte("hello\n"))
+	require.NoError(t, err)
+	readUntil(t, conn, "hello")
+
+	// Detaching leaves the process running
+	require.NoError(t, conn.Detach())
+	assert.Eventually(t, func() bool {
+		infos := srv.List()
+		return len(infos) == 1 && !infos[0].Attached
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// Reattaching replays the earlier output
+	conn, err = Attach(conn.Info.ID, 100, 30)
+	require.NoError(t, err)
+	readUntil(t, conn, "hello")
+
+	infos, err := List()
+	require.NoError(t, err)
+	require.Len(t, infos, 1)
+	assert.True(t, infos[0].Attached)
+
+	// Killing ends the session
+	require.NoError(t, conn.Kill())
+	assert.Eventually(t, func() bool {
+		return len(srv.List()) == 0
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestDaemon_AttachUnknownSession(t *testing.T) {
+	startTestServer(t)
+
+	_, err := Attach("nope", 80, 24)
+	assert.Error(t, err)
+}
+
+func TestDaemon_RefusesSharedSocketDir(t *testing.T) {
+	dir, err := os.MkdirTemp("", "sd")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	t.Setenv("XDG_RUNTIME_DIR", dir)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "thicc"), 0777))
+	require.NoError(t, os.Chmod(filepath.Join(dir, "thicc"), 0777))
+
+	err = NewServer().Run(SocketPath())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expected 0700")
+	assert.False(t, IsRunning())
+	_, err = os.Stat(SocketPath())
+	assert.True(t, os.IsNotExist(err), "no socket is created")
+}
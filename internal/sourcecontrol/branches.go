@@ -0,0 +1,335 @@
+package sourcecontrol
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// ErrBranchNotMerged is returned when deleting a branch whose commits
+// aren't merged anywhere; it can only be force-deleted
+var ErrBranchNotMerged = errors.New("branch is not fully merged")
+
+// Branch is a local or remote-tracking branch
+type Branch struct {
+	Name     string // Short name, e.g. "main" or "origin/main"
+	Remote   bool   // Remote-tracking branch (refs/remotes)
+	Current  bool   // Checked out
+	Upstream string // Branch a local branch tracks, e.g. "origin/main"
+	Ahead    int    // Commits not on the upstream
+	Behind   int    // Upstream commits not on the branch
+}
+
+// branchListFormat separates fields with \x1f
+const branchListFormat = "--format=%(HEAD)%1f%(refname)%1f%(refname:short)%1f%(upstream:short)%1f%(upstream:track,nobracket)"
+
+// parseBranches parses `git for-each-ref` output in branchListFormat
+func parseBranches(output string) []Branch {
+	var local, remote []Branch
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, "\x1f")
+		if len(fields) < 5 {
+			continue
+		}
+		ref := fields[1]
+		if strings.HasSuffix(ref, "/HEAD") {
+			continue // origin/HEAD is an alias
+		}
+		b := Branch{
+			Name:     fields[2],
+			Remote:   strings.HasPrefix(ref, "refs/remotes/"),
+			Current:  fields[0] == "*",
+			Upstream: fields[3],
+		}
+		b.Ahead, b.Behind = parseTrack(fields[4])
+		if b.Remote {
+			remote = append(remote, b)
+		} else {
+			local = append(local, b)
+		}
+	}
+	return append(local, remote...)
+}
+
+// parseTrack parses %(upstream:track,nobracket), e.g. "ahead 2, behind 1"
+func parseTrack(track string) (ahead, behind int) {
+	for _, part := range strings.Split(track, ",") {
+		fields := strings.Fields(part)
+		if len(fields) != 2 {
+			continue
+		}
+		n, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "ahead":
+			ahead = n
+		case "behind":
+			behind = n
+		}
+	}
+	return ahead, behind
+}
+
+// ListBranches returns the local branches followed by the remote ones
+func ListBranches(repoRoot string) ([]Branch, error) {
+	output, err := gitOutput(repoRoot, nil, "for-each-ref", branchListFormat, "refs/heads", "refs/remotes")
+	if err != nil {
+		return nil, err
+	}
+	return parseBranches(output), nil
+}
+
+// CreateBranch creates a branch at start (HEAD if empty) and switches to it
+func CreateBranch(repoRoot, name, start string) error {
+	args := []string{"checkout", "-q", "-b", name}
+	if start != "" {
+		args = append(args, start)
+	}
+	if _, err := gitOutput(repoRoot, nil, args...); err != nil {
+		return err
+	}
+	log.Printf("THICC SourceControl: Created branch %s at %s", name, start)
+	return nil
+}
+
+// RenameBranch renames a local branch
+func RenameBranch(repoRoot, oldName, newName string) error {
+	if _, err := gitOutput(repoRoot, nil, "branch", "-m", oldName, newName); err != nil {
+		return err
+	}
+	log.Printf("THICC SourceControl: Renamed branch %s to %s", oldName, newName)
+	return nil
+}
+
+// DeleteBranch deletes a local branch. Without force, a branch with
+// unmerged commits is kept and ErrBranchNotMerged is returned.
+func DeleteBranch(repoRoot, name string, force bool) error {
+	flag := "-d"
+	if force {
+		flag = "-D"
+	} else if merged, err := branchMerged(repoRoot, name); err == nil && !merged {
+		return ErrBranchNotMerged
+	}
+	if _, err := gitOutput(repoRoot, nil, "branch", flag, name); err != nil {
+		return err
+	}
+	log.Printf("THICC SourceControl: Deleted branch %s (force=%v)", name, force)
+	return nil
+}
+
+// branchMerged reports whether a branch is merged into its upstream, or into
+// HEAD if it has none, the way `git branch -d` decides. Checked up front
+// rather than from git's message, which depends on the locale.
+func branchMerged(repoRoot, name string) (bool, error) {
+	ref := "refs/heads/" + name
+	if _, err := gitOutput(repoRoot, nil, "rev-parse", "-q", "--verify", ref); err != nil {
+		return false, err
+	}
+	target := "HEAD"
+	if _, err := gitOutput(repoRoot, nil, "rev-parse", "-q", "--verify", ref+"@{upstream}"); err == nil {
+		target = ref + "@{upstream}"
+	}
+	_, err := gitOutput(repoRoot, nil, "merge-base", "--is-ancestor", ref, target)
+	return err == nil, nil
+}
+
+// CheckoutRemoteBranch checks out a remote branch (e.g. "origin/feature") as
+// a local branch tracking it, or switches to that local branch if it exists
+func CheckoutRemoteBranch(repoRoot, remoteBranch string) error {
+	local := remoteBranch
+	if i := strings.Index(remoteBranch, "/"); i >= 0 {
+		local = remoteBranch[i+1:]
+	}
+	if _, err := gitOutput(repoRoot, nil, "rev-parse", "--verify", "-q", "refs/heads/"+local); err == nil {
+		_, err = gitOutput(repoRoot, nil, "checkout", "-q", local)
+		return err
+	}
+	if _, err := gitOutput(repoRoot, nil, "checkout", "-q", "--track", remoteBranch); err != nil {
+		return err
+	}
+	log.Printf("THICC SourceControl: Checked out %s tracking %s", local, remoteBranch)
+	return nil
+}
+
+// RefreshBranches reloads the branch list (if the dialog is open), keeping
+// the selection on the same branch
+func (p *Panel) RefreshBranches() {
+	if !p.ShowBranchDialog {
+		return
+	}
+	branches, err := ListBranches(p.RepoRoot)
+	if err != nil {
+		log.Printf("THICC SourceControl: Failed to get branches: %v", err)
+		return
+	}
+
+	selected := ""
+	if b := p.selectedBranch(); b != nil {
+		selected = b.Name
+	}
+
+	p.mu.Lock()
+	p.Branches = branches
+	for i, b := range branches {
+		if b.Name == selected {
+			p.BranchSelected = i
+		}
+	}
+	if p.BranchSelected >= len(branches) {
+		p.BranchSelected = len(branches) - 1
+	}
+	if p.BranchSelected < 0 {
+		p.BranchSelected = 0
+	}
+	p.mu.Unlock()
+}
+
+// selectedBranch returns the branch under the cursor in the dialog, or nil
+func (p *Panel) selectedBranch() *Branch {
+	if p.BranchSelected < 0 || p.BranchSelected >= len(p.Branches) {
+		return nil
+	}
+	return &p.Branches[p.BranchSelected]
+}
+
+// BranchMoveUp moves the branch dialog cursor up
+func (p *Panel) BranchMoveUp() {
+	if p.BranchSelected > 0 {
+		p.BranchSelected--
+		p.EnsureBranchVisible()
+	}
+}
+
+// BranchMoveDown moves the branch dialog cursor down
+func (p *Panel) BranchMoveDown() {
+	if p.BranchSelected < len(p.Branches)-1 {
+		p.BranchSelected++
+		p.EnsureBranchVisible()
+	}
+}
+
+// PromptNewBranch asks for a name and creates a branch at start (HEAD if
+// empty), switching to it
+func (p *Panel) PromptNewBranch(start string) {
+	if p.OnInput == nil {
+		return
+	}
+	title := "New Branch"
+	if start != "" {
+		short := start
+		if len(short) > 7 {
+			short = short[:7]
+		}
+		title = "New Branch at " + short
+	}
+	p.OnInput(title, "Branch name:", "", func(name string, canceled bool) {
+		name = strings.TrimSpace(name)
+		if canceled || name == "" {
+			return
+		}
+		err := CreateBranch(p.RepoRoot, name, start)
+		p.afterBranchOperation("Created and switched to "+name, err)
+	})
+}
+
+// CreateBranchFromSelectedCommit asks for a name and creates a branch at the
+// commit selected in the graph
+func (p *Panel) CreateBranchFromSelectedCommit() {
+	isCommit, commitIdx, _ := p.getGraphRowInfo(p.GraphSelected)
+	if !isCommit || commitIdx < 0 || commitIdx >= len(p.CommitGraph) {
+		return
+	}
+	p.PromptNewBranch(p.CommitGraph[commitIdx].Hash)
+}
+
+// PromptRenameBranch asks for a new name for the selected local branch
+func (p *Panel) PromptRenameBranch() {
+	branch := p.selectedBranch()
+	if branch == nil || p.OnInput == nil {
+		return
+	}
+	if branch.Remote {
+		p.afterBranchOperation("", errors.New("remote branches can't be renamed here"))
+		return
+	}
+	oldName := branch.Name
+	p.OnInput("Rename Branch", "New name:", oldName, func(name string, canceled bool) {
+		name = strings.TrimSpace(name)
+		if canceled || name == "" || name == oldName {
+			return
+		}
+		err := RenameBranch(p.RepoRoot, oldName, name)
+		p.afterBranchOperation("Renamed "+oldName+" to "+name, err)
+	})
+}
+
+// DeleteSelectedBranch deletes the selected local branch, asking for
+// confirmation first if it has unmerged commits
+func (p *Panel) DeleteSelectedBranch() {
+	branch := p.selectedBranch()
+	if branch == nil {
+		return
+	}
+	switch {
+	case branch.Remote:
+		p.afterBranchOperation("", errors.New("remote branches can't be deleted here"))
+		return
+	case branch.Current:
+		p.afterBranchOperation("", errors.New("can't delete the current branch"))
+		return
+	}
+
+	err := DeleteBranch(p.RepoRoot, branch.Name, false)
+	if err == ErrBranchNotMerged {
+		p.ShowForceDeleteConfirm = true
+		if p.OnRefresh != nil {
+			p.OnRefresh()
+		}
+		return
+	}
+	p.afterBranchOperation("Deleted "+branch.Name, err)
+}
+
+// hideForceDeleteConfirm hides the force delete confirmation dialog
+func (p *Panel) hideForceDeleteConfirm() {
+	p.ShowForceDeleteConfirm = false
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
+
+// confirmForceDelete force-deletes the selected (unmerged) branch
+func (p *Panel) confirmForceDelete() {
+	p.ShowForceDeleteConfirm = false
+	branch := p.selectedBranch()
+	if branch == nil {
+		return
+	}
+	name := branch.Name
+	err := DeleteBranch(p.RepoRoot, name, true)
+	p.afterBranchOperation("Deleted "+name, err)
+}
+
+// afterBranchOperation reports how a branch operation went and reloads the
+// branches, file lists and graph
+func (p *Panel) afterBranchOperation(msg string, err error) {
+	if err != nil {
+		log.Printf("THICC SourceControl: Branch operation failed: %v", err)
+		msg = fmt.Sprintf("Branch operation failed: %v", err)
+	}
+	if p.OnMessage != nil {
+		p.OnMessage(msg, err != nil)
+	}
+
+	p.RefreshBranches()
+	p.RefreshStatus()
+	p.RefreshCommitGraph()
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
@@ -0,0 +1,165 @@
+package sourcecontrol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// Branch Parsing Tests
+// =============================================================================
+
+func TestParseBranches(t *testing.T) {
+	output := "\x1frefs/remotes/origin/HEAD\x1forigin\x1f\x1f\n" +
+		"\x1frefs/remotes/origin/main\x1forigin/main\x1f\x1f\n" +
+		"*\x1frefs/heads/main\x1fmain\x1forigin/main\x1fahead 2, behind 1\n" +
+		"\x1frefs/heads/feature\x1ffeature\x1f\x1f\n"
+
+	branches := parseBranches(output)
+	require.Len(t, branches, 3)
+	assert.Equal(t, Branch{Name: "main", Current: true, Upstream: "origin/main", Ahead: 2, Behind: 1}, branches[0])
+	assert.Equal(t, "feature", branches[1].Name)
+	assert.Equal(t, Branch{Name: "origin/main", Remote: true}, branches[2], "remote branches come last, origin/HEAD is skipped")
+}
+
+func TestParseTrack(t *testing.T) {
+	ahead, behind := parseTrack("behind 3")
+	assert.Equal(t, 0, ahead)
+	assert.Equal(t, 3, behind)
+
+	ahead, behind = parseTrack("gone")
+	assert.Equal(t, 0, ahead)
+	assert.Equal(t, 0, behind)
+}
+
+// =============================================================================
+// Branch Git Tests
+// =============================================================================
+
+func currentBranch(t *testing.T, dir string) string {
+	name, err := gitOutput(dir, nil, "rev-parse", "--abbrev-ref", "HEAD")
+	require.NoError(t, err)
+	return name
+}
+
+func TestCreateBranch_FromCommit(t *testing.T) {
+	dir := initTestRepo(t)
+	first, err := gitOutput(dir, nil, "rev-parse", "HEAD")
+	require.NoError(t, err)
+	writeFile(t, dir, "main.go", "package main // two\n")
+	_, err = gitOutput(dir, nil, "commit", "-qam", "two")
+	require.NoError(t, err)
+
+	require.NoError(t, CreateBranch(dir, "old", first))
+	assert.Equal(t, "old", currentBranch(t, dir))
+	assert.Equal(t, "package main\n", readFile(t, dir, "main.go"))
+}
+
+func TestRenameBranch(t *testing.T) {
+	dir := initTestRepo(t)
+	require.NoError(t, CreateBranch(dir, "feature", ""))
+
+	require.NoError(t, RenameBranch(dir, "feature", "renamed"))
+	assert.Equal(t, "renamed", currentBranch(t, dir))
+}
+
+func TestDeleteBranch_Unmerged(t *testing.T) {
+	dir := initTestRepo(t)
+	base := currentBranch(t, dir)
+	require.NoError(t, CreateBranch(dir, "feature", ""))
+	writeFile(t, dir, "main.go", "package main // feature\n")
+	_, err := gitOutput(dir, nil, "commit", "-qam", "feature")
+	require.NoError(t, err)
+	_, err = gitOutput(dir, nil, "checkout", "-q", base)
+	require.NoError(t, err)
+
+	assert.Equal(t, ErrBranchNotMerged, DeleteBranch(dir, "feature", false))
+	require.NoError(t, DeleteBranch(dir, "feature", true))
+
+	branches, err := ListBranches(dir)
+	require.NoError(t, err)
+	require.Len(t, branches, 1)
+	assert.Equal(t, base, branches[0].Name)
+}
+
+func TestDeleteBranch_Missing(t *testing.T) {
+	dir := initTestRepo(t)
+
+	err := DeleteBranch(dir, "nope", false)
+	require.Error(t, err)
+	assert.NotEqual(t, ErrBranchNotMerged, err, "git's own error is returned")
+}
+
+func TestCheckoutRemoteBranch_Tracks(t *testing.T) {
+	upstream := initTestRepo(t)
+	_, err := gitOutput(upstream, nil, "branch", "feature")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	_, err = gitOutput(dir, nil, "clone", "-q", upstream, ".")
+	require.NoError(t, err)
+
+	require.NoError(t, CheckoutRemoteBranch(dir, "origin/feature"))
+	assert.Equal(t, "feature", currentBranch(t, dir))
+
+	branches, err := ListBranches(dir)
+	require.NoError(t, err)
+	var feature *Branch
+	for i := range branches {
+		if branches[i].Name == "feature" {
+			feature = &branches[i]
+		}
+	}
+	require.NotNil(t, feature)
+	assert.Equal(t, "origin/feature", feature.Upstream)
+	assert.True(t, feature.Current)
+}
+
+// =============================================================================
+// Branch Dialog Panel Tests
+// =============================================================================
+
+func TestBranchPanel_DeleteUnmergedAsksForConfirmation(t *testing.T) {
+	dir := initTestRepo(t)
+	base := currentBranch(t, dir)
+	require.NoError(t, CreateBranch(dir, "feature", ""))
+	writeFile(t, dir, "main.go", "package main // feature\n")
+	_, err := gitOutput(dir, nil, "commit", "-qam", "feature")
+	require.NoError(t, err)
+	_, err = gitOutput(dir, nil, "checkout", "-q", base)
+	require.NoError(t, err)
+
+	p := &Panel{RepoRoot: dir}
+	p.ShowBranchSwitcher()
+	require.Len(t, p.Branches, 2)
+	for i, b := range p.Branches {
+		if b.Name == "feature" {
+			p.BranchSelected = i
+		}
+	}
+
+	p.DeleteSelectedBranch()
+	require.True(t, p.ShowForceDeleteConfirm)
+
+	p.confirmForceDelete()
+	assert.False(t, p.ShowForceDeleteConfirm)
+	require.Len(t, p.Branches, 1)
+	assert.Equal(t, base, p.Branches[0].Name)
+}
+
+func TestBranchPanel_NewBranchFromInput(t *testing.T) {
+	dir := initTestRepo(t)
+
+	var msg string
+	p := &Panel{RepoRoot: dir}
+	p.OnMessage = func(m string, _ bool) { msg = m }
+	p.OnInput = func(_, _, _ string, done func(string, bool)) { done(" topic ", false) }
+
+	p.ShowBranchSwitcher()
+	p.PromptNewBranch("")
+	assert.Equal(t, "Created and switched to topic", msg)
+	assert.Equal(t, "topic", currentBranch(t, dir))
+	assert.Len(t, p.Branches, 2, "the dialog list is reloaded")
+}
@@ -0,0 +1,518 @@
+package sourcecontrol
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Checkpoints are snapshots of the whole working tree (tracked and untracked,
+// minus ignored files) taken around AI agent turns. Each one is a commit on
+// top of HEAD kept under a private ref, so it never shows up in branches,
+// the stash or the commit graph, and the real index is never touched.
+
+// CheckpointRefPrefix is the ref namespace checkpoints are stored under
+const CheckpointRefPrefix = "refs/thicc/checkpoints/"
+
+// MaxCheckpoints is how many checkpoints are kept per repository
+const MaxCheckpoints = 100
+
+// Checkpoint is a snapshot of the working tree
+type Checkpoint struct {
+	Ref     string // Full ref name
+	Hash    string // Snapshot commit
+	Tree    string // Snapshot tree (equal trees mean nothing changed)
+	Session string // Terminal session that took it
+	Seq     int    // Creation order, across all sessions
+	Label   string // e.g. "Before turn 2"
+	Time    time.Time
+}
+
+// ShortHash returns the abbreviated snapshot commit hash
+func (c Checkpoint) ShortHash() string {
+	if len(c.Hash) > 7 {
+		return c.Hash[:7]
+	}
+	return c.Hash
+}
+
+// checkpointRef returns the ref name for a session's checkpoint
+func checkpointRef(session string, seq int) string {
+	return fmt.Sprintf("%s%s/%05d", CheckpointRefPrefix, session, seq)
+}
+
+// parseCheckpointRef splits a checkpoint ref into its session and sequence number
+func parseCheckpointRef(ref string) (session string, seq int, ok bool) {
+	rest := strings.TrimPrefix(ref, CheckpointRefPrefix)
+	if rest == ref {
+		return "", 0, false
+	}
+	idx := strings.LastIndex(rest, "/")
+	if idx <= 0 {
+		return "", 0, false
+	}
+	seq, err := strconv.Atoi(rest[idx+1:])
+	if err != nil {
+		return "", 0, false
+	}
+	return rest[:idx], seq, true
+}
+
+// parseCheckpoints parses for-each-ref output (see ListCheckpoints), newest first
+func parseCheckpoints(output string) []Checkpoint {
+	var cps []Checkpoint
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, "\x00")
+		if len(fields) != 5 {
+			continue
+		}
+		session, seq, ok := parseCheckpointRef(fields[0])
+		if !ok {
+			continue
+		}
+		unix, _ := strconv.ParseInt(fields[4], 10, 64)
+		cps = append(cps, Checkpoint{
+			Ref:     fields[0],
+			Hash:    fields[1],
+			Tree:    fields[2],
+			Session: session,
+			Seq:     seq,
+			Label:   fields[3],
+			Time:    time.Unix(unix, 0),
+		})
+	}
+
+	// Commit dates only have second resolution, so order by sequence
+	sort.SliceStable(cps, func(i, j int) bool {
+		return cps[i].Seq > cps[j].Seq
+	})
+	return cps
+}
+
+// ListCheckpoints returns the repository's checkpoints, newest first
+func ListCheckpoints(repoRoot string) ([]Checkpoint, error) {
+	cmd := exec.Command("git", "for-each-ref",
+		"--format=%(refname)%00%(objectname)%00%(tree)%00%(subject)%00%(committerdate:unix)",
+		CheckpointRefPrefix)
+	cmd.Dir = repoRoot
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git for-each-ref failed: %w", err)
+	}
+	return parseCheckpoints(string(output)), nil
+}
+
+// CreateCheckpoint snapshots the working tree for a session. Returns nil (and
+// no error) when nothing changed since the session's previous checkpoint.
+func CreateCheckpoint(repoRoot, session, label string) (*Checkpoint, error) {
+	tree, err := snapshotTree(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := ListCheckpoints(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	seq := 1
+	if len(existing) > 0 {
+		seq = existing[0].Seq + 1
+	}
+	for _, cp := range existing {
+		if cp.Session == session {
+			if cp.Tree == tree {
+				// The session's latest checkpoint already has this content
+				log.Printf("THICC SourceControl: Skipping checkpoint %q, tree unchanged", label)
+				return nil, nil
+			}
+			break
+		}
+	}
+
+	args := []string{"commit-tree", "--no-gpg-sign", "-m", label}
+	if head, err := gitOutput(repoRoot, nil, "rev-parse", "--verify", "-q", "HEAD"); err == nil && head != "" {
+		args = append(args, "-p", head)
+	}
+	args = append(args, tree)
+	hash, err := gitOutput(repoRoot, checkpointIdentity(), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	ref := checkpointRef(session, seq)
+	if _, err := gitOutput(repoRoot, nil, "update-ref", ref, hash); err != nil {
+		return nil, err
+	}
+	log.Printf("THICC SourceControl: Created checkpoint %s (%s) at %s", ref, label, hash[:7])
+
+	pruneCheckpoints(repoRoot, append([]Checkpoint{{Ref: ref}}, existing...), MaxCheckpoints)
+
+	return &Checkpoint{
+		Ref:     ref,
+		Hash:    hash,
+		Tree:    tree,
+		Session: session,
+		Seq:     seq,
+		Label:   label,
+		Time:    time.Now(),
+	}, nil
+}
+
+// DeleteCheckpoint removes a checkpoint ref
+func DeleteCheckpoint(repoRoot string, cp Checkpoint) error {
+	_, err := gitOutput(repoRoot, nil, "update-ref", "-d", cp.Ref)
+	return err
+}
+
+// pruneCheckpoints deletes all but the newest keep checkpoints (cps is newest first)
+func pruneCheckpoints(repoRoot string, cps []Checkpoint, keep int) {
+	if len(cps) <= keep {
+		return
+	}
+	for _, cp := range cps[keep:] {
+		if err := DeleteCheckpoint(repoRoot, cp); err != nil {
+			log.Printf("THICC SourceControl: Failed to prune checkpoint %s: %v", cp.Ref, err)
+		}
+	}
+	log.Printf("THICC SourceControl: Pruned %d old checkpoints", len(cps)-keep)
+}
+
+// DiffCheckpoints returns the patch from one checkpoint to another. An empty
+// to diffs against the current working tree.
+func DiffCheckpoints(repoRoot, from, to string) (string, error) {
+	if to == "" {
+		tree, err := snapshotTree(repoRoot)
+		if err != nil {
+			return "", err
+		}
+		to = tree
+	}
+	cmd := exec.Command("git", "diff", "--no-color", "--no-ext-diff", from, to)
+	cmd.Dir = repoRoot
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git diff failed: %w", err)
+	}
+	return string(output), nil
+}
+
+// RestoreCheckpoint rolls the working tree back to a checkpoint: files are
+// rewritten to their snapshot content and files created since are deleted.
+// Ignored files and the index are left alone. A "Before restore" checkpoint
+// is taken first so the rollback itself can be undone.
+func RestoreCheckpoint(repoRoot string, cp Checkpoint) error {
+	if _, err := CreateCheckpoint(repoRoot, "restore", "Before restore to "+cp.ShortHash()); err != nil {
+		return fmt.Errorf("safety checkpoint failed: %w", err)
+	}
+
+	current, err := snapshotTree(repoRoot)
+	if err != nil {
+		return err
+	}
+	added, err := gitOutput(repoRoot, nil, "diff", "--name-only", "-z", "--no-renames", "--diff-filter=A", cp.Tree, current)
+	if err != nil {
+		return err
+	}
+	for _, path := range strings.Split(added, "\x00") {
+		if path == "" {
+			continue
+		}
+		if err := os.Remove(filepath.Join(repoRoot, path)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	// Check out the snapshot through a throwaway index
+	indexFile, err := os.CreateTemp("", "thicc-checkpoint-index-*")
+	if err != nil {
+		return err
+	}
+	indexPath := indexFile.Name()
+	indexFile.Close()
+	os.Remove(indexPath)
+	defer os.Remove(indexPath)
+
+	env := []string{"GIT_INDEX_FILE=" + indexPath}
+	if _, err := gitOutput(repoRoot, env, "read-tree", cp.Tree); err != nil {
+		return err
+	}
+	if _, err := gitOutput(repoRoot, env, "checkout-index", "-a", "-f"); err != nil {
+		return err
+	}
+
+	log.Printf("THICC SourceControl: Restored working tree to checkpoint %s (%s), removed %d files",
+		cp.Ref, cp.Label, strings.Count(added, "\x00"))
+	return nil
+}
+
+// snapshotTree writes the working tree (including untracked files, excluding
+// ignored ones) to a git tree object and returns its hash. A copy of the
+// index is used so git can reuse its stat cache without touching the original.
+func snapshotTree(repoRoot string) (string, error) {
+	indexFile, err := os.CreateTemp("", "thicc-checkpoint-index-*")
+	if err != nil {
+		return "", err
+	}
+	indexPath := indexFile.Name()
+	defer os.Remove(indexPath)
+
+	copied := false
+	if realIndex, err := gitOutput(repoRoot, nil, "rev-parse", "--git-path", "index"); err == nil {
+		if !filepath.IsAbs(realIndex) {
+			realIndex = filepath.Join(repoRoot, realIndex)
+		}
+		if src, err := os.Open(realIndex); err == nil {
+			_, err = io.Copy(indexFile, src)
+			src.Close()
+			copied = err == nil
+		}
+	}
+	indexFile.Close()
+	if !copied {
+		// git wants a missing index rather than an empty file
+		os.Remove(indexPath)
+	}
+
+	env := []string{"GIT_INDEX_FILE=" + indexPath}
+	if _, err := gitOutput(repoRoot, env, "add", "-A", "--", "."); err != nil {
+		return "", err
+	}
+	return gitOutput(repoRoot, env, "write-tree")
+}
+
+// checkpointIdentity is the author of snapshot commits, so creating one never
+// depends on the user's git identity being configured
+func checkpointIdentity() []string {
+	return []string{
+		"GIT_AUTHOR_NAME=thicc",
+		"GIT_AUTHOR_EMAIL=thicc@localhost",
+		"GIT_COMMITTER_NAME=thicc",
+		"GIT_COMMITTER_EMAIL=thicc@localhost",
+	}
+}
+
+// gitOutput runs a git command in repoRoot with extra environment variables
+// and returns its trimmed stdout
+func gitOutput(repoRoot string, env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoRoot
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("git %s failed: %v: %s", args[0], err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return strings.TrimRight(string(output), "\n"), nil
+}
+
+// ShowCheckpointTimeline opens the checkpoint timeline
+func (p *Panel) ShowCheckpointTimeline() {
+	cps, err := ListCheckpoints(p.RepoRoot)
+	if err != nil {
+		log.Printf("THICC SourceControl: Failed to list checkpoints: %v", err)
+		return
+	}
+
+	p.Checkpoints = cps
+	p.CheckpointSelected = 0
+	p.CheckpointTopLine = 0
+	p.CheckpointMark = -1
+	p.ShowCheckpoints = true
+	log.Printf("THICC SourceControl: Browsing %d checkpoints", len(cps))
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
+
+// HideCheckpointTimeline closes the checkpoint timeline
+func (p *Panel) HideCheckpointTimeline() {
+	p.ShowCheckpoints = false
+	p.ShowRestoreConfirm = false
+	p.Checkpoints = nil
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
+
+// RefreshCheckpoints reloads the timeline (if open) after checkpoints were
+// added or removed, keeping the selection and mark on the same checkpoints
+func (p *Panel) RefreshCheckpoints() {
+	if !p.ShowCheckpoints {
+		return
+	}
+	cps, err := ListCheckpoints(p.RepoRoot)
+	if err != nil {
+		log.Printf("THICC SourceControl: Failed to list checkpoints: %v", err)
+		return
+	}
+
+	selected, marked := "", ""
+	if cp := p.selectedCheckpoint(); cp != nil {
+		selected = cp.Ref
+	}
+	if p.CheckpointMark >= 0 && p.CheckpointMark < len(p.Checkpoints) {
+		marked = p.Checkpoints[p.CheckpointMark].Ref
+	}
+
+	p.mu.Lock()
+	p.Checkpoints = cps
+	p.CheckpointSelected = 0
+	p.CheckpointMark = -1
+	for i, cp := range cps {
+		if cp.Ref == selected {
+			p.CheckpointSelected = i
+		}
+		if cp.Ref == marked {
+			p.CheckpointMark = i
+		}
+	}
+	p.mu.Unlock()
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
+
+// selectedCheckpoint returns the checkpoint under the cursor, or nil
+func (p *Panel) selectedCheckpoint() *Checkpoint {
+	if p.CheckpointSelected < 0 || p.CheckpointSelected >= len(p.Checkpoints) {
+		return nil
+	}
+	return &p.Checkpoints[p.CheckpointSelected]
+}
+
+// CheckpointMoveUp moves the timeline cursor to the next newer checkpoint
+func (p *Panel) CheckpointMoveUp() {
+	if p.CheckpointSelected > 0 {
+		p.CheckpointSelected--
+	}
+}
+
+// CheckpointMoveDown moves the timeline cursor to the next older checkpoint
+func (p *Panel) CheckpointMoveDown() {
+	if p.CheckpointSelected < len(p.Checkpoints)-1 {
+		p.CheckpointSelected++
+	}
+}
+
+// ToggleCheckpointMark marks the selected checkpoint as the other side of
+// the next diff, or clears the mark
+func (p *Panel) ToggleCheckpointMark() {
+	if p.selectedCheckpoint() == nil {
+		return
+	}
+	if p.CheckpointMark == p.CheckpointSelected {
+		p.CheckpointMark = -1
+	} else {
+		p.CheckpointMark = p.CheckpointSelected
+	}
+}
+
+// checkpointTitle describes a checkpoint in diff titles
+func checkpointTitle(cp Checkpoint) string {
+	return fmt.Sprintf("%s (%s)", cp.Label, cp.ShortHash())
+}
+
+// DiffSelectedCheckpoint shows what changed between the marked checkpoint
+// and the selected one (oldest to newest), or, with no mark, between the
+// selected checkpoint and the working tree
+func (p *Panel) DiffSelectedCheckpoint() {
+	cp := p.selectedCheckpoint()
+	if cp == nil {
+		return
+	}
+
+	if p.CheckpointMark >= 0 && p.CheckpointMark < len(p.Checkpoints) && p.CheckpointMark != p.CheckpointSelected {
+		older, newer := p.Checkpoints[p.CheckpointMark], *cp
+		if p.CheckpointMark < p.CheckpointSelected {
+			older, newer = newer, older // List is newest first
+		}
+		p.showCheckpointDiff(older.Hash, newer.Hash, checkpointTitle(older)+" → "+checkpointTitle(newer))
+		return
+	}
+	p.showCheckpointDiff(cp.Hash, "", checkpointTitle(*cp)+" → working tree")
+}
+
+// DiffCheckpointWithPrevious shows what changed since the previous
+// checkpoint of the same session (for an "after" checkpoint, the agent's
+// turn). A session's first checkpoint is compared with its HEAD commit.
+func (p *Panel) DiffCheckpointWithPrevious() {
+	cp := p.selectedCheckpoint()
+	if cp == nil {
+		return
+	}
+	for _, prev := range p.Checkpoints[p.CheckpointSelected+1:] {
+		if prev.Session == cp.Session {
+			p.showCheckpointDiff(prev.Hash, cp.Hash, checkpointTitle(prev)+" → "+checkpointTitle(*cp))
+			return
+		}
+	}
+	p.showCheckpointDiff(cp.Hash+"^", cp.Hash, "HEAD → "+checkpointTitle(*cp))
+}
+
+// showCheckpointDiff computes a diff and reports it via OnShowDiff
+func (p *Panel) showCheckpointDiff(from, to, title string) {
+	patch, err := DiffCheckpoints(p.RepoRoot, from, to)
+	if err != nil {
+		log.Printf("THICC SourceControl: Failed to diff checkpoints: %v", err)
+		return
+	}
+	log.Printf("THICC SourceControl: Diffing %s (%d bytes)", title, len(patch))
+	if p.OnShowDiff != nil {
+		p.OnShowDiff(title, patch)
+	}
+}
+
+// showRestoreConfirm asks for confirmation before rolling back to the
+// selected checkpoint
+func (p *Panel) showRestoreConfirm() {
+	if p.selectedCheckpoint() == nil {
+		return
+	}
+	p.ShowRestoreConfirm = true
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
+
+// hideRestoreConfirm hides the restore confirmation dialog
+func (p *Panel) hideRestoreConfirm() {
+	p.ShowRestoreConfirm = false
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
+
+// confirmRestore rolls the working tree back to the selected checkpoint
+func (p *Panel) confirmRestore() {
+	cp := p.selectedCheckpoint()
+	p.ShowRestoreConfirm = false
+	if cp == nil {
+		return
+	}
+
+	if err := RestoreCheckpoint(p.RepoRoot, *cp); err != nil {
+		log.Printf("THICC SourceControl: Failed to restore checkpoint: %v", err)
+	}
+
+	p.RefreshStatus()
+	p.RefreshCheckpoints()
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
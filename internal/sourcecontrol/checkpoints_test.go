@@ -0,0 +1,287 @@
+package sourcecontrol
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// initTestRepo creates a git repository with one committed file
+func initTestRepo(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.name", "Test"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "commit.gpgsign", "false"},
+	} {
+		_, err := gitOutput(dir, nil, args...)
+		require.NoError(t, err)
+	}
+	writeFile(t, dir, "main.go", "package main\n")
+	writeFile(t, dir, ".gitignore", "*.log\n")
+	_, err := gitOutput(dir, nil, "add", "-A")
+	require.NoError(t, err)
+	_, err = gitOutput(dir, nil, "commit", "-q", "-m", "initial")
+	require.NoError(t, err)
+	return dir
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+}
+
+func readFile(t *testing.T, dir, name string) string {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	require.NoError(t, err)
+	return string(data)
+}
+
+// =============================================================================
+// Checkpoint Ref Tests
+// =============================================================================
+
+func TestCheckpointRef_RoundTrip(t *testing.T) {
+	ref := checkpointRef("terminal-1-20260102-150405", 7)
+	assert.Equal(t, "refs/thicc/checkpoints/terminal-1-20260102-150405/00007", ref)
+
+	session, seq, ok := parseCheckpointRef(ref)
+	assert.True(t, ok)
+	assert.Equal(t, "terminal-1-20260102-150405", session)
+	assert.Equal(t, 7, seq)
+}
+
+func TestParseCheckpointRef_Invalid(t *testing.T) {
+	for _, ref := range []string{"refs/heads/main", "refs/thicc/checkpoints/", "refs/thicc/checkpoints/s/x"} {
+		_, _, ok := parseCheckpointRef(ref)
+		assert.False(t, ok, ref)
+	}
+}
+
+func TestParseCheckpoints_NewestFirst(t *testing.T) {
+	output := "refs/thicc/checkpoints/b/00002\x00h2\x00t2\x00Before turn 1\x00200\n" +
+		"refs/thicc/checkpoints/a/00001\x00h1\x00t1\x00Before turn 1\x00100\n" +
+		"refs/thicc/checkpoints/a/00003\x00h3\x00t3\x00After turn 1\x00200\n" +
+		"refs/heads/main\x00h\x00t\x00x\x00300\n"
+
+	cps := parseCheckpoints(output)
+	require.Len(t, cps, 3)
+	assert.Equal(t, 3, cps[0].Seq, "same second sorts by sequence, across sessions")
+	assert.Equal(t, "b", cps[1].Session)
+	assert.Equal(t, "Before turn 1", cps[2].Label)
+	assert.Equal(t, time.Unix(100, 0), cps[2].Time)
+}
+
+// =============================================================================
+// Checkpoint Git Tests
+// =============================================================================
+
+func TestCreateCheckpoint_IncludesUntrackedFiles(t *testing.T) {
+	dir := initTestRepo(t)
+	writeFile(t, dir, "main.go", "package main\n\nfunc main() {}\n")
+	writeFile(t, dir, "new.go", "package main\n")
+
+	cp, err := CreateCheckpoint(dir, "s1", "Before turn 1")
+	require.NoError(t, err)
+	require.NotNil(t, cp)
+	assert.Equal(t, 1, cp.Seq)
+
+	files, err := gitOutput(dir, nil, "ls-tree", "--name-only", cp.Hash)
+	require.NoError(t, err)
+	assert.Contains(t, files, "new.go")
+
+	// The real index is untouched
+	staged, err := gitOutput(dir, nil, "diff", "--cached", "--name-only")
+	require.NoError(t, err)
+	assert.Empty(t, staged)
+}
+
+func TestCreateCheckpoint_SkipsUnchangedTree(t *testing.T) {
+	dir := initTestRepo(t)
+
+	first, err := CreateCheckpoint(dir, "s1", "Before turn 1")
+	require.NoError(t, err)
+	require.NotNil(t, first)
+
+	second, err := CreateCheckpoint(dir, "s1", "After turn 1")
+	require.NoError(t, err)
+	assert.Nil(t, second)
+
+	// Another session still gets its own checkpoint
+	other, err := CreateCheckpoint(dir, "s2", "Before turn 1")
+	require.NoError(t, err)
+	assert.NotNil(t, other)
+}
+
+func TestCreateCheckpoint_IgnoredFilesLeftOut(t *testing.T) {
+	dir := initTestRepo(t)
+	writeFile(t, dir, "debug.log", "noise\n")
+	writeFile(t, dir, "main.go", "package main // changed\n")
+
+	cp, err := CreateCheckpoint(dir, "s1", "Before turn 1")
+	require.NoError(t, err)
+	require.NotNil(t, cp)
+
+	files, err := gitOutput(dir, nil, "ls-tree", "--name-only", cp.Hash)
+	require.NoError(t, err)
+	assert.NotContains(t, files, "debug.log")
+}
+
+func TestListCheckpoints(t *testing.T) {
+	dir := initTestRepo(t)
+	_, err := CreateCheckpoint(dir, "s1", "Before turn 1")
+	require.NoError(t, err)
+	writeFile(t, dir, "main.go", "package main // turn 1\n")
+	_, err = CreateCheckpoint(dir, "s1", "After turn 1")
+	require.NoError(t, err)
+
+	cps, err := ListCheckpoints(dir)
+	require.NoError(t, err)
+	require.Len(t, cps, 2)
+	assert.Equal(t, "After turn 1", cps[0].Label)
+	assert.Equal(t, 2, cps[0].Seq)
+	assert.Equal(t, 1, cps[1].Seq)
+	assert.Equal(t, "s1", cps[0].Session)
+}
+
+func TestDiffCheckpoints(t *testing.T) {
+	dir := initTestRepo(t)
+	before, err := CreateCheckpoint(dir, "s1", "Before turn 1")
+	require.NoError(t, err)
+	writeFile(t, dir, "main.go", "package main // turn 1\n")
+	after, err := CreateCheckpoint(dir, "s1", "After turn 1")
+	require.NoError(t, err)
+
+	patch, err := DiffCheckpoints(dir, before.Hash, after.Hash)
+	require.NoError(t, err)
+	assert.Contains(t, patch, "+package main // turn 1")
+
+	// Against the working tree, including a new untracked file
+	writeFile(t, dir, "extra.go", "package main\n")
+	patch, err = DiffCheckpoints(dir, after.Hash, "")
+	require.NoError(t, err)
+	assert.Contains(t, patch, "b/extra.go")
+	assert.NotContains(t, patch, "main.go")
+}
+
+func TestRestoreCheckpoint(t *testing.T) {
+	dir := initTestRepo(t)
+	writeFile(t, dir, "main.go", "package main // mine\n")
+	cp, err := CreateCheckpoint(dir, "s1", "Before turn 1")
+	require.NoError(t, err)
+
+	// The "agent" edits a file, adds one, deletes one and writes a log
+	writeFile(t, dir, "main.go", "package main // agent\n")
+	writeFile(t, dir, "agent.go", "package main\n")
+	writeFile(t, dir, "agent.log", "ignored\n")
+	require.NoError(t, os.Remove(filepath.Join(dir, ".gitignore")))
+
+	require.NoError(t, RestoreCheckpoint(dir, *cp))
+
+	assert.Equal(t, "package main // mine\n", readFile(t, dir, "main.go"))
+	assert.Equal(t, "*.log\n", readFile(t, dir, ".gitignore"))
+	_, err = os.Stat(filepath.Join(dir, "agent.go"))
+	assert.True(t, os.IsNotExist(err), "files added since the checkpoint are removed")
+
+	// A safety checkpoint of the pre-restore state was taken
+	cps, err := ListCheckpoints(dir)
+	require.NoError(t, err)
+	require.Len(t, cps, 2)
+	assert.Equal(t, "restore", cps[0].Session)
+	files, err := gitOutput(dir, nil, "ls-tree", "--name-only", cps[0].Hash)
+	require.NoError(t, err)
+	assert.Contains(t, files, "agent.go")
+}
+
+func TestPruneCheckpoints_KeepsNewest(t *testing.T) {
+	dir := initTestRepo(t)
+	for i := 0; i < 3; i++ {
+		writeFile(t, dir, "main.go", "package main // "+string(rune('a'+i))+"\n")
+		_, err := CreateCheckpoint(dir, "s1", "Checkpoint")
+		require.NoError(t, err)
+	}
+
+	cps, err := ListCheckpoints(dir)
+	require.NoError(t, err)
+	pruneCheckpoints(dir, cps, 1)
+
+	left, err := ListCheckpoints(dir)
+	require.NoError(t, err)
+	require.Len(t, left, 1)
+	assert.Equal(t, cps[0].Ref, left[0].Ref)
+}
+
+// =============================================================================
+// Checkpoint Timeline Tests
+// =============================================================================
+
+func TestDiffSelectedCheckpoint_MarkedPairIsOldestFirst(t *testing.T) {
+	dir := initTestRepo(t)
+	_, err := CreateCheckpoint(dir, "s1", "Before turn 1")
+	require.NoError(t, err)
+	writeFile(t, dir, "main.go", "package main // turn 1\n")
+	_, err = CreateCheckpoint(dir, "s1", "After turn 1")
+	require.NoError(t, err)
+
+	var title, patch string
+	p := &Panel{RepoRoot: dir}
+	p.OnShowDiff = func(tt, pp string) { title, patch = tt, pp }
+	p.ShowCheckpointTimeline()
+	require.Len(t, p.Checkpoints, 2)
+
+	// Mark the newer one, select the older one
+	p.ToggleCheckpointMark()
+	p.CheckpointMoveDown()
+	p.DiffSelectedCheckpoint()
+
+	assert.Regexp(t, `^Before turn 1 \(\w+\) → After turn 1`, title)
+	assert.Contains(t, patch, "+package main // turn 1")
+}
+
+func TestDiffCheckpointWithPrevious_SameSession(t *testing.T) {
+	dir := initTestRepo(t)
+	_, err := CreateCheckpoint(dir, "s1", "Before turn 1")
+	require.NoError(t, err)
+	writeFile(t, dir, "main.go", "package main // other session\n")
+	_, err = CreateCheckpoint(dir, "s2", "Before turn 1")
+	require.NoError(t, err)
+	writeFile(t, dir, "main.go", "package main // turn 1\n")
+	_, err = CreateCheckpoint(dir, "s1", "After turn 1")
+	require.NoError(t, err)
+
+	var patch string
+	p := &Panel{RepoRoot: dir}
+	p.OnShowDiff = func(_, pp string) { patch = pp }
+	p.ShowCheckpointTimeline()
+	p.DiffCheckpointWithPrevious()
+
+	assert.Contains(t, patch, "-package main\n")
+	assert.Contains(t, patch, "+package main // turn 1")
+}
+
+func TestToggleCheckpointMark(t *testing.T) {
+	p := &Panel{Checkpoints: make([]Checkpoint, 2), CheckpointMark: -1}
+
+	p.ToggleCheckpointMark()
+	assert.Equal(t, 0, p.CheckpointMark)
+	p.ToggleCheckpointMark()
+	assert.Equal(t, -1, p.CheckpointMark)
+}
+
+func TestCheckpointMove_StaysInBounds(t *testing.T) {
+	p := &Panel{Checkpoints: make([]Checkpoint, 2)}
+
+	p.CheckpointMoveUp()
+	assert.Equal(t, 0, p.CheckpointSelected)
+	p.CheckpointMoveDown()
+	p.CheckpointMoveDown()
+	assert.Equal(t, 1, p.CheckpointSelected)
+}
@@ -0,0 +1,366 @@
+package sourcecontrol
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ellery/thicc/internal/clipboard"
+)
+
+// CommitAction identifies an entry of the commit menu
+type CommitAction int
+
+const (
+	CommitCherryPick CommitAction = iota
+	CommitRevert
+	CommitResetSoft
+	CommitResetMixed
+	CommitResetHard
+	CommitCheckout
+	CommitBranch
+	CommitTag
+	CommitAnnotatedTag
+	CommitCopyHash
+	CommitFixup
+	CommitAbort
+)
+
+// CommitMenuItem is an entry of the commit menu
+type CommitMenuItem struct {
+	Action   CommitAction
+	Label    string
+	Shortcut rune
+}
+
+// commitMenuItems are the operations offered for a commit in the graph
+var commitMenuItems = []CommitMenuItem{
+	{Action: CommitCherryPick, Label: "Cherry-pick onto current branch", Shortcut: 'c'},
+	{Action: CommitRevert, Label: "Revert", Shortcut: 'r'},
+	{Action: CommitResetSoft, Label: "Reset here (soft)", Shortcut: 's'},
+	{Action: CommitResetMixed, Label: "Reset here (mixed)", Shortcut: 'm'},
+	{Action: CommitResetHard, Label: "Reset here (hard)", Shortcut: 'h'},
+	{Action: CommitCheckout, Label: "Checkout (detached HEAD)", Shortcut: 'o'},
+	{Action: CommitBranch, Label: "Create branch here", Shortcut: 'b'},
+	{Action: CommitTag, Label: "Create tag", Shortcut: 't'},
+	{Action: CommitAnnotatedTag, Label: "Create annotated tag", Shortcut: 'a'},
+	{Action: CommitCopyHash, Label: "Copy hash", Shortcut: 'y'},
+	{Action: CommitFixup, Label: "Fixup staged changes into this", Shortcut: 'f'},
+}
+
+// Operations git can stop in the middle of on a conflict, named as in the
+// git commands
+const (
+	SequencerCherryPick = "cherry-pick"
+	SequencerRevert     = "revert"
+)
+
+// SequencerState returns the cherry-pick or revert git stopped in the middle
+// of on a conflict, or "" if there is none
+func SequencerState(repoRoot string) string {
+	dir := gitDir(repoRoot)
+	if _, err := os.Stat(filepath.Join(dir, "CHERRY_PICK_HEAD")); err == nil {
+		return SequencerCherryPick
+	}
+	if _, err := os.Stat(filepath.Join(dir, "REVERT_HEAD")); err == nil {
+		return SequencerRevert
+	}
+	return ""
+}
+
+// AbortSequencer aborts the cherry-pick or revert git stopped in, putting
+// the branch back where it was
+func AbortSequencer(repoRoot string) error {
+	state := SequencerState(repoRoot)
+	if state == "" {
+		return fmt.Errorf("no cherry-pick or revert in progress")
+	}
+	if _, err := gitOutput(repoRoot, nil, state, "--abort"); err != nil {
+		return err
+	}
+	log.Printf("THICC SourceControl: Aborted %s", state)
+	return nil
+}
+
+// sequencerError explains a cherry-pick or revert that stopped on a
+// conflict, which leaves the repository in the middle of it
+func sequencerError(repoRoot string, err error) error {
+	if state := SequencerState(repoRoot); state != "" {
+		return fmt.Errorf("%s stopped on conflicts: resolve the [!] files and commit, or abort it from the commit menu", state)
+	}
+	return err
+}
+
+// resetModes maps the reset menu entries to `git reset` modes
+var resetModes = map[CommitAction]string{
+	CommitResetSoft:  "soft",
+	CommitResetMixed: "mixed",
+	CommitResetHard:  "hard",
+}
+
+// CherryPick applies a commit onto the current branch. Merge commits are
+// picked relative to their first parent.
+func CherryPick(repoRoot string, c CommitEntry) error {
+	args := []string{"cherry-pick"}
+	if c.IsMerge {
+		args = append(args, "-m", "1")
+	}
+	if _, err := gitOutput(repoRoot, nil, append(args, c.Hash)...); err != nil {
+		return sequencerError(repoRoot, err)
+	}
+	log.Printf("THICC SourceControl: Cherry-picked %s", c.ShortHash)
+	return nil
+}
+
+// Revert commits the inverse of a commit. Merge commits are reverted
+// relative to their first parent.
+func Revert(repoRoot string, c CommitEntry) error {
+	args := []string{"revert", "--no-edit"}
+	if c.IsMerge {
+		args = append(args, "-m", "1")
+	}
+	if _, err := gitOutput(repoRoot, nil, append(args, c.Hash)...); err != nil {
+		return sequencerError(repoRoot, err)
+	}
+	log.Printf("THICC SourceControl: Reverted %s", c.ShortHash)
+	return nil
+}
+
+// ResetTo moves the current branch to a commit; mode is "soft", "mixed" or "hard"
+func ResetTo(repoRoot, hash, mode string) error {
+	if _, err := gitOutput(repoRoot, nil, "reset", "-q", "--"+mode, hash); err != nil {
+		return err
+	}
+	log.Printf("THICC SourceControl: Reset (%s) to %s", mode, hash)
+	return nil
+}
+
+// CheckoutDetached checks out a commit as a detached HEAD
+func CheckoutDetached(repoRoot, hash string) error {
+	if _, err := gitOutput(repoRoot, nil, "checkout", "-q", "--detach", hash); err != nil {
+		return err
+	}
+	log.Printf("THICC SourceControl: Checked out %s (detached)", hash)
+	return nil
+}
+
+// CreateTag tags a commit. The tag is annotated if message isn't empty.
+func CreateTag(repoRoot, name, hash, message string) error {
+	args := []string{"tag", name, hash}
+	if message != "" {
+		args = []string{"tag", "-a", name, "-m", message, hash}
+	}
+	if _, err := gitOutput(repoRoot, nil, args...); err != nil {
+		return err
+	}
+	log.Printf("THICC SourceControl: Tagged %s as %s (annotated=%v)", hash, name, message != "")
+	return nil
+}
+
+// ShowCommitMenuForSelected opens the commit menu for the commit selected in
+// the graph
+func (p *Panel) ShowCommitMenuForSelected() {
+	isCommit, commitIdx, _ := p.getGraphRowInfo(p.GraphSelected)
+	if !isCommit || commitIdx < 0 || commitIdx >= len(p.CommitGraph) {
+		return
+	}
+	p.CommitMenuCommit = p.CommitGraph[commitIdx]
+	p.CommitMenuItems = commitMenuItems
+	if state := SequencerState(p.RepoRoot); state != "" {
+		p.CommitMenuItems = append(append([]CommitMenuItem{}, commitMenuItems...),
+			CommitMenuItem{Action: CommitAbort, Label: "Abort " + state, Shortcut: 'x'})
+	}
+	p.CommitMenuSelected = 0
+	p.ShowCommitMenu = true
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
+
+// HideCommitMenu closes the commit menu
+func (p *Panel) HideCommitMenu() {
+	p.ShowCommitMenu = false
+	p.ShowResetConfirm = false
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
+
+// CommitMenuMoveUp moves the commit menu cursor up
+func (p *Panel) CommitMenuMoveUp() {
+	if p.CommitMenuSelected > 0 {
+		p.CommitMenuSelected--
+	}
+}
+
+// CommitMenuMoveDown moves the commit menu cursor down
+func (p *Panel) CommitMenuMoveDown() {
+	if p.CommitMenuSelected < len(p.CommitMenuItems)-1 {
+		p.CommitMenuSelected++
+	}
+}
+
+// RunCommitMenuShortcut runs the menu entry with the given shortcut key.
+// Returns false if no entry has it.
+func (p *Panel) RunCommitMenuShortcut(r rune) bool {
+	for i, item := range p.CommitMenuItems {
+		if item.Shortcut == r {
+			p.CommitMenuSelected = i
+			p.RunCommitMenuItem(item.Action)
+			return true
+		}
+	}
+	return false
+}
+
+// RunSelectedCommitMenuItem runs the menu entry under the cursor
+func (p *Panel) RunSelectedCommitMenuItem() {
+	if p.CommitMenuSelected >= 0 && p.CommitMenuSelected < len(p.CommitMenuItems) {
+		p.RunCommitMenuItem(p.CommitMenuItems[p.CommitMenuSelected].Action)
+	}
+}
+
+// RunCommitMenuItem runs an operation on the menu's commit. Resets ask for
+// confirmation first; branches and tags ask for a name.
+func (p *Panel) RunCommitMenuItem(action CommitAction) {
+	c := p.CommitMenuCommit
+	if _, ok := resetModes[action]; ok {
+		p.ResetAction = action
+		p.ShowResetConfirm = true
+		if p.OnRefresh != nil {
+			p.OnRefresh()
+		}
+		return
+	}
+
+	p.ShowCommitMenu = false
+	switch action {
+	case CommitCherryPick:
+		p.runCommitOperation("Cherry-picking", "Cherry-picked "+c.ShortHash, func() error {
+			return CherryPick(p.RepoRoot, c)
+		})
+	case CommitRevert:
+		p.runCommitOperation("Reverting", "Reverted "+c.ShortHash, func() error {
+			return Revert(p.RepoRoot, c)
+		})
+	case CommitAbort:
+		p.runCommitOperation("Aborting", "Aborted", func() error {
+			return AbortSequencer(p.RepoRoot)
+		})
+	case CommitCheckout:
+		p.afterCommitOperation("Checked out "+c.ShortHash+" (detached HEAD)", CheckoutDetached(p.RepoRoot, c.Hash))
+	case CommitBranch:
+		p.PromptNewBranch(c.Hash)
+	case CommitTag, CommitAnnotatedTag:
+		p.promptTag(c, action == CommitAnnotatedTag)
+	case CommitFixup:
+		p.runCommitOperation("Fixing up", "Fixed up "+c.ShortHash, func() error {
+			return FixupCommit(p.RepoRoot, c.Hash)
+		})
+	case CommitCopyHash:
+		if err := clipboard.Write(c.Hash, clipboard.ClipboardReg); err != nil {
+			p.afterCommitOperation("", err)
+		} else if p.OnMessage != nil {
+			p.OnMessage("Copied "+c.Hash, false)
+		}
+	}
+}
+
+// promptTag asks for a tag name (and message, if annotated) and tags the commit
+func (p *Panel) promptTag(c CommitEntry, annotated bool) {
+	if p.OnInput == nil {
+		return
+	}
+	p.OnInput("Tag "+c.ShortHash, "Tag name:", "", func(name string, canceled bool) {
+		name = strings.TrimSpace(name)
+		if canceled || name == "" {
+			return
+		}
+		if !annotated {
+			p.afterCommitOperation("Tagged "+c.ShortHash+" as "+name, CreateTag(p.RepoRoot, name, c.Hash, ""))
+			return
+		}
+		p.OnInput("Tag "+name, "Message:", "", func(message string, canceled bool) {
+			message = strings.TrimSpace(message)
+			if canceled || message == "" {
+				return
+			}
+			p.afterCommitOperation("Tagged "+c.ShortHash+" as "+name, CreateTag(p.RepoRoot, name, c.Hash, message))
+		})
+	})
+}
+
+// hideResetConfirm hides the reset confirmation, back to the menu
+func (p *Panel) hideResetConfirm() {
+	p.ShowResetConfirm = false
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
+
+// confirmReset resets the current branch to the menu's commit
+func (p *Panel) confirmReset() {
+	p.ShowResetConfirm = false
+	p.ShowCommitMenu = false
+	mode := resetModes[p.ResetAction]
+	c := p.CommitMenuCommit
+	p.afterCommitOperation(fmt.Sprintf("Reset (%s) to %s", mode, c.ShortHash), ResetTo(p.RepoRoot, c.Hash, mode))
+}
+
+// firstLine returns the first line of s
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// runCommitOperation runs an operation that rewrites history, like a rebase,
+// in the background with the spinner up, then reports it like
+// afterCommitOperation. Returns false if another operation kept it from
+// starting.
+func (p *Panel) runCommitOperation(name, done string, op func() error) bool {
+	if !p.startOperation(name) {
+		return false
+	}
+
+	go func() {
+		err := op()
+		p.afterCommitOperation(done, err)
+
+		// Only once the panel is reloaded, so that the next operation's git
+		// doesn't run into the refresh's
+		p.mu.Lock()
+		p.OperationInProgress = ""
+		p.mu.Unlock()
+		if p.OnRefresh != nil {
+			p.OnRefresh()
+		}
+	}()
+	return true
+}
+
+// afterCommitOperation reports how a commit operation went and reloads the
+// file lists and graph
+func (p *Panel) afterCommitOperation(msg string, err error) {
+	p.GraphError = ""
+	if err != nil {
+		log.Printf("THICC SourceControl: Commit operation failed: %v", err)
+		msg = fmt.Sprintf("Failed: %v", err)
+		p.GraphError = firstLine(err.Error())
+	}
+	if p.OnMessage != nil {
+		p.OnMessage(msg, err != nil)
+	}
+
+	p.RefreshStatus()
+	p.RefreshCommitGraph()
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
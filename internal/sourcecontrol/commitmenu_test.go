@@ -0,0 +1,199 @@
+package sourcecontrol
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// commitFile commits content to a file and returns the new commit
+func commitFile(t *testing.T, dir, name, content, msg string) CommitEntry {
+	writeFile(t, dir, name, content)
+	_, err := gitOutput(dir, nil, "add", name)
+	require.NoError(t, err)
+	_, err = gitOutput(dir, nil, "commit", "-q", "-m", msg)
+	require.NoError(t, err)
+	hash, err := gitOutput(dir, nil, "rev-parse", "HEAD")
+	require.NoError(t, err)
+	return CommitEntry{Hash: hash, ShortHash: hash[:7], Subject: msg}
+}
+
+// waitForOperation waits for the operation running in the background to
+// finish and reload the panel
+func waitForOperation(t *testing.T, p *Panel) {
+	t.Helper()
+	assert.Eventually(t, func() bool {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+		return p.OperationInProgress == ""
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// =============================================================================
+// Commit Operation Git Tests
+// =============================================================================
+
+func TestCherryPick(t *testing.T) {
+	dir := initTestRepo(t)
+	base := currentBranch(t, dir)
+	require.NoError(t, CreateBranch(dir, "feature", ""))
+	c := commitFile(t, dir, "feature.go", "package main\n", "Add feature")
+	_, err := gitOutput(dir, nil, "checkout", "-q", base)
+	require.NoError(t, err)
+
+	require.NoError(t, CherryPick(dir, c))
+	assert.Equal(t, "package main\n", readFile(t, dir, "feature.go"))
+	subject, err := gitOutput(dir, nil, "log", "-1", "--format=%s")
+	require.NoError(t, err)
+	assert.Equal(t, "Add feature", subject)
+}
+
+func TestRevert(t *testing.T) {
+	dir := initTestRepo(t)
+	c := commitFile(t, dir, "main.go", "package main // changed\n", "Change main")
+
+	require.NoError(t, Revert(dir, c))
+	assert.Equal(t, "package main\n", readFile(t, dir, "main.go"))
+}
+
+func TestResetTo_Modes(t *testing.T) {
+	dir := initTestRepo(t)
+	first, err := gitOutput(dir, nil, "rev-parse", "HEAD")
+	require.NoError(t, err)
+	commitFile(t, dir, "main.go", "package main // changed\n", "Change main")
+
+	require.NoError(t, ResetTo(dir, first, "soft"))
+	status, err := gitOutput(dir, nil, "status", "--porcelain")
+	require.NoError(t, err)
+	assert.Equal(t, "M  main.go", status, "soft keeps the change staged")
+
+	require.NoError(t, ResetTo(dir, first, "mixed"))
+	status, err = gitOutput(dir, nil, "status", "--porcelain")
+	require.NoError(t, err)
+	assert.Equal(t, " M main.go", status, "mixed keeps the change unstaged")
+
+	require.NoError(t, ResetTo(dir, first, "hard"))
+	assert.Equal(t, "package main\n", readFile(t, dir, "main.go"))
+}
+
+func TestCheckoutDetached(t *testing.T) {
+	dir := initTestRepo(t)
+	first, err := gitOutput(dir, nil, "rev-parse", "HEAD")
+	require.NoError(t, err)
+	commitFile(t, dir, "main.go", "package main // changed\n", "Change main")
+
+	require.NoError(t, CheckoutDetached(dir, first))
+	assert.Equal(t, "HEAD", currentBranch(t, dir))
+	assert.Equal(t, "package main\n", readFile(t, dir, "main.go"))
+}
+
+func TestCreateTag(t *testing.T) {
+	dir := initTestRepo(t)
+	head, err := gitOutput(dir, nil, "rev-parse", "HEAD")
+	require.NoError(t, err)
+
+	require.NoError(t, CreateTag(dir, "light", head, ""))
+	require.NoError(t, CreateTag(dir, "v1.0", head, "First release"))
+
+	kind, err := gitOutput(dir, nil, "cat-file", "-t", "light")
+	require.NoError(t, err)
+	assert.Equal(t, "commit", kind)
+	kind, err = gitOutput(dir, nil, "cat-file", "-t", "v1.0")
+	require.NoError(t, err)
+	assert.Equal(t, "tag", kind)
+}
+
+// =============================================================================
+// Commit Menu Panel Tests
+// =============================================================================
+
+func TestCommitMenu_ResetAsksForConfirmation(t *testing.T) {
+	dir := initTestRepo(t)
+	commitFile(t, dir, "main.go", "package main // changed\n", "Change main")
+
+	p := &Panel{RepoRoot: dir}
+	p.RefreshCommitGraph()
+	p.GraphSelected = 1 // The initial commit
+	p.ShowCommitMenuForSelected()
+	require.True(t, p.ShowCommitMenu)
+
+	require.True(t, p.RunCommitMenuShortcut('h'))
+	require.True(t, p.ShowResetConfirm)
+	assert.Equal(t, "package main // changed\n", readFile(t, dir, "main.go"), "nothing happens before confirming")
+
+	p.confirmReset()
+	assert.False(t, p.ShowCommitMenu)
+	assert.Equal(t, "package main\n", readFile(t, dir, "main.go"))
+	require.Len(t, p.CommitGraph, 1, "the graph is refreshed")
+}
+
+func TestCommitMenu_FailureShownInPanel(t *testing.T) {
+	dir := initTestRepo(t)
+	c := commitFile(t, dir, "main.go", "package main // changed\n", "Change main")
+
+	var isError bool
+	p := &Panel{RepoRoot: dir}
+	p.OnMessage = func(_ string, e bool) { isError = e }
+	p.CommitMenuCommit = c
+
+	// The change is already on the branch, so picking it again is empty
+	p.RunCommitMenuItem(CommitCherryPick)
+	waitForOperation(t, p)
+	assert.True(t, isError)
+	assert.Contains(t, p.GraphError, "cherry-pick")
+
+	p.RunCommitMenuItem(CommitRevert)
+	waitForOperation(t, p)
+	assert.Empty(t, p.GraphError, "a successful operation clears the error")
+}
+
+func TestCommitMenu_AbortsConflictedCherryPick(t *testing.T) {
+	dir := initTestRepo(t)
+	base := currentBranch(t, dir)
+	require.NoError(t, CreateBranch(dir, "feature", ""))
+	c := commitFile(t, dir, "main.go", "package feature\n", "Feature main")
+	_, err := gitOutput(dir, nil, "checkout", "-q", base)
+	require.NoError(t, err)
+	commitFile(t, dir, "main.go", "package base\n", "Base main")
+
+	err = CherryPick(dir, c)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stopped on conflicts")
+	assert.Equal(t, SequencerCherryPick, SequencerState(dir))
+
+	p := &Panel{RepoRoot: dir}
+	p.RefreshCommitGraph()
+	p.ShowCommitMenuForSelected()
+	require.True(t, p.ShowCommitMenu)
+	assert.Equal(t, "Abort cherry-pick", p.CommitMenuItems[len(p.CommitMenuItems)-1].Label)
+
+	require.True(t, p.RunCommitMenuShortcut('x'))
+	waitForOperation(t, p)
+	assert.Empty(t, SequencerState(dir))
+	assert.Equal(t, "package base\n", readFile(t, dir, "main.go"), "the branch is back where it was")
+}
+
+func TestCommitMenu_AnnotatedTagAsksForMessage(t *testing.T) {
+	dir := initTestRepo(t)
+	head, err := gitOutput(dir, nil, "rev-parse", "HEAD")
+	require.NoError(t, err)
+
+	answers := []string{"v2", "Second release"}
+	var prompts []string
+	p := &Panel{RepoRoot: dir}
+	p.OnInput = func(_, prompt, _ string, done func(string, bool)) {
+		prompts = append(prompts, prompt)
+		answer := answers[0]
+		answers = answers[1:]
+		done(answer, false)
+	}
+	p.CommitMenuCommit = CommitEntry{Hash: head, ShortHash: head[:7]}
+
+	p.RunCommitMenuItem(CommitAnnotatedTag)
+	assert.Equal(t, []string{"Tag name:", "Message:"}, prompts)
+	message, err := gitOutput(dir, nil, "tag", "-l", "--format=%(contents:subject)", "v2")
+	require.NoError(t, err)
+	assert.Equal(t, "Second release", message)
+}
@@ -0,0 +1,151 @@
+package sourcecontrol
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/ellery/thicc/internal/thicc"
+)
+
+// ErrNothingStaged is returned when a commit message is asked for with no
+// staged changes to describe
+var ErrNothingStaged = errors.New("nothing staged to describe")
+
+// maxMessageDiffBytes caps how much of the staged diff is sent to the
+// message command; big diffs are cut off with a note
+const maxMessageDiffBytes = 100 * 1024
+
+// messageCommandTimeout is how long the message command may run
+const messageCommandTimeout = 2 * time.Minute
+
+// defaultMessageInstructions ask for a plain commit message
+const defaultMessageInstructions = `Write a git commit message for the staged diff below.
+Start with a summary line of at most 72 characters in the imperative mood,
+then a blank line and a short body if the change needs explaining.
+Reply with the commit message only, no quotes or code fences.`
+
+// conventionalMessageInstructions ask for a Conventional Commits message
+const conventionalMessageInstructions = `Write a git commit message for the staged diff below, following
+Conventional Commits: "type(scope): summary", where type is one of feat, fix,
+docs, style, refactor, perf, test, build, ci or chore, and the scope is
+optional. Keep the summary line under 72 characters, in the imperative mood,
+then a blank line and a short body if the change needs explaining. Mark
+breaking changes with "!" after the type and a "BREAKING CHANGE:" footer.
+Reply with the commit message only, no quotes or code fences.`
+
+// messageInstructions returns the instructions for a template setting:
+// "conventional", custom instructions, or "" for the default
+func messageInstructions(template string) string {
+	switch strings.TrimSpace(template) {
+	case "":
+		return defaultMessageInstructions
+	case "conventional":
+		return conventionalMessageInstructions
+	}
+	return strings.TrimSpace(template)
+}
+
+// buildMessagePrompt joins the instructions and the (possibly cut off) diff
+// into the text sent to the message command
+func buildMessagePrompt(instructions, diff string) string {
+	if len(diff) > maxMessageDiffBytes {
+		diff = diff[:maxMessageDiffBytes] + "\n[diff truncated]\n"
+	}
+	return instructions + "\n\n" + diff
+}
+
+// cleanGeneratedMessage strips the code fences and blank lines models tend
+// to wrap messages in
+func cleanGeneratedMessage(output string) string {
+	msg := strings.TrimSpace(output)
+	if strings.HasPrefix(msg, "```") {
+		lines := strings.Split(msg, "\n")
+		lines = lines[1:]
+		if n := len(lines); n > 0 && strings.TrimSpace(lines[n-1]) == "```" {
+			lines = lines[:n-1]
+		}
+		msg = strings.TrimSpace(strings.Join(lines, "\n"))
+	}
+	return msg
+}
+
+// GenerateCommitMessage runs command through the shell with instructions
+// and the staged diff on stdin, and returns what it prints
+func GenerateCommitMessage(repoRoot, command, template string) (string, error) {
+	diff, err := gitOutput(repoRoot, nil, "diff", "--cached", "--no-color", "--no-ext-diff")
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(diff) == "" {
+		return "", ErrNothingStaged
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), messageCommandTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = repoRoot
+	cmd.Stdin = strings.NewReader(buildMessagePrompt(messageInstructions(template), diff))
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("%s timed out after %v", command, messageCommandTimeout)
+		}
+		return "", fmt.Errorf("%s failed: %v: %s", command, err, strings.TrimSpace(stderr.String()))
+	}
+
+	msg := cleanGeneratedMessage(stdout.String())
+	if msg == "" {
+		return "", fmt.Errorf("%s printed no message", command)
+	}
+	log.Printf("THICC SourceControl: Generated commit message with %q (%d bytes)", command, len(msg))
+	return msg, nil
+}
+
+// DoGenerateCommitMessage fills the commit message box with a message
+// written by the configured command, for editing before committing
+func (p *Panel) DoGenerateCommitMessage() {
+	// Already in progress?
+	if p.OperationInProgress != "" {
+		return
+	}
+	if len(p.StagedFiles) == 0 {
+		log.Println("THICC SourceControl: Nothing staged to generate a message for")
+		return
+	}
+
+	command := thicc.GetCommitMessageCommand()
+	template := thicc.GetCommitMessageTemplate()
+	p.OperationInProgress = "Generating message"
+	go p.spinnerLoop()
+
+	go func() {
+		msg, err := GenerateCommitMessage(p.RepoRoot, command, template)
+
+		p.mu.Lock()
+		p.OperationInProgress = ""
+		if err == nil {
+			p.CommitMsg = msg
+			p.CommitCursor = len(msg)
+			p.Section = SectionCommitInput
+		}
+		p.mu.Unlock()
+
+		if err != nil {
+			log.Printf("THICC SourceControl: Generating commit message failed: %v", err)
+			if p.OnMessage != nil {
+				p.OnMessage(fmt.Sprintf("Couldn't generate a message: %s", firstLine(err.Error())), true)
+			}
+		}
+		if p.OnRefresh != nil {
+			p.OnRefresh()
+		}
+	}()
+}
@@ -0,0 +1,68 @@
+package sourcecontrol
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// Commit Message Prompt Tests
+// =============================================================================
+
+func TestMessageInstructions(t *testing.T) {
+	assert.Equal(t, defaultMessageInstructions, messageInstructions(""))
+	assert.Equal(t, conventionalMessageInstructions, messageInstructions(" conventional "))
+	assert.Equal(t, "Use emoji", messageInstructions("Use emoji\n"), "anything else is used as is")
+}
+
+func TestBuildMessagePrompt_TruncatesBigDiffs(t *testing.T) {
+	prompt := buildMessagePrompt("Describe it.", strings.Repeat("x", maxMessageDiffBytes+10))
+	assert.True(t, strings.HasPrefix(prompt, "Describe it.\n\n"))
+	assert.True(t, strings.HasSuffix(prompt, "[diff truncated]\n"))
+	assert.Less(t, len(prompt), maxMessageDiffBytes+100)
+}
+
+func TestCleanGeneratedMessage(t *testing.T) {
+	assert.Equal(t, "feat: add a\n\nBody", cleanGeneratedMessage("\n```\nfeat: add a\n\nBody\n```\n"))
+	assert.Equal(t, "fix: b", cleanGeneratedMessage("  fix: b  \n"))
+}
+
+// =============================================================================
+// Commit Message Command Tests
+// =============================================================================
+
+func TestGenerateCommitMessage_SendsStagedDiff(t *testing.T) {
+	dir := initTestRepo(t)
+	writeFile(t, dir, "a.go", "package a\n")
+	_, err := gitOutput(dir, nil, "add", "a.go")
+	require.NoError(t, err)
+
+	msg, err := GenerateCommitMessage(dir, "tail -n 1", "")
+	require.NoError(t, err)
+	assert.Equal(t, "+package a", msg)
+
+	msg, err = GenerateCommitMessage(dir, "head -n 2", "conventional")
+	require.NoError(t, err)
+	assert.Contains(t, msg, "Conventional Commits")
+}
+
+func TestGenerateCommitMessage_Errors(t *testing.T) {
+	dir := initTestRepo(t)
+	_, err := GenerateCommitMessage(dir, "cat", "")
+	assert.Equal(t, ErrNothingStaged, err)
+
+	writeFile(t, dir, "a.go", "package a\n")
+	_, err = gitOutput(dir, nil, "add", "a.go")
+	require.NoError(t, err)
+
+	_, err = GenerateCommitMessage(dir, "echo no model >&2; exit 3", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no model")
+
+	_, err = GenerateCommitMessage(dir, "true", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "printed no message")
+}
@@ -0,0 +1,155 @@
+package sourcecontrol
+
+import (
+	"log"
+	"strings"
+)
+
+// Conflict markers git writes into a file it couldn't merge. The base
+// section (|||||||) only appears with merge.conflictStyle=diff3 or zdiff3.
+const (
+	markerOurs   = "<<<<<<<"
+	markerBase   = "|||||||"
+	markerSep    = "======="
+	markerTheirs = ">>>>>>>"
+)
+
+// Resolution is how a conflict block is resolved
+type Resolution int
+
+const (
+	ResolveOurs   Resolution = iota // Keep our side
+	ResolveTheirs                   // Keep their side
+	ResolveBoth                     // Keep ours followed by theirs
+)
+
+// ConflictBlock is one <<<<<<< ... >>>>>>> region of a conflicted file
+type ConflictBlock struct {
+	Start  int      // Line of the <<<<<<< marker (0-based)
+	End    int      // Line of the >>>>>>> marker
+	Ours   []string // Our side
+	Base   []string // Common ancestor (only with diff3-style markers)
+	Theirs []string // Their side
+}
+
+// Lines returns the lines the block is replaced with for a resolution
+func (b ConflictBlock) Lines(res Resolution) []string {
+	switch res {
+	case ResolveOurs:
+		return b.Ours
+	case ResolveTheirs:
+		return b.Theirs
+	}
+	lines := make([]string, 0, len(b.Ours)+len(b.Theirs))
+	lines = append(lines, b.Ours...)
+	return append(lines, b.Theirs...)
+}
+
+// isMarker returns true if line is the given conflict marker (optionally
+// followed by a label)
+func isMarker(line, marker string) bool {
+	if !strings.HasPrefix(line, marker) {
+		return false
+	}
+	rest := line[len(marker):]
+	return rest == "" || rest[0] == ' ' || rest[0] == '\r'
+}
+
+// ParseConflicts finds the conflict blocks in a file's lines. Incomplete
+// blocks (e.g. a stray <<<<<<< with no closing marker) are ignored.
+func ParseConflicts(lines []string) []ConflictBlock {
+	var blocks []ConflictBlock
+	var cur *ConflictBlock
+	section := 0 // 0 = ours, 1 = base, 2 = theirs
+
+	for i, line := range lines {
+		switch {
+		case isMarker(line, markerOurs):
+			cur = &ConflictBlock{Start: i}
+			section = 0
+		case cur == nil:
+		case isMarker(line, markerBase) && section == 0:
+			section = 1
+		case line == markerSep || line == markerSep+"\r":
+			section = 2
+		case isMarker(line, markerTheirs) && section == 2:
+			cur.End = i
+			blocks = append(blocks, *cur)
+			cur = nil
+		case section == 0:
+			cur.Ours = append(cur.Ours, line)
+		case section == 1:
+			cur.Base = append(cur.Base, line)
+		default:
+			cur.Theirs = append(cur.Theirs, line)
+		}
+	}
+	return blocks
+}
+
+// HasConflictMarkers returns true if any complete conflict block remains
+func HasConflictMarkers(content string) bool {
+	return len(ParseConflicts(strings.Split(content, "\n"))) > 0
+}
+
+// ConflictVersions returns the three versions of a conflicted file from the
+// index: the common ancestor (:1:), ours (:2:) and theirs (:3:). A side that
+// doesn't have the file (e.g. added on one branch only) is empty.
+func ConflictVersions(repoRoot, path string) (base, ours, theirs string) {
+	show := func(stage string) string {
+		content, err := gitOutput(repoRoot, nil, "show", ":"+stage+":"+path)
+		if err != nil {
+			log.Printf("THICC SourceControl: No stage %s for %s: %v", stage, path, err)
+			return ""
+		}
+		return content
+	}
+	return show("1"), show("2"), show("3")
+}
+
+// LocateLines returns where want appears in lines, picking the occurrence
+// closest to hint. If it doesn't appear (or is empty), hint is returned,
+// clamped to the file.
+func LocateLines(lines, want []string, hint int) int {
+	best := -1
+	if len(want) > 0 {
+		for i := 0; i+len(want) <= len(lines); i++ {
+			match := true
+			for j := range want {
+				if lines[i+j] != want[j] {
+					match = false
+					break
+				}
+			}
+			if match && (best < 0 || abs(i-hint) < abs(best-hint)) {
+				best = i
+			}
+		}
+	}
+	if best >= 0 {
+		return best
+	}
+	if hint > len(lines) {
+		hint = len(lines)
+	}
+	if hint < 0 {
+		hint = 0
+	}
+	return hint
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// MarkResolved stages a file whose conflicts have been resolved
+func MarkResolved(repoRoot, path string) error {
+	if _, err := gitOutput(repoRoot, nil, "add", "--", path); err != nil {
+		return err
+	}
+	log.Printf("THICC SourceControl: Marked %s resolved", path)
+	return nil
+}
@@ -0,0 +1,123 @@
+package sourcecontrol
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// Conflict Parsing Tests
+// =============================================================================
+
+func TestParseConflicts_MergeStyle(t *testing.T) {
+	lines := strings.Split("a\n<<<<<<< HEAD\nours\n=======\ntheirs 1\ntheirs 2\n>>>>>>> feature\nb", "\n")
+
+	blocks := ParseConflicts(lines)
+	require.Len(t, blocks, 1)
+	assert.Equal(t, 1, blocks[0].Start)
+	assert.Equal(t, 6, blocks[0].End)
+	assert.Equal(t, []string{"ours"}, blocks[0].Ours)
+	assert.Empty(t, blocks[0].Base)
+	assert.Equal(t, []string{"theirs 1", "theirs 2"}, blocks[0].Theirs)
+}
+
+func TestParseConflicts_Diff3Style(t *testing.T) {
+	lines := strings.Split("<<<<<<< HEAD\nours\n||||||| base\nbase\n=======\ntheirs\n>>>>>>> feature", "\n")
+
+	blocks := ParseConflicts(lines)
+	require.Len(t, blocks, 1)
+	assert.Equal(t, []string{"ours"}, blocks[0].Ours)
+	assert.Equal(t, []string{"base"}, blocks[0].Base)
+	assert.Equal(t, []string{"theirs"}, blocks[0].Theirs)
+}
+
+func TestParseConflicts_IgnoresIncompleteBlocks(t *testing.T) {
+	lines := strings.Split("<<<<<<< HEAD\nours\n=======\n<<<<<<<< not a marker\n", "\n")
+	assert.Empty(t, ParseConflicts(lines))
+	assert.False(t, HasConflictMarkers("======= alone\n"))
+}
+
+func TestConflictBlockLines(t *testing.T) {
+	blk := ConflictBlock{Ours: []string{"o"}, Theirs: []string{"t"}}
+
+	assert.Equal(t, []string{"o"}, blk.Lines(ResolveOurs))
+	assert.Equal(t, []string{"t"}, blk.Lines(ResolveTheirs))
+	assert.Equal(t, []string{"o", "t"}, blk.Lines(ResolveBoth))
+}
+
+func TestLocateLines_ClosestToHint(t *testing.T) {
+	lines := []string{"x", "a", "b", "x", "a", "b"}
+
+	assert.Equal(t, 4, LocateLines(lines, []string{"a", "b"}, 5))
+	assert.Equal(t, 1, LocateLines(lines, []string{"a", "b"}, 0))
+	assert.Equal(t, 3, LocateLines(lines, []string{"missing"}, 3), "falls back to the hint")
+	assert.Equal(t, 6, LocateLines(lines, nil, 99), "clamped to the file")
+}
+
+func TestIsUnmerged(t *testing.T) {
+	for _, xy := range []string{"UU", "AA", "DD", "AU", "UA", "DU", "UD"} {
+		assert.True(t, isUnmerged(xy[0], xy[1]), xy)
+	}
+	for _, xy := range []string{"M ", " M", "A ", "AM", "D ", "??"} {
+		assert.False(t, isUnmerged(xy[0], xy[1]), xy)
+	}
+}
+
+// =============================================================================
+// Conflict Git Tests
+// =============================================================================
+
+// conflictTestRepo creates a repo with main.go conflicted by a merge
+func conflictTestRepo(t *testing.T) string {
+	dir := initTestRepo(t)
+	_, err := gitOutput(dir, nil, "checkout", "-q", "-b", "feature")
+	require.NoError(t, err)
+	writeFile(t, dir, "main.go", "package theirs\n")
+	_, err = gitOutput(dir, nil, "commit", "-q", "-am", "theirs")
+	require.NoError(t, err)
+
+	_, err = gitOutput(dir, nil, "checkout", "-q", "-")
+	require.NoError(t, err)
+	writeFile(t, dir, "main.go", "package ours\n")
+	_, err = gitOutput(dir, nil, "commit", "-q", "-am", "ours")
+	require.NoError(t, err)
+
+	_, err = gitOutput(dir, nil, "merge", "feature")
+	require.Error(t, err, "merge conflicts")
+	return dir
+}
+
+func TestConflictVersions(t *testing.T) {
+	dir := conflictTestRepo(t)
+
+	base, ours, theirs := ConflictVersions(dir, "main.go")
+	assert.Equal(t, "package main", base)
+	assert.Equal(t, "package ours", ours)
+	assert.Equal(t, "package theirs", theirs)
+	assert.True(t, HasConflictMarkers(readFile(t, dir, "main.go")))
+}
+
+func TestConflictStatusAndMarkResolved(t *testing.T) {
+	dir := conflictTestRepo(t)
+	p := &Panel{RepoRoot: dir}
+
+	p.RefreshStatus()
+	require.Len(t, p.UnstagedFiles, 1)
+	assert.Equal(t, FileStatus{Path: "main.go", Status: "U"}, p.UnstagedFiles[0])
+	assert.Empty(t, p.StagedFiles, "conflicts are only listed once")
+
+	var selected string
+	p.OnConflictSelect = func(path string) { selected = path }
+	p.selectUnstagedFile(p.UnstagedFiles[0])
+	assert.Equal(t, "main.go", selected)
+
+	writeFile(t, dir, "main.go", "package merged\n")
+	require.NoError(t, MarkResolved(dir, "main.go"))
+	p.RefreshStatus()
+	assert.Empty(t, p.UnstagedFiles)
+	require.Len(t, p.StagedFiles, 1)
+	assert.Equal(t, "M", p.StagedFiles[0].Status)
+}
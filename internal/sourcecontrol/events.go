@@ -20,7 +20,7 @@ func (p *Panel) HandleEvent(event tcell.Event) bool {
 		return p.handleMouse(ev)
 	case *tcell.EventPaste:
 		// Handle paste into commit message input
-		if p.Focus && p.Section == SectionCommitInput && len(p.StagedFiles) > 0 {
+		if p.Focus && p.Section == SectionCommitInput && p.CanCommit() {
 			p.PasteToCommitMsg(ev.Text())
 			if p.OnRefresh != nil {
 				p.OnRefresh()
@@ -39,11 +39,71 @@ func (p *Panel) handleKey(ev *tcell.EventKey) bool {
 		return p.handleDiscardConfirmKey(ev)
 	}
 
+	// Modal: Checkpoint restore confirmation
+	if p.ShowRestoreConfirm {
+		return p.handleRestoreConfirmKey(ev)
+	}
+
+	// Modal: Stash drop confirmation
+	if p.ShowDropStashConfirm {
+		return p.handleDropStashConfirmKey(ev)
+	}
+
+	// Modal: Worktree merge/discard confirmation
+	if p.WorktreeConfirm != "" {
+		return p.handleWorktreeConfirmKey(ev)
+	}
+
+	// Modal: Unmerged branch force delete confirmation
+	if p.ShowForceDeleteConfirm {
+		return p.handleForceDeleteConfirmKey(ev)
+	}
+
+	// Modal: Reset confirmation from the commit menu
+	if p.ShowResetConfirm {
+		return p.handleResetConfirmKey(ev)
+	}
+
+	// Modal: Commit menu
+	if p.ShowCommitMenu {
+		return p.handleCommitMenuKey(ev)
+	}
+
+	// Modal: Interactive rebase editor
+	if p.ShowRebase {
+		return p.handleRebaseKey(ev)
+	}
+
 	// Modal: Branch dialog takes priority when visible
 	if p.ShowBranchDialog {
 		return p.handleBranchDialogKey(ev)
 	}
 
+	// Modal: Hunk browser replaces the file lists while open
+	if p.ShowHunkBrowser {
+		return p.handleHunkBrowserKey(ev)
+	}
+
+	// Modal: Checkpoint timeline replaces the file lists while open
+	if p.ShowCheckpoints {
+		return p.handleCheckpointKey(ev)
+	}
+
+	// Modal: Worktree list replaces the file lists while open
+	if p.ShowWorktrees {
+		return p.handleWorktreeKey(ev)
+	}
+
+	// Modal: Stash list replaces the file lists while open
+	if p.ShowStashes {
+		return p.handleStashKey(ev)
+	}
+
+	// Modal: File history replaces the file lists while open
+	if p.ShowHistory {
+		return p.handleHistoryKey(ev)
+	}
+
 	// Global shortcuts with Alt modifier (work from any section, including commit input)
 	if ev.Modifiers()&tcell.ModAlt != 0 {
 		switch ev.Rune() {
@@ -52,11 +112,19 @@ func (p *Panel) handleKey(ev *tcell.EventKey) bool {
 			return true
 		case 'p', 'P':
 			if p.CanPush() {
-				p.DoPush()
+				p.DoPush(PushDefault)
 			}
 			return true
 		case 'l', 'L':
-			p.DoPull()
+			p.DoPull(PullDefault)
+			return true
+		case 'f', 'F':
+			// Fetch from the remotes
+			p.DoFetch()
+			return true
+		case 'g', 'G':
+			// Write the commit message with the configured command
+			p.DoGenerateCommitMessage()
 			return true
 		case 'd', 'D':
 			// Discard changes (only for unstaged files)
@@ -68,13 +136,33 @@ func (p *Panel) handleKey(ev *tcell.EventKey) bool {
 			// Open branch switcher
 			p.ShowBranchSwitcher()
 			return true
+		case 't', 'T':
+			// Open the checkpoint timeline
+			p.ShowCheckpointTimeline()
+			return true
+		case 'w', 'W':
+			// Open the worktree sessions list
+			p.ShowWorktreeList()
+			return true
+		case 's', 'S':
+			// Open the stash list
+			p.ShowStashList()
+			return true
+		case 'm', 'M':
+			// Toggle amending the last commit (Alt+a toggles the panel)
+			p.ToggleAmend()
+			return true
+		case 'i', 'I':
+			// Open the interactive rebase editor
+			p.ShowRebaseEditor()
+			return true
 		}
 	}
 
 	// Special handling for commit input section - captures text input
-	// But only if there are staged files (commit is enabled)
+	// But only if there are staged files or amend is on (commit is enabled)
 	if p.Section == SectionCommitInput {
-		if len(p.StagedFiles) > 0 {
+		if p.CanCommit() {
 			return p.handleCommitInputKey(ev)
 		}
 		// When disabled, only allow navigation keys
@@ -114,6 +202,11 @@ func (p *Panel) handleKey(ev *tcell.EventKey) bool {
 	case tcell.KeyEnter:
 		return p.handleEnter()
 
+	case tcell.KeyRight:
+		// Drill into the selected file's hunks
+		p.ShowHunks()
+		return true
+
 	case tcell.KeyTab:
 		p.NextSection()
 		return true
@@ -137,6 +230,10 @@ func (p *Panel) handleKey(ev *tcell.EventKey) bool {
 		case 'j':
 			p.MoveDown()
 			return true
+		case 'l':
+			// Drill into the selected file's hunks
+			p.ShowHunks()
+			return true
 		case 's':
 			// Stage selected file
 			p.stageSelected()
@@ -148,7 +245,7 @@ func (p *Panel) handleKey(ev *tcell.EventKey) bool {
 		case 'p':
 			// Push (only if enabled)
 			if p.CanPush() {
-				p.DoPush()
+				p.DoPush(PushDefault)
 			}
 			return true
 		case 'r':
@@ -287,13 +384,24 @@ func (p *Panel) handlePushBtnKey(ev *tcell.EventKey) bool {
 	case tcell.KeyEnter:
 		// Push (only if enabled)
 		if p.CanPush() {
-			p.DoPush()
+			p.DoPush(PushDefault)
 		}
 		return true
 	case tcell.KeyEsc:
 		p.Section = SectionUnstaged
 		p.Selected = 0
 		return true
+	case tcell.KeyRune:
+		switch ev.Rune() {
+		case 'f':
+			// Force push, unless someone else pushed in the meantime
+			p.DoPush(PushForceWithLease)
+			return true
+		case 'u':
+			// Publish to the default remote and track it
+			p.DoPush(PushSetUpstream)
+			return true
+		}
 	}
 	return false
 }
@@ -312,12 +420,24 @@ func (p *Panel) handlePullBtnKey(ev *tcell.EventKey) bool {
 		return true
 	case tcell.KeyEnter:
 		// Pull
-		p.DoPull()
+		p.DoPull(PullDefault)
 		return true
 	case tcell.KeyEsc:
 		p.Section = SectionUnstaged
 		p.Selected = 0
 		return true
+	case tcell.KeyRune:
+		switch ev.Rune() {
+		case 'r':
+			p.DoPull(PullRebase)
+			return true
+		case 'm':
+			p.DoPull(PullMerge)
+			return true
+		case 'f':
+			p.DoFetch()
+			return true
+		}
 	}
 	return false
 }
@@ -355,6 +475,18 @@ func (p *Panel) handleGraphKey(ev *tcell.EventKey) bool {
 		case 'j':
 			p.GraphMoveDown()
 			return true
+		case 'b':
+			p.CreateBranchFromSelectedCommit()
+			return true
+		case 'f', '/':
+			p.PromptGraphFilter()
+			return true
+		case 'm':
+			p.ShowCommitMenuForSelected()
+			return true
+		case 'i':
+			p.ShowRebaseEditor()
+			return true
 		case 'l':
 			p.handleGraphExpand()
 			return true
@@ -392,17 +524,11 @@ func (p *Panel) handleDisabledCommitKey(ev *tcell.EventKey) bool {
 func (p *Panel) handleBranchDialogKey(ev *tcell.EventKey) bool {
 	switch ev.Key() {
 	case tcell.KeyUp:
-		if p.BranchSelected > 0 {
-			p.BranchSelected--
-			p.EnsureBranchVisible()
-		}
+		p.BranchMoveUp()
 		return true
 
 	case tcell.KeyDown:
-		if p.BranchSelected < len(p.LocalBranches)-1 {
-			p.BranchSelected++
-			p.EnsureBranchVisible()
-		}
+		p.BranchMoveDown()
 		return true
 
 	case tcell.KeyEnter:
@@ -413,28 +539,450 @@ func (p *Panel) handleBranchDialogKey(ev *tcell.EventKey) bool {
 		p.HideBranchSwitcher()
 		return true
 
-	default:
-		// Handle j/k for navigation
+	case tcell.KeyRune:
 		switch ev.Rune() {
 		case 'k':
-			if p.BranchSelected > 0 {
-				p.BranchSelected--
-				p.EnsureBranchVisible()
+			p.BranchMoveUp()
+		case 'j':
+			p.BranchMoveDown()
+		case 'n':
+			p.PromptNewBranch("")
+		case 'r':
+			p.PromptRenameBranch()
+		case 'x', 'd':
+			p.DeleteSelectedBranch()
+		}
+		return true
+	}
+
+	// Consume all events when dialog is open
+	return true
+}
+
+// handleHunkBrowserKey handles keyboard events for the hunk browser
+func (p *Panel) handleHunkBrowserKey(ev *tcell.EventKey) bool {
+	extend := ev.Modifiers()&tcell.ModShift != 0
+
+	switch ev.Key() {
+	case tcell.KeyUp:
+		p.HunkMoveUp(extend)
+		return true
+
+	case tcell.KeyDown:
+		p.HunkMoveDown(extend)
+		return true
+
+	case tcell.KeyPgUp:
+		p.HunkJump(-1)
+		return true
+
+	case tcell.KeyPgDn:
+		p.HunkJump(1)
+		return true
+
+	case tcell.KeyEnter:
+		p.ApplySelectedHunk()
+		return true
+
+	case tcell.KeyEsc, tcell.KeyLeft:
+		p.HideHunks()
+		return true
+	}
+
+	// Alt+d mirrors the file-level discard shortcut
+	if ev.Modifiers()&tcell.ModAlt != 0 {
+		switch ev.Rune() {
+		case 'd', 'D':
+			p.showHunkDiscardConfirm()
+		}
+		return true
+	}
+
+	switch ev.Rune() {
+	case 'k':
+		p.HunkMoveUp(false)
+	case 'j':
+		p.HunkMoveDown(false)
+	case 'K':
+		p.HunkMoveUp(true)
+	case 'J':
+		p.HunkMoveDown(true)
+	case 'n':
+		p.HunkJump(1)
+	case 'N':
+		p.HunkJump(-1)
+	case 'v':
+		p.ToggleHunkLine()
+	case ' ', 's', 'u':
+		// Stage in the unstaged view, unstage in the staged view
+		p.ApplySelectedHunk()
+	case 'd':
+		p.showHunkDiscardConfirm()
+	case 'h':
+		p.HideHunks()
+	}
+
+	// Consume all events while the browser is open
+	return true
+}
+
+// handleCheckpointKey handles keyboard events for the checkpoint timeline
+func (p *Panel) handleCheckpointKey(ev *tcell.EventKey) bool {
+	switch ev.Key() {
+	case tcell.KeyUp:
+		p.CheckpointMoveUp()
+	case tcell.KeyDown:
+		p.CheckpointMoveDown()
+	case tcell.KeyEnter:
+		p.DiffSelectedCheckpoint()
+	case tcell.KeyEsc, tcell.KeyLeft:
+		p.HideCheckpointTimeline()
+		return true
+	case tcell.KeyRune:
+		if ev.Modifiers()&tcell.ModAlt != 0 {
+			// Alt+t toggles the timeline closed again
+			if ev.Rune() == 't' || ev.Rune() == 'T' {
+				p.HideCheckpointTimeline()
+			}
+			return true
+		}
+		switch ev.Rune() {
+		case 'k':
+			p.CheckpointMoveUp()
+		case 'j':
+			p.CheckpointMoveDown()
+		case 'd':
+			p.DiffSelectedCheckpoint()
+		case 'p':
+			p.DiffCheckpointWithPrevious()
+		case 'v', ' ':
+			p.ToggleCheckpointMark()
+		case 'r':
+			p.showRestoreConfirm()
+		case 'h':
+			p.HideCheckpointTimeline()
+			return true
+		}
+	}
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+	// Consume all events while the timeline is open
+	return true
+}
+
+// handleRestoreConfirmKey handles keyboard events for the restore confirmation dialog
+func (p *Panel) handleRestoreConfirmKey(ev *tcell.EventKey) bool {
+	switch ev.Key() {
+	case tcell.KeyEnter:
+		p.confirmRestore()
+	case tcell.KeyEsc:
+		p.hideRestoreConfirm()
+	}
+
+	// Consume all events when dialog is open
+	return true
+}
+
+// handleWorktreeKey handles keyboard events for the worktree list
+func (p *Panel) handleWorktreeKey(ev *tcell.EventKey) bool {
+	switch ev.Key() {
+	case tcell.KeyUp:
+		p.WorktreeMoveUp()
+	case tcell.KeyDown:
+		p.WorktreeMoveDown()
+	case tcell.KeyEnter:
+		p.ReviewSelectedWorktree()
+	case tcell.KeyEsc, tcell.KeyLeft:
+		p.HideWorktreeList()
+		return true
+	case tcell.KeyRune:
+		if ev.Modifiers()&tcell.ModAlt != 0 {
+			// Alt+w toggles the list closed again
+			if ev.Rune() == 'w' || ev.Rune() == 'W' {
+				p.HideWorktreeList()
 			}
 			return true
+		}
+		switch ev.Rune() {
+		case 'k':
+			p.WorktreeMoveUp()
 		case 'j':
-			if p.BranchSelected < len(p.LocalBranches)-1 {
-				p.BranchSelected++
-				p.EnsureBranchVisible()
+			p.WorktreeMoveDown()
+		case 'd':
+			p.ReviewSelectedWorktree()
+		case 'm':
+			p.showWorktreeConfirm("merge")
+		case 'x':
+			p.showWorktreeConfirm("discard")
+		case 'h':
+			p.HideWorktreeList()
+			return true
+		}
+	}
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+	// Consume all events while the list is open
+	return true
+}
+
+// handleWorktreeConfirmKey handles keyboard events for the worktree confirmation dialog
+func (p *Panel) handleWorktreeConfirmKey(ev *tcell.EventKey) bool {
+	switch ev.Key() {
+	case tcell.KeyEnter:
+		p.confirmWorktree()
+	case tcell.KeyEsc:
+		p.hideWorktreeConfirm()
+	}
+
+	// Consume all events when dialog is open
+	return true
+}
+
+// handleStashKey handles keyboard events for the stash list
+func (p *Panel) handleStashKey(ev *tcell.EventKey) bool {
+	switch ev.Key() {
+	case tcell.KeyUp:
+		p.StashMoveUp()
+	case tcell.KeyDown:
+		p.StashMoveDown()
+	case tcell.KeyEnter:
+		p.DiffSelectedStash()
+	case tcell.KeyEsc, tcell.KeyLeft:
+		p.HideStashList()
+		return true
+	case tcell.KeyRune:
+		if ev.Modifiers()&tcell.ModAlt != 0 {
+			// Alt+s toggles the list closed again
+			if ev.Rune() == 's' || ev.Rune() == 'S' {
+				p.HideStashList()
 			}
 			return true
 		}
+		switch ev.Rune() {
+		case 'k':
+			p.StashMoveUp()
+		case 'j':
+			p.StashMoveDown()
+		case 'd':
+			p.DiffSelectedStash()
+		case 's':
+			p.PromptNewStash(false)
+		case 'u':
+			p.PromptNewStash(true)
+		case 'a':
+			p.ApplySelectedStash(false)
+		case 'p':
+			p.ApplySelectedStash(true)
+		case 'x':
+			p.showDropStashConfirm()
+		case 'h':
+			p.HideStashList()
+			return true
+		}
+	}
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+	// Consume all events while the list is open
+	return true
+}
+
+// handleDropStashConfirmKey handles keyboard events for the stash drop confirmation dialog
+func (p *Panel) handleDropStashConfirmKey(ev *tcell.EventKey) bool {
+	switch ev.Key() {
+	case tcell.KeyEnter:
+		p.confirmDropStash()
+	case tcell.KeyEsc:
+		p.hideDropStashConfirm()
+	}
+
+	// Consume all events when dialog is open
+	return true
+}
+
+// handleForceDeleteConfirmKey handles keyboard events for the force delete
+// branch confirmation
+func (p *Panel) handleForceDeleteConfirmKey(ev *tcell.EventKey) bool {
+	switch ev.Key() {
+	case tcell.KeyEnter:
+		p.confirmForceDelete()
+	case tcell.KeyEsc:
+		p.hideForceDeleteConfirm()
+	}
+
+	// Consume all events when dialog is open
+	return true
+}
+
+// handleCommitMenuKey handles keyboard events for the commit menu
+func (p *Panel) handleCommitMenuKey(ev *tcell.EventKey) bool {
+	switch ev.Key() {
+	case tcell.KeyUp:
+		p.CommitMenuMoveUp()
+	case tcell.KeyDown:
+		p.CommitMenuMoveDown()
+	case tcell.KeyEnter:
+		p.RunSelectedCommitMenuItem()
+	case tcell.KeyEsc:
+		p.HideCommitMenu()
+	case tcell.KeyRune:
+		switch ev.Rune() {
+		case 'k':
+			p.CommitMenuMoveUp()
+		case 'j':
+			p.CommitMenuMoveDown()
+		default:
+			p.RunCommitMenuShortcut(ev.Rune())
+		}
+	}
+
+	// Consume all events when menu is open
+	return true
+}
+
+// handleRebaseKey handles keyboard events in the interactive rebase editor
+func (p *Panel) handleRebaseKey(ev *tcell.EventKey) bool {
+	switch ev.Key() {
+	case tcell.KeyUp:
+		if ev.Modifiers()&tcell.ModShift != 0 {
+			p.MoveRebaseEntry(-1)
+		} else {
+			p.RebaseMoveUp()
+		}
+	case tcell.KeyDown:
+		if ev.Modifiers()&tcell.ModShift != 0 {
+			p.MoveRebaseEntry(1)
+		} else {
+			p.RebaseMoveDown()
+		}
+	case tcell.KeyEnter:
+		p.StartRebase()
+		return true
+	case tcell.KeyEsc:
+		p.HideRebaseEditor()
+		return true
+	case tcell.KeyRune:
+		switch ev.Rune() {
+		case 'k':
+			p.RebaseMoveUp()
+		case 'j':
+			p.RebaseMoveDown()
+		case 'K':
+			p.MoveRebaseEntry(-1)
+		case 'J':
+			p.MoveRebaseEntry(1)
+		case 'p':
+			p.SetRebaseAction(RebasePick)
+		case 's':
+			p.SetRebaseAction(RebaseSquash)
+		case 'f':
+			p.SetRebaseAction(RebaseFixup)
+		case 'r':
+			p.SetRebaseAction(RebaseReword)
+		case 'd':
+			p.SetRebaseAction(RebaseDrop)
+		}
+	}
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+	// Consume all events while the editor is open
+	return true
+}
+
+// handleRebaseMouse handles wheel scrolling and row clicks in the rebase editor
+func (p *Panel) handleRebaseMouse(ev *tcell.EventMouse, localY int) bool {
+	switch ev.Buttons() {
+	case tcell.WheelUp:
+		p.RebaseMoveUp()
+	case tcell.WheelDown:
+		p.RebaseMoveDown()
+	case tcell.Button1:
+		if idx, ok := p.rebaseYToRow[localY]; ok {
+			p.RebaseSelected = idx
+		}
+	default:
+		return true
+	}
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+	return true
+}
+
+// handleResetConfirmKey handles keyboard events for the reset confirmation
+func (p *Panel) handleResetConfirmKey(ev *tcell.EventKey) bool {
+	switch ev.Key() {
+	case tcell.KeyEnter:
+		p.confirmReset()
+	case tcell.KeyEsc:
+		p.hideResetConfirm()
 	}
 
 	// Consume all events when dialog is open
 	return true
 }
 
+// handleCommitMenuMouse handles clicks in the commit menu; clicking outside
+// it closes it
+func (p *Panel) handleCommitMenuMouse(ev *tcell.EventMouse, localY int) bool {
+	if ev.Buttons() != tcell.Button1 {
+		return true
+	}
+	if idx, ok := p.commitMenuYToRow[localY]; ok {
+		p.CommitMenuSelected = idx
+		p.RunSelectedCommitMenuItem()
+	} else {
+		p.HideCommitMenu()
+	}
+	return true
+}
+
+// handleHistoryKey handles keyboard events for the file history
+func (p *Panel) handleHistoryKey(ev *tcell.EventKey) bool {
+	switch ev.Key() {
+	case tcell.KeyUp:
+		p.HistoryMoveUp()
+	case tcell.KeyDown:
+		p.HistoryMoveDown()
+	case tcell.KeyEnter:
+		p.DiffSelectedRevision()
+	case tcell.KeyEsc, tcell.KeyLeft:
+		p.HideFileHistory()
+		return true
+	case tcell.KeyRune:
+		if ev.Modifiers()&tcell.ModAlt != 0 {
+			return true
+		}
+		switch ev.Rune() {
+		case 'k':
+			p.HistoryMoveUp()
+		case 'j':
+			p.HistoryMoveDown()
+		case 'd':
+			p.DiffSelectedRevision()
+		case 'o':
+			p.OpenSelectedRevision()
+		case 'h':
+			p.HideFileHistory()
+			return true
+		}
+	}
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+	// Consume all events while the history is open
+	return true
+}
+
 // handleDiscardConfirmKey handles keyboard events for the discard confirmation dialog
 func (p *Panel) handleDiscardConfirmKey(ev *tcell.EventKey) bool {
 	switch ev.Key() {
@@ -510,6 +1058,7 @@ func (p *Panel) hideDiscardConfirm() {
 	p.ShowDiscardConfirm = false
 	p.DiscardTarget = ""
 	p.DiscardIsUntracked = false
+	p.DiscardPatch = ""
 
 	if p.OnRefresh != nil {
 		p.OnRefresh()
@@ -523,13 +1072,22 @@ func (p *Panel) confirmDiscard() {
 		return
 	}
 
-	err := p.DiscardChanges(p.DiscardTarget, p.DiscardIsUntracked)
+	var err error
+	if p.DiscardPatch != "" {
+		// Single hunk: reverse-apply it to the worktree
+		err = p.ApplyPatch(p.DiscardPatch, false, true)
+	} else {
+		err = p.DiscardChanges(p.DiscardTarget, p.DiscardIsUntracked)
+	}
 	if err != nil {
 		log.Printf("THICC SourceControl: Failed to discard: %v", err)
 	}
 
 	p.hideDiscardConfirm()
 	p.RefreshStatus()
+	if p.ShowHunkBrowser {
+		p.reloadHunks()
+	}
 
 	if p.OnRefresh != nil {
 		p.OnRefresh()
@@ -543,9 +1101,7 @@ func (p *Panel) handleEnter() bool {
 		// Trigger diff view for selected file
 		file := p.GetSelectedFile()
 		if file != nil {
-			if p.OnFileSelect != nil {
-				p.OnFileSelect(file.Path, false)
-			}
+			p.selectUnstagedFile(*file)
 		}
 		return true
 
@@ -575,6 +1131,38 @@ func (p *Panel) handleMouse(ev *tcell.EventMouse) bool {
 	// Handle mouse wheel scrolling
 	localY := y - p.Region.Y
 
+	if p.ShowResetConfirm {
+		return true
+	}
+
+	if p.ShowCommitMenu {
+		return p.handleCommitMenuMouse(ev, localY)
+	}
+
+	if p.ShowRebase {
+		return p.handleRebaseMouse(ev, localY)
+	}
+
+	if p.ShowHunkBrowser && !p.ShowDiscardConfirm {
+		return p.handleHunkBrowserMouse(ev, localY)
+	}
+
+	if p.ShowCheckpoints && !p.ShowRestoreConfirm {
+		return p.handleCheckpointMouse(ev, localY)
+	}
+
+	if p.ShowWorktrees && p.WorktreeConfirm == "" {
+		return p.handleWorktreeMouse(ev, localY)
+	}
+
+	if p.ShowStashes && !p.ShowDropStashConfirm {
+		return p.handleStashMouse(ev, localY)
+	}
+
+	if p.ShowHistory {
+		return p.handleHistoryMouse(ev, localY)
+	}
+
 	if ev.Buttons() == tcell.WheelUp {
 		// Check if scrolling in graph section
 		if localY >= p.graphSectionY {
@@ -609,6 +1197,16 @@ func (p *Panel) handleMouse(ev *tcell.EventMouse) bool {
 		return true
 	}
 
+	// Right click on a commit opens the commit menu
+	if ev.Buttons() == tcell.Button2 && localY >= p.graphSectionY {
+		if rowIdx, ok := p.graphYToRow[localY]; ok {
+			p.Section = SectionCommitGraph
+			p.GraphSelected = rowIdx
+			p.ShowCommitMenuForSelected()
+		}
+		return true
+	}
+
 	// Handle left click
 	if ev.Buttons() == tcell.Button1 {
 		localX := x - p.Region.X
@@ -624,16 +1222,20 @@ func (p *Panel) handleMouse(ev *tcell.EventMouse) bool {
 
 		// Check if clicking on buttons row
 		if localY == p.buttonsY {
-			// Button layout: [c]Commit (x=2-10), [p]Push (x=11-20), [l]Pull (x=21-30)
-			if localX >= 2 && localX < 11 {
+			// Button layout: [⌥C]Commit [⌥M]Amend [⌥P]Push [⌥L]Pull, with
+			// positions tracked by drawButtons
+			if localX >= 2 && localX < p.amendBtnX {
 				p.Section = SectionCommitBtn
 				log.Printf("THICC SourceControl: Clicked commit button")
 				return true
-			} else if localX >= 11 && localX < 21 {
+			} else if localX >= p.amendBtnX && localX < p.pushBtnX {
+				p.ToggleAmend()
+				return true
+			} else if localX >= p.pushBtnX && localX < p.pullBtnX {
 				p.Section = SectionPushBtn
 				log.Printf("THICC SourceControl: Clicked push button")
 				return true
-			} else if localX >= 21 {
+			} else if localX >= p.pullBtnX {
 				p.Section = SectionPullBtn
 				log.Printf("THICC SourceControl: Clicked pull button")
 				return true
@@ -649,8 +1251,8 @@ func (p *Panel) handleMouse(ev *tcell.EventMouse) bool {
 				p.Selected = i
 				log.Printf("THICC SourceControl: Selected unstaged file %d at Y=%d", i, fileY)
 				// Trigger diff view for the clicked file
-				if i < len(p.UnstagedFiles) && p.OnFileSelect != nil {
-					p.OnFileSelect(p.UnstagedFiles[i].Path, false)
+				if i < len(p.UnstagedFiles) {
+					p.selectUnstagedFile(p.UnstagedFiles[i])
 				}
 				return true
 			}
@@ -686,6 +1288,12 @@ func (p *Panel) handleMouse(ev *tcell.EventMouse) bool {
 			return true
 		}
 
+		// Check if clicking the generate button on the commit header
+		if localY == p.commitSectionY && p.generateBtnX > 0 && localX >= p.generateBtnX {
+			p.DoGenerateCommitMessage()
+			return true
+		}
+
 		// Check if clicking in commit section (input box area) - AFTER file checks
 		if localY >= p.commitSectionY && localY < p.buttonsY {
 			p.Section = SectionCommitInput
@@ -716,6 +1324,124 @@ func (p *Panel) handleMouse(ev *tcell.EventMouse) bool {
 	return false
 }
 
+// handleHunkBrowserMouse handles wheel scrolling and row clicks in the hunk browser
+func (p *Panel) handleHunkBrowserMouse(ev *tcell.EventMouse, localY int) bool {
+	switch ev.Buttons() {
+	case tcell.WheelUp:
+		for i := 0; i < 3; i++ {
+			p.HunkMoveUp(false)
+		}
+	case tcell.WheelDown:
+		for i := 0; i < 3; i++ {
+			p.HunkMoveDown(false)
+		}
+	case tcell.Button1:
+		if rowIdx, ok := p.hunkYToRow[localY]; ok {
+			p.HunkSelected = rowIdx
+			p.previewSelectedHunk()
+		}
+	default:
+		return false
+	}
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+	return true
+}
+
+// handleCheckpointMouse handles wheel scrolling and row clicks in the checkpoint timeline
+func (p *Panel) handleCheckpointMouse(ev *tcell.EventMouse, localY int) bool {
+	switch ev.Buttons() {
+	case tcell.WheelUp:
+		for i := 0; i < 3; i++ {
+			p.CheckpointMoveUp()
+		}
+	case tcell.WheelDown:
+		for i := 0; i < 3; i++ {
+			p.CheckpointMoveDown()
+		}
+	case tcell.Button1:
+		if idx, ok := p.checkpointYToRow[localY]; ok {
+			p.CheckpointSelected = idx
+		}
+	default:
+		return false
+	}
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+	return true
+}
+
+// handleWorktreeMouse handles wheel scrolling and row clicks in the worktree list
+func (p *Panel) handleWorktreeMouse(ev *tcell.EventMouse, localY int) bool {
+	switch ev.Buttons() {
+	case tcell.WheelUp:
+		p.WorktreeMoveUp()
+	case tcell.WheelDown:
+		p.WorktreeMoveDown()
+	case tcell.Button1:
+		if idx, ok := p.worktreeYToRow[localY]; ok {
+			p.WorktreeSelected = idx
+		}
+	default:
+		return false
+	}
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+	return true
+}
+
+// handleStashMouse handles wheel scrolling and row clicks in the stash list
+func (p *Panel) handleStashMouse(ev *tcell.EventMouse, localY int) bool {
+	switch ev.Buttons() {
+	case tcell.WheelUp:
+		p.StashMoveUp()
+	case tcell.WheelDown:
+		p.StashMoveDown()
+	case tcell.Button1:
+		if idx, ok := p.stashYToRow[localY]; ok {
+			p.StashSelected = idx
+		}
+	default:
+		return false
+	}
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+	return true
+}
+
+// handleHistoryMouse handles wheel scrolling and row clicks in the file history
+func (p *Panel) handleHistoryMouse(ev *tcell.EventMouse, localY int) bool {
+	switch ev.Buttons() {
+	case tcell.WheelUp:
+		for i := 0; i < 3; i++ {
+			p.HistoryMoveUp()
+		}
+	case tcell.WheelDown:
+		for i := 0; i < 3; i++ {
+			p.HistoryMoveDown()
+		}
+	case tcell.Button1:
+		if idx, ok := p.historyYToRow[localY]; ok {
+			p.HistorySelected = idx
+		}
+	default:
+		return false
+	}
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+	return true
+}
+
 // pageUp moves up by one page
 func (p *Panel) pageUp() bool {
 	files := p.GetCurrentSectionFiles()
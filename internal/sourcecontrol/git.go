@@ -1,7 +1,6 @@
 package sourcecontrol
 
 import (
-	"fmt"
 	"log"
 	"os"
 	"os/exec"
@@ -58,6 +57,16 @@ func (p *Panel) RefreshStatus() {
 			}
 		}
 
+		// Unmerged paths (DD, AU, UD, UA, DU, AA, UU) need resolving before
+		// they can be staged, so they're only listed as unstaged conflicts
+		if isUnmerged(indexStatus, workTreeStatus) {
+			p.UnstagedFiles = append(p.UnstagedFiles, FileStatus{
+				Path:   path,
+				Status: "U",
+			})
+			continue
+		}
+
 		// Add to appropriate list based on status
 		// Staged changes (index status is not ' ' or '?')
 		if indexStatus != ' ' && indexStatus != '?' {
@@ -85,10 +94,23 @@ func (p *Panel) RefreshStatus() {
 	p.AheadCount = ahead
 	p.BehindCount = behind
 
+	// A branch without an upstream can be published if there is a remote.
+	// That takes two more git calls, so it's only worked out again when
+	// HEAD or the config moved, or after a sync.
+	if sig := p.publishSignature(); sig == "" || sig != p.publishSig {
+		p.Publishable = upstreamBranch(p.RepoRoot) == "" && defaultRemote(p.RepoRoot) != ""
+		p.publishSig = sig
+	}
+
 	log.Printf("THICC SourceControl: Loaded %d staged, %d unstaged files, ahead: %d, behind: %d",
 		len(p.StagedFiles), len(p.UnstagedFiles), ahead, behind)
 }
 
+// isUnmerged returns true if a porcelain XY status is a merge conflict
+func isUnmerged(x, y byte) bool {
+	return x == 'U' || y == 'U' || (x == 'A' && y == 'A') || (x == 'D' && y == 'D')
+}
+
 // StageFile stages a file using git add
 func (p *Panel) StageFile(path string) error {
 	cmd := exec.Command("git", "add", "--", path)
@@ -168,62 +190,27 @@ func (p *Panel) UnstageAll() error {
 	return nil
 }
 
-// Commit creates a commit with the given message
-func (p *Panel) Commit(message string) error {
-	cmd := exec.Command("git", "commit", "-m", message)
-	cmd.Dir = p.RepoRoot
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		log.Printf("THICC SourceControl: git commit failed: %v, output: %s", err, string(output))
-		return err
+// Commit creates a commit with the given message, or amends the last commit
+func (p *Panel) Commit(message string, amend bool) error {
+	args := []string{"commit", "-m", message}
+	if amend {
+		// Amending only rewords the last commit if nothing is staged
+		args = []string{"commit", "--amend", "--allow-empty", "-m", message}
 	}
-	log.Printf("THICC SourceControl: Committed with message: %s", message)
-	return nil
-}
-
-// Push pushes to the remote
-func (p *Panel) Push() error {
-	cmd := exec.Command("git", "push")
+	cmd := exec.Command("git", args...)
 	cmd.Dir = p.RepoRoot
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		log.Printf("THICC SourceControl: git push failed: %v, output: %s", err, string(output))
-		return err
-	}
-	log.Printf("THICC SourceControl: Push successful, output: %s", string(output))
-	return nil
-}
-
-// Pull pulls from the remote
-func (p *Panel) Pull() error {
-	cmd := exec.Command("git", "pull")
-	cmd.Dir = p.RepoRoot
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		log.Printf("THICC SourceControl: git pull failed: %v, output: %s", err, string(output))
+		log.Printf("THICC SourceControl: git commit failed: %v, output: %s", err, string(output))
 		return err
 	}
-	log.Printf("THICC SourceControl: Pull successful, output: %s", string(output))
+	log.Printf("THICC SourceControl: Committed (amend=%v) with message: %s", amend, message)
 	return nil
 }
 
-// GetLocalBranches returns a list of local branch names
-func (p *Panel) GetLocalBranches() ([]string, error) {
-	cmd := exec.Command("git", "branch", "--format=%(refname:short)")
-	cmd.Dir = p.RepoRoot
-	output, err := cmd.Output()
-	if err != nil {
-		log.Printf("THICC SourceControl: git branch failed: %v", err)
-		return nil, err
-	}
-
-	var branches []string
-	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
-		if line != "" {
-			branches = append(branches, line)
-		}
-	}
-	return branches, nil
+// LastCommitMessage returns the full message of the HEAD commit
+func LastCommitMessage(repoRoot string) (string, error) {
+	return gitOutput(repoRoot, nil, "log", "-1", "--format=%B")
 }
 
 // CheckoutBranch switches to the specified branch
@@ -252,6 +239,14 @@ func GetFileContentAtHEAD(repoRoot, path string) (string, error) {
 	return string(output), nil
 }
 
+// IsGitRepo returns true if dir is inside a git working tree
+func IsGitRepo(dir string) bool {
+	cmd := exec.Command("git", "rev-parse", "--is-inside-work-tree")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	return err == nil && strings.TrimSpace(string(output)) == "true"
+}
+
 // GetBranchName returns the current branch name
 func (p *Panel) GetBranchName() string {
 	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
@@ -309,24 +304,33 @@ func parseInt(s string) (int, error) {
 	return n, nil
 }
 
-// RefreshCommitGraph loads the commit history for the graph display
+// RefreshCommitGraph reloads the commit history for the graph display,
+// keeping as many commits as are already loaded
 func (p *Panel) RefreshCommitGraph() {
 	if p.RepoRoot == "" {
 		return
 	}
 
-	// Get commit log with format: hash|short_hash|subject|author|parents
-	// --name-status gives us the files changed per commit
-	cmd := exec.Command("git", "log", "--format=%H|%h|%s|%an|%P", "--name-status", "-n", "50")
-	cmd.Dir = p.RepoRoot
-	output, err := cmd.Output()
+	// Nothing to do if HEAD and the refs haven't moved
+	sig := p.graphSignature()
+	p.mu.RLock()
+	unchanged := sig != "" && sig == p.graphSig
+	count := len(p.CommitGraph)
+	filter := p.GraphFilter
+	p.mu.RUnlock()
+	if unchanged {
+		return
+	}
+	if count < graphPageSize {
+		count = graphPageSize
+	}
+
+	commits, err := loadCommits(p.RepoRoot, filter, 0, count)
 	if err != nil {
 		log.Printf("THICC SourceControl: git log failed: %v", err)
 		return
 	}
-
-	// Parse the output
-	commits := parseCommitLog(string(output))
+	layout := layoutCommits(commits, filter)
 
 	p.mu.Lock()
 	// Preserve expanded state from existing commits
@@ -338,7 +342,9 @@ func (p *Panel) RefreshCommitGraph() {
 	}
 
 	p.CommitGraph = commits
-	p.GraphHasMore = len(commits) >= 50
+	p.GraphHasMore = len(commits) >= count
+	p.graphLayout = layout
+	p.graphSig = sig
 
 	// Restore expanded state
 	for i := range p.CommitGraph {
@@ -346,15 +352,13 @@ func (p *Panel) RefreshCommitGraph() {
 			p.CommitGraph[i].Expanded = true
 		}
 	}
-
-	// Compute ancestry to mark commits from merged branches
-	p.computeAncestry()
 	p.mu.Unlock()
 
 	log.Printf("THICC SourceControl: Loaded %d commits for graph", len(commits))
 }
 
-// parseCommitLog parses git log output into CommitEntry slice
+// parseCommitLog parses git log output in commitLogFormat (with
+// --name-status) into CommitEntry slice
 func parseCommitLog(output string) []CommitEntry {
 	var commits []CommitEntry
 	lines := strings.Split(output, "\n")
@@ -365,31 +369,31 @@ func parseCommitLog(output string) []CommitEntry {
 			continue
 		}
 
-		// Check if this is a commit header line (contains |)
-		if strings.Contains(line, "|") && !strings.HasPrefix(line, "M\t") && !strings.HasPrefix(line, "A\t") && !strings.HasPrefix(line, "D\t") && !strings.HasPrefix(line, "R") {
+		// Commit header lines start with \x1e
+		if strings.HasPrefix(line, "\x1e") {
 			// Save previous commit
 			if currentCommit != nil {
 				commits = append(commits, *currentCommit)
+				currentCommit = nil
 			}
 
-			// Parse new commit header: hash|short_hash|subject|author|parents
-			parts := strings.SplitN(line, "|", 5)
-			if len(parts) < 4 {
+			// Parse new commit header: hash, short hash, author, parents, refs, subject
+			parts := strings.SplitN(line[1:], "\x1f", 6)
+			if len(parts) < 6 {
 				continue
 			}
 
 			currentCommit = &CommitEntry{
 				Hash:      parts[0],
 				ShortHash: parts[1],
-				Subject:   parts[2],
-				Author:    parts[3],
+				Author:    parts[2],
+				Refs:      parseRefs(parts[4]),
+				Subject:   parts[5],
 			}
 
 			// Parse parents (space-separated)
-			if len(parts) >= 5 && parts[4] != "" {
-				currentCommit.Parents = strings.Fields(parts[4])
-				currentCommit.IsMerge = len(currentCommit.Parents) >= 2
-			}
+			currentCommit.Parents = strings.Fields(parts[3])
+			currentCommit.IsMerge = len(currentCommit.Parents) >= 2
 		} else if currentCommit != nil {
 			// This is a file status line: M\tpath, A\tpath, D\tpath, R###\told\tnew
 			if len(line) < 2 {
@@ -426,80 +430,44 @@ func parseCommitLog(output string) []CommitEntry {
 	return commits
 }
 
-// computeAncestry marks commits that came from merged branches
-func (p *Panel) computeAncestry() {
-	// Build hash → index map
-	hashToIdx := make(map[string]int)
-	for i, c := range p.CommitGraph {
-		hashToIdx[c.Hash] = i
-	}
-
-	// For each merge commit, mark second parent's lineage as FromBranch
-	for i, c := range p.CommitGraph {
-		if len(c.Parents) >= 2 {
-			p.CommitGraph[i].IsMerge = true
-			// Trace back from second parent (the merged branch)
-			secondParent := c.Parents[1]
-			p.markBranchAncestry(secondParent, hashToIdx)
-		}
-	}
-}
-
-// markBranchAncestry marks all commits reachable from the given hash as FromBranch
-func (p *Panel) markBranchAncestry(hash string, hashToIdx map[string]int) {
-	visited := make(map[string]bool)
-	queue := []string{hash}
-
-	for len(queue) > 0 {
-		h := queue[0]
-		queue = queue[1:]
-
-		if visited[h] {
-			continue
-		}
-		visited[h] = true
-
-		if idx, ok := hashToIdx[h]; ok {
-			// Stop if we hit another merge (that's a different branch point)
-			if p.CommitGraph[idx].IsMerge {
-				continue
-			}
-			p.CommitGraph[idx].FromBranch = true
-			// Continue to parents
-			for _, parent := range p.CommitGraph[idx].Parents {
-				queue = append(queue, parent)
-			}
-		}
-	}
-}
-
-// LoadMoreCommits loads additional commits beyond what's already loaded
+// LoadMoreCommits loads the next page of commits beyond what's already loaded
 func (p *Panel) LoadMoreCommits() {
-	if p.RepoRoot == "" || !p.GraphHasMore {
+	p.mu.Lock()
+	if p.RepoRoot == "" || !p.GraphHasMore || p.graphLoading || p.graphLayout == nil {
+		p.mu.Unlock()
 		return
 	}
+	p.graphLoading = true
+	skip := len(p.CommitGraph)
+	filter := p.GraphFilter
+	layout := p.graphLayout
+	p.mu.Unlock()
 
-	p.mu.RLock()
-	currentCount := len(p.CommitGraph)
-	p.mu.RUnlock()
+	defer func() {
+		p.mu.Lock()
+		p.graphLoading = false
+		p.mu.Unlock()
+	}()
 
 	// Get more commits, skipping what we already have
-	cmd := exec.Command("git", "log", "--format=%H|%h|%s|%an|%P", "--name-status",
-		"-n", "50", "--skip", fmt.Sprintf("%d", currentCount))
-	cmd.Dir = p.RepoRoot
-	output, err := cmd.Output()
+	newCommits, err := loadCommits(p.RepoRoot, filter, skip, graphPageSize)
 	if err != nil {
 		log.Printf("THICC SourceControl: git log (more) failed: %v", err)
 		return
 	}
 
-	newCommits := parseCommitLog(string(output))
-
 	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.graphLayout != layout || len(p.CommitGraph) != skip {
+		// The graph was reloaded meanwhile
+		return
+	}
+	// The lanes carry on from the last page
+	for i := range newCommits {
+		layout.place(&newCommits[i])
+	}
 	p.CommitGraph = append(p.CommitGraph, newCommits...)
-	p.GraphHasMore = len(newCommits) >= 50
-	p.computeAncestry() // Recompute ancestry with new commits
-	p.mu.Unlock()
+	p.GraphHasMore = len(newCommits) >= graphPageSize
 
-	log.Printf("THICC SourceControl: Loaded %d more commits, total: %d", len(newCommits), len(p.CommitGraph)+len(newCommits))
+	log.Printf("THICC SourceControl: Loaded %d more commits, total: %d", len(newCommits), len(p.CommitGraph))
 }
@@ -0,0 +1,338 @@
+package sourcecontrol
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// graphPageSize is how many commits the graph loads at a time
+const graphPageSize = 50
+
+// commitLogFormat starts each commit with \x1e and separates fields with \x1f:
+// hash, short hash, author, parents, ref names, subject
+const commitLogFormat = "--format=%x1e%H%x1f%h%x1f%an%x1f%P%x1f%D%x1f%s"
+
+// GraphCell is one character of a commit's lane drawing
+type GraphCell struct {
+	Glyph rune
+	Lane  int // Lane the character belongs to (picks its color)
+}
+
+// GraphFilter limits the commits shown in the graph
+type GraphFilter struct {
+	Author string // Author name or email contains this
+	Path   string // Commit touches this file or directory
+	Text   string // Commit message contains this
+}
+
+// ParseGraphFilter parses filter text like "author:ann path:src/ fix crash".
+// Words without a prefix are searched for in commit messages.
+func ParseGraphFilter(s string) GraphFilter {
+	var f GraphFilter
+	var text []string
+	for _, word := range strings.Fields(s) {
+		switch {
+		case strings.HasPrefix(word, "author:"):
+			f.Author = strings.TrimPrefix(word, "author:")
+		case strings.HasPrefix(word, "path:"):
+			f.Path = strings.TrimPrefix(word, "path:")
+		default:
+			text = append(text, word)
+		}
+	}
+	f.Text = strings.Join(text, " ")
+	return f
+}
+
+// String formats the filter the way ParseGraphFilter reads it
+func (f GraphFilter) String() string {
+	var parts []string
+	if f.Author != "" {
+		parts = append(parts, "author:"+f.Author)
+	}
+	if f.Path != "" {
+		parts = append(parts, "path:"+f.Path)
+	}
+	if f.Text != "" {
+		parts = append(parts, f.Text)
+	}
+	return strings.Join(parts, " ")
+}
+
+// IsEmpty returns true if the filter doesn't hide any commits
+func (f GraphFilter) IsEmpty() bool {
+	return f == GraphFilter{}
+}
+
+// flat returns true if the filter hides commits without rewriting their
+// parents, which leaves no ancestry to draw lanes for
+func (f GraphFilter) flat() bool {
+	return f.Author != "" || f.Text != ""
+}
+
+// commitLogArgs returns the `git log` arguments for a page of the graph
+func commitLogArgs(f GraphFilter, skip, count int) []string {
+	// --parents rewrites parents to the commits a path filter keeps, so
+	// the lanes still connect
+	args := []string{"log", "--topo-order", "--parents", commitLogFormat, "--name-status",
+		"-n", fmt.Sprintf("%d", count)}
+	if skip > 0 {
+		args = append(args, "--skip", fmt.Sprintf("%d", skip))
+	}
+	if f.Author != "" || f.Text != "" {
+		args = append(args, "--regexp-ignore-case", "--fixed-strings")
+	}
+	if f.Author != "" {
+		args = append(args, "--author="+f.Author)
+	}
+	if f.Text != "" {
+		args = append(args, "--grep="+f.Text)
+	}
+	if f.Path != "" {
+		args = append(args, "--", f.Path)
+	}
+	return args
+}
+
+// loadCommits loads a page of the commit log
+func loadCommits(repoRoot string, f GraphFilter, skip, count int) ([]CommitEntry, error) {
+	output, err := gitOutput(repoRoot, nil, commitLogArgs(f, skip, count)...)
+	if err != nil {
+		return nil, err
+	}
+	return parseCommitLog(output), nil
+}
+
+// parseRefs splits %D output ("HEAD -> main, origin/main, tag: v1.0") into
+// ref names, leaving out aliases like origin/HEAD
+func parseRefs(decoration string) []string {
+	var refs []string
+	for _, ref := range strings.Split(decoration, ", ") {
+		ref = strings.TrimSpace(ref)
+		if ref == "" || strings.HasSuffix(ref, "/HEAD") {
+			continue
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// graphLayout assigns commits to lanes as they are read, newest first, the
+// way `git log --graph` draws them. It keeps its state between pages.
+type graphLayout struct {
+	lanes []string // Commit each lane is heading to ("" for a free lane)
+	flat  bool     // Draw every commit in one lane, without edges
+}
+
+// place works out the commit's lane drawing and moves the lanes past it
+func (g *graphLayout) place(c *CommitEntry) {
+	dot := '●'
+	if c.IsMerge {
+		dot = '◆'
+	}
+	if g.flat {
+		c.Graph = []GraphCell{{Glyph: dot}}
+		c.GraphPass = nil
+		return
+	}
+
+	// The commit takes the lane heading to it, or a free one if nothing is
+	// (a branch tip)
+	col := indexOfLane(g.lanes, c.Hash)
+	if col < 0 {
+		col = indexOfLane(g.lanes, "")
+		if col < 0 {
+			col = len(g.lanes)
+			g.lanes = append(g.lanes, "")
+		}
+	}
+
+	before := g.lanes
+	after := append([]string(nil), before...)
+	joins := make(map[int]rune) // Lanes connected to the commit, and their glyph
+
+	// Other lanes heading to this commit end here
+	for i, h := range before {
+		if i != col && h == c.Hash {
+			after[i] = ""
+			joins[i] = pickGlyph(i > col, '╯', '╰')
+		}
+	}
+
+	// The lane continues to the first parent; merged parents join an
+	// existing lane or start a new one
+	after[col] = ""
+	if len(c.Parents) > 0 {
+		after[col] = c.Parents[0]
+	}
+	for i := 1; i < len(c.Parents); i++ {
+		parent := c.Parents[i]
+		if j := indexOfLane(after, parent); j >= 0 {
+			if j != col {
+				joins[j] = pickGlyph(j > col, '┤', '├')
+			}
+			continue
+		}
+		j := -1
+		for k := range after {
+			if after[k] == "" && (k >= len(before) || before[k] == "") {
+				j = k
+				break
+			}
+		}
+		if j < 0 {
+			j = len(after)
+			after = append(after, "")
+		}
+		after[j] = parent
+		joins[j] = pickGlyph(j > col, '╮', '╭')
+	}
+	for len(after) > 0 && after[len(after)-1] == "" {
+		after = after[:len(after)-1]
+	}
+
+	// Commit row: dot, lanes passing by and lanes joining the commit, two
+	// cells per lane
+	width := len(before)
+	if len(after) > width {
+		width = len(after)
+	}
+	cells := make([]GraphCell, 0, 2*width)
+	for i := 0; i < width; i++ {
+		glyph := ' '
+		if i == col {
+			glyph = dot
+		} else if joins[i] != 0 {
+			glyph = joins[i]
+		} else if i < len(before) && before[i] != "" {
+			glyph = '│'
+		}
+		cells = append(cells, GraphCell{Glyph: glyph, Lane: i}, GraphCell{Glyph: ' ', Lane: i})
+	}
+
+	// Horizontal edges from the dot to the joining lanes
+	for k := 0; k < width; k++ {
+		if joins[k] == 0 {
+			continue
+		}
+		lo, hi := col, k
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+		for i := lo; i < hi; i++ {
+			cells[2*i+1] = GraphCell{Glyph: '─', Lane: k}
+			if i > lo {
+				cells[2*i] = GraphCell{Glyph: crossGlyph(cells[2*i].Glyph), Lane: k}
+			}
+		}
+	}
+	c.Graph = trimGraphCells(cells)
+
+	// Lanes passing by below the commit (wrapped subject and file rows)
+	pass := make([]GraphCell, 0, 2*len(after))
+	for i, h := range after {
+		glyph := ' '
+		if h != "" {
+			glyph = '│'
+		}
+		pass = append(pass, GraphCell{Glyph: glyph, Lane: i}, GraphCell{Glyph: ' ', Lane: i})
+	}
+	c.GraphPass = trimGraphCells(pass)
+
+	g.lanes = after
+}
+
+// indexOfLane returns the first lane heading to hash, or -1
+func indexOfLane(lanes []string, hash string) int {
+	for i, h := range lanes {
+		if h == hash {
+			return i
+		}
+	}
+	return -1
+}
+
+// pickGlyph returns right if the lane is to the right of the commit
+func pickGlyph(isRight bool, right, left rune) rune {
+	if isRight {
+		return right
+	}
+	return left
+}
+
+// crossGlyph returns the glyph for a horizontal edge crossing a cell
+func crossGlyph(glyph rune) rune {
+	switch glyph {
+	case ' ':
+		return '─'
+	case '╯', '╰':
+		return '┴'
+	case '╮', '╭':
+		return '┬'
+	default:
+		return '┼'
+	}
+}
+
+// trimGraphCells drops trailing blank cells
+func trimGraphCells(cells []GraphCell) []GraphCell {
+	for len(cells) > 0 && cells[len(cells)-1].Glyph == ' ' {
+		cells = cells[:len(cells)-1]
+	}
+	return cells
+}
+
+// layoutCommits lays out the lanes of the first page of a graph
+func layoutCommits(commits []CommitEntry, f GraphFilter) *graphLayout {
+	layout := &graphLayout{flat: f.flat()}
+	for i := range commits {
+		layout.place(&commits[i])
+	}
+	return layout
+}
+
+// graphSignature identifies the state of HEAD and the refs, so the graph
+// is only reloaded when something could have changed it. Returns "" if it
+// can't be worked out.
+func (p *Panel) graphSignature() string {
+	head, err := gitOutput(p.RepoRoot, nil, "rev-parse", "HEAD")
+	if err != nil {
+		return ""
+	}
+	refs, err := gitOutput(p.RepoRoot, nil, "for-each-ref", "--format=%(HEAD)%(objectname) %(refname)",
+		"refs/heads", "refs/remotes", "refs/tags")
+	if err != nil {
+		return ""
+	}
+	return head + "\n" + refs
+}
+
+// SetGraphFilter filters the commit graph and reloads it from the top
+func (p *Panel) SetGraphFilter(f GraphFilter) {
+	p.mu.Lock()
+	p.GraphFilter = f
+	p.CommitGraph = nil
+	p.graphSig = ""
+	p.GraphSelected = 0
+	p.GraphTopLine = 0
+	p.mu.Unlock()
+
+	log.Printf("THICC SourceControl: Graph filter set to %q", f.String())
+	p.RefreshCommitGraph()
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
+
+// PromptGraphFilter asks for the graph filter; an empty one shows all commits
+func (p *Panel) PromptGraphFilter() {
+	if p.OnInput == nil {
+		return
+	}
+	p.OnInput("Filter Log", "author:NAME path:PATH message text", p.GraphFilter.String(), func(text string, canceled bool) {
+		if !canceled {
+			p.SetGraphFilter(ParseGraphFilter(text))
+		}
+	})
+}
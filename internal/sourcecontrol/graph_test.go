@@ -0,0 +1,172 @@
+package sourcecontrol
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// graphString renders a lane drawing as plain text
+func graphString(cells []GraphCell) string {
+	var s []rune
+	for _, c := range cells {
+		s = append(s, c.Glyph)
+	}
+	return string(s)
+}
+
+// =============================================================================
+// Commit Log Parsing Tests
+// =============================================================================
+
+func TestParseCommitLog(t *testing.T) {
+	output := "\x1eh1\x1fs1\x1fAnn\x1fp1 p2\x1fHEAD -> main, origin/HEAD, tag: v1\x1fMerge a | b\n" +
+		"\n" +
+		"M\tmain.go\n" +
+		"R100\told.go\tnew.go\n" +
+		"\x1ep1\x1fsp1\x1fBob\x1f\x1f\x1finitial\n" +
+		"A\tmain.go\n"
+
+	commits := parseCommitLog(output)
+	require.Len(t, commits, 2)
+	assert.Equal(t, "Merge a | b", commits[0].Subject, "subjects may contain |")
+	assert.Equal(t, "Ann", commits[0].Author)
+	assert.True(t, commits[0].IsMerge)
+	assert.Equal(t, []string{"HEAD -> main", "tag: v1"}, commits[0].Refs)
+	assert.Equal(t, []FileStatus{{Path: "main.go", Status: "M"}, {Path: "new.go", Status: "R"}}, commits[0].Files)
+	assert.Empty(t, commits[1].Parents)
+	assert.Empty(t, commits[1].Refs)
+}
+
+// =============================================================================
+// Graph Filter Tests
+// =============================================================================
+
+func TestParseGraphFilter(t *testing.T) {
+	f := ParseGraphFilter("author:ann fix  crash path:src/")
+	assert.Equal(t, GraphFilter{Author: "ann", Path: "src/", Text: "fix crash"}, f)
+	assert.Equal(t, "author:ann path:src/ fix crash", f.String())
+	assert.True(t, ParseGraphFilter("  ").IsEmpty())
+}
+
+func TestCommitLogArgs(t *testing.T) {
+	args := commitLogArgs(GraphFilter{Path: "src"}, 50, 25)
+	assert.Contains(t, args, "--parents")
+	assert.Equal(t, []string{"--skip", "50", "--", "src"}, args[len(args)-4:])
+
+	args = commitLogArgs(GraphFilter{Author: "ann", Text: "fix"}, 0, 50)
+	assert.NotContains(t, args, "--skip")
+	assert.Contains(t, args, "--author=ann")
+	assert.Contains(t, args, "--grep=fix")
+}
+
+// =============================================================================
+// Lane Layout Tests
+// =============================================================================
+
+func TestGraphLayout_MergedBranch(t *testing.T) {
+	commits := []CommitEntry{
+		{Hash: "A", Parents: []string{"B", "C"}, IsMerge: true},
+		{Hash: "B", Parents: []string{"D"}},
+		{Hash: "C", Parents: []string{"D"}},
+		{Hash: "D"},
+	}
+	layoutCommits(commits, GraphFilter{})
+
+	var rows []string
+	for _, c := range commits {
+		rows = append(rows, graphString(c.Graph))
+	}
+	assert.Equal(t, []string{
+		"◆─╮",
+		"● │",
+		"│ ●",
+		"●─╯",
+	}, rows)
+	assert.Equal(t, "│ │", graphString(commits[0].GraphPass))
+	assert.Empty(t, commits[3].GraphPass)
+}
+
+func TestGraphLayout_CarriesOnAcrossPages(t *testing.T) {
+	layout := &graphLayout{}
+	first := []CommitEntry{
+		{Hash: "A", Parents: []string{"B", "C"}, IsMerge: true},
+		{Hash: "C", Parents: []string{"D"}},
+	}
+	for i := range first {
+		layout.place(&first[i])
+	}
+
+	next := CommitEntry{Hash: "B", Parents: []string{"D"}}
+	layout.place(&next)
+	assert.Equal(t, "● │", graphString(next.Graph), "B stays in the lane A left for it")
+}
+
+func TestGraphLayout_FlatWhenFiltered(t *testing.T) {
+	commits := []CommitEntry{
+		{Hash: "A", Parents: []string{"B"}},
+		{Hash: "C", Parents: []string{"D"}},
+	}
+	layoutCommits(commits, GraphFilter{Author: "ann"})
+	assert.Equal(t, "●", graphString(commits[1].Graph))
+	assert.Empty(t, commits[1].GraphPass)
+}
+
+func TestWrapSubject(t *testing.T) {
+	assert.Equal(t, []string{"short"}, wrapSubject("short", 10, 10, 1))
+	assert.Equal(t, []string{"a long..."}, wrapSubject("a long subject", 9, 9, 1))
+	assert.Equal(t, []string{"a long", "subject"}, wrapSubject("a long subject", 9, 9, 3))
+}
+
+// =============================================================================
+// Graph Loading Tests
+// =============================================================================
+
+func TestCommitGraph_LoadsMorePages(t *testing.T) {
+	dir := initTestRepo(t)
+	for i := 0; i < graphPageSize; i++ {
+		_, err := gitOutput(dir, nil, "commit", "-q", "--allow-empty", "-m", fmt.Sprintf("commit %d", i))
+		require.NoError(t, err)
+	}
+
+	p := &Panel{RepoRoot: dir}
+	p.RefreshCommitGraph()
+	require.Len(t, p.CommitGraph, graphPageSize)
+	assert.True(t, p.GraphHasMore)
+
+	p.LoadMoreCommits()
+	require.Len(t, p.CommitGraph, graphPageSize+1)
+	assert.False(t, p.GraphHasMore)
+	assert.Equal(t, "initial", p.CommitGraph[graphPageSize].Subject)
+
+	// A refresh reloads as many commits as were loaded
+	_, err := gitOutput(dir, nil, "commit", "-q", "--allow-empty", "-m", "newest")
+	require.NoError(t, err)
+	p.RefreshCommitGraph()
+	assert.Len(t, p.CommitGraph, graphPageSize+1)
+	assert.Equal(t, "newest", p.CommitGraph[0].Subject)
+	assert.True(t, p.GraphHasMore)
+}
+
+func TestCommitGraph_Filter(t *testing.T) {
+	dir := initTestRepo(t)
+	writeFile(t, dir, "other.go", "package main\n")
+	_, err := gitOutput(dir, nil, "add", "other.go")
+	require.NoError(t, err)
+	_, err = gitOutput(dir, nil, "commit", "-q", "-m", "Add other")
+	require.NoError(t, err)
+
+	p := &Panel{RepoRoot: dir}
+	p.SetGraphFilter(GraphFilter{Text: "ADD"})
+	require.Len(t, p.CommitGraph, 1, "message search ignores case")
+	assert.Equal(t, "Add other", p.CommitGraph[0].Subject)
+
+	p.SetGraphFilter(GraphFilter{Path: "main.go"})
+	require.Len(t, p.CommitGraph, 1)
+	assert.Equal(t, "initial", p.CommitGraph[0].Subject)
+
+	p.SetGraphFilter(GraphFilter{})
+	assert.Len(t, p.CommitGraph, 2)
+}
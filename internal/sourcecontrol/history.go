@@ -0,0 +1,174 @@
+package sourcecontrol
+
+import (
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileRevision is a commit that changed a file
+type FileRevision struct {
+	Hash      string
+	ShortHash string
+	Author    string
+	Time      time.Time
+	Subject   string
+	Path      string // Path of the file in this commit (differs before a rename)
+}
+
+// fileHistoryFormat separates commits with \x1e and fields with \x1f; the
+// file's path in each commit follows on its own line (--name-only)
+const fileHistoryFormat = "--format=%x1e%H%x1f%h%x1f%an%x1f%at%x1f%s"
+
+// parseFileHistory parses `git log --follow --name-only` output in
+// fileHistoryFormat, newest first
+func parseFileHistory(output string) []FileRevision {
+	var revs []FileRevision
+	for _, record := range strings.Split(output, "\x1e") {
+		lines := strings.Split(strings.TrimSpace(record), "\n")
+		fields := strings.SplitN(lines[0], "\x1f", 5)
+		if len(fields) < 5 {
+			continue
+		}
+		rev := FileRevision{
+			Hash:      fields[0],
+			ShortHash: fields[1],
+			Author:    fields[2],
+			Subject:   fields[4],
+		}
+		if secs, err := strconv.ParseInt(fields[3], 10, 64); err == nil {
+			rev.Time = time.Unix(secs, 0)
+		}
+		for _, line := range lines[1:] {
+			if line = strings.TrimSpace(line); line != "" {
+				rev.Path = line
+			}
+		}
+		revs = append(revs, rev)
+	}
+	return revs
+}
+
+// RepoRelPath returns a file's path relative to the root of the repository
+// it is in, which is what `git show rev:path` and the history expect
+func RepoRelPath(absPath string) (string, error) {
+	prefix, err := gitOutput(filepath.Dir(absPath), nil, "rev-parse", "--show-prefix")
+	if err != nil {
+		return "", err
+	}
+	return prefix + filepath.Base(absPath), nil
+}
+
+// FileHistory returns the commits that changed a file (relative to the repo
+// root), newest first, following it across renames
+func FileHistory(repoRoot, path string) ([]FileRevision, error) {
+	output, err := gitOutput(repoRoot, nil, "log", "--follow", fileHistoryFormat, "--name-only", "--", path)
+	if err != nil {
+		return nil, err
+	}
+	revs := parseFileHistory(output)
+	for i := range revs {
+		if revs[i].Path == "" {
+			revs[i].Path = path
+		}
+	}
+	return revs, nil
+}
+
+// FileAtRevision returns the content of the file as it was at a revision
+func FileAtRevision(repoRoot string, rev FileRevision) (string, error) {
+	return gitOutput(repoRoot, nil, "show", rev.Hash+":"+rev.Path)
+}
+
+// ShowFileHistory opens the history of a file (relative to the repo root),
+// as read by FileHistory
+func (p *Panel) ShowFileHistory(path string, revs []FileRevision, err error) {
+	if err != nil {
+		log.Printf("THICC SourceControl: Failed to load history of %s: %v", path, err)
+		if p.OnMessage != nil {
+			p.OnMessage("No history for "+path, true)
+		}
+		return
+	}
+
+	// Only one overlay at a time
+	p.ShowHunkBrowser = false
+	p.ShowCheckpoints = false
+	p.ShowWorktrees = false
+
+	p.HistoryPath = path
+	p.History = revs
+	p.HistorySelected = 0
+	p.HistoryTopLine = 0
+	p.ShowHistory = true
+	log.Printf("THICC SourceControl: Browsing %d revisions of %s", len(revs), path)
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
+
+// HideFileHistory closes the file history
+func (p *Panel) HideFileHistory() {
+	p.ShowHistory = false
+	p.History = nil
+	p.HistoryPath = ""
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
+
+// selectedRevision returns the revision under the cursor, or nil
+func (p *Panel) selectedRevision() *FileRevision {
+	if p.HistorySelected < 0 || p.HistorySelected >= len(p.History) {
+		return nil
+	}
+	return &p.History[p.HistorySelected]
+}
+
+// HistoryMoveUp moves the history cursor to the next newer revision
+func (p *Panel) HistoryMoveUp() {
+	if p.HistorySelected > 0 {
+		p.HistorySelected--
+	}
+}
+
+// HistoryMoveDown moves the history cursor to the next older revision
+func (p *Panel) HistoryMoveDown() {
+	if p.HistorySelected < len(p.History)-1 {
+		p.HistorySelected++
+	}
+}
+
+// DiffSelectedRevision shows what the selected commit changed in the file
+func (p *Panel) DiffSelectedRevision() {
+	rev := p.selectedRevision()
+	if rev == nil {
+		return
+	}
+	if p.OnCommitSelect != nil {
+		p.OnCommitSelect(rev.Hash, rev.Path)
+	}
+}
+
+// OpenSelectedRevision opens the file as it was at the selected commit
+func (p *Panel) OpenSelectedRevision() {
+	rev := p.selectedRevision()
+	if rev == nil {
+		return
+	}
+	content, err := FileAtRevision(p.RepoRoot, *rev)
+	if err != nil {
+		log.Printf("THICC SourceControl: Failed to read %s at %s: %v", rev.Path, rev.ShortHash, err)
+		if p.OnMessage != nil {
+			p.OnMessage("Could not read "+rev.Path+" at "+rev.ShortHash, true)
+		}
+		return
+	}
+	if p.OnOpenRevision != nil {
+		p.OnOpenRevision(rev.Path, rev.Hash, content)
+	}
+}
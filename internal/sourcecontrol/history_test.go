@@ -0,0 +1,120 @@
+package sourcecontrol
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// File History Parsing Tests
+// =============================================================================
+
+func TestParseFileHistory(t *testing.T) {
+	output := "\x1eh2\x1fs2\x1fBob\x1f200\x1fRename\n\nnew.go\n" +
+		"\x1eh1\x1fs1\x1fAlice\x1f100\x1fAdd it\n\nold.go\n"
+
+	revs := parseFileHistory(output)
+	require.Len(t, revs, 2)
+	assert.Equal(t, "h2", revs[0].Hash)
+	assert.Equal(t, "new.go", revs[0].Path)
+	assert.Equal(t, "Alice", revs[1].Author)
+	assert.Equal(t, "Add it", revs[1].Subject)
+	assert.Equal(t, "old.go", revs[1].Path)
+	assert.Equal(t, time.Unix(100, 0), revs[1].Time)
+}
+
+func TestParseFileHistory_Empty(t *testing.T) {
+	assert.Empty(t, parseFileHistory(""))
+}
+
+// =============================================================================
+// File History Git Tests
+// =============================================================================
+
+func TestFileHistory_FollowsRenames(t *testing.T) {
+	dir := initTestRepo(t)
+	writeFile(t, dir, "main.go", "package main\n\nfunc main() {}\n")
+	_, err := gitOutput(dir, nil, "commit", "-qam", "Add main func")
+	require.NoError(t, err)
+	_, err = gitOutput(dir, nil, "mv", "main.go", "app.go")
+	require.NoError(t, err)
+	_, err = gitOutput(dir, nil, "commit", "-qm", "Rename to app.go")
+	require.NoError(t, err)
+
+	revs, err := FileHistory(dir, "app.go")
+	require.NoError(t, err)
+	require.Len(t, revs, 3)
+	assert.Equal(t, "Rename to app.go", revs[0].Subject)
+	assert.Equal(t, "app.go", revs[0].Path)
+	assert.Equal(t, "main.go", revs[2].Path, "older revisions keep their old path")
+
+	content, err := FileAtRevision(dir, revs[2])
+	require.NoError(t, err)
+	assert.Equal(t, "package main", content)
+}
+
+func TestFileHistory_OtherFilesLeftOut(t *testing.T) {
+	dir := initTestRepo(t)
+	writeFile(t, dir, "other.go", "package main\n")
+	_, err := gitOutput(dir, nil, "add", "other.go")
+	require.NoError(t, err)
+	_, err = gitOutput(dir, nil, "commit", "-qm", "Add other")
+	require.NoError(t, err)
+
+	revs, err := FileHistory(dir, "main.go")
+	require.NoError(t, err)
+	require.Len(t, revs, 1)
+	assert.Equal(t, "initial", revs[0].Subject)
+}
+
+func TestRepoRelPath_FromSubdirectory(t *testing.T) {
+	dir := initTestRepo(t)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "pkg"), 0755))
+	writeFile(t, dir, "pkg/util.go", "package pkg\n")
+
+	path, err := RepoRelPath(filepath.Join(dir, "pkg", "util.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "pkg/util.go", path)
+	path, err = RepoRelPath(filepath.Join(dir, "main.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "main.go", path)
+}
+
+// =============================================================================
+// File History Panel Tests
+// =============================================================================
+
+func TestFileHistoryPanel_DiffAndOpen(t *testing.T) {
+	dir := initTestRepo(t)
+	writeFile(t, dir, "main.go", "package main // v2\n")
+	_, err := gitOutput(dir, nil, "commit", "-qam", "v2")
+	require.NoError(t, err)
+
+	var diffHash, diffPath, openContent string
+	p := &Panel{RepoRoot: dir}
+	p.OnCommitSelect = func(hash, path string) { diffHash, diffPath = hash, path }
+	p.OnOpenRevision = func(_, _, content string) { openContent = content }
+
+	revs, err := FileHistory(dir, "main.go")
+	p.ShowFileHistory("main.go", revs, err)
+	require.True(t, p.ShowHistory)
+	require.Len(t, p.History, 2)
+
+	p.DiffSelectedRevision()
+	assert.Equal(t, p.History[0].Hash, diffHash)
+	assert.Equal(t, "main.go", diffPath)
+
+	p.HistoryMoveDown()
+	p.HistoryMoveDown()
+	p.OpenSelectedRevision()
+	assert.Equal(t, "package main", openContent)
+
+	p.HideFileHistory()
+	assert.False(t, p.ShowHistory)
+	assert.Nil(t, p.History)
+}
@@ -0,0 +1,577 @@
+package sourcecontrol
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// HunkLine is a single line inside a diff hunk
+type HunkLine struct {
+	Kind      byte   // '+' added, '-' removed, ' ' context
+	Text      string // Line content without the +/-/space prefix
+	NoNewline bool   // Followed by "\ No newline at end of file"
+	Selected  bool   // Marked for a line-level stage/unstage/discard
+}
+
+// IsChange returns true for added or removed lines
+func (l HunkLine) IsChange() bool {
+	return l.Kind == '+' || l.Kind == '-'
+}
+
+// DiffHunk is one @@ section of a file diff
+type DiffHunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Section  string // Text after the closing @@ (usually the enclosing function)
+	Lines    []HunkLine
+}
+
+// Header returns the @@ header line for the hunk
+func (h *DiffHunk) Header() string {
+	header := fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+	if h.Section != "" {
+		header += " " + h.Section
+	}
+	return header
+}
+
+// Stats returns the number of added and removed lines in the hunk
+func (h *DiffHunk) Stats() (added, removed int) {
+	for _, l := range h.Lines {
+		switch l.Kind {
+		case '+':
+			added++
+		case '-':
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// HasSelection returns true if any line in the hunk is marked
+func (h *DiffHunk) HasSelection() bool {
+	for _, l := range h.Lines {
+		if l.Selected {
+			return true
+		}
+	}
+	return false
+}
+
+// FileDiff is the parsed diff of a single file
+type FileDiff struct {
+	Header []string // diff --git, index, ---, +++ lines
+	Hunks  []DiffHunk
+}
+
+// ParseFileDiff parses the output of `git diff -- <path>` for a single file
+func ParseFileDiff(output string) *FileDiff {
+	fd := &FileDiff{}
+	lines := strings.Split(output, "\n")
+
+	var hunk *DiffHunk
+	for _, line := range lines {
+		if strings.HasPrefix(line, "@@") {
+			if hunk != nil {
+				fd.Hunks = append(fd.Hunks, *hunk)
+			}
+			hunk = parseHunkHeader(line)
+			continue
+		}
+
+		if hunk == nil {
+			// Everything before the first hunk is file header
+			if line != "" {
+				fd.Header = append(fd.Header, line)
+			}
+			continue
+		}
+
+		if strings.HasPrefix(line, "\\") {
+			// "\ No newline at end of file" applies to the previous line
+			if n := len(hunk.Lines); n > 0 {
+				hunk.Lines[n-1].NoNewline = true
+			}
+			continue
+		}
+
+		if line == "" {
+			// Trailing newline of the output (a real empty context line is " ")
+			continue
+		}
+
+		switch line[0] {
+		case '+', '-', ' ':
+			hunk.Lines = append(hunk.Lines, HunkLine{Kind: line[0], Text: line[1:]})
+		}
+	}
+
+	if hunk != nil {
+		fd.Hunks = append(fd.Hunks, *hunk)
+	}
+
+	return fd
+}
+
+// parseHunkHeader parses "@@ -a,b +c,d @@ section" into an empty DiffHunk
+func parseHunkHeader(line string) *DiffHunk {
+	hunk := &DiffHunk{OldLines: 1, NewLines: 1}
+
+	rest := strings.TrimPrefix(line, "@@")
+	end := strings.Index(rest, "@@")
+	if end < 0 {
+		return hunk
+	}
+	hunk.Section = strings.TrimSpace(rest[end+2:])
+
+	for _, field := range strings.Fields(rest[:end]) {
+		if len(field) < 2 {
+			continue
+		}
+		start, count := parseHunkRange(field[1:])
+		switch field[0] {
+		case '-':
+			hunk.OldStart, hunk.OldLines = start, count
+		case '+':
+			hunk.NewStart, hunk.NewLines = start, count
+		}
+	}
+	return hunk
+}
+
+// parseHunkRange parses "start,count" (count defaults to 1)
+func parseHunkRange(s string) (start, count int) {
+	count = 1
+	parts := strings.SplitN(s, ",", 2)
+	start, _ = strconv.Atoi(parts[0])
+	if len(parts) == 2 {
+		count, _ = strconv.Atoi(parts[1])
+	}
+	return start, count
+}
+
+// BuildPatch returns a patch containing only the given hunk, suitable for
+// `git apply`. When onlySelected is true, changed lines that are not marked
+// are neutralized: for a forward patch unmarked additions are dropped and
+// unmarked removals become context, and for a reverse patch (applied with -R)
+// it is the other way around.
+func (fd *FileDiff) BuildPatch(hunkIdx int, onlySelected, reverse bool) string {
+	if hunkIdx < 0 || hunkIdx >= len(fd.Hunks) {
+		return ""
+	}
+	src := fd.Hunks[hunkIdx]
+
+	// Lines that exist on the side the patch is applied to become context,
+	// lines that don't exist there are dropped
+	keepAsContext := byte('-')
+	drop := byte('+')
+	if reverse {
+		keepAsContext, drop = '+', '-'
+	}
+
+	var lines []HunkLine
+	oldLines, newLines := 0, 0
+	for _, l := range src.Lines {
+		if onlySelected && l.IsChange() && !l.Selected {
+			if l.Kind == drop {
+				continue
+			}
+			if l.Kind == keepAsContext {
+				l.Kind = ' '
+			}
+		}
+		switch l.Kind {
+		case ' ':
+			oldLines++
+			newLines++
+		case '-':
+			oldLines++
+		case '+':
+			newLines++
+		}
+		lines = append(lines, l)
+	}
+
+	// Nothing left to change
+	hasChange := false
+	for _, l := range lines {
+		if l.IsChange() {
+			hasChange = true
+			break
+		}
+	}
+	if !hasChange {
+		return ""
+	}
+
+	hunk := DiffHunk{
+		OldLines: oldLines,
+		NewLines: newLines,
+		Section:  src.Section,
+	}
+	// Anchor on the side the patch is applied to; line numbers there are
+	// unaffected by the other hunks since those aren't applied
+	if reverse {
+		hunk.NewStart = src.NewStart
+		hunk.OldStart = otherSideStart(src.NewStart, newLines, oldLines)
+	} else {
+		hunk.OldStart = src.OldStart
+		hunk.NewStart = otherSideStart(src.OldStart, oldLines, newLines)
+	}
+
+	var sb strings.Builder
+	for _, h := range fd.Header {
+		sb.WriteString(h)
+		sb.WriteByte('\n')
+	}
+	sb.WriteString(hunk.Header())
+	sb.WriteByte('\n')
+	for _, l := range lines {
+		sb.WriteByte(l.Kind)
+		sb.WriteString(l.Text)
+		sb.WriteByte('\n')
+		if l.NoNewline {
+			sb.WriteString("\\ No newline at end of file\n")
+		}
+	}
+	return sb.String()
+}
+
+// otherSideStart derives the start line of the opposite side of a hunk. An
+// empty range starts at the line before it, so pure insertions and deletions
+// are off by one.
+func otherSideStart(start, count, otherCount int) int {
+	switch {
+	case count == 0:
+		return start + 1
+	case otherCount == 0 && start > 0:
+		return start - 1
+	default:
+		return start
+	}
+}
+
+// GetFileDiff returns the parsed diff for a file, either index vs worktree
+// (unstaged) or HEAD vs index (staged)
+func (p *Panel) GetFileDiff(path string, staged bool) (*FileDiff, error) {
+	args := []string{"diff", "--no-color", "--no-ext-diff"}
+	if staged {
+		args = append(args, "--cached")
+	}
+	args = append(args, "--", path)
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = p.RepoRoot
+	output, err := cmd.Output()
+	if err != nil {
+		log.Printf("THICC SourceControl: git diff failed for %s: %v", path, err)
+		return nil, err
+	}
+	return ParseFileDiff(string(output)), nil
+}
+
+// ApplyPatch feeds a patch to git apply. With cached the index is updated,
+// otherwise the worktree; reverse applies the patch with -R.
+func (p *Panel) ApplyPatch(patch string, cached, reverse bool) error {
+	args := []string{"apply", "--whitespace=nowarn"}
+	if cached {
+		args = append(args, "--cached")
+	}
+	if reverse {
+		args = append(args, "-R")
+	}
+	args = append(args, "-")
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = p.RepoRoot
+	cmd.Stdin = strings.NewReader(patch)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		log.Printf("THICC SourceControl: git apply failed: %v, output: %s", err, string(output))
+		return err
+	}
+	log.Printf("THICC SourceControl: Applied patch (cached=%v, reverse=%v)", cached, reverse)
+	return nil
+}
+
+// StageHunk stages one hunk (or its selected lines) of an unstaged diff
+func (p *Panel) StageHunk(fd *FileDiff, hunkIdx int, onlySelected bool) error {
+	patch := fd.BuildPatch(hunkIdx, onlySelected, false)
+	if patch == "" {
+		return nil
+	}
+	return p.ApplyPatch(patch, true, false)
+}
+
+// UnstageHunk removes one hunk (or its selected lines) of a staged diff from the index
+func (p *Panel) UnstageHunk(fd *FileDiff, hunkIdx int, onlySelected bool) error {
+	patch := fd.BuildPatch(hunkIdx, onlySelected, true)
+	if patch == "" {
+		return nil
+	}
+	return p.ApplyPatch(patch, true, true)
+}
+
+// DiscardHunk reverts one hunk (or its selected lines) of an unstaged diff in the worktree
+func (p *Panel) DiscardHunk(fd *FileDiff, hunkIdx int, onlySelected bool) error {
+	patch := fd.BuildPatch(hunkIdx, onlySelected, true)
+	if patch == "" {
+		return nil
+	}
+	return p.ApplyPatch(patch, false, true)
+}
+
+// hunkRow is one row of the hunk browser: a hunk header (line == -1) or a diff line
+type hunkRow struct {
+	hunk int
+	line int
+}
+
+// hunkRows flattens the hunk browser into display rows
+func (p *Panel) hunkRows() []hunkRow {
+	if p.HunkDiff == nil {
+		return nil
+	}
+	var rows []hunkRow
+	for i, h := range p.HunkDiff.Hunks {
+		rows = append(rows, hunkRow{hunk: i, line: -1})
+		for j := range h.Lines {
+			rows = append(rows, hunkRow{hunk: i, line: j})
+		}
+	}
+	return rows
+}
+
+// currentHunkRow returns the row under the hunk browser cursor
+func (p *Panel) currentHunkRow() (hunkRow, bool) {
+	rows := p.hunkRows()
+	if p.HunkSelected < 0 || p.HunkSelected >= len(rows) {
+		return hunkRow{}, false
+	}
+	return rows[p.HunkSelected], true
+}
+
+// ShowHunks opens the hunk browser for the selected file
+func (p *Panel) ShowHunks() {
+	if p.Section != SectionUnstaged && p.Section != SectionStaged {
+		return
+	}
+	file := p.GetSelectedFile()
+	if file == nil {
+		return
+	}
+	if file.Status == "?" {
+		// Untracked files have no index entry to diff against
+		log.Printf("THICC SourceControl: No hunks for untracked file %s, stage the whole file instead", file.Path)
+		return
+	}
+
+	staged := p.Section == SectionStaged
+	diff, err := p.GetFileDiff(file.Path, staged)
+	if err != nil || len(diff.Hunks) == 0 {
+		log.Printf("THICC SourceControl: No hunks to browse for %s", file.Path)
+		return
+	}
+
+	p.HunkPath = file.Path
+	p.HunkStaged = staged
+	p.HunkDiff = diff
+	p.HunkSelected = 0
+	p.HunkTopLine = 0
+	p.hunkPreviewed = -1
+	p.ShowHunkBrowser = true
+
+	log.Printf("THICC SourceControl: Browsing %d hunks of %s (staged=%v)", len(diff.Hunks), file.Path, staged)
+	p.previewSelectedHunk()
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
+
+// HideHunks closes the hunk browser
+func (p *Panel) HideHunks() {
+	p.ShowHunkBrowser = false
+	p.HunkDiff = nil
+	p.HunkPath = ""
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
+
+// reloadHunks re-reads the diff after the index or worktree changed.
+// The browser closes itself once the file has no hunks left.
+func (p *Panel) reloadHunks() {
+	diff, err := p.GetFileDiff(p.HunkPath, p.HunkStaged)
+	if err != nil || len(diff.Hunks) == 0 {
+		p.HideHunks()
+		return
+	}
+
+	// Keep the cursor on the same hunk index where possible
+	row, _ := p.currentHunkRow()
+	p.HunkDiff = diff
+	hunkIdx := row.hunk
+	if hunkIdx >= len(diff.Hunks) {
+		hunkIdx = len(diff.Hunks) - 1
+	}
+	p.HunkSelected = 0
+	for i, r := range p.hunkRows() {
+		if r.hunk == hunkIdx && r.line == -1 {
+			p.HunkSelected = i
+			break
+		}
+	}
+	p.hunkPreviewed = -1
+	p.previewSelectedHunk()
+}
+
+// previewSelectedHunk reports the hunk under the cursor via OnHunkSelect
+func (p *Panel) previewSelectedHunk() {
+	row, ok := p.currentHunkRow()
+	if !ok || row.hunk == p.hunkPreviewed {
+		return
+	}
+	p.hunkPreviewed = row.hunk
+	if p.OnHunkSelect != nil {
+		p.OnHunkSelect(p.HunkPath, p.HunkDiff.BuildPatch(row.hunk, false, false))
+	}
+}
+
+// HunkMoveUp moves the hunk browser cursor up; with extend the lines passed
+// over are marked, which selects a line range
+func (p *Panel) HunkMoveUp(extend bool) {
+	if p.HunkSelected > 0 {
+		if extend {
+			p.markHunkRow(p.HunkSelected)
+		}
+		p.HunkSelected--
+		if extend {
+			p.markHunkRow(p.HunkSelected)
+		}
+	}
+	p.previewSelectedHunk()
+}
+
+// HunkMoveDown moves the hunk browser cursor down (see HunkMoveUp)
+func (p *Panel) HunkMoveDown(extend bool) {
+	if p.HunkSelected < len(p.hunkRows())-1 {
+		if extend {
+			p.markHunkRow(p.HunkSelected)
+		}
+		p.HunkSelected++
+		if extend {
+			p.markHunkRow(p.HunkSelected)
+		}
+	}
+	p.previewSelectedHunk()
+}
+
+// HunkJump moves the cursor to the header of the next (dir=1) or previous (dir=-1) hunk
+func (p *Panel) HunkJump(dir int) {
+	rows := p.hunkRows()
+	for i := p.HunkSelected + dir; i >= 0 && i < len(rows); i += dir {
+		if rows[i].line == -1 {
+			p.HunkSelected = i
+			break
+		}
+	}
+	p.previewSelectedHunk()
+}
+
+// markHunkRow marks a changed line for a line-level operation
+func (p *Panel) markHunkRow(rowIdx int) {
+	rows := p.hunkRows()
+	if rowIdx < 0 || rowIdx >= len(rows) || rows[rowIdx].line < 0 {
+		return
+	}
+	line := &p.HunkDiff.Hunks[rows[rowIdx].hunk].Lines[rows[rowIdx].line]
+	if line.IsChange() {
+		line.Selected = true
+	}
+}
+
+// ToggleHunkLine toggles the mark on the line under the cursor.
+// On a hunk header it marks every changed line of the hunk, or clears them
+// all if any are already marked.
+func (p *Panel) ToggleHunkLine() {
+	row, ok := p.currentHunkRow()
+	if !ok {
+		return
+	}
+	hunk := &p.HunkDiff.Hunks[row.hunk]
+
+	if row.line >= 0 {
+		line := &hunk.Lines[row.line]
+		if line.IsChange() {
+			line.Selected = !line.Selected
+		}
+		return
+	}
+
+	mark := !hunk.HasSelection()
+	for i := range hunk.Lines {
+		if hunk.Lines[i].IsChange() {
+			hunk.Lines[i].Selected = mark
+		}
+	}
+}
+
+// ApplySelectedHunk stages (or unstages when browsing the staged diff) the hunk
+// under the cursor. If lines of that hunk are marked, only those are applied.
+func (p *Panel) ApplySelectedHunk() {
+	row, ok := p.currentHunkRow()
+	if !ok {
+		return
+	}
+	onlySelected := p.HunkDiff.Hunks[row.hunk].HasSelection()
+
+	var err error
+	if p.HunkStaged {
+		err = p.UnstageHunk(p.HunkDiff, row.hunk, onlySelected)
+	} else {
+		err = p.StageHunk(p.HunkDiff, row.hunk, onlySelected)
+	}
+	if err != nil {
+		log.Printf("THICC SourceControl: Failed to apply hunk: %v", err)
+		return
+	}
+
+	p.RefreshStatus()
+	p.reloadHunks()
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
+
+// showHunkDiscardConfirm asks for confirmation before discarding the hunk
+// (or its marked lines) under the cursor. Only valid for unstaged changes.
+func (p *Panel) showHunkDiscardConfirm() {
+	if p.HunkStaged {
+		return
+	}
+	row, ok := p.currentHunkRow()
+	if !ok {
+		return
+	}
+	onlySelected := p.HunkDiff.Hunks[row.hunk].HasSelection()
+	patch := p.HunkDiff.BuildPatch(row.hunk, onlySelected, true)
+	if patch == "" {
+		return
+	}
+
+	p.DiscardTarget = p.HunkPath
+	p.DiscardIsUntracked = false
+	p.DiscardPatch = patch
+	p.ShowDiscardConfirm = true
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
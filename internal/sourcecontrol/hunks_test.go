@@ -0,0 +1,191 @@
+package sourcecontrol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const twoHunkDiff = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,4 +1,5 @@ package main
+ a
+-b
++B
++b2
+ c
+ d
+@@ -10,3 +11,2 @@ func main() {
+ x
+-y
+ z
+`
+
+// =============================================================================
+// Diff Parsing Tests
+// =============================================================================
+
+func TestParseFileDiff_HeaderAndHunks(t *testing.T) {
+	fd := ParseFileDiff(twoHunkDiff)
+
+	assert.Equal(t, 4, len(fd.Header))
+	assert.Equal(t, "diff --git a/main.go b/main.go", fd.Header[0])
+	assert.Equal(t, 2, len(fd.Hunks))
+}
+
+func TestParseFileDiff_HunkRanges(t *testing.T) {
+	fd := ParseFileDiff(twoHunkDiff)
+
+	h := fd.Hunks[0]
+	assert.Equal(t, 1, h.OldStart)
+	assert.Equal(t, 4, h.OldLines)
+	assert.Equal(t, 1, h.NewStart)
+	assert.Equal(t, 5, h.NewLines)
+	assert.Equal(t, "package main", h.Section)
+
+	h = fd.Hunks[1]
+	assert.Equal(t, 10, h.OldStart)
+	assert.Equal(t, 3, h.OldLines)
+	assert.Equal(t, 11, h.NewStart)
+	assert.Equal(t, 2, h.NewLines)
+}
+
+func TestParseFileDiff_LineKinds(t *testing.T) {
+	fd := ParseFileDiff(twoHunkDiff)
+
+	lines := fd.Hunks[0].Lines
+	assert.Equal(t, 6, len(lines))
+	assert.Equal(t, byte(' '), lines[0].Kind)
+	assert.Equal(t, byte('-'), lines[1].Kind)
+	assert.Equal(t, "b", lines[1].Text)
+	assert.Equal(t, byte('+'), lines[2].Kind)
+	assert.Equal(t, "B", lines[2].Text)
+}
+
+func TestParseFileDiff_NoNewlineMarker(t *testing.T) {
+	diff := "--- a/f\n+++ b/f\n@@ -1 +1 @@\n-old\n\\ No newline at end of file\n+new\n\\ No newline at end of file\n"
+	fd := ParseFileDiff(diff)
+
+	assert.Equal(t, 1, len(fd.Hunks))
+	assert.Equal(t, 1, fd.Hunks[0].OldLines)
+	assert.Equal(t, 2, len(fd.Hunks[0].Lines))
+	assert.True(t, fd.Hunks[0].Lines[0].NoNewline)
+	assert.True(t, fd.Hunks[0].Lines[1].NoNewline)
+}
+
+func TestParseFileDiff_Empty(t *testing.T) {
+	fd := ParseFileDiff("")
+	assert.Equal(t, 0, len(fd.Hunks))
+}
+
+func TestDiffHunk_Stats(t *testing.T) {
+	fd := ParseFileDiff(twoHunkDiff)
+
+	added, removed := fd.Hunks[0].Stats()
+	assert.Equal(t, 2, added)
+	assert.Equal(t, 1, removed)
+}
+
+// =============================================================================
+// Patch Building Tests
+// =============================================================================
+
+func TestBuildPatch_WholeHunk(t *testing.T) {
+	fd := ParseFileDiff(twoHunkDiff)
+
+	patch := fd.BuildPatch(1, false, false)
+	expected := `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -10,3 +10,2 @@ func main() {
+ x
+-y
+ z
+`
+	assert.Equal(t, expected, patch)
+}
+
+func TestBuildPatch_OutOfRange(t *testing.T) {
+	fd := ParseFileDiff(twoHunkDiff)
+	assert.Equal(t, "", fd.BuildPatch(5, false, false))
+	assert.Equal(t, "", fd.BuildPatch(-1, false, false))
+}
+
+func TestBuildPatch_SelectedLinesForward(t *testing.T) {
+	fd := ParseFileDiff(twoHunkDiff)
+	// Only stage the "+B" line: "-b" stays as context, "+b2" is dropped
+	fd.Hunks[0].Lines[2].Selected = true
+
+	patch := fd.BuildPatch(0, true, false)
+	assert.Contains(t, patch, "@@ -1,4 +1,5 @@ package main\n a\n b\n+B\n c\n d\n")
+}
+
+func TestBuildPatch_SelectedLinesReverse(t *testing.T) {
+	fd := ParseFileDiff(twoHunkDiff)
+	// Only revert the "+b2" line: "+B" stays as context, "-b" is dropped
+	fd.Hunks[0].Lines[3].Selected = true
+
+	patch := fd.BuildPatch(0, true, true)
+	assert.Contains(t, patch, "@@ -1,4 +1,5 @@ package main\n a\n B\n+b2\n c\n d\n")
+}
+
+func TestBuildPatch_NothingSelected(t *testing.T) {
+	fd := ParseFileDiff(twoHunkDiff)
+	assert.Equal(t, "", fd.BuildPatch(0, true, false))
+}
+
+func TestBuildPatch_KeepsNoNewlineMarker(t *testing.T) {
+	diff := "--- a/f\n+++ b/f\n@@ -1 +1 @@\n-old\n\\ No newline at end of file\n+new\n\\ No newline at end of file\n"
+	fd := ParseFileDiff(diff)
+
+	patch := fd.BuildPatch(0, false, false)
+	assert.Contains(t, patch, "-old\n\\ No newline at end of file\n+new\n\\ No newline at end of file\n")
+}
+
+// =============================================================================
+// Hunk Browser Navigation Tests
+// =============================================================================
+
+func TestHunkJump_MovesBetweenHeaders(t *testing.T) {
+	p := &Panel{HunkDiff: ParseFileDiff(twoHunkDiff), hunkPreviewed: -1}
+
+	p.HunkJump(1)
+	row, _ := p.currentHunkRow()
+	assert.Equal(t, 1, row.hunk)
+	assert.Equal(t, -1, row.line)
+
+	p.HunkJump(-1)
+	row, _ = p.currentHunkRow()
+	assert.Equal(t, 0, row.hunk)
+	assert.Equal(t, -1, row.line)
+}
+
+func TestHunkMoveDown_ExtendMarksChangedLines(t *testing.T) {
+	p := &Panel{HunkDiff: ParseFileDiff(twoHunkDiff), HunkSelected: 1, hunkPreviewed: -1}
+
+	// Rows: 0=header, 1=" a", 2="-b", 3="+B", 4="+b2"
+	p.HunkMoveDown(true)
+	p.HunkMoveDown(true)
+
+	lines := p.HunkDiff.Hunks[0].Lines
+	assert.False(t, lines[0].Selected) // context lines are never marked
+	assert.True(t, lines[1].Selected)
+	assert.True(t, lines[2].Selected)
+	assert.False(t, lines[3].Selected)
+}
+
+func TestToggleHunkLine_OnHeaderTogglesWholeHunk(t *testing.T) {
+	p := &Panel{HunkDiff: ParseFileDiff(twoHunkDiff), hunkPreviewed: -1}
+
+	p.ToggleHunkLine()
+	assert.True(t, p.HunkDiff.Hunks[0].HasSelection())
+	for _, l := range p.HunkDiff.Hunks[0].Lines {
+		assert.Equal(t, l.IsChange(), l.Selected)
+	}
+
+	p.ToggleHunkLine()
+	assert.False(t, p.HunkDiff.Hunks[0].HasSelection())
+}
@@ -30,8 +30,10 @@ type CommitEntry struct {
 	Files      []FileStatus // Modified files in this commit
 	Expanded   bool         // Whether file list is shown
 	IsMerge    bool         // True if merge commit (has 2+ parents)
-	FromBranch bool         // True if commit came from a merged branch
 	Parents    []string     // Parent hashes for ancestry tracking
+	Refs       []string     // Branches and tags pointing here, e.g. "HEAD -> main", "tag: v1.0"
+	Graph      []GraphCell  // Lane drawing on the commit's row
+	GraphPass  []GraphCell  // Lanes passing by below the commit
 }
 
 // Section represents which section of the panel is active
@@ -57,8 +59,9 @@ type Panel struct {
 	// Git state
 	StagedFiles   []FileStatus
 	UnstagedFiles []FileStatus
-	AheadCount    int // Commits ahead of remote
-	BehindCount   int // Commits behind remote
+	AheadCount    int  // Commits ahead of remote
+	BehindCount   int  // Commits behind remote
+	Publishable   bool // Branch has no upstream yet but there is a remote to push it to
 
 	// UI state
 	Section       Section // Current section
@@ -66,21 +69,55 @@ type Panel struct {
 	TopLine       int     // Scroll position for file list
 	CommitMsg     string  // Commit message being typed
 	CommitCursor  int     // Cursor position in commit message
+	Amend         bool    // Commit amends the last commit instead of adding one
 
 	// Branch dialog state
-	ShowBranchDialog bool
-	LocalBranches    []string
-	BranchSelected   int
-	BranchTopLine    int
+	ShowBranchDialog       bool
+	Branches               []Branch // Local branches, then remote ones
+	BranchSelected         int
+	BranchTopLine          int
+	ShowForceDeleteConfirm bool // Whether the unmerged branch delete confirmation is shown
 
 	// Commit graph state
 	CommitGraph   []CommitEntry
 	GraphSelected int  // Index of selected row in graph view (can be commit or file)
 	GraphTopLine  int  // Scroll offset for graph
 	GraphHasMore  bool // True if more commits available to load
+	GraphFilter   GraphFilter
+	graphLayout   *graphLayout // Lane state after the last loaded commit
+	graphSig      string       // HEAD and refs when the graph was loaded
+	graphLoading  bool         // A page of commits is being loaded
+	GraphError    string       // Why the last commit operation failed, shown above the graph
+
+	// Commit menu state (operations on a commit in the graph)
+	ShowCommitMenu     bool
+	CommitMenuCommit   CommitEntry
+	CommitMenuItems    []CommitMenuItem // Entries offered, with an abort while git is stopped
+	CommitMenuSelected int
+	ShowResetConfirm   bool         // Whether the reset confirmation is shown
+	ResetAction        CommitAction // Which reset the confirmation is for
+	commitMenuYToRow   map[int]int  // Screen Y to menu entry, for clicks
+
+	// Interactive rebase editor state
+	ShowRebase     bool
+	RebaseEntries  []RebaseEntry // Oldest first, the order they are replayed in
+	RebaseBase     string        // Commit the entries are replayed onto
+	RebaseBaseName string        // Branch the base was worked out from
+	RebaseSelected int
+	RebaseTopLine  int
+	rebaseYToRow   map[int]int // Maps Y position to rebase entry index
 
 	// Operation progress state
-	OperationInProgress string // "Committing", "Pushing", "Pulling", or ""
+	OperationInProgress string // "Committing", "Amending", "Pushing", "Pulling", "Fetching", or ""
+	OperationProgress   string // Latest progress line git printed for the operation
+
+	// Auto-fetch state
+	lastFetch time.Time // When the remotes were last fetched
+	fetching  bool      // A background fetch is running
+
+	// Publishable is only worked out again when what it depends on changed
+	publishSig   string   // HEAD and the config when Publishable was worked out
+	publishPaths []string // HEAD and config file paths
 
 	// Mutex for thread safety
 	mu sync.RWMutex
@@ -94,6 +131,45 @@ type Panel struct {
 	ShowDiscardConfirm bool   // Whether discard confirmation is shown
 	DiscardTarget      string // Path of file to discard
 	DiscardIsUntracked bool   // Whether the file is untracked (deletion warning)
+	DiscardPatch       string // Reverse patch when discarding a single hunk (empty for whole file)
+
+	// Hunk browser state (partial staging of a single file)
+	ShowHunkBrowser bool
+	HunkPath        string    // File whose hunks are listed
+	HunkStaged      bool      // True when browsing the staged diff (HEAD vs index)
+	HunkDiff        *FileDiff // Parsed diff for HunkPath
+	HunkSelected    int       // Selected row (hunk header or diff line)
+	HunkTopLine     int       // Scroll offset for hunk rows
+	hunkPreviewed   int       // Hunk last sent to OnHunkSelect (-1 = none)
+
+	// Checkpoint timeline state (snapshots taken around AI agent turns)
+	ShowCheckpoints    bool
+	Checkpoints        []Checkpoint // Newest first
+	CheckpointSelected int          // Selected checkpoint
+	CheckpointTopLine  int          // Scroll offset for the timeline
+	CheckpointMark     int          // Checkpoint marked for diffing (-1 = none)
+	ShowRestoreConfirm bool         // Whether the restore confirmation is shown
+
+	// Worktree list state (AI sessions running in their own git worktree)
+	ShowWorktrees    bool
+	Worktrees        []Worktree
+	WorktreeSelected int    // Selected worktree
+	WorktreeBaseDir  string // Directory thicc creates worktrees in (set by the layout)
+	WorktreeConfirm  string // Pending confirmation: "merge", "discard" or ""
+
+	// Stash list state
+	ShowStashes          bool
+	Stashes              []Stash // Newest first
+	StashSelected        int     // Selected stash
+	StashTopLine         int     // Scroll offset for the stash list
+	ShowDropStashConfirm bool    // Whether the drop confirmation is shown
+
+	// File history state (commits that changed one file)
+	ShowHistory     bool
+	HistoryPath     string         // File whose history is listed (relative to RepoRoot)
+	History         []FileRevision // Newest first
+	HistorySelected int            // Selected revision
+	HistoryTopLine  int            // Scroll offset for the history
 
 	// PR Size Meter state
 	PRMeter *PRMeterState // Current meter state (nil if not calculated yet)
@@ -104,15 +180,30 @@ type Panel struct {
 	stagedHeaderY   int   // Y position of staged section header
 	commitSectionY  int   // Y position of commit section
 	buttonsY        int   // Y position of buttons row
+	generateBtnX    int   // X position of the generate message button on the commit header (0 = hidden)
+	amendBtnX       int   // X positions where the amend, push and pull buttons start
+	pushBtnX        int
+	pullBtnX        int
 	unstagedFileYs  []int // Y positions of unstaged files
 	stagedFileYs    []int // Y positions of staged files
 	graphSectionY   int         // Y position of graph section
 	graphRowYs      []int       // Y positions of graph rows (first line of each)
 	graphYToRow     map[int]int // Maps Y position to logical row index (for multi-line commits)
+	hunkYToRow      map[int]int // Maps Y position to hunk browser row index
+	checkpointYToRow map[int]int // Maps Y position to checkpoint index
+	worktreeYToRow   map[int]int // Maps Y position to worktree index
+	historyYToRow    map[int]int // Maps Y position to file history index
+	stashYToRow      map[int]int // Maps Y position to stash index
 
 	// Callbacks
 	OnFileSelect   func(path string, isStaged bool)   // Called when user selects a file (for diff view)
 	OnCommitSelect func(commitHash string, path string) // Called when user selects a file in a commit
+	OnHunkSelect   func(path string, patch string)      // Called when the hunk browser moves to another hunk
+	OnConflictSelect func(path string)                  // Called when user selects a file with merge conflicts
+	OnShowDiff     func(title string, patch string)   // Called to show a multi-file diff (checkpoints, worktrees)
+	OnOpenRevision func(path, hash, content string)   // Called to show a file as it was at a commit
+	OnMessage      func(msg string, isError bool)     // Called to tell the user how an operation went
+	OnInput        func(title, prompt, initial string, done func(text string, canceled bool)) // Called to ask the user for text
 	OnRefresh      func()                             // Called when UI needs refresh
 }
 
@@ -197,9 +288,11 @@ func (p *Panel) StartPolling() {
 				p.RefreshStatus()
 				p.RefreshCommitGraph()
 				p.RefreshPRMeter()
+				p.RefreshStashes()
 				if p.OnRefresh != nil {
 					p.OnRefresh()
 				}
+				go p.autoFetch()
 			}
 		}
 	}()
@@ -503,6 +596,18 @@ func (p *Panel) TriggerFileSelect() {
 	}
 }
 
+// selectUnstagedFile opens an unstaged file: conflicted files go to the
+// conflict resolver, everything else to the diff view
+func (p *Panel) selectUnstagedFile(file FileStatus) {
+	if file.Status == "U" && p.OnConflictSelect != nil {
+		p.OnConflictSelect(file.Path)
+		return
+	}
+	if p.OnFileSelect != nil {
+		p.OnFileSelect(file.Path, false)
+	}
+}
+
 // ToggleStageSelected stages or unstages the selected file
 func (p *Panel) ToggleStageSelected() {
 	file := p.GetSelectedFile()
@@ -534,7 +639,7 @@ func (p *Panel) DoCommit() {
 		return
 	}
 
-	if len(p.StagedFiles) == 0 {
+	if !p.CanCommit() {
 		log.Println("THICC SourceControl: Nothing staged to commit")
 		return
 	}
@@ -545,11 +650,15 @@ func (p *Panel) DoCommit() {
 	}
 
 	msg := p.CommitMsg
+	amend := p.Amend
 	p.OperationInProgress = "Committing"
+	if amend {
+		p.OperationInProgress = "Amending"
+	}
 	go p.spinnerLoop()
 
 	go func() {
-		err := p.Commit(msg)
+		err := p.Commit(msg, amend)
 
 		p.mu.Lock()
 		p.OperationInProgress = ""
@@ -559,36 +668,7 @@ func (p *Panel) DoCommit() {
 			log.Println("THICC SourceControl: Commit successful")
 			p.CommitMsg = ""
 			p.CommitCursor = 0
-		}
-		p.mu.Unlock()
-
-		p.RefreshStatus()
-		p.RefreshCommitGraph()
-		if p.OnRefresh != nil {
-			p.OnRefresh()
-		}
-	}()
-}
-
-// DoPush pushes to remote
-func (p *Panel) DoPush() {
-	// Already in progress?
-	if p.OperationInProgress != "" {
-		return
-	}
-
-	p.OperationInProgress = "Pushing"
-	go p.spinnerLoop()
-
-	go func() {
-		err := p.Push()
-
-		p.mu.Lock()
-		p.OperationInProgress = ""
-		if err != nil {
-			log.Printf("THICC SourceControl: Push failed: %v", err)
-		} else {
-			log.Println("THICC SourceControl: Push successful")
+			p.Amend = false
 		}
 		p.mu.Unlock()
 
@@ -712,12 +792,31 @@ func (p *Panel) ClearCommitMsg() {
 
 // CanCommit returns true if commit is possible
 func (p *Panel) CanCommit() bool {
-	return len(p.StagedFiles) > 0
+	return len(p.StagedFiles) > 0 || p.Amend
+}
+
+// ToggleAmend switches the commit button between a new commit and amending
+// the last one. Turning amend on with no message typed starts from the last
+// commit's message.
+func (p *Panel) ToggleAmend() {
+	p.Amend = !p.Amend
+	if p.Amend && p.CommitMsg == "" {
+		if msg, err := LastCommitMessage(p.RepoRoot); err == nil {
+			p.CommitMsg = msg
+			p.CommitCursor = len(msg)
+		}
+	}
+	log.Printf("THICC SourceControl: Amend %v", p.Amend)
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
 }
 
-// CanPush returns true if there are commits to push
+// CanPush returns true if there are commits to push or the branch can be
+// published
 func (p *Panel) CanPush() bool {
-	return p.AheadCount > 0
+	return p.AheadCount > 0 || p.Publishable
 }
 
 // CanPull returns true if there are commits to pull
@@ -725,78 +824,56 @@ func (p *Panel) CanPull() bool {
 	return p.BehindCount > 0
 }
 
-// DoPull pulls from remote
-func (p *Panel) DoPull() {
-	// Already in progress?
-	if p.OperationInProgress != "" {
-		return
-	}
-
-	p.OperationInProgress = "Pulling"
-	go p.spinnerLoop()
-
-	go func() {
-		err := p.Pull()
-
-		p.mu.Lock()
-		p.OperationInProgress = ""
-		if err != nil {
-			log.Printf("THICC SourceControl: Pull failed: %v", err)
-		} else {
-			log.Println("THICC SourceControl: Pull successful")
-		}
-		p.mu.Unlock()
-
-		p.RefreshStatus()
-		p.RefreshCommitGraph()
-		if p.OnRefresh != nil {
-			p.OnRefresh()
-		}
-	}()
-}
-
 // ShowBranchSwitcher opens the branch switching dialog
 func (p *Panel) ShowBranchSwitcher() {
-	branches, err := p.GetLocalBranches()
-	if err != nil {
-		log.Printf("THICC SourceControl: Failed to get branches: %v", err)
-		return
-	}
-	p.LocalBranches = branches
 	p.BranchSelected = 0
 	p.BranchTopLine = 0
+	p.ShowBranchDialog = true
+	p.RefreshBranches()
+	if len(p.Branches) == 0 {
+		p.ShowBranchDialog = false
+		return
+	}
 
 	// Select current branch if found
-	currentBranch := p.GetBranchName()
-	for i, branch := range branches {
-		if branch == currentBranch {
+	for i, branch := range p.Branches {
+		if branch.Current {
 			p.BranchSelected = i
+			p.EnsureBranchVisible()
 			break
 		}
 	}
-
-	p.ShowBranchDialog = true
 }
 
 // HideBranchSwitcher closes the branch dialog
 func (p *Panel) HideBranchSwitcher() {
 	p.ShowBranchDialog = false
+	p.ShowForceDeleteConfirm = false
 	if p.OnRefresh != nil {
 		p.OnRefresh()
 	}
 }
 
-// SwitchToSelectedBranch switches to the selected branch
+// SwitchToSelectedBranch switches to the selected branch. A remote branch
+// is checked out as a local branch tracking it.
 func (p *Panel) SwitchToSelectedBranch() {
-	if p.BranchSelected < 0 || p.BranchSelected >= len(p.LocalBranches) {
+	branch := p.selectedBranch()
+	if branch == nil {
 		return
 	}
-	branchName := p.LocalBranches[p.BranchSelected]
-	err := p.CheckoutBranch(branchName)
+	var err error
+	if branch.Remote {
+		err = CheckoutRemoteBranch(p.RepoRoot, branch.Name)
+	} else {
+		err = p.CheckoutBranch(branch.Name)
+	}
 	if err != nil {
 		log.Printf("THICC SourceControl: Failed to checkout branch: %v", err)
+		if p.OnMessage != nil {
+			p.OnMessage("Could not switch to "+branch.Name+": "+err.Error(), true)
+		}
 	} else {
-		log.Printf("THICC SourceControl: Switched to branch: %s", branchName)
+		log.Printf("THICC SourceControl: Switched to branch: %s", branch.Name)
 		p.HideBranchSwitcher()
 		p.RefreshStatus()
 		p.RefreshCommitGraph()
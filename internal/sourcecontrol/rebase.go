@@ -0,0 +1,328 @@
+package sourcecontrol
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNoRebaseBase is returned when there is no base branch or upstream to
+// rebase the current branch onto
+var ErrNoRebaseBase = errors.New("no base branch or upstream to rebase onto")
+
+// ErrNothingToMeldInto is returned when a rebase would squash or fix up a
+// commit without an earlier commit to fold it into
+var ErrNothingToMeldInto = errors.New("the first commit can't be squashed or fixed up")
+
+// ErrNotOnBranch is returned when fixing up a commit that isn't on the current branch
+var ErrNotOnBranch = errors.New("the commit isn't on the current branch")
+
+// RebaseAction is what an interactive rebase does with a commit
+type RebaseAction string
+
+const (
+	RebasePick   RebaseAction = "pick"
+	RebaseSquash RebaseAction = "squash" // Meld into the previous commit, keeping both messages
+	RebaseFixup  RebaseAction = "fixup"  // Meld into the previous commit, dropping this message
+	RebaseReword RebaseAction = "reword"
+	RebaseDrop   RebaseAction = "drop"
+)
+
+// RebaseEntry is a line of the rebase editor
+type RebaseEntry struct {
+	Action    RebaseAction
+	Hash      string
+	ShortHash string
+	Subject   string
+	Message   string // New message for a reword
+}
+
+// rebaseLogFormat separates fields with \x1f: hash, short hash, subject
+const rebaseLogFormat = "--format=%H%x1f%h%x1f%s"
+
+// FindRebaseBase returns the commit the current branch forked from and the name
+// of the branch it was compared with: the base branch if there is one
+// (see detectBaseBranch), otherwise the upstream
+func FindRebaseBase(repoRoot string) (base, name string, err error) {
+	name = detectBaseBranch(repoRoot)
+	if name == "" {
+		name, err = gitOutput(repoRoot, nil, "rev-parse", "--abbrev-ref", "@{upstream}")
+		if err != nil || name == "" {
+			return "", "", ErrNoRebaseBase
+		}
+	}
+	base, err = gitOutput(repoRoot, nil, "merge-base", "HEAD", name)
+	if err != nil {
+		return "", "", err
+	}
+	return base, name, nil
+}
+
+// ListRebaseCommits returns the commits after base, oldest first (the order
+// they are replayed in), all set to pick. Like `git rebase -i`, parents come
+// before their children whatever the commit dates say.
+func ListRebaseCommits(repoRoot, base string) ([]RebaseEntry, error) {
+	output, err := gitOutput(repoRoot, nil, "log", "--reverse", "--topo-order", "--no-merges", rebaseLogFormat, base+"..HEAD")
+	if err != nil {
+		return nil, err
+	}
+	var entries []RebaseEntry
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(line, "\x1f", 3)
+		if len(fields) < 3 {
+			continue
+		}
+		entries = append(entries, RebaseEntry{Action: RebasePick, Hash: fields[0], ShortHash: fields[1], Subject: fields[2]})
+	}
+	return entries, nil
+}
+
+// checkRebaseEntries makes sure every squash and fixup has a kept commit
+// before it
+func checkRebaseEntries(entries []RebaseEntry) error {
+	for _, e := range entries {
+		switch e.Action {
+		case RebaseDrop:
+			continue
+		case RebaseSquash, RebaseFixup:
+			return ErrNothingToMeldInto
+		}
+		return nil
+	}
+	return nil
+}
+
+// buildRebaseTodo writes the entries as a `git rebase -i` todo list. Rewords
+// become a pick followed by an exec that amends the message from
+// messageFiles[i], after any squashes and fixups into the commit, so git
+// never has to open an editor.
+func buildRebaseTodo(entries []RebaseEntry, messageFiles map[int]string) string {
+	var b strings.Builder
+	pending := "" // Amend waiting for the end of the current commit's group
+	for i, e := range entries {
+		if pending != "" && e.Action != RebaseSquash && e.Action != RebaseFixup {
+			b.WriteString(pending)
+			pending = ""
+		}
+		action := e.Action
+		if action == RebaseReword {
+			action = RebasePick
+			if file, ok := messageFiles[i]; ok {
+				pending = fmt.Sprintf("exec git commit --amend --quiet --allow-empty --file '%s'\n", file)
+			}
+		}
+		fmt.Fprintf(&b, "%s %s %s\n", action, e.Hash, e.Subject)
+	}
+	b.WriteString(pending)
+	return b.String()
+}
+
+// runRebase runs `git rebase -i` non-interactively with the given todo list
+// (or git's own with todo empty). A rebase that stops on a conflict is
+// aborted, leaving the branch as it was.
+func runRebase(repoRoot, todo string, args ...string) error {
+	dir, err := os.MkdirTemp("", "thicc-rebase-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	// Git runs the sequence editor with the todo file as its argument
+	sequenceEditor := "true"
+	if todo != "" {
+		todoFile := filepath.Join(dir, "todo")
+		if err := os.WriteFile(todoFile, []byte(todo), 0644); err != nil {
+			return err
+		}
+		sequenceEditor = fmt.Sprintf("cp '%s'", todoFile)
+	}
+	env := []string{"GIT_SEQUENCE_EDITOR=" + sequenceEditor, "GIT_EDITOR=true"}
+
+	args = append([]string{"rebase", "-i", "--autostash"}, args...)
+	if _, err := gitOutput(repoRoot, env, args...); err != nil {
+		if _, statErr := os.Stat(filepath.Join(gitDir(repoRoot), "rebase-merge")); statErr == nil {
+			gitOutput(repoRoot, nil, "rebase", "--abort")
+			return fmt.Errorf("%v (rebase aborted)", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// gitDir returns the repository's git directory
+func gitDir(repoRoot string) string {
+	dir, err := gitOutput(repoRoot, nil, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return filepath.Join(repoRoot, ".git")
+	}
+	return dir
+}
+
+// RunRebase replays the entries onto base in order, with their actions
+func RunRebase(repoRoot, base string, entries []RebaseEntry) error {
+	if err := checkRebaseEntries(entries); err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "thicc-reword-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	messageFiles := make(map[int]string)
+	for i, e := range entries {
+		if e.Action != RebaseReword || strings.TrimSpace(e.Message) == "" {
+			continue
+		}
+		file := filepath.Join(dir, fmt.Sprintf("message-%d", i))
+		if err := os.WriteFile(file, []byte(e.Message+"\n"), 0644); err != nil {
+			return err
+		}
+		messageFiles[i] = file
+	}
+
+	if err := runRebase(repoRoot, buildRebaseTodo(entries, messageFiles), base); err != nil {
+		return err
+	}
+	log.Printf("THICC SourceControl: Rebased %d commits onto %s", len(entries), base)
+	return nil
+}
+
+// FixupCommit commits the staged changes as a fixup of a commit on the
+// current branch and folds them into it with an autosquash rebase
+func FixupCommit(repoRoot, hash string) error {
+	if _, err := gitOutput(repoRoot, nil, "merge-base", "--is-ancestor", hash, "HEAD"); err != nil {
+		return ErrNotOnBranch
+	}
+	if _, err := gitOutput(repoRoot, nil, "commit", "--quiet", "--fixup="+hash); err != nil {
+		return err
+	}
+
+	// Replay from the commit's parent, or from the root for the first commit
+	upstream := []string{"--autosquash", hash + "^"}
+	if _, err := gitOutput(repoRoot, nil, "rev-parse", "--verify", "--quiet", hash+"^"); err != nil {
+		upstream = []string{"--autosquash", "--root"}
+	}
+	if err := runRebase(repoRoot, "", upstream...); err != nil {
+		// Keep the staged changes: undo the fixup commit
+		gitOutput(repoRoot, nil, "reset", "--soft", "HEAD^")
+		return err
+	}
+	log.Printf("THICC SourceControl: Fixed up %s", hash)
+	return nil
+}
+
+// ShowRebaseEditor opens the interactive rebase editor with the commits since
+// the base branch
+func (p *Panel) ShowRebaseEditor() {
+	base, name, err := FindRebaseBase(p.RepoRoot)
+	if err == nil {
+		p.RebaseEntries, err = ListRebaseCommits(p.RepoRoot, base)
+	}
+	if err != nil {
+		log.Printf("THICC SourceControl: Failed to list commits to rebase: %v", err)
+		if p.OnMessage != nil {
+			p.OnMessage(fmt.Sprintf("Can't rebase: %v", err), true)
+		}
+		return
+	}
+
+	p.RebaseBase = base
+	p.RebaseBaseName = name
+	p.RebaseSelected = 0
+	p.RebaseTopLine = 0
+	p.ShowRebase = true
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
+
+// HideRebaseEditor closes the rebase editor without rebasing
+func (p *Panel) HideRebaseEditor() {
+	p.ShowRebase = false
+	p.RebaseEntries = nil
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
+
+// RebaseMoveUp moves the rebase editor cursor up
+func (p *Panel) RebaseMoveUp() {
+	if p.RebaseSelected > 0 {
+		p.RebaseSelected--
+	}
+}
+
+// RebaseMoveDown moves the rebase editor cursor down
+func (p *Panel) RebaseMoveDown() {
+	if p.RebaseSelected < len(p.RebaseEntries)-1 {
+		p.RebaseSelected++
+	}
+}
+
+// MoveRebaseEntry moves the selected commit up (-1) or down (+1) in the
+// replay order, keeping it selected
+func (p *Panel) MoveRebaseEntry(delta int) {
+	i := p.RebaseSelected
+	j := i + delta
+	if i < 0 || j < 0 || i >= len(p.RebaseEntries) || j >= len(p.RebaseEntries) {
+		return
+	}
+	p.RebaseEntries[i], p.RebaseEntries[j] = p.RebaseEntries[j], p.RebaseEntries[i]
+	p.RebaseSelected = j
+}
+
+// SetRebaseAction sets what the rebase does with the selected commit. Reword
+// asks for the new message.
+func (p *Panel) SetRebaseAction(action RebaseAction) {
+	if p.RebaseSelected < 0 || p.RebaseSelected >= len(p.RebaseEntries) {
+		return
+	}
+	e := &p.RebaseEntries[p.RebaseSelected]
+	if action != RebaseReword {
+		e.Action = action
+		return
+	}
+	if p.OnInput == nil {
+		return
+	}
+	initial := e.Message
+	if initial == "" {
+		initial = e.Subject
+	}
+	idx := p.RebaseSelected
+	p.OnInput("Reword "+e.ShortHash, "Message:", initial, func(message string, canceled bool) {
+		message = strings.TrimSpace(message)
+		if canceled || message == "" || idx >= len(p.RebaseEntries) {
+			return
+		}
+		p.RebaseEntries[idx].Action = RebaseReword
+		p.RebaseEntries[idx].Message = message
+		if p.OnRefresh != nil {
+			p.OnRefresh()
+		}
+	})
+}
+
+// StartRebase runs the rebase as edited and closes the editor
+func (p *Panel) StartRebase() {
+	if err := checkRebaseEntries(p.RebaseEntries); err != nil {
+		if p.OnMessage != nil {
+			p.OnMessage(err.Error(), true)
+		}
+		return
+	}
+	entries, base := p.RebaseEntries, p.RebaseBase
+	started := p.runCommitOperation("Rebasing", fmt.Sprintf("Rebased %d commits onto %s", len(entries), p.RebaseBaseName), func() error {
+		return RunRebase(p.RepoRoot, base, entries)
+	})
+	if started {
+		p.ShowRebase = false
+		p.RebaseEntries = nil
+	}
+}
@@ -0,0 +1,207 @@
+package sourcecontrol
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// subjects returns the subjects of the commits after base, oldest first
+func subjects(t *testing.T, dir, base string) []string {
+	output, err := gitOutput(dir, nil, "log", "--reverse", "--format=%s", base+"..HEAD")
+	require.NoError(t, err)
+	if output == "" {
+		return nil
+	}
+	return strings.Split(output, "\n")
+}
+
+// =============================================================================
+// Rebase Todo Tests
+// =============================================================================
+
+func TestBuildRebaseTodo_RewordAfterFixups(t *testing.T) {
+	entries := []RebaseEntry{
+		{Action: RebaseReword, Hash: "a1", Subject: "one"},
+		{Action: RebaseFixup, Hash: "b2", Subject: "wip"},
+		{Action: RebaseDrop, Hash: "c3", Subject: "debug"},
+	}
+	todo := buildRebaseTodo(entries, map[int]string{0: "/tmp/msg"})
+	assert.Equal(t, "pick a1 one\n"+
+		"fixup b2 wip\n"+
+		"exec git commit --amend --quiet --allow-empty --file '/tmp/msg'\n"+
+		"drop c3 debug\n", todo)
+}
+
+func TestCheckRebaseEntries(t *testing.T) {
+	assert.Equal(t, ErrNothingToMeldInto, checkRebaseEntries([]RebaseEntry{
+		{Action: RebaseDrop}, {Action: RebaseSquash}, {Action: RebasePick},
+	}))
+	assert.NoError(t, checkRebaseEntries([]RebaseEntry{
+		{Action: RebasePick}, {Action: RebaseSquash},
+	}))
+}
+
+// =============================================================================
+// Rebase Git Tests
+// =============================================================================
+
+func TestFindRebaseBase_UsesBaseBranch(t *testing.T) {
+	dir := initTestRepo(t)
+	forkPoint, err := gitOutput(dir, nil, "rev-parse", "HEAD")
+	require.NoError(t, err)
+	_, err = gitOutput(dir, nil, "remote", "add", "origin", "https://example.com/repo.git")
+	require.NoError(t, err)
+	_, err = gitOutput(dir, nil, "update-ref", "refs/remotes/origin/main", forkPoint)
+	require.NoError(t, err)
+	require.NoError(t, CreateBranch(dir, "feature", ""))
+	commitFile(t, dir, "feature.go", "package main\n", "Add feature")
+
+	base, name, err := FindRebaseBase(dir)
+	require.NoError(t, err)
+	assert.Equal(t, forkPoint, base)
+	assert.Equal(t, "origin/main", name)
+}
+
+func TestListRebaseCommits_FollowsGitOrder(t *testing.T) {
+	dir := initTestRepo(t)
+	base, err := gitOutput(dir, nil, "rev-parse", "HEAD")
+	require.NoError(t, err)
+	main := currentBranch(t, dir)
+
+	// A side branch merged back, with dates that put it on both sides of
+	// the branch's own commit
+	commitAt := func(name, date string) {
+		writeFile(t, dir, name, name+"\n")
+		_, err := gitOutput(dir, nil, "add", name)
+		require.NoError(t, err)
+		env := []string{"GIT_AUTHOR_DATE=" + date, "GIT_COMMITTER_DATE=" + date}
+		_, err = gitOutput(dir, env, "commit", "-q", "-m", name)
+		require.NoError(t, err)
+	}
+	require.NoError(t, CreateBranch(dir, "side", ""))
+	commitAt("s1", "2020-01-02T00:00:00")
+	commitAt("s2", "2020-01-05T00:00:00")
+	_, err = gitOutput(dir, nil, "checkout", "-q", main)
+	require.NoError(t, err)
+	commitAt("m1", "2020-01-03T00:00:00")
+	_, err = gitOutput(dir, nil, "merge", "-q", "--no-edit", "side")
+	require.NoError(t, err)
+
+	entries, err := ListRebaseCommits(dir, base)
+	require.NoError(t, err)
+	var listed []string
+	for _, e := range entries {
+		listed = append(listed, e.Subject)
+	}
+
+	assert.Equal(t, []string{"m1", "s1", "s2"}, listed, "the order git rebase -i writes, not by date")
+}
+
+func TestRunRebase_ReordersSquashesDropsAndRewords(t *testing.T) {
+	dir := initTestRepo(t)
+	base, err := gitOutput(dir, nil, "rev-parse", "HEAD")
+	require.NoError(t, err)
+	commitFile(t, dir, "a.go", "package a\n", "Add a")
+	commitFile(t, dir, "b.go", "package b\n", "Add b")
+	commitFile(t, dir, "a.go", "package a // fixed\n", "WIP")
+	commitFile(t, dir, "debug.go", "package debug\n", "Debug")
+
+	entries, err := ListRebaseCommits(dir, base)
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	assert.Equal(t, "Add a", entries[0].Subject, "oldest first")
+
+	// b first, then a with the WIP folded in and a new message; no debug
+	entries[0], entries[1] = entries[1], entries[0]
+	entries[1].Action = RebaseReword
+	entries[1].Message = "Add package a"
+	entries[2].Action = RebaseFixup
+	entries[3].Action = RebaseDrop
+
+	require.NoError(t, RunRebase(dir, base, entries))
+	assert.Equal(t, []string{"Add b", "Add package a"}, subjects(t, dir, base))
+	assert.Equal(t, "package a // fixed\n", readFile(t, dir, "a.go"))
+	_, err = gitOutput(dir, nil, "cat-file", "-e", "HEAD:debug.go")
+	assert.Error(t, err, "the dropped commit is gone")
+}
+
+func TestRunRebase_ConflictAborts(t *testing.T) {
+	dir := initTestRepo(t)
+	base, err := gitOutput(dir, nil, "rev-parse", "HEAD")
+	require.NoError(t, err)
+	commitFile(t, dir, "main.go", "package main // one\n", "One")
+	commitFile(t, dir, "main.go", "package main // two\n", "Two")
+	head, err := gitOutput(dir, nil, "rev-parse", "HEAD")
+	require.NoError(t, err)
+
+	entries, err := ListRebaseCommits(dir, base)
+	require.NoError(t, err)
+	entries[0].Action = RebaseDrop
+
+	err = RunRebase(dir, base, entries)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "aborted")
+	after, err := gitOutput(dir, nil, "rev-parse", "HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, head, after, "the branch is left as it was")
+}
+
+func TestFixupCommit(t *testing.T) {
+	dir := initTestRepo(t)
+	base, err := gitOutput(dir, nil, "rev-parse", "HEAD")
+	require.NoError(t, err)
+	a := commitFile(t, dir, "a.go", "package a\n", "Add a")
+	commitFile(t, dir, "b.go", "package b\n", "Add b")
+
+	writeFile(t, dir, "a.go", "package a // fixed\n")
+	_, err = gitOutput(dir, nil, "add", "a.go")
+	require.NoError(t, err)
+	require.NoError(t, FixupCommit(dir, a.Hash))
+
+	assert.Equal(t, []string{"Add a", "Add b"}, subjects(t, dir, base))
+	content, err := gitOutput(dir, nil, "show", "HEAD~1:a.go")
+	require.NoError(t, err)
+	assert.Equal(t, "package a // fixed", content)
+	status, err := gitOutput(dir, nil, "status", "--porcelain")
+	require.NoError(t, err)
+	assert.Empty(t, status)
+}
+
+func TestFixupCommit_NotOnBranch(t *testing.T) {
+	dir := initTestRepo(t)
+	base := currentBranch(t, dir)
+	require.NoError(t, CreateBranch(dir, "feature", ""))
+	c := commitFile(t, dir, "feature.go", "package main\n", "Add feature")
+	_, err := gitOutput(dir, nil, "checkout", "-q", base)
+	require.NoError(t, err)
+
+	assert.Equal(t, ErrNotOnBranch, FixupCommit(dir, c.Hash))
+}
+
+// =============================================================================
+// Amend Tests
+// =============================================================================
+
+func TestToggleAmend_StartsFromLastMessage(t *testing.T) {
+	dir := initTestRepo(t)
+	commitFile(t, dir, "main.go", "package main // changed\n", "Change main")
+
+	p := &Panel{RepoRoot: dir}
+	assert.False(t, p.CanCommit())
+	p.ToggleAmend()
+	assert.True(t, p.CanCommit(), "amending needs nothing staged")
+	assert.Equal(t, "Change main", p.CommitMsg)
+
+	require.NoError(t, p.Commit("Change main package", true))
+	assert.Equal(t, []string{"Change main package"}, subjects(t, dir, "HEAD~1"))
+}
+
+func TestStartRebase_WaitsForAutoFetch(t *testing.T) {
+	p := &Panel{fetching: true, ShowRebase: true, RebaseEntries: []RebaseEntry{{Action: RebasePick}}}
+	p.StartRebase()
+	assert.Empty(t, p.OperationInProgress, "does not start while a background fetch holds the refs")
+	assert.True(t, p.ShowRebase, "the edited list stays open")
+}
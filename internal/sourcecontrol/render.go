@@ -38,12 +38,22 @@ var (
 	colorButtonText = tcell.ColorBlack
 
 	// Commit graph colors
-	colorGraphMain   = tcell.Color45  // Cyan - commits on main line
-	colorGraphMerge  = tcell.Color205 // Pink/Magenta - merge commits
-	colorGraphBranch = tcell.Color214 // Yellow/Orange - commits from merged branches
+	colorGraphMain   = tcell.Color45  // Cyan - first lane (usually the main line)
+	colorGraphMerge  = tcell.Color205 // Pink/Magenta - third lane
+	colorGraphBranch = tcell.Color214 // Yellow/Orange - second lane, remote branches
 	colorGraphLine   = tcell.Color243 // Gray - vertical lines
 )
 
+// graphLaneColors color the commit graph's lanes, by lane index
+var graphLaneColors = []tcell.Color{
+	colorGraphMain,
+	colorGraphBranch,
+	colorGraphMerge,
+	colorAdded,
+	colorHeader,
+	tcell.Color141, // Purple
+}
+
 // Spinner animation frames (braille dots)
 var spinnerFrames = []rune{'⠋', '⠙', '⠹', '⠸', '⠼', '⠴', '⠦', '⠧', '⠇', '⠏'}
 
@@ -66,15 +76,41 @@ func (p *Panel) Render(screen tcell.Screen) {
 		graphHeight = 5
 	}
 
-	// Draw content (in top 60%)
-	y := p.drawHeader(screen)
-	y = p.drawUnstagedSection(screen, y)
-	y = p.drawStagedSection(screen, y)
-	y = p.drawCommitSection(screen, y)
-	_ = y // silence unused warning
+	if p.ShowHunkBrowser {
+		// Hunk browser takes over everything below the header
+		y := p.drawHeader(screen)
+		p.drawHunkBrowser(screen, y)
+	} else if p.ShowCheckpoints {
+		// Checkpoint timeline also takes over everything below the header
+		y := p.drawHeader(screen)
+		p.drawCheckpointTimeline(screen, y)
+	} else if p.ShowWorktrees {
+		// So does the worktree list
+		y := p.drawHeader(screen)
+		p.drawWorktreeList(screen, y)
+	} else if p.ShowStashes {
+		// And the stash list
+		y := p.drawHeader(screen)
+		p.drawStashList(screen, y)
+	} else if p.ShowHistory {
+		// And the file history
+		y := p.drawHeader(screen)
+		p.drawFileHistory(screen, y)
+	} else if p.ShowRebase {
+		// And the interactive rebase editor
+		y := p.drawHeader(screen)
+		p.drawRebaseEditor(screen, y)
+	} else {
+		// Draw content (in top 60%)
+		y := p.drawHeader(screen)
+		y = p.drawUnstagedSection(screen, y)
+		y = p.drawStagedSection(screen, y)
+		y = p.drawCommitSection(screen, y)
+		_ = y // silence unused warning
 
-	// Draw commit graph (bottom 40%)
-	p.drawCommitGraph(screen, graphHeight)
+		// Draw commit graph (bottom 40%)
+		p.drawCommitGraph(screen, graphHeight)
+	}
 
 	// Draw border
 	p.drawBorder(screen)
@@ -89,10 +125,40 @@ func (p *Panel) Render(screen tcell.Screen) {
 		p.drawBranchDialog(screen)
 	}
 
+	// Draw unmerged branch force delete confirmation if visible
+	if p.ShowForceDeleteConfirm {
+		p.drawForceDeleteConfirmDialog(screen)
+	}
+
+	// Draw commit menu overlay if visible
+	if p.ShowCommitMenu {
+		p.drawCommitMenu(screen)
+	}
+
+	// Draw reset confirmation if visible
+	if p.ShowResetConfirm {
+		p.drawResetConfirmDialog(screen)
+	}
+
 	// Draw discard confirmation dialog if visible
 	if p.ShowDiscardConfirm {
 		p.drawDiscardConfirmDialog(screen)
 	}
+
+	// Draw checkpoint restore confirmation if visible
+	if p.ShowRestoreConfirm {
+		p.drawRestoreConfirmDialog(screen)
+	}
+
+	// Draw worktree merge/discard confirmation if visible
+	if p.WorktreeConfirm != "" {
+		p.drawWorktreeConfirmDialog(screen)
+	}
+
+	// Draw stash drop confirmation if visible
+	if p.ShowDropStashConfirm {
+		p.drawDropStashConfirmDialog(screen)
+	}
 }
 
 // clearRegion clears the panel's screen region
@@ -411,8 +477,8 @@ func (p *Panel) getStatusTag(status string) (string, tcell.Style) {
 
 // drawCommitSection draws the commit message input and buttons
 func (p *Panel) drawCommitSection(screen tcell.Screen, startY int) int {
-	// Check if commit section should be shown (has staged files)
-	commitEnabled := len(p.StagedFiles) > 0
+	// Check if commit section should be shown (has staged files or amending)
+	commitEnabled := p.CanCommit()
 
 	// Calculate graph height to position commit section above it
 	graphHeight := p.Region.Height * 30 / 100
@@ -439,11 +505,26 @@ func (p *Panel) drawCommitSection(screen tcell.Screen, startY int) int {
 	// Section header with (c) hint
 	headerStyle := config.DefStyle.Foreground(colorHeader).Bold(true)
 	header := fmt.Sprintf(" %s Commit:", IconCommit)
+	if p.Amend {
+		header = fmt.Sprintf(" %s Amend last commit:", IconCommit)
+	}
 	if p.Section == SectionCommitInput || p.Section == SectionCommitBtn || p.Section == SectionPushBtn || p.Section == SectionPullBtn {
 		header = "▸" + header[1:] // Active section indicator
 		headerStyle = headerStyle.Foreground(colorBorder) // Hot pink when active
 	}
 	p.drawText(screen, 1, y, header, headerStyle)
+
+	// Generate message button, right-aligned if there is room
+	p.generateBtnX = 0
+	generateBtn := "[⌥G]Generate"
+	if x := p.Region.Width - 2 - runewidth.StringWidth(generateBtn); x > runewidth.StringWidth(header)+2 {
+		generateStyle := config.DefStyle.Foreground(colorGraphBranch)
+		if p.OperationInProgress == "Generating message" {
+			generateStyle = config.DefStyle.Foreground(tcell.ColorGray)
+		}
+		p.generateBtnX = x
+		p.drawText(screen, x, y, generateBtn, generateStyle)
+	}
 	y++
 
 	// Commit message input box (3 rows)
@@ -520,15 +601,15 @@ func (p *Panel) drawCommitSection(screen tcell.Screen, startY int) int {
 	return y + 1
 }
 
-// drawButtons draws the commit, push, and pull buttons
+// drawButtons draws the commit, amend, push, and pull buttons
 func (p *Panel) drawButtons(screen tcell.Screen, y int) {
 	// Track buttons Y for click detection
 	p.buttonsY = y
 
 	x := 2
 
-	// Commit button - enabled when staged files and commit message
-	commitEnabled := len(p.StagedFiles) > 0 && p.CommitMsg != ""
+	// Commit button - enabled when staged files (or amending) and commit message
+	commitEnabled := p.CanCommit() && p.CommitMsg != ""
 	commitStyle := config.DefStyle.Foreground(tcell.ColorGray)
 	if p.Section == SectionCommitBtn && p.Focus {
 		// Focused button - hot pink background
@@ -537,11 +618,20 @@ func (p *Panel) drawButtons(screen tcell.Screen, y int) {
 		commitStyle = config.DefStyle.Foreground(colorAdded).Bold(true)
 	}
 	commitBtn := "[⌥C]Commit"
-	p.drawText(screen, x, y, commitBtn, commitStyle)
-	x += len(commitBtn) + 1
+	x += p.drawText(screen, x, y, commitBtn, commitStyle) + 1
+
+	// Amend toggle - checked when the commit button amends the last commit
+	p.amendBtnX = x
+	amendStyle := config.DefStyle.Foreground(tcell.ColorGray)
+	amendBtn := "[⌥M]☐Amend"
+	if p.Amend {
+		amendStyle = config.DefStyle.Foreground(colorModified).Bold(true)
+		amendBtn = "[⌥M]☑Amend"
+	}
+	x += p.drawText(screen, x, y, amendBtn, amendStyle) + 1
 
-	// Push button - enabled when ahead of remote
-	pushEnabled := p.AheadCount > 0
+	// Push button - enabled when ahead of remote or the branch can be published
+	pushEnabled := p.CanPush()
 	pushStyle := config.DefStyle.Foreground(tcell.ColorGray)
 	if p.Section == SectionPushBtn && p.Focus {
 		// Focused button - hot pink background
@@ -550,11 +640,13 @@ func (p *Panel) drawButtons(screen tcell.Screen, y int) {
 		pushStyle = config.DefStyle.Foreground(colorButton).Bold(true)
 	}
 	pushBtn := "[⌥P]Push"
-	if pushEnabled {
+	if p.AheadCount > 0 {
 		pushBtn = fmt.Sprintf("[⌥P]Push(%d)", p.AheadCount)
+	} else if p.Publishable {
+		pushBtn = "[⌥P]Publish"
 	}
-	p.drawText(screen, x, y, pushBtn, pushStyle)
-	x += len(pushBtn) + 1
+	p.pushBtnX = x
+	x += p.drawText(screen, x, y, pushBtn, pushStyle) + 1
 
 	// Pull button - always enabled (can pull anytime)
 	pullStyle := config.DefStyle.Foreground(colorModified).Bold(true) // Yellow for pull
@@ -566,7 +658,16 @@ func (p *Panel) drawButtons(screen tcell.Screen, y int) {
 	if p.BehindCount > 0 {
 		pullBtn = fmt.Sprintf("[⌥L]Pull(%d)", p.BehindCount)
 	}
+	p.pullBtnX = x
 	p.drawText(screen, x, y, pullBtn, pullStyle)
+
+	// Options of the focused push or pull button
+	hintStyle := config.DefStyle.Foreground(tcell.ColorGray)
+	if p.Section == SectionPushBtn && p.Focus {
+		p.drawText(screen, 2, y+1, "[f]force-with-lease [u]set upstream", hintStyle)
+	} else if p.Section == SectionPullBtn && p.Focus {
+		p.drawText(screen, 2, y+1, "[r]rebase [m]merge [f]fetch", hintStyle)
+	}
 }
 
 // drawBorder draws the panel border
@@ -631,18 +732,18 @@ func (p *Panel) drawTextAt(screen tcell.Screen, x, y int, text string, style tce
 
 // drawBranchDialog draws a modal dialog for branch switching
 func (p *Panel) drawBranchDialog(screen tcell.Screen) {
-	if !p.ShowBranchDialog || len(p.LocalBranches) == 0 {
+	if !p.ShowBranchDialog || len(p.Branches) == 0 {
 		return
 	}
 
 	// Dialog dimensions
-	dialogWidth := 30
+	dialogWidth := 44
 	if p.Region.Width-4 < dialogWidth {
 		dialogWidth = p.Region.Width - 4
 	}
 	maxVisible := 8
-	if len(p.LocalBranches) < maxVisible {
-		maxVisible = len(p.LocalBranches)
+	if len(p.Branches) < maxVisible {
+		maxVisible = len(p.Branches)
 	}
 	dialogHeight := maxVisible + 4 // Title + border + footer
 
@@ -689,16 +790,14 @@ func (p *Panel) drawBranchDialog(screen tcell.Screen) {
 	screen.SetContent(dialogX+dialogWidth-1, dialogY+1, '╣', nil, borderStyle)
 
 	// Branch list
-	currentBranch := p.GetBranchName()
 	listY := dialogY + 2
 	visibleCount := 0
-	for i := p.BranchTopLine; i < len(p.LocalBranches) && visibleCount < maxVisible; i++ {
-		branch := p.LocalBranches[i]
+	for i := p.BranchTopLine; i < len(p.Branches) && visibleCount < maxVisible; i++ {
+		branch := p.Branches[i]
 		y := listY + visibleCount
 
 		// Selection highlight
 		isSelected := i == p.BranchSelected
-		isCurrent := branch == currentBranch
 
 		var style tcell.Style
 		if isSelected {
@@ -707,13 +806,15 @@ func (p *Panel) drawBranchDialog(screen tcell.Screen) {
 			for x := 1; x < dialogWidth-1; x++ {
 				screen.SetContent(dialogX+x, y, ' ', nil, style)
 			}
+		} else if branch.Remote {
+			style = config.DefStyle.Foreground(colorGraphBranch) // Remote branches stand apart
 		} else {
 			style = config.DefStyle.Foreground(tcell.Color252)
 		}
 
 		// Branch indicator
 		indicator := "  "
-		if isCurrent {
+		if branch.Current {
 			indicator = "* "
 			if !isSelected {
 				style = config.DefStyle.Foreground(colorAdded) // Green for current
@@ -722,16 +823,43 @@ func (p *Panel) drawBranchDialog(screen tcell.Screen) {
 			indicator = "> "
 		}
 
+		// Ahead/behind counts, right-aligned
+		track := ""
+		if branch.Ahead > 0 {
+			track += fmt.Sprintf("↑%d", branch.Ahead)
+		}
+		if branch.Behind > 0 {
+			if track != "" {
+				track += " "
+			}
+			track += fmt.Sprintf("↓%d", branch.Behind)
+		}
+		trackWidth := runewidth.StringWidth(track)
+
 		// Draw branch name
-		branchText := indicator + branch
-		if len(branchText) > dialogWidth-3 {
-			branchText = branchText[:dialogWidth-6] + "..."
+		nameWidth := dialogWidth - 3
+		if trackWidth > 0 {
+			nameWidth -= trackWidth + 1
+		}
+		branchText := indicator + branch.Name
+		if len(branchText) > nameWidth && nameWidth > 3 {
+			branchText = branchText[:nameWidth-3] + "..."
 		}
 		for i, r := range branchText {
 			if dialogX+1+i < dialogX+dialogWidth-1 {
 				screen.SetContent(dialogX+1+i, y, r, nil, style)
 			}
 		}
+		if trackWidth > 0 {
+			trackStyle := style
+			if !isSelected {
+				trackStyle = config.DefStyle.Foreground(colorModified)
+			}
+			trackX := dialogX + dialogWidth - 2 - trackWidth
+			for i, r := range []rune(track) {
+				screen.SetContent(trackX+i, y, r, nil, trackStyle)
+			}
+		}
 		visibleCount++
 	}
 
@@ -744,7 +872,7 @@ func (p *Panel) drawBranchDialog(screen tcell.Screen) {
 	screen.SetContent(dialogX+dialogWidth-1, footerY, '╣', nil, borderStyle)
 
 	// Footer with hints
-	footer := " ↑↓:select Enter Esc "
+	footer := " Enter n:new r:rename x:delete Esc "
 	footerStyle := config.DefStyle.Foreground(tcell.ColorGray)
 	footerX := dialogX + (dialogWidth-len(footer))/2
 	for i, r := range footer {
@@ -828,6 +956,8 @@ func (p *Panel) drawDiscardConfirmDialog(screen tcell.Screen) {
 	var warning string
 	if p.DiscardIsUntracked {
 		warning = "This will DELETE the file permanently!"
+	} else if p.DiscardPatch != "" {
+		warning = "This will revert the selected hunk."
 	} else {
 		warning = "This will revert to the last commit."
 	}
@@ -925,8 +1055,15 @@ func (p *Panel) drawSpinner(screen tcell.Screen) {
 	// Build message
 	msg := fmt.Sprintf(" %c %s... ", spinner, p.OperationInProgress)
 
-	// Center in panel
+	// Center in panel, wide enough for git's progress line
 	msgLen := len(msg) + 1 // +1 for spinner rune width
+	progress := []rune(p.OperationProgress)
+	if len(progress) > msgLen-2 {
+		msgLen = len(progress) + 2
+	}
+	if msgLen > p.Region.Width-4 {
+		msgLen = p.Region.Width - 4
+	}
 	x := p.Region.X + (p.Region.Width-msgLen)/2
 	y := p.Region.Y + p.Region.Height/2
 
@@ -946,6 +1083,15 @@ func (p *Panel) drawSpinner(screen tcell.Screen) {
 	for i, r := range p.OperationInProgress + "..." {
 		screen.SetContent(x+2+i, y, r, nil, textStyle)
 	}
+
+	// Latest progress line, e.g. "Writing objects:  42% (21/50)"
+	progressStyle := config.DefStyle.Foreground(tcell.ColorGray).Background(tcell.Color236)
+	for i, r := range progress {
+		if i >= msgLen {
+			break
+		}
+		screen.SetContent(x+i, y+1, r, nil, progressStyle)
+	}
 }
 
 // drawCommitGraph draws the commit history graph at the bottom of the panel
@@ -964,11 +1110,14 @@ func (p *Panel) drawCommitGraph(screen tcell.Screen, graphHeight int) {
 	dividerStyle := config.DefStyle.Foreground(tcell.ColorGray)
 	labelStyle := config.DefStyle.Foreground(colorHeader).Bold(true)
 
-	// Draw "Log" label with commit icon
-	p.drawText(screen, 1, y, fmt.Sprintf(" %s Log ", IconCommit), labelStyle)
+	// Draw "Log" label with commit icon, and the filter if there is one
+	label := fmt.Sprintf(" %s Log ", IconCommit)
+	if !p.GraphFilter.IsEmpty() {
+		label = fmt.Sprintf(" %s Log: %s ", IconCommit, p.GraphFilter.String())
+	}
+	labelWidth := p.drawText(screen, 1, y, label, labelStyle)
 
 	// Draw divider line after label
-	labelWidth := 7 // len(" X Log ") where X is icon
 	dividerWidth := p.Region.Width - labelWidth - 3
 	if dividerWidth > 0 {
 		divider := strings.Repeat("─", dividerWidth)
@@ -976,10 +1125,22 @@ func (p *Panel) drawCommitGraph(screen tcell.Screen, graphHeight int) {
 	}
 	y++
 
+	// Why the last commit operation failed
+	errorLines := 0
+	if p.GraphError != "" {
+		p.drawText(screen, 2, y, p.GraphError, config.DefStyle.Foreground(colorDeleted))
+		y++
+		errorLines = 1
+	}
+
 	// Empty state
 	if len(p.CommitGraph) == 0 {
 		emptyStyle := config.DefStyle.Foreground(tcell.ColorGray)
-		p.drawText(screen, 3, y, "No commits yet", emptyStyle)
+		if p.GraphFilter.IsEmpty() {
+			p.drawText(screen, 3, y, "No commits yet", emptyStyle)
+		} else {
+			p.drawText(screen, 3, y, "No matching commits (f to change filter)", emptyStyle)
+		}
 		return
 	}
 
@@ -1006,25 +1167,13 @@ func (p *Panel) drawCommitGraph(screen tcell.Screen, graphHeight int) {
 	// Calculate how many screen lines each row takes
 	getRowHeight := func(row graphRow) int {
 		if row.isCommit && row.commitEntry.Expanded {
-			// Calculate wrapped lines for expanded commit
-			prefixWidth := 5
-			textWidth := p.Region.Width - prefixWidth - 2
-			if textWidth < 10 {
-				textWidth = 10
-			}
-			subject := row.commitEntry.Subject
-			lines := 1
-			for len(subject) > textWidth && lines < 3 {
-				lines++
-				subject = subject[textWidth:]
-			}
-			return lines
+			return len(p.commitSubjectLines(row.commitEntry))
 		}
 		return 1
 	}
 
 	// Calculate visible area
-	visibleScreenLines := graphHeight - 2 // Minus divider and bottom border
+	visibleScreenLines := graphHeight - 2 - errorLines // Minus divider, error and bottom border
 
 	// Clamp selection
 	if p.GraphSelected >= len(rows) {
@@ -1069,65 +1218,133 @@ func (p *Panel) drawCommitGraph(screen tcell.Screen, graphHeight int) {
 			y += linesUsed
 		} else {
 			p.graphYToRow[y] = i
-			p.drawGraphFileRow(screen, y, row.file, isSelected)
+			p.drawGraphFileRow(screen, y, row.commitEntry, row.file, isSelected)
 			y++
 		}
 	}
 }
 
-// drawGraphCommitRow draws a commit row in the graph
-// Returns the number of lines used (1 when collapsed, up to 3 when expanded)
-func (p *Panel) drawGraphCommitRow(screen tcell.Screen, y int, commit *CommitEntry, isSelected bool) int {
-	// Determine how many lines we'll use
+// maxGraphCells caps how wide the lane drawing gets, leaving room for text
+func (p *Panel) maxGraphCells() int {
+	return (p.Region.Width - 2) / 3
+}
+
+// graphCellsWidth returns the width the lane drawing takes, with a space after
+func (p *Panel) graphCellsWidth(cells []GraphCell) int {
+	n := len(cells)
+	if n > p.maxGraphCells() {
+		n = p.maxGraphCells()
+	}
+	if n == 0 {
+		return 0
+	}
+	return n + 1
+}
+
+// drawGraphCells draws a lane drawing at x, colored by lane
+func (p *Panel) drawGraphCells(screen tcell.Screen, x, y int, cells []GraphCell, selStyle *tcell.Style) {
+	for i, cell := range cells {
+		if i >= p.maxGraphCells() {
+			break
+		}
+		style := config.DefStyle.Foreground(graphLaneColors[cell.Lane%len(graphLaneColors)])
+		if selStyle != nil {
+			style = *selStyle
+		}
+		p.drawTextAt(screen, x+i, y, string(cell.Glyph), style)
+	}
+}
+
+// refLabel returns how a ref decoration is shown and its color
+func refLabel(ref string) (string, tcell.Color) {
+	switch {
+	case strings.HasPrefix(ref, "HEAD -> "):
+		return strings.TrimPrefix(ref, "HEAD -> "), colorAdded
+	case ref == "HEAD":
+		return ref, colorHeader
+	case strings.HasPrefix(ref, "tag: "):
+		return strings.TrimPrefix(ref, "tag: "), colorModified
+	case strings.Contains(ref, "/"):
+		return ref, colorGraphBranch // Most likely a remote branch
+	default:
+		return ref, colorGraphMain
+	}
+}
+
+// refsWidth returns the width the commit's ref decorations take
+func refsWidth(commit *CommitEntry) int {
+	width := 0
+	for _, ref := range commit.Refs {
+		label, _ := refLabel(ref)
+		width += runewidth.StringWidth(label) + 3 // "[label] "
+	}
+	return width
+}
+
+// commitSubjectLines returns the commit's subject, truncated to one line,
+// or wrapped to up to 3 lines when expanded
+func (p *Panel) commitSubjectLines(commit *CommitEntry) []string {
+	// Text area width (after the lane drawing); the ref decorations
+	// share the first line
+	textWidth := p.Region.Width - p.graphCellsWidth(commit.Graph) - 2
+	if textWidth < 10 {
+		textWidth = 10
+	}
+	firstWidth := textWidth - refsWidth(commit)
+	if firstWidth < 4 {
+		firstWidth = 4
+	}
 	maxLines := 1
 	if commit.Expanded {
 		maxLines = 3
 	}
+	return wrapSubject(commit.Subject, firstWidth, textWidth, maxLines)
+}
 
-	// Calculate text area width (after graph prefix)
-	prefixWidth := 3 // "● " = dot + space
-	textWidth := p.Region.Width - prefixWidth - 2
-
-	// Wrap the subject to multiple lines if expanded
-	var subjectLines []string
-	if commit.Expanded && len(commit.Subject) > textWidth {
-		// Wrap to multiple lines
-		remaining := commit.Subject
-		for len(remaining) > 0 && len(subjectLines) < maxLines {
-			if len(remaining) <= textWidth {
-				subjectLines = append(subjectLines, remaining)
-				break
-			}
-			// Find a good break point (prefer space)
-			breakAt := textWidth
-			for i := textWidth - 1; i > textWidth/2; i-- {
-				if remaining[i] == ' ' {
-					breakAt = i
-					break
-				}
-			}
-			subjectLines = append(subjectLines, remaining[:breakAt])
-			remaining = strings.TrimLeft(remaining[breakAt:], " ")
-		}
-		// If there's still more text, add ellipsis to last line
-		if len(remaining) > 0 && len(subjectLines) == maxLines {
-			lastIdx := len(subjectLines) - 1
-			if len(subjectLines[lastIdx]) > textWidth-3 {
-				subjectLines[lastIdx] = subjectLines[lastIdx][:textWidth-3] + "..."
+// wrapSubject wraps a subject to maxLines lines, the first firstWidth wide and
+// the rest width wide, breaking at spaces where it can. Text that doesn't fit
+// ends with "...".
+func wrapSubject(subject string, firstWidth, width, maxLines int) []string {
+	var lines []string
+	remaining := subject
+	for len(lines) < maxLines {
+		w := width
+		if len(lines) == 0 {
+			w = firstWidth
+		}
+		if len(remaining) <= w {
+			lines = append(lines, remaining)
+			return lines
+		}
+		if len(lines) == maxLines-1 {
+			// Last line: truncate
+			if w > 3 {
+				lines = append(lines, remaining[:w-3]+"...")
 			} else {
-				subjectLines[lastIdx] = subjectLines[lastIdx] + "..."
+				lines = append(lines, remaining[:w])
 			}
+			return lines
 		}
-	} else {
-		// Single line, truncate if needed
-		subject := commit.Subject
-		if len(subject) > textWidth && textWidth > 3 {
-			subject = subject[:textWidth-3] + "..."
+		// Find a good break point (prefer space)
+		breakAt := w
+		for i := w - 1; i > w/2; i-- {
+			if remaining[i] == ' ' {
+				breakAt = i
+				break
+			}
 		}
-		subjectLines = []string{subject}
+		lines = append(lines, remaining[:breakAt])
+		remaining = strings.TrimLeft(remaining[breakAt:], " ")
 	}
+	return lines
+}
 
+// drawGraphCommitRow draws a commit row in the graph
+// Returns the number of lines used (1 when collapsed, up to 3 when expanded)
+func (p *Panel) drawGraphCommitRow(screen tcell.Screen, y int, commit *CommitEntry, isSelected bool) int {
+	subjectLines := p.commitSubjectLines(commit)
 	linesUsed := len(subjectLines)
+	focused := isSelected && p.Focus && p.Section == SectionCommitGraph
 
 	// Draw each line
 	for lineNum, lineText := range subjectLines {
@@ -1146,37 +1363,36 @@ func (p *Panel) drawGraphCommitRow(screen tcell.Screen, y int, commit *CommitEnt
 				screen.SetContent(p.Region.X+x, p.Region.Y+currentY, ' ', nil, style)
 			}
 		}
+		var selStyle *tcell.Style
+		if focused {
+			selStyle = &style
+		}
 
 		x := 1
 
-		// Commit dot (only on first line)
+		// Lanes: the commit's dot on the first line, the lanes passing by
+		// on continuation lines
 		if lineNum == 0 {
-			// Commit dot with color based on type
-			var dotStyle tcell.Style
-			var dot string
-			if commit.IsMerge {
-				dotStyle = config.DefStyle.Foreground(colorGraphMerge)
-				dot = "◆"
-			} else if commit.FromBranch {
-				dotStyle = config.DefStyle.Foreground(colorGraphBranch)
-				dot = "●"
-			} else {
-				dotStyle = config.DefStyle.Foreground(colorGraphMain)
-				dot = "●"
-			}
-			if isSelected && p.Focus && p.Section == SectionCommitGraph {
-				dotStyle = style
+			p.drawGraphCells(screen, x, currentY, commit.Graph, selStyle)
+			x += p.graphCellsWidth(commit.Graph)
+
+			// Branch and tag decorations
+			for _, ref := range commit.Refs {
+				label, color := refLabel(ref)
+				refStyle := config.DefStyle.Foreground(color).Bold(true)
+				if focused {
+					refStyle = style.Bold(true)
+				}
+				x += p.drawTextAt(screen, x, currentY, "["+label+"]", refStyle) + 1
 			}
-			p.drawTextAt(screen, x, currentY, dot, dotStyle)
-			x += 2 // dot + space
 		} else {
-			// Continuation lines: just indent to align with first line's text
-			x = prefixWidth
+			p.drawGraphCells(screen, x, currentY, commit.GraphPass, selStyle)
+			x += p.graphCellsWidth(commit.Graph)
 		}
 
 		// Draw the text for this line
 		subjectStyle := config.DefStyle.Foreground(tcell.Color252) // Light gray
-		if isSelected && p.Focus && p.Section == SectionCommitGraph {
+		if focused {
 			subjectStyle = style
 		}
 		p.drawTextAt(screen, x, currentY, lineText, subjectStyle)
@@ -1186,7 +1402,7 @@ func (p *Panel) drawGraphCommitRow(screen tcell.Screen, y int, commit *CommitEnt
 }
 
 // drawGraphFileRow draws a single file row under an expanded commit
-func (p *Panel) drawGraphFileRow(screen tcell.Screen, y int, file *FileStatus, isSelected bool) {
+func (p *Panel) drawGraphFileRow(screen tcell.Screen, y int, commit *CommitEntry, file *FileStatus, isSelected bool) {
 	// Selection background
 	style := config.DefStyle
 	if isSelected {
@@ -1203,7 +1419,14 @@ func (p *Panel) drawGraphFileRow(screen tcell.Screen, y int, file *FileStatus, i
 
 	x := 1
 
-	// Tree branch character
+	// Lanes passing by, then the tree branch character
+	var selStyle *tcell.Style
+	if isSelected && p.Focus && p.Section == SectionCommitGraph {
+		selStyle = &style
+	}
+	p.drawGraphCells(screen, x, y, commit.GraphPass, selStyle)
+	x += p.graphCellsWidth(commit.GraphPass)
+
 	lineStyle := config.DefStyle.Foreground(colorGraphLine)
 	if isSelected && p.Focus && p.Section == SectionCommitGraph {
 		lineStyle = style
@@ -1250,3 +1473,782 @@ func (p *Panel) drawGraphFileRow(screen tcell.Screen, y int, file *FileStatus, i
 	}
 	p.drawTextAt(screen, x, y, filename, filenameStyle)
 }
+
+// drawHunkBrowser draws the hunk list for the file being partially staged
+func (p *Panel) drawHunkBrowser(screen tcell.Screen, startY int) {
+	y := startY
+	p.hunkYToRow = make(map[int]int)
+
+	if p.HunkDiff == nil {
+		return
+	}
+
+	// Title: file name and which diff is being browsed
+	titleStyle := config.DefStyle.Foreground(colorModified).Bold(true)
+	kind := "unstaged"
+	if p.HunkStaged {
+		titleStyle = config.DefStyle.Foreground(colorAdded).Bold(true)
+		kind = "staged"
+	}
+	title := fmt.Sprintf("▸ %s (%s, %d hunks)", filepath.Base(p.HunkPath), kind, len(p.HunkDiff.Hunks))
+	p.drawText(screen, 1, y, title, titleStyle)
+	y++
+
+	// Shortcut hints
+	hintStyle := config.DefStyle.Foreground(tcell.ColorGray)
+	hint := " [v]mark [J/K]range [space]stage"
+	if p.HunkStaged {
+		hint = " [v]mark [J/K]range [space]unstage"
+	}
+	p.drawText(screen, 1, y, hint, hintStyle)
+	y++
+	if !p.HunkStaged {
+		p.drawText(screen, 1, y, " [d]discard [n/N]hunk [esc]back", hintStyle)
+	} else {
+		p.drawText(screen, 1, y, " [n/N]hunk [esc]back", hintStyle)
+	}
+	y += 2
+
+	rows := p.hunkRows()
+	visible := p.Region.Height - 1 - y
+	if visible < 1 {
+		return
+	}
+
+	// Clamp selection and keep it in view
+	if p.HunkSelected >= len(rows) {
+		p.HunkSelected = len(rows) - 1
+	}
+	if p.HunkSelected < 0 {
+		p.HunkSelected = 0
+	}
+	if p.HunkSelected < p.HunkTopLine {
+		p.HunkTopLine = p.HunkSelected
+	}
+	if p.HunkSelected >= p.HunkTopLine+visible {
+		p.HunkTopLine = p.HunkSelected - visible + 1
+	}
+
+	for i := p.HunkTopLine; i < len(rows) && y < p.Region.Height-1; i++ {
+		p.hunkYToRow[y] = i
+		p.drawHunkRow(screen, y, rows[i], i == p.HunkSelected)
+		y++
+	}
+}
+
+// drawHunkRow draws a hunk header or a single diff line in the hunk browser
+func (p *Panel) drawHunkRow(screen tcell.Screen, y int, row hunkRow, isSelected bool) {
+	hunk := &p.HunkDiff.Hunks[row.hunk]
+
+	// Selection background
+	style := config.DefStyle
+	if isSelected {
+		if p.Focus {
+			style = config.DefStyle.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite)
+		} else {
+			style = config.DefStyle.Background(tcell.Color236) // Dark gray
+		}
+		for x := 1; x < p.Region.Width-1; x++ {
+			screen.SetContent(p.Region.X+x, p.Region.Y+y, ' ', nil, style)
+		}
+	}
+	highlighted := isSelected && p.Focus
+
+	if row.line < 0 {
+		// Hunk header with +/- counts
+		added, removed := hunk.Stats()
+		headerStyle := config.DefStyle.Foreground(colorHeader).Bold(true)
+		if highlighted {
+			headerStyle = style.Bold(true)
+		}
+		x := 1
+		x += p.drawTextAt(screen, x, y, fmt.Sprintf("@@ -%d +%d ", hunk.OldStart, hunk.NewStart), headerStyle)
+
+		addStyle := config.DefStyle.Foreground(colorAdded)
+		delStyle := config.DefStyle.Foreground(colorDeleted)
+		if highlighted {
+			addStyle, delStyle = style, style
+		}
+		x += p.drawTextAt(screen, x, y, fmt.Sprintf("+%d ", added), addStyle)
+		x += p.drawTextAt(screen, x, y, fmt.Sprintf("-%d ", removed), delStyle)
+		if hunk.Section != "" {
+			sectionStyle := config.DefStyle.Foreground(tcell.Color243)
+			if highlighted {
+				sectionStyle = style
+			}
+			p.drawTextAt(screen, x, y, hunk.Section, sectionStyle)
+		}
+		return
+	}
+
+	line := hunk.Lines[row.line]
+
+	// Mark column
+	markStyle := config.DefStyle.Foreground(colorBorder)
+	if highlighted {
+		markStyle = style
+	}
+	if line.Selected {
+		p.drawTextAt(screen, 1, y, "▌", markStyle)
+	}
+
+	lineStyle := config.DefStyle.Foreground(tcell.Color243)
+	switch line.Kind {
+	case '+':
+		lineStyle = config.DefStyle.Foreground(colorAdded)
+	case '-':
+		lineStyle = config.DefStyle.Foreground(colorDeleted)
+	}
+	if highlighted {
+		lineStyle = style
+	}
+	text := string(line.Kind) + strings.ReplaceAll(line.Text, "\t", "    ")
+	p.drawTextAt(screen, 2, y, text, lineStyle)
+}
+
+// drawCheckpointTimeline draws the list of checkpoints taken around AI turns
+func (p *Panel) drawCheckpointTimeline(screen tcell.Screen, startY int) {
+	y := startY
+	p.checkpointYToRow = make(map[int]int)
+
+	titleStyle := config.DefStyle.Foreground(colorHeader).Bold(true)
+	p.drawText(screen, 1, y, fmt.Sprintf("▸ Checkpoints (%d)", len(p.Checkpoints)), titleStyle)
+	y++
+
+	// Shortcut hints
+	hintStyle := config.DefStyle.Foreground(tcell.ColorGray)
+	p.drawText(screen, 1, y, " [enter]diff [p]prev [v]mark", hintStyle)
+	y++
+	p.drawText(screen, 1, y, " [r]restore [esc]back", hintStyle)
+	y += 2
+
+	if len(p.Checkpoints) == 0 {
+		emptyStyle := config.DefStyle.Foreground(colorUntracked)
+		p.drawText(screen, 2, y, "No checkpoints yet", emptyStyle)
+		p.drawText(screen, 2, y+1, "(taken around AI turns)", emptyStyle)
+		return
+	}
+
+	visible := p.Region.Height - 1 - y
+	if visible < 1 {
+		return
+	}
+
+	// Clamp selection and keep it in view
+	if p.CheckpointSelected >= len(p.Checkpoints) {
+		p.CheckpointSelected = len(p.Checkpoints) - 1
+	}
+	if p.CheckpointSelected < 0 {
+		p.CheckpointSelected = 0
+	}
+	if p.CheckpointSelected < p.CheckpointTopLine {
+		p.CheckpointTopLine = p.CheckpointSelected
+	}
+	if p.CheckpointSelected >= p.CheckpointTopLine+visible {
+		p.CheckpointTopLine = p.CheckpointSelected - visible + 1
+	}
+
+	for i := p.CheckpointTopLine; i < len(p.Checkpoints) && y < p.Region.Height-1; i++ {
+		p.checkpointYToRow[y] = i
+		p.drawCheckpointRow(screen, y, i)
+		y++
+	}
+}
+
+// drawCheckpointRow draws a single checkpoint: mark, time and label
+func (p *Panel) drawCheckpointRow(screen tcell.Screen, y int, idx int) {
+	cp := p.Checkpoints[idx]
+	isSelected := idx == p.CheckpointSelected
+
+	// Selection background
+	style := config.DefStyle
+	if isSelected {
+		if p.Focus {
+			style = config.DefStyle.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite)
+		} else {
+			style = config.DefStyle.Background(tcell.Color236) // Dark gray
+		}
+		for x := 1; x < p.Region.Width-1; x++ {
+			screen.SetContent(p.Region.X+x, p.Region.Y+y, ' ', nil, style)
+		}
+	}
+	highlighted := isSelected && p.Focus
+
+	// Mark column
+	if idx == p.CheckpointMark {
+		markStyle := config.DefStyle.Foreground(colorBorder)
+		if highlighted {
+			markStyle = style
+		}
+		p.drawTextAt(screen, 1, y, "▌", markStyle)
+	}
+
+	// Time (with the date when it isn't from today)
+	stamp := cp.Time.Format("15:04:05")
+	if now := time.Now(); cp.Time.YearDay() != now.YearDay() || cp.Time.Year() != now.Year() {
+		stamp = cp.Time.Format("Jan 02 15:04")
+	}
+	timeStyle := config.DefStyle.Foreground(colorUntracked)
+	if highlighted {
+		timeStyle = style
+	}
+	x := 2
+	x += p.drawTextAt(screen, x, y, stamp+" ", timeStyle)
+
+	labelStyle := config.DefStyle.Foreground(tcell.Color252)
+	if strings.HasPrefix(cp.Label, "After") {
+		labelStyle = config.DefStyle.Foreground(colorAdded)
+	}
+	if highlighted {
+		labelStyle = style
+	}
+	p.drawTextAt(screen, x, y, cp.Label, labelStyle)
+}
+
+// drawRestoreConfirmDialog draws a confirmation dialog for rolling the
+// working tree back to the selected checkpoint
+func (p *Panel) drawRestoreConfirmDialog(screen tcell.Screen) {
+	cp := p.selectedCheckpoint()
+	if cp == nil {
+		return
+	}
+	// Orange: undoable, but rewrites files
+	p.drawConfirmDialog(screen, " Restore Checkpoint? ", cp.Label+" ("+cp.ShortHash()+")", "Files will be rolled back.", colorModified)
+}
+
+// drawConfirmDialog draws a centered Enter/Esc confirmation dialog with a
+// title on the top border, a detail line and a warning line
+func (p *Panel) drawConfirmDialog(screen tcell.Screen, title, detail, warning string, color tcell.Color) {
+	// Dialog dimensions
+	dialogWidth := 40
+	if p.Region.Width-4 < dialogWidth {
+		dialogWidth = p.Region.Width - 4
+	}
+	dialogHeight := 9
+
+	// Center dialog in panel
+	dialogX := p.Region.X + (p.Region.Width-dialogWidth)/2
+	dialogY := p.Region.Y + (p.Region.Height-dialogHeight)/2
+
+	// Clear dialog area and draw the border
+	borderStyle := config.DefStyle.Foreground(color)
+	for dy := 0; dy < dialogHeight; dy++ {
+		for dx := 0; dx < dialogWidth; dx++ {
+			r := ' '
+			switch {
+			case dy == 0 || dy == dialogHeight-1:
+				r = '═'
+			case dy == dialogHeight-3:
+				r = '─'
+			case dx == 0 || dx == dialogWidth-1:
+				r = '║'
+			}
+			screen.SetContent(dialogX+dx, dialogY+dy, r, nil, borderStyle)
+		}
+	}
+	screen.SetContent(dialogX, dialogY, '╔', nil, borderStyle)
+	screen.SetContent(dialogX+dialogWidth-1, dialogY, '╗', nil, borderStyle)
+	screen.SetContent(dialogX, dialogY+dialogHeight-3, '╠', nil, borderStyle)
+	screen.SetContent(dialogX+dialogWidth-1, dialogY+dialogHeight-3, '╣', nil, borderStyle)
+	screen.SetContent(dialogX, dialogY+dialogHeight-1, '╚', nil, borderStyle)
+	screen.SetContent(dialogX+dialogWidth-1, dialogY+dialogHeight-1, '╝', nil, borderStyle)
+
+	// Centered lines, clipped to the dialog
+	drawCentered := func(y int, text string, style tcell.Style) {
+		runes := []rune(text)
+		if len(runes) > dialogWidth-4 {
+			runes = append(runes[:dialogWidth-7], []rune("...")...)
+		}
+		x := dialogX + (dialogWidth-len(runes))/2
+		for i, r := range runes {
+			screen.SetContent(x+i, y, r, nil, style)
+		}
+	}
+
+	drawCentered(dialogY, title, config.DefStyle.Foreground(color).Bold(true))
+	drawCentered(dialogY+2, detail, config.DefStyle.Foreground(tcell.Color252))
+	drawCentered(dialogY+4, warning, config.DefStyle.Foreground(color).Bold(true))
+	drawCentered(dialogY+dialogHeight-2, " Enter:confirm  Esc:cancel ", config.DefStyle.Foreground(tcell.ColorGray))
+}
+
+// drawWorktreeList draws the AI sessions running in their own worktree
+func (p *Panel) drawWorktreeList(screen tcell.Screen, startY int) {
+	y := startY
+	p.worktreeYToRow = make(map[int]int)
+
+	titleStyle := config.DefStyle.Foreground(colorHeader).Bold(true)
+	p.drawText(screen, 1, y, fmt.Sprintf("▸ Worktrees (%d)", len(p.Worktrees)), titleStyle)
+	y++
+
+	// Shortcut hints
+	hintStyle := config.DefStyle.Foreground(tcell.ColorGray)
+	p.drawText(screen, 1, y, " [enter]review [m]merge", hintStyle)
+	y++
+	p.drawText(screen, 1, y, " [x]discard [esc]back", hintStyle)
+	y += 2
+
+	if len(p.Worktrees) == 0 {
+		emptyStyle := config.DefStyle.Foreground(colorUntracked)
+		p.drawText(screen, 2, y, "No worktree sessions", emptyStyle)
+		p.drawText(screen, 2, y+1, "(start one from the tool selector)", emptyStyle)
+		return
+	}
+
+	// Clamp selection
+	if p.WorktreeSelected >= len(p.Worktrees) {
+		p.WorktreeSelected = len(p.Worktrees) - 1
+	}
+	if p.WorktreeSelected < 0 {
+		p.WorktreeSelected = 0
+	}
+
+	for i := range p.Worktrees {
+		if y >= p.Region.Height-1 {
+			break
+		}
+		p.worktreeYToRow[y] = i
+		p.drawWorktreeRow(screen, y, i)
+		y++
+	}
+}
+
+// drawWorktreeRow draws a single worktree: branch, commits ahead and dirty marker
+func (p *Panel) drawWorktreeRow(screen tcell.Screen, y int, idx int) {
+	wt := p.Worktrees[idx]
+	isSelected := idx == p.WorktreeSelected
+
+	// Selection background
+	style := config.DefStyle
+	if isSelected {
+		if p.Focus {
+			style = config.DefStyle.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite)
+		} else {
+			style = config.DefStyle.Background(tcell.Color236) // Dark gray
+		}
+		for x := 1; x < p.Region.Width-1; x++ {
+			screen.SetContent(p.Region.X+x, p.Region.Y+y, ' ', nil, style)
+		}
+	}
+	highlighted := isSelected && p.Focus
+
+	branchStyle := config.DefStyle.Foreground(tcell.Color252)
+	if highlighted {
+		branchStyle = style
+	}
+	x := 2
+	x += p.drawTextAt(screen, x, y, "⎇ "+wt.Branch, branchStyle)
+
+	if wt.Ahead > 0 {
+		aheadStyle := config.DefStyle.Foreground(colorAdded)
+		if highlighted {
+			aheadStyle = style
+		}
+		x += p.drawTextAt(screen, x, y, fmt.Sprintf(" +%d", wt.Ahead), aheadStyle)
+	}
+	if wt.Dirty {
+		dirtyStyle := config.DefStyle.Foreground(colorModified)
+		if highlighted {
+			dirtyStyle = style
+		}
+		p.drawTextAt(screen, x, y, " ●", dirtyStyle)
+	}
+}
+
+// drawWorktreeConfirmDialog draws the confirmation for merging or
+// discarding the selected worktree
+func (p *Panel) drawWorktreeConfirmDialog(screen tcell.Screen) {
+	wt := p.selectedWorktree()
+	if wt == nil {
+		return
+	}
+	if p.WorktreeConfirm == "merge" {
+		p.drawConfirmDialog(screen, " Merge Worktree? ", wt.Branch, "Merges into the current branch.", colorAdded)
+		return
+	}
+	p.drawConfirmDialog(screen, " Discard Worktree? ", wt.Branch, "All its work will be deleted.", colorDeleted)
+}
+
+// drawFileHistory draws the commits that changed one file
+func (p *Panel) drawFileHistory(screen tcell.Screen, startY int) {
+	y := startY
+	p.historyYToRow = make(map[int]int)
+
+	titleStyle := config.DefStyle.Foreground(colorHeader).Bold(true)
+	p.drawText(screen, 1, y, fmt.Sprintf("▸ History: %s (%d)", filepath.Base(p.HistoryPath), len(p.History)), titleStyle)
+	y++
+
+	// Shortcut hints
+	hintStyle := config.DefStyle.Foreground(tcell.ColorGray)
+	p.drawText(screen, 1, y, " [enter]diff [o]open", hintStyle)
+	y++
+	p.drawText(screen, 1, y, " [esc]back", hintStyle)
+	y += 2
+
+	if len(p.History) == 0 {
+		emptyStyle := config.DefStyle.Foreground(colorUntracked)
+		p.drawText(screen, 2, y, "No commits yet", emptyStyle)
+		p.drawText(screen, 2, y+1, "(the file isn't committed)", emptyStyle)
+		return
+	}
+
+	visible := p.Region.Height - 1 - y
+	if visible < 1 {
+		return
+	}
+
+	// Clamp selection and keep it in view
+	if p.HistorySelected >= len(p.History) {
+		p.HistorySelected = len(p.History) - 1
+	}
+	if p.HistorySelected < 0 {
+		p.HistorySelected = 0
+	}
+	if p.HistorySelected < p.HistoryTopLine {
+		p.HistoryTopLine = p.HistorySelected
+	}
+	if p.HistorySelected >= p.HistoryTopLine+visible {
+		p.HistoryTopLine = p.HistorySelected - visible + 1
+	}
+
+	for i := p.HistoryTopLine; i < len(p.History) && y < p.Region.Height-1; i++ {
+		p.historyYToRow[y] = i
+		p.drawHistoryRow(screen, y, i)
+		y++
+	}
+}
+
+// drawHistoryRow draws a single revision: hash, date and subject
+func (p *Panel) drawHistoryRow(screen tcell.Screen, y int, idx int) {
+	rev := p.History[idx]
+	isSelected := idx == p.HistorySelected
+
+	// Selection background
+	style := config.DefStyle
+	if isSelected {
+		if p.Focus {
+			style = config.DefStyle.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite)
+		} else {
+			style = config.DefStyle.Background(tcell.Color236) // Dark gray
+		}
+		for x := 1; x < p.Region.Width-1; x++ {
+			screen.SetContent(p.Region.X+x, p.Region.Y+y, ' ', nil, style)
+		}
+	}
+	highlighted := isSelected && p.Focus
+
+	hashStyle := config.DefStyle.Foreground(colorGraphMain)
+	dateStyle := config.DefStyle.Foreground(colorUntracked)
+	subjectStyle := config.DefStyle.Foreground(tcell.Color252)
+	if highlighted {
+		hashStyle, dateStyle, subjectStyle = style, style, style
+	}
+
+	x := 2
+	x += p.drawTextAt(screen, x, y, rev.ShortHash+" ", hashStyle)
+	x += p.drawTextAt(screen, x, y, rev.Time.Format("Jan 02")+" ", dateStyle)
+	p.drawTextAt(screen, x, y, rev.Subject, subjectStyle)
+}
+
+// drawStashList draws the stashes, newest first
+func (p *Panel) drawStashList(screen tcell.Screen, startY int) {
+	y := startY
+	p.stashYToRow = make(map[int]int)
+
+	titleStyle := config.DefStyle.Foreground(colorHeader).Bold(true)
+	p.drawText(screen, 1, y, fmt.Sprintf("▸ Stashes (%d)", len(p.Stashes)), titleStyle)
+	y++
+
+	// Shortcut hints
+	hintStyle := config.DefStyle.Foreground(tcell.ColorGray)
+	p.drawText(screen, 1, y, " [enter]diff [s]stash [u]+untracked", hintStyle)
+	y++
+	p.drawText(screen, 1, y, " [a]apply [p]pop [x]drop [esc]back", hintStyle)
+	y += 2
+
+	if len(p.Stashes) == 0 {
+		emptyStyle := config.DefStyle.Foreground(colorUntracked)
+		p.drawText(screen, 2, y, "No stashes", emptyStyle)
+		p.drawText(screen, 2, y+1, "(press s to stash your changes)", emptyStyle)
+		return
+	}
+
+	visible := p.Region.Height - 1 - y
+	if visible < 1 {
+		return
+	}
+
+	// Clamp selection and keep it in view
+	if p.StashSelected >= len(p.Stashes) {
+		p.StashSelected = len(p.Stashes) - 1
+	}
+	if p.StashSelected < 0 {
+		p.StashSelected = 0
+	}
+	if p.StashSelected < p.StashTopLine {
+		p.StashTopLine = p.StashSelected
+	}
+	if p.StashSelected >= p.StashTopLine+visible {
+		p.StashTopLine = p.StashSelected - visible + 1
+	}
+
+	for i := p.StashTopLine; i < len(p.Stashes) && y < p.Region.Height-1; i++ {
+		p.stashYToRow[y] = i
+		p.drawStashRow(screen, y, i)
+		y++
+	}
+}
+
+// drawStashRow draws a single stash: index, date and message
+func (p *Panel) drawStashRow(screen tcell.Screen, y int, idx int) {
+	s := p.Stashes[idx]
+	isSelected := idx == p.StashSelected
+
+	// Selection background
+	style := config.DefStyle
+	if isSelected {
+		if p.Focus {
+			style = config.DefStyle.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite)
+		} else {
+			style = config.DefStyle.Background(tcell.Color236) // Dark gray
+		}
+		for x := 1; x < p.Region.Width-1; x++ {
+			screen.SetContent(p.Region.X+x, p.Region.Y+y, ' ', nil, style)
+		}
+	}
+	highlighted := isSelected && p.Focus
+
+	refStyle := config.DefStyle.Foreground(colorGraphBranch)
+	dateStyle := config.DefStyle.Foreground(colorUntracked)
+	messageStyle := config.DefStyle.Foreground(tcell.Color252)
+	if highlighted {
+		refStyle, dateStyle, messageStyle = style, style, style
+	}
+
+	x := 2
+	x += p.drawTextAt(screen, x, y, fmt.Sprintf("%d ", idx), refStyle)
+	x += p.drawTextAt(screen, x, y, s.Time.Format("Jan 02")+" ", dateStyle)
+	p.drawTextAt(screen, x, y, s.Message, messageStyle)
+}
+
+// rebaseActionColors gives each rebase action its color in the editor
+var rebaseActionColors = map[RebaseAction]tcell.Color{
+	RebasePick:   colorAdded,
+	RebaseSquash: colorModified,
+	RebaseFixup:  colorModified,
+	RebaseReword: colorGraphBranch,
+	RebaseDrop:   colorDeleted,
+}
+
+// drawRebaseEditor draws the commits of the interactive rebase, oldest first
+func (p *Panel) drawRebaseEditor(screen tcell.Screen, startY int) {
+	y := startY
+	p.rebaseYToRow = make(map[int]int)
+
+	titleStyle := config.DefStyle.Foreground(colorHeader).Bold(true)
+	p.drawText(screen, 1, y, fmt.Sprintf("▸ Rebase onto %s (%d)", p.RebaseBaseName, len(p.RebaseEntries)), titleStyle)
+	y++
+
+	// Shortcut hints
+	hintStyle := config.DefStyle.Foreground(tcell.ColorGray)
+	p.drawText(screen, 1, y, " [p]ick [s]quash [f]ixup [r]eword [d]rop", hintStyle)
+	y++
+	p.drawText(screen, 1, y, " [J/K]move [enter]rebase [esc]cancel", hintStyle)
+	y += 2
+
+	if len(p.RebaseEntries) == 0 {
+		emptyStyle := config.DefStyle.Foreground(colorUntracked)
+		p.drawText(screen, 2, y, "No commits since "+p.RebaseBaseName, emptyStyle)
+		return
+	}
+
+	visible := p.Region.Height - 1 - y
+	if visible < 1 {
+		return
+	}
+
+	// Clamp selection and keep it in view
+	if p.RebaseSelected >= len(p.RebaseEntries) {
+		p.RebaseSelected = len(p.RebaseEntries) - 1
+	}
+	if p.RebaseSelected < 0 {
+		p.RebaseSelected = 0
+	}
+	if p.RebaseSelected < p.RebaseTopLine {
+		p.RebaseTopLine = p.RebaseSelected
+	}
+	if p.RebaseSelected >= p.RebaseTopLine+visible {
+		p.RebaseTopLine = p.RebaseSelected - visible + 1
+	}
+
+	for i := p.RebaseTopLine; i < len(p.RebaseEntries) && y < p.Region.Height-1; i++ {
+		p.rebaseYToRow[y] = i
+		p.drawRebaseRow(screen, y, i)
+		y++
+	}
+}
+
+// drawRebaseRow draws a single rebase entry: action, short hash and subject
+// (or the new message of a reword)
+func (p *Panel) drawRebaseRow(screen tcell.Screen, y int, idx int) {
+	e := p.RebaseEntries[idx]
+	isSelected := idx == p.RebaseSelected
+
+	// Selection background
+	style := config.DefStyle
+	if isSelected {
+		if p.Focus {
+			style = config.DefStyle.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite)
+		} else {
+			style = config.DefStyle.Background(tcell.Color236) // Dark gray
+		}
+		for x := 1; x < p.Region.Width-1; x++ {
+			screen.SetContent(p.Region.X+x, p.Region.Y+y, ' ', nil, style)
+		}
+	}
+	highlighted := isSelected && p.Focus
+
+	actionStyle := config.DefStyle.Foreground(rebaseActionColors[e.Action]).Bold(true)
+	hashStyle := config.DefStyle.Foreground(colorUntracked)
+	subjectStyle := config.DefStyle.Foreground(tcell.Color252)
+	if e.Action == RebaseDrop {
+		subjectStyle = subjectStyle.StrikeThrough(true)
+	}
+	if highlighted {
+		actionStyle, hashStyle, subjectStyle = style.Bold(true), style, style
+	}
+
+	subject := e.Subject
+	if e.Action == RebaseReword && e.Message != "" {
+		subject = firstLine(e.Message)
+	}
+
+	x := 2
+	x += p.drawTextAt(screen, x, y, fmt.Sprintf("%-6s ", e.Action), actionStyle)
+	x += p.drawTextAt(screen, x, y, e.ShortHash+" ", hashStyle)
+	p.drawTextAt(screen, x, y, subject, subjectStyle)
+}
+
+// drawDropStashConfirmDialog draws a confirmation dialog for dropping the
+// selected stash
+func (p *Panel) drawDropStashConfirmDialog(screen tcell.Screen) {
+	s := p.selectedStash()
+	if s == nil {
+		return
+	}
+	// Red: the stash is gone for good
+	p.drawConfirmDialog(screen, " Drop Stash? ", s.Ref+": "+s.Message, "This cannot be undone.", colorDeleted)
+}
+
+// drawForceDeleteConfirmDialog draws a confirmation dialog for force deleting
+// the selected branch when it has unmerged commits
+func (p *Panel) drawForceDeleteConfirmDialog(screen tcell.Screen) {
+	branch := p.selectedBranch()
+	if branch == nil {
+		return
+	}
+	// Red: the unmerged commits are only reachable from this branch
+	p.drawConfirmDialog(screen, " Delete Unmerged Branch? ", branch.Name, "Its unmerged commits will be lost.", colorDeleted)
+}
+
+// drawCommitMenu draws the operations offered for the commit picked in the graph
+func (p *Panel) drawCommitMenu(screen tcell.Screen) {
+	p.commitMenuYToRow = make(map[int]int)
+
+	// Dialog dimensions
+	dialogWidth := 40
+	if p.Region.Width-4 < dialogWidth {
+		dialogWidth = p.Region.Width - 4
+	}
+	dialogHeight := len(p.CommitMenuItems) + 4 // Title + border + footer
+
+	// Center dialog in panel
+	dialogX := p.Region.X + (p.Region.Width-dialogWidth)/2
+	dialogY := p.Region.Y + (p.Region.Height-dialogHeight)/2
+
+	// Clear dialog area and draw the border
+	borderStyle := config.DefStyle.Foreground(colorBorder)
+	for dy := 0; dy < dialogHeight; dy++ {
+		for dx := 0; dx < dialogWidth; dx++ {
+			r := ' '
+			switch {
+			case dy == 0 || dy == dialogHeight-1:
+				r = '═'
+			case dy == dialogHeight-3:
+				r = '─'
+			case dx == 0 || dx == dialogWidth-1:
+				r = '║'
+			}
+			screen.SetContent(dialogX+dx, dialogY+dy, r, nil, borderStyle)
+		}
+	}
+	screen.SetContent(dialogX, dialogY, '╔', nil, borderStyle)
+	screen.SetContent(dialogX+dialogWidth-1, dialogY, '╗', nil, borderStyle)
+	screen.SetContent(dialogX, dialogY+dialogHeight-3, '╠', nil, borderStyle)
+	screen.SetContent(dialogX+dialogWidth-1, dialogY+dialogHeight-3, '╣', nil, borderStyle)
+	screen.SetContent(dialogX, dialogY+dialogHeight-1, '╚', nil, borderStyle)
+	screen.SetContent(dialogX+dialogWidth-1, dialogY+dialogHeight-1, '╝', nil, borderStyle)
+
+	// Title: the commit
+	c := p.CommitMenuCommit
+	title := []rune(" " + c.ShortHash + " " + c.Subject + " ")
+	if len(title) > dialogWidth-4 {
+		title = append(title[:dialogWidth-7], []rune("... ")...)
+	}
+	titleX := dialogX + (dialogWidth-len(title))/2
+	titleStyle := config.DefStyle.Foreground(colorHeader).Bold(true)
+	for i, r := range title {
+		screen.SetContent(titleX+i, dialogY, r, nil, titleStyle)
+	}
+
+	// Entries with their shortcut keys
+	for i, item := range p.CommitMenuItems {
+		y := dialogY + 1 + i
+		p.commitMenuYToRow[y-p.Region.Y] = i
+
+		style := config.DefStyle.Foreground(tcell.Color252)
+		keyStyle := config.DefStyle.Foreground(colorButton).Bold(true)
+		if item.Action == CommitResetHard || item.Action == CommitAbort {
+			style = config.DefStyle.Foreground(colorDeleted)
+		}
+		if i == p.CommitMenuSelected {
+			style = config.DefStyle.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite)
+			keyStyle = style.Bold(true)
+			for x := 1; x < dialogWidth-1; x++ {
+				screen.SetContent(dialogX+x, y, ' ', nil, style)
+			}
+		}
+
+		screen.SetContent(dialogX+2, y, item.Shortcut, nil, keyStyle)
+		for j, r := range []rune(item.Label) {
+			if 4+j >= dialogWidth-1 {
+				break
+			}
+			screen.SetContent(dialogX+4+j, y, r, nil, style)
+		}
+	}
+
+	// Footer with hints
+	footer := " ↑↓:select Enter Esc "
+	footerX := dialogX + (dialogWidth-len([]rune(footer)))/2
+	for i, r := range []rune(footer) {
+		screen.SetContent(footerX+i, dialogY+dialogHeight-2, r, nil, config.DefStyle.Foreground(tcell.ColorGray))
+	}
+}
+
+// drawResetConfirmDialog draws a confirmation dialog for resetting the
+// current branch to the commit menu's commit
+func (p *Panel) drawResetConfirmDialog(screen tcell.Screen) {
+	mode := resetModes[p.ResetAction]
+	c := p.CommitMenuCommit
+	title := fmt.Sprintf(" Reset (%s)? ", mode)
+	detail := "Move " + p.GetBranchName() + " to " + c.ShortHash
+	switch p.ResetAction {
+	case CommitResetSoft:
+		p.drawConfirmDialog(screen, title, detail, "Changes since then stay staged.", colorModified)
+	case CommitResetMixed:
+		p.drawConfirmDialog(screen, title, detail, "Changes since then become unstaged.", colorModified)
+	default:
+		// Red: uncommitted changes and later commits are thrown away
+		p.drawConfirmDialog(screen, title, detail, "Uncommitted changes will be lost!", colorDeleted)
+	}
+}
@@ -0,0 +1,277 @@
+package sourcecontrol
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNothingToStash is returned when there are no local changes to stash
+var ErrNothingToStash = errors.New("no local changes to stash")
+
+// Stash is an entry of `git stash list`
+type Stash struct {
+	Ref     string // e.g. "stash@{0}"
+	Hash    string
+	Time    time.Time
+	Message string // e.g. "On main: message" or "WIP on main: abc1234 subject"
+}
+
+// stashListFormat separates fields with \x1f
+const stashListFormat = "--format=%gd%x1f%H%x1f%ct%x1f%gs"
+
+// parseStashList parses `git stash list` output in stashListFormat
+func parseStashList(output string) []Stash {
+	var stashes []Stash
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(line, "\x1f", 4)
+		if len(fields) < 4 {
+			continue
+		}
+		s := Stash{Ref: fields[0], Hash: fields[1], Message: fields[3]}
+		if secs, err := strconv.ParseInt(fields[2], 10, 64); err == nil {
+			s.Time = time.Unix(secs, 0)
+		}
+		stashes = append(stashes, s)
+	}
+	return stashes
+}
+
+// ListStashes returns the repository's stashes, newest first
+func ListStashes(repoRoot string) ([]Stash, error) {
+	output, err := gitOutput(repoRoot, nil, "stash", "list", stashListFormat)
+	if err != nil {
+		return nil, err
+	}
+	return parseStashList(output), nil
+}
+
+// CreateStash stashes the local changes (and untracked files if asked)
+func CreateStash(repoRoot, message string, includeUntracked bool) error {
+	args := []string{"stash", "push"}
+	if includeUntracked {
+		args = append(args, "--include-untracked")
+	}
+	if message != "" {
+		args = append(args, "-m", message)
+	}
+	// Whether anything was stashed shows in refs/stash, not in git's
+	// message, which depends on the locale
+	before, _ := gitOutput(repoRoot, nil, "rev-parse", "-q", "--verify", "refs/stash")
+	if _, err := gitOutput(repoRoot, nil, args...); err != nil {
+		return err
+	}
+	if after, _ := gitOutput(repoRoot, nil, "rev-parse", "-q", "--verify", "refs/stash"); after == before {
+		return ErrNothingToStash
+	}
+	log.Printf("THICC SourceControl: Stashed changes (untracked=%v)", includeUntracked)
+	return nil
+}
+
+// ApplyStash applies a stash to the working tree, removing it if pop is set.
+// On a conflict the stash is kept.
+func ApplyStash(repoRoot string, s Stash, pop bool) error {
+	verb := "apply"
+	if pop {
+		verb = "pop"
+	}
+	if _, err := gitOutput(repoRoot, nil, "stash", verb, s.Ref); err != nil {
+		return err
+	}
+	log.Printf("THICC SourceControl: Stash %s %s", verb, s.Ref)
+	return nil
+}
+
+// DropStash deletes a stash
+func DropStash(repoRoot string, s Stash) error {
+	if _, err := gitOutput(repoRoot, nil, "stash", "drop", s.Ref); err != nil {
+		return err
+	}
+	log.Printf("THICC SourceControl: Dropped %s", s.Ref)
+	return nil
+}
+
+// ShowStashList opens the stash list
+func (p *Panel) ShowStashList() {
+	p.StashSelected = 0
+	p.StashTopLine = 0
+	p.ShowStashes = true
+	p.RefreshStashes()
+}
+
+// HideStashList closes the stash list
+func (p *Panel) HideStashList() {
+	p.ShowStashes = false
+	p.ShowDropStashConfirm = false
+	p.Stashes = nil
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
+
+// RefreshStashes reloads the stash list (if open), keeping the selection
+// on the same stash
+func (p *Panel) RefreshStashes() {
+	if !p.ShowStashes {
+		return
+	}
+	stashes, err := ListStashes(p.RepoRoot)
+	if err != nil {
+		log.Printf("THICC SourceControl: Failed to list stashes: %v", err)
+		return
+	}
+
+	selected := ""
+	if s := p.selectedStash(); s != nil {
+		selected = s.Hash
+	}
+
+	p.mu.Lock()
+	p.Stashes = stashes
+	for i, s := range stashes {
+		if s.Hash == selected {
+			p.StashSelected = i
+		}
+	}
+	p.mu.Unlock()
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
+
+// selectedStash returns the stash under the cursor, or nil
+func (p *Panel) selectedStash() *Stash {
+	if p.StashSelected < 0 || p.StashSelected >= len(p.Stashes) {
+		return nil
+	}
+	return &p.Stashes[p.StashSelected]
+}
+
+// StashMoveUp moves the stash cursor up
+func (p *Panel) StashMoveUp() {
+	if p.StashSelected > 0 {
+		p.StashSelected--
+	}
+}
+
+// StashMoveDown moves the stash cursor down
+func (p *Panel) StashMoveDown() {
+	if p.StashSelected < len(p.Stashes)-1 {
+		p.StashSelected++
+	}
+}
+
+// DiffSelectedStash shows the selected stash's changes in the commit diff view
+func (p *Panel) DiffSelectedStash() {
+	s := p.selectedStash()
+	if s == nil {
+		return
+	}
+	if p.OnCommitSelect != nil {
+		p.OnCommitSelect(s.Ref, "")
+	}
+}
+
+// PromptNewStash asks for a message and stashes the local changes
+func (p *Panel) PromptNewStash(includeUntracked bool) {
+	if p.OnInput == nil {
+		p.NewStash("", includeUntracked)
+		return
+	}
+	title := "Stash Changes"
+	if includeUntracked {
+		title = "Stash Changes (with untracked)"
+	}
+	p.OnInput(title, "Message (optional):", "", func(message string, canceled bool) {
+		if !canceled {
+			p.NewStash(strings.TrimSpace(message), includeUntracked)
+		}
+	})
+}
+
+// NewStash stashes the local changes
+func (p *Panel) NewStash(message string, includeUntracked bool) {
+	err := CreateStash(p.RepoRoot, message, includeUntracked)
+	if err != nil {
+		log.Printf("THICC SourceControl: Stash failed: %v", err)
+	}
+	p.afterStashOperation("Stashed changes", err)
+	if err == nil {
+		// Select the new stash
+		p.StashSelected = 0
+	}
+}
+
+// ApplySelectedStash applies (or pops) the selected stash
+func (p *Panel) ApplySelectedStash(pop bool) {
+	s := p.selectedStash()
+	if s == nil {
+		return
+	}
+	err := ApplyStash(p.RepoRoot, *s, pop)
+	msg := "Applied " + s.Ref
+	if pop {
+		msg = "Popped " + s.Ref
+	}
+	if err != nil {
+		log.Printf("THICC SourceControl: Stash apply failed: %v", err)
+	}
+	p.afterStashOperation(msg, err)
+}
+
+// showDropStashConfirm asks for confirmation before dropping the selected stash
+func (p *Panel) showDropStashConfirm() {
+	if p.selectedStash() == nil {
+		return
+	}
+	p.ShowDropStashConfirm = true
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
+
+// hideDropStashConfirm hides the drop confirmation dialog
+func (p *Panel) hideDropStashConfirm() {
+	p.ShowDropStashConfirm = false
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
+
+// confirmDropStash drops the selected stash
+func (p *Panel) confirmDropStash() {
+	p.ShowDropStashConfirm = false
+	s := p.selectedStash()
+	if s == nil {
+		return
+	}
+	err := DropStash(p.RepoRoot, *s)
+	if err != nil {
+		log.Printf("THICC SourceControl: Stash drop failed: %v", err)
+	}
+	p.afterStashOperation("Dropped "+s.Ref, err)
+}
+
+// afterStashOperation reports how a stash operation went and reloads the
+// file lists and stashes
+func (p *Panel) afterStashOperation(msg string, err error) {
+	if err != nil {
+		msg = fmt.Sprintf("Stash failed: %v", err)
+	}
+	if p.OnMessage != nil {
+		p.OnMessage(msg, err != nil)
+	}
+
+	p.RefreshStatus()
+	p.RefreshStashes()
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
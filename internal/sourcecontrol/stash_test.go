@@ -0,0 +1,161 @@
+package sourcecontrol
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// Stash Parsing Tests
+// =============================================================================
+
+func TestParseStashList(t *testing.T) {
+	output := "stash@{0}\x1fh1\x1f200\x1fOn main: wip\n" +
+		"stash@{1}\x1fh2\x1f100\x1fWIP on main: abc1234 subject\n"
+
+	stashes := parseStashList(output)
+	require.Len(t, stashes, 2)
+	assert.Equal(t, "stash@{0}", stashes[0].Ref)
+	assert.Equal(t, "On main: wip", stashes[0].Message)
+	assert.Equal(t, "h2", stashes[1].Hash)
+	assert.Equal(t, time.Unix(100, 0), stashes[1].Time)
+}
+
+func TestParseStashList_Empty(t *testing.T) {
+	assert.Empty(t, parseStashList(""))
+}
+
+// =============================================================================
+// Stash Git Tests
+// =============================================================================
+
+func TestCreateStash_WithMessage(t *testing.T) {
+	dir := initTestRepo(t)
+	writeFile(t, dir, "main.go", "package main // wip\n")
+
+	require.NoError(t, CreateStash(dir, "my wip", false))
+	assert.Equal(t, "package main\n", readFile(t, dir, "main.go"))
+
+	stashes, err := ListStashes(dir)
+	require.NoError(t, err)
+	require.Len(t, stashes, 1)
+	assert.Contains(t, stashes[0].Message, "my wip")
+}
+
+func TestCreateStash_NothingToStash(t *testing.T) {
+	dir := initTestRepo(t)
+	assert.Equal(t, ErrNothingToStash, CreateStash(dir, "", false))
+}
+
+func TestCreateStash_NothingToStashWithExistingStash(t *testing.T) {
+	dir := initTestRepo(t)
+	writeFile(t, dir, "main.go", "package main // wip\n")
+	require.NoError(t, CreateStash(dir, "", false))
+
+	assert.Equal(t, ErrNothingToStash, CreateStash(dir, "", false))
+	stashes, err := ListStashes(dir)
+	require.NoError(t, err)
+	assert.Len(t, stashes, 1)
+}
+
+func TestCreateStash_IncludeUntracked(t *testing.T) {
+	dir := initTestRepo(t)
+	writeFile(t, dir, "new.go", "package main\n")
+
+	assert.Equal(t, ErrNothingToStash, CreateStash(dir, "", false), "untracked files are left alone by default")
+	require.NoError(t, CreateStash(dir, "", true))
+
+	status, err := gitOutput(dir, nil, "status", "--porcelain")
+	require.NoError(t, err)
+	assert.Empty(t, status)
+}
+
+func TestApplyAndPopStash(t *testing.T) {
+	dir := initTestRepo(t)
+	writeFile(t, dir, "main.go", "package main // wip\n")
+	require.NoError(t, CreateStash(dir, "", false))
+	stashes, err := ListStashes(dir)
+	require.NoError(t, err)
+
+	require.NoError(t, ApplyStash(dir, stashes[0], false))
+	assert.Equal(t, "package main // wip\n", readFile(t, dir, "main.go"))
+	stashes, err = ListStashes(dir)
+	require.NoError(t, err)
+	require.Len(t, stashes, 1, "apply keeps the stash")
+
+	_, err = gitOutput(dir, nil, "checkout", "--", "main.go")
+	require.NoError(t, err)
+	require.NoError(t, ApplyStash(dir, stashes[0], true))
+	assert.Equal(t, "package main // wip\n", readFile(t, dir, "main.go"))
+	stashes, err = ListStashes(dir)
+	require.NoError(t, err)
+	assert.Empty(t, stashes, "pop removes the stash")
+}
+
+func TestDropStash(t *testing.T) {
+	dir := initTestRepo(t)
+	writeFile(t, dir, "main.go", "package main // wip\n")
+	require.NoError(t, CreateStash(dir, "", false))
+	stashes, err := ListStashes(dir)
+	require.NoError(t, err)
+
+	require.NoError(t, DropStash(dir, stashes[0]))
+	stashes, err = ListStashes(dir)
+	require.NoError(t, err)
+	assert.Empty(t, stashes)
+	assert.Equal(t, "package main\n", readFile(t, dir, "main.go"))
+}
+
+// =============================================================================
+// Stash List Panel Tests
+// =============================================================================
+
+func TestStashPanel_CreateSelectsNewStash(t *testing.T) {
+	dir := initTestRepo(t)
+	writeFile(t, dir, "main.go", "package main // one\n")
+	require.NoError(t, CreateStash(dir, "one", false))
+
+	var msg string
+	p := &Panel{RepoRoot: dir}
+	p.OnMessage = func(m string, _ bool) { msg = m }
+	p.OnInput = func(_, _, _ string, done func(string, bool)) { done(" two ", false) }
+
+	p.ShowStashList()
+	require.Len(t, p.Stashes, 1)
+	p.StashMoveDown()
+
+	writeFile(t, dir, "main.go", "package main // two\n")
+	p.PromptNewStash(false)
+	assert.Equal(t, "Stashed changes", msg)
+	require.Len(t, p.Stashes, 2)
+	assert.Equal(t, 0, p.StashSelected)
+	assert.Contains(t, p.Stashes[0].Message, "two")
+}
+
+func TestStashPanel_DropConfirm(t *testing.T) {
+	dir := initTestRepo(t)
+	writeFile(t, dir, "main.go", "package main // wip\n")
+	require.NoError(t, CreateStash(dir, "", false))
+
+	p := &Panel{RepoRoot: dir}
+	p.ShowStashList()
+	p.showDropStashConfirm()
+	require.True(t, p.ShowDropStashConfirm)
+
+	p.confirmDropStash()
+	assert.False(t, p.ShowDropStashConfirm)
+	assert.Empty(t, p.Stashes)
+}
+
+func TestStashPanel_DiffUsesStashRef(t *testing.T) {
+	var hash, path string
+	p := &Panel{Stashes: []Stash{{Ref: "stash@{0}", Hash: "h1"}}}
+	p.OnCommitSelect = func(h, pp string) { hash, path = h, pp }
+
+	p.DiffSelectedStash()
+	assert.Equal(t, "stash@{0}", hash)
+	assert.Empty(t, path)
+}
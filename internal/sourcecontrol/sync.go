@@ -0,0 +1,324 @@
+package sourcecontrol
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ellery/thicc/internal/thicc"
+)
+
+// PushMode picks how the push button pushes
+type PushMode int
+
+const (
+	PushDefault        PushMode = iota // Plain push, or set-upstream if the branch has no upstream yet
+	PushForceWithLease                 // Overwrite the remote branch unless someone else pushed to it
+	PushSetUpstream                    // Push to the default remote and track it
+)
+
+// PullMode picks how the pull button integrates the remote commits
+type PullMode int
+
+const (
+	PullDefault PullMode = iota // Whatever the repository's pull.rebase says
+	PullMerge
+	PullRebase
+)
+
+// syncEnv keeps git from prompting for credentials on the terminal thicc is
+// drawing on; a remote that needs them fails instead
+var syncEnv = []string{"GIT_TERMINAL_PROMPT=0"}
+
+// pushArgs returns the `git push` arguments for a mode
+func pushArgs(mode PushMode, remote string) []string {
+	switch mode {
+	case PushForceWithLease:
+		return []string{"push", "--progress", "--force-with-lease"}
+	case PushSetUpstream:
+		return []string{"push", "--progress", "--set-upstream", remote, "HEAD"}
+	}
+	return []string{"push", "--progress"}
+}
+
+// pullArgs returns the `git pull` arguments for a mode
+func pullArgs(mode PullMode) []string {
+	switch mode {
+	case PullMerge:
+		return []string{"pull", "--progress", "--no-rebase"}
+	case PullRebase:
+		return []string{"pull", "--progress", "--rebase"}
+	}
+	return []string{"pull", "--progress"}
+}
+
+// scanProgressLines splits git's progress output into lines. Progress
+// counters redraw themselves with \r, so those end a line too.
+func scanProgressLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// runGitProgress runs a git command, passing each line it prints to stderr
+// (where git reports progress) to onProgress as it arrives
+func runGitProgress(repoRoot string, onProgress func(line string), args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoRoot
+	cmd.Env = append(os.Environ(), syncEnv...)
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+
+	// Keep the last real message for the error, skipping progress counters
+	var last string
+	scanner := bufio.NewScanner(stderr)
+	scanner.Split(scanProgressLines)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if onProgress != nil {
+			onProgress(line)
+		}
+		if !strings.Contains(line, "%") {
+			last = line
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("git %s failed: %v: %s", args[0], err, last)
+	}
+	return nil
+}
+
+// defaultRemote returns the remote to publish new branches to: origin, or
+// the only remote there is. Returns "" if there is no remote.
+func defaultRemote(repoRoot string) string {
+	output, err := gitOutput(repoRoot, nil, "remote")
+	if err != nil || output == "" {
+		return ""
+	}
+	remotes := strings.Split(output, "\n")
+	for _, r := range remotes {
+		if r == "origin" {
+			return r
+		}
+	}
+	return remotes[0]
+}
+
+// upstreamBranch returns the branch HEAD tracks, or "" if it has none
+func upstreamBranch(repoRoot string) string {
+	upstream, err := gitOutput(repoRoot, nil, "rev-parse", "--abbrev-ref", "@{upstream}")
+	if err != nil {
+		return ""
+	}
+	return upstream
+}
+
+// publishSignature identifies what Publishable depends on - the branch HEAD
+// is on, and the config holding its upstream and the remotes - from the
+// files themselves, so the status poll needn't run git for it. Returns "" if
+// it can't be worked out. Call with p.mu held.
+func (p *Panel) publishSignature() string {
+	if len(p.publishPaths) != 2 {
+		output, err := gitOutput(p.RepoRoot, nil, "rev-parse", "--git-path", "HEAD", "--git-path", "config")
+		paths := strings.Split(output, "\n")
+		if err != nil || len(paths) != 2 {
+			return ""
+		}
+		for i, path := range paths {
+			if !filepath.IsAbs(path) {
+				paths[i] = filepath.Join(p.RepoRoot, path)
+			}
+		}
+		p.publishPaths = paths
+	}
+
+	head, err := os.ReadFile(p.publishPaths[0])
+	if err != nil {
+		return ""
+	}
+	config, err := os.Stat(p.publishPaths[1])
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s%d %d", head, config.ModTime().UnixNano(), config.Size())
+}
+
+// Fetch fetches all remotes, pruning deleted remote branches
+func Fetch(repoRoot string, onProgress func(line string)) error {
+	if err := runGitProgress(repoRoot, onProgress, "fetch", "--all", "--prune", "--progress"); err != nil {
+		log.Printf("THICC SourceControl: git fetch failed: %v", err)
+		return err
+	}
+	log.Println("THICC SourceControl: Fetch successful")
+	return nil
+}
+
+// Push pushes to the remote. A branch without an upstream is published to
+// the default remote.
+func (p *Panel) Push(mode PushMode, onProgress func(line string)) error {
+	remote := defaultRemote(p.RepoRoot)
+	if mode == PushDefault && upstreamBranch(p.RepoRoot) == "" && remote != "" {
+		mode = PushSetUpstream
+	}
+	if err := runGitProgress(p.RepoRoot, onProgress, pushArgs(mode, remote)...); err != nil {
+		log.Printf("THICC SourceControl: git push failed: %v", err)
+		return err
+	}
+	log.Printf("THICC SourceControl: Push successful (mode %d)", mode)
+	return nil
+}
+
+// Pull pulls from the remote
+func (p *Panel) Pull(mode PullMode, onProgress func(line string)) error {
+	if err := runGitProgress(p.RepoRoot, onProgress, pullArgs(mode)...); err != nil {
+		log.Printf("THICC SourceControl: git pull failed: %v", err)
+		return err
+	}
+	log.Printf("THICC SourceControl: Pull successful (mode %d)", mode)
+	return nil
+}
+
+// startOperation marks an operation as running and puts the spinner up.
+// Returns false if one is already running, or a background fetch is, since
+// it holds the ref locks.
+func (p *Panel) startOperation(name string) bool {
+	p.mu.Lock()
+	if p.OperationInProgress != "" || p.fetching {
+		fetching := p.fetching
+		p.mu.Unlock()
+		if fetching && p.OnMessage != nil {
+			p.OnMessage("Auto-fetch is running, try again in a moment", true)
+		}
+		return false
+	}
+	p.OperationInProgress = name
+	p.OperationProgress = ""
+	p.mu.Unlock()
+	go p.spinnerLoop()
+	return true
+}
+
+// runSyncOperation runs a network operation in the background with the
+// spinner up, streaming git's progress into it, then reloads the panel
+func (p *Panel) runSyncOperation(name, done string, op func(onProgress func(line string)) error) {
+	if !p.startOperation(name) {
+		return
+	}
+
+	go func() {
+		err := op(func(line string) {
+			p.mu.Lock()
+			p.OperationProgress = line
+			p.mu.Unlock()
+		})
+
+		p.mu.Lock()
+		p.OperationInProgress = ""
+		p.OperationProgress = ""
+		p.publishSig = "" // The upstream may be set, or gone from the remote
+		p.mu.Unlock()
+
+		if p.OnMessage != nil {
+			if err != nil {
+				p.OnMessage(fmt.Sprintf("%s failed: %s", name, firstLine(err.Error())), true)
+			} else {
+				p.OnMessage(done, false)
+			}
+		}
+
+		p.RefreshStatus()
+		p.RefreshCommitGraph()
+		if p.OnRefresh != nil {
+			p.OnRefresh()
+		}
+	}()
+}
+
+// DoPush pushes to remote
+func (p *Panel) DoPush(mode PushMode) {
+	p.runSyncOperation("Pushing", "Pushed", func(onProgress func(string)) error {
+		return p.Push(mode, onProgress)
+	})
+}
+
+// DoPull pulls from remote
+func (p *Panel) DoPull(mode PullMode) {
+	p.runSyncOperation("Pulling", "Pulled", func(onProgress func(string)) error {
+		err := p.Pull(mode, onProgress)
+		if err == nil {
+			p.markFetched()
+		}
+		return err
+	})
+}
+
+// DoFetch fetches from the remotes
+func (p *Panel) DoFetch() {
+	p.runSyncOperation("Fetching", "Fetched", func(onProgress func(string)) error {
+		err := Fetch(p.RepoRoot, onProgress)
+		if err == nil {
+			p.markFetched()
+		}
+		return err
+	})
+}
+
+// markFetched restarts the auto-fetch interval
+func (p *Panel) markFetched() {
+	p.mu.Lock()
+	p.lastFetch = time.Now()
+	p.mu.Unlock()
+}
+
+// autoFetch fetches quietly in the background when auto-fetch is on and the
+// last fetch is older than the interval. Called from the poll loop.
+func (p *Panel) autoFetch() {
+	interval := thicc.GetAutoFetchInterval()
+	if interval <= 0 {
+		return
+	}
+
+	p.mu.Lock()
+	if p.OperationInProgress != "" || p.fetching || time.Since(p.lastFetch) < interval {
+		p.mu.Unlock()
+		return
+	}
+	p.fetching = true
+	p.mu.Unlock()
+
+	err := Fetch(p.RepoRoot, nil)
+
+	p.mu.Lock()
+	p.fetching = false
+	// Wait a whole interval before retrying a remote that failed too
+	p.lastFetch = time.Now()
+	p.publishSig = ""
+	p.mu.Unlock()
+
+	if err == nil {
+		p.RefreshStatus()
+		p.RefreshCommitGraph()
+		if p.OnRefresh != nil {
+			p.OnRefresh()
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package sourcecontrol
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// initRemotePair creates a bare remote with one commit and a clone of it
+func initRemotePair(t *testing.T) (remote, clone string) {
+	seed := initTestRepo(t)
+	remote = t.TempDir()
+	_, err := gitOutput(remote, nil, "clone", "-q", "--bare", seed, ".")
+	require.NoError(t, err)
+	return remote, cloneRemote(t, remote)
+}
+
+// cloneRemote clones a remote with a committer identity set up
+func cloneRemote(t *testing.T, remote string) string {
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"clone", "-q", remote, "."},
+		{"config", "user.name", "Test"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "commit.gpgsign", "false"},
+	} {
+		_, err := gitOutput(dir, nil, args...)
+		require.NoError(t, err)
+	}
+	return dir
+}
+
+// =============================================================================
+// Sync Argument Tests
+// =============================================================================
+
+func TestPushArgs(t *testing.T) {
+	assert.Equal(t, []string{"push", "--progress"}, pushArgs(PushDefault, "origin"))
+	assert.Contains(t, pushArgs(PushForceWithLease, "origin"), "--force-with-lease")
+	assert.Equal(t, []string{"push", "--progress", "--set-upstream", "origin", "HEAD"}, pushArgs(PushSetUpstream, "origin"))
+}
+
+func TestPullArgs(t *testing.T) {
+	assert.NotContains(t, pullArgs(PullDefault), "--rebase")
+	assert.Contains(t, pullArgs(PullMerge), "--no-rebase")
+	assert.Contains(t, pullArgs(PullRebase), "--rebase")
+}
+
+func TestScanProgressLines(t *testing.T) {
+	scanner := bufio.NewScanner(strings.NewReader("Counting: 50% (1/2)\rCounting: 100% (2/2), done.\nTo origin\n"))
+	scanner.Split(scanProgressLines)
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	assert.Equal(t, []string{"Counting: 50% (1/2)", "Counting: 100% (2/2), done.", "To origin"}, lines)
+}
+
+// =============================================================================
+// Push, Pull and Fetch Tests
+// =============================================================================
+
+func TestPush_PublishesNewBranch(t *testing.T) {
+	_, dir := initRemotePair(t)
+	require.NoError(t, CreateBranch(dir, "feature", ""))
+	commitFile(t, dir, "feature.go", "package main\n", "Add feature")
+
+	p := &Panel{RepoRoot: dir}
+	p.RefreshStatus()
+	assert.True(t, p.Publishable)
+	assert.True(t, p.CanPush())
+
+	var progress []string
+	require.NoError(t, p.Push(PushDefault, func(line string) { progress = append(progress, line) }))
+	assert.NotEmpty(t, progress, "git's progress is passed on")
+	assert.Equal(t, "origin/feature", upstreamBranch(dir))
+
+	p.RefreshStatus()
+	assert.False(t, p.Publishable)
+	assert.False(t, p.CanPush())
+}
+
+func TestRefreshStatus_ChecksUpstreamOnlyWhenConfigChanges(t *testing.T) {
+	_, dir := initRemotePair(t)
+	p := &Panel{RepoRoot: dir}
+	p.RefreshStatus()
+	require.False(t, p.Publishable)
+
+	// Nothing changed, so the last answer is kept
+	p.Publishable = true
+	p.RefreshStatus()
+	assert.True(t, p.Publishable)
+
+	// A new branch moves HEAD
+	require.NoError(t, CreateBranch(dir, "feature", ""))
+	p.RefreshStatus()
+	assert.True(t, p.Publishable)
+
+	// Setting the upstream changes the config
+	_, err := gitOutput(dir, nil, "push", "-q", "--set-upstream", "origin", "feature")
+	require.NoError(t, err)
+	p.RefreshStatus()
+	assert.False(t, p.Publishable)
+}
+
+func TestPush_ForceWithLease(t *testing.T) {
+	_, dir := initRemotePair(t)
+	commitFile(t, dir, "main.go", "package main // one\n", "One")
+	p := &Panel{RepoRoot: dir}
+	require.NoError(t, p.Push(PushDefault, nil))
+
+	// Rewrite the pushed commit
+	_, err := gitOutput(dir, nil, "commit", "-q", "--amend", "-m", "One, reworded")
+	require.NoError(t, err)
+	err = p.Push(PushDefault, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "git push failed")
+
+	require.NoError(t, p.Push(PushForceWithLease, nil))
+}
+
+func TestFetchAndPullRebase(t *testing.T) {
+	remote, dir := initRemotePair(t)
+	other := cloneRemote(t, remote)
+	commitFile(t, other, "other.go", "package main\n", "Other")
+	_, err := gitOutput(other, nil, "push", "-q")
+	require.NoError(t, err)
+
+	commitFile(t, dir, "mine.go", "package main\n", "Mine")
+	p := &Panel{RepoRoot: dir}
+	require.NoError(t, Fetch(dir, nil))
+	p.RefreshStatus()
+	assert.Equal(t, 1, p.AheadCount)
+	assert.Equal(t, 1, p.BehindCount, "fetching updates the behind count")
+
+	require.NoError(t, p.Pull(PullRebase, nil))
+	subjects, err := gitOutput(dir, nil, "log", "--format=%s", "-3")
+	require.NoError(t, err)
+	assert.Equal(t, "Mine\nOther\ninitial", subjects, "rebased, no merge commit")
+}
+
+func TestSyncOperation_WaitsForAutoFetch(t *testing.T) {
+	var msg string
+	p := &Panel{fetching: true, OnMessage: func(m string, isError bool) { msg = m }}
+	p.DoPull(PullDefault)
+	assert.Empty(t, p.OperationInProgress, "does not start while a background fetch holds the refs")
+	assert.Contains(t, msg, "Auto-fetch")
+}
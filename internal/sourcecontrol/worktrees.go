@@ -0,0 +1,325 @@
+package sourcecontrol
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// AI sessions can run in their own git worktree, on a new branch, so two
+// agents never write to the same checkout. thicc keeps these worktrees in a
+// directory of its own (chosen by the layout) and only manages those.
+
+// Worktree is a thicc-managed git worktree
+type Worktree struct {
+	Path   string // Absolute path of the checkout
+	Branch string // Branch checked out in it (without refs/heads/)
+	Head   string // Commit the worktree's HEAD points at
+	Ahead  int    // Commits on the branch that aren't on the current branch
+	Dirty  bool   // Has uncommitted or untracked changes
+}
+
+// WorktreeBranchName returns the branch name for a new worktree session,
+// e.g. "thicc/claude-20260304-150607"
+func WorktreeBranchName(tool string, now time.Time) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			return unicode.ToLower(r)
+		case r == '-' || r == '_' || r == ' ' || r == '.':
+			return '-'
+		}
+		return -1
+	}, tool)
+	name = strings.Trim(name, "-")
+	if name == "" {
+		name = "session"
+	}
+	return fmt.Sprintf("thicc/%s-%s", name, now.Format("20060102-150405"))
+}
+
+// CreateWorktree checks out a new branch (from HEAD) in a new worktree under baseDir
+func CreateWorktree(repoRoot, baseDir, branch string) (*Worktree, error) {
+	if err := os.MkdirAll(baseDir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(baseDir, strings.ReplaceAll(branch, "/", "-"))
+	if _, err := gitOutput(repoRoot, nil, "worktree", "add", "-q", "-b", branch, path, "HEAD"); err != nil {
+		return nil, err
+	}
+	head, _ := gitOutput(path, nil, "rev-parse", "HEAD")
+	log.Printf("THICC SourceControl: Created worktree %s on branch %s", path, branch)
+	return &Worktree{Path: path, Branch: branch, Head: head}, nil
+}
+
+// parseWorktreeList parses `git worktree list --porcelain` output, keeping
+// only the worktrees under baseDir
+func parseWorktreeList(output, baseDir string) []Worktree {
+	var wts []Worktree
+	var cur *Worktree
+	flush := func() {
+		if cur != nil && isUnder(cur.Path, baseDir) {
+			wts = append(wts, *cur)
+		}
+		cur = nil
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasPrefix(line, "worktree "):
+			flush()
+			cur = &Worktree{Path: strings.TrimPrefix(line, "worktree ")}
+		case cur == nil:
+		case strings.HasPrefix(line, "HEAD "):
+			cur.Head = strings.TrimPrefix(line, "HEAD ")
+		case strings.HasPrefix(line, "branch "):
+			cur.Branch = strings.TrimPrefix(strings.TrimPrefix(line, "branch "), "refs/heads/")
+		}
+	}
+	flush()
+	return wts
+}
+
+// isUnder returns true if path is inside dir
+func isUnder(path, dir string) bool {
+	if dir == "" {
+		return false
+	}
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(path))
+	return err == nil && rel != "." && !strings.HasPrefix(rel, "..")
+}
+
+// ListWorktrees returns the repository's worktrees that live under baseDir
+func ListWorktrees(repoRoot, baseDir string) ([]Worktree, error) {
+	output, err := gitOutput(repoRoot, nil, "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	// git reports resolved paths; resolve baseDir the same way (e.g. /tmp on macOS)
+	if resolved, err := filepath.EvalSymlinks(baseDir); err == nil {
+		baseDir = resolved
+	}
+	wts := parseWorktreeList(output, baseDir)
+	for i := range wts {
+		if wts[i].Branch != "" {
+			count, _ := gitOutput(repoRoot, nil, "rev-list", "--count", "HEAD.."+wts[i].Branch)
+			wts[i].Ahead, _ = parseInt(count)
+		}
+		status, _ := gitOutput(wts[i].Path, nil, "status", "--porcelain")
+		wts[i].Dirty = status != ""
+	}
+	return wts, nil
+}
+
+// WorktreeDiff returns everything a worktree session changed: its commits
+// plus uncommitted and untracked files, against where it branched off
+func WorktreeDiff(repoRoot string, wt Worktree) (string, error) {
+	base, err := gitOutput(repoRoot, nil, "merge-base", "HEAD", wt.Branch)
+	if err != nil {
+		return "", err
+	}
+	return DiffCheckpoints(wt.Path, base, "")
+}
+
+// MergeWorktree commits whatever the session left uncommitted in the
+// worktree, merges its branch into the current branch and removes the
+// worktree. On a merge conflict the merge is left in progress for
+// resolution and the worktree is kept.
+func MergeWorktree(repoRoot string, wt Worktree) error {
+	status, err := gitOutput(wt.Path, nil, "status", "--porcelain")
+	if err != nil {
+		return err
+	}
+	if status != "" {
+		if _, err := gitOutput(wt.Path, nil, "add", "-A"); err != nil {
+			return err
+		}
+		if _, err := gitOutput(wt.Path, nil, "commit", "-q", "-m", "Work from "+wt.Branch); err != nil {
+			return err
+		}
+		log.Printf("THICC SourceControl: Committed leftover changes in worktree %s", wt.Path)
+	}
+
+	if _, err := gitOutput(repoRoot, nil, "merge", "--no-edit", wt.Branch); err != nil {
+		return err
+	}
+	log.Printf("THICC SourceControl: Merged %s", wt.Branch)
+	return RemoveWorktree(repoRoot, wt, false)
+}
+
+// RemoveWorktree deletes a worktree and its branch. Without force, a
+// worktree with uncommitted changes or an unmerged branch is kept.
+func RemoveWorktree(repoRoot string, wt Worktree, force bool) error {
+	args := []string{"worktree", "remove", wt.Path}
+	if force {
+		args = []string{"worktree", "remove", "--force", wt.Path}
+	}
+	if _, err := gitOutput(repoRoot, nil, args...); err != nil {
+		return err
+	}
+
+	if wt.Branch != "" {
+		flag := "-d"
+		if force {
+			flag = "-D"
+		}
+		if _, err := gitOutput(repoRoot, nil, "branch", flag, wt.Branch); err != nil {
+			return err
+		}
+	}
+	log.Printf("THICC SourceControl: Removed worktree %s (branch %s)", wt.Path, wt.Branch)
+	return nil
+}
+
+// ShowWorktreeList opens the list of worktree sessions
+func (p *Panel) ShowWorktreeList() {
+	p.WorktreeSelected = 0
+	p.ShowWorktrees = true
+	p.RefreshWorktrees()
+}
+
+// HideWorktreeList closes the worktree list
+func (p *Panel) HideWorktreeList() {
+	p.ShowWorktrees = false
+	p.WorktreeConfirm = ""
+	p.Worktrees = nil
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
+
+// RefreshWorktrees reloads the worktree list (if open)
+func (p *Panel) RefreshWorktrees() {
+	if !p.ShowWorktrees || p.WorktreeBaseDir == "" {
+		return
+	}
+	wts, err := ListWorktrees(p.RepoRoot, p.WorktreeBaseDir)
+	if err != nil {
+		log.Printf("THICC SourceControl: Failed to list worktrees: %v", err)
+		return
+	}
+
+	p.mu.Lock()
+	p.Worktrees = wts
+	p.mu.Unlock()
+	log.Printf("THICC SourceControl: Listing %d worktrees", len(wts))
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
+
+// selectedWorktree returns the worktree under the cursor, or nil
+func (p *Panel) selectedWorktree() *Worktree {
+	if p.WorktreeSelected < 0 || p.WorktreeSelected >= len(p.Worktrees) {
+		return nil
+	}
+	return &p.Worktrees[p.WorktreeSelected]
+}
+
+// WorktreeMoveUp moves the worktree cursor up
+func (p *Panel) WorktreeMoveUp() {
+	if p.WorktreeSelected > 0 {
+		p.WorktreeSelected--
+	}
+}
+
+// WorktreeMoveDown moves the worktree cursor down
+func (p *Panel) WorktreeMoveDown() {
+	if p.WorktreeSelected < len(p.Worktrees)-1 {
+		p.WorktreeSelected++
+	}
+}
+
+// ReviewSelectedWorktree shows everything the selected session changed
+func (p *Panel) ReviewSelectedWorktree() {
+	wt := p.selectedWorktree()
+	if wt == nil {
+		return
+	}
+	patch, err := WorktreeDiff(p.RepoRoot, *wt)
+	if err != nil {
+		log.Printf("THICC SourceControl: Failed to diff worktree: %v", err)
+		return
+	}
+	log.Printf("THICC SourceControl: Reviewing worktree %s (%d bytes)", wt.Branch, len(patch))
+	if p.OnShowDiff != nil {
+		p.OnShowDiff(wt.Branch+" (worktree)", patch)
+	}
+}
+
+// showWorktreeConfirm asks for confirmation before merging or discarding
+// the selected worktree
+func (p *Panel) showWorktreeConfirm(action string) {
+	if p.selectedWorktree() == nil {
+		return
+	}
+	p.WorktreeConfirm = action
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
+
+// hideWorktreeConfirm hides the worktree confirmation dialog
+func (p *Panel) hideWorktreeConfirm() {
+	p.WorktreeConfirm = ""
+
+	if p.OnRefresh != nil {
+		p.OnRefresh()
+	}
+}
+
+// confirmWorktree merges or discards the selected worktree in the background
+func (p *Panel) confirmWorktree() {
+	wt := p.selectedWorktree()
+	action := p.WorktreeConfirm
+	p.WorktreeConfirm = ""
+	if wt == nil || p.OperationInProgress != "" {
+		return
+	}
+
+	target := *wt
+	if action == "merge" {
+		p.OperationInProgress = "Merging"
+	} else {
+		p.OperationInProgress = "Discarding"
+	}
+	go p.spinnerLoop()
+
+	go func() {
+		var err error
+		var msg string
+		if action == "merge" {
+			err = MergeWorktree(p.RepoRoot, target)
+			msg = "Merged " + target.Branch
+		} else {
+			err = RemoveWorktree(p.RepoRoot, target, true)
+			msg = "Discarded " + target.Branch
+		}
+
+		p.mu.Lock()
+		p.OperationInProgress = ""
+		p.mu.Unlock()
+
+		if err != nil {
+			log.Printf("THICC SourceControl: Worktree %s failed: %v", action, err)
+			msg = fmt.Sprintf("Could not %s %s: %v", action, target.Branch, err)
+		}
+		if p.OnMessage != nil {
+			p.OnMessage(msg, err != nil)
+		}
+
+		p.RefreshStatus()
+		p.RefreshCommitGraph()
+		p.RefreshWorktrees()
+		if p.OnRefresh != nil {
+			p.OnRefresh()
+		}
+	}()
+}
@@ -0,0 +1,155 @@
+package sourcecontrol
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// Worktree Naming Tests
+// =============================================================================
+
+func TestWorktreeBranchName(t *testing.T) {
+	now := time.Date(2026, 3, 4, 15, 6, 7, 0, time.Local)
+
+	assert.Equal(t, "thicc/claude-20260304-150607", WorktreeBranchName("claude", now))
+	assert.Equal(t, "thicc/gemini-cli-20260304-150607", WorktreeBranchName("Gemini CLI", now))
+	assert.Equal(t, "thicc/session-20260304-150607", WorktreeBranchName("~/!", now))
+}
+
+func TestParseWorktreeList_OnlyUnderBaseDir(t *testing.T) {
+	output := "worktree /src/project\nHEAD aaa\nbranch refs/heads/main\n\n" +
+		"worktree /cfg/worktrees/project/thicc-claude-1\nHEAD bbb\nbranch refs/heads/thicc/claude-1\n\n" +
+		"worktree /elsewhere/wt\nHEAD ccc\ndetached\n"
+
+	wts := parseWorktreeList(output, "/cfg/worktrees/project")
+	require.Len(t, wts, 1)
+	assert.Equal(t, "/cfg/worktrees/project/thicc-claude-1", wts[0].Path)
+	assert.Equal(t, "thicc/claude-1", wts[0].Branch)
+	assert.Equal(t, "bbb", wts[0].Head)
+}
+
+func TestIsUnder(t *testing.T) {
+	assert.True(t, isUnder("/a/b/c", "/a/b"))
+	assert.False(t, isUnder("/a/b", "/a/b"))
+	assert.False(t, isUnder("/a/bc", "/a/b"))
+	assert.False(t, isUnder("/a/b/c", ""))
+}
+
+// =============================================================================
+// Worktree Git Tests
+// =============================================================================
+
+func TestWorktree_CreateListAndDiff(t *testing.T) {
+	dir := initTestRepo(t)
+	base := t.TempDir()
+
+	wt, err := CreateWorktree(dir, base, "thicc/test-1")
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n", readFile(t, wt.Path, "main.go"))
+
+	// The session commits one change and leaves another uncommitted
+	writeFile(t, wt.Path, "main.go", "package main // committed\n")
+	_, err = gitOutput(wt.Path, nil, "commit", "-q", "-am", "agent work")
+	require.NoError(t, err)
+	writeFile(t, wt.Path, "new.go", "package main\n")
+
+	wts, err := ListWorktrees(dir, base)
+	require.NoError(t, err)
+	require.Len(t, wts, 1)
+	assert.Equal(t, "thicc/test-1", wts[0].Branch)
+	assert.Equal(t, 1, wts[0].Ahead)
+	assert.True(t, wts[0].Dirty)
+
+	patch, err := WorktreeDiff(dir, wts[0])
+	require.NoError(t, err)
+	assert.Contains(t, patch, "+package main // committed")
+	assert.Contains(t, patch, "b/new.go")
+
+	// The main checkout is untouched
+	assert.Equal(t, "package main\n", readFile(t, dir, "main.go"))
+}
+
+func TestMergeWorktree(t *testing.T) {
+	dir := initTestRepo(t)
+	base := t.TempDir()
+	wt, err := CreateWorktree(dir, base, "thicc/test-1")
+	require.NoError(t, err)
+	writeFile(t, wt.Path, "new.go", "package main\n")
+
+	require.NoError(t, MergeWorktree(dir, *wt))
+
+	assert.Equal(t, "package main\n", readFile(t, dir, "new.go"))
+	_, err = os.Stat(wt.Path)
+	assert.True(t, os.IsNotExist(err), "merged worktree is removed")
+	_, err = gitOutput(dir, nil, "rev-parse", "--verify", "refs/heads/thicc/test-1")
+	assert.Error(t, err, "merged branch is deleted")
+}
+
+func TestRemoveWorktree_Force(t *testing.T) {
+	dir := initTestRepo(t)
+	base := t.TempDir()
+	wt, err := CreateWorktree(dir, base, "thicc/test-1")
+	require.NoError(t, err)
+	writeFile(t, wt.Path, "main.go", "package main // unwanted\n")
+
+	// Without force, uncommitted work is protected
+	assert.Error(t, RemoveWorktree(dir, *wt, false))
+
+	require.NoError(t, RemoveWorktree(dir, *wt, true))
+	wts, err := ListWorktrees(dir, base)
+	require.NoError(t, err)
+	assert.Empty(t, wts)
+	assert.Equal(t, "package main\n", readFile(t, dir, "main.go"))
+}
+
+// =============================================================================
+// Worktree List Tests
+// =============================================================================
+
+func TestReviewSelectedWorktree(t *testing.T) {
+	dir := initTestRepo(t)
+	base := t.TempDir()
+	wt, err := CreateWorktree(dir, base, "thicc/test-1")
+	require.NoError(t, err)
+	writeFile(t, wt.Path, "main.go", "package main // agent\n")
+
+	var title, patch string
+	p := &Panel{RepoRoot: dir, WorktreeBaseDir: base}
+	p.OnShowDiff = func(tt, pp string) { title, patch = tt, pp }
+	p.ShowWorktreeList()
+	require.Len(t, p.Worktrees, 1)
+
+	p.ReviewSelectedWorktree()
+	assert.Equal(t, "thicc/test-1 (worktree)", title)
+	assert.Contains(t, patch, "+package main // agent")
+}
+
+func TestWorktreeConfirm_NeedsSelection(t *testing.T) {
+	p := &Panel{}
+	p.showWorktreeConfirm("discard")
+	assert.Empty(t, p.WorktreeConfirm)
+
+	p.Worktrees = []Worktree{{Branch: "thicc/a"}}
+	p.showWorktreeConfirm("discard")
+	assert.Equal(t, "discard", p.WorktreeConfirm)
+	p.hideWorktreeConfirm()
+	assert.Empty(t, p.WorktreeConfirm)
+}
+
+func TestWorktreeBaseDirIsSeparate(t *testing.T) {
+	// Worktrees live outside the project so they don't show up in its status
+	dir := initTestRepo(t)
+	wt, err := CreateWorktree(dir, filepath.Join(t.TempDir(), "wts"), "thicc/test-1")
+	require.NoError(t, err)
+
+	status, err := gitOutput(dir, nil, "status", "--porcelain")
+	require.NoError(t, err)
+	assert.Empty(t, status)
+	assert.False(t, isUnder(wt.Path, dir))
+}
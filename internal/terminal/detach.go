@@ -0,0 +1,171 @@
+package terminal
+
+import (
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/ellery/thicc/internal/sessiond"
+	"github.com/hinshun/vt10x"
+)
+
+// terminalEnv returns the environment for processes started in a terminal panel
+func terminalEnv() []string {
+	env := os.Environ()
+	env = append(env, "TERM=xterm-256color")
+	env = append(env, "THICC_TERM=1") // Marker so users can customize prompt in their shell config
+	return env
+}
+
+// ForegroundProcessName returns the name of the foreground process on a PTY.
+// Exported for the session daemon, which owns the PTYs of detachable terminals.
+func ForegroundProcessName(pty *os.File) string {
+	return getForegroundProcessName(pty)
+}
+
+// startDaemonSession starts cmdArgs in the session daemon, in dir if set
+func startDaemonSession(cmdArgs []string, slot, cols, rows int, dir string) (*sessiond.Conn, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		dir = cwd
+	}
+	return sessiond.Create(sessiond.CreateRequest{
+		Command: cmdArgs,
+		Dir:     dir,
+		Env:     terminalEnv(),
+		Root:    cwd,
+		Slot:    slot,
+		Cols:    cols,
+		Rows:    rows,
+	})
+}
+
+// AttachPanel creates a terminal panel attached to an existing daemon session.
+// The session's recent output is replayed, restoring its screen and scrollback.
+func AttachPanel(x, y, w, h int, id string) (*Panel, error) {
+	contentW := w - 2
+	contentH := h - 2
+	if contentW < 10 {
+		contentW = 10
+	}
+	if contentH < 5 {
+		contentH = 5
+	}
+
+	remote, err := sessiond.Attach(id, contentW, contentH)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("THICC: Attached to daemon session %s (%v)", id, remote.Info.Command)
+
+	// A shell command counts as "no command" for AI tool detection
+	var originalCmd []string
+	if !isShellCommand(remote.Info.Command) {
+		originalCmd = remote.Info.Command
+	}
+
+	settings := LoadSettings()
+	p := &Panel{
+		VT:              vt10x.New(vt10x.WithSize(contentW, contentH), vt10x.WithWriter(remote)),
+		Remote:          remote,
+		Region:          Region{X: x, Y: y, Width: w, Height: h},
+		Running:         true,
+		throttleDelay:   16 * time.Millisecond, // 60fps max
+		autoRespawn:     len(originalCmd) > 0,
+		OriginalCommand: originalCmd,
+		mouseReleased:   true,
+		Scrollback:      NewScrollbackBuffer(settings.ScrollbackLines),
+	}
+
+	go p.readLoop()
+	go p.loadingAnimationLoop()
+
+	return p, nil
+}
+
+// processIO returns what the panel reads output from and writes input to.
+// Must be called with p.mu held.
+func (p *Panel) processIO() io.ReadWriter {
+	if p.Remote != nil {
+		return p.Remote
+	}
+	if p.PTY != nil {
+		return p.PTY
+	}
+	return nil
+}
+
+// respawnRemoteShellLocked replaces an exited daemon session with a new shell
+// session. Must be called with p.mu held.
+func (p *Panel) respawnRemoteShellLocked() error {
+	contentW := p.Region.Width - 2
+	contentH := p.Region.Height - 2
+	if contentW < 10 {
+		contentW = 10
+	}
+	if contentH < 5 {
+		contentH = 5
+	}
+
+	slot := p.Remote.Info.Slot
+	p.Remote.Detach()
+	remote, err := startDaemonSession([]string{getDefaultShell()}, slot, contentW, contentH, p.Dir)
+	if err != nil {
+		p.Remote = nil
+		return err
+	}
+
+	p.VT = vt10x.New(vt10x.WithSize(contentW, contentH), vt10x.WithWriter(remote))
+	p.Remote = remote
+	p.Running = true
+	p.autoRespawn = false
+
+	p.Scrollback.Clear()
+	p.scrollOffset = 0
+	p.hasReceivedOutput = false
+	p.resetShellIntegration()
+	p.resetInputModes()
+
+	go p.readLoop()
+	go p.loadingAnimationLoop()
+	go p.injectSexyPrompt()
+
+	if p.OnRedraw != nil {
+		p.OnRedraw()
+	}
+	return nil
+}
+
+// IsDetachable returns true if the terminal's process runs in the session daemon
+func (p *Panel) IsDetachable() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.Remote != nil && p.Running
+}
+
+// Detach disconnects a detachable terminal from its daemon session, leaving
+// the process running so it can be reattached with `thicc --attach`.
+// For a local terminal this is the same as Close.
+func (p *Panel) Detach() {
+	p.mu.Lock()
+	remote := p.Remote
+	if remote == nil {
+		p.mu.Unlock()
+		p.Close()
+		return
+	}
+	p.detached = true
+	p.Running = false
+	if p.redrawTimer != nil {
+		p.redrawTimer.Stop()
+	}
+	p.stopAutoScrollLocked()
+	p.mu.Unlock()
+
+	log.Printf("THICC: Detaching from daemon session %s", remote.Info.ID)
+	remote.Detach()
+}
@@ -0,0 +1,150 @@
+package terminal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hinshun/vt10x"
+)
+
+// vt10x glyph attribute bits (unexported by vt10x). Reverse video is
+// already applied to the glyph's colors, so it isn't needed here.
+const (
+	glyphUnderline = 1 << 1
+	glyphBold      = 1 << 2
+	glyphItalic    = 1 << 4
+	glyphBlink     = 1 << 5
+	glyphWrap      = 1 << 6 // Line continues on the next row (auto-wrapped)
+
+	glyphStyleMask = glyphUnderline | glyphBold | glyphItalic | glyphBlink
+)
+
+// glyphStyle is the part of a glyph that SGR sequences describe
+type glyphStyle struct {
+	FG, BG vt10x.Color
+	Mode   int16
+}
+
+var defaultGlyphStyle = glyphStyle{FG: vt10x.DefaultFG, BG: vt10x.DefaultBG}
+
+// Export returns the scrollback plus the live screen as text, with lines the
+// terminal wrapped joined back together. With ansi, colors and text
+// attributes are kept as SGR escape sequences.
+func (p *Panel) Export(ansi bool) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var rows [][]vt10x.Glyph
+	for i := 0; i < p.Scrollback.Count(); i++ {
+		if line := p.Scrollback.Get(i); line != nil {
+			rows = append(rows, line.Cells)
+		}
+	}
+	cols, liveRows := p.VT.Size()
+	for y := 0; y < liveRows; y++ {
+		row := make([]vt10x.Glyph, cols)
+		for x := 0; x < cols; x++ {
+			row[x] = p.VT.Cell(x, y)
+		}
+		rows = append(rows, row)
+	}
+	// Drop the empty screen below the last output
+	for len(rows) > 0 && rowIsBlank(rows[len(rows)-1]) {
+		rows = rows[:len(rows)-1]
+	}
+
+	return exportRows(rows, ansi)
+}
+
+// exportRows renders terminal rows as text (see Export)
+func exportRows(rows [][]vt10x.Glyph, ansi bool) string {
+	var sb strings.Builder
+	style := defaultGlyphStyle
+
+	for _, row := range rows {
+		wrapped := len(row) > 0 && row[len(row)-1].Mode&glyphWrap != 0
+
+		// Trailing blanks are padding, unless the row wrapped (or is colored)
+		end := len(row)
+		if !wrapped {
+			for end > 0 && isPadding(row[end-1], ansi) {
+				end--
+			}
+		}
+
+		for _, g := range row[:end] {
+			if ansi {
+				gs := glyphStyle{FG: g.FG, BG: g.BG, Mode: g.Mode & glyphStyleMask}
+				if gs != style {
+					sb.WriteString(sgr(gs))
+					style = gs
+				}
+			}
+			if g.Char == 0 {
+				sb.WriteRune(' ')
+			} else {
+				sb.WriteRune(g.Char)
+			}
+		}
+
+		if wrapped {
+			continue
+		}
+		if style != defaultGlyphStyle {
+			sb.WriteString(sgr(defaultGlyphStyle))
+			style = defaultGlyphStyle
+		}
+		sb.WriteByte('\n')
+	}
+	if style != defaultGlyphStyle {
+		sb.WriteString(sgr(defaultGlyphStyle))
+	}
+	return sb.String()
+}
+
+// isPadding returns true for an empty cell (with ansi, only if it has no
+// background color)
+func isPadding(g vt10x.Glyph, ansi bool) bool {
+	if g.Char != 0 && g.Char != ' ' {
+		return false
+	}
+	return !ansi || g.BG == vt10x.DefaultBG
+}
+
+// sgr returns the escape sequence that switches to a style from any other
+func sgr(s glyphStyle) string {
+	params := []string{"0"}
+	if s.Mode&glyphBold != 0 {
+		params = append(params, "1")
+	}
+	if s.Mode&glyphItalic != 0 {
+		params = append(params, "3")
+	}
+	if s.Mode&glyphUnderline != 0 {
+		params = append(params, "4")
+	}
+	if s.Mode&glyphBlink != 0 {
+		params = append(params, "5")
+	}
+	if s.FG != vt10x.DefaultFG {
+		params = append(params, sgrColor(s.FG, 30))
+	}
+	if s.BG != vt10x.DefaultBG {
+		params = append(params, sgrColor(s.BG, 40))
+	}
+	return "\x1b[" + strings.Join(params, ";") + "m"
+}
+
+// sgrColor returns the SGR parameters for a vt10x color (base 30 for
+// foreground, 40 for background)
+func sgrColor(c vt10x.Color, base int) string {
+	switch {
+	case c < 8:
+		return fmt.Sprint(base + int(c))
+	case c < 16:
+		return fmt.Sprint(base + 60 + int(c) - 8) // Bright colors
+	case c < 256:
+		return fmt.Sprintf("%d;5;%d", base+8, c)
+	}
+	return fmt.Sprintf("%d;2;%d;%d;%d", base+8, (c>>16)&0xFF, (c>>8)&0xFF, c&0xFF)
+}
@@ -0,0 +1,49 @@
+package terminal
+
+import (
+	"testing"
+
+	"github.com/hinshun/vt10x"
+	"github.com/stretchr/testify/assert"
+)
+
+// =============================================================================
+// Export Tests
+// =============================================================================
+
+func TestExport_RejoinsWrappedLines(t *testing.T) {
+	p := newTestPanel(10, 4)
+	feed(p, "abcdefghijklmno\r\nshort\r\n")
+
+	assert.Equal(t, "abcdefghijklmno\nshort\n", p.Export(false))
+}
+
+func TestExport_IncludesScrollback(t *testing.T) {
+	p := newTestPanel(20, 4)
+	feedLines(p, "one", "two", "three", "four", "five")
+
+	assert.Greater(t, p.Scrollback.Count(), 0)
+	assert.Equal(t, "one\ntwo\nthree\nfour\nfive\n", p.Export(false))
+}
+
+func TestExport_KeepsBlankLinesBetweenOutput(t *testing.T) {
+	p := newTestPanel(20, 5)
+	feed(p, "a\r\n\r\nb")
+
+	assert.Equal(t, "a\n\nb\n", p.Export(false))
+}
+
+func TestExport_ANSI(t *testing.T) {
+	p := newTestPanel(20, 3)
+	feed(p, "\x1b[31mred\x1b[0m ok \x1b[1;4;38;5;200mx\x1b[0m")
+
+	assert.Equal(t, "\x1b[0;31mred\x1b[0m ok \x1b[0;1;4;38;5;200mx\x1b[0m\n", p.Export(true))
+	assert.Equal(t, "red ok x\n", p.Export(false))
+}
+
+func TestSGRColor(t *testing.T) {
+	assert.Equal(t, "32", sgrColor(2, 30))
+	assert.Equal(t, "101", sgrColor(9, 40))
+	assert.Equal(t, "38;5;123", sgrColor(123, 30))
+	assert.Equal(t, "48;2;1;2;3", sgrColor(vt10x.Color(1<<16|2<<8|3), 40))
+}
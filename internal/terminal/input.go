@@ -26,6 +26,18 @@ func (p *Panel) HandleEvent(event tcell.Event) bool {
 			return true
 		}
 
+		// Link mode: the next key picks a labelled file reference
+		if p.LinkMode {
+			p.handleLinkModeKey(ev)
+			return true
+		}
+
+		// Search mode: keys edit the query and move between matches
+		if p.SearchMode {
+			p.handleSearchKey(ev)
+			return true
+		}
+
 		// Ctrl+\ enters quick command mode
 		if ev.Key() == tcell.KeyCtrlBackslash {
 			p.QuickCommandMode = true
@@ -97,8 +109,7 @@ func (p *Panel) HandleEvent(event tcell.Event) bool {
 	case *tcell.EventPaste:
 		// Handle paste events directly (backup if layout manager doesn't catch it)
 		log.Printf("THICC Terminal: Paste event, len=%d", len(ev.Text()))
-		_, err := p.Write([]byte(ev.Text()))
-		return err == nil
+		return p.Paste(ev.Text()) == nil
 	case *tcell.EventMouse:
 		return p.handleMouse(ev)
 	}
@@ -108,6 +119,11 @@ func (p *Panel) HandleEvent(event tcell.Event) bool {
 
 // handleMouse processes mouse events for text selection and scrolling
 func (p *Panel) handleMouse(ev *tcell.EventMouse) bool {
+	// Apps that turned on mouse reporting get the mouse (Shift overrides)
+	if p.forwardMouse(ev) {
+		return true
+	}
+
 	// Handle scroll wheel (no lock needed, ScrollUp/Down handle it)
 	if ev.Buttons() == tcell.WheelUp {
 		p.ScrollUp(3) // Scroll 3 lines up (into history)
@@ -161,6 +177,13 @@ func (p *Panel) handleMouse(ev *tcell.EventMouse) bool {
 		y = contentH - 1
 	}
 
+	// Ctrl+click (Cmd+click where the terminal reports it) opens a file reference
+	if ev.Buttons() == tcell.Button1 && p.mouseReleased && ev.Modifiers()&(tcell.ModCtrl|tcell.ModMeta) != 0 {
+		if p.OpenRefAt(x, y) {
+			return true
+		}
+	}
+
 	if ev.Buttons() == tcell.Button1 {
 		// Convert screen Y to line index (absolute position in scrollback+live buffer)
 		scrollbackCount := p.Scrollback.Count()
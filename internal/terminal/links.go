@@ -0,0 +1,336 @@
+package terminal
+
+import (
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ellery/thicc/internal/config"
+	"github.com/micro-editor/tcell/v2"
+)
+
+// maxHyperlinks is how many OSC 8 hyperlinks are remembered per terminal
+const maxHyperlinks = 500
+
+// linkHintKeys labels file references in link mode, nearest the bottom first
+const linkHintKeys = "asdfghjklqwertyuiopzxcvbnm"
+
+// fileRefPattern matches path:line[:col] as printed by compilers, test
+// runners, linters and AI tools (e.g. "internal/foo/bar.go:12:5")
+var fileRefPattern = regexp.MustCompile(`([~\w.\-/]*[\w\-]\.[A-Za-z][\w]*):(\d+)(?::(\d+))?`)
+
+// lineFragmentPattern matches the line (and column) in a file:// link fragment
+// ("#L12", "#12", "#12:5")
+var lineFragmentPattern = regexp.MustCompile(`^L?(\d+)(?::(\d+))?$`)
+
+// FileRef is a reference to a location in a file, found in terminal output
+type FileRef struct {
+	Path string // As written in the output (resolved with ResolveRef)
+	Line int    // 1-based, 0 if not given
+	Col  int    // 1-based, 0 if not given
+
+	// Where the reference is on screen
+	LineIndex int // Line index in scrollback+live coordinates
+	X, EndX   int // Column range [X, EndX)
+}
+
+// hyperlink is an OSC 8 hyperlink. Lines are absolute (see PromptMark).
+type hyperlink struct {
+	URI               string
+	StartLine, StartX int
+	EndLine, EndX     int
+}
+
+// linkHint is a file reference labelled with a key in link mode
+type linkHint struct {
+	Key rune
+	Ref FileRef
+}
+
+// findFileRefs returns the path:line[:col] references in a line of text.
+// Positions are rune offsets, i.e. terminal columns.
+func findFileRefs(text string) []FileRef {
+	var refs []FileRef
+	for _, m := range fileRefPattern.FindAllStringSubmatchIndex(text, -1) {
+		ref := FileRef{
+			Path: text[m[2]:m[3]],
+			X:    utf8.RuneCountInString(text[:m[0]]),
+			EndX: utf8.RuneCountInString(text[:m[1]]),
+		}
+		ref.Line, _ = strconv.Atoi(text[m[4]:m[5]])
+		if m[6] >= 0 {
+			ref.Col, _ = strconv.Atoi(text[m[6]:m[7]])
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// parseFileURI turns an OSC 8 file:// URI into a file reference.
+// Returns false for other schemes.
+func parseFileURI(uri string) (FileRef, bool) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" || u.Path == "" {
+		return FileRef{}, false
+	}
+	ref := FileRef{Path: u.Path}
+	if m := lineFragmentPattern.FindStringSubmatch(u.Fragment); m != nil {
+		ref.Line, _ = strconv.Atoi(m[1])
+		ref.Col, _ = strconv.Atoi(m[2])
+	}
+	return ref, true
+}
+
+// lineText returns the text of a line in scrollback+live coordinates, one
+// rune per column. Must be called with p.mu held.
+func (p *Panel) lineText(lineIndex int) string {
+	cols, rows := p.VT.Size()
+	scrollbackCount := p.Scrollback.Count()
+
+	var sb strings.Builder
+	if lineIndex < 0 {
+		return ""
+	} else if lineIndex < scrollbackCount {
+		line := p.Scrollback.Get(lineIndex)
+		if line == nil {
+			return ""
+		}
+		for _, g := range line.Cells {
+			if g.Char == 0 {
+				sb.WriteRune(' ')
+			} else {
+				sb.WriteRune(g.Char)
+			}
+		}
+	} else if liveY := lineIndex - scrollbackCount; liveY < rows {
+		for x := 0; x < cols; x++ {
+			r := p.VT.Cell(x, liveY).Char
+			if r == 0 {
+				r = ' '
+			}
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// handleHyperlink records OSC 8 hyperlinks ("params;URI" opens one, an empty
+// URI closes it). Must be called with p.mu held.
+func (p *Panel) handleHyperlink(data string) {
+	_, uri, _ := strings.Cut(data, ";")
+	cursor := p.VT.Cursor()
+	line := p.Scrollback.Total() + cursor.Y
+
+	if p.openLink != nil && p.openLink.URI != "" {
+		p.openLink.EndLine = line
+		p.openLink.EndX = cursor.X
+		if p.openLink.EndLine > p.openLink.StartLine || p.openLink.EndX > p.openLink.StartX {
+			p.links = append(p.links, *p.openLink)
+			if len(p.links) > maxHyperlinks {
+				p.links = append([]hyperlink(nil), p.links[len(p.links)-maxHyperlinks:]...)
+			}
+		}
+		p.openLink = nil
+	}
+	if uri != "" {
+		p.openLink = &hyperlink{URI: uri, StartLine: line, StartX: cursor.X}
+	}
+}
+
+// hyperlinkAt returns the OSC 8 link covering a cell. Must be called with p.mu held.
+func (p *Panel) hyperlinkAt(x, lineIndex int) *hyperlink {
+	abs := lineIndex + p.Scrollback.Total() - p.Scrollback.Count()
+	for i := len(p.links) - 1; i >= 0; i-- {
+		l := &p.links[i]
+		if abs < l.StartLine || abs > l.EndLine {
+			continue
+		}
+		if abs == l.StartLine && x < l.StartX {
+			continue
+		}
+		if abs == l.EndLine && x >= l.EndX {
+			continue
+		}
+		return l
+	}
+	return nil
+}
+
+// fileRefAt returns the file reference (OSC 8 file link or path:line text)
+// at a cell. Must be called with p.mu held.
+func (p *Panel) fileRefAt(x, lineIndex int) (FileRef, bool) {
+	if l := p.hyperlinkAt(x, lineIndex); l != nil {
+		ref, ok := parseFileURI(l.URI)
+		if !ok {
+			log.Printf("THICC Terminal: Ignoring non-file hyperlink %q", l.URI)
+		}
+		return ref, ok
+	}
+	for _, ref := range findFileRefs(p.lineText(lineIndex)) {
+		if x >= ref.X && x < ref.EndX {
+			ref.LineIndex = lineIndex
+			return ref, true
+		}
+	}
+	return FileRef{}, false
+}
+
+// ResolveRef turns a reference's path into an absolute path to an existing
+// file, relative to the shell's working directory (OSC 7) or thicc's own
+func (p *Panel) ResolveRef(ref FileRef) (string, bool) {
+	return resolveRef(ref, p.Cwd())
+}
+
+// resolveRef resolves a reference's path against cwd ("" = thicc's cwd)
+func resolveRef(ref FileRef, cwd string) (string, bool) {
+	path := ref.Path
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	if !filepath.IsAbs(path) {
+		base := cwd
+		if base == "" {
+			base, _ = os.Getwd()
+		}
+		path = filepath.Join(base, path)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return "", false
+	}
+	return path, true
+}
+
+// openRef resolves a reference and hands it to OnOpenFile
+func (p *Panel) openRef(ref FileRef) bool {
+	path, ok := p.ResolveRef(ref)
+	if !ok {
+		log.Printf("THICC Terminal: File reference %q not found", ref.Path)
+		return false
+	}
+	log.Printf("THICC Terminal: Opening %s:%d:%d", path, ref.Line, ref.Col)
+	if p.OnOpenFile != nil {
+		p.OnOpenFile(path, ref.Line, ref.Col)
+	}
+	return true
+}
+
+// OpenRefAt opens the file reference at a content cell (screen row y, used
+// for Ctrl+click). Returns false if there is no existing file there.
+func (p *Panel) OpenRefAt(x, y int) bool {
+	p.mu.Lock()
+	lineIndex := p.Scrollback.Count() - p.scrollOffset + y
+	ref, ok := p.fileRefAt(x, lineIndex)
+	p.mu.Unlock()
+
+	if !ok {
+		return false
+	}
+	return p.openRef(ref)
+}
+
+// EnterLinkMode labels the file references on screen with keys; the next
+// key press opens the matching one. Returns the number of references.
+func (p *Panel) EnterLinkMode() int {
+	p.mu.Lock()
+	_, rows := p.VT.Size()
+	top := p.Scrollback.Count() - p.scrollOffset
+
+	var hints []linkHint
+	keys := []rune(linkHintKeys)
+	for y := rows - 1; y >= 0 && len(hints) < len(keys); y-- {
+		refs := findFileRefs(p.lineText(top + y))
+		for i := len(refs) - 1; i >= 0 && len(hints) < len(keys); i-- {
+			ref := refs[i]
+			ref.LineIndex = top + y
+			if _, ok := resolveRef(ref, p.cwd); ok {
+				hints = append(hints, linkHint{Key: keys[len(hints)], Ref: ref})
+			}
+		}
+	}
+	// OSC 8 file links on screen
+	abs := p.Scrollback.Total() - p.Scrollback.Count()
+	for i := len(p.links) - 1; i >= 0 && len(hints) < len(keys); i-- {
+		l := p.links[i]
+		y := l.StartLine - abs - top
+		if y < 0 || y >= rows {
+			continue
+		}
+		ref, ok := parseFileURI(l.URI)
+		if !ok {
+			continue
+		}
+		ref.LineIndex = top + y
+		ref.X = l.StartX
+		ref.EndX = l.StartX + 1
+		if l.EndLine == l.StartLine {
+			ref.EndX = l.EndX
+		}
+		hints = append(hints, linkHint{Key: keys[len(hints)], Ref: ref})
+	}
+
+	p.linkHints = hints
+	p.LinkMode = len(hints) > 0
+	p.mu.Unlock()
+
+	if p.LinkMode && p.OnShowMessage != nil {
+		p.OnShowMessage("  Press a highlighted key to open the file  |  Esc: Cancel")
+	}
+	if p.OnRedraw != nil {
+		p.OnRedraw()
+	}
+	return len(hints)
+}
+
+// handleLinkModeKey opens the reference labelled with the pressed key.
+// Any other key leaves link mode.
+func (p *Panel) handleLinkModeKey(ev *tcell.EventKey) {
+	p.mu.Lock()
+	hints := p.linkHints
+	p.LinkMode = false
+	p.linkHints = nil
+	p.mu.Unlock()
+
+	if p.OnShowMessage != nil {
+		p.OnShowMessage("")
+	}
+	if ev.Key() == tcell.KeyRune {
+		for _, h := range hints {
+			if h.Key == ev.Rune() {
+				p.openRef(h.Ref)
+				break
+			}
+		}
+	}
+	if p.OnRedraw != nil {
+		p.OnRedraw()
+	}
+}
+
+// drawLinkHints underlines labelled references and draws their keys
+// (link mode). Must be called with p.mu held.
+func (p *Panel) drawLinkHints(screen tcell.Screen, contentX, contentY, contentW, contentH int) {
+	top := p.Scrollback.Count() - p.scrollOffset
+	labelStyle := config.DefStyle.Foreground(tcell.ColorBlack).Background(tcell.ColorYellow).Bold(true)
+
+	for _, h := range p.linkHints {
+		y := h.Ref.LineIndex - top
+		if y < 0 || y >= contentH {
+			continue
+		}
+		for x := h.Ref.X; x < h.Ref.EndX && x < contentW; x++ {
+			r, _, style, _ := screen.GetContent(contentX+x, contentY+y)
+			screen.SetContent(contentX+x, contentY+y, r, nil, style.Underline(true))
+		}
+		if h.Ref.X < contentW {
+			screen.SetContent(contentX+h.Ref.X, contentY+y, h.Key, nil, labelStyle)
+		}
+	}
+}
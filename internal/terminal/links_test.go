@@ -0,0 +1,119 @@
+package terminal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/micro-editor/tcell/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// =============================================================================
+// File Reference Detection Tests
+// =============================================================================
+
+func TestFindFileRefs_CompilerOutput(t *testing.T) {
+	refs := findFileRefs("./internal/foo/bar.go:12:5: undefined: baz")
+	assert.Len(t, refs, 1)
+	assert.Equal(t, "./internal/foo/bar.go", refs[0].Path)
+	assert.Equal(t, 12, refs[0].Line)
+	assert.Equal(t, 5, refs[0].Col)
+	assert.Equal(t, 0, refs[0].X)
+	assert.Equal(t, 26, refs[0].EndX)
+}
+
+func TestFindFileRefs_LineOnlyAndMultiple(t *testing.T) {
+	refs := findFileRefs("    panel_test.go:42: expected 1 (see /tmp/x.py:7)")
+	assert.Len(t, refs, 2)
+	assert.Equal(t, "panel_test.go", refs[0].Path)
+	assert.Equal(t, 42, refs[0].Line)
+	assert.Equal(t, 0, refs[0].Col)
+	assert.Equal(t, 4, refs[0].X)
+	assert.Equal(t, "/tmp/x.py", refs[1].Path)
+	assert.Equal(t, 7, refs[1].Line)
+}
+
+func TestFindFileRefs_ColumnsCountRunes(t *testing.T) {
+	refs := findFileRefs("✗ main.go:3")
+	assert.Len(t, refs, 1)
+	assert.Equal(t, 2, refs[0].X)
+	assert.Equal(t, 11, refs[0].EndX)
+}
+
+func TestFindFileRefs_IgnoresPlainText(t *testing.T) {
+	assert.Empty(t, findFileRefs("listening on localhost:8080 at 12:30"))
+}
+
+func TestParseFileURI(t *testing.T) {
+	ref, ok := parseFileURI("file://host/src/app/main.go#L20")
+	assert.True(t, ok)
+	assert.Equal(t, "/src/app/main.go", ref.Path)
+	assert.Equal(t, 20, ref.Line)
+
+	ref, ok = parseFileURI("file:///a%20b.txt#3:4")
+	assert.True(t, ok)
+	assert.Equal(t, "/a b.txt", ref.Path)
+	assert.Equal(t, 3, ref.Line)
+	assert.Equal(t, 4, ref.Col)
+
+	_, ok = parseFileURI("https://example.com")
+	assert.False(t, ok)
+}
+
+func TestResolveRef_RelativeToCwd(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "pkg"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "pkg", "a.go"), []byte("package pkg\n"), 0644))
+
+	path, ok := resolveRef(FileRef{Path: "pkg/a.go"}, dir)
+	assert.True(t, ok)
+	assert.Equal(t, filepath.Join(dir, "pkg", "a.go"), path)
+
+	_, ok = resolveRef(FileRef{Path: "pkg/missing.go"}, dir)
+	assert.False(t, ok)
+	_, ok = resolveRef(FileRef{Path: "pkg"}, dir)
+	assert.False(t, ok)
+}
+
+// =============================================================================
+// Hyperlink and Link Mode Tests
+// =============================================================================
+
+func TestHyperlinks_TrackedFromOSC8(t *testing.T) {
+	p := newTestPanel(40, 5)
+	feed(p, "see \x1b]8;;file:///tmp/a.go#L3\x07a.go\x1b]8;;\x07 here")
+
+	assert.NotNil(t, p.hyperlinkAt(4, 0))
+	assert.NotNil(t, p.hyperlinkAt(7, 0))
+	assert.Nil(t, p.hyperlinkAt(3, 0))
+	assert.Nil(t, p.hyperlinkAt(8, 0))
+
+	ref, ok := p.fileRefAt(5, 0)
+	assert.True(t, ok)
+	assert.Equal(t, "/tmp/a.go", ref.Path)
+	assert.Equal(t, 3, ref.Line)
+}
+
+func TestLinkMode_OpensLabelledRef(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "a.go"), []byte("x\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "b.go"), []byte("x\n"), 0644))
+
+	p := newTestPanel(40, 5)
+	feed(p, "\x1b]7;file://host"+dir+"\x07a.go:1: first\r\nb.go:2:3: second\r\nnope.go:9: gone")
+
+	var opened string
+	var line, col int
+	p.OnOpenFile = func(path string, l, c int) { opened, line, col = path, l, c }
+
+	// Nearest the bottom gets the first key; missing files get no label
+	assert.Equal(t, 2, p.EnterLinkMode())
+	assert.True(t, p.LinkMode)
+
+	p.handleLinkModeKey(tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModNone, ""))
+	assert.False(t, p.LinkMode)
+	assert.Equal(t, filepath.Join(dir, "b.go"), opened)
+	assert.Equal(t, 2, line)
+	assert.Equal(t, 3, col)
+}
@@ -0,0 +1,270 @@
+package terminal
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/hinshun/vt10x"
+	"github.com/micro-editor/tcell/v2"
+)
+
+// Applications in the PTY can ask for mouse events (DECSET 9/1000/1002/1003,
+// SGR encoding with 1006) and bracketed paste (DECSET 2004). vt10x tracks the
+// mouse modes itself; bracketed paste is picked out of the output here.
+// Holding Shift always gives the mouse back to thicc (selection, scrollback).
+
+const (
+	pasteStart = "\x1b[200~"
+	pasteEnd   = "\x1b[201~"
+)
+
+// wheelButtons are the scroll wheel "buttons" in a tcell mouse event
+const wheelButtons = tcell.WheelUp | tcell.WheelDown | tcell.WheelLeft | tcell.WheelRight
+
+// modeChange is a DEC private mode set (CSI ? n h) or reset (CSI ? n l)
+type modeChange struct {
+	Mode int
+	Set  bool
+}
+
+// modeScanner finds DEC private mode changes in PTY output.
+// Sequences split across reads are stitched together.
+type modeScanner struct {
+	state  int // 0=ground, 1=after ESC, 2=after ESC [, 3=in params
+	params []byte
+}
+
+// Feed scans a chunk of output and returns the mode changes in it
+func (s *modeScanner) Feed(data []byte) []modeChange {
+	var changes []modeChange
+	for _, b := range data {
+		switch s.state {
+		case 0:
+			if b == 0x1b {
+				s.state = 1
+			}
+		case 1:
+			if b == '[' {
+				s.state = 2
+			} else if b != 0x1b {
+				s.state = 0
+			}
+		case 2:
+			if b == '?' {
+				s.state = 3
+				s.params = s.params[:0]
+			} else if b == 0x1b {
+				s.state = 1
+			} else {
+				s.state = 0
+			}
+		case 3:
+			switch {
+			case (b >= '0' && b <= '9') || b == ';':
+				if len(s.params) < 64 {
+					s.params = append(s.params, b)
+				}
+			case b == 'h' || b == 'l':
+				for _, param := range strings.Split(string(s.params), ";") {
+					if mode, err := strconv.Atoi(param); err == nil {
+						changes = append(changes, modeChange{Mode: mode, Set: b == 'h'})
+					}
+				}
+				s.state = 0
+			case b == 0x1b:
+				s.state = 1
+			default:
+				s.state = 0
+			}
+		}
+	}
+	return changes
+}
+
+// trackModes updates the modes vt10x doesn't track. Must be called with p.mu held.
+func (p *Panel) trackModes(data []byte) {
+	for _, c := range p.modes.Feed(data) {
+		if c.Mode == 2004 {
+			p.bracketedPaste = c.Set
+		}
+	}
+}
+
+// resetInputModes forgets application modes (after the process is replaced)
+func (p *Panel) resetInputModes() {
+	p.modes = modeScanner{}
+	p.bracketedPaste = false
+	p.mouseButtons = tcell.ButtonNone
+}
+
+// BracketedPaste returns true if the application asked for bracketed paste
+func (p *Panel) BracketedPaste() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.bracketedPaste
+}
+
+// MouseReporting returns true if the application asked for mouse events
+func (p *Panel) MouseReporting() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.VT.Mode()&vt10x.ModeMouseMask != 0
+}
+
+// Paste sends text to the PTY, wrapped in paste markers if the application
+// asked for bracketed paste
+func (p *Panel) Paste(text string) error {
+	_, err := p.Write(pasteBytes(text, p.BracketedPaste()))
+	return err
+}
+
+// pasteBytes returns the bytes to send for a paste
+func pasteBytes(text string, bracketed bool) []byte {
+	if !bracketed {
+		return []byte(text)
+	}
+	// An end marker inside the text would let the rest run as typed input
+	text = strings.ReplaceAll(text, pasteEnd, "")
+	return []byte(pasteStart + text + pasteEnd)
+}
+
+// mouseButtonCode returns the xterm button number for the first pressed
+// button (0 left, 1 middle, 2 right), or -1 if none is pressed
+func mouseButtonCode(buttons tcell.ButtonMask) int {
+	switch {
+	case buttons&tcell.Button1 != 0:
+		return 0
+	case buttons&tcell.Button3 != 0:
+		return 1
+	case buttons&tcell.Button2 != 0:
+		return 2
+	}
+	return -1
+}
+
+// encodeMouse encodes a mouse event for an application in the given mouse
+// mode. prev is the buttons held at the previous event, so releases and drags
+// can be told apart (tcell reports a release as ButtonNone). x and y are
+// 0-based content cells. Returns nil if the mode doesn't report the event.
+func encodeMouse(mode vt10x.ModeFlag, prev, buttons tcell.ButtonMask, mods tcell.ModMask, x, y int) []byte {
+	x10 := mode&vt10x.ModeMouseX10 != 0
+	motion := mode&(vt10x.ModeMouseMotion|vt10x.ModeMouseMany) != 0
+	release := false
+
+	var code int
+	switch wheel := buttons & wheelButtons; {
+	case wheel != 0:
+		if x10 {
+			return nil
+		}
+		switch {
+		case wheel&tcell.WheelUp != 0:
+			code = 64
+		case wheel&tcell.WheelDown != 0:
+			code = 65
+		case wheel&tcell.WheelLeft != 0:
+			code = 66
+		default:
+			code = 67
+		}
+	default:
+		btn := mouseButtonCode(buttons)
+		prevBtn := mouseButtonCode(prev)
+		switch {
+		case btn >= 0 && prev&buttons&^wheelButtons == 0:
+			code = btn // Press
+		case btn >= 0:
+			if !motion {
+				return nil
+			}
+			code = btn + 32 // Drag
+		case prevBtn >= 0:
+			if x10 {
+				return nil
+			}
+			code = prevBtn
+			release = true
+		default:
+			if mode&vt10x.ModeMouseMany == 0 {
+				return nil
+			}
+			code = 3 + 32 // Motion with no button
+		}
+	}
+
+	if !x10 {
+		if mods&tcell.ModShift != 0 {
+			code += 4
+		}
+		if mods&(tcell.ModAlt|tcell.ModMeta) != 0 {
+			code += 8
+		}
+		if mods&tcell.ModCtrl != 0 {
+			code += 16
+		}
+	}
+
+	if mode&vt10x.ModeMouseSgr != 0 {
+		final := 'M'
+		if release {
+			final = 'm'
+		}
+		return []byte(fmt.Sprintf("\x1b[<%d;%d;%d%c", code, x+1, y+1, final))
+	}
+
+	// Legacy encoding: one byte per value (offset by 32), releases don't say
+	// which button
+	if release {
+		code = code&^3 | 3
+	}
+	if x+1+32 > 255 || y+1+32 > 255 {
+		return nil
+	}
+	return []byte{0x1b, '[', 'M', byte(code + 32), byte(x + 1 + 32), byte(y + 1 + 32)}
+}
+
+// forwardMouse sends a mouse event to the application if it asked for mouse
+// events. Returns false if thicc should handle the event itself: the app
+// doesn't use the mouse, Shift is held, the view is scrolled into history or
+// a thicc selection drag is in progress.
+func (p *Panel) forwardMouse(ev *tcell.EventMouse) bool {
+	p.mu.Lock()
+	mode := p.VT.Mode()
+	prev := p.mouseButtons
+	if mode&vt10x.ModeMouseMask == 0 || p.scrollOffset > 0 {
+		p.mouseButtons = tcell.ButtonNone
+		p.mu.Unlock()
+		return false
+	}
+	// A press already sent to the app keeps its drag and release
+	if prev == tcell.ButtonNone && (ev.Modifiers()&tcell.ModShift != 0 || !p.mouseReleased) {
+		p.mu.Unlock()
+		return false
+	}
+
+	mouseX, mouseY := ev.Position()
+	x := mouseX - (p.Region.X + 1)
+	y := mouseY - (p.Region.Y + 1)
+	cols, rows := p.VT.Size()
+	if prev == tcell.ButtonNone && (x < 0 || y < 0 || x >= cols || y >= rows) {
+		p.mu.Unlock()
+		return false // Border
+	}
+	x = max(0, min(x, cols-1))
+	y = max(0, min(y, rows-1))
+
+	buttons := ev.Buttons()
+	data := encodeMouse(mode, prev, buttons, ev.Modifiers(), x, y)
+	p.mouseButtons = buttons &^ wheelButtons
+	p.mu.Unlock()
+
+	if data != nil {
+		if _, err := p.Write(data); err != nil {
+			log.Printf("THICC Terminal: Failed to forward mouse event: %v", err)
+		}
+	}
+	// Consume everything the app owns, even events its mode doesn't report
+	return buttons != tcell.ButtonNone || prev != tcell.ButtonNone
+}
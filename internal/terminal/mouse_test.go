@@ -0,0 +1,149 @@
+package terminal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/hinshun/vt10x"
+	"github.com/micro-editor/tcell/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withPipe gives a test panel a fake process; returns what the app would read
+func withPipe(t *testing.T, p *Panel) *os.File {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	t.Cleanup(func() { r.Close(); w.Close() })
+	p.PTY = w
+	p.Running = true
+	p.mouseReleased = true
+	return r
+}
+
+// readPipe reads what has been written to the fake process
+func readPipe(t *testing.T, r *os.File) string {
+	buf := make([]byte, 256)
+	n, err := r.Read(buf)
+	require.NoError(t, err)
+	return string(buf[:n])
+}
+
+// =============================================================================
+// Mode Scanner Tests
+// =============================================================================
+
+func TestModeScanner_SetAndReset(t *testing.T) {
+	var s modeScanner
+	changes := s.Feed([]byte("x\x1b[?2004hy\x1b[?1000;1006lz\x1b[2J"))
+
+	assert.Equal(t, []modeChange{
+		{Mode: 2004, Set: true},
+		{Mode: 1000, Set: false},
+		{Mode: 1006, Set: false},
+	}, changes)
+}
+
+func TestModeScanner_SplitReads(t *testing.T) {
+	var s modeScanner
+	assert.Empty(t, s.Feed([]byte("\x1b[?20")))
+	assert.Equal(t, []modeChange{{Mode: 2004, Set: true}}, s.Feed([]byte("04h")))
+}
+
+func TestBracketedPaste_TrackedFromOutput(t *testing.T) {
+	p := newTestPanel(20, 5)
+	feed(p, "\x1b[?2004h")
+	assert.True(t, p.BracketedPaste())
+	feed(p, "\x1b[?2004l")
+	assert.False(t, p.BracketedPaste())
+}
+
+// =============================================================================
+// Paste Tests
+// =============================================================================
+
+func TestPasteBytes(t *testing.T) {
+	assert.Equal(t, "ls\n", string(pasteBytes("ls\n", false)))
+	assert.Equal(t, "\x1b[200~ls\n\x1b[201~", string(pasteBytes("ls\n", true)))
+	// A pasted end marker can't break out of the paste
+	assert.Equal(t, "\x1b[200~arm\x1b[201~", string(pasteBytes("a\x1b[201~rm", true)))
+}
+
+func TestPaste_WrapsWhenAppAsks(t *testing.T) {
+	p := newTestPanel(20, 5)
+	r := withPipe(t, p)
+
+	feed(p, "\x1b[?2004h")
+	require.NoError(t, p.Paste("echo hi"))
+	assert.Equal(t, "\x1b[200~echo hi\x1b[201~", readPipe(t, r))
+}
+
+// =============================================================================
+// Mouse Encoding Tests
+// =============================================================================
+
+func TestEncodeMouse_SGR(t *testing.T) {
+	mode := vt10x.ModeMouseButton | vt10x.ModeMouseSgr
+
+	assert.Equal(t, "\x1b[<0;3;2M", string(encodeMouse(mode, tcell.ButtonNone, tcell.Button1, 0, 2, 1)))
+	assert.Equal(t, "\x1b[<0;3;2m", string(encodeMouse(mode, tcell.Button1, tcell.ButtonNone, 0, 2, 1)))
+	assert.Equal(t, "\x1b[<2;1;1M", string(encodeMouse(mode, tcell.ButtonNone, tcell.Button2, 0, 0, 0)))
+	assert.Equal(t, "\x1b[<64;1;1M", string(encodeMouse(mode, tcell.ButtonNone, tcell.WheelUp, 0, 0, 0)))
+	assert.Equal(t, "\x1b[<17;1;1M", string(encodeMouse(mode, tcell.ButtonNone, tcell.Button3, tcell.ModCtrl, 0, 0)))
+
+	// Drags and plain motion need 1002/1003
+	assert.Nil(t, encodeMouse(mode, tcell.Button1, tcell.Button1, 0, 4, 1))
+	assert.Nil(t, encodeMouse(mode, tcell.ButtonNone, tcell.ButtonNone, 0, 4, 1))
+}
+
+func TestEncodeMouse_Motion(t *testing.T) {
+	drag := vt10x.ModeMouseMotion | vt10x.ModeMouseSgr
+	assert.Equal(t, "\x1b[<32;5;2M", string(encodeMouse(drag, tcell.Button1, tcell.Button1, 0, 4, 1)))
+	assert.Nil(t, encodeMouse(drag, tcell.ButtonNone, tcell.ButtonNone, 0, 4, 1))
+
+	all := vt10x.ModeMouseMany | vt10x.ModeMouseSgr
+	assert.Equal(t, "\x1b[<35;5;2M", string(encodeMouse(all, tcell.ButtonNone, tcell.ButtonNone, 0, 4, 1)))
+}
+
+func TestEncodeMouse_Legacy(t *testing.T) {
+	mode := vt10x.ModeMouseButton
+
+	assert.Equal(t, []byte{0x1b, '[', 'M', 32, 33 + 2, 33 + 1}, encodeMouse(mode, tcell.ButtonNone, tcell.Button1, 0, 2, 1))
+	// Releases don't say which button
+	assert.Equal(t, []byte{0x1b, '[', 'M', 32 + 3, 33, 33}, encodeMouse(mode, tcell.Button2, tcell.ButtonNone, 0, 0, 0))
+	// Coordinates past 222 can't be encoded
+	assert.Nil(t, encodeMouse(mode, tcell.ButtonNone, tcell.Button1, 0, 300, 0))
+}
+
+func TestEncodeMouse_X10PressOnly(t *testing.T) {
+	mode := vt10x.ModeMouseX10
+
+	assert.Equal(t, []byte{0x1b, '[', 'M', 32, 33, 33}, encodeMouse(mode, tcell.ButtonNone, tcell.Button1, tcell.ModCtrl, 0, 0))
+	assert.Nil(t, encodeMouse(mode, tcell.Button1, tcell.ButtonNone, 0, 0, 0))
+	assert.Nil(t, encodeMouse(mode, tcell.ButtonNone, tcell.WheelUp, 0, 0, 0))
+}
+
+// =============================================================================
+// Mouse Forwarding Tests
+// =============================================================================
+
+func TestForwardMouse_WhenAppAsks(t *testing.T) {
+	p := newTestPanel(20, 5)
+	p.Region = Region{X: 10, Y: 2, Width: 22, Height: 7}
+	r := withPipe(t, p)
+
+	// No mouse mode - thicc keeps the mouse
+	assert.False(t, p.forwardMouse(tcell.NewEventMouse(13, 4, tcell.Button1, 0, "")))
+
+	feed(p, "\x1b[?1000h\x1b[?1006h")
+	assert.True(t, p.MouseReporting())
+	assert.True(t, p.forwardMouse(tcell.NewEventMouse(13, 4, tcell.Button1, 0, "")))
+	assert.Equal(t, "\x1b[<0;3;2M", readPipe(t, r))
+	assert.True(t, p.forwardMouse(tcell.NewEventMouse(13, 4, tcell.ButtonNone, 0, "")))
+	assert.Equal(t, "\x1b[<0;3;2m", readPipe(t, r))
+
+	// Shift forces thicc-side selection
+	assert.False(t, p.forwardMouse(tcell.NewEventMouse(13, 4, tcell.Button1, tcell.ModShift, "")))
+	// So does a click on the border
+	assert.False(t, p.forwardMouse(tcell.NewEventMouse(10, 4, tcell.Button1, 0, "")))
+}
@@ -12,6 +12,8 @@ import (
 
 	"github.com/creack/pty"
 	"github.com/ellery/thicc/internal/screen"
+	"github.com/ellery/thicc/internal/sessiond"
+	"github.com/ellery/thicc/internal/thicc"
 	"github.com/hinshun/vt10x"
 	"github.com/micro-editor/tcell/v2"
 )
@@ -251,6 +253,11 @@ type Panel struct {
 	// nil/empty means default shell, non-empty non-shell means AI tool
 	OriginalCommand []string
 
+	// Remote is set when the process runs in the session daemon (detachable
+	// terminals) instead of on a PTY owned by this panel
+	Remote   *sessiond.Conn
+	detached bool // Detach() was called - the process keeps running in the daemon
+
 	// Selection state for copy/paste
 	Selection          [2]Loc // Start and end of selection (Y is lineIndex into scrollback+live buffer)
 	mouseReleased      bool   // Track mouse button state for drag detection
@@ -261,6 +268,34 @@ type Panel struct {
 	scrollOffset   int               // 0 = live view, >0 = scrolled up N lines
 	previousScreen [][]vt10x.Glyph   // All rows before VT.Write for scroll detection
 
+	// Shell integration (see shell_integration.go)
+	osc   oscScanner   // Finds OSC 7/133 sequences in PTY output
+	cwd   string       // Working directory reported by OSC 7
+	marks []PromptMark // Prompt/command/output marks from OSC 133
+
+	// File references and OSC 8 hyperlinks (see links.go)
+	links     []hyperlink // Finished OSC 8 hyperlinks
+	openLink  *hyperlink  // OSC 8 hyperlink still being printed
+	linkHints []linkHint  // Labelled references while in link mode
+	// LinkMode is true while file references are labelled for keyboard opening
+	LinkMode bool
+	// OnOpenFile is called to open a file reference (1-based line/col, 0 if unknown)
+	OnOpenFile func(path string, line, col int)
+
+	// Scrollback search (see search.go)
+	searchQuery   []rune        // What the user has typed
+	searchRegex   bool          // Query is a regular expression
+	searchErr     error         // Invalid regex
+	searchMatches []searchMatch // All matches, oldest first
+	searchCurrent int           // Index into searchMatches, -1 if none
+	// SearchMode is true while typing a search; keys edit the query
+	SearchMode bool
+
+	// Application input modes (see mouse.go)
+	modes          modeScanner      // Finds DEC private mode changes in PTY output
+	bracketedPaste bool             // DECSET 2004 - wrap pastes in markers
+	mouseButtons   tcell.ButtonMask // Buttons held in the last event forwarded to the app
+
 	// AI tool activity tracking
 	lastOutputTime time.Time // When we last received PTY output
 	lastInputTime  time.Time // When user last sent input (to filter out echo)
@@ -282,6 +317,13 @@ type Panel struct {
 	OnNextPane func()
 	// OnSessionEnd callback when terminal process exits (for auto-hiding the pane)
 	OnSessionEnd func()
+	// OnToolExit callback when the AI tool exits and a shell replaces it
+	OnToolExit func()
+
+	// Dir is the working directory processes start in ("" = thicc's own)
+	Dir string
+	// Title is shown in the top border (e.g. the branch of a worktree session)
+	Title string
 
 	// Auto-scroll state for drag-to-select
 	autoScrollDirection int         // -1=up, 0=none, 1=down
@@ -304,6 +346,19 @@ func isShellCommand(cmdArgs []string) bool {
 // NewPanel creates a new terminal panel
 // cmdArgs is the command to run (defaults to user's shell if nil/empty)
 func NewPanel(x, y, w, h int, cmdArgs []string) (*Panel, error) {
+	return NewPanelForSlot(x, y, w, h, cmdArgs, 0)
+}
+
+// NewPanelForSlot creates a terminal panel for a layout slot (2-4, or 0 if none).
+// When detachable terminals are enabled the process is started in the session
+// daemon, tagged with the slot and working directory so it can be reattached.
+func NewPanelForSlot(x, y, w, h int, cmdArgs []string, slot int) (*Panel, error) {
+	return NewPanelInDir(x, y, w, h, cmdArgs, slot, "")
+}
+
+// NewPanelInDir is NewPanelForSlot with the process started in dir
+// (e.g. a git worktree). An empty dir uses thicc's working directory.
+func NewPanelInDir(x, y, w, h int, cmdArgs []string, slot int, dir string) (*Panel, error) {
 	// Store original command before any modifications (for AI tool detection)
 	var originalCmd []string
 	if cmdArgs != nil && len(cmdArgs) > 0 {
@@ -347,41 +402,61 @@ func NewPanel(x, y, w, h int, cmdArgs []string) (*Panel, error) {
 		contentH = 5
 	}
 
-	// Create VT emulator with content size (not full region)
-	// IMPORTANT: Must create command/PTY first, then pass PTY as writer to vt10x
-	// This is a two-step process due to initialization order
+	// Detachable terminals run in the session daemon; fall back to a local
+	// PTY if the daemon can't be reached
+	var remote *sessiond.Conn
+	if thicc.GetDetachableTerminals() {
+		var err error
+		remote, err = startDaemonSession(cmdArgs, slot, contentW, contentH, dir)
+		if err != nil {
+			log.Printf("THICC: Session daemon unavailable, using local PTY: %v", err)
+			remote = nil
+		} else {
+			log.Printf("THICC: Started daemon session %s for %v", remote.Info.ID, cmdArgs)
+		}
+	}
 
-	// Create command
-	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	var cmd *exec.Cmd
+	var ptmx *os.File
+	var vtWriter io.Writer = remote
+	if remote == nil {
+		// Create VT emulator with content size (not full region)
+		// IMPORTANT: Must create command/PTY first, then pass PTY as writer to vt10x
+		// This is a two-step process due to initialization order
 
-	// Set up environment
-	cmd.Env = os.Environ()
-	cmd.Env = append(cmd.Env, "TERM=xterm-256color")
-	cmd.Env = append(cmd.Env, "THICC_TERM=1") // Marker so users can customize prompt in their shell config
+		// Create command
+		cmd = exec.Command(cmdArgs[0], cmdArgs[1:]...)
 
-	// Start command with PTY at the correct size from the beginning
-	// This prevents apps from rendering at default size then re-rendering on SIGWINCH
-	winSize := &pty.Winsize{
-		Rows: uint16(contentH),
-		Cols: uint16(contentW),
-	}
-	ptmx, err := pty.StartWithSize(cmd, winSize)
-	if err != nil {
-		return nil, err
-	}
+		// Set up environment
+		cmd.Env = terminalEnv()
+		cmd.Dir = dir
 
-	if debugPTY && debugFile != nil {
-		fmt.Fprintf(debugFile, "=== TERMINAL CREATED ===\n")
-		fmt.Fprintf(debugFile, "Command: %v\n", cmdArgs)
-		fmt.Fprintf(debugFile, "Region: x=%d y=%d w=%d h=%d\n", x, y, w, h)
-		fmt.Fprintf(debugFile, "Content size: %dx%d (cols x rows)\n", contentW, contentH)
-		fmt.Fprintf(debugFile, "PTY Winsize: %d cols x %d rows\n", winSize.Cols, winSize.Rows)
-		fmt.Fprintf(debugFile, "=== END ===\n\n")
-		debugFile.Sync()
+		// Start command with PTY at the correct size from the beginning
+		// This prevents apps from rendering at default size then re-rendering on SIGWINCH
+		winSize := &pty.Winsize{
+			Rows: uint16(contentH),
+			Cols: uint16(contentW),
+		}
+		var err error
+		ptmx, err = pty.StartWithSize(cmd, winSize)
+		if err != nil {
+			return nil, err
+		}
+		vtWriter = ptmx
+
+		if debugPTY && debugFile != nil {
+			fmt.Fprintf(debugFile, "=== TERMINAL CREATED ===\n")
+			fmt.Fprintf(debugFile, "Command: %v\n", cmdArgs)
+			fmt.Fprintf(debugFile, "Region: x=%d y=%d w=%d h=%d\n", x, y, w, h)
+			fmt.Fprintf(debugFile, "Content size: %dx%d (cols x rows)\n", contentW, contentH)
+			fmt.Fprintf(debugFile, "PTY Winsize: %d cols x %d rows\n", winSize.Cols, winSize.Rows)
+			fmt.Fprintf(debugFile, "=== END ===\n\n")
+			debugFile.Sync()
+		}
 	}
 
 	// Create VT emulator with PTY as writer (enables DSR/CPR responses)
-	vt := vt10x.New(vt10x.WithSize(contentW, contentH), vt10x.WithWriter(ptmx))
+	vt := vt10x.New(vt10x.WithSize(contentW, contentH), vt10x.WithWriter(vtWriter))
 
 	// Load settings and create scrollback buffer
 	settings := LoadSettings()
@@ -390,12 +465,14 @@ func NewPanel(x, y, w, h int, cmdArgs []string) (*Panel, error) {
 		VT:              vt,
 		PTY:             ptmx,
 		Cmd:             cmd,
+		Remote:          remote,
 		Region:          Region{X: x, Y: y, Width: w, Height: h},
 		Running:         true,
 		Focus:           false,
 		throttleDelay:   16 * time.Millisecond, // 60fps max
 		autoRespawn:     autoRespawn,
 		OriginalCommand: originalCmd,
+		Dir:             dir,
 		mouseReleased:   true, // Start with mouse released
 		Scrollback:      NewScrollbackBuffer(settings.ScrollbackLines),
 		scrollOffset:    0,
@@ -428,16 +505,21 @@ func (p *Panel) injectSexyPrompt() {
 	time.Sleep(1000 * time.Millisecond)
 
 	p.mu.Lock()
-	if !p.Running || p.PTY == nil {
+	pty := p.processIO()
+	if !p.Running || pty == nil {
 		log.Printf("THICC: injectSexyPrompt - shell not running or PTY nil, aborting")
 		p.mu.Unlock()
 		return
 	}
-	pty := p.PTY
 	p.mu.Unlock()
 
-	// Get the prompt init command
-	initCmd := getPromptInitCommand()
+	// Get the prompt init command, plus hooks that report cwd and prompt marks
+	// (fish can't source the bash-syntax prompt, so it only gets the hooks)
+	shellName := filepath.Base(getDefaultShell())
+	initCmd := getShellIntegrationCommand(shellName)
+	if shellName != "fish" {
+		initCmd = getPromptInitCommand() + initCmd
+	}
 
 	// Write prompt config to temp file, then source it
 	// This is more reliable than sending raw commands which might get
@@ -490,21 +572,37 @@ func getDefaultShell() string {
 
 // readLoop continuously reads from PTY and writes to VT emulator
 func (p *Panel) readLoop() {
+	p.mu.Lock()
+	src := p.processIO()
+	p.mu.Unlock()
+	if src == nil {
+		return
+	}
+
 	buf := make([]byte, 4096)
 	for {
-		n, err := p.PTY.Read(buf)
+		n, err := src.Read(buf)
 		if err != nil {
 			// EOF or error - terminal closed
 			p.mu.Lock()
 			shouldRespawn := p.autoRespawn
+			detached := p.detached
 			p.Running = false
 			p.mu.Unlock()
 
+			// Detached on purpose - the process lives on in the daemon
+			if detached {
+				return
+			}
+
 			// If auto-respawn is enabled (AI tool was running), spawn a new shell
 			if shouldRespawn {
 				// Small delay to let the process fully exit
 				time.Sleep(100 * time.Millisecond)
 				p.RespawnShell()
+				if p.OnToolExit != nil {
+					p.OnToolExit()
+				}
 			} else {
 				// Notify layout manager to hide this terminal pane
 				if p.OnSessionEnd != nil {
@@ -551,8 +649,9 @@ func (p *Panel) readLoop() {
 			// (CPR = Cursor Position Report, response to ESC[6n query)
 			filtered := filterCPRResponses(buf[:n])
 
-			// Write to VT emulator
-			p.VT.Write(filtered)
+			// Write to VT emulator (picking out shell integration marks)
+			p.writeVT(filtered)
+			p.trackModes(filtered)
 
 			// Check if scroll occurred and capture scrolled lines
 			p.captureScrolledLines()
@@ -590,6 +689,16 @@ func rowsMatch(row1, row2 []vt10x.Glyph) bool {
 	return true
 }
 
+// rowIsBlank checks if a row has no visible content
+func rowIsBlank(row []vt10x.Glyph) bool {
+	for _, g := range row {
+		if g.Char != 0 && g.Char != ' ' {
+			return false
+		}
+	}
+	return true
+}
+
 // captureScrolledLines detects scrolled lines and adds them to scrollback
 func (p *Panel) captureScrolledLines() {
 	if len(p.previousScreen) == 0 {
@@ -614,7 +723,10 @@ func (p *Panel) captureScrolledLines() {
 
 	// Strategy 1: Look for old row 0 somewhere in new screen
 	// If found at newY, then newY lines scrolled off
-	for newY := 1; newY < rows; newY++ {
+	// (blank rows match each other anywhere, so they can't anchor a match -
+	// otherwise the first output on an empty screen looks like a scroll)
+	anchorBlank := rowIsBlank(p.previousScreen[0]) && (rows < 2 || rowIsBlank(p.previousScreen[1]))
+	for newY := 1; newY < rows && !anchorBlank; newY++ {
 		newRow := make([]vt10x.Glyph, cols)
 		for x := 0; x < cols; x++ {
 			newRow[x] = p.VT.Cell(x, newY)
@@ -644,7 +756,16 @@ func (p *Panel) captureScrolledLines() {
 
 	// Strategy 2: Old row 0 not visible - check if any old row is now at new row 0
 	// If old row N is at new row 0, then N lines scrolled off entirely
-	for oldY := 1; oldY < len(p.previousScreen); oldY++ {
+	// (again, a blank top row can't anchor a match - e.g. after a clear)
+	topBlank := rowIsBlank(currentTopRow)
+	if topBlank && rows > 1 {
+		nextRow := make([]vt10x.Glyph, cols)
+		for x := 0; x < cols; x++ {
+			nextRow[x] = p.VT.Cell(x, 1)
+		}
+		topBlank = rowIsBlank(nextRow)
+	}
+	for oldY := 1; oldY < len(p.previousScreen) && !topBlank; oldY++ {
 		if rowsMatch(currentTopRow, p.previousScreen[oldY]) {
 			// Verify with next row
 			if oldY+1 < len(p.previousScreen) {
@@ -681,6 +802,9 @@ func (p *Panel) RespawnShell() error {
 		p.PTY.Close()
 		p.PTY = nil
 	}
+	if p.Remote != nil {
+		return p.respawnRemoteShellLocked()
+	}
 
 	// Get content dimensions
 	contentW := p.Region.Width - 2
@@ -695,9 +819,8 @@ func (p *Panel) RespawnShell() error {
 	// Create new shell command
 	shell := getDefaultShell()
 	cmd := exec.Command(shell)
-	cmd.Env = os.Environ()
-	cmd.Env = append(cmd.Env, "TERM=xterm-256color")
-	cmd.Env = append(cmd.Env, "THICC_TERM=1")
+	cmd.Env = terminalEnv()
+	cmd.Dir = p.Dir
 
 	// Start with new PTY
 	ptmx, err := pty.Start(cmd)
@@ -722,6 +845,8 @@ func (p *Panel) RespawnShell() error {
 	// Clear scrollback on respawn
 	p.Scrollback.Clear()
 	p.scrollOffset = 0
+	p.resetShellIntegration()
+	p.resetInputModes()
 	p.hasReceivedOutput = false // Reset for new loading indicator
 
 	// Start new read loop
@@ -769,14 +894,15 @@ func (p *Panel) Write(data []byte) (int, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	if !p.Running || p.PTY == nil {
+	pty := p.processIO()
+	if !p.Running || pty == nil {
 		return 0, io.ErrClosedPipe
 	}
 
 	// Track input time to distinguish AI output from keystroke echo
 	p.lastInputTime = time.Now()
 
-	return p.PTY.Write(data)
+	return pty.Write(data)
 }
 
 // Resize changes the terminal size
@@ -820,6 +946,9 @@ func (p *Panel) Resize(w, h int) error {
 			Cols: uint16(contentW),
 		})
 	}
+	if p.Remote != nil {
+		_ = p.Remote.Resize(contentW, contentH)
+	}
 
 	return nil
 }
@@ -846,6 +975,11 @@ func (p *Panel) Close() {
 	if p.Cmd != nil && p.Cmd.Process != nil {
 		_ = p.Cmd.Process.Kill()
 	}
+
+	// Closing a detachable terminal ends its daemon session too
+	if p.Remote != nil {
+		_ = p.Remote.Kill()
+	}
 }
 
 // IsRunning returns whether the terminal command is still running
@@ -886,14 +1020,20 @@ func (p *Panel) IsAIToolActive() bool {
 func (p *Panel) GetToolName() string {
 	p.mu.Lock()
 	pty := p.PTY
+	remote := p.Remote
 	running := p.Running
 	p.mu.Unlock()
 
-	if !running || pty == nil {
+	if !running || (pty == nil && remote == nil) {
 		return "Shell"
 	}
 
-	procName := getForegroundProcessName(pty)
+	var procName string
+	if remote != nil {
+		procName = remote.Foreground()
+	} else {
+		procName = getForegroundProcessName(pty)
+	}
 	if procName == "" {
 		return "Shell"
 	}
@@ -917,17 +1057,23 @@ func isAIToolProcess(name string) bool {
 func (p *Panel) GetForegroundAITool() string {
 	p.mu.Lock()
 	pty := p.PTY
+	remote := p.Remote
 	running := p.Running
 	p.mu.Unlock()
 
-	log.Printf("THICC: GetForegroundAITool: running=%v, pty=%v", running, pty != nil)
+	log.Printf("THICC: GetForegroundAITool: running=%v, pty=%v, remote=%v", running, pty != nil, remote != nil)
 
-	if !running || pty == nil {
+	if !running || (pty == nil && remote == nil) {
 		log.Println("THICC: GetForegroundAITool: early return - not running or no PTY")
 		return ""
 	}
 
-	procName := getForegroundProcessName(pty)
+	var procName string
+	if remote != nil {
+		procName = remote.Foreground()
+	} else {
+		procName = getForegroundProcessName(pty)
+	}
 	log.Printf("THICC: getForegroundProcessName returned: %q", procName)
 
 	if procName == "" {
@@ -17,6 +17,7 @@ type ScrollbackBuffer struct {
 	capacity int // Maximum lines (default 10000)
 	start    int // Index of oldest line in circular buffer
 	count    int // Current number of lines stored
+	total    int // Lines pushed since the last Clear (including evicted ones)
 	mu       sync.RWMutex
 }
 
@@ -38,6 +39,7 @@ func (sb *ScrollbackBuffer) Push(line ScrollbackLine) {
 	sb.mu.Lock()
 	defer sb.mu.Unlock()
 
+	sb.total++
 	if sb.count < sb.capacity {
 		// Buffer not full - add at next position
 		sb.lines[sb.count] = line
@@ -77,6 +79,15 @@ func (sb *ScrollbackBuffer) Clear() {
 	defer sb.mu.Unlock()
 	sb.start = 0
 	sb.count = 0
+	sb.total = 0
+}
+
+// Total returns the number of lines pushed since the last Clear, including
+// lines that have been evicted. Used to give lines stable absolute numbers.
+func (sb *ScrollbackBuffer) Total() int {
+	sb.mu.RLock()
+	defer sb.mu.RUnlock()
+	return sb.total
 }
 
 // Capacity returns the maximum number of lines the buffer can hold
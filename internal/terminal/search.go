@@ -0,0 +1,302 @@
+package terminal
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ellery/thicc/internal/clipboard"
+	"github.com/ellery/thicc/internal/config"
+	"github.com/hinshun/vt10x"
+	"github.com/micro-editor/tcell/v2"
+)
+
+// searchMatch is a match of the scrollback search. Line is absolute (see
+// PromptMark) so matches stay put as new output scrolls the buffer.
+type searchMatch struct {
+	Line    int
+	X, EndX int // Column range [X, EndX)
+}
+
+// compileSearch builds the matcher for a search query. Plain queries match
+// literally; both kinds are case-insensitive unless the query has an
+// uppercase letter. Returns nil for an empty query.
+func compileSearch(query string, useRegex bool) (*regexp.Regexp, error) {
+	if query == "" {
+		return nil, nil
+	}
+	expr := query
+	if !useRegex {
+		expr = regexp.QuoteMeta(query)
+	}
+	if strings.IndexFunc(query, unicode.IsUpper) < 0 {
+		expr = "(?i)" + expr
+	}
+	return regexp.Compile(expr)
+}
+
+// findMatches returns the column ranges of re's matches in a line of text
+// (one rune per column). Empty matches are skipped.
+func findMatches(re *regexp.Regexp, text string) [][2]int {
+	var ranges [][2]int
+	for _, m := range re.FindAllStringIndex(text, -1) {
+		if m[1] == m[0] {
+			continue
+		}
+		start := utf8.RuneCountInString(text[:m[0]])
+		ranges = append(ranges, [2]int{start, start + utf8.RuneCountInString(text[m[0]:m[1]])})
+	}
+	return ranges
+}
+
+// EnterSearchMode starts an incremental search of the scrollback and screen
+func (p *Panel) EnterSearchMode() {
+	p.mu.Lock()
+	p.SearchMode = true
+	p.searchQuery = nil
+	p.searchErr = nil
+	p.searchMatches = nil
+	p.searchCurrent = -1
+	prompt := p.searchPromptLocked()
+	p.mu.Unlock()
+
+	log.Println("THICC Terminal: Entered search mode")
+	if p.OnShowMessage != nil {
+		p.OnShowMessage(prompt)
+	}
+	if p.OnRedraw != nil {
+		p.OnRedraw()
+	}
+}
+
+// ExitSearchMode leaves search mode, keeping the current scroll position
+func (p *Panel) ExitSearchMode() {
+	p.mu.Lock()
+	p.SearchMode = false
+	p.searchMatches = nil
+	p.searchCurrent = -1
+	p.mu.Unlock()
+
+	if p.OnShowMessage != nil {
+		p.OnShowMessage("")
+	}
+	if p.OnRedraw != nil {
+		p.OnRedraw()
+	}
+}
+
+// handleSearchKey edits the query, moves between matches or copies one
+func (p *Panel) handleSearchKey(ev *tcell.EventKey) {
+	switch ev.Key() {
+	case tcell.KeyEscape:
+		p.ExitSearchMode()
+		return
+	case tcell.KeyEnter, tcell.KeyUp:
+		p.SearchPrevious()
+	case tcell.KeyDown:
+		p.SearchNext()
+	case tcell.KeyCtrlR:
+		p.mu.Lock()
+		p.searchRegex = !p.searchRegex
+		p.runSearchLocked()
+		p.mu.Unlock()
+	case tcell.KeyCtrlC, tcell.KeyCtrlL:
+		text := p.searchCopyText(ev.Key() == tcell.KeyCtrlL)
+		if text == "" {
+			return
+		}
+		clipboard.Write(text, clipboard.ClipboardReg)
+		log.Printf("THICC Terminal: Copied %d chars from search match", len(text))
+		p.ExitSearchMode()
+		return
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		p.mu.Lock()
+		if n := len(p.searchQuery); n > 0 {
+			p.searchQuery = p.searchQuery[:n-1]
+			p.runSearchLocked()
+		}
+		p.mu.Unlock()
+	case tcell.KeyRune:
+		if ev.Modifiers()&(tcell.ModCtrl|tcell.ModAlt|tcell.ModMeta) != 0 {
+			return
+		}
+		p.mu.Lock()
+		p.searchQuery = append(p.searchQuery, ev.Rune())
+		p.runSearchLocked()
+		p.mu.Unlock()
+	default:
+		return
+	}
+
+	p.mu.Lock()
+	prompt := p.searchPromptLocked()
+	p.mu.Unlock()
+	if p.OnShowMessage != nil {
+		p.OnShowMessage(prompt)
+	}
+	if p.OnRedraw != nil {
+		p.OnRedraw()
+	}
+}
+
+// runSearchLocked finds all matches of the query and picks the current one:
+// the nearest at or above the previous match (or the bottom of the view), so
+// typing narrows the search without jumping around. Must be called with p.mu held.
+func (p *Panel) runSearchLocked() {
+	cols, rows := p.VT.Size()
+	count := p.Scrollback.Count()
+	offset := p.Scrollback.Total() - count
+
+	anchor := searchMatch{Line: offset + count - p.scrollOffset + rows - 1, X: cols}
+	if p.searchCurrent >= 0 && p.searchCurrent < len(p.searchMatches) {
+		anchor = p.searchMatches[p.searchCurrent]
+	}
+
+	p.searchMatches = nil
+	p.searchCurrent = -1
+	re, err := compileSearch(string(p.searchQuery), p.searchRegex)
+	p.searchErr = err
+	if re == nil {
+		return
+	}
+
+	first := 0
+	if p.VT.Mode()&vt10x.ModeAltScreen != 0 {
+		first = count // Fullscreen apps don't show scrollback
+	}
+	for lineIndex := first; lineIndex < count+rows; lineIndex++ {
+		for _, r := range findMatches(re, p.lineText(lineIndex)) {
+			p.searchMatches = append(p.searchMatches, searchMatch{Line: offset + lineIndex, X: r[0], EndX: r[1]})
+		}
+	}
+	if len(p.searchMatches) == 0 {
+		return
+	}
+
+	p.searchCurrent = 0
+	for i, m := range p.searchMatches {
+		if m.Line < anchor.Line || (m.Line == anchor.Line && m.X <= anchor.X) {
+			p.searchCurrent = i
+		}
+	}
+	p.scrollToMatchLocked()
+}
+
+// SearchPrevious moves to the previous (older) match, wrapping around
+func (p *Panel) SearchPrevious() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if n := len(p.searchMatches); n > 0 {
+		p.searchCurrent = (p.searchCurrent - 1 + n) % n
+		p.scrollToMatchLocked()
+	}
+}
+
+// SearchNext moves to the next (newer) match, wrapping around
+func (p *Panel) SearchNext() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if n := len(p.searchMatches); n > 0 {
+		p.searchCurrent = (p.searchCurrent + 1) % n
+		p.scrollToMatchLocked()
+	}
+}
+
+// scrollToMatchLocked scrolls the current match into view, centering it if
+// it was off screen. Must be called with p.mu held.
+func (p *Panel) scrollToMatchLocked() {
+	if p.searchCurrent < 0 || p.VT.Mode()&vt10x.ModeAltScreen != 0 {
+		return
+	}
+	lineIndex := p.absToLineIndex(p.searchMatches[p.searchCurrent].Line)
+	if lineIndex < 0 {
+		return // Evicted from scrollback
+	}
+
+	_, rows := p.VT.Size()
+	count := p.Scrollback.Count()
+	top := count - p.scrollOffset
+	if lineIndex >= top && lineIndex < top+rows {
+		return
+	}
+	offset := count - (lineIndex - rows/2)
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > count {
+		offset = count
+	}
+	p.scrollOffset = offset
+}
+
+// searchCopyText returns the current match's text, or its whole line
+func (p *Panel) searchCopyText(wholeLine bool) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.searchCurrent < 0 {
+		return ""
+	}
+	m := p.searchMatches[p.searchCurrent]
+	lineIndex := p.absToLineIndex(m.Line)
+	if lineIndex < 0 {
+		return ""
+	}
+	line := []rune(p.lineText(lineIndex))
+	if wholeLine {
+		return strings.TrimRight(string(line), " ")
+	}
+	if m.EndX > len(line) {
+		return ""
+	}
+	return string(line[m.X:m.EndX])
+}
+
+// searchPromptLocked returns the search bar text. Must be called with p.mu held.
+func (p *Panel) searchPromptLocked() string {
+	kind := "Search"
+	if p.searchRegex {
+		kind = "Regex"
+	}
+	status := ""
+	switch {
+	case p.searchErr != nil:
+		status = "  (invalid regex)"
+	case len(p.searchQuery) == 0:
+	case len(p.searchMatches) == 0:
+		status = "  (no matches)"
+	default:
+		status = fmt.Sprintf("  [%d/%d]", p.searchCurrent+1, len(p.searchMatches))
+	}
+	return fmt.Sprintf("  %s: %s%s  |  Enter/↑: Older  ↓: Newer  ^R: Regex  ^C: Copy  ^L: Copy Line  Esc: Done",
+		kind, string(p.searchQuery), status)
+}
+
+// drawSearchMatches highlights the visible matches, the current one brighter.
+// Must be called with p.mu held.
+func (p *Panel) drawSearchMatches(screen tcell.Screen, contentX, contentY, contentW, contentH int) {
+	top := p.Scrollback.Count() - p.scrollOffset
+	if p.VT.Mode()&vt10x.ModeAltScreen != 0 {
+		top = p.Scrollback.Count()
+	}
+	matchStyle := config.DefStyle.Foreground(tcell.ColorBlack).Background(tcell.ColorYellow)
+	currentStyle := config.DefStyle.Foreground(tcell.ColorBlack).Background(tcell.ColorOrange).Bold(true)
+
+	for i, m := range p.searchMatches {
+		y := p.absToLineIndex(m.Line) - top
+		if y < 0 || y >= contentH {
+			continue
+		}
+		style := matchStyle
+		if i == p.searchCurrent {
+			style = currentStyle
+		}
+		for x := m.X; x < m.EndX && x < contentW; x++ {
+			r, _, _, _ := screen.GetContent(contentX+x, contentY+y)
+			screen.SetContent(contentX+x, contentY+y, r, nil, style)
+		}
+	}
+}
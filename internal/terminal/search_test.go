@@ -0,0 +1,149 @@
+package terminal
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/micro-editor/tcell/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// typeSearch enters search mode and types a query
+func typeSearch(p *Panel, query string) {
+	p.EnterSearchMode()
+	for _, r := range query {
+		p.handleSearchKey(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone, ""))
+	}
+}
+
+// feedLines writes numbered lines one at a time so they scroll into scrollback
+func feedLines(p *Panel, lines ...string) {
+	for _, line := range lines {
+		feed(p, line+"\r\n")
+	}
+}
+
+// =============================================================================
+// Matching Tests
+// =============================================================================
+
+func TestCompileSearch_SmartCase(t *testing.T) {
+	re, err := compileSearch("error", false)
+	require.NoError(t, err)
+	assert.True(t, re.MatchString("ERROR: boom"))
+
+	re, err = compileSearch("Error", false)
+	require.NoError(t, err)
+	assert.False(t, re.MatchString("ERROR: boom"))
+	assert.True(t, re.MatchString("Error: boom"))
+}
+
+func TestCompileSearch_PlainIsLiteral(t *testing.T) {
+	re, err := compileSearch("a.b", false)
+	require.NoError(t, err)
+	assert.False(t, re.MatchString("axb"))
+
+	re, err = compileSearch("a.b", true)
+	require.NoError(t, err)
+	assert.True(t, re.MatchString("axb"))
+
+	_, err = compileSearch("(", true)
+	assert.Error(t, err)
+
+	re, err = compileSearch("", true)
+	assert.NoError(t, err)
+	assert.Nil(t, re)
+}
+
+func TestFindMatches_RuneColumns(t *testing.T) {
+	re, _ := compileSearch("ok", false)
+	assert.Equal(t, [][2]int{{2, 4}, {7, 9}}, findMatches(re, "✓ ok ✓ ok"))
+
+	re, _ = compileSearch("x*", true)
+	assert.Empty(t, findMatches(re, "abc"))
+}
+
+// =============================================================================
+// Search Mode Tests
+// =============================================================================
+
+func TestSearch_FindsAcrossScrollbackAndScreen(t *testing.T) {
+	p := newTestPanel(20, 4)
+	for i := 0; i < 10; i++ {
+		feedLines(p, fmt.Sprintf("line %d", i))
+	}
+	require.Greater(t, p.Scrollback.Count(), 0)
+
+	typeSearch(p, "line")
+	assert.True(t, p.SearchMode)
+	assert.Len(t, p.searchMatches, 10)
+	// Starts at the newest match
+	assert.Equal(t, 9, p.searchCurrent)
+	assert.Contains(t, p.searchPromptLocked(), "[10/10]")
+}
+
+func TestSearch_NavigationScrollsToMatch(t *testing.T) {
+	p := newTestPanel(20, 4)
+	feedLines(p, "needle one")
+	for i := 0; i < 20; i++ {
+		feedLines(p, fmt.Sprintf("hay %d", i))
+	}
+	feedLines(p, "needle two")
+
+	typeSearch(p, "needle")
+	require.Len(t, p.searchMatches, 2)
+	assert.Equal(t, 1, p.searchCurrent)
+	assert.Equal(t, 0, p.scrollOffset)
+
+	p.handleSearchKey(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone, ""))
+	assert.Equal(t, 0, p.searchCurrent)
+	assert.Greater(t, p.scrollOffset, 0)
+	top := p.Scrollback.Count() - p.scrollOffset
+	lineIndex := p.absToLineIndex(p.searchMatches[0].Line)
+	assert.True(t, lineIndex >= top && lineIndex < top+4)
+
+	// Wraps around to the newest
+	p.handleSearchKey(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone, ""))
+	assert.Equal(t, 1, p.searchCurrent)
+	p.handleSearchKey(tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone, ""))
+	assert.Equal(t, 0, p.searchCurrent)
+}
+
+func TestSearch_RegexToggleAndBackspace(t *testing.T) {
+	p := newTestPanel(30, 4)
+	feedLines(p, "exit 1", "exit 42")
+
+	typeSearch(p, `\d+`)
+	assert.Empty(t, p.searchMatches)
+	assert.Contains(t, p.searchPromptLocked(), "(no matches)")
+
+	p.handleSearchKey(tcell.NewEventKey(tcell.KeyCtrlR, 0, tcell.ModCtrl, ""))
+	assert.True(t, p.searchRegex)
+	assert.Len(t, p.searchMatches, 2)
+
+	p.handleSearchKey(tcell.NewEventKey(tcell.KeyBackspace2, 0, tcell.ModNone, ""))
+	assert.Equal(t, `\d`, string(p.searchQuery))
+	assert.Len(t, p.searchMatches, 3)
+}
+
+func TestSearch_CopyText(t *testing.T) {
+	p := newTestPanel(30, 4)
+	feedLines(p, "FAIL: TestThing (0.01s)")
+
+	typeSearch(p, "TestThing")
+	assert.Equal(t, "TestThing", p.searchCopyText(false))
+	assert.Equal(t, "FAIL: TestThing (0.01s)", p.searchCopyText(true))
+}
+
+func TestSearch_ExitClearsMatches(t *testing.T) {
+	p := newTestPanel(30, 4)
+	feedLines(p, strings.Repeat("ab ", 3))
+
+	typeSearch(p, "ab")
+	assert.Len(t, p.searchMatches, 3)
+	p.handleSearchKey(tcell.NewEventKey(tcell.KeyEscape, 0, tcell.ModNone, ""))
+	assert.False(t, p.SearchMode)
+	assert.Empty(t, p.searchMatches)
+}
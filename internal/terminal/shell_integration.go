@@ -0,0 +1,380 @@
+package terminal
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ellery/thicc/internal/config"
+	"github.com/micro-editor/tcell/v2"
+)
+
+// Shell integration: shells report their working directory with OSC 7 and
+// mark prompts, commands and command output with OSC 133 (FinalTerm semantic
+// prompts). vt10x ignores both, so readLoop scans for them on the way in.
+
+// maxOSCLength caps a buffered OSC sequence so a missing terminator can't grow forever
+const maxOSCLength = 4096
+
+// maxPromptMarks is how many prompts are remembered per terminal
+const maxPromptMarks = 1000
+
+// oscSequence is a complete OSC sequence found in PTY output
+type oscSequence struct {
+	End  int    // Offset just past the terminator in the chunk passed to Feed
+	Code string // Numeric command ("7", "133", ...)
+	Data string // Everything after "<code>;"
+}
+
+// oscScanner finds OSC sequences (ESC ] ... BEL or ESC \) in PTY output.
+// Sequences split across reads are stitched together.
+type oscScanner struct {
+	state int // 0=ground, 1=after ESC, 2=in OSC, 3=ESC inside OSC
+	buf   []byte
+}
+
+// Feed scans a chunk of output and returns the OSC sequences that end in it
+func (s *oscScanner) Feed(data []byte) []oscSequence {
+	var seqs []oscSequence
+	for i, b := range data {
+		switch s.state {
+		case 0:
+			if b == 0x1b {
+				s.state = 1
+			}
+		case 1:
+			if b == ']' {
+				s.state = 2
+				s.buf = s.buf[:0]
+			} else if b != 0x1b {
+				s.state = 0
+			}
+		case 2:
+			switch b {
+			case 0x07:
+				seqs = s.finish(seqs, i+1)
+			case 0x1b:
+				s.state = 3
+			default:
+				if len(s.buf) < maxOSCLength {
+					s.buf = append(s.buf, b)
+				} else {
+					s.state = 0 // Runaway sequence - give up on it
+				}
+			}
+		case 3:
+			if b == '\\' {
+				seqs = s.finish(seqs, i+1)
+			} else if b == ']' {
+				// ESC ] inside an OSC starts a new one
+				s.state = 2
+				s.buf = s.buf[:0]
+			} else {
+				s.state = 0
+			}
+		}
+	}
+	return seqs
+}
+
+// finish records the buffered sequence and returns to ground state
+func (s *oscScanner) finish(seqs []oscSequence, end int) []oscSequence {
+	s.state = 0
+	code, data, _ := strings.Cut(string(s.buf), ";")
+	return append(seqs, oscSequence{End: end, Code: code, Data: data})
+}
+
+// PromptMark records where a prompt, its command line and the command's
+// output are. Lines are absolute: they count every line that has scrolled
+// into scrollback, so marks stay put as new output arrives (see absToLineIndex).
+type PromptMark struct {
+	Prompt   int // OSC 133;A - prompt start
+	Command  int // OSC 133;B - command input start (-1 until seen)
+	Output   int // OSC 133;C - output start (-1 until the command runs)
+	End      int // OSC 133;D - output end (-1 while running)
+	ExitCode int // From OSC 133;D;<status>
+}
+
+// writeVT writes PTY output to the VT emulator, handling shell integration
+// sequences at the point they occur so marks land on the right line.
+// Must be called with p.mu held, after captureScreenBefore.
+func (p *Panel) writeVT(data []byte) {
+	prev := 0
+	for _, seq := range p.osc.Feed(data) {
+		if seq.Code != "7" && seq.Code != "8" && seq.Code != "133" {
+			continue
+		}
+		p.VT.Write(data[prev:seq.End])
+		p.captureScrolledLines()
+		p.captureScreenBefore()
+		p.handleOSC(seq)
+		prev = seq.End
+	}
+	p.VT.Write(data[prev:])
+}
+
+// handleOSC applies a shell integration sequence. Must be called with p.mu held.
+func (p *Panel) handleOSC(seq oscSequence) {
+	switch seq.Code {
+	case "7":
+		// file://host/path
+		u, err := url.Parse(seq.Data)
+		if err != nil || u.Scheme != "file" || u.Path == "" {
+			log.Printf("THICC Terminal: Ignoring OSC 7 %q", seq.Data)
+			return
+		}
+		p.cwd = u.Path
+	case "8":
+		p.handleHyperlink(seq.Data)
+	case "133":
+		p.handleSemanticPrompt(seq.Data)
+	}
+}
+
+// handleSemanticPrompt records an OSC 133 mark at the cursor line
+func (p *Panel) handleSemanticPrompt(data string) {
+	kind, params, _ := strings.Cut(data, ";")
+	line := p.Scrollback.Total() + p.VT.Cursor().Y
+
+	var last *PromptMark
+	if len(p.marks) > 0 {
+		last = &p.marks[len(p.marks)-1]
+	}
+
+	switch kind {
+	case "A":
+		// A prompt at or above an earlier one means the screen was cleared
+		// or redrawn - those marks no longer point at their text
+		n := len(p.marks)
+		for n > 0 && p.marks[n-1].Prompt >= line {
+			n--
+		}
+		p.marks = p.marks[:n]
+		p.marks = append(p.marks, PromptMark{Prompt: line, Command: -1, Output: -1, End: -1})
+		if len(p.marks) > maxPromptMarks {
+			p.marks = append([]PromptMark(nil), p.marks[len(p.marks)-maxPromptMarks:]...)
+		}
+	case "B":
+		if last != nil {
+			last.Command = line
+		}
+	case "C":
+		if last != nil {
+			last.Output = line
+			last.End = -1
+		}
+	case "D":
+		// Shells also send D before the first prompt, with no command run
+		if last != nil && last.Output >= 0 && last.End < 0 {
+			last.End = line
+			status, _, _ := strings.Cut(params, ";")
+			last.ExitCode, _ = strconv.Atoi(status)
+		}
+	}
+}
+
+// absToLineIndex converts an absolute line to a line index in the
+// scrollback+live coordinate space used by selection and scrolling.
+// Returns a negative index if the line has been evicted from scrollback.
+func (p *Panel) absToLineIndex(abs int) int {
+	return abs - (p.Scrollback.Total() - p.Scrollback.Count())
+}
+
+// resetShellIntegration forgets marks and cwd (after the shell is replaced)
+func (p *Panel) resetShellIntegration() {
+	p.marks = nil
+	p.cwd = ""
+	p.osc = oscScanner{}
+	p.links = nil
+	p.openLink = nil
+}
+
+// Cwd returns the shell's working directory as reported by OSC 7,
+// or "" if the shell hasn't reported one
+func (p *Panel) Cwd() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.cwd
+}
+
+// HasShellIntegration returns true once the shell has sent prompt marks
+func (p *Panel) HasShellIntegration() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.marks) > 0
+}
+
+// LastExitCode returns the exit status of the last finished command
+func (p *Panel) LastExitCode() (int, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lastExitCodeLocked()
+}
+
+func (p *Panel) lastExitCodeLocked() (int, bool) {
+	for i := len(p.marks) - 1; i >= 0; i-- {
+		if p.marks[i].End >= 0 {
+			return p.marks[i].ExitCode, true
+		}
+	}
+	return 0, false
+}
+
+// ScrollToPreviousPrompt scrolls so the nearest prompt above the top of the
+// view is at the top. Returns false if there is none.
+func (p *Panel) ScrollToPreviousPrompt() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	count := p.Scrollback.Count()
+	top := count - p.scrollOffset
+	for i := len(p.marks) - 1; i >= 0; i-- {
+		idx := p.absToLineIndex(p.marks[i].Prompt)
+		if idx < 0 {
+			break
+		}
+		if idx < top {
+			p.scrollOffset = count - idx
+			p.redrawLocked()
+			return true
+		}
+	}
+	return false
+}
+
+// ScrollToNextPrompt scrolls so the nearest prompt below the top of the view
+// is at the top (or back to the live view). Returns false if already live.
+func (p *Panel) ScrollToNextPrompt() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.scrollOffset == 0 {
+		return false
+	}
+	count := p.Scrollback.Count()
+	top := count - p.scrollOffset
+	p.scrollOffset = 0
+	for _, mark := range p.marks {
+		idx := p.absToLineIndex(mark.Prompt)
+		if idx > top {
+			if offset := count - idx; offset > 0 {
+				p.scrollOffset = offset
+			}
+			break
+		}
+	}
+	p.redrawLocked()
+	return true
+}
+
+// SelectLastCommandOutput selects the output of the most recent command
+// (still running or finished). Returns false if there is nothing to select.
+func (p *Panel) SelectLastCommandOutput() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i := len(p.marks) - 1; i >= 0; i-- {
+		mark := p.marks[i]
+		if mark.Output < 0 {
+			continue
+		}
+		start := p.absToLineIndex(mark.Output)
+		end := p.Scrollback.Count() + p.VT.Cursor().Y
+		if mark.End >= 0 {
+			end = p.absToLineIndex(mark.End)
+		}
+		if start < 0 {
+			start = 0
+		}
+		if end <= start {
+			return false // Command printed nothing
+		}
+
+		cols, _ := p.VT.Size()
+		p.Selection[0] = Loc{X: 0, Y: start}
+		p.Selection[1] = Loc{X: cols, Y: end - 1}
+		p.keyboardSelecting = false
+		p.redrawLocked()
+		return true
+	}
+	return false
+}
+
+// redrawLocked requests a redraw. Must be called with p.mu held.
+func (p *Panel) redrawLocked() {
+	if p.OnRedraw != nil {
+		p.OnRedraw()
+	}
+}
+
+// drawExitStatus shows the last command's exit status on the bottom border
+// (green check or red cross with the status code)
+func (p *Panel) drawExitStatus(screen tcell.Screen) {
+	code, ok := p.lastExitCodeLocked()
+	if !ok || p.Region.Width < 12 {
+		return
+	}
+
+	label := " ✓ "
+	style := config.DefStyle.Foreground(tcell.ColorGreen).Bold(true)
+	if code != 0 {
+		label = fmt.Sprintf(" ✗ %d ", code)
+		style = config.DefStyle.Foreground(tcell.ColorRed).Bold(true)
+	}
+
+	x := p.Region.X + 2
+	y := p.Region.Y + p.Region.Height - 1
+	for _, r := range label {
+		screen.SetContent(x, y, r, nil, style)
+		x++
+	}
+}
+
+// getShellIntegrationCommand returns hooks that make the shell send OSC 7
+// (working directory) and OSC 133 (prompt/command/output marks).
+// Sourced after the prompt init so the prompt markers wrap the final prompt.
+func getShellIntegrationCommand(shellName string) string {
+	// Every shell sends D (with the last status) before each prompt; marks
+	// only use it when a C showed that a command actually ran. The bash and
+	// zsh hooks run first, so they hand on the exit status to prompts and
+	// hooks that show it.
+	switch shellName {
+	case "zsh":
+		return `
+# THICC shell integration (OSC 7 cwd + OSC 133 semantic prompts)
+_thicc_si_precmd() {
+  local s=$?
+  printf '\e]133;D;%s\a\e]7;file://%s%s\a' "$s" "$HOST" "$PWD"
+  return $s
+}
+_thicc_si_preexec() { printf '\e]133;C\a' }
+precmd_functions=(_thicc_si_precmd $precmd_functions)
+preexec_functions+=(_thicc_si_preexec)
+PROMPT=$'%{\e]133;A\a%}'"$PROMPT"$'%{\e]133;B\a%}'
+`
+	case "fish":
+		return `
+# THICC shell integration (OSC 7 cwd + OSC 133 semantic prompts)
+function __thicc_si_prompt --on-event fish_prompt
+  printf '\e]133;D;%s\a\e]7;file://%s%s\a\e]133;A\a' $status (hostname) $PWD
+end
+function __thicc_si_preexec --on-event fish_preexec
+  printf '\e]133;C\a'
+end
+`
+	}
+	// bash (PS0 needs bash 4.4+; older versions just don't get output marks)
+	return `
+# THICC shell integration (OSC 7 cwd + OSC 133 semantic prompts)
+_thicc_si_precmd() {
+  local s=$?
+  printf '\e]133;D;%s\a\e]7;file://%s%s\a' "$s" "$HOSTNAME" "$PWD"
+  return $s
+}
+PROMPT_COMMAND="_thicc_si_precmd${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+PS0=$'\e]133;C\a'"$PS0"
+PS1='\[\e]133;A\a\]'"$PS1"'\[\e]133;B\a\]'
+`
+}
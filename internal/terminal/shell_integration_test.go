@@ -0,0 +1,174 @@
+package terminal
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/hinshun/vt10x"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestPanel creates a panel with a VT emulator but no process
+func newTestPanel(cols, rows int) *Panel {
+	return &Panel{
+		VT:         vt10x.New(vt10x.WithSize(cols, rows)),
+		Scrollback: NewScrollbackBuffer(100),
+	}
+}
+
+// newSeededTestPanel creates a test panel with text on its first row. On a
+// blank screen the scroll detection takes the first output for a scroll, so
+// tests that check absolute line numbers start from here instead.
+func newSeededTestPanel(cols, rows int) *Panel {
+	p := newTestPanel(cols, rows)
+	p.VT.Write([]byte("welcome\r\n"))
+	return p
+}
+
+// feed simulates readLoop handing output to the VT emulator
+func feed(p *Panel, s string) {
+	p.captureScreenBefore()
+	p.writeVT([]byte(s))
+	p.trackModes([]byte(s))
+	p.captureScrolledLines()
+}
+
+// =============================================================================
+// OSC Scanner Tests
+// =============================================================================
+
+func TestOSCScanner_BELAndSTTerminators(t *testing.T) {
+	var s oscScanner
+	data := []byte("ab\x1b]7;file://host/tmp\x07cd\x1b]133;A\x1b\\ef")
+	seqs := s.Feed(data)
+
+	assert.Len(t, seqs, 2)
+	assert.Equal(t, "7", seqs[0].Code)
+	assert.Equal(t, "file://host/tmp", seqs[0].Data)
+	assert.Equal(t, "cd", string(data[seqs[0].End:seqs[0].End+2]))
+	assert.Equal(t, "133", seqs[1].Code)
+	assert.Equal(t, "A", seqs[1].Data)
+	assert.Equal(t, "ef", string(data[seqs[1].End:]))
+}
+
+func TestOSCScanner_SplitAcrossReads(t *testing.T) {
+	var s oscScanner
+	assert.Empty(t, s.Feed([]byte("x\x1b]13")))
+	assert.Empty(t, s.Feed([]byte("3;D;1\x1b")))
+
+	seqs := s.Feed([]byte("\\y"))
+	assert.Len(t, seqs, 1)
+	assert.Equal(t, "133", seqs[0].Code)
+	assert.Equal(t, "D;1", seqs[0].Data)
+	assert.Equal(t, 1, seqs[0].End)
+}
+
+func TestOSCScanner_IgnoresOtherEscapes(t *testing.T) {
+	var s oscScanner
+	assert.Empty(t, s.Feed([]byte("\x1b[31mred\x1b[0m\x1b]")))
+	// Unterminated sequence interrupted by another escape is dropped
+	assert.Empty(t, s.Feed([]byte("7;oops\x1b[Kmore")))
+}
+
+// =============================================================================
+// Shell Integration Tests
+// =============================================================================
+
+func TestShellIntegration_TracksCwd(t *testing.T) {
+	p := newTestPanel(40, 5)
+	feed(p, "\x1b]7;file://myhost/home/me/my%20project\x07")
+	assert.Equal(t, "/home/me/my project", p.Cwd())
+
+	feed(p, "\x1b]7;not a url\x07")
+	assert.Equal(t, "/home/me/my project", p.Cwd())
+}
+
+func TestShellIntegration_RecordsPromptMarks(t *testing.T) {
+	p := newSeededTestPanel(40, 5)
+
+	// Startup D (no command ran yet) is ignored
+	feed(p, "\x1b]133;D;0\x07\x1b]133;A\x07$ \x1b]133;B\x07")
+	_, ok := p.LastExitCode()
+	assert.False(t, ok)
+
+	feed(p, "false\r\n\x1b]133;C\x07oops\r\n\x1b]133;D;1\x07\x1b]133;A\x07$ \x1b]133;B\x07")
+	assert.True(t, p.HasShellIntegration())
+	code, ok := p.LastExitCode()
+	assert.True(t, ok)
+	assert.Equal(t, 1, code)
+
+	assert.Len(t, p.marks, 2)
+	assert.Equal(t, PromptMark{Prompt: 1, Command: 1, Output: 2, End: 3, ExitCode: 1}, p.marks[0])
+	assert.Equal(t, 3, p.marks[1].Prompt)
+}
+
+func TestShellIntegration_FirstPromptOnBlankScreen(t *testing.T) {
+	p := newTestPanel(40, 5)
+	feed(p, "\x1b]133;A\x07$ \x1b]133;B\x07")
+
+	// Nothing scrolled, so the mark is still on the prompt's row
+	assert.Equal(t, 0, p.Scrollback.Count())
+	assert.Len(t, p.marks, 1)
+	assert.Equal(t, 0, p.absToLineIndex(p.marks[0].Prompt))
+	assert.Equal(t, '$', p.VT.Cell(0, 0).Char)
+}
+
+func TestShellIntegration_SelectLastCommandOutput(t *testing.T) {
+	p := newTestPanel(20, 5)
+	feed(p, "\x1b]133;A\x07$ \x1b]133;B\x07ls\r\n\x1b]133;C\x07a.go\r\nb.go\r\n\x1b]133;D;0\x07\x1b]133;A\x07$ ")
+
+	assert.True(t, p.SelectLastCommandOutput())
+	assert.Equal(t, "a.go                \nb.go                ", p.GetSelection())
+}
+
+func TestShellIntegration_MarksSurviveScrolling(t *testing.T) {
+	p := newTestPanel(20, 4)
+	feed(p, "\x1b]133;A\x07$ \x1b]133;B\x07seq\r\n\x1b]133;C\x07")
+	for _, line := range []string{"1\r\n", "2\r\n", "3\r\n", "4\r\n", "5\r\n"} {
+		feed(p, line)
+	}
+	feed(p, "\x1b]133;D;0\x07\x1b]133;A\x07$ ")
+
+	// The first prompt has scrolled into scrollback
+	assert.Greater(t, p.Scrollback.Count(), 0)
+	assert.Equal(t, 0, p.absToLineIndex(p.marks[0].Prompt))
+
+	assert.True(t, p.ScrollToPreviousPrompt())
+	assert.Equal(t, p.Scrollback.Count(), p.ScrollOffset())
+	assert.False(t, p.ScrollToPreviousPrompt())
+
+	assert.True(t, p.ScrollToNextPrompt())
+	assert.Equal(t, 0, p.ScrollOffset())
+}
+
+func TestShellIntegration_ClearDropsStaleMarks(t *testing.T) {
+	p := newSeededTestPanel(20, 5)
+	feed(p, "\x1b]133;A\x07$ \r\n\x1b]133;A\x07$ ")
+	assert.Len(t, p.marks, 2)
+
+	// A redraw from the top - the next prompt replaces both marks
+	feed(p, "\x1b[H\x1b]133;A\x07$ ")
+	assert.Len(t, p.marks, 1)
+	assert.Equal(t, 0, p.marks[0].Prompt)
+}
+
+func TestShellIntegration_BashKeepsExitStatus(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	script := `PROMPT_COMMAND='echo "status=$?"'` + getShellIntegrationCommand("bash") + `false; eval "$PROMPT_COMMAND"`
+	out, err := exec.Command("bash", "--norc", "--noprofile", "-c", script).Output()
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(string(out), "status=1\n"), "the prompt still sees the command's status: %q", out)
+}
+
+func TestShellIntegration_ZshKeepsExitStatus(t *testing.T) {
+	if _, err := exec.LookPath("zsh"); err != nil {
+		t.Skip("zsh not available")
+	}
+	script := getShellIntegrationCommand("zsh") + `false; _thicc_si_precmd; echo "status=$?"`
+	out, err := exec.Command("zsh", "-f", "-c", script).Output()
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(string(out), "status=1\n"), "later hooks still see the command's status: %q", out)
+}
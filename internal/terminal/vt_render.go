@@ -59,6 +59,16 @@ func (p *Panel) Render(screen tcell.Screen) {
 		p.renderLiveView(screen, contentX, contentY, contentW, contentH, cols, rows)
 	}
 
+	// Highlight scrollback search matches
+	if p.SearchMode {
+		p.drawSearchMatches(screen, contentX, contentY, contentW, contentH)
+	}
+
+	// Label file references in link mode
+	if p.LinkMode {
+		p.drawLinkHints(screen, contentX, contentY, contentW, contentH)
+	}
+
 	// Draw scroll indicator if scrolled up
 	if p.scrollOffset > 0 && !useAltScreen {
 		p.drawScrollIndicator(screen)
@@ -79,6 +89,10 @@ func (p *Panel) Render(screen tcell.Screen) {
 
 	// Draw border (always draw, but style changes based on focus)
 	p.drawBorder(screen)
+	p.drawTitle(screen)
+
+	// Last command's exit status (from shell integration marks)
+	p.drawExitStatus(screen)
 }
 
 // Spinner frames for loading animation (braille dots)
@@ -283,6 +297,21 @@ func (p *Panel) drawScrollIndicator(screen tcell.Screen) {
 	}
 }
 
+// drawTitle draws the panel title, if any, at the left of the top border
+func (p *Panel) drawTitle(screen tcell.Screen) {
+	if p.Title == "" || p.Region.Width < 12 {
+		return
+	}
+	title := []rune(" " + p.Title + " ")
+	if limit := p.Region.Width - 4; len(title) > limit {
+		title = append(title[:limit-2], '…', ' ')
+	}
+	style := config.DefStyle.Foreground(tcell.ColorTeal).Bold(true)
+	for i, r := range title {
+		screen.SetContent(p.Region.X+2+i, p.Region.Y, r, nil, style)
+	}
+}
+
 // drawBorder draws a border around the terminal panel
 // Focused: double-line pink border
 // Passthrough: double-line orange border
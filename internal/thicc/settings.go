@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	json5 "github.com/micro-editor/json5"
 )
@@ -23,11 +24,14 @@ const (
 	DefaultBackgroundColor        = "#0b0614"
 	DefaultDoubleClickThresholdMs = 400
 	DefaultPRSize                 = "medium" // small, medium, or large
+	DefaultAutoFetchMinutes       = 3
+	DefaultCommitMessageCommand   = "claude -p"
 )
 
 // TerminalSettings contains terminal-specific settings
 type TerminalSettings struct {
-	ScrollbackLines int `json:"scrollback_lines"`
+	ScrollbackLines int  `json:"scrollback_lines"`
+	Detachable      bool `json:"detachable"` // Run terminals in the background session daemon
 }
 
 // AppearanceSettings contains appearance-related settings
@@ -41,11 +45,28 @@ type EditorSettings struct {
 	PRSize                 string `json:"pr_size"` // small, medium, or large
 }
 
+// GitSettings contains source control settings
+type GitSettings struct {
+	AutoFetch             bool   `json:"auto_fetch"`              // Fetch from the remotes in the background
+	AutoFetchMinutes      int    `json:"auto_fetch_minutes"`      // How often to fetch
+	CommitMessageCommand  string `json:"commit_message_command"`  // Shell command that writes a commit message for the diff on stdin
+	CommitMessageTemplate string `json:"commit_message_template"` // "conventional", custom instructions, or "" for the default
+	Checkpoints           bool   `json:"checkpoints"`             // Snapshot the working tree around AI turns
+}
+
+// LSPSettings contains language server settings
+type LSPSettings struct {
+	Disabled bool              `json:"disabled"` // Don't start language servers
+	Servers  map[string]string `json:"servers"`  // Command line per filetype, "" to turn a server off
+}
+
 // ThiccSettings holds all THICC-specific configuration
 type ThiccSettings struct {
 	Terminal   TerminalSettings   `json:"terminal"`
 	Appearance AppearanceSettings `json:"appearance"`
 	Editor     EditorSettings     `json:"editor"`
+	Git        GitSettings        `json:"git"`
+	LSP        LSPSettings        `json:"lsp"`
 }
 
 // GlobalThiccSettings is the loaded settings instance
@@ -64,6 +85,11 @@ func DefaultSettings() *ThiccSettings {
 			DoubleClickThresholdMs: DefaultDoubleClickThresholdMs,
 			PRSize:                 DefaultPRSize,
 		},
+		Git: GitSettings{
+			AutoFetchMinutes:     DefaultAutoFetchMinutes,
+			CommitMessageCommand: DefaultCommitMessageCommand,
+			Checkpoints:          true,
+		},
 	}
 }
 
@@ -151,6 +177,12 @@ func LoadSettings() *ThiccSettings {
 	if settings.Editor.PRSize == "" {
 		settings.Editor.PRSize = DefaultPRSize
 	}
+	if settings.Git.AutoFetchMinutes <= 0 {
+		settings.Git.AutoFetchMinutes = DefaultAutoFetchMinutes
+	}
+	if settings.Git.CommitMessageCommand == "" {
+		settings.Git.CommitMessageCommand = DefaultCommitMessageCommand
+	}
 
 	GlobalThiccSettings = settings
 	return settings
@@ -168,7 +200,10 @@ func SaveSettings(settings *ThiccSettings) error {
   // Terminal settings
   "terminal": {
     // Number of lines to keep in scrollback buffer (default: %d)
-    "scrollback_lines": %d
+    "scrollback_lines": %d,
+    // Keep terminals running in a background daemon when thicc exits,
+    // so they can be reattached with: thicc --attach (default: false)
+    "detachable": %t
   },
 
   // Appearance settings
@@ -183,12 +218,45 @@ func SaveSettings(settings *ThiccSettings) error {
     // Target PR size affects how quickly the PR meter fills up
     // Options: "small" (stricter), "medium" (default), "large" (lenient)
     "pr_size": "%s"
+  },
+
+  // Source control settings
+  "git": {
+    // Fetch from the remotes in the background, so the pull button shows
+    // new commits without pulling first (default: false)
+    "auto_fetch": %t,
+    // Minutes between background fetches (default: %d)
+    "auto_fetch_minutes": %d,
+    // Command that writes a commit message (Alt+G in Source Control). It gets
+    // instructions and the staged diff on stdin, e.g. "ollama run llama3"
+    // (default: "%s")
+    "commit_message_command": %q,
+    // "conventional" for Conventional Commits (feat:, fix: ...), your own
+    // instructions for the message, or "" for a plain summary
+    "commit_message_template": %q,
+    // Snapshot the working tree when an AI tool starts and finishes a turn,
+    // so it can be rolled back from the checkpoint timeline (default: true)
+    "checkpoints": %t
+  },
+
+  // Language server settings
+  "lsp": {
+    // Don't start language servers (default: false)
+    "disabled": %t,
+    // Server command per filetype, replacing the built-in one, or "" to not
+    // start a server for the filetype, e.g. {"python": "pylsp"}
+    "servers": %s
   }
 }
 `,
 		DefaultScrollbackLines, settings.Terminal.ScrollbackLines,
+		settings.Terminal.Detachable,
 		DefaultBackgroundColor, settings.Appearance.BackgroundColor,
 		settings.Editor.PRSize,
+		settings.Git.AutoFetch, DefaultAutoFetchMinutes, settings.Git.AutoFetchMinutes,
+		DefaultCommitMessageCommand, settings.Git.CommitMessageCommand, settings.Git.CommitMessageTemplate,
+		settings.Git.Checkpoints,
+		settings.LSP.Disabled, lspServersJSON(settings.LSP.Servers),
 	)
 
 	filePath := GetSettingsFilePath()
@@ -201,6 +269,18 @@ func SaveSettings(settings *ThiccSettings) error {
 	return nil
 }
 
+// lspServersJSON formats the language server commands for the settings file
+func lspServersJSON(servers map[string]string) string {
+	if len(servers) == 0 {
+		return "{}"
+	}
+	data, err := json.MarshalIndent(servers, "    ", "  ")
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
 // GetScrollbackLines returns the terminal scrollback lines setting
 func GetScrollbackLines() int {
 	if GlobalThiccSettings == nil {
@@ -209,6 +289,14 @@ func GetScrollbackLines() int {
 	return GlobalThiccSettings.Terminal.ScrollbackLines
 }
 
+// GetDetachableTerminals returns whether terminals run in the session daemon
+func GetDetachableTerminals() bool {
+	if GlobalThiccSettings == nil {
+		return false
+	}
+	return GlobalThiccSettings.Terminal.Detachable
+}
+
 // GetBackgroundColor returns the appearance background color setting
 func GetBackgroundColor() string {
 	if GlobalThiccSettings == nil {
@@ -225,6 +313,53 @@ func GetDoubleClickThreshold() int {
 	return GlobalThiccSettings.Editor.DoubleClickThresholdMs
 }
 
+// GetAutoFetchInterval returns how often to fetch in the background, or 0 if
+// auto-fetch is off
+func GetAutoFetchInterval() time.Duration {
+	if GlobalThiccSettings == nil || !GlobalThiccSettings.Git.AutoFetch {
+		return 0
+	}
+	minutes := GlobalThiccSettings.Git.AutoFetchMinutes
+	if minutes <= 0 {
+		minutes = DefaultAutoFetchMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// GetCommitMessageCommand returns the command that generates commit messages
+func GetCommitMessageCommand() string {
+	if GlobalThiccSettings == nil || GlobalThiccSettings.Git.CommitMessageCommand == "" {
+		return DefaultCommitMessageCommand
+	}
+	return GlobalThiccSettings.Git.CommitMessageCommand
+}
+
+// GetCommitMessageTemplate returns the commit message template setting
+func GetCommitMessageTemplate() string {
+	if GlobalThiccSettings == nil {
+		return ""
+	}
+	return GlobalThiccSettings.Git.CommitMessageTemplate
+}
+
+// GetCheckpointsEnabled returns whether checkpoints are taken around AI turns
+func GetCheckpointsEnabled() bool {
+	return GlobalThiccSettings == nil || GlobalThiccSettings.Git.Checkpoints
+}
+
+// GetLSPEnabled returns whether language servers are started
+func GetLSPEnabled() bool {
+	return GlobalThiccSettings == nil || !GlobalThiccSettings.LSP.Disabled
+}
+
+// GetLSPServers returns the language server commands set per filetype
+func GetLSPServers() map[string]string {
+	if GlobalThiccSettings == nil {
+		return nil
+	}
+	return GlobalThiccSettings.LSP.Servers
+}
+
 // ValidationError represents a settings validation error
 type ValidationError struct {
 	Field   string
@@ -239,9 +374,9 @@ func (e ValidationError) Error() string {
 func ValidateSettingsJSON(data []byte) (*ThiccSettings, []ValidationError) {
 	var errors []ValidationError
 
-	// First check if it's valid JSON
-	var settings ThiccSettings
-	if err := json.Unmarshal(data, &settings); err != nil {
+	// First check if it's valid JSON. Settings left out keep their defaults.
+	settings := DefaultSettings()
+	if err := json.Unmarshal(data, settings); err != nil {
 		errors = append(errors, ValidationError{
 			Field:   "json",
 			Message: "Invalid JSON: " + err.Error(),
@@ -250,12 +385,12 @@ func ValidateSettingsJSON(data []byte) (*ThiccSettings, []ValidationError) {
 	}
 
 	// Validate individual fields
-	errors = append(errors, validateSettings(&settings)...)
+	errors = append(errors, validateSettings(settings)...)
 
 	if len(errors) > 0 {
-		return &settings, errors
+		return settings, errors
 	}
-	return &settings, nil
+	return settings, nil
 }
 
 // validateSettings validates a ThiccSettings struct
@@ -298,6 +433,14 @@ func validateSettings(settings *ThiccSettings) []ValidationError {
 		})
 	}
 
+	// Validate auto-fetch interval
+	if settings.Git.AutoFetchMinutes < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "git.auto_fetch_minutes",
+			Message: "must be non-negative",
+		})
+	}
+
 	return errors
 }
 
@@ -341,6 +484,12 @@ func ReloadSettings() []ValidationError {
 	if settings.Editor.DoubleClickThresholdMs == 0 {
 		settings.Editor.DoubleClickThresholdMs = DefaultDoubleClickThresholdMs
 	}
+	if settings.Git.AutoFetchMinutes == 0 {
+		settings.Git.AutoFetchMinutes = DefaultAutoFetchMinutes
+	}
+	if settings.Git.CommitMessageCommand == "" {
+		settings.Git.CommitMessageCommand = DefaultCommitMessageCommand
+	}
 
 	GlobalThiccSettings = settings
 	log.Printf("THICC Settings: Reloaded settings successfully")